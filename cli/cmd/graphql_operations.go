@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fluxbase-eu/fluxbase/cli/output"
+)
+
+var graphqlOperationsCmd = &cobra.Command{
+	Use:     "operations",
+	Aliases: []string{"operation", "ops"},
+	Short:   "Manage registered GraphQL operations",
+	Long: `Register, list, and delete persisted GraphQL operations.
+
+Registered operations can be executed by sending the SHA-256 hash of their
+document instead of the document itself. When graphql.persisted_queries.allowlist_only
+is enabled, they are the only operations non-admin roles can run.`,
+}
+
+var (
+	gqlOpsManifest string
+	gqlOpsName     string
+	gqlOpsForce    bool
+)
+
+var graphqlOperationsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered operations",
+	Long: `List registered GraphQL operations.
+
+Examples:
+  fluxbase graphql operations list
+  fluxbase graphql operations list --name GetUser
+  fluxbase graphql operations list -o json`,
+	PreRunE: requireAuth,
+	RunE:    runGraphQLOperationsList,
+}
+
+var graphqlOperationsRegisterCmd = &cobra.Command{
+	Use:   "register [files...]",
+	Short: "Register operations",
+	Long: `Register GraphQL operations from .graphql files or a persisted query manifest.
+
+Each file is registered as one document, hashed exactly as it is written, so
+clients must send the same text. Registering a document again is a no-op.
+
+Examples:
+  # Register the operations of a mobile app
+  fluxbase graphql operations register ./src/graphql/*.graphql
+
+  # Register an Apollo persisted query manifest
+  fluxbase graphql operations register --manifest ./persisted-query-manifest.json`,
+	PreRunE: requireAuth,
+	RunE:    runGraphQLOperationsRegister,
+}
+
+var graphqlOperationsDeleteCmd = &cobra.Command{
+	Use:     "delete [hash]",
+	Aliases: []string{"rm", "remove"},
+	Short:   "Delete a registered operation",
+	Long: `Delete a registered GraphQL operation by hash.
+
+Examples:
+  fluxbase graphql operations delete 5b2a...e1
+  fluxbase graphql operations delete 5b2a...e1 --force`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: requireAuth,
+	RunE:    runGraphQLOperationsDelete,
+}
+
+func init() {
+	graphqlOperationsListCmd.Flags().StringVar(&gqlOpsName, "name", "", "Only list operations with this name")
+	graphqlOperationsRegisterCmd.Flags().StringVar(&gqlOpsManifest, "manifest", "", "Apollo persisted query manifest (JSON)")
+	graphqlOperationsDeleteCmd.Flags().BoolVarP(&gqlOpsForce, "force", "f", false, "Skip confirmation prompt")
+
+	graphqlOperationsCmd.AddCommand(graphqlOperationsListCmd)
+	graphqlOperationsCmd.AddCommand(graphqlOperationsRegisterCmd)
+	graphqlOperationsCmd.AddCommand(graphqlOperationsDeleteCmd)
+	graphqlCmd.AddCommand(graphqlOperationsCmd)
+}
+
+// graphqlOperation represents a registered operation
+type graphqlOperation struct {
+	Hash      string `json:"hash"`
+	Name      string `json:"name,omitempty"`
+	Query     string `json:"query,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// persistedQueryManifest is the Apollo persisted query manifest format
+type persistedQueryManifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+func runGraphQLOperationsList(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := url.Values{}
+	if gqlOpsName != "" {
+		query.Set("name", gqlOpsName)
+	}
+
+	var result struct {
+		Operations []graphqlOperation `json:"operations"`
+		Count      int                `json:"count"`
+	}
+	if err := apiClient.DoGet(ctx, "/api/v1/admin/graphql/operations", query, &result); err != nil {
+		return err
+	}
+
+	if len(result.Operations) == 0 {
+		fmt.Println("No registered operations found.")
+		return nil
+	}
+
+	formatter := GetFormatter()
+	if formatter.Format != output.FormatTable {
+		return formatter.Print(result.Operations)
+	}
+
+	data := output.TableData{
+		Headers: []string{"HASH", "NAME", "CREATED"},
+		Rows:    make([][]string, 0, len(result.Operations)),
+	}
+	for _, op := range result.Operations {
+		name := op.Name
+		if name == "" {
+			name = "-"
+		}
+		data.Rows = append(data.Rows, []string{op.Hash, name, formatDate(op.CreatedAt)})
+	}
+	formatter.PrintTable(data)
+
+	return nil
+}
+
+// readOperationDocuments returns the documents to register from files or a manifest
+func readOperationDocuments(files []string, manifestPath string) ([]string, error) {
+	var documents []string
+
+	if manifestPath != "" {
+		content, err := os.ReadFile(manifestPath) //nolint:gosec // CLI tool reads user-provided file path
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		var manifest persistedQueryManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if manifest.Format != "apollo-persisted-query-manifest" {
+			return nil, fmt.Errorf("unsupported manifest format %q", manifest.Format)
+		}
+		for _, op := range manifest.Operations {
+			// Clients send the manifest ID as the hash, so it must be the server's hash of the body
+			sum := sha256.Sum256([]byte(op.Body))
+			if op.ID != "" && !strings.EqualFold(op.ID, hex.EncodeToString(sum[:])) {
+				return nil, fmt.Errorf("operation %s: id is not the SHA-256 hash of its body", op.Name)
+			}
+			documents = append(documents, op.Body)
+		}
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file) //nolint:gosec // CLI tool reads user-provided file path
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		documents = append(documents, string(content))
+	}
+
+	return documents, nil
+}
+
+func runGraphQLOperationsRegister(cmd *cobra.Command, args []string) error {
+	documents, err := readOperationDocuments(args, gqlOpsManifest)
+	if err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return fmt.Errorf("provide .graphql files as arguments or use --manifest")
+	}
+
+	operations := make([]map[string]string, len(documents))
+	for i, document := range documents {
+		operations[i] = map[string]string{"query": document}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var result struct {
+		Operations []graphqlOperation `json:"operations"`
+		Count      int                `json:"count"`
+	}
+	if err := apiClient.DoPost(ctx, "/api/v1/admin/graphql/operations", map[string]interface{}{"operations": operations}, &result); err != nil {
+		return err
+	}
+
+	formatter := GetFormatter()
+	if formatter.Format != output.FormatTable {
+		return formatter.Print(result.Operations)
+	}
+
+	for _, op := range result.Operations {
+		name := op.Name
+		if name == "" {
+			name = "(anonymous)"
+		}
+		fmt.Printf("%s  %s\n", op.Hash, name)
+	}
+	fmt.Printf("\nRegistered %d operation(s)\n", result.Count)
+
+	return nil
+}
+
+func runGraphQLOperationsDelete(cmd *cobra.Command, args []string) error {
+	hash := args[0]
+
+	if !gqlOpsForce {
+		fmt.Printf("Are you sure you want to delete operation '%s'?\n", hash)
+		fmt.Printf("Type 'yes' to confirm: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+
+		if strings.ToLower(confirm) != "yes" {
+			fmt.Println("Deletion cancelled")
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := apiClient.DoDelete(ctx, "/api/v1/admin/graphql/operations/"+url.PathEscape(hash)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Operation '%s' deleted successfully\n", hash)
+	return nil
+}
@@ -24,16 +24,29 @@ var (
 	typesIncludeViews     bool
 	typesOutput           string
 	typesFormat           string
+	typesLang             string
+	typesPackage          string
 )
 
+// typesLangTitles are the display names of the languages types are generated in
+var typesLangTitles = map[string]string{
+	"typescript": "TypeScript",
+	"go":         "Go",
+	"python":     "Python",
+	"dart":       "Dart",
+}
+
 var typesGenerateCmd = &cobra.Command{
 	Use:   "generate",
-	Short: "Generate TypeScript types from schema",
-	Long: `Generate TypeScript type definitions from your database schema.
+	Short: "Generate TypeScript, Go, Python or Dart types from schema",
+	Long: `Generate type definitions from your database schema.
 
-This command fetches your database schema and generates TypeScript interfaces
-for all tables, views, and optionally RPC functions. The generated types can
-be used with the Fluxbase TypeScript SDK for type-safe database queries.
+This command fetches your database schema and generates types for all tables,
+views, enums, and optionally RPC function arguments and results. TypeScript
+interfaces are generated by default and can be used with the Fluxbase
+TypeScript SDK for type-safe database queries. Use --lang to generate Go
+structs (with json and db tags), Python dataclasses and TypedDicts, or Dart
+classes instead.
 
 Examples:
   # Generate types for the public schema and write to types.ts
@@ -49,7 +62,14 @@ Examples:
   fluxbase types generate
 
   # Generate types without views
-  fluxbase types generate --include-views=false --output types.ts`,
+  fluxbase types generate --include-views=false --output types.ts
+
+  # Generate Go structs in package "models"
+  fluxbase types generate --lang go --package models --output models/types.go
+
+  # Generate Python or Dart types
+  fluxbase types generate --lang python --output types.py
+  fluxbase types generate --lang dart --output lib/types.dart`,
 	PreRunE: requireAuth,
 	RunE:    runTypesGenerate,
 }
@@ -72,6 +92,8 @@ func init() {
 	typesGenerateCmd.Flags().BoolVar(&typesIncludeViews, "include-views", true, "Include view types")
 	typesGenerateCmd.Flags().StringVarP(&typesOutput, "output", "o", "", "Output file path (default: stdout)")
 	typesGenerateCmd.Flags().StringVar(&typesFormat, "format", "types", "Output format: 'types' (interfaces only) or 'full' (with helpers)")
+	typesGenerateCmd.Flags().StringVar(&typesLang, "lang", "typescript", "Language: typescript, go, python or dart")
+	typesGenerateCmd.Flags().StringVar(&typesPackage, "package", "types", "Package name of the generated Go code")
 
 	typesCmd.AddCommand(typesGenerateCmd)
 	typesCmd.AddCommand(typesListCmd)
@@ -87,6 +109,8 @@ func runTypesGenerate(cmd *cobra.Command, args []string) error {
 		"include_functions": typesIncludeFunctions,
 		"include_views":     typesIncludeViews,
 		"format":            typesFormat,
+		"lang":              typesLang,
+		"package":           typesPackage,
 	}
 
 	// Make request
@@ -96,19 +120,29 @@ func runTypesGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to generate types: %w", err)
 	}
 
-	// Extract TypeScript content
-	typescript, ok := result["typescript"].(string)
+	// Extract generated code; servers without --lang support only return "typescript"
+	code, ok := result["code"].(string)
 	if !ok {
-		return fmt.Errorf("unexpected response format")
+		isTypeScript := typesLang == "" || strings.EqualFold(typesLang, "typescript") || strings.EqualFold(typesLang, "ts")
+		if code, ok = result["typescript"].(string); !ok || !isTypeScript {
+			return fmt.Errorf("unexpected response format (does the server support --lang %s?)", typesLang)
+		}
+	}
+
+	lang := "TypeScript"
+	if l, ok := result["lang"].(string); ok {
+		if title, ok := typesLangTitles[l]; ok {
+			lang = title
+		}
 	}
 
 	// Output
 	if typesOutput != "" {
 		// Write to file
-		if err := os.WriteFile(typesOutput, []byte(typescript), 0600); err != nil {
+		if err := os.WriteFile(typesOutput, []byte(code), 0600); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
-		fmt.Printf("TypeScript types written to %s\n", typesOutput)
+		fmt.Printf("%s types written to %s\n", lang, typesOutput)
 
 		// Show schemas included
 		if schemas, ok := result["schemas"].([]interface{}); ok {
@@ -120,7 +154,7 @@ func runTypesGenerate(cmd *cobra.Command, args []string) error {
 		}
 	} else {
 		// Output to stdout
-		fmt.Print(typescript)
+		fmt.Print(code)
 	}
 
 	return nil
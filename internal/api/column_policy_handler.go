@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ColumnPolicyHandler manages per-role column visibility and masking policies
+type ColumnPolicyHandler struct {
+	db          *database.Connection
+	schemaCache *database.SchemaCache
+}
+
+// NewColumnPolicyHandler creates a new column policy handler
+func NewColumnPolicyHandler(db *database.Connection, schemaCache *database.SchemaCache) *ColumnPolicyHandler {
+	return &ColumnPolicyHandler{db: db, schemaCache: schemaCache}
+}
+
+// ColumnPolicyRequest represents a request to create or replace a column policy
+type ColumnPolicyRequest struct {
+	Schema      string `json:"schema"`
+	Table       string `json:"table"`
+	Column      string `json:"column"`
+	Role        string `json:"role"`
+	Mode        string `json:"mode"`                   // hide, mask, hash or null
+	MaskVisible *int   `json:"mask_visible,omitempty"` // Trailing characters left visible by mask (default 4)
+}
+
+// ColumnPolicyResponse represents a stored column policy
+type ColumnPolicyResponse struct {
+	ID int `json:"id"`
+	database.ColumnPolicy
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// validate checks the request and fills in defaults
+func (r *ColumnPolicyRequest) validate() error {
+	if r.Schema == "" {
+		r.Schema = "public"
+	}
+	if err := validateIdentifier(r.Schema, "schema"); err != nil {
+		return err
+	}
+	if err := validateIdentifier(r.Table, "table"); err != nil {
+		return err
+	}
+	if r.Column == "" {
+		return fmt.Errorf("column name cannot be empty")
+	}
+	r.Role = strings.TrimSpace(r.Role)
+	if r.Role == "" {
+		return fmt.Errorf("role is required")
+	}
+	if len(r.Role) > 63 || strings.ContainsAny(r.Role, " \t\n") {
+		return fmt.Errorf("invalid role name: %s", r.Role)
+	}
+	if !database.ColumnPolicyMode(r.Mode).IsValid() {
+		return fmt.Errorf("invalid mode: %s. Must be hide, mask, hash, or null", r.Mode)
+	}
+	if r.MaskVisible == nil {
+		visible := database.DefaultMaskVisible
+		r.MaskVisible = &visible
+	}
+	if *r.MaskVisible < 0 {
+		return fmt.Errorf("mask_visible must be a non-negative integer")
+	}
+	return nil
+}
+
+// HandleSetColumnPolicy creates or replaces the policy for a column and role
+func (h *ColumnPolicyHandler) HandleSetColumnPolicy(c *fiber.Ctx) error {
+	var req ColumnPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendInvalidBody(c)
+	}
+	if err := req.validate(); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+
+	ctx := c.Context()
+
+	table, exists, err := h.schemaCache.GetTable(ctx, req.Schema, req.Table)
+	if err != nil {
+		log.Error().Err(err).Str("table", req.Schema+"."+req.Table).Msg("Failed to lookup table")
+		return SendInternalError(c, "Failed to lookup table metadata")
+	}
+	if !exists {
+		return SendErrorWithCode(c, fiber.StatusNotFound, fmt.Sprintf("Table '%s.%s' not found", req.Schema, req.Table), ErrCodeNotFound)
+	}
+	if !table.HasColumn(req.Column) {
+		return SendErrorWithCode(c, fiber.StatusNotFound, fmt.Sprintf("Column '%s' not found in table '%s.%s'", req.Column, req.Schema, req.Table), ErrCodeNotFound)
+	}
+
+	var id int
+	var createdAt, updatedAt interface{}
+	err = h.db.Pool().QueryRow(ctx, `
+INSERT INTO api.column_policies (schema_name, table_name, column_name, role, mode, mask_visible)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (schema_name, table_name, column_name, role) DO UPDATE
+SET mode = EXCLUDED.mode,
+    mask_visible = EXCLUDED.mask_visible,
+    updated_at = NOW()
+RETURNING id, created_at, updated_at`,
+		req.Schema, req.Table, req.Column, req.Role, req.Mode, *req.MaskVisible,
+	).Scan(&id, &createdAt, &updatedAt)
+	if err != nil {
+		log.Error().Err(err).Str("table", req.Schema+"."+req.Table).Str("column", req.Column).Msg("Failed to save column policy")
+		return SendInternalError(c, "Failed to save column policy")
+	}
+
+	// Policies are served from the schema cache on every instance
+	h.schemaCache.InvalidateAll(ctx)
+
+	log.Info().
+		Str("table", req.Schema+"."+req.Table).
+		Str("column", req.Column).
+		Str("role", req.Role).
+		Str("mode", req.Mode).
+		Msg("Column policy saved")
+
+	return c.Status(fiber.StatusCreated).JSON(ColumnPolicyResponse{
+		ID: id,
+		ColumnPolicy: database.ColumnPolicy{
+			Schema:      req.Schema,
+			Table:       req.Table,
+			Column:      req.Column,
+			Role:        req.Role,
+			Mode:        database.ColumnPolicyMode(req.Mode),
+			MaskVisible: *req.MaskVisible,
+		},
+		CreatedAt: fmt.Sprintf("%v", createdAt),
+		UpdatedAt: fmt.Sprintf("%v", updatedAt),
+	})
+}
+
+// HandleListColumnPolicies lists column policies, optionally filtered by ?schema=, ?table= and ?role=
+func (h *ColumnPolicyHandler) HandleListColumnPolicies(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	query := `
+SELECT id, schema_name, table_name, column_name, role, mode, mask_visible, created_at, updated_at
+FROM api.column_policies`
+
+	var conditions []string
+	var args []interface{}
+	for _, filter := range []struct{ param, column string }{
+		{"schema", "schema_name"},
+		{"table", "table_name"},
+		{"role", "role"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", filter.column, len(args)))
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY schema_name, table_name, column_name, role"
+
+	rows, err := h.db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list column policies")
+		return SendInternalError(c, "Failed to list column policies")
+	}
+	defer rows.Close()
+
+	policies := []ColumnPolicyResponse{}
+	for rows.Next() {
+		var p ColumnPolicyResponse
+		var mode string
+		var createdAt, updatedAt interface{}
+		if err := rows.Scan(&p.ID, &p.Schema, &p.Table, &p.Column, &p.Role, &mode, &p.MaskVisible, &createdAt, &updatedAt); err != nil {
+			log.Error().Err(err).Msg("Failed to scan column policy row")
+			continue
+		}
+		p.Mode = database.ColumnPolicyMode(mode)
+		p.CreatedAt = fmt.Sprintf("%v", createdAt)
+		p.UpdatedAt = fmt.Sprintf("%v", updatedAt)
+		policies = append(policies, p)
+	}
+
+	return c.JSON(fiber.Map{
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+// HandleDeleteColumnPolicy deletes a column policy by ID
+func (h *ColumnPolicyHandler) HandleDeleteColumnPolicy(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return SendBadRequest(c, "Invalid column policy ID", ErrCodeInvalidID)
+	}
+
+	ctx := c.Context()
+
+	var deletedID int
+	err = h.db.Pool().QueryRow(ctx, `DELETE FROM api.column_policies WHERE id = $1 RETURNING id`, id).Scan(&deletedID)
+	if err == pgx.ErrNoRows {
+		return SendErrorWithCode(c, fiber.StatusNotFound, "Column policy not found", ErrCodeNotFound)
+	}
+	if err != nil {
+		log.Error().Err(err).Int("id", id).Msg("Failed to delete column policy")
+		return SendInternalError(c, "Failed to delete column policy")
+	}
+
+	h.schemaCache.InvalidateAll(ctx)
+
+	log.Info().Int("id", id).Msg("Column policy deleted")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Column policy deleted",
+	})
+}
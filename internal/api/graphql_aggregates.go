@@ -0,0 +1,303 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// graphqlAggregateFunctions are the per-column aggregates of the aggregate queries, in field order
+var graphqlAggregateFunctions = []AggregateFunction{AggSum, AggAvg, AggMin, AggMax}
+
+// graphqlAggregate is a column aggregate selected in an aggregate query
+type graphqlAggregate struct {
+	Aggregation
+	field string // GraphQL field name of the column
+	cast  string // Cast giving the result a type its GraphQL scalar serializes
+}
+
+// graphqlAggregateColumnType returns the GraphQL type of an aggregate of a column and the cast
+// applied to its result, or nil if the aggregate doesn't apply to the column's type. Sums and
+// averages apply to numbers; minimums and maximums to numbers, text and timestamps.
+func graphqlAggregateColumnType(fn AggregateFunction, pgType string) (graphql.Output, string) {
+	var integer, float, ordered bool
+	switch pgType {
+	case "integer", "int", "int4", "smallint", "int2", "bigint", "int8",
+		"serial", "serial4", "bigserial", "serial8":
+		integer = true
+	case "real", "float4", "double precision", "float8", "numeric", "decimal":
+		float = true
+	case "text", "varchar", "character varying", "char", "character", "name", "citext",
+		"timestamp", "timestamp without time zone", "timestamp with time zone", "timestamptz", "date":
+		ordered = true
+	}
+
+	switch fn {
+	case AggSum:
+		// Sums of bigints are numerics, which may exceed 64 bits
+		if integer {
+			return BigIntScalar, "text"
+		}
+		if float {
+			return graphql.Float, "float8"
+		}
+	case AggAvg:
+		if integer || float {
+			return graphql.Float, "float8"
+		}
+	case AggMin, AggMax:
+		if float {
+			return graphql.Float, "float8"
+		}
+		if integer || ordered {
+			return PostgresTypeToGraphQL(pgType, true), ""
+		}
+	}
+	return nil, ""
+}
+
+// isGroupableType tells whether grouping by a column of the type is possible
+func isGroupableType(pgType string) bool {
+	return pgType != "json" && pgType != "xml"
+}
+
+// generateAggregateField generates the aggregate query of a table, e.g. postsAggregate,
+// returning the row count and the sum, average, minimum and maximum of its columns, over all
+// matching records or per group
+func (g *GraphQLSchemaGenerator) generateAggregateField(table database.TableInfo, filterType *graphql.InputObject) *graphql.Field {
+	typeName := g.tableToTypeName(table.Schema, table.Name)
+
+	aggregateFields := graphql.Fields{
+		"count": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of records",
+		},
+	}
+
+	for _, fn := range graphqlAggregateFunctions {
+		fields := graphql.Fields{}
+		for _, col := range table.Columns {
+			if fieldType, _ := graphqlAggregateColumnType(fn, col.DataType); fieldType != nil {
+				fields[g.columnToFieldName(col.Name)] = &graphql.Field{
+					Type:        fieldType,
+					Description: fmt.Sprintf("%s of column %s", strings.ToUpper(string(fn)), col.Name),
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		name := toPascalCase(string(fn))
+		aggregateFields[string(fn)] = &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name:        typeName + name + "Fields",
+				Description: fmt.Sprintf("%s of the %s columns", name, table.Name),
+				Fields:      fields,
+			}),
+		}
+	}
+
+	args := graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{
+			Type:        filterType,
+			Description: "Filter conditions",
+		},
+	}
+
+	keyFields := graphql.Fields{}
+	columnValues := graphql.EnumValueConfigMap{}
+	for _, col := range table.Columns {
+		if !isGroupableType(col.DataType) {
+			continue
+		}
+		fieldName := g.columnToFieldName(col.Name)
+		keyFields[fieldName] = &graphql.Field{
+			Type:        PostgresTypeToGraphQL(col.DataType, true),
+			Description: fmt.Sprintf("Column %s (%s)", col.Name, col.DataType),
+		}
+		columnValues[fieldName] = &graphql.EnumValueConfig{Value: col.Name}
+	}
+	if len(keyFields) > 0 {
+		aggregateFields["keys"] = &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name:        typeName + "AggregateKeys",
+				Description: fmt.Sprintf("Grouped %s columns of a group", table.Name),
+				Fields:      keyFields,
+			}),
+			Description: "Values of the grouped columns (null when not grouping)",
+		}
+		args["groupBy"] = &graphql.ArgumentConfig{
+			Type: graphql.NewList(graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{
+				Name:        typeName + "Column",
+				Description: fmt.Sprintf("Columns of %s.%s", table.Schema, table.Name),
+				Values:      columnValues,
+			}))),
+			Description: "Columns to group by, one result per group",
+		}
+	}
+
+	aggregateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        typeName + "Aggregate",
+		Description: fmt.Sprintf("Aggregates of %s records", table.Name),
+		Fields:      aggregateFields,
+	})
+
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(aggregateType))),
+		Description: fmt.Sprintf("Aggregate %s records: one result, or one per group with groupBy", table.Name),
+		Args:        args,
+		Resolve:     g.makeAggregateResolver(table),
+	}
+}
+
+// makeAggregateResolver creates a resolver computing the aggregates selected in the query
+func (g *GraphQLSchemaGenerator) makeAggregateResolver(table database.TableInfo) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+
+		var filters []Filter
+		if filter, ok := p.Args["filter"].(map[string]interface{}); ok && len(filter) > 0 {
+			filters = g.buildFiltersFromArgs(table, filter)
+		}
+		var groupBy []string
+		if columns, ok := p.Args["groupBy"].([]interface{}); ok {
+			for _, column := range columns {
+				if name, ok := column.(string); ok {
+					groupBy = append(groupBy, name)
+				}
+			}
+		}
+		aggregates := g.selectedAggregates(table, p.Info)
+
+		// Hidden and masked columns can't be filtered, grouped or aggregated
+		current, err := g.currentTableInfo(ctx, table.Schema, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		params := &QueryParams{Filters: filters, GroupBy: groupBy}
+		for _, agg := range aggregates {
+			params.Aggregations = append(params.Aggregations, agg.Aggregation)
+		}
+		if err := checkProtectedColumns(current, graphqlColumnPolicyRole(ctx), params); err != nil {
+			return nil, err
+		}
+
+		sql, args := buildGraphQLAggregateQuery(table, filters, groupBy, aggregates)
+		rows, err := g.queryWithRLS(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			count, _ := row["count"].(int64)
+			result := map[string]interface{}{"count": int(count)}
+			if len(groupBy) > 0 {
+				keys := make(map[string]interface{}, len(groupBy))
+				for j, column := range groupBy {
+					keys[g.columnToFieldName(column)] = row[fmt.Sprintf("g%d", j)]
+				}
+				result["keys"] = keys
+			}
+			for j, agg := range aggregates {
+				values, ok := result[string(agg.Function)].(map[string]interface{})
+				if !ok {
+					values = map[string]interface{}{}
+					result[string(agg.Function)] = values
+				}
+				values[agg.field] = row[fmt.Sprintf("a%d", j)]
+			}
+			results[i] = result
+		}
+		return results, nil
+	}
+}
+
+// selectedAggregates returns the column aggregates selected under the resolved field
+func (g *GraphQLSchemaGenerator) selectedAggregates(table database.TableInfo, info graphql.ResolveInfo) []graphqlAggregate {
+	fieldToColumn := make(map[string]database.ColumnInfo, len(table.Columns))
+	for _, col := range table.Columns {
+		fieldToColumn[g.columnToFieldName(col.Name)] = col
+	}
+
+	var aggregates []graphqlAggregate
+	seen := make(map[string]bool)
+	for _, fieldAST := range info.FieldASTs {
+		visitSelectedFields(info, fieldAST.SelectionSet, func(fnField *ast.Field) {
+			fn := AggregateFunction(fnField.Name.Value)
+			visitSelectedFields(info, fnField.SelectionSet, func(colField *ast.Field) {
+				col, ok := fieldToColumn[colField.Name.Value]
+				if !ok || seen[string(fn)+"."+col.Name] {
+					return
+				}
+				fieldType, cast := graphqlAggregateColumnType(fn, col.DataType)
+				if fieldType == nil {
+					return
+				}
+				seen[string(fn)+"."+col.Name] = true
+				aggregates = append(aggregates, graphqlAggregate{
+					Aggregation: Aggregation{Function: fn, Column: col.Name},
+					field:       colField.Name.Value,
+					cast:        cast,
+				})
+			})
+		})
+	}
+	return aggregates
+}
+
+// visitSelectedFields calls visit for the fields of a selection set, following fragments
+func visitSelectedFields(info graphql.ResolveInfo, selSet *ast.SelectionSet, visit func(*ast.Field)) {
+	if selSet == nil {
+		return
+	}
+	for _, sel := range selSet.Selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			visit(s)
+		case *ast.InlineFragment:
+			visitSelectedFields(info, s.SelectionSet, visit)
+		case *ast.FragmentSpread:
+			if fragment, ok := info.Fragments[s.Name.Value].(*ast.FragmentDefinition); ok {
+				visitSelectedFields(info, fragment.SelectionSet, visit)
+			}
+		}
+	}
+}
+
+// buildGraphQLAggregateQuery builds the query of an aggregate field. Grouped columns are
+// selected as g0, g1, ... and aggregates as a0, a1, ...; groups are sorted by their columns.
+func buildGraphQLAggregateQuery(table database.TableInfo, filters []Filter, groupBy []string, aggregates []graphqlAggregate) (string, []interface{}) {
+	var selects, groupCols []string
+	for i, column := range groupBy {
+		quoted := quoteIdentifier(column)
+		selects = append(selects, fmt.Sprintf("%s AS \"g%d\"", quoted, i))
+		groupCols = append(groupCols, quoted)
+	}
+	selects = append(selects, `COUNT(*) AS "count"`)
+	for i, agg := range aggregates {
+		expr := agg.expression()
+		if agg.cast != "" {
+			expr += "::" + agg.cast
+		}
+		selects = append(selects, fmt.Sprintf("%s AS \"a%d\"", expr, i))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s",
+		strings.Join(selects, ", "),
+		quoteIdentifier(table.Schema),
+		quoteIdentifier(table.Name))
+
+	qb := NewQueryBuilder(table.Schema, table.Name).WithFilters(filters)
+	where, args := qb.buildWhereClause()
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if len(groupCols) > 0 {
+		query += " GROUP BY " + strings.Join(groupCols, ", ") + " ORDER BY " + strings.Join(groupCols, ", ")
+	}
+	return query, args
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func aggregateTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema:     "public",
+		Name:       "orders",
+		Type:       "table",
+		PrimaryKey: []string{"id"},
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "bigint"},
+			{Name: "region", DataType: "text"},
+			{Name: "quantity", DataType: "integer"},
+			{Name: "amount", DataType: "numeric"},
+			{Name: "paid", DataType: "boolean"},
+			{Name: "metadata", DataType: "json", IsNullable: true},
+			{Name: "created_at", DataType: "timestamp with time zone"},
+		},
+	}
+}
+
+func TestGenerateAggregateField(t *testing.T) {
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	table := aggregateTestTable()
+
+	field := g.generateAggregateField(table, g.generateFilterType(table))
+	assert.Equal(t, "[OrdersAggregate!]!", field.Type.String())
+	assert.Equal(t, "[OrdersColumn!]", field.Args["groupBy"].Type.String())
+
+	aggregate := field.Type.(*graphql.NonNull).OfType.(*graphql.List).OfType.(*graphql.NonNull).OfType.(*graphql.Object).Fields()
+	assert.ElementsMatch(t, []string{"count", "keys", "sum", "avg", "min", "max"}, fieldNamesOf(aggregate))
+	assert.Equal(t, "Int!", aggregate["count"].Type.String())
+
+	sum := aggregate["sum"].Type.(*graphql.Object).Fields()
+	assert.ElementsMatch(t, []string{"id", "quantity", "amount"}, fieldNamesOf(sum))
+	assert.Equal(t, "BigInt", sum["quantity"].Type.String(), "integer sums may exceed the column type")
+	assert.Equal(t, "Float", sum["amount"].Type.String())
+
+	avg := aggregate["avg"].Type.(*graphql.Object).Fields()
+	assert.Equal(t, "Float", avg["quantity"].Type.String())
+
+	max := aggregate["max"].Type.(*graphql.Object).Fields()
+	assert.ElementsMatch(t, []string{"id", "region", "quantity", "amount", "createdAt"}, fieldNamesOf(max))
+	assert.Equal(t, "Int", max["quantity"].Type.String())
+	assert.Equal(t, "DateTime", max["createdAt"].Type.String())
+
+	keys := aggregate["keys"].Type.(*graphql.Object).Fields()
+	assert.NotContains(t, keys, "metadata", "json columns can't be grouped")
+	assert.Equal(t, "String", keys["region"].Type.String())
+}
+
+func fieldNamesOf(fields graphql.FieldDefinitionMap) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestSelectedAggregates(t *testing.T) {
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	table := aggregateTestTable()
+
+	var selected []graphqlAggregate
+	field := g.generateAggregateField(table, g.generateFilterType(table))
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		selected = g.selectedAggregates(table, p.Info)
+		return []interface{}{}, nil
+	}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{"ordersAggregate": field}}),
+	})
+	require.NoError(t, err)
+
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: `
+		{ ordersAggregate(groupBy: [region]) { count keys { region } sum { amount } ...Totals } }
+		fragment Totals on OrdersAggregate { sum { amount quantity } max { createdAt } }`})
+	require.Empty(t, result.Errors)
+
+	assert.Equal(t, []graphqlAggregate{
+		{Aggregation: Aggregation{Function: AggSum, Column: "amount"}, field: "amount", cast: "float8"},
+		{Aggregation: Aggregation{Function: AggSum, Column: "quantity"}, field: "quantity", cast: "text"},
+		{Aggregation: Aggregation{Function: AggMax, Column: "created_at"}, field: "createdAt"},
+	}, selected)
+}
+
+func TestBuildGraphQLAggregateQuery(t *testing.T) {
+	table := aggregateTestTable()
+	aggregates := []graphqlAggregate{
+		{Aggregation: Aggregation{Function: AggSum, Column: "amount"}, field: "amount", cast: "float8"},
+		{Aggregation: Aggregation{Function: AggMax, Column: "created_at"}, field: "createdAt"},
+	}
+
+	sql, args := buildGraphQLAggregateQuery(table, nil, nil, nil)
+	assert.Equal(t, `SELECT COUNT(*) AS "count" FROM "public"."orders"`, sql)
+	assert.Empty(t, args)
+
+	sql, args = buildGraphQLAggregateQuery(table,
+		[]Filter{{Column: "paid", Operator: OpEqual, Value: true}},
+		[]string{"region", "quantity"},
+		aggregates)
+	assert.Equal(t, `SELECT "region" AS "g0", "quantity" AS "g1", COUNT(*) AS "count", `+
+		`SUM("amount")::float8 AS "a0", MAX("created_at") AS "a1" FROM "public"."orders" `+
+		`WHERE "paid" = $1 GROUP BY "region", "quantity" ORDER BY "region", "quantity"`, sql)
+	assert.Equal(t, []interface{}{true}, args)
+}
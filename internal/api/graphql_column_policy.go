@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// graphqlColumnPolicyRole returns the application role that column policies are matched against
+func graphqlColumnPolicyRole(ctx context.Context) string {
+	if rlsCtx, ok := ctx.Value(GraphQLRLSContextKey).(*RLSContext); ok && rlsCtx != nil && rlsCtx.Role != "" {
+		return rlsCtx.Role
+	}
+	return "anon"
+}
+
+// currentTableInfo returns the current metadata of a table so column policy and JSON Schema
+// changes apply without regenerating the GraphQL schema
+func (g *GraphQLSchemaGenerator) currentTableInfo(ctx context.Context, schema, name string) (database.TableInfo, error) {
+	if g.schemaCache == nil {
+		return database.TableInfo{Schema: schema, Name: name}, nil
+	}
+	table, exists, err := g.schemaCache.GetTable(ctx, schema, name)
+	if err != nil {
+		return database.TableInfo{}, fmt.Errorf("failed to load table metadata: %w", err)
+	}
+	if !exists {
+		return database.TableInfo{Schema: schema, Name: name}, nil
+	}
+	return *table, nil
+}
+
+// checkProtectedColumns rejects filters and ordering on columns that are hidden or masked for the caller
+func (g *GraphQLSchemaGenerator) checkProtectedColumns(ctx context.Context, table database.TableInfo, filters []Filter, orders []OrderBy) error {
+	if len(filters) == 0 && len(orders) == 0 {
+		return nil
+	}
+	current, err := g.currentTableInfo(ctx, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	params := &QueryParams{Filters: filters, Order: orders}
+	return checkProtectedColumns(current, graphqlColumnPolicyRole(ctx), params)
+}
+
+// applyColumnPolicies masks resolver results for the caller's role
+func (g *GraphQLSchemaGenerator) applyColumnPolicies(ctx context.Context, schema, name string, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	table, err := g.currentTableInfo(ctx, schema, name)
+	if err != nil {
+		return err
+	}
+	role := graphqlColumnPolicyRole(ctx)
+	for _, row := range rows {
+		table.ApplyColumnPolicies(role, row)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlPageInfoType is the Relay PageInfo type shared by all connections
+var graphqlPageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "PageInfo",
+	Description: "Information about a page of a connection",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether more edges follow the page",
+		},
+		"hasPreviousPage": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether more edges precede the page",
+		},
+		"startCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Cursor of the first edge of the page",
+		},
+		"endCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Cursor of the last edge of the page",
+		},
+	},
+})
+
+// graphqlConnection is a page of a table's records. The total count is only queried when selected.
+type graphqlConnection struct {
+	edges    []map[string]interface{}
+	pageInfo map[string]interface{}
+	count    func(ctx context.Context) (int, error)
+}
+
+// keysetCursor is the content of a connection cursor: the values of the ordering's columns
+// for an edge. The columns are kept to reject cursors from another ordering.
+type keysetCursor struct {
+	Columns []string      `json:"c"`
+	Values  []interface{} `json:"v"`
+}
+
+// generateConnectionField generates the Relay connection query of a table, e.g. postsConnection.
+// Cursors end in the primary key, so tables without one get no connection.
+func (g *GraphQLSchemaGenerator) generateConnectionField(table database.TableInfo, objType *graphql.Object, filterType, orderByType *graphql.InputObject) *graphql.Field {
+	if len(table.PrimaryKey) == 0 {
+		return nil
+	}
+	typeName := g.tableToTypeName(table.Schema, table.Name)
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        typeName + "Edge",
+		Description: fmt.Sprintf("A %s record in a connection", table.Name),
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Opaque cursor of the record, for after and before",
+			},
+			"node": &graphql.Field{
+				Type:        graphql.NewNonNull(objType),
+				Description: "The record",
+			},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        typeName + "Connection",
+		Description: fmt.Sprintf("A page of %s records", table.Name),
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))),
+				Description: "Records of the page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*graphqlConnection).edges, nil
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type:        graphql.NewNonNull(graphqlPageInfoType),
+				Description: "Information to fetch the next or previous page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*graphqlConnection).pageInfo, nil
+				},
+			},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of records matching the filter, across all pages",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*graphqlConnection).count(p.Context)
+				},
+			},
+		},
+	})
+
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(connectionType),
+		Description: fmt.Sprintf("Query %s records as a Relay connection", table.Name),
+		Args: graphql.FieldConfigArgument{
+			"filter": &graphql.ArgumentConfig{
+				Type:        filterType,
+				Description: "Filter conditions",
+			},
+			"orderBy": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(orderByType),
+				Description: "Sort order, followed by the primary key",
+			},
+			"first": &graphql.ArgumentConfig{
+				Type:        graphql.Int,
+				Description: "Number of records to return from the start",
+			},
+			"after": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Return records after this cursor",
+			},
+			"last": &graphql.ArgumentConfig{
+				Type:        graphql.Int,
+				Description: "Number of records to return from the end",
+			},
+			"before": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Return records before this cursor",
+			},
+		},
+		Resolve: g.makeConnectionResolver(table),
+	}
+}
+
+// makeConnectionResolver creates a resolver paging through a table with keyset cursors.
+// One more record than requested is fetched to tell whether another page follows.
+func (g *GraphQLSchemaGenerator) makeConnectionResolver(table database.TableInfo) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+
+		first, hasFirst := p.Args["first"].(int)
+		last, hasLast := p.Args["last"].(int)
+		if hasFirst && hasLast {
+			return nil, fmt.Errorf("first and last cannot be combined")
+		}
+		if first < 0 || last < 0 {
+			return nil, fmt.Errorf("first and last must not be negative")
+		}
+		after, _ := p.Args["after"].(string)
+		before, _ := p.Args["before"].(string)
+
+		var filters []Filter
+		if filter, ok := p.Args["filter"].(map[string]interface{}); ok && len(filter) > 0 {
+			filters = g.buildFiltersFromArgs(table, filter)
+		}
+		var orders []OrderBy
+		if orderBy, ok := p.Args["orderBy"].([]interface{}); ok && len(orderBy) > 0 {
+			orders = g.buildOrderFromArgs(table, orderBy)
+		}
+
+		// Hidden and masked columns can't be filtered or sorted on, nor be the primary key that
+		// cursors carry
+		current, err := g.currentTableInfo(ctx, table.Schema, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		role := graphqlColumnPolicyRole(ctx)
+		if err := checkKeysetColumns(table, current.ProtectedColumns(role)); err != nil {
+			return nil, err
+		}
+		if err := checkProtectedColumns(current, role, &QueryParams{Filters: filters, Order: orders}); err != nil {
+			return nil, err
+		}
+		orders = keysetOrder(table, orders)
+		notNull := keysetNotNullColumns(table)
+
+		qb := NewQueryBuilder(table.Schema, table.Name).WithFilters(filters)
+		if after != "" {
+			values, err := decodeKeysetCursor(after, orders)
+			if err != nil {
+				return nil, err
+			}
+			qb.WithKeyset(orders, values, notNull)
+		}
+		if before != "" {
+			values, err := decodeKeysetCursor(before, orders)
+			if err != nil {
+				return nil, err
+			}
+			qb.WithKeyset(reverseOrder(orders), values, notNull)
+		}
+
+		// The last records are the first ones in reverse
+		limit := -1
+		if hasLast {
+			qb.WithOrder(reverseOrder(orders))
+			limit = last
+		} else {
+			qb.WithOrder(orders)
+			if hasFirst {
+				limit = first
+			}
+		}
+		if limit >= 0 {
+			qb.WithLimit(limit + 1)
+		}
+
+		sql, args := qb.BuildSelect()
+		rows, err := g.queryWithRLS(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		hasMore := limit >= 0 && len(rows) > limit
+		if hasMore {
+			rows = rows[:limit]
+		}
+		if hasLast {
+			for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+				rows[i], rows[j] = rows[j], rows[i]
+			}
+		}
+
+		// Cursors are taken before masking, which may change the values
+		edges := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			edges[i] = map[string]interface{}{"cursor": encodeKeysetCursor(orders, row)}
+		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, rows); err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			edges[i]["node"] = row
+		}
+
+		// Like most servers, the page is assumed to have neighbours on the side of its cursor
+		pageInfo := map[string]interface{}{
+			"hasNextPage":     hasMore,
+			"hasPreviousPage": after != "",
+			"startCursor":     nil,
+			"endCursor":       nil,
+		}
+		if hasLast {
+			pageInfo["hasNextPage"] = before != ""
+			pageInfo["hasPreviousPage"] = hasMore
+		}
+		if len(edges) > 0 {
+			pageInfo["startCursor"] = edges[0]["cursor"]
+			pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+		}
+
+		return &graphqlConnection{
+			edges:    edges,
+			pageInfo: pageInfo,
+			count: func(ctx context.Context) (int, error) {
+				sql, args := NewQueryBuilder(table.Schema, table.Name).WithFilters(filters).BuildCount()
+				results, err := g.queryWithRLS(ctx, sql, args...)
+				if err != nil {
+					return 0, err
+				}
+				if len(results) == 0 {
+					return 0, nil
+				}
+				count, _ := results[0]["count"].(int64)
+				return int(count), nil
+			},
+		}, nil
+	}
+}
+
+// keysetOrder completes an ordering with the primary key columns it lacks, making it total
+func keysetOrder(table database.TableInfo, orders []OrderBy) []OrderBy {
+	ordered := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		ordered[order.Column] = true
+	}
+	result := append([]OrderBy{}, orders...)
+	for _, pkCol := range table.PrimaryKey {
+		if !ordered[pkCol] {
+			result = append(result, OrderBy{Column: pkCol})
+		}
+	}
+	return result
+}
+
+// checkKeysetColumns rejects connections of a table whose primary key is protected for the
+// caller: cursors would expose its values, and cursor conditions compare against them
+func checkKeysetColumns(table database.TableInfo, protected map[string]bool) error {
+	for _, pkCol := range table.PrimaryKey {
+		if protected[pkCol] {
+			return fmt.Errorf("connection of %s is not available: primary key column '%s' is protected", table.Name, pkCol)
+		}
+	}
+	return nil
+}
+
+// keysetNotNullColumns returns the columns of a table that can't hold NULLs
+func keysetNotNullColumns(table database.TableInfo) map[string]bool {
+	notNull := make(map[string]bool, len(table.Columns))
+	for _, col := range table.Columns {
+		if !col.IsNullable {
+			notNull[col.Name] = true
+		}
+	}
+	for _, pkCol := range table.PrimaryKey {
+		notNull[pkCol] = true
+	}
+	return notNull
+}
+
+// reverseOrder returns the opposite of an ordering, NULLs included
+func reverseOrder(orders []OrderBy) []OrderBy {
+	reversed := make([]OrderBy, len(orders))
+	for i, order := range orders {
+		order.Desc = !order.Desc
+		switch order.Nulls {
+		case "first":
+			order.Nulls = "last"
+		case "last":
+			order.Nulls = "first"
+		}
+		reversed[i] = order
+	}
+	return reversed
+}
+
+// encodeKeysetCursor creates the cursor of a row in an ordering
+func encodeKeysetCursor(orders []OrderBy, row map[string]interface{}) string {
+	cursor := keysetCursor{
+		Columns: make([]string, len(orders)),
+		Values:  make([]interface{}, len(orders)),
+	}
+	for i, order := range orders {
+		cursor.Columns[i] = order.Column
+		cursor.Values[i] = keysetCursorValue(row[order.Column])
+	}
+	jsonBytes, _ := json.Marshal(cursor)
+	return base64.URLEncoding.EncodeToString(jsonBytes)
+}
+
+// keysetCursorValue converts a column value to one that survives a JSON round trip
+func keysetCursorValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case [16]byte:
+		return uuid.UUID(v).String()
+	case uuid.UUID:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// decodeKeysetCursor decodes a cursor into query arguments for an ordering. Numbers are
+// returned as text so the database parses them with the column's type, keeping precision.
+func decodeKeysetCursor(cursor string, orders []OrderBy) ([]interface{}, error) {
+	jsonBytes, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var data keysetCursor
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid cursor format: %w", err)
+	}
+
+	if len(data.Columns) != len(orders) || len(data.Values) != len(orders) {
+		return nil, fmt.Errorf("cursor does not match the requested ordering")
+	}
+	for i, order := range orders {
+		if data.Columns[i] != order.Column {
+			return nil, fmt.Errorf("cursor does not match the requested ordering")
+		}
+		if n, ok := data.Values[i].(json.Number); ok {
+			data.Values[i] = n.String()
+		}
+	}
+	return data.Values, nil
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func connectionTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema:     "public",
+		Name:       "posts",
+		Type:       "table",
+		PrimaryKey: []string{"id"},
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "uuid"},
+			{Name: "title", DataType: "text", IsNullable: true},
+			{Name: "view_count", DataType: "integer"},
+			{Name: "created_at", DataType: "timestamp with time zone"},
+		},
+	}
+}
+
+func TestGenerateConnectionField(t *testing.T) {
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	table := connectionTestTable()
+	objType := graphql.NewObject(graphql.ObjectConfig{Name: "Posts", Fields: g.generateTableFields(table)})
+
+	field := g.generateConnectionField(table, objType, g.generateFilterType(table), g.generateOrderByType(table))
+	require.NotNil(t, field)
+	assert.Equal(t, "PostsConnection!", field.Type.String())
+	for _, arg := range []string{"filter", "orderBy", "first", "after", "last", "before"} {
+		assert.Contains(t, field.Args, arg)
+	}
+
+	connection := field.Type.(*graphql.NonNull).OfType.(*graphql.Object).Fields()
+	assert.Equal(t, "[PostsEdge!]!", connection["edges"].Type.String())
+	assert.Equal(t, "PageInfo!", connection["pageInfo"].Type.String())
+	assert.Equal(t, "Int!", connection["totalCount"].Type.String())
+
+	edge := connection["edges"].Type.(*graphql.NonNull).OfType.(*graphql.List).OfType.(*graphql.NonNull).OfType.(*graphql.Object).Fields()
+	assert.Equal(t, "String!", edge["cursor"].Type.String())
+	assert.Equal(t, "Posts!", edge["node"].Type.String())
+
+	// Without a primary key records can't be told apart by cursor
+	table.PrimaryKey = nil
+	assert.Nil(t, g.generateConnectionField(table, objType, g.generateFilterType(table), g.generateOrderByType(table)))
+}
+
+func TestKeysetOrder(t *testing.T) {
+	table := connectionTestTable()
+
+	assert.Equal(t, []OrderBy{{Column: "id"}}, keysetOrder(table, nil))
+	assert.Equal(t,
+		[]OrderBy{{Column: "created_at", Desc: true, Nulls: "first"}, {Column: "id"}},
+		keysetOrder(table, []OrderBy{{Column: "created_at", Desc: true, Nulls: "first"}}))
+	assert.Equal(t,
+		[]OrderBy{{Column: "id", Desc: true}},
+		keysetOrder(table, []OrderBy{{Column: "id", Desc: true}}), "the primary key isn't added twice")
+
+	assert.Equal(t,
+		[]OrderBy{{Column: "created_at", Nulls: "last"}, {Column: "id", Desc: true}},
+		reverseOrder([]OrderBy{{Column: "created_at", Desc: true, Nulls: "first"}, {Column: "id"}}))
+}
+
+func TestCheckKeysetColumns(t *testing.T) {
+	table := connectionTestTable()
+	table.ColumnPolicies = []database.ColumnPolicy{
+		{Column: "id", Role: "anon", Mode: database.ColumnPolicyMask, MaskVisible: 4},
+		{Column: "title", Role: "authenticated", Mode: database.ColumnPolicyHide},
+	}
+
+	err := checkKeysetColumns(table, table.ProtectedColumns("anon"))
+	assert.EqualError(t, err, "connection of posts is not available: primary key column 'id' is protected")
+	assert.NoError(t, checkKeysetColumns(table, table.ProtectedColumns("authenticated")))
+}
+
+func TestKeysetCursor(t *testing.T) {
+	orders := []OrderBy{{Column: "created_at", Desc: true}, {Column: "view_count"}, {Column: "id"}}
+	id := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	row := map[string]interface{}{
+		"id":         [16]byte(id),
+		"title":      "Hello",
+		"view_count": int32(12),
+		"created_at": time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	cursor := encodeKeysetCursor(orders, row)
+	values, err := decodeKeysetCursor(cursor, orders)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"2025-01-02T03:04:05Z", "12", id.String()}, values)
+
+	_, err = decodeKeysetCursor(cursor, []OrderBy{{Column: "title"}, {Column: "view_count"}, {Column: "id"}})
+	assert.EqualError(t, err, "cursor does not match the requested ordering")
+
+	_, err = decodeKeysetCursor(cursor, orders[1:])
+	assert.EqualError(t, err, "cursor does not match the requested ordering")
+
+	_, err = decodeKeysetCursor("not a cursor", orders)
+	assert.Error(t, err)
+}
+
+func TestConnectionResolver_Arguments(t *testing.T) {
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	resolve := g.makeConnectionResolver(connectionTestTable())
+
+	_, err := resolve(graphql.ResolveParams{Context: context.Background(), Args: map[string]interface{}{"first": 10, "last": 10}})
+	assert.EqualError(t, err, "first and last cannot be combined")
+
+	_, err = resolve(graphql.ResolveParams{Context: context.Background(), Args: map[string]interface{}{"first": -1}})
+	assert.EqualError(t, err, "first and last must not be negative")
+
+	_, err = resolve(graphql.ResolveParams{Context: context.Background(), Args: map[string]interface{}{"after": "bm90IGpzb24="}})
+	assert.ErrorContains(t, err, "invalid cursor format")
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlLoadersContextKey is used to store the relation loaders of an operation in its context
+const graphqlLoadersContextKey graphqlContextKey = "graphql_loaders"
+
+// graphqlRelation describes the rows of a table whose column matches the key of a parent record
+type graphqlRelation struct {
+	schema  string
+	table   string
+	column  string
+	filters []Filter  // Extra conditions from the field arguments
+	orders  []OrderBy // Order of the related rows of each key
+}
+
+// graphqlRelationFetchFn loads the rows of a relation for a set of keys, grouped by key
+type graphqlRelationFetchFn func(ctx context.Context, rel graphqlRelation, keys []interface{}) (map[string][]map[string]interface{}, error)
+
+// graphqlLoaders batches and caches the relation lookups of a GraphQL operation.
+// Relation resolvers register their key and return a thunk; graphql-go runs the thunks
+// of a level only after all its fields are resolved, so the first thunk loads the keys
+// of every sibling with a single query.
+type graphqlLoaders struct {
+	mu      sync.Mutex
+	loaders map[string]*graphqlRelationLoader
+}
+
+// graphqlRelationLoader holds the pending keys and loaded rows of one relation
+type graphqlRelationLoader struct {
+	rel     graphqlRelation
+	fetch   graphqlRelationFetchFn
+	pending []interface{}
+	queued  map[string]bool
+	rows    map[string][]map[string]interface{}
+	errs    map[string]error
+}
+
+// newGraphQLLoaders creates an empty loader registry
+func newGraphQLLoaders() *graphqlLoaders {
+	return &graphqlLoaders{loaders: make(map[string]*graphqlRelationLoader)}
+}
+
+// withGraphQLLoaders returns a context holding new relation loaders for an operation
+func withGraphQLLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, graphqlLoadersContextKey, newGraphQLLoaders())
+}
+
+// graphqlLoadersFromContext returns the relation loaders of an operation. Without any,
+// lookups aren't shared and each resolver gets loaders of its own.
+func graphqlLoadersFromContext(ctx context.Context) *graphqlLoaders {
+	if loaders, ok := ctx.Value(graphqlLoadersContextKey).(*graphqlLoaders); ok && loaders != nil {
+		return loaders
+	}
+	return newGraphQLLoaders()
+}
+
+// reset drops the cached rows, e.g. after a mutation or for the next subscription event.
+// Keys already registered are still loaded by their thunks.
+func (l *graphqlLoaders) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaders = make(map[string]*graphqlRelationLoader)
+}
+
+// resetGraphQLLoaders drops the relation lookups cached by an operation, as a write may
+// have changed them
+func resetGraphQLLoaders(ctx context.Context) {
+	if loaders, ok := ctx.Value(graphqlLoadersContextKey).(*graphqlLoaders); ok && loaders != nil {
+		loaders.reset()
+	}
+}
+
+// load registers a key of a relation and returns a thunk resolving to its rows. Loaders
+// are shared by relations with the same cacheKey.
+func (l *graphqlLoaders) load(ctx context.Context, cacheKey string, rel graphqlRelation, key interface{}, fetch graphqlRelationFetchFn) func() ([]map[string]interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	loader, ok := l.loaders[cacheKey]
+	if !ok {
+		loader = &graphqlRelationLoader{
+			rel:    rel,
+			fetch:  fetch,
+			queued: make(map[string]bool),
+			rows:   make(map[string][]map[string]interface{}),
+			errs:   make(map[string]error),
+		}
+		l.loaders[cacheKey] = loader
+	}
+
+	k := relationKey(key)
+	if _, loaded := loader.rows[k]; !loaded && loader.errs[k] == nil && !loader.queued[k] {
+		loader.queued[k] = true
+		loader.pending = append(loader.pending, key)
+	}
+
+	return func() ([]map[string]interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if loader.queued[k] {
+			loader.flush(ctx)
+		}
+		if err := loader.errs[k]; err != nil {
+			return nil, err
+		}
+		return loader.rows[k], nil
+	}
+}
+
+// flush loads all pending keys with one query
+func (rl *graphqlRelationLoader) flush(ctx context.Context) {
+	keys := rl.pending
+	rl.pending = nil
+
+	rows, err := rl.fetch(ctx, rl.rel, keys)
+	for _, key := range keys {
+		k := relationKey(key)
+		delete(rl.queued, k)
+		if err != nil {
+			rl.errs[k] = err
+			continue
+		}
+		rl.rows[k] = rows[k]
+	}
+}
+
+// relationKey returns the value a relation key is matched by. Foreign key values of
+// subscription events come from JSON, so UUIDs are compared in their text form.
+func relationKey(value interface{}) string {
+	if u, ok := value.([16]byte); ok {
+		return uuid.UUID(u).String()
+	}
+	return fmt.Sprint(value)
+}
+
+// buildRelationQuery builds the query loading the rows of a relation for a set of keys
+func buildRelationQuery(rel graphqlRelation, keys []interface{}) (string, []interface{}) {
+	filters := append([]Filter{}, rel.filters...)
+	filters = append(filters, Filter{
+		Column:   rel.column,
+		Operator: OpIn,
+		Value:    keys,
+	})
+
+	qb := NewQueryBuilder(rel.schema, rel.table)
+	qb.WithFilters(filters)
+	if len(rel.orders) > 0 {
+		qb.WithOrder(rel.orders)
+	}
+	return qb.BuildSelect()
+}
+
+// fetchRelation loads the rows of a relation with RLS, groups them by key and masks
+// them for the caller's role. Rows are grouped first as the key column may be hidden.
+func (g *GraphQLSchemaGenerator) fetchRelation(ctx context.Context, rel graphqlRelation, keys []interface{}) (map[string][]map[string]interface{}, error) {
+	sql, args := buildRelationQuery(rel, keys)
+
+	// Execute with RLS - this is critical for security
+	// Relation traversals must respect RLS policies
+	results, err := g.queryWithRLS(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	grouped := make(map[string][]map[string]interface{})
+	for _, row := range results {
+		k := relationKey(row[rel.column])
+		grouped[k] = append(grouped[k], row)
+	}
+
+	if err := g.applyColumnPolicies(ctx, rel.schema, rel.table, results); err != nil {
+		return nil, err
+	}
+	return grouped, nil
+}
+
+// makeReverseRelationResolver creates a resolver for the records of another table whose
+// foreign key references the source record. Records are ordered by orderBy, then by the
+// primary key of the referencing table.
+func (g *GraphQLSchemaGenerator) makeReverseRelationResolver(refTable database.TableInfo, fk database.ForeignKey) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		source, ok := p.Source.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		key := source[fk.ReferencedColumn]
+		if key == nil {
+			return []map[string]interface{}{}, nil
+		}
+
+		ctx := p.Context
+
+		var filters []Filter
+		if filter, ok := p.Args["filter"].(map[string]interface{}); ok && len(filter) > 0 {
+			filters = g.buildFiltersFromArgs(refTable, filter)
+		}
+
+		var orders []OrderBy
+		if orderBy, ok := p.Args["orderBy"].([]interface{}); ok && len(orderBy) > 0 {
+			orders = g.buildOrderFromArgs(refTable, orderBy)
+		}
+
+		// Hidden and masked columns can't be filtered or sorted on
+		if err := g.checkProtectedColumns(ctx, refTable, filters, orders); err != nil {
+			return nil, err
+		}
+
+		for _, pkCol := range refTable.PrimaryKey {
+			orders = append(orders, OrderBy{Column: pkCol})
+		}
+
+		// Fields with the same arguments share a loader
+		argsKey, err := json.Marshal(p.Args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		cacheKey := fmt.Sprintf("%s.%s.%s:%s", refTable.Schema, refTable.Name, fk.ColumnName, argsKey)
+
+		rel := graphqlRelation{
+			schema:  refTable.Schema,
+			table:   refTable.Name,
+			column:  fk.ColumnName,
+			filters: filters,
+			orders:  orders,
+		}
+		thunk := graphqlLoadersFromContext(ctx).load(ctx, cacheKey, rel, key, g.fetchRelation)
+
+		return func() (interface{}, error) {
+			rows, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			if rows == nil {
+				return []map[string]interface{}{}, nil
+			}
+			return rows, nil
+		}, nil
+	}
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFetch returns a fetch function serving rows keyed by their "id" column and recording
+// the keys of each call
+func countingFetch(calls *[][]interface{}) graphqlRelationFetchFn {
+	return func(ctx context.Context, rel graphqlRelation, keys []interface{}) (map[string][]map[string]interface{}, error) {
+		*calls = append(*calls, keys)
+		grouped := make(map[string][]map[string]interface{})
+		for _, key := range keys {
+			if key == 404 {
+				continue
+			}
+			grouped[relationKey(key)] = []map[string]interface{}{{"id": key, "name": fmt.Sprintf("customer %v", key)}}
+		}
+		return grouped, nil
+	}
+}
+
+func TestGraphQLLoaders_BatchesAndCaches(t *testing.T) {
+	var calls [][]interface{}
+	fetch := countingFetch(&calls)
+	loaders := newGraphQLLoaders()
+	ctx := context.Background()
+	rel := graphqlRelation{schema: "public", table: "customers", column: "id"}
+
+	first := loaders.load(ctx, "customers", rel, 1, fetch)
+	second := loaders.load(ctx, "customers", rel, 2, fetch)
+	duplicate := loaders.load(ctx, "customers", rel, 1, fetch)
+	missing := loaders.load(ctx, "customers", rel, 404, fetch)
+
+	rows, err := second()
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "customer 2", rows[0]["name"])
+
+	rows, err = first()
+	require.NoError(t, err)
+	assert.Equal(t, "customer 1", rows[0]["name"])
+	rows, err = duplicate()
+	require.NoError(t, err)
+	assert.Equal(t, "customer 1", rows[0]["name"])
+	rows, err = missing()
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	require.Len(t, calls, 1, "all registered keys are loaded by one query")
+	assert.Equal(t, []interface{}{1, 2, 404}, calls[0])
+
+	// Loaded keys are served from the cache
+	rows, err = loaders.load(ctx, "customers", rel, 2, fetch)()
+	require.NoError(t, err)
+	assert.Equal(t, "customer 2", rows[0]["name"])
+	assert.Len(t, calls, 1)
+
+	// After a reset they are loaded again
+	loaders.reset()
+	_, err = loaders.load(ctx, "customers", rel, 2, fetch)()
+	require.NoError(t, err)
+	assert.Len(t, calls, 2)
+}
+
+func TestGraphQLLoaders_Error(t *testing.T) {
+	fetchErr := errors.New("permission denied")
+	calls := 0
+	fetch := func(ctx context.Context, rel graphqlRelation, keys []interface{}) (map[string][]map[string]interface{}, error) {
+		calls++
+		return nil, fetchErr
+	}
+	loaders := newGraphQLLoaders()
+	rel := graphqlRelation{schema: "public", table: "customers", column: "id"}
+
+	first := loaders.load(context.Background(), "customers", rel, 1, fetch)
+	second := loaders.load(context.Background(), "customers", rel, 2, fetch)
+
+	_, err := first()
+	assert.ErrorIs(t, err, fetchErr)
+	_, err = second()
+	assert.ErrorIs(t, err, fetchErr)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGraphQLLoaders_SeparateRelations(t *testing.T) {
+	var calls [][]interface{}
+	fetch := countingFetch(&calls)
+	loaders := newGraphQLLoaders()
+	ctx := context.Background()
+
+	customers := loaders.load(ctx, "customers", graphqlRelation{table: "customers", column: "id"}, 1, fetch)
+	products := loaders.load(ctx, "products", graphqlRelation{table: "products", column: "id"}, 1, fetch)
+
+	_, err := customers()
+	require.NoError(t, err)
+	_, err = products()
+	require.NoError(t, err)
+	assert.Len(t, calls, 2)
+}
+
+func TestGraphQLLoadersFromContext(t *testing.T) {
+	ctx := withGraphQLLoaders(context.Background())
+	assert.Same(t, graphqlLoadersFromContext(ctx), graphqlLoadersFromContext(ctx))
+
+	// Without loaders in the context, lookups aren't shared
+	assert.NotSame(t, graphqlLoadersFromContext(context.Background()), graphqlLoadersFromContext(context.Background()))
+}
+
+func TestGraphQLLoaders_BatchesListFields(t *testing.T) {
+	var calls [][]interface{}
+	fetch := countingFetch(&calls)
+	rel := graphqlRelation{schema: "public", table: "customers", column: "id"}
+
+	customerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Customers",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Orders",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.Int},
+			"customer": &graphql.Field{
+				Type: customerType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					source := p.Source.(map[string]interface{})
+					thunk := graphqlLoadersFromContext(p.Context).load(p.Context, "customers", rel, source["customer_id"], fetch)
+					return func() (interface{}, error) {
+						rows, err := thunk()
+						if err != nil || len(rows) == 0 {
+							return nil, err
+						}
+						return rows[0], nil
+					}, nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{"orders": &graphql.Field{
+				Type: graphql.NewList(orderType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var orders []map[string]interface{}
+					for i := 1; i <= 100; i++ {
+						orders = append(orders, map[string]interface{}{"id": i, "customer_id": i % 3})
+					}
+					return orders, nil
+				},
+			}},
+		}),
+	})
+	require.NoError(t, err)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ orders { id customer { name } } }`,
+		Context:       withGraphQLLoaders(context.Background()),
+	})
+	require.Empty(t, result.Errors)
+
+	orders := result.Data.(map[string]interface{})["orders"].([]interface{})
+	require.Len(t, orders, 100)
+	assert.Equal(t, map[string]interface{}{"name": "customer 2"}, orders[1].(map[string]interface{})["customer"])
+	assert.Equal(t, map[string]interface{}{"name": "customer 0"}, orders[2].(map[string]interface{})["customer"])
+
+	require.Len(t, calls, 1, "100 orders resolve their customers with one query")
+	assert.ElementsMatch(t, []interface{}{1, 2, 0}, calls[0])
+}
+
+func TestRelationKey(t *testing.T) {
+	id := [16]byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00}
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", relationKey(id))
+	assert.Equal(t, relationKey("550e8400-e29b-41d4-a716-446655440000"), relationKey(id))
+	assert.Equal(t, relationKey(int32(3)), relationKey(float64(3)))
+}
+
+func TestBuildRelationQuery(t *testing.T) {
+	rel := graphqlRelation{
+		schema:  "public",
+		table:   "orders",
+		column:  "customer_id",
+		filters: []Filter{{Column: "status", Operator: OpEqual, Value: "paid"}},
+		orders:  []OrderBy{{Column: "id"}},
+	}
+
+	sql, args := buildRelationQuery(rel, []interface{}{1, 2})
+	assert.Contains(t, sql, `FROM "public"."orders"`)
+	assert.Contains(t, sql, `"status" = $1 AND "customer_id" = ANY($2)`)
+	assert.Contains(t, sql, `ORDER BY "id"`)
+	assert.Equal(t, []interface{}{"paid", []interface{}{1, 2}}, args)
+}
+
+func TestGenerateReverseRelationFields(t *testing.T) {
+	customers := database.TableInfo{
+		Schema:     "public",
+		Name:       "customers",
+		Columns:    []database.ColumnInfo{{Name: "id", DataType: "integer"}, {Name: "invoices", DataType: "jsonb"}},
+		PrimaryKey: []string{"id"},
+	}
+	orders := database.TableInfo{
+		Schema:     "public",
+		Name:       "orders",
+		Columns:    []database.ColumnInfo{{Name: "id", DataType: "integer"}, {Name: "customer_id", DataType: "integer"}},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []database.ForeignKey{
+			{ColumnName: "customer_id", ReferencedTable: "customers", ReferencedColumn: "id"},
+		},
+	}
+	transfers := database.TableInfo{
+		Schema:     "public",
+		Name:       "transfers",
+		Columns:    []database.ColumnInfo{{Name: "id", DataType: "integer"}},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []database.ForeignKey{
+			{ColumnName: "sender_id", ReferencedTable: "customers", ReferencedColumn: "id"},
+			{ColumnName: "recipient_id", ReferencedTable: "customers", ReferencedColumn: "id"},
+		},
+	}
+	invoices := database.TableInfo{
+		Schema:     "public",
+		Name:       "invoices",
+		Columns:    []database.ColumnInfo{{Name: "id", DataType: "integer"}},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []database.ForeignKey{
+			{ColumnName: "customer_id", ReferencedTable: "customers", ReferencedColumn: "id"},
+		},
+	}
+	tables := []database.TableInfo{customers, orders, transfers, invoices}
+
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	for _, table := range tables {
+		typeName := g.tableToTypeName(table.Schema, table.Name)
+		g.objectTypes[typeName] = graphql.NewObject(graphql.ObjectConfig{Name: typeName, Fields: graphql.Fields{"id": &graphql.Field{Type: graphql.Int}}})
+		g.filterTypes[typeName+"Filter"] = g.generateFilterType(table)
+		g.orderByTypes[typeName+"OrderBy"] = g.generateOrderByType(table)
+	}
+
+	fields := g.generateReverseRelationFields(customers, tables)
+
+	assert.Contains(t, fields, "orders")
+	assert.Contains(t, fields, "transfersBySenderId")
+	assert.Contains(t, fields, "transfersByRecipientId")
+	assert.NotContains(t, fields, "invoices", "the invoices column takes precedence")
+	assert.Len(t, fields, 3)
+
+	ordersField := fields["orders"]
+	assert.Equal(t, "[Orders]", ordersField.Type.String())
+	assert.Contains(t, ordersField.Args, "filter")
+	assert.Contains(t, ordersField.Args, "orderBy")
+
+	assert.Empty(t, g.generateReverseRelationFields(orders, tables))
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/graphql-go/graphql"
+)
+
+// federationLink is the Federation 2 schema link emitted at the top of the subgraph SDL
+const federationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@shareable"])`
+
+// federationShareableTypes are value types other subgraphs may define as well
+var federationShareableTypes = map[string]bool{
+	"PageInfo": true,
+}
+
+// federationAnyScalar is the _Any scalar of entity representations: a JSON object holding
+// __typename and the key fields of an entity
+var federationAnyScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:         "_Any",
+	Description:  "Representation of an entity: its __typename and key fields",
+	Serialize:    JSONScalar.Serialize,
+	ParseValue:   JSONScalar.ParseValue,
+	ParseLiteral: JSONScalar.ParseLiteral,
+})
+
+// federationEntity is a table exposed as a federation entity, keyed by its primary key
+type federationEntity struct {
+	table   database.TableInfo
+	objType *graphql.Object
+	keys    []string // GraphQL field names of the primary key columns
+}
+
+// federationEntities returns the tables that are federation entities, by type name.
+// Any table or view with a primary key can be referenced from other subgraphs.
+func (g *GraphQLSchemaGenerator) federationEntities(tables []database.TableInfo) map[string]*federationEntity {
+	entities := make(map[string]*federationEntity)
+	for _, table := range tables {
+		if len(table.PrimaryKey) == 0 {
+			continue
+		}
+		typeName := g.tableToTypeName(table.Schema, table.Name)
+		entity := &federationEntity{table: table, objType: g.objectTypes[typeName]}
+		for _, pkCol := range table.PrimaryKey {
+			entity.keys = append(entity.keys, g.columnToFieldName(pkCol))
+		}
+		entities[typeName] = entity
+	}
+	return entities
+}
+
+// generateFederationFields generates the _service and _entities query fields of an Apollo
+// Federation subgraph. _entities is left out when no table has a primary key.
+func (g *GraphQLSchemaGenerator) generateFederationFields(entities map[string]*federationEntity) graphql.Fields {
+	keys := make(map[string][]string, len(entities))
+	for typeName, entity := range entities {
+		keys[typeName] = entity.keys
+	}
+
+	fields := graphql.Fields{
+		"_service": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name: "_Service",
+				Fields: graphql.Fields{
+					"sdl": &graphql.Field{Type: graphql.String},
+				},
+			})),
+			Description: "Federation subgraph schema",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"sdl": printFederationSDL(p.Info.Schema, keys)}, nil
+			},
+		},
+	}
+	if len(entities) == 0 {
+		return fields
+	}
+
+	typeNames := make([]string, 0, len(entities))
+	for typeName := range entities {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+	types := make([]*graphql.Object, len(typeNames))
+	for i, typeName := range typeNames {
+		types[i] = entities[typeName].objType
+	}
+
+	entityType := graphql.NewUnion(graphql.UnionConfig{
+		Name:  "_Entity",
+		Types: types,
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			if row, ok := p.Value.(map[string]interface{}); ok {
+				if typeName, ok := row["__typename"].(string); ok && entities[typeName] != nil {
+					return entities[typeName].objType
+				}
+			}
+			return nil
+		},
+	})
+
+	fields["_entities"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(entityType)),
+		Description: "Federation entities by key, for the gateway",
+		Args: graphql.FieldConfigArgument{
+			"representations": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(federationAnyScalar))),
+			},
+		},
+		Resolve: g.makeEntitiesResolver(entities),
+	}
+	return fields
+}
+
+// makeEntitiesResolver creates the _entities resolver. Entities with a single-column key
+// are loaded through the relation loaders, one query per type; others one by one.
+// Entities that don't exist or aren't visible to the caller resolve to null.
+func (g *GraphQLSchemaGenerator) makeEntitiesResolver(entities map[string]*federationEntity) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+		representations, _ := p.Args["representations"].([]interface{})
+		loaders := graphqlLoadersFromContext(ctx)
+
+		lookups := make([]func() (map[string]interface{}, error), len(representations))
+		for i, representation := range representations {
+			fields, ok := representation.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("representation %d is not an object", i)
+			}
+			typeName, _ := fields["__typename"].(string)
+			entity := entities[typeName]
+			if entity == nil {
+				return nil, fmt.Errorf("unknown entity type %q", typeName)
+			}
+			filters, err := entity.keyFilters(fields)
+			if err != nil {
+				return nil, fmt.Errorf("representation %d: %w", i, err)
+			}
+			table := entity.table
+
+			if len(filters) == 1 {
+				rel := graphqlRelation{schema: table.Schema, table: table.Name, column: filters[0].Column}
+				cacheKey := fmt.Sprintf("%s.%s.%s", rel.schema, rel.table, rel.column)
+				thunk := loaders.load(ctx, cacheKey, rel, filters[0].Value, g.fetchRelation)
+				lookups[i] = func() (map[string]interface{}, error) {
+					rows, err := thunk()
+					if err != nil || len(rows) == 0 {
+						return nil, err
+					}
+					return rows[0], nil
+				}
+				continue
+			}
+
+			lookups[i] = func() (map[string]interface{}, error) {
+				sql, args := NewQueryBuilder(table.Schema, table.Name).WithFilters(filters).WithLimit(1).BuildSelect()
+				rows, err := g.queryWithRLS(ctx, sql, args...)
+				if err != nil || len(rows) == 0 {
+					return nil, err
+				}
+				if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, rows); err != nil {
+					return nil, err
+				}
+				return rows[0], nil
+			}
+		}
+
+		return func() (interface{}, error) {
+			results := make([]interface{}, len(lookups))
+			for i, lookup := range lookups {
+				row, err := lookup()
+				if err != nil {
+					return nil, err
+				}
+				if row == nil {
+					continue
+				}
+				// Rows may be shared with other fields through the loaders, so they're copied
+				entity := make(map[string]interface{}, len(row)+1)
+				for k, v := range row {
+					entity[k] = v
+				}
+				entity["__typename"] = representations[i].(map[string]interface{})["__typename"]
+				results[i] = entity
+			}
+			return results, nil
+		}, nil
+	}
+}
+
+// keyFilters returns the primary key conditions of a representation
+func (e *federationEntity) keyFilters(fields map[string]interface{}) ([]Filter, error) {
+	filters := make([]Filter, len(e.keys))
+	for i, key := range e.keys {
+		value, ok := fields[key]
+		if !ok || value == nil {
+			return nil, fmt.Errorf("missing key field %q", key)
+		}
+		// Whole JSON numbers are compared as integers, so they match integer keys
+		if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			value = int64(f)
+		}
+		filters[i] = Filter{Column: e.table.PrimaryKey[i], Operator: OpEqual, Value: value}
+	}
+	return filters, nil
+}
+
+// printFederationSDL prints a schema in SDL with the @key directives of its entities, without
+// the federation fields and types, as gateways expect from _service
+func printFederationSDL(schema graphql.Schema, keys map[string][]string) string {
+	typeMap := schema.TypeMap()
+	names := make([]string, 0, len(typeMap))
+	for name := range typeMap {
+		if strings.HasPrefix(name, "__") || isSDLBuiltin(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(federationLink)
+	sb.WriteString("\n")
+	for _, name := range names {
+		sb.WriteString("\n")
+		printSDLType(&sb, typeMap[name], keys[name])
+	}
+	return sb.String()
+}
+
+// isSDLBuiltin tells whether a type is a built-in scalar or a federation type, left out of the SDL
+func isSDLBuiltin(name string) bool {
+	switch name {
+	case "String", "Int", "Float", "Boolean", "ID", "_Any", "_Entity", "_Service":
+		return true
+	}
+	return false
+}
+
+// printSDLType prints the definition of a named type
+func printSDLType(sb *strings.Builder, t graphql.Type, keys []string) {
+	printSDLDescription(sb, t.Description(), "")
+
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		fmt.Fprintf(sb, "scalar %s\n", t.Name())
+
+	case *graphql.Enum:
+		fmt.Fprintf(sb, "enum %s {\n", t.Name())
+		for _, value := range t.Values() {
+			printSDLDescription(sb, value.Description, "  ")
+			fmt.Fprintf(sb, "  %s%s\n", value.Name, sdlDeprecated(value.DeprecationReason))
+		}
+		sb.WriteString("}\n")
+
+	case *graphql.Union:
+		types := make([]string, len(t.Types()))
+		for i, member := range t.Types() {
+			types[i] = member.Name()
+		}
+		fmt.Fprintf(sb, "union %s = %s\n", t.Name(), strings.Join(types, " | "))
+
+	case *graphql.InputObject:
+		fmt.Fprintf(sb, "input %s {\n", t.Name())
+		fields := t.Fields()
+		for _, name := range sortedKeys(fields) {
+			field := fields[name]
+			printSDLDescription(sb, field.PrivateDescription, "  ")
+			fmt.Fprintf(sb, "  %s: %s\n", name, field.Type)
+		}
+		sb.WriteString("}\n")
+
+	case *graphql.Object:
+		fmt.Fprintf(sb, "type %s", t.Name())
+		if len(keys) > 0 {
+			fmt.Fprintf(sb, " @key(fields: %s)", sdlString(strings.Join(keys, " ")))
+		}
+		if federationShareableTypes[t.Name()] {
+			sb.WriteString(" @shareable")
+		}
+		sb.WriteString(" {\n")
+		fields := t.Fields()
+		for _, name := range sortedKeys(fields) {
+			if name == "_service" || name == "_entities" {
+				continue
+			}
+			field := fields[name]
+			printSDLDescription(sb, field.Description, "  ")
+			fmt.Fprintf(sb, "  %s", name)
+			if len(field.Args) > 0 {
+				args := make([]string, len(field.Args))
+				for i, arg := range field.Args {
+					args[i] = fmt.Sprintf("%s: %s", arg.Name(), arg.Type)
+				}
+				fmt.Fprintf(sb, "(%s)", strings.Join(args, ", "))
+			}
+			fmt.Fprintf(sb, ": %s%s\n", field.Type, sdlDeprecated(field.DeprecationReason))
+		}
+		sb.WriteString("}\n")
+	}
+}
+
+// printSDLDescription prints a description as a string above a definition
+func printSDLDescription(sb *strings.Builder, description, indent string) {
+	if description != "" {
+		fmt.Fprintf(sb, "%s%s\n", indent, sdlString(description))
+	}
+}
+
+// sdlDeprecated returns the @deprecated directive for a deprecation reason
+func sdlDeprecated(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return fmt.Sprintf(" @deprecated(reason: %s)", sdlString(reason))
+}
+
+// sdlString quotes a GraphQL string; JSON string escapes are valid GraphQL
+func sdlString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
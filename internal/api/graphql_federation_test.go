@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFederationTestSchema returns a schema with the federation fields of a posts table
+// and a memberships table keyed by two columns
+func newFederationTestSchema(t *testing.T) (*GraphQLSchemaGenerator, graphql.Schema) {
+	t.Helper()
+
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	tables := []database.TableInfo{
+		connectionTestTable(),
+		{
+			Schema:     "public",
+			Name:       "memberships",
+			Type:       "table",
+			PrimaryKey: []string{"org_id", "user_id"},
+			Columns: []database.ColumnInfo{
+				{Name: "org_id", DataType: "integer"},
+				{Name: "user_id", DataType: "uuid"},
+				{Name: "role", DataType: "text"},
+			},
+		},
+		{
+			Schema: "public",
+			Name:   "audit_log",
+			Type:   "view",
+			Columns: []database.ColumnInfo{
+				{Name: "message", DataType: "text"},
+			},
+		},
+	}
+
+	queryFields := graphql.Fields{}
+	for _, table := range tables {
+		typeName := g.tableToTypeName(table.Schema, table.Name)
+		g.objectTypes[typeName] = graphql.NewObject(graphql.ObjectConfig{
+			Name:        typeName,
+			Description: "Auto-generated type for table " + table.Name,
+			Fields:      g.generateTableFields(table),
+		})
+		queryFields[g.tableToCollectionName(table.Name)] = &graphql.Field{
+			Type: graphql.NewList(g.objectTypes[typeName]),
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+		}
+	}
+	for name, field := range g.generateFederationFields(g.federationEntities(tables)) {
+		queryFields[name] = field
+	}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queryFields}),
+	})
+	require.NoError(t, err)
+	return g, schema
+}
+
+func TestFederationService(t *testing.T) {
+	_, schema := newFederationTestSchema(t)
+
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ _service { sdl } }`})
+	require.Empty(t, result.Errors)
+	sdl := result.Data.(map[string]interface{})["_service"].(map[string]interface{})["sdl"].(string)
+
+	assert.Contains(t, sdl, federationLink)
+	assert.Contains(t, sdl, "\"Auto-generated type for table posts\"\ntype Posts @key(fields: \"id\") {\n")
+	assert.Contains(t, sdl, "type Memberships @key(fields: \"orgId userId\") {\n")
+	assert.Contains(t, sdl, "type AuditLog {\n", "tables without a primary key aren't entities")
+	assert.Contains(t, sdl, "  viewCount: Int!\n")
+	assert.Contains(t, sdl, "  posts(limit: Int): [Posts]\n")
+	assert.Contains(t, sdl, "scalar UUID\n")
+
+	// Federation fields and types are left to the gateway
+	assert.NotContains(t, sdl, "_entities")
+	assert.NotContains(t, sdl, "_service")
+	assert.NotContains(t, sdl, "_Any")
+	assert.NotContains(t, sdl, "scalar String")
+	assert.NotContains(t, sdl, "__Schema")
+}
+
+func TestFederationEntities_Errors(t *testing.T) {
+	_, schema := newFederationTestSchema(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{
+			name:    "unknown type",
+			query:   `{ _entities(representations: [{__typename: "Comments", id: 1}]) { __typename } }`,
+			wantErr: `unknown entity type "Comments"`,
+		},
+		{
+			name:    "not an entity",
+			query:   `{ _entities(representations: [{__typename: "AuditLog", message: "x"}]) { __typename } }`,
+			wantErr: `unknown entity type "AuditLog"`,
+		},
+		{
+			name:    "missing key field",
+			query:   `{ _entities(representations: [{__typename: "Memberships", orgId: 1}]) { __typename } }`,
+			wantErr: `representation 0: missing key field "userId"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := graphql.Do(graphql.Params{Schema: schema, RequestString: tt.query, Context: context.Background()})
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, tt.wantErr, result.Errors[0].Message)
+		})
+	}
+}
+
+func TestFederationEntity_KeyFilters(t *testing.T) {
+	g, _ := newFederationTestSchema(t)
+	entity := g.federationEntities([]database.TableInfo{connectionTestTable()})["Posts"]
+	require.NotNil(t, entity)
+	assert.Equal(t, []string{"id"}, entity.keys)
+
+	filters, err := entity.keyFilters(map[string]interface{}{"__typename": "Posts", "id": float64(42)})
+	require.NoError(t, err)
+	assert.Equal(t, []Filter{{Column: "id", Operator: OpEqual, Value: int64(42)}}, filters)
+
+	filters, err = entity.keyFilters(map[string]interface{}{"__typename": "Posts", "id": "550e8400-e29b-41d4-a716-446655440000"})
+	require.NoError(t, err)
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", filters[0].Value)
+
+	_, err = entity.keyFilters(map[string]interface{}{"__typename": "Posts", "id": nil})
+	assert.EqualError(t, err, `missing key field "id"`)
+}
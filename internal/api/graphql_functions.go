@@ -0,0 +1,512 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/auth"
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/rpc"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// graphqlNamePattern matches valid GraphQL field and argument names
+var graphqlNamePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// graphqlProcedureInvoker lists the RPC procedures exposed in the GraphQL schema and invokes them
+// for GraphQL callers. It's implemented by rpc.Handler.
+type graphqlProcedureInvoker interface {
+	ListEnabledProcedures(ctx context.Context) ([]*rpc.Procedure, error)
+	InvokeAs(ctx context.Context, namespace, name string, execCtx *rpc.ExecuteContext) (*rpc.ExecuteResult, error)
+}
+
+// graphqlFunctionReturn is the kind of value a Postgres function returns
+type graphqlFunctionReturn int
+
+const (
+	functionReturnsScalar graphqlFunctionReturn = iota
+	functionReturnsVoid
+	functionReturnsRow    // Rows of a table or view, typed as its object type
+	functionReturnsRecord // TABLE(...) or record results, as JSON objects
+)
+
+// SetProcedureInvoker exposes RPC procedures in the schema while enabled reports the RPC feature
+// flag as on. The schema is regenerated on next access.
+func (g *GraphQLSchemaGenerator) SetProcedureInvoker(invoker graphqlProcedureInvoker, enabled func(ctx context.Context) bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.procedures = invoker
+	g.rpcEnabled = enabled
+	g.schema = nil
+}
+
+// proceduresEnabled reports whether RPC procedures are exposed
+func (g *GraphQLSchemaGenerator) proceduresEnabled(ctx context.Context) bool {
+	return g.procedures != nil && (g.rpcEnabled == nil || g.rpcEnabled(ctx))
+}
+
+// addCallableFields adds fields to a query or mutation type, skipping names already taken so
+// table fields take precedence over procedures, and procedures over functions
+func addCallableFields(target, fields graphql.Fields, kind string) {
+	for name, field := range fields {
+		if _, exists := target[name]; exists {
+			log.Warn().Str("field", name).Msgf("GraphQL: %s field name is already taken, skipping", kind)
+			continue
+		}
+		target[name] = field
+	}
+}
+
+// procedureFieldName returns the GraphQL field name of a procedure: the camelCase name, prefixed
+// with the namespace outside the default namespace (e.g. "billingCreateInvoice")
+func procedureFieldName(proc *rpc.Procedure) string {
+	if proc.Namespace == "" || proc.Namespace == "default" {
+		return toCamelCase(proc.Name)
+	}
+	return toCamelCase(proc.Namespace + "_" + proc.Name)
+}
+
+// generateProcedureFields generates fields for the enabled RPC procedures. Read-only procedures
+// are queries, the others mutations.
+func (g *GraphQLSchemaGenerator) generateProcedureFields(ctx context.Context) (queries, mutations graphql.Fields) {
+	queries, mutations = graphql.Fields{}, graphql.Fields{}
+	if !g.proceduresEnabled(ctx) {
+		return queries, mutations
+	}
+
+	procedures, err := g.procedures.ListEnabledProcedures(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get RPC procedures for GraphQL schema")
+		return queries, mutations
+	}
+
+	for _, proc := range procedures {
+		fieldName := procedureFieldName(proc)
+		readOnly := proc.IsReadOnly()
+		field, err := g.generateProcedureField(proc, fieldName, readOnly)
+		if err != nil {
+			log.Warn().Err(err).Str("namespace", proc.Namespace).Str("procedure", proc.Name).Msg("GraphQL: skipping RPC procedure")
+			continue
+		}
+		if readOnly {
+			queries[fieldName] = field
+		} else {
+			mutations[fieldName] = field
+		}
+	}
+	return queries, mutations
+}
+
+// generateProcedureField generates the field invoking a procedure, with an argument per input
+// schema field and a list of result objects typed by the output schema, or JSON without one
+func (g *GraphQLSchemaGenerator) generateProcedureField(proc *rpc.Procedure, fieldName string, readOnly bool) (*graphql.Field, error) {
+	if !graphqlNamePattern.MatchString(fieldName) {
+		return nil, fmt.Errorf("%q is not a valid GraphQL name", fieldName)
+	}
+
+	args := graphql.FieldConfigArgument{}
+	params := make(map[string]string) // GraphQL argument -> procedure parameter
+	if len(proc.InputSchema) > 0 {
+		var input map[string]string
+		if err := json.Unmarshal(proc.InputSchema, &input); err != nil {
+			return nil, fmt.Errorf("invalid input schema: %w", err)
+		}
+		for key, schemaType := range input {
+			param := rpc.CleanFieldName(key)
+			argName := toCamelCase(param)
+			if !graphqlNamePattern.MatchString(argName) {
+				return nil, fmt.Errorf("parameter %q has no valid GraphQL name", param)
+			}
+			argType := postgresTypeToGraphQLInput(rpc.SchemaTypeToGoType(schemaType))
+			if !rpc.IsOptionalField(key) {
+				argType = graphql.NewNonNull(argType)
+			}
+			args[argName] = &graphql.ArgumentConfig{
+				Type:        argType,
+				Description: fmt.Sprintf("Parameter %s (%s)", param, schemaType),
+			}
+			params[argName] = param
+		}
+	}
+
+	var outputType graphql.Output = JSONScalar
+	typed := false
+	if len(proc.OutputSchema) > 0 {
+		var output map[string]string
+		if err := json.Unmarshal(proc.OutputSchema, &output); err != nil {
+			return nil, fmt.Errorf("invalid output schema: %w", err)
+		}
+		typeName := toPascalCase(fieldName) + "Result"
+		if _, exists := g.objectTypes[typeName]; exists {
+			return nil, fmt.Errorf("result type %s is already taken by a table", typeName)
+		}
+		fields := graphql.Fields{}
+		for key, schemaType := range output {
+			column := rpc.CleanFieldName(key)
+			name := g.columnToFieldName(column)
+			if !graphqlNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("output field %q has no valid GraphQL name", column)
+			}
+			fields[name] = &graphql.Field{
+				Type:        PostgresTypeToGraphQL(rpc.SchemaTypeToGoType(schemaType), true),
+				Description: fmt.Sprintf("Column %s (%s)", column, schemaType),
+				Resolve:     graphqlColumnResolver(column),
+			}
+		}
+		if len(fields) > 0 {
+			outputType = graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name:        typeName,
+				Description: fmt.Sprintf("A row returned by RPC procedure %s/%s", proc.Namespace, proc.Name),
+				Fields:      fields,
+			}))
+			typed = true
+		}
+	}
+
+	description := proc.Description
+	if description == "" {
+		description = fmt.Sprintf("Invoke RPC procedure %s/%s", proc.Namespace, proc.Name)
+	}
+
+	return &graphql.Field{
+		Type:        outputType,
+		Description: description,
+		Args:        args,
+		Resolve:     g.makeProcedureResolver(proc.Namespace, proc.Name, params, typed, readOnly),
+	}, nil
+}
+
+// makeProcedureResolver creates a resolver invoking a procedure as the caller, with the access
+// checks and RLS context of the RPC endpoint
+func (g *GraphQLSchemaGenerator) makeProcedureResolver(namespace, name string, params map[string]string, typed, readOnly bool) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+
+		// The feature flag and scope the RPC endpoint's routes require
+		if !g.proceduresEnabled(ctx) {
+			return nil, fmt.Errorf("RPC is not enabled")
+		}
+		if err := graphqlRequireScope(ctx, auth.ScopeRPCExecute); err != nil {
+			return nil, err
+		}
+
+		execCtx := &rpc.ExecuteContext{
+			Params:   make(map[string]interface{}, len(p.Args)),
+			UserRole: "anon",
+		}
+		for argName, value := range p.Args {
+			if param, ok := params[argName]; ok {
+				execCtx.Params[param] = graphqlParamValue(value)
+			}
+		}
+		if rlsCtx, ok := ctx.Value(GraphQLRLSContextKey).(*RLSContext); ok && rlsCtx != nil {
+			execCtx.UserID = rlsCtx.UserID
+			if rlsCtx.Role != "" {
+				execCtx.UserRole = rlsCtx.Role
+			}
+			execCtx.Claims = rlsCtx.tokenClaims()
+			execCtx.UserEmail = execCtx.Claims.Email
+		}
+
+		result, err := g.procedures.InvokeAs(ctx, namespace, name, execCtx)
+		if errors.Is(err, rpc.ErrProcedureNotFound) || errors.Is(err, rpc.ErrAccessDenied) {
+			return nil, err
+		}
+		if err != nil {
+			log.Error().Err(err).Str("namespace", namespace).Str("procedure", name).Msg("GraphQL: failed to invoke RPC procedure")
+			return nil, fmt.Errorf("failed to execute procedure")
+		}
+		if result.Status != rpc.StatusCompleted {
+			if result.Error != nil {
+				return nil, errors.New(*result.Error)
+			}
+			return nil, fmt.Errorf("procedure execution %s", result.Status)
+		}
+
+		// Writes may have changed related records
+		if !readOnly {
+			resetGraphQLLoaders(ctx)
+		}
+
+		if len(result.Result) == 0 {
+			return nil, nil
+		}
+		if typed {
+			var rows []map[string]interface{}
+			if err := json.Unmarshal(result.Result, &rows); err != nil {
+				return nil, fmt.Errorf("invalid procedure result: %w", err)
+			}
+			return rows, nil
+		}
+		var value interface{}
+		if err := json.Unmarshal(result.Result, &value); err != nil {
+			return nil, fmt.Errorf("invalid procedure result: %w", err)
+		}
+		return value, nil
+	}
+}
+
+// tokenClaims returns the caller's claims in the form the RPC executor sets its RLS context from
+func (r *RLSContext) tokenClaims() *auth.TokenClaims {
+	claims := &auth.TokenClaims{
+		UserID:       r.UserID,
+		Role:         r.Role,
+		UserMetadata: r.Claims["user_metadata"],
+		AppMetadata:  r.Claims["app_metadata"],
+		RawClaims:    r.jwtClaims(),
+	}
+	claims.Email, _ = r.Claims["email"].(string)
+	claims.SessionID, _ = r.Claims["session_id"].(string)
+	claims.IsAnonymous, _ = r.Claims["is_anonymous"].(bool)
+	return claims
+}
+
+// graphqlParamValue converts a parsed GraphQL argument to the JSON value procedures take
+func graphqlParamValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case uuid.UUID:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = graphqlParamValue(item)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// graphqlColumnResolver resolves a field from a column of its source row
+func graphqlColumnResolver(column string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if source, ok := p.Source.(map[string]interface{}); ok {
+			return source[column], nil
+		}
+		return nil, nil
+	}
+}
+
+// generateFunctionFields generates fields for the Postgres functions of the exposed schema.
+// Stable and immutable functions are queries, volatile ones mutations. Overloaded functions,
+// functions with unnamed or polymorphic arguments and trigger functions are skipped.
+func (g *GraphQLSchemaGenerator) generateFunctionFields(functions []database.FunctionInfo, tables []database.TableInfo) (queries, mutations graphql.Fields) {
+	queries, mutations = graphql.Fields{}, graphql.Fields{}
+
+	overloads := make(map[string]int)
+	for _, fn := range functions {
+		overloads[fn.Schema+"."+fn.Name]++
+	}
+	tablesByName := make(map[string]database.TableInfo)
+	for _, table := range tables {
+		tablesByName[table.Schema+"."+table.Name] = table
+	}
+
+	for _, fn := range functions {
+		if overloads[fn.Schema+"."+fn.Name] > 1 {
+			log.Debug().Str("function", fn.Schema+"."+fn.Name).Msg("GraphQL: skipping overloaded function")
+			continue
+		}
+		fieldName := toCamelCase(fn.Name)
+		if !graphqlNamePattern.MatchString(fieldName) {
+			continue
+		}
+		field := g.generateFunctionField(fn, tablesByName)
+		if field == nil {
+			continue
+		}
+		if fn.Volatility == "VOLATILE" {
+			mutations[fieldName] = field
+		} else {
+			queries[fieldName] = field
+		}
+	}
+	return queries, mutations
+}
+
+// functionReturn determines what a function returns and its GraphQL type. ok is false for
+// results that can't be queried, like triggers.
+func (g *GraphQLSchemaGenerator) functionReturn(fn database.FunctionInfo, tablesByName map[string]database.TableInfo) (kind graphqlFunctionReturn, outputType graphql.Output, table *database.TableInfo, ok bool) {
+	returnType := strings.TrimPrefix(fn.ReturnType, "SETOF ")
+
+	name := strings.Trim(strings.TrimPrefix(returnType, fn.Schema+"."), `"`)
+	t, isTable := tablesByName[fn.Schema+"."+name]
+	objType := g.objectTypes[g.tableToTypeName(t.Schema, t.Name)]
+
+	switch {
+	case returnType == "trigger" || returnType == "event_trigger" || returnType == "internal" ||
+		returnType == "cstring" || isPolymorphicType(returnType):
+		return 0, nil, nil, false
+	case returnType == "void":
+		return functionReturnsVoid, graphql.Boolean, nil, true
+	case returnType == "record" || strings.HasPrefix(returnType, "TABLE("):
+		outputType = JSONScalar
+		kind = functionReturnsRecord
+	case isTable && objType != nil:
+		outputType = objType
+		kind = functionReturnsRow
+		table = &t
+	default:
+		outputType = PostgresTypeToGraphQL(returnType, true)
+		kind = functionReturnsScalar
+	}
+
+	if fn.IsSetOf {
+		outputType = graphql.NewList(outputType)
+	}
+	return kind, outputType, table, true
+}
+
+// isPolymorphicType reports whether a type is a pseudo-type like anyelement, whose actual type
+// is only known from the call
+func isPolymorphicType(pgType string) bool {
+	return strings.HasPrefix(pgType, "any")
+}
+
+// generateFunctionField generates the field calling a function, or nil if its arguments or
+// result can't be represented
+func (g *GraphQLSchemaGenerator) generateFunctionField(fn database.FunctionInfo, tablesByName map[string]database.TableInfo) *graphql.Field {
+	kind, outputType, table, ok := g.functionReturn(fn, tablesByName)
+	if !ok {
+		return nil
+	}
+
+	args := graphql.FieldConfigArgument{}
+	params := make(map[string]string) // GraphQL argument -> function parameter
+	for _, param := range fn.Parameters {
+		if param.Mode == "OUT" {
+			continue
+		}
+		argName := toCamelCase(param.Name)
+		if param.Name == "" || !graphqlNamePattern.MatchString(argName) || isPolymorphicType(param.Type) {
+			log.Debug().Str("function", fn.Schema+"."+fn.Name).Msg("GraphQL: skipping function with unnamed or polymorphic arguments")
+			return nil
+		}
+
+		var argType graphql.Input
+		if strings.HasSuffix(param.Type, "[]") {
+			argType = graphql.NewList(postgresTypeToGraphQLInput(strings.TrimSuffix(param.Type, "[]")))
+		} else {
+			argType = postgresTypeToGraphQLInput(param.Type)
+		}
+		if !param.HasDefault {
+			argType = graphql.NewNonNull(argType)
+		}
+		args[argName] = &graphql.ArgumentConfig{
+			Type:        argType,
+			Description: fmt.Sprintf("Argument %s (%s)", param.Name, param.Type),
+		}
+		params[argName] = param.Name
+	}
+
+	description := fn.Description
+	if description == "" {
+		description = fmt.Sprintf("Call function %s.%s", fn.Schema, fn.Name)
+	}
+
+	return &graphql.Field{
+		Type:        outputType,
+		Description: description,
+		Args:        args,
+		Resolve:     g.makeFunctionResolver(fn, kind, table, params),
+	}
+}
+
+// buildFunctionCall builds the query calling a function with named arguments, so arguments
+// with defaults can be omitted
+func buildFunctionCall(fn database.FunctionInfo, kind graphqlFunctionReturn, args map[string]interface{}) (string, []interface{}) {
+	var names []string
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	placeholders := make([]string, len(names))
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("%s => $%d", quoteIdentifier(name), i+1)
+		values[i] = args[name]
+	}
+
+	call := fmt.Sprintf("%s.%s(%s)", quoteIdentifier(fn.Schema), quoteIdentifier(fn.Name), strings.Join(placeholders, ", "))
+	switch kind {
+	case functionReturnsRow, functionReturnsRecord:
+		return "SELECT * FROM " + call, values
+	default:
+		return "SELECT " + call + " AS result", values
+	}
+}
+
+// makeFunctionResolver creates a resolver calling a function as the caller
+func (g *GraphQLSchemaGenerator) makeFunctionResolver(fn database.FunctionInfo, kind graphqlFunctionReturn, table *database.TableInfo, params map[string]string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+
+		args := make(map[string]interface{}, len(p.Args))
+		for argName, value := range p.Args {
+			if param, ok := params[argName]; ok {
+				args[param] = value
+			}
+		}
+
+		sql, values := buildFunctionCall(fn, kind, args)
+		rows, err := g.callFunctionWithRLS(ctx, fn, sql, values)
+		if err != nil {
+			return nil, fmt.Errorf("function call failed: %w", err)
+		}
+
+		switch kind {
+		case functionReturnsVoid:
+			return true, nil
+		case functionReturnsScalar:
+			results := make([]interface{}, len(rows))
+			for i, row := range rows {
+				results[i] = row["result"]
+			}
+			if fn.IsSetOf {
+				return results, nil
+			}
+			if len(results) == 0 {
+				return nil, nil
+			}
+			return results[0], nil
+		case functionReturnsRow:
+			if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, rows); err != nil {
+				return nil, err
+			}
+		}
+
+		if fn.IsSetOf {
+			if rows == nil {
+				return []map[string]interface{}{}, nil
+			}
+			return rows, nil
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows[0], nil
+	}
+}
+
+// callFunctionWithRLS calls a function with the caller's RLS context, or as anon for anonymous
+// callers, so EXECUTE privileges apply. Volatile functions may write, so they run like mutations.
+func (g *GraphQLSchemaGenerator) callFunctionWithRLS(ctx context.Context, fn database.FunctionInfo, sql string, args []interface{}) ([]map[string]interface{}, error) {
+	if fn.Volatility == "VOLATILE" {
+		return g.execWithRLS(ctx, sql, args...)
+	}
+	if rlsCtx, ok := ctx.Value(GraphQLRLSContextKey).(*RLSContext); !ok || rlsCtx == nil {
+		ctx = context.WithValue(ctx, GraphQLRLSContextKey, &RLSContext{Role: "anon"})
+	}
+	return g.queryWithRLS(ctx, sql, args...)
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/rpc"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcedureInvoker serves procedures from memory and records invocations
+type fakeProcedureInvoker struct {
+	procedures []*rpc.Procedure
+	result     *rpc.ExecuteResult
+	err        error
+	invoked    []*rpc.ExecuteContext
+}
+
+func (f *fakeProcedureInvoker) ListEnabledProcedures(ctx context.Context) ([]*rpc.Procedure, error) {
+	return f.procedures, nil
+}
+
+func (f *fakeProcedureInvoker) InvokeAs(ctx context.Context, namespace, name string, execCtx *rpc.ExecuteContext) (*rpc.ExecuteResult, error) {
+	f.invoked = append(f.invoked, execCtx)
+	return f.result, f.err
+}
+
+func testProcedures() []*rpc.Procedure {
+	return []*rpc.Procedure{
+		{
+			Name:         "user_stats",
+			Namespace:    "default",
+			SQLQuery:     "SELECT count(*) AS post_count FROM posts WHERE author_id = $user_id::uuid",
+			InputSchema:  json.RawMessage(`{"user_id": "uuid", "since?": "timestamp"}`),
+			OutputSchema: json.RawMessage(`{"post_count": "integer"}`),
+		},
+		{
+			Name:      "create_invoice",
+			Namespace: "billing",
+			SQLQuery:  "INSERT INTO invoices (amount) VALUES ($amount) RETURNING *",
+		},
+		{
+			Name:      "2fa-reset",
+			Namespace: "default",
+			SQLQuery:  "SELECT 1",
+		},
+	}
+}
+
+func TestGenerateProcedureFields(t *testing.T) {
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	g.procedures = &fakeProcedureInvoker{procedures: testProcedures()}
+
+	queries, mutations := g.generateProcedureFields(context.Background())
+
+	require.Len(t, queries, 1)
+	stats := queries["userStats"]
+	require.NotNil(t, stats, "read-only procedures are queries")
+	assert.Equal(t, "[UserStatsResult]", stats.Type.String())
+	assert.Equal(t, "UUID!", stats.Args["userId"].Type.String())
+	assert.Equal(t, "DateTime", stats.Args["since"].Type.String(), "optional parameters are nullable")
+
+	require.Len(t, mutations, 1, "procedures without a valid GraphQL name are skipped")
+	invoice := mutations["billingCreateInvoice"]
+	require.NotNil(t, invoice, "writing procedures are mutations, prefixed with their namespace")
+	assert.Equal(t, "JSON", invoice.Type.String(), "results without an output schema are JSON")
+	assert.Empty(t, invoice.Args)
+}
+
+func TestProcedureResolver(t *testing.T) {
+	invoker := &fakeProcedureInvoker{
+		procedures: testProcedures(),
+		result: &rpc.ExecuteResult{
+			Status: rpc.StatusCompleted,
+			Result: json.RawMessage(`[{"post_count": 3}]`),
+		},
+	}
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	g.procedures = invoker
+
+	queries, mutations := g.generateProcedureFields(context.Background())
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queries}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutations}),
+	})
+	require.NoError(t, err)
+
+	query := `{ userStats(userId: "550e8400-e29b-41d4-a716-446655440000") { postCount } }`
+	ctx := context.WithValue(context.Background(), GraphQLRLSContextKey, &RLSContext{
+		UserID: "user-1",
+		Role:   "authenticated",
+		Claims: map[string]interface{}{"email": "jane@example.com"},
+	})
+
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: ctx})
+	require.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"userStats": []interface{}{map[string]interface{}{"postCount": 3}},
+	}, result.Data)
+
+	require.Len(t, invoker.invoked, 1)
+	execCtx := invoker.invoked[0]
+	assert.Equal(t, map[string]interface{}{"user_id": "550e8400-e29b-41d4-a716-446655440000"}, execCtx.Params)
+	assert.Equal(t, "user-1", execCtx.UserID)
+	assert.Equal(t, "authenticated", execCtx.UserRole)
+	assert.Equal(t, "jane@example.com", execCtx.UserEmail)
+	require.NotNil(t, execCtx.Claims)
+	assert.Equal(t, "user-1", execCtx.Claims.UserID)
+
+	// Anonymous callers invoke procedures as anon
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: context.Background()})
+	require.Empty(t, result.Errors)
+	require.Len(t, invoker.invoked, 2)
+	assert.Equal(t, "anon", invoker.invoked[1].UserRole)
+	assert.Empty(t, invoker.invoked[1].UserID)
+
+	// Access errors are reported as they are
+	invoker.result = nil
+	invoker.err = fmt.Errorf("%w: procedure requires authentication", rpc.ErrAccessDenied)
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: context.Background()})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "access denied: procedure requires authentication", result.Errors[0].Message)
+
+	// So are failed executions
+	failure := "Input validation failed: missing required parameter: amount"
+	invoker.err = nil
+	invoker.result = &rpc.ExecuteResult{Status: rpc.StatusFailed, Error: &failure}
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: `mutation { billingCreateInvoice }`, Context: context.Background()})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, failure, result.Errors[0].Message)
+}
+
+func TestProcedureResolver_RPCAccess(t *testing.T) {
+	invoker := &fakeProcedureInvoker{
+		procedures: testProcedures(),
+		result:     &rpc.ExecuteResult{Status: rpc.StatusCompleted, Result: json.RawMessage(`{"id": 1}`)},
+	}
+	enabled := true
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	g.SetProcedureInvoker(invoker, func(ctx context.Context) bool { return enabled })
+
+	_, mutations := g.generateProcedureFields(context.Background())
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{"ok": &graphql.Field{Type: graphql.Boolean}}}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutations}),
+	})
+	require.NoError(t, err)
+	mutation := `mutation { billingCreateInvoice }`
+
+	// Keys need the execute:rpc scope; JWTs aren't scoped
+	ctx := withGraphQLKeyScopes(context.Background(), graphqlKeyScopes{"read:tables"})
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: mutation, Context: ctx})
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "execute:rpc")
+	assert.Empty(t, invoker.invoked)
+
+	ctx = withGraphQLKeyScopes(context.Background(), graphqlKeyScopes{"execute:rpc"})
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: mutation, Context: ctx})
+	require.Empty(t, result.Errors)
+	require.Len(t, invoker.invoked, 1)
+
+	// Procedures can't be invoked, and aren't in the schema, while RPC is disabled
+	enabled = false
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: mutation, Context: context.Background()})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "RPC is not enabled", result.Errors[0].Message)
+	assert.Len(t, invoker.invoked, 1)
+
+	queries, mutations := g.generateProcedureFields(context.Background())
+	assert.Empty(t, queries)
+	assert.Empty(t, mutations)
+}
+
+func TestGenerateFunctionFields(t *testing.T) {
+	posts := database.TableInfo{Schema: "public", Name: "posts", Type: "table"}
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	g.objectTypes["Posts"] = graphql.NewObject(graphql.ObjectConfig{Name: "Posts", Fields: graphql.Fields{"id": &graphql.Field{Type: graphql.Int}}})
+
+	functions := []database.FunctionInfo{
+		{
+			Schema: "public", Name: "search_posts", ReturnType: "SETOF posts", IsSetOf: true, Volatility: "STABLE",
+			Parameters: []database.FunctionParam{
+				{Name: "query", Type: "text", Mode: "IN"},
+				{Name: "max_results", Type: "integer", Mode: "IN", HasDefault: true},
+			},
+		},
+		{
+			Schema: "public", Name: "publish_post", ReturnType: "posts", Volatility: "VOLATILE",
+			Parameters: []database.FunctionParam{{Name: "post_id", Type: "bigint", Mode: "IN"}},
+		},
+		{
+			Schema: "public", Name: "tag_counts", ReturnType: "TABLE(tag text, count bigint)", IsSetOf: true, Volatility: "STABLE",
+			Parameters: []database.FunctionParam{
+				{Name: "tags", Type: "text[]", Mode: "IN"},
+				{Name: "tag", Type: "text", Mode: "OUT"},
+				{Name: "count", Type: "bigint", Mode: "OUT"},
+			},
+		},
+		{Schema: "public", Name: "refresh_stats", ReturnType: "void", Volatility: "VOLATILE"},
+		{Schema: "public", Name: "now_utc", ReturnType: "timestamp with time zone", Volatility: "STABLE"},
+		{Schema: "public", Name: "add", ReturnType: "integer", Volatility: "IMMUTABLE", Parameters: []database.FunctionParam{{Type: "integer", Mode: "IN"}}},
+		{Schema: "public", Name: "first_of", ReturnType: "anyelement", Volatility: "IMMUTABLE", Parameters: []database.FunctionParam{{Name: "items", Type: "anyarray", Mode: "IN"}}},
+		{Schema: "public", Name: "set_updated_at", ReturnType: "trigger", Volatility: "VOLATILE"},
+		{Schema: "public", Name: "score", ReturnType: "integer", Volatility: "STABLE"},
+		{Schema: "public", Name: "score", ReturnType: "numeric", Volatility: "STABLE"},
+	}
+
+	queries, mutations := g.generateFunctionFields(functions, []database.TableInfo{posts})
+
+	assert.ElementsMatch(t, []string{"searchPosts", "tagCounts", "nowUtc"}, fieldNames(queries))
+	assert.ElementsMatch(t, []string{"publishPost", "refreshStats"}, fieldNames(mutations))
+
+	search := queries["searchPosts"]
+	assert.Equal(t, "[Posts]", search.Type.String())
+	assert.Equal(t, "String!", search.Args["query"].Type.String())
+	assert.Equal(t, "Int", search.Args["maxResults"].Type.String(), "arguments with defaults are optional")
+
+	assert.Equal(t, "Posts", mutations["publishPost"].Type.String())
+	assert.Equal(t, "BigInt!", mutations["publishPost"].Args["postId"].Type.String())
+
+	tagCounts := queries["tagCounts"]
+	assert.Equal(t, "[JSON]", tagCounts.Type.String())
+	assert.Equal(t, "[String]!", tagCounts.Args["tags"].Type.String())
+	assert.Len(t, tagCounts.Args, 1, "OUT parameters aren't arguments")
+
+	assert.Equal(t, "Boolean", mutations["refreshStats"].Type.String())
+	assert.Equal(t, "DateTime", queries["nowUtc"].Type.String())
+}
+
+func fieldNames(fields graphql.Fields) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestBuildFunctionCall(t *testing.T) {
+	fn := database.FunctionInfo{Schema: "public", Name: "search_posts"}
+
+	sql, args := buildFunctionCall(fn, functionReturnsRow, map[string]interface{}{"query": "graphql", "max_results": 10})
+	assert.Equal(t, `SELECT * FROM "public"."search_posts"("max_results" => $1, "query" => $2)`, sql)
+	assert.Equal(t, []interface{}{10, "graphql"}, args)
+
+	sql, args = buildFunctionCall(fn, functionReturnsScalar, nil)
+	assert.Equal(t, `SELECT "public"."search_posts"() AS result`, sql)
+	assert.Empty(t, args)
+}
+
+func TestAddCallableFields(t *testing.T) {
+	tableField := &graphql.Field{Type: graphql.String}
+	target := graphql.Fields{"posts": tableField}
+
+	addCallableFields(target, graphql.Fields{
+		"posts":       &graphql.Field{Type: graphql.Int},
+		"searchPosts": &graphql.Field{Type: graphql.Int},
+	}, "function")
+
+	assert.Same(t, tableField, target["posts"], "table fields take precedence")
+	assert.Contains(t, target, "searchPosts")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/fluxbase-eu/fluxbase/internal/auth"
 	"github.com/fluxbase-eu/fluxbase/internal/config"
 	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/realtime"
 	"github.com/gofiber/fiber/v2"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
@@ -24,6 +25,12 @@ type GraphQLHandler struct {
 	db              *database.Connection
 	config          *config.GraphQLConfig
 	resolverFactory *GraphQLResolverFactory
+
+	persistedQueries *graphqlPersistedQueries
+
+	// Set by EnableSubscriptions
+	subscriptions    *graphqlSubscriptionBroker
+	subscriptionAuth realtime.AuthService
 }
 
 // GraphQLRequest represents a GraphQL HTTP request body
@@ -31,6 +38,8 @@ type GraphQLRequest struct {
 	Query         string                 `json:"query"`
 	OperationName string                 `json:"operationName,omitempty"`
 	Variables     map[string]interface{} `json:"variables,omitempty"`
+
+	Extensions *GraphQLRequestExtensions `json:"extensions,omitempty"`
 }
 
 // GraphQLResponse represents a GraphQL HTTP response body
@@ -61,12 +70,14 @@ func NewGraphQLHandler(db *database.Connection, schemaCache *database.SchemaCach
 	// Create schema generator
 	schemaGenerator := NewGraphQLSchemaGenerator(schemaCache, db, cfg.Introspection)
 	schemaGenerator.SetResolverFactory(resolverFactory)
+	schemaGenerator.federation = cfg.Federation
 
 	return &GraphQLHandler{
-		schemaGenerator: schemaGenerator,
-		db:              db,
-		config:          cfg,
-		resolverFactory: resolverFactory,
+		schemaGenerator:  schemaGenerator,
+		db:               db,
+		config:           cfg,
+		resolverFactory:  resolverFactory,
+		persistedQueries: newGraphQLPersistedQueries(db.Pool(), cfg.PersistedQueries.CacheSize),
 	}
 }
 
@@ -85,6 +96,14 @@ func (h *GraphQLHandler) HandleGraphQL(c *fiber.Ctx) error {
 		})
 	}
 
+	// Resolve persisted query hashes and enforce the operation allowlist
+	role, _ := c.Locals("user_role").(string)
+	if gqlErr, status := h.resolvePersistedQuery(ctx, &req, role); gqlErr != nil {
+		return c.Status(status).JSON(GraphQLResponse{
+			Errors: []GraphQLError{*gqlErr},
+		})
+	}
+
 	// Validate query is present
 	if req.Query == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
@@ -94,35 +113,22 @@ func (h *GraphQLHandler) HandleGraphQL(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate query depth
-	if h.config.MaxDepth > 0 {
-		depth, err := calculateQueryDepth(req.Query)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
-				Errors: []GraphQLError{{
-					Message: "Invalid query syntax",
-				}},
-			})
-		}
-		if depth > h.config.MaxDepth {
-			return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
-				Errors: []GraphQLError{{
-					Message: fmt.Sprintf("query depth %d exceeds maximum allowed depth of %d", depth, h.config.MaxDepth),
-				}},
-			})
-		}
+	// Validate query depth and complexity
+	if msg := h.checkQueryLimits(req.Query); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
+			Errors: []GraphQLError{{
+				Message: msg,
+			}},
+		})
 	}
 
-	// Validate query complexity
-	if h.config.MaxComplexity > 0 {
-		complexity := calculateQueryComplexity(req.Query)
-		if complexity > h.config.MaxComplexity {
-			return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
-				Errors: []GraphQLError{{
-					Message: fmt.Sprintf("query complexity %d exceeds maximum of %d", complexity, h.config.MaxComplexity),
-				}},
-			})
-		}
+	// Subscriptions are only served over WebSocket
+	if graphqlOperationType(req.Query, req.OperationName) == ast.OperationTypeSubscription {
+		return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
+			Errors: []GraphQLError{{
+				Message: "Subscriptions require a WebSocket connection using the graphql-transport-ws protocol",
+			}},
+		})
 	}
 
 	// Get GraphQL schema
@@ -138,6 +144,10 @@ func (h *GraphQLHandler) HandleGraphQL(c *fiber.Ctx) error {
 
 	// Set up RLS context if user is authenticated
 	ctx = h.setupRLSContext(c, ctx)
+	ctx = withGraphQLKeyScopes(ctx, graphqlRequestKeyScopes(c))
+
+	// Relation lookups are batched and cached for the duration of the query
+	ctx = withGraphQLLoaders(ctx)
 
 	// Execute the query
 	result := graphql.Do(graphql.Params{
@@ -184,11 +194,57 @@ func (h *GraphQLHandler) HandleGraphQL(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// checkQueryLimits checks a query against the configured depth and complexity limits.
+// Returns the error message, or "" if the query is within the limits.
+func (h *GraphQLHandler) checkQueryLimits(query string) string {
+	if h.config.MaxDepth > 0 {
+		depth, err := calculateQueryDepth(query)
+		if err != nil {
+			return "Invalid query syntax"
+		}
+		if depth > h.config.MaxDepth {
+			return fmt.Sprintf("query depth %d exceeds maximum allowed depth of %d", depth, h.config.MaxDepth)
+		}
+	}
+
+	if h.config.MaxComplexity > 0 {
+		complexity := calculateQueryComplexity(query)
+		if complexity > h.config.MaxComplexity {
+			return fmt.Sprintf("query complexity %d exceeds maximum of %d", complexity, h.config.MaxComplexity)
+		}
+	}
+
+	return ""
+}
+
+// graphqlOperationType returns the type of the operation a request executes, or "" if the query
+// can't be parsed or has no such operation
+func graphqlOperationType(query, operationName string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation
+		}
+	}
+	return ""
+}
+
 // setupRLSContext sets up Row Level Security context for the query
 func (h *GraphQLHandler) setupRLSContext(c *fiber.Ctx, ctx context.Context) context.Context {
 	// Get user from fiber context (set by auth middleware)
 	user, ok := c.Locals("user").(*auth.User)
 	if !ok || user == nil {
+		// Otherwise use the credentials the auth middleware validated, as the RPC endpoint does
+		if rlsCtx := upgradeRLSContext(c); rlsCtx != nil {
+			return context.WithValue(ctx, GraphQLRLSContextKey, rlsCtx)
+		}
 		return ctx
 	}
 
@@ -211,6 +267,50 @@ func (h *GraphQLHandler) setupRLSContext(c *fiber.Ctx, ctx context.Context) cont
 	return context.WithValue(ctx, GraphQLRLSContextKey, rlsCtx)
 }
 
+// graphqlKeyScopesContextKey is used to store the scopes of the key a request was authenticated with
+const graphqlKeyScopesContextKey graphqlContextKey = "graphql_key_scopes"
+
+// graphqlKeyScopes are the scopes of the client or service key a request was authenticated with
+type graphqlKeyScopes []string
+
+// graphqlRequestKeyScopes returns the scopes of the request's client or service key, or nil when
+// it wasn't authenticated with a key. Like middleware.RequireScope, JWTs aren't scoped.
+func graphqlRequestKeyScopes(c *fiber.Ctx) graphqlKeyScopes {
+	var scopes []string
+	switch c.Locals("auth_type") {
+	case "clientkey":
+		scopes, _ = c.Locals("client_key_scopes").([]string)
+	case "service_key":
+		scopes, _ = c.Locals("service_key_scopes").([]string)
+	default:
+		return nil
+	}
+	// A key without scopes is granted none
+	return append(graphqlKeyScopes{}, scopes...)
+}
+
+// withGraphQLKeyScopes stores the key scopes of a request in its context
+func withGraphQLKeyScopes(ctx context.Context, scopes graphqlKeyScopes) context.Context {
+	if scopes == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, graphqlKeyScopesContextKey, scopes)
+}
+
+// graphqlRequireScope returns an error when the request was authenticated with a key lacking the scope
+func graphqlRequireScope(ctx context.Context, required string) error {
+	scopes, ok := ctx.Value(graphqlKeyScopesContextKey).(graphqlKeyScopes)
+	if !ok {
+		return nil
+	}
+	for _, scope := range scopes {
+		if scope == required || scope == "*" {
+			return nil
+		}
+	}
+	return fmt.Errorf("insufficient permissions: the %s scope is required", required)
+}
+
 // HandleIntrospection handles GET /api/v1/graphql (returns introspection data)
 func (h *GraphQLHandler) HandleIntrospection(c *fiber.Ctx) error {
 	if !h.config.Introspection {
@@ -259,6 +359,12 @@ func (h *GraphQLHandler) RegisterRoutes(app *fiber.App, authService *auth.Servic
 	graphqlGroup.Get("/", h.HandleIntrospection)
 }
 
+// EnableProcedures exposes the enabled RPC procedures as query and mutation fields while the
+// RPC feature flag is on
+func (h *GraphQLHandler) EnableProcedures(invoker graphqlProcedureInvoker, enabled func(ctx context.Context) bool) {
+	h.schemaGenerator.SetProcedureInvoker(invoker, enabled)
+}
+
 // InvalidateSchema invalidates the cached GraphQL schema
 func (h *GraphQLHandler) InvalidateSchema() {
 	h.schemaGenerator.InvalidateSchema()
@@ -273,8 +379,9 @@ func convertErrors(errors []gqlerrors.FormattedError) []GraphQLError {
 	result := make([]GraphQLError, len(errors))
 	for i, err := range errors {
 		gqlErr := GraphQLError{
-			Message: err.Message,
-			Path:    err.Path,
+			Message:    err.Message,
+			Path:       err.Path,
+			Extensions: err.Extensions,
 		}
 		if len(err.Locations) > 0 {
 			gqlErr.Locations = make([]GraphQLErrorLocation, len(err.Locations))
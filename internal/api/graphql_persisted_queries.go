@@ -0,0 +1,426 @@
+package api
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// Error codes of persisted query errors, as expected by Apollo clients
+const (
+	graphqlPersistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	graphqlPersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	graphqlPersistedQueryInvalid      = "PERSISTED_QUERY_INVALID"
+	graphqlOperationNotAllowed        = "OPERATION_NOT_ALLOWED"
+)
+
+// registeredOperationsRefreshInterval bounds how long an instance takes to see operations
+// registered or deleted on another instance
+const registeredOperationsRefreshInterval = 30 * time.Second
+
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// GraphQLRequestExtensions represents the extensions of a GraphQL request
+type GraphQLRequestExtensions struct {
+	PersistedQuery *GraphQLPersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// GraphQLPersistedQuery identifies a query by the SHA-256 hash of its document
+type GraphQLPersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// persistedQuery returns the persisted query extension of a request, or nil
+func (r *GraphQLRequest) persistedQuery() *GraphQLPersistedQuery {
+	if r.Extensions == nil {
+		return nil
+	}
+	return r.Extensions.PersistedQuery
+}
+
+// graphqlPersistedQueries resolves persisted query hashes to documents. Automatic persisted
+// queries are kept in an in-memory LRU per instance, as clients resend the document on a miss.
+// Registered operations are stored in api.graphql_operations and form the allowlist.
+type graphqlPersistedQueries struct {
+	maxEntries     int
+	loadRegistered func(ctx context.Context) (map[string]string, error) // hash -> document
+
+	mu        sync.Mutex
+	automatic map[string]*list.Element
+	lru       *list.List // front is most recently used
+
+	registeredMu sync.Mutex
+	registered   map[string]string
+	loadedAt     time.Time
+}
+
+type automaticPersistedQuery struct {
+	hash     string
+	document string
+}
+
+// newGraphQLPersistedQueries creates a persisted query store reading registered operations from db
+func newGraphQLPersistedQueries(db *pgxpool.Pool, maxEntries int) *graphqlPersistedQueries {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &graphqlPersistedQueries{
+		maxEntries: maxEntries,
+		loadRegistered: func(ctx context.Context) (map[string]string, error) {
+			return loadGraphQLOperations(ctx, db)
+		},
+		automatic: make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// loadGraphQLOperations reads all registered operations
+func loadGraphQLOperations(ctx context.Context, db *pgxpool.Pool) (map[string]string, error) {
+	if db == nil {
+		return map[string]string{}, nil
+	}
+	rows, err := db.Query(ctx, `SELECT hash, document FROM api.graphql_operations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make(map[string]string)
+	for rows.Next() {
+		var hash, document string
+		if err := rows.Scan(&hash, &document); err != nil {
+			return nil, err
+		}
+		operations[hash] = document
+	}
+	return operations, rows.Err()
+}
+
+// registeredOperations returns the registered operations, reloading them when they're stale.
+// If reloading fails the previous set is kept until the next refresh.
+func (pq *graphqlPersistedQueries) registeredOperations(ctx context.Context) map[string]string {
+	pq.registeredMu.Lock()
+	defer pq.registeredMu.Unlock()
+
+	if time.Since(pq.loadedAt) < registeredOperationsRefreshInterval {
+		return pq.registered
+	}
+	pq.loadedAt = time.Now()
+
+	operations, err := pq.loadRegistered(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load registered GraphQL operations")
+		return pq.registered
+	}
+	pq.registered = operations
+	return pq.registered
+}
+
+// invalidateRegistered makes the next lookup reload the registered operations
+func (pq *graphqlPersistedQueries) invalidateRegistered() {
+	pq.registeredMu.Lock()
+	defer pq.registeredMu.Unlock()
+	pq.loadedAt = time.Time{}
+}
+
+// isRegistered reports whether a hash belongs to a registered operation
+func (pq *graphqlPersistedQueries) isRegistered(ctx context.Context, hash string) bool {
+	_, ok := pq.registeredOperations(ctx)[hash]
+	return ok
+}
+
+// lookup returns the document of a hash. Only registered operations are returned when
+// registeredOnly is set.
+func (pq *graphqlPersistedQueries) lookup(ctx context.Context, hash string, registeredOnly bool) (string, bool) {
+	if document, ok := pq.registeredOperations(ctx)[hash]; ok {
+		return document, true
+	}
+	if registeredOnly {
+		return "", false
+	}
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	el, ok := pq.automatic[hash]
+	if !ok {
+		return "", false
+	}
+	pq.lru.MoveToFront(el)
+	return el.Value.(*automaticPersistedQuery).document, true
+}
+
+// remember stores the document of an automatic persisted query
+func (pq *graphqlPersistedQueries) remember(hash, document string) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if el, ok := pq.automatic[hash]; ok {
+		pq.lru.MoveToFront(el)
+		return
+	}
+	pq.automatic[hash] = pq.lru.PushFront(&automaticPersistedQuery{hash: hash, document: document})
+	for pq.lru.Len() > pq.maxEntries {
+		el := pq.lru.Back()
+		pq.lru.Remove(el)
+		delete(pq.automatic, el.Value.(*automaticPersistedQuery).hash)
+	}
+}
+
+// graphqlDocumentHash returns the lowercase hex SHA-256 digest of a document
+func graphqlDocumentHash(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:])
+}
+
+// graphqlAdminRole reports whether a role may run operations that aren't registered
+func graphqlAdminRole(role string) bool {
+	return role == "admin" || role == "dashboard_admin" || role == "service_role"
+}
+
+// resolvePersistedQuery fills in the query of a request sent as a persisted query hash, stores
+// new automatic persisted queries and enforces the allowlist for non-admin roles.
+// Returns the error to respond with and its HTTP status, or nil if the request can run.
+func (h *GraphQLHandler) resolvePersistedQuery(ctx context.Context, req *GraphQLRequest, role string) (*GraphQLError, int) {
+	if h.persistedQueries == nil {
+		return nil, 0
+	}
+	cfg := h.config.PersistedQueries
+	allowlisted := cfg.AllowlistOnly && !graphqlAdminRole(role)
+
+	persisted := req.persistedQuery()
+	if persisted == nil {
+		if allowlisted && req.Query != "" && !h.persistedQueries.isRegistered(ctx, graphqlDocumentHash(req.Query)) {
+			return graphqlOperationNotAllowedError(), fiber.StatusForbidden
+		}
+		return nil, 0
+	}
+
+	if !cfg.Enabled {
+		return &GraphQLError{
+			Message:    "PersistedQueryNotSupported",
+			Extensions: map[string]interface{}{"code": graphqlPersistedQueryNotSupported},
+		}, fiber.StatusBadRequest
+	}
+	if persisted.Version != 1 {
+		return &GraphQLError{
+			Message:    "Unsupported persisted query version",
+			Extensions: map[string]interface{}{"code": graphqlPersistedQueryInvalid},
+		}, fiber.StatusBadRequest
+	}
+	hash := strings.ToLower(persisted.SHA256Hash)
+	if !sha256HexPattern.MatchString(hash) {
+		return &GraphQLError{
+			Message:    "Invalid persisted query hash",
+			Extensions: map[string]interface{}{"code": graphqlPersistedQueryInvalid},
+		}, fiber.StatusBadRequest
+	}
+
+	// Hash only: the client resends the document when it isn't known
+	if req.Query == "" {
+		document, ok := h.persistedQueries.lookup(ctx, hash, allowlisted)
+		if !ok {
+			return &GraphQLError{
+				Message:    "PersistedQueryNotFound",
+				Extensions: map[string]interface{}{"code": graphqlPersistedQueryNotFound},
+			}, fiber.StatusOK
+		}
+		req.Query = document
+		return nil, 0
+	}
+
+	if graphqlDocumentHash(req.Query) != hash {
+		return &GraphQLError{
+			Message:    "provided sha does not match query",
+			Extensions: map[string]interface{}{"code": graphqlPersistedQueryInvalid},
+		}, fiber.StatusBadRequest
+	}
+	if allowlisted {
+		if !h.persistedQueries.isRegistered(ctx, hash) {
+			return graphqlOperationNotAllowedError(), fiber.StatusForbidden
+		}
+		return nil, 0
+	}
+	h.persistedQueries.remember(hash, req.Query)
+	return nil, 0
+}
+
+// graphqlOperationNotAllowedError is returned for operations outside the allowlist
+func graphqlOperationNotAllowedError() *GraphQLError {
+	return &GraphQLError{
+		Message:    "Operation is not registered. Only registered operations can be executed",
+		Extensions: map[string]interface{}{"code": graphqlOperationNotAllowed},
+	}
+}
+
+// GraphQLOperationRequest represents an operation to register
+type GraphQLOperationRequest struct {
+	Query string `json:"query"`
+}
+
+// GraphQLOperationsRequest represents a request to register operations
+type GraphQLOperationsRequest struct {
+	Operations []GraphQLOperationRequest `json:"operations"`
+}
+
+// GraphQLOperationResponse represents a registered operation
+type GraphQLOperationResponse struct {
+	Hash      string `json:"hash"`
+	Name      string `json:"name,omitempty"`
+	Query     string `json:"query,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// graphqlDocumentName parses a document and returns the name of its first named operation
+func graphqlDocumentName(document string) (string, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: document})
+	if err != nil {
+		return "", fmt.Errorf("invalid GraphQL document: %w", err)
+	}
+	name := ""
+	operations := 0
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		operations++
+		if name == "" && op.Name != nil {
+			name = op.Name.Value
+		}
+	}
+	if operations == 0 {
+		return "", fmt.Errorf("document contains no operation")
+	}
+	return name, nil
+}
+
+// HandleRegisterOperations handles POST /api/v1/admin/graphql/operations. Operations are keyed
+// by the hash of their document, so registering one again is a no-op.
+func (h *GraphQLHandler) HandleRegisterOperations(c *fiber.Ctx) error {
+	var req GraphQLOperationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendInvalidBody(c)
+	}
+	if len(req.Operations) == 0 {
+		return SendBadRequest(c, "operations is required", ErrCodeValidationFailed)
+	}
+
+	registered := make([]GraphQLOperationResponse, len(req.Operations))
+	for i, op := range req.Operations {
+		name, err := graphqlDocumentName(op.Query)
+		if err != nil {
+			return SendBadRequest(c, fmt.Sprintf("operation %d: %s", i+1, err.Error()), ErrCodeValidationFailed)
+		}
+		registered[i] = GraphQLOperationResponse{Hash: graphqlDocumentHash(op.Query), Name: name, Query: op.Query}
+	}
+
+	ctx := c.Context()
+
+	batch := &pgx.Batch{}
+	for _, op := range registered {
+		batch.Queue(`
+INSERT INTO api.graphql_operations (hash, name, document)
+VALUES ($1, NULLIF($2, ''), $3)
+ON CONFLICT (hash) DO NOTHING`,
+			op.Hash, op.Name, op.Query)
+	}
+	if err := h.db.Pool().SendBatch(ctx, batch).Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to register GraphQL operations")
+		return SendInternalError(c, "Failed to register GraphQL operations")
+	}
+
+	if h.persistedQueries != nil {
+		h.persistedQueries.invalidateRegistered()
+	}
+
+	log.Info().Int("count", len(registered)).Msg("GraphQL operations registered")
+
+	for i := range registered {
+		registered[i].Query = ""
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"operations": registered,
+		"count":      len(registered),
+	})
+}
+
+// HandleListOperations handles GET /api/v1/admin/graphql/operations, optionally filtered by ?name=
+func (h *GraphQLHandler) HandleListOperations(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	query := `SELECT hash, COALESCE(name, ''), document, created_at FROM api.graphql_operations`
+	var args []interface{}
+	if name := c.Query("name"); name != "" {
+		query += " WHERE name = $1"
+		args = append(args, name)
+	}
+	query += " ORDER BY name NULLS LAST, created_at"
+
+	rows, err := h.db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list GraphQL operations")
+		return SendInternalError(c, "Failed to list GraphQL operations")
+	}
+	defer rows.Close()
+
+	operations := []GraphQLOperationResponse{}
+	for rows.Next() {
+		var op GraphQLOperationResponse
+		var createdAt interface{}
+		if err := rows.Scan(&op.Hash, &op.Name, &op.Query, &createdAt); err != nil {
+			log.Error().Err(err).Msg("Failed to scan GraphQL operation row")
+			continue
+		}
+		op.CreatedAt = fmt.Sprintf("%v", createdAt)
+		operations = append(operations, op)
+	}
+
+	return c.JSON(fiber.Map{
+		"operations": operations,
+		"count":      len(operations),
+	})
+}
+
+// HandleDeleteOperation handles DELETE /api/v1/admin/graphql/operations/:hash
+func (h *GraphQLHandler) HandleDeleteOperation(c *fiber.Ctx) error {
+	hash := strings.ToLower(c.Params("hash"))
+	if !sha256HexPattern.MatchString(hash) {
+		return SendBadRequest(c, "Invalid operation hash", ErrCodeInvalidID)
+	}
+
+	ctx := c.Context()
+
+	tag, err := h.db.Pool().Exec(ctx, `DELETE FROM api.graphql_operations WHERE hash = $1`, hash)
+	if err != nil {
+		log.Error().Err(err).Str("hash", hash).Msg("Failed to delete GraphQL operation")
+		return SendInternalError(c, "Failed to delete GraphQL operation")
+	}
+	if tag.RowsAffected() == 0 {
+		return SendErrorWithCode(c, fiber.StatusNotFound, "GraphQL operation not found", ErrCodeNotFound)
+	}
+
+	if h.persistedQueries != nil {
+		h.persistedQueries.invalidateRegistered()
+	}
+
+	log.Info().Str("hash", hash).Msg("GraphQL operation deleted")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "GraphQL operation deleted",
+	})
+}
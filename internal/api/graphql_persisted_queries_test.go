@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const registeredTestQuery = `query Health { _health }`
+
+// newPersistedQueryTestApp returns an app serving a handler whose registered operations are
+// registeredTestQuery, with the caller's role taken from the X-Test-Role header
+func newPersistedQueryTestApp(t *testing.T, pqConfig config.GraphQLPersistedQueriesConfig) (*fiber.App, *graphqlPersistedQueries) {
+	t.Helper()
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{"_health": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return "ok", nil },
+			}},
+		}),
+	})
+	require.NoError(t, err)
+
+	g := NewGraphQLSchemaGenerator(nil, nil, false)
+	g.schema = &schema
+
+	store := newGraphQLPersistedQueries(nil, pqConfig.CacheSize)
+	store.loadRegistered = func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{graphqlDocumentHash(registeredTestQuery): registeredTestQuery}, nil
+	}
+
+	h := &GraphQLHandler{
+		schemaGenerator:  g,
+		config:           &config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000, PersistedQueries: pqConfig},
+		persistedQueries: store,
+	}
+
+	app := fiber.New()
+	app.Post("/graphql", func(c *fiber.Ctx) error {
+		if role := c.Get("X-Test-Role"); role != "" {
+			c.Locals("user_role", role)
+		}
+		return c.Next()
+	}, h.HandleGraphQL)
+	return app, store
+}
+
+// postGraphQL sends a request and returns the status and decoded response
+func postGraphQL(t *testing.T, app *fiber.App, role string, body map[string]interface{}) (int, GraphQLResponse) {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("X-Test-Role", role)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var result GraphQLResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, result
+}
+
+func persistedQueryExtension(hash string) map[string]interface{} {
+	return map[string]interface{}{
+		"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+	}
+}
+
+func TestHandleGraphQL_AutomaticPersistedQueries(t *testing.T) {
+	app, _ := newPersistedQueryTestApp(t, config.GraphQLPersistedQueriesConfig{Enabled: true, CacheSize: 10})
+	query := `{ _health }`
+	hash := graphqlDocumentHash(query)
+
+	// Unknown hash: the client is asked for the document
+	status, resp := postGraphQL(t, app, "", map[string]interface{}{"extensions": persistedQueryExtension(hash)})
+	assert.Equal(t, fiber.StatusOK, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "PersistedQueryNotFound", resp.Errors[0].Message)
+	assert.Equal(t, graphqlPersistedQueryNotFound, resp.Errors[0].Extensions["code"])
+
+	// Document and hash: executed and remembered
+	status, resp = postGraphQL(t, app, "", map[string]interface{}{"query": query, "extensions": persistedQueryExtension(hash)})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"_health": "ok"}, resp.Data)
+
+	// Hash only from now on
+	status, resp = postGraphQL(t, app, "", map[string]interface{}{"extensions": persistedQueryExtension(hash)})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"_health": "ok"}, resp.Data)
+
+	// Registered operations are known without being sent first
+	status, resp = postGraphQL(t, app, "", map[string]interface{}{"extensions": persistedQueryExtension(graphqlDocumentHash(registeredTestQuery))})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+}
+
+func TestHandleGraphQL_PersistedQueryErrors(t *testing.T) {
+	app, _ := newPersistedQueryTestApp(t, config.GraphQLPersistedQueriesConfig{Enabled: true, CacheSize: 10})
+
+	status, resp := postGraphQL(t, app, "", map[string]interface{}{
+		"query":      `{ _health }`,
+		"extensions": persistedQueryExtension(graphqlDocumentHash(`{ other }`)),
+	})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "provided sha does not match query", resp.Errors[0].Message)
+
+	status, resp = postGraphQL(t, app, "", map[string]interface{}{
+		"extensions": map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 2, "sha256Hash": graphqlDocumentHash(`{ _health }`)}},
+	})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, graphqlPersistedQueryInvalid, resp.Errors[0].Extensions["code"])
+
+	status, resp = postGraphQL(t, app, "", map[string]interface{}{"extensions": persistedQueryExtension("not-a-hash")})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "Invalid persisted query hash", resp.Errors[0].Message)
+
+	disabled, _ := newPersistedQueryTestApp(t, config.GraphQLPersistedQueriesConfig{})
+	status, resp = postGraphQL(t, disabled, "", map[string]interface{}{"extensions": persistedQueryExtension(graphqlDocumentHash(`{ _health }`))})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, graphqlPersistedQueryNotSupported, resp.Errors[0].Extensions["code"])
+}
+
+func TestHandleGraphQL_AllowlistOnly(t *testing.T) {
+	app, _ := newPersistedQueryTestApp(t, config.GraphQLPersistedQueriesConfig{Enabled: true, AllowlistOnly: true, CacheSize: 10})
+	adhoc := `{ _health }`
+
+	tests := []struct {
+		name       string
+		role       string
+		body       map[string]interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "registered document",
+			role:       "authenticated",
+			body:       map[string]interface{}{"query": registeredTestQuery},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "registered hash",
+			role:       "anon",
+			body:       map[string]interface{}{"extensions": persistedQueryExtension(graphqlDocumentHash(registeredTestQuery))},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "ad hoc document",
+			role:       "authenticated",
+			body:       map[string]interface{}{"query": adhoc},
+			wantStatus: fiber.StatusForbidden,
+			wantCode:   graphqlOperationNotAllowed,
+		},
+		{
+			name:       "ad hoc document with hash",
+			role:       "",
+			body:       map[string]interface{}{"query": adhoc, "extensions": persistedQueryExtension(graphqlDocumentHash(adhoc))},
+			wantStatus: fiber.StatusForbidden,
+			wantCode:   graphqlOperationNotAllowed,
+		},
+		{
+			name:       "unregistered hash",
+			role:       "authenticated",
+			body:       map[string]interface{}{"extensions": persistedQueryExtension(graphqlDocumentHash(adhoc))},
+			wantStatus: fiber.StatusOK,
+			wantCode:   graphqlPersistedQueryNotFound,
+		},
+		{
+			name:       "admin ad hoc document",
+			role:       "dashboard_admin",
+			body:       map[string]interface{}{"query": adhoc},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "service role ad hoc document",
+			role:       "service_role",
+			body:       map[string]interface{}{"query": adhoc},
+			wantStatus: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := postGraphQL(t, app, tt.role, tt.body)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantCode == "" {
+				assert.Empty(t, resp.Errors)
+				assert.Equal(t, map[string]interface{}{"_health": "ok"}, resp.Data)
+			} else {
+				require.Len(t, resp.Errors, 1)
+				assert.Equal(t, tt.wantCode, resp.Errors[0].Extensions["code"])
+			}
+		})
+	}
+}
+
+func TestGraphQLPersistedQueries_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := newGraphQLPersistedQueries(nil, 2)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		store.remember(fmt.Sprintf("hash%d", i), fmt.Sprintf("query%d", i))
+	}
+	_, ok := store.lookup(ctx, "hash1", false)
+	require.True(t, ok)
+
+	store.remember("hash3", "query3")
+
+	_, ok = store.lookup(ctx, "hash2", false)
+	assert.False(t, ok, "least recently used entry is evicted")
+	document, ok := store.lookup(ctx, "hash1", false)
+	assert.True(t, ok)
+	assert.Equal(t, "query1", document)
+	_, ok = store.lookup(ctx, "hash3", true)
+	assert.False(t, ok, "automatic persisted queries aren't registered")
+}
+
+func TestGraphQLPersistedQueries_RegisteredReload(t *testing.T) {
+	store := newGraphQLPersistedQueries(nil, 10)
+	ctx := context.Background()
+
+	loads := 0
+	registered := map[string]string{"a": "query a"}
+	var loadErr error
+	store.loadRegistered = func(ctx context.Context) (map[string]string, error) {
+		loads++
+		return registered, loadErr
+	}
+
+	assert.True(t, store.isRegistered(ctx, "a"))
+	assert.False(t, store.isRegistered(ctx, "b"))
+	assert.Equal(t, 1, loads, "registered operations are cached")
+
+	registered = map[string]string{"b": "query b"}
+	store.invalidateRegistered()
+	assert.True(t, store.isRegistered(ctx, "b"))
+	assert.Equal(t, 2, loads)
+
+	// A failed reload keeps the previous operations
+	loadErr = errors.New("connection refused")
+	registered = nil
+	store.invalidateRegistered()
+	assert.True(t, store.isRegistered(ctx, "b"))
+}
+
+func TestGraphQLDocumentName(t *testing.T) {
+	name, err := graphqlDocumentName(`fragment F on User { id } query GetUser { user { ...F } } query Other { _health }`)
+	require.NoError(t, err)
+	assert.Equal(t, "GetUser", name)
+
+	name, err = graphqlDocumentName(`{ _health }`)
+	require.NoError(t, err)
+	assert.Empty(t, name)
+
+	_, err = graphqlDocumentName(`fragment F on User { id }`)
+	assert.EqualError(t, err, "document contains no operation")
+
+	_, err = graphqlDocumentName(`query {`)
+	assert.Error(t, err)
+}
+
+func TestGraphQLDocumentHash(t *testing.T) {
+	// The hash Apollo clients send for { __typename }
+	assert.Equal(t, "ecf4edb46db40b5132295c0291d62fb65d6759a9eedfa4d5d612dd5ec54a6b38", graphqlDocumentHash("{__typename}"))
+}
@@ -15,8 +15,9 @@ import (
 
 // GraphQLResolverFactory creates resolvers for GraphQL queries and mutations
 type GraphQLResolverFactory struct {
-	db          *pgxpool.Pool
-	schemaCache *database.SchemaCache
+	db            *pgxpool.Pool
+	schemaCache   *database.SchemaCache
+	subscriptions *graphqlSubscriptionBroker // nil when subscriptions are disabled
 }
 
 // NewGraphQLResolverFactory creates a new resolver factory
@@ -42,6 +43,20 @@ type RLSContext struct {
 	Claims map[string]interface{}
 }
 
+// jwtClaims returns the JWT claims RLS policies are evaluated with
+func (r *RLSContext) jwtClaims() map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub":  r.UserID,
+		"role": r.Role, // Original application role for fine-grained policies
+	}
+
+	// Add additional claims if present
+	for k, v := range r.Claims {
+		claims[k] = v
+	}
+	return claims
+}
+
 // mapAppRoleToDatabaseRole maps application-level roles to database-level roles
 // This is a copy of the middleware function to avoid import cycles
 func mapAppRoleToDatabaseRole(appRole string) string {
@@ -154,6 +169,7 @@ func (g *GraphQLSchemaGenerator) execWithRLS(ctx context.Context, query string,
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	resetGraphQLLoaders(ctx)
 
 	return results, nil
 }
@@ -170,18 +186,7 @@ func setGraphQLRLSContext(ctx context.Context, tx pgx.Tx, rlsCtx *RLSContext) er
 		return fmt.Errorf("failed to SET LOCAL ROLE %s: %w", dbRole, err)
 	}
 
-	// Build JWT claims for RLS policies
-	jwtClaims := map[string]interface{}{
-		"sub":  rlsCtx.UserID,
-		"role": rlsCtx.Role, // Original application role for fine-grained policies
-	}
-
-	// Add additional claims if present
-	for k, v := range rlsCtx.Claims {
-		jwtClaims[k] = v
-	}
-
-	jwtClaimsJSON, err := json.Marshal(jwtClaims)
+	jwtClaimsJSON, err := json.Marshal(rlsCtx.jwtClaims())
 	if err != nil {
 		log.Error().Err(err).Msg("GraphQL: Failed to marshal JWT claims")
 		return fmt.Errorf("failed to marshal JWT claims: %w", err)
@@ -211,17 +216,24 @@ func (g *GraphQLSchemaGenerator) makeCollectionResolver(table database.TableInfo
 		qb := NewQueryBuilder(table.Schema, table.Name)
 
 		// Apply filters
+		var filters []Filter
 		if filter, ok := p.Args["filter"].(map[string]interface{}); ok && len(filter) > 0 {
-			filters := g.buildFiltersFromArgs(table, filter)
+			filters = g.buildFiltersFromArgs(table, filter)
 			qb.WithFilters(filters)
 		}
 
 		// Apply ordering
+		var orders []OrderBy
 		if orderBy, ok := p.Args["orderBy"].([]interface{}); ok && len(orderBy) > 0 {
-			orders := g.buildOrderFromArgs(table, orderBy)
+			orders = g.buildOrderFromArgs(table, orderBy)
 			qb.WithOrder(orders)
 		}
 
+		// Hidden and masked columns can't be filtered or sorted on
+		if err := g.checkProtectedColumns(ctx, table, filters, orders); err != nil {
+			return nil, err
+		}
+
 		// Apply limit
 		if limit, ok := p.Args["limit"].(int); ok {
 			qb.WithLimit(limit)
@@ -234,7 +246,14 @@ func (g *GraphQLSchemaGenerator) makeCollectionResolver(table database.TableInfo
 
 		// Build and execute query with RLS
 		sql, args := qb.BuildSelect()
-		return g.queryWithRLS(ctx, sql, args...)
+		results, err := g.queryWithRLS(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
+		return results, nil
 	}
 }
 
@@ -276,6 +295,9 @@ func (g *GraphQLSchemaGenerator) makeSingleResolver(table database.TableInfo) gr
 		if len(results) == 0 {
 			return nil, nil
 		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
 		return results[0], nil
 	}
 }
@@ -292,6 +314,9 @@ func (g *GraphQLSchemaGenerator) makeInsertResolver(table database.TableInfo) gr
 
 		// Convert GraphQL field names to database column names
 		dbData := g.graphqlToDBColumnNames(table, data)
+		if err := g.validateJSONColumns(ctx, table, []map[string]interface{}{dbData}, false); err != nil {
+			return nil, err
+		}
 
 		// Build the insert query
 		qb := NewQueryBuilder(table.Schema, table.Name)
@@ -307,6 +332,9 @@ func (g *GraphQLSchemaGenerator) makeInsertResolver(table database.TableInfo) gr
 		if len(results) == 0 {
 			return nil, nil
 		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
 		return results[0], nil
 	}
 }
@@ -321,6 +349,17 @@ func (g *GraphQLSchemaGenerator) makeInsertManyResolver(table database.TableInfo
 			return nil, fmt.Errorf("data array argument required")
 		}
 
+		// Validate every document before inserting any
+		records := make([]map[string]interface{}, len(dataArr))
+		for i, item := range dataArr {
+			if data, ok := item.(map[string]interface{}); ok {
+				records[i] = g.graphqlToDBColumnNames(table, data)
+			}
+		}
+		if err := g.validateJSONColumns(ctx, table, records, true); err != nil {
+			return nil, err
+		}
+
 		db := g.getDBFromContext(ctx)
 		if db == nil {
 			return nil, fmt.Errorf("database connection not available")
@@ -378,7 +417,11 @@ func (g *GraphQLSchemaGenerator) makeInsertManyResolver(table database.TableInfo
 		if err := tx.Commit(ctx); err != nil {
 			return nil, fmt.Errorf("failed to commit transaction: %w", err)
 		}
+		resetGraphQLLoaders(ctx)
 
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
 		return results, nil
 	}
 }
@@ -411,6 +454,9 @@ func (g *GraphQLSchemaGenerator) makeUpdateResolver(table database.TableInfo) gr
 		}
 
 		dbData := g.graphqlToDBColumnNames(table, data)
+		if err := g.validateJSONColumns(ctx, table, []map[string]interface{}{dbData}, false); err != nil {
+			return nil, err
+		}
 
 		qb := NewQueryBuilder(table.Schema, table.Name)
 		qb.WithFilters(filters)
@@ -426,6 +472,9 @@ func (g *GraphQLSchemaGenerator) makeUpdateResolver(table database.TableInfo) gr
 		if len(results) == 0 {
 			return nil, nil
 		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
 		return results[0], nil
 	}
 }
@@ -446,7 +495,13 @@ func (g *GraphQLSchemaGenerator) makeUpdateManyResolver(table database.TableInfo
 		}
 
 		dbData := g.graphqlToDBColumnNames(table, data)
+		if err := g.validateJSONColumns(ctx, table, []map[string]interface{}{dbData}, false); err != nil {
+			return nil, err
+		}
 		filters := g.buildFiltersFromArgs(table, filter)
+		if err := g.checkProtectedColumns(ctx, table, filters, nil); err != nil {
+			return nil, err
+		}
 
 		qb := NewQueryBuilder(table.Schema, table.Name)
 		qb.WithFilters(filters)
@@ -454,7 +509,14 @@ func (g *GraphQLSchemaGenerator) makeUpdateManyResolver(table database.TableInfo
 		sql, args := qb.BuildUpdate(dbData)
 
 		// Execute with RLS
-		return g.execWithRLS(ctx, sql, args...)
+		results, err := g.execWithRLS(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
+		return results, nil
 	}
 }
 
@@ -494,6 +556,9 @@ func (g *GraphQLSchemaGenerator) makeDeleteResolver(table database.TableInfo) gr
 		if len(results) == 0 {
 			return nil, nil
 		}
+		if err := g.applyColumnPolicies(ctx, table.Schema, table.Name, results); err != nil {
+			return nil, err
+		}
 		return results[0], nil
 	}
 }
@@ -509,6 +574,9 @@ func (g *GraphQLSchemaGenerator) makeDeleteManyResolver(table database.TableInfo
 		}
 
 		filters := g.buildFiltersFromArgs(table, filter)
+		if err := g.checkProtectedColumns(ctx, table, filters, nil); err != nil {
+			return nil, err
+		}
 
 		db := g.getDBFromContext(ctx)
 		if db == nil {
@@ -561,6 +629,7 @@ func (g *GraphQLSchemaGenerator) makeDeleteManyResolver(table database.TableInfo
 		if err := tx.Commit(ctx); err != nil {
 			return nil, fmt.Errorf("failed to commit transaction: %w", err)
 		}
+		resetGraphQLLoaders(ctx)
 
 		return count, nil
 	}
@@ -569,7 +638,15 @@ func (g *GraphQLSchemaGenerator) makeDeleteManyResolver(table database.TableInfo
 // makeForeignKeyResolver creates a resolver for fetching related records via foreign key
 // IMPORTANT: This resolver must enforce RLS when following foreign key relationships
 // to prevent unauthorized access via GraphQL traversals
+// Lookups are batched with those of the sibling records, see graphqlLoaders.
 func (g *GraphQLSchemaGenerator) makeForeignKeyResolver(table database.TableInfo, fk database.ForeignKey) graphql.FieldResolveFn {
+	rel := graphqlRelation{
+		schema: table.Schema,
+		table:  fk.ReferencedTable,
+		column: fk.ReferencedColumn,
+	}
+	cacheKey := fmt.Sprintf("%s.%s.%s", rel.schema, rel.table, rel.column)
+
 	return func(p graphql.ResolveParams) (interface{}, error) {
 		source, ok := p.Source.(map[string]interface{})
 		if !ok {
@@ -583,29 +660,18 @@ func (g *GraphQLSchemaGenerator) makeForeignKeyResolver(table database.TableInfo
 		}
 
 		ctx := p.Context
+		thunk := graphqlLoadersFromContext(ctx).load(ctx, cacheKey, rel, fkValue, g.fetchRelation)
 
-		// Query the related table
-		qb := NewQueryBuilder(table.Schema, fk.ReferencedTable)
-		qb.WithFilters([]Filter{{
-			Column:   fk.ReferencedColumn,
-			Operator: OpEqual,
-			Value:    fkValue,
-		}})
-		qb.WithLimit(1)
-
-		sql, args := qb.BuildSelect()
-
-		// Execute with RLS - this is critical for security
-		// Foreign key traversals must respect RLS policies
-		results, err := g.queryWithRLS(ctx, sql, args...)
-		if err != nil {
-			return nil, fmt.Errorf("query failed: %w", err)
-		}
-
-		if len(results) == 0 {
-			return nil, nil
-		}
-		return results[0], nil
+		return func() (interface{}, error) {
+			results, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			if len(results) == 0 {
+				return nil, nil
+			}
+			return results[0], nil
+		}, nil
 	}
 }
 
@@ -858,6 +924,19 @@ func (g *GraphQLSchemaGenerator) graphqlToDBColumnNames(table database.TableInfo
 	return result
 }
 
+// validateJSONColumns checks json column values against the table's current JSON Schemas.
+// Violations are reported in the error's extensions.
+func (g *GraphQLSchemaGenerator) validateJSONColumns(ctx context.Context, table database.TableInfo, records []map[string]interface{}, batch bool) error {
+	current, err := g.currentTableInfo(ctx, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	if verr := validateJSONColumns(current, records, batch); verr != nil {
+		return verr
+	}
+	return nil
+}
+
 // getDBFromContext gets the database connection from context
 func (g *GraphQLSchemaGenerator) getDBFromContext(ctx context.Context) *pgxpool.Pool {
 	if g.resolverFactory != nil {
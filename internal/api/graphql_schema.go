@@ -25,6 +25,10 @@ type GraphQLSchemaGenerator struct {
 	orderByTypes    map[string]*graphql.InputObject // "schema_table_order_by" -> GraphQL order by input type
 	introspectionOn bool
 	resolverFactory *GraphQLResolverFactory
+	procedures      graphqlProcedureInvoker        // nil when RPC procedures aren't exposed
+	rpcEnabled      func(ctx context.Context) bool // RPC feature flag, nil when always enabled
+	schemaRPC       bool                           // Whether the cached schema has procedure fields
+	federation      bool                           // Serve the schema as an Apollo Federation subgraph
 }
 
 // NewGraphQLSchemaGenerator creates a new schema generator
@@ -50,7 +54,8 @@ func (g *GraphQLSchemaGenerator) SetResolverFactory(factory *GraphQLResolverFact
 // GetSchema returns the current GraphQL schema, regenerating if needed
 func (g *GraphQLSchemaGenerator) GetSchema(ctx context.Context) (*graphql.Schema, error) {
 	g.mu.RLock()
-	if g.schema != nil {
+	// Procedure fields come and go with the RPC feature flag
+	if g.schema != nil && g.schemaRPC == g.proceduresEnabled(ctx) {
 		g.mu.RUnlock()
 		return g.schema, nil
 	}
@@ -139,6 +144,14 @@ func (g *GraphQLSchemaGenerator) regenerateSchema(ctx context.Context) (*graphql
 		g.orderByTypes[typeName+"OrderBy"] = g.generateOrderByType(table)
 	}
 
+	// Third pass - add reverse relations, which take the filter and order types of the referencing table
+	for _, table := range publicTables {
+		objType := g.objectTypes[g.tableToTypeName(table.Schema, table.Name)]
+		for name, field := range g.generateReverseRelationFields(table, publicTables) {
+			objType.AddFieldConfig(name, field)
+		}
+	}
+
 	// Build query fields
 	queryFields := graphql.Fields{}
 
@@ -183,6 +196,14 @@ func (g *GraphQLSchemaGenerator) regenerateSchema(ctx context.Context) (*graphql
 			Resolve: g.makeCollectionResolver(table),
 		}
 
+		// Relay connection (e.g., postsConnection) paged with keyset cursors
+		if connectionField := g.generateConnectionField(table, objType, filterType, orderByType); connectionField != nil {
+			queryFields[collectionName+"Connection"] = connectionField
+		}
+
+		// Aggregates (e.g., postsAggregate), optionally grouped
+		queryFields[collectionName+"Aggregate"] = g.generateAggregateField(table, filterType)
+
 		// Single record query by primary key (e.g., user, post)
 		if len(table.PrimaryKey) > 0 {
 			singleName := g.tableToSingleName(table.Name)
@@ -296,6 +317,92 @@ func (g *GraphQLSchemaGenerator) regenerateSchema(ctx context.Context) (*graphql
 		}
 	}
 
+	// RPC procedures and Postgres functions: reads are queries, writes mutations
+	g.schemaRPC = g.proceduresEnabled(ctx)
+	procedureQueries, procedureMutations := g.generateProcedureFields(ctx)
+	addCallableFields(queryFields, procedureQueries, "RPC procedure")
+	addCallableFields(mutationFields, procedureMutations, "RPC procedure")
+
+	if g.db != nil {
+		functions, err := database.NewSchemaInspector(g.db).GetAllFunctions(ctx, "public")
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to get functions for GraphQL schema")
+		} else {
+			functionQueries, functionMutations := g.generateFunctionFields(functions, publicTables)
+			addCallableFields(queryFields, functionQueries, "function")
+			addCallableFields(mutationFields, functionMutations, "function")
+		}
+	}
+
+	// Apollo Federation subgraph fields; tables with a primary key are entities
+	if g.federation {
+		for name, field := range g.generateFederationFields(g.federationEntities(publicTables)) {
+			queryFields[name] = field
+		}
+	}
+
+	// Build subscription fields, one per table, delivering its realtime change events
+	subscriptionFields := graphql.Fields{}
+	changeTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ChangeType",
+		Description: "Type of a record change",
+		Values: graphql.EnumValueConfigMap{
+			"INSERT": &graphql.EnumValueConfig{Value: "INSERT"},
+			"UPDATE": &graphql.EnumValueConfig{Value: "UPDATE"},
+			"DELETE": &graphql.EnumValueConfig{Value: "DELETE"},
+		},
+	})
+	for _, table := range publicTables {
+		if table.Type != "table" {
+			continue // Only tables emit change events
+		}
+
+		typeName := g.tableToTypeName(table.Schema, table.Name)
+		objType := g.objectTypes[typeName]
+		filterType := g.filterTypes[typeName+"Filter"]
+
+		changeType := graphql.NewObject(graphql.ObjectConfig{
+			Name:        typeName + "Change",
+			Description: fmt.Sprintf("A change to a %s record", table.Name),
+			Fields: graphql.Fields{
+				"type": &graphql.Field{
+					Type:        graphql.NewNonNull(changeTypeEnum),
+					Description: "Type of the change",
+				},
+				"record": &graphql.Field{
+					Type:        objType,
+					Description: "The record after the change (null for deletes)",
+				},
+				"oldRecord": &graphql.Field{
+					Type:        objType,
+					Description: "The record before the change (null for inserts)",
+				},
+			},
+		})
+
+		subscriptionName := g.tableToCollectionName(table.Name) + "Changes"
+		subscriptionFields[subscriptionName] = &graphql.Field{
+			Type:        changeType,
+			Description: fmt.Sprintf("Subscribe to changes of %s records", table.Name),
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{
+					Type:        filterType,
+					Description: "Filter conditions the changed record must match",
+				},
+				"events": &graphql.ArgumentConfig{
+					Type:        graphql.NewList(graphql.NewNonNull(changeTypeEnum)),
+					Description: "Types of changes to receive (default: all)",
+				},
+			},
+			Subscribe: g.makeSubscriptionResolver(table),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				// Each event is resolved with the operation's context, so relations are loaded afresh
+				resetGraphQLLoaders(p.Context)
+				return p.Source, nil
+			},
+		}
+	}
+
 	// Create schema config
 	schemaConfig := graphql.SchemaConfig{
 		Query: graphql.NewObject(graphql.ObjectConfig{
@@ -312,6 +419,14 @@ func (g *GraphQLSchemaGenerator) regenerateSchema(ctx context.Context) (*graphql
 		})
 	}
 
+	// Only add subscriptions if there are any
+	if len(subscriptionFields) > 0 {
+		schemaConfig.Subscription = graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: subscriptionFields,
+		})
+	}
+
 	// Create schema
 	schema, err := graphql.NewSchema(schemaConfig)
 	if err != nil {
@@ -360,6 +475,70 @@ func (g *GraphQLSchemaGenerator) generateTableFields(table database.TableInfo) g
 	return fields
 }
 
+// generateReverseRelationFields generates one-to-many fields for the foreign keys of other
+// tables referencing a table, e.g. "orders" on Customers for orders.customer_id. A table
+// referencing it through several foreign keys gets one field per key, e.g. "ordersByBillingCustomerId".
+func (g *GraphQLSchemaGenerator) generateReverseRelationFields(table database.TableInfo, tables []database.TableInfo) graphql.Fields {
+	// Names of the column and foreign key fields, which take precedence
+	taken := make(map[string]bool)
+	for _, col := range table.Columns {
+		taken[g.columnToFieldName(col.Name)] = true
+	}
+	for _, fk := range table.ForeignKeys {
+		if _, ok := g.objectTypes[g.tableToTypeName(table.Schema, fk.ReferencedTable)]; ok {
+			taken[g.fkToRelationName(fk.ColumnName)] = true
+		}
+	}
+
+	fields := graphql.Fields{}
+	for _, refTable := range tables {
+		if refTable.Schema != table.Schema {
+			continue
+		}
+
+		var fks []database.ForeignKey
+		for _, fk := range refTable.ForeignKeys {
+			if fk.ReferencedTable == table.Name {
+				fks = append(fks, fk)
+			}
+		}
+
+		refTypeName := g.tableToTypeName(refTable.Schema, refTable.Name)
+		for _, fk := range fks {
+			name := g.tableToCollectionName(refTable.Name)
+			if len(fks) > 1 {
+				name += "By" + toPascalCase(fk.ColumnName)
+			}
+			if taken[name] {
+				log.Debug().
+					Str("table", table.Name).
+					Str("field", name).
+					Msg("Skipping GraphQL reverse relation clashing with another field")
+				continue
+			}
+			taken[name] = true
+
+			fields[name] = &graphql.Field{
+				Type:        graphql.NewList(g.objectTypes[refTypeName]),
+				Description: fmt.Sprintf("Related %s records via %s", refTable.Name, fk.ColumnName),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{
+						Type:        g.filterTypes[refTypeName+"Filter"],
+						Description: "Filter conditions",
+					},
+					"orderBy": &graphql.ArgumentConfig{
+						Type:        graphql.NewList(g.orderByTypes[refTypeName+"OrderBy"]),
+						Description: "Sort order",
+					},
+				},
+				Resolve: g.makeReverseRelationResolver(refTable, fk),
+			}
+		}
+	}
+
+	return fields
+}
+
 // generateInputType generates a GraphQL input type for insert/update operations
 func (g *GraphQLSchemaGenerator) generateInputType(table database.TableInfo) *graphql.InputObject {
 	typeName := g.tableToTypeName(table.Schema, table.Name)
@@ -0,0 +1,730 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/auth"
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/realtime"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// graphqlWSProtocol is the WebSocket subprotocol of the graphql-ws protocol
+const graphqlWSProtocol = "graphql-transport-ws"
+
+// graphql-ws message types
+const (
+	graphqlWSConnectionInit = "connection_init"
+	graphqlWSConnectionAck  = "connection_ack"
+	graphqlWSPing           = "ping"
+	graphqlWSPong           = "pong"
+	graphqlWSSubscribe      = "subscribe"
+	graphqlWSNext           = "next"
+	graphqlWSError          = "error"
+	graphqlWSComplete       = "complete"
+)
+
+// graphql-ws close codes
+const (
+	graphqlWSCloseBadRequest         = 4400
+	graphqlWSCloseUnauthorized       = 4401
+	graphqlWSCloseForbidden          = 4403
+	graphqlWSCloseBadProtocol        = 4406
+	graphqlWSCloseInitTimeout        = 4408
+	graphqlWSCloseSubscriberExists   = 4409
+	graphqlWSCloseTooManyInitRequest = 4429
+)
+
+const (
+	// graphqlWSInitTimeout is how long a client has to send connection_init
+	graphqlWSInitTimeout = 10 * time.Second
+
+	// graphqlWSPingInterval is how often the server pings idle clients
+	graphqlWSPingInterval = 30 * time.Second
+
+	// graphqlSubscriptionBuffer is the number of change events buffered per subscription
+	// before events are dropped for a slow client
+	graphqlSubscriptionBuffer = 64
+
+	// graphqlChangeQueueSize is the number of change events queued for dispatch
+	graphqlChangeQueueSize = 1000
+)
+
+// graphqlWSMessage is a message of the graphql-ws protocol
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlSubscriptionBroker delivers realtime change events to GraphQL subscriptions. Events are
+// filtered with the RLS, column policy and event type checks of realtime subscriptions; the
+// subscription's filter argument is evaluated in SQL so it has the semantics of query filters.
+type graphqlSubscriptionBroker struct {
+	subManager *realtime.SubscriptionManager
+	db         *pgxpool.Pool
+	events     chan *realtime.ChangeEvent
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.RWMutex
+	subs map[string]map[*graphqlSubscription]bool // "schema.table" -> subscriptions
+}
+
+// graphqlSubscription is a subscription to the changes of a table
+type graphqlSubscription struct {
+	sub        *realtime.Subscription
+	events     map[string]bool // change types to deliver, all if empty
+	filterSQL  string          // query evaluating the filter against a record, "" without filter
+	filterArgs []interface{}
+	columns    []string // columns the filter reads
+	out        chan interface{}
+}
+
+// newGraphQLSubscriptionBroker creates a broker and starts dispatching change events
+func newGraphQLSubscriptionBroker(subManager *realtime.SubscriptionManager, db *pgxpool.Pool) *graphqlSubscriptionBroker {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &graphqlSubscriptionBroker{
+		subManager: subManager,
+		db:         db,
+		events:     make(chan *realtime.ChangeEvent, graphqlChangeQueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		subs:       make(map[string]map[*graphqlSubscription]bool),
+	}
+	go b.run()
+	return b
+}
+
+// ChangeReceived queues a change event for dispatch. It implements realtime.EventObserver.
+func (b *graphqlSubscriptionBroker) ChangeReceived(event *realtime.ChangeEvent) {
+	b.mu.RLock()
+	_, subscribed := b.subs[event.Schema+"."+event.Table]
+	b.mu.RUnlock()
+	if !subscribed {
+		return
+	}
+
+	select {
+	case b.events <- event:
+	default:
+		log.Warn().
+			Str("table", event.Schema+"."+event.Table).
+			Str("type", event.Type).
+			Msg("GraphQL subscription queue full, change event dropped")
+	}
+}
+
+// stop stops dispatching change events
+func (b *graphqlSubscriptionBroker) stop() {
+	b.cancel()
+}
+
+// run dispatches the queued change events until the broker is stopped
+func (b *graphqlSubscriptionBroker) run() {
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case event := <-b.events:
+			b.dispatch(event)
+		}
+	}
+}
+
+// dispatch delivers a change event to the subscriptions of its table that may see it
+func (b *graphqlSubscriptionBroker) dispatch(event *realtime.ChangeEvent) {
+	b.mu.RLock()
+	subs := make([]*graphqlSubscription, 0, len(b.subs[event.Schema+"."+event.Table]))
+	for s := range b.subs[event.Schema+"."+event.Table] {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if len(s.events) > 0 && !s.events[event.Type] {
+			continue
+		}
+
+		visible := b.subManager.VisibleEvent(b.ctx, s.sub, event)
+		if visible == nil {
+			continue
+		}
+
+		record := visible.Record
+		if record == nil {
+			record = visible.OldRecord
+		}
+		if !b.matchesFilter(s, record) {
+			continue
+		}
+
+		payload := map[string]interface{}{
+			"type":      visible.Type,
+			"record":    visible.Record,
+			"oldRecord": visible.OldRecord,
+		}
+
+		b.mu.RLock()
+		if b.subs[event.Schema+"."+event.Table][s] {
+			select {
+			case s.out <- payload:
+			default:
+				log.Warn().
+					Str("subscription_id", s.sub.ID).
+					Str("table", event.Schema+"."+event.Table).
+					Msg("GraphQL subscription buffer full, change event dropped")
+			}
+		}
+		b.mu.RUnlock()
+	}
+}
+
+// matchesFilter evaluates the subscription's filter against a changed record. Only the filtered
+// columns are populated, so values masked for the subscriber never have to parse as their
+// column type; filters on masked columns are rejected when subscribing.
+func (b *graphqlSubscriptionBroker) matchesFilter(s *graphqlSubscription, record map[string]interface{}) bool {
+	if s.filterSQL == "" {
+		return true
+	}
+	if b.db == nil || record == nil {
+		return false
+	}
+
+	values := make(map[string]interface{}, len(s.columns))
+	for _, column := range s.columns {
+		values[column] = record[column]
+	}
+	recordJSON, err := json.Marshal(values)
+	if err != nil {
+		return false
+	}
+
+	var matches bool
+	args := append([]interface{}{string(recordJSON)}, s.filterArgs...)
+	if err := b.db.QueryRow(b.ctx, s.filterSQL, args...).Scan(&matches); err != nil {
+		log.Warn().Err(err).Str("subscription_id", s.sub.ID).Msg("Failed to evaluate GraphQL subscription filter")
+		return false
+	}
+	return matches
+}
+
+// subscribe registers a subscription until ctx is done
+func (b *graphqlSubscriptionBroker) subscribe(ctx context.Context, s *graphqlSubscription) {
+	key := s.sub.Schema + "." + s.sub.Table
+
+	b.mu.Lock()
+	if b.subs[key] == nil {
+		b.subs[key] = make(map[*graphqlSubscription]bool)
+	}
+	b.subs[key][s] = true
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs[key], s)
+		if len(b.subs[key]) == 0 {
+			delete(b.subs, key)
+		}
+		b.mu.Unlock()
+	}()
+}
+
+// buildChangeFilterSQL builds the query evaluating filters against a record passed as JSON in $1
+func buildChangeFilterSQL(schema, table string, filters []Filter) (string, []interface{}) {
+	qb := NewQueryBuilder(schema, table).WithFilters(filters)
+	qb.argCounter = 2
+	where, args := qb.buildWhereClause()
+	if where == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM jsonb_populate_record(NULL::%s.%s, $1::jsonb) WHERE %s)",
+		quoteIdentifier(schema), quoteIdentifier(table), where), args
+}
+
+// makeSubscriptionResolver creates the subscribe resolver of a table's change subscription. The
+// subscription is registered with the caller's RLS context until the operation completes.
+func (g *GraphQLSchemaGenerator) makeSubscriptionResolver(table database.TableInfo) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx := p.Context
+
+		var broker *graphqlSubscriptionBroker
+		if g.resolverFactory != nil {
+			broker = g.resolverFactory.subscriptions
+		}
+		if broker == nil {
+			return nil, fmt.Errorf("subscriptions are not enabled")
+		}
+		if !broker.subManager.IsTableEnabled(table.Schema, table.Name) {
+			return nil, fmt.Errorf("table %s.%s is not enabled for realtime", table.Schema, table.Name)
+		}
+
+		var filters []Filter
+		if filter, ok := p.Args["filter"].(map[string]interface{}); ok && len(filter) > 0 {
+			filters = g.buildFiltersFromArgs(table, filter)
+		}
+
+		// Hidden and masked columns can't be filtered on
+		if err := g.checkProtectedColumns(ctx, table, filters, nil); err != nil {
+			return nil, err
+		}
+
+		events := make(map[string]bool)
+		if list, ok := p.Args["events"].([]interface{}); ok {
+			for _, e := range list {
+				if eventType, ok := e.(string); ok {
+					events[eventType] = true
+				}
+			}
+		}
+
+		// Change events are checked with the subscriber's role and claims, like realtime subscriptions
+		sub := &realtime.Subscription{
+			ID:     uuid.New().String(),
+			Role:   "anon",
+			Schema: table.Schema,
+			Table:  table.Name,
+			Event:  "*",
+		}
+		if rlsCtx, ok := ctx.Value(GraphQLRLSContextKey).(*RLSContext); ok && rlsCtx != nil {
+			sub.UserID = rlsCtx.UserID
+			if rlsCtx.Role != "" {
+				sub.Role = rlsCtx.Role
+			}
+			sub.Claims = rlsCtx.jwtClaims()
+		}
+
+		s := &graphqlSubscription{
+			sub:    sub,
+			events: events,
+			out:    make(chan interface{}, graphqlSubscriptionBuffer),
+		}
+		s.filterSQL, s.filterArgs = buildChangeFilterSQL(table.Schema, table.Name, filters)
+		for _, f := range filters {
+			s.columns = append(s.columns, f.Column)
+		}
+
+		broker.subscribe(ctx, s)
+		return s.out, nil
+	}
+}
+
+// EnableSubscriptions enables GraphQL subscriptions fed by the realtime change events. The
+// handler must then be registered as an event observer of the realtime listener.
+func (h *GraphQLHandler) EnableSubscriptions(subManager *realtime.SubscriptionManager, authService realtime.AuthService) {
+	h.subscriptions = newGraphQLSubscriptionBroker(subManager, h.resolverFactory.db)
+	h.subscriptionAuth = authService
+	h.resolverFactory.subscriptions = h.subscriptions
+}
+
+// ChangeReceived passes realtime change events to the subscriptions. It implements
+// realtime.EventObserver.
+func (h *GraphQLHandler) ChangeReceived(event *realtime.ChangeEvent) {
+	if h.subscriptions != nil {
+		h.subscriptions.ChangeReceived(event)
+	}
+}
+
+// Shutdown stops delivering change events to subscriptions
+func (h *GraphQLHandler) Shutdown() {
+	if h.subscriptions != nil {
+		h.subscriptions.stop()
+	}
+}
+
+// HandleWebSocket handles GraphQL WebSocket connections using the graphql-ws protocol
+func (h *GraphQLHandler) HandleWebSocket(c *fiber.Ctx) error {
+	if h.subscriptions == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(GraphQLResponse{
+			Errors: []GraphQLError{{
+				Message: "GraphQL subscriptions are not enabled",
+			}},
+		})
+	}
+
+	// Credentials validated by the auth middleware apply until connection_init provides a token
+	c.Locals("graphql_rls_context", upgradeRLSContext(c))
+	c.Locals("graphql_key_scopes", graphqlRequestKeyScopes(c))
+
+	return websocket.New(h.handleWebSocketConnection, websocket.Config{
+		Subprotocols: []string{graphqlWSProtocol},
+	})(c)
+}
+
+// upgradeRLSContext returns the RLS context of the credentials the auth middleware validated
+func upgradeRLSContext(c *fiber.Ctx) *RLSContext {
+	role, _ := c.Locals("rls_role").(string)
+	if role == "" {
+		return nil
+	}
+	userID, _ := c.Locals("rls_user_id").(string)
+
+	rlsCtx := &RLSContext{
+		UserID: userID,
+		Role:   role,
+		Claims: make(map[string]interface{}),
+	}
+	if claims, ok := c.Locals("jwt_claims").(*auth.TokenClaims); ok && claims != nil {
+		for k, v := range claims.RawClaims {
+			rlsCtx.Claims[k] = v
+		}
+	}
+	return rlsCtx
+}
+
+// graphqlWSSession is a graphql-ws connection
+type graphqlWSSession struct {
+	handler *GraphQLHandler
+	conn    *websocket.Conn
+
+	ctx    context.Context // cancelled when the connection closes
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+	closed  bool
+
+	mu           sync.Mutex
+	rlsCtx       *RLSContext      // credentials operations run with, nil for anonymous access
+	keyScopes    graphqlKeyScopes // scopes of the key the connection was upgraded with, nil for unscoped credentials
+	initReceived bool
+	acknowledged bool
+	operations   map[string]context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// handleWebSocketConnection runs a graphql-ws connection
+func (h *GraphQLHandler) handleWebSocketConnection(conn *websocket.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &graphqlWSSession{
+		handler:    h,
+		conn:       conn,
+		ctx:        ctx,
+		cancel:     cancel,
+		operations: make(map[string]context.CancelFunc),
+	}
+	// The connection is released when this returns, so wait for the operations to stop writing
+	defer func() {
+		s.cancel()
+		s.wg.Wait()
+		s.writeMu.Lock()
+		s.closed = true
+		s.writeMu.Unlock()
+	}()
+
+	if conn.Subprotocol() != graphqlWSProtocol {
+		s.close(graphqlWSCloseBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+
+	if rlsCtx, ok := conn.Locals("graphql_rls_context").(*RLSContext); ok && rlsCtx != nil {
+		s.rlsCtx = rlsCtx
+	}
+	s.keyScopes, _ = conn.Locals("graphql_key_scopes").(graphqlKeyScopes)
+
+	s.wg.Add(1)
+	go s.ping()
+
+	_ = conn.SetReadDeadline(time.Now().Add(graphqlWSInitTimeout))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				s.close(graphqlWSCloseInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		var msg graphqlWSMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			s.close(graphqlWSCloseBadRequest, "Invalid message received")
+			return
+		}
+
+		if code, reason := s.handleMessage(msg); code != 0 {
+			s.close(code, reason)
+			return
+		}
+	}
+}
+
+// handleMessage handles a client message. Returns the close code and reason if the message
+// terminates the connection.
+func (s *graphqlWSSession) handleMessage(msg graphqlWSMessage) (int, string) {
+	switch msg.Type {
+	case graphqlWSConnectionInit:
+		s.mu.Lock()
+		if s.initReceived {
+			s.mu.Unlock()
+			return graphqlWSCloseTooManyInitRequest, "Too many initialisation requests"
+		}
+		s.initReceived = true
+		s.mu.Unlock()
+
+		if err := s.authenticate(msg.Payload); err != nil {
+			log.Debug().Err(err).Msg("GraphQL WebSocket authentication failed")
+			return graphqlWSCloseForbidden, "Forbidden"
+		}
+
+		s.mu.Lock()
+		s.acknowledged = true
+		s.mu.Unlock()
+		_ = s.conn.SetReadDeadline(time.Time{})
+		s.send(graphqlWSMessage{Type: graphqlWSConnectionAck})
+
+	case graphqlWSPing:
+		s.send(graphqlWSMessage{Type: graphqlWSPong})
+
+	case graphqlWSPong:
+		// Response to our ping, nothing to do
+
+	case graphqlWSSubscribe:
+		s.mu.Lock()
+		acknowledged := s.acknowledged
+		_, exists := s.operations[msg.ID]
+		s.mu.Unlock()
+
+		if !acknowledged {
+			return graphqlWSCloseUnauthorized, "Unauthorized"
+		}
+		if msg.ID == "" {
+			return graphqlWSCloseBadRequest, "Subscribe message requires an id"
+		}
+		if exists {
+			return graphqlWSCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID)
+		}
+
+		var req GraphQLRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil || (req.Query == "" && req.persistedQuery() == nil) {
+			return graphqlWSCloseBadRequest, "Invalid subscribe payload"
+		}
+		s.startOperation(msg.ID, req)
+
+	case graphqlWSComplete:
+		s.mu.Lock()
+		if cancel, ok := s.operations[msg.ID]; ok {
+			cancel()
+			delete(s.operations, msg.ID)
+		}
+		s.mu.Unlock()
+
+	default:
+		return graphqlWSCloseBadRequest, fmt.Sprintf("Unexpected message of type %s received", msg.Type)
+	}
+
+	return 0, ""
+}
+
+// authenticate switches the connection to the credentials of the connection_init payload, if it
+// has any. The token is read from the authorization, token or access_token field.
+func (s *graphqlWSSession) authenticate(payload json.RawMessage) error {
+	var params map[string]interface{}
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return fmt.Errorf("invalid connection_init payload: %w", err)
+		}
+	}
+
+	var token string
+	for _, key := range []string{"authorization", "Authorization", "token", "access_token"} {
+		if value, ok := params[key].(string); ok && value != "" {
+			token = strings.TrimPrefix(value, "Bearer ")
+			break
+		}
+	}
+	if token == "" {
+		return nil
+	}
+	if s.handler.subscriptionAuth == nil {
+		return fmt.Errorf("token authentication is not available")
+	}
+
+	claims, err := s.handler.subscriptionAuth.ValidateToken(token)
+	if err != nil {
+		return err
+	}
+
+	rlsCtx := &RLSContext{
+		UserID: claims.UserID,
+		Role:   claims.Role,
+		Claims: make(map[string]interface{}),
+	}
+	if rlsCtx.Role == "" {
+		rlsCtx.Role = "authenticated"
+	}
+	for k, v := range claims.RawClaims {
+		rlsCtx.Claims[k] = v
+	}
+
+	s.mu.Lock()
+	s.rlsCtx = rlsCtx
+	s.keyScopes = nil // Tokens aren't scoped
+	s.mu.Unlock()
+	return nil
+}
+
+// startOperation executes an operation in the background. Subscriptions send a next message per
+// change event; queries and mutations send a single one.
+func (s *graphqlWSSession) startOperation(id string, req GraphQLRequest) {
+	role := "anon"
+	s.mu.Lock()
+	if s.rlsCtx != nil {
+		role = s.rlsCtx.Role
+	}
+	s.mu.Unlock()
+	if gqlErr, _ := s.handler.resolvePersistedQuery(s.ctx, &req, role); gqlErr != nil {
+		s.sendErrors(id, []GraphQLError{*gqlErr})
+		return
+	}
+
+	if msg := s.handler.checkQueryLimits(req.Query); msg != "" {
+		s.sendErrors(id, []GraphQLError{{Message: msg}})
+		return
+	}
+
+	schema, err := s.handler.schemaGenerator.GetSchema(s.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get GraphQL schema")
+		s.sendErrors(id, []GraphQLError{{Message: "Failed to initialize GraphQL schema"}})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	if s.rlsCtx != nil {
+		ctx = context.WithValue(ctx, GraphQLRLSContextKey, s.rlsCtx)
+	}
+	ctx = withGraphQLKeyScopes(ctx, s.keyScopes)
+	s.operations[id] = cancel
+	s.mu.Unlock()
+	ctx = withGraphQLLoaders(ctx)
+
+	params := graphql.Params{
+		Schema:         *schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		if graphqlOperationType(req.Query, req.OperationName) == ast.OperationTypeSubscription {
+			delivered := false
+			// The results channel must be drained until closed, even once the operation is cancelled
+			for result := range graphql.Subscribe(params) {
+				if ctx.Err() != nil {
+					continue
+				}
+				if !delivered && result.Data == nil && len(result.Errors) > 0 {
+					// The subscription failed to start, which the error message terminates
+					s.finishOperation(id)
+					s.sendErrors(id, convertErrors(result.Errors))
+					return
+				}
+				delivered = true
+				s.sendResult(id, result)
+			}
+		} else {
+			result := graphql.Do(params)
+			if ctx.Err() == nil {
+				s.sendResult(id, result)
+			}
+		}
+
+		// Operations completed by the client or ended by the connection closing aren't completed again
+		if ctx.Err() == nil && s.finishOperation(id) {
+			s.send(graphqlWSMessage{ID: id, Type: graphqlWSComplete})
+		}
+	}()
+}
+
+// finishOperation removes an operation. Returns false if it was already removed.
+func (s *graphqlWSSession) finishOperation(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.operations[id]; !ok {
+		return false
+	}
+	delete(s.operations, id)
+	return true
+}
+
+// sendResult sends an execution result as a next message
+func (s *graphqlWSSession) sendResult(id string, result *graphql.Result) {
+	payload, err := json.Marshal(GraphQLResponse{
+		Data:   result.Data,
+		Errors: convertErrors(result.Errors),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("operation_id", id).Msg("Failed to encode GraphQL result")
+		return
+	}
+	s.send(graphqlWSMessage{ID: id, Type: graphqlWSNext, Payload: payload})
+}
+
+// sendErrors sends an error message, which terminates the operation
+func (s *graphqlWSSession) sendErrors(id string, errs []GraphQLError) {
+	payload, err := json.Marshal(errs)
+	if err != nil {
+		return
+	}
+	s.send(graphqlWSMessage{ID: id, Type: graphqlWSError, Payload: payload})
+}
+
+// ping pings the client periodically, keeping idle connections open through proxies
+func (s *graphqlWSSession) ping() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(graphqlWSPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.send(graphqlWSMessage{Type: graphqlWSPing})
+		}
+	}
+}
+
+// send writes a message to the client
+func (s *graphqlWSSession) send(msg graphqlWSMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closed {
+		return
+	}
+	if err := s.conn.WriteJSON(msg); err != nil {
+		log.Debug().Err(err).Str("type", msg.Type).Msg("Failed to write GraphQL WebSocket message")
+	}
+}
+
+// close closes the connection with a graphql-ws close code
+func (s *graphqlWSSession) close(code int, reason string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/realtime"
+	"github.com/fluxbase-eu/fluxbase/internal/testutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var subscriptionTestTable = database.TableInfo{
+	Schema: "public",
+	Name:   "posts",
+	Type:   "table",
+	Columns: []database.ColumnInfo{
+		{Name: "id", DataType: "integer", IsPrimaryKey: true},
+		{Name: "title", DataType: "text", IsNullable: true},
+	},
+	PrimaryKey: []string{"id"},
+}
+
+// newSubscriptionTestHandler returns a GraphQL handler with subscriptions enabled for the posts
+// table, whose schema is built without a database
+func newSubscriptionTestHandler(t *testing.T, mockDB *testutil.MockSubscriptionDB) *GraphQLHandler {
+	t.Helper()
+
+	g := NewGraphQLSchemaGenerator(nil, nil, true)
+	g.SetResolverFactory(&GraphQLResolverFactory{})
+	h := &GraphQLHandler{
+		schemaGenerator: g,
+		config:          &config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000},
+		resolverFactory: g.resolverFactory,
+	}
+	h.EnableSubscriptions(realtime.NewSubscriptionManager(mockDB), nil)
+	t.Cleanup(h.Shutdown)
+
+	postType := graphql.NewObject(graphql.ObjectConfig{Name: "Posts", Fields: g.generateTableFields(subscriptionTestTable)})
+	changeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostsChange",
+		Fields: graphql.Fields{
+			"type":      &graphql.Field{Type: graphql.String},
+			"record":    &graphql.Field{Type: postType},
+			"oldRecord": &graphql.Field{Type: postType},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{"_health": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return "ok", nil },
+			}},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{"postsChanges": &graphql.Field{
+				Type: changeType,
+				Args: graphql.FieldConfigArgument{
+					"events": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+				},
+				Subscribe: g.makeSubscriptionResolver(subscriptionTestTable),
+				Resolve:   func(p graphql.ResolveParams) (interface{}, error) { return p.Source, nil },
+			}},
+		}),
+	})
+	require.NoError(t, err)
+	g.schema = &schema
+
+	return h
+}
+
+// dialGraphQLWS serves the handler and opens a WebSocket connection to it
+func dialGraphQLWS(t *testing.T, h *GraphQLHandler, subprotocols ...string) *websocket.Conn {
+	t.Helper()
+
+	app := fiber.New()
+	app.Get("/graphql", h.HandleWebSocket)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	dialer := websocket.Dialer{Subprotocols: subprotocols, HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial("ws://"+ln.Addr().String()+"/graphql", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readGraphQLWS(t *testing.T, conn *websocket.Conn) graphqlWSMessage {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var msg graphqlWSMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func expectGraphQLWSClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, code, closeErr.Code)
+}
+
+func subscriptionCount(h *GraphQLHandler) int {
+	h.subscriptions.mu.RLock()
+	defer h.subscriptions.mu.RUnlock()
+	return len(h.subscriptions.subs["public.posts"])
+}
+
+func TestGraphQLSubscriptions_DeliversVisibleChanges(t *testing.T) {
+	mockDB := testutil.NewMockSubscriptionDB()
+	mockDB.EnableTable("public", "posts")
+	mockDB.RLSResults["public.posts.2"] = false
+	h := newSubscriptionTestHandler(t, mockDB)
+	conn := dialGraphQLWS(t, h, graphqlWSProtocol)
+
+	require.NoError(t, conn.WriteJSON(graphqlWSMessage{Type: graphqlWSConnectionInit}))
+	assert.Equal(t, graphqlWSConnectionAck, readGraphQLWS(t, conn).Type)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":      "1",
+		"type":    graphqlWSSubscribe,
+		"payload": map[string]interface{}{"query": `subscription { postsChanges(events: ["INSERT"]) { type record { id title } } }`},
+	}))
+	require.Eventually(t, func() bool { return subscriptionCount(h) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Updates aren't subscribed to and record 2 is hidden by RLS
+	h.ChangeReceived(&realtime.ChangeEvent{Type: "UPDATE", Schema: "public", Table: "posts", Record: map[string]interface{}{"id": float64(1), "title": "updated"}})
+	h.ChangeReceived(&realtime.ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts", Record: map[string]interface{}{"id": float64(2), "title": "hidden"}})
+	h.ChangeReceived(&realtime.ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts", Record: map[string]interface{}{"id": float64(3), "title": "hello"}})
+
+	msg := readGraphQLWS(t, conn)
+	require.Equal(t, graphqlWSNext, msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.JSONEq(t, `{"data":{"postsChanges":{"type":"INSERT","record":{"id":3,"title":"hello"}}}}`, string(msg.Payload))
+
+	require.NoError(t, conn.WriteJSON(graphqlWSMessage{ID: "1", Type: graphqlWSComplete}))
+	require.Eventually(t, func() bool { return subscriptionCount(h) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGraphQLSubscriptions_Queries(t *testing.T) {
+	mockDB := testutil.NewMockSubscriptionDB()
+	h := newSubscriptionTestHandler(t, mockDB)
+	conn := dialGraphQLWS(t, h, graphqlWSProtocol)
+
+	require.NoError(t, conn.WriteJSON(graphqlWSMessage{Type: graphqlWSConnectionInit}))
+	assert.Equal(t, graphqlWSConnectionAck, readGraphQLWS(t, conn).Type)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":      "q",
+		"type":    graphqlWSSubscribe,
+		"payload": map[string]interface{}{"query": `{ _health }`},
+	}))
+	msg := readGraphQLWS(t, conn)
+	require.Equal(t, graphqlWSNext, msg.Type)
+	assert.JSONEq(t, `{"data":{"_health":"ok"}}`, string(msg.Payload))
+	assert.Equal(t, graphqlWSComplete, readGraphQLWS(t, conn).Type)
+
+	// Tables that aren't enabled for realtime can't be subscribed to
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":      "s",
+		"type":    graphqlWSSubscribe,
+		"payload": map[string]interface{}{"query": `subscription { postsChanges { type } }`},
+	}))
+	msg = readGraphQLWS(t, conn)
+	require.Equal(t, graphqlWSError, msg.Type)
+	var errs []GraphQLError
+	require.NoError(t, json.Unmarshal(msg.Payload, &errs))
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "not enabled for realtime")
+}
+
+func TestGraphQLSubscriptions_ProtocolErrors(t *testing.T) {
+	t.Run("subscribe before connection_init", func(t *testing.T) {
+		conn := dialGraphQLWS(t, newSubscriptionTestHandler(t, testutil.NewMockSubscriptionDB()), graphqlWSProtocol)
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{
+			"id":      "1",
+			"type":    graphqlWSSubscribe,
+			"payload": map[string]interface{}{"query": `{ _health }`},
+		}))
+		expectGraphQLWSClose(t, conn, graphqlWSCloseUnauthorized)
+	})
+
+	t.Run("repeated connection_init", func(t *testing.T) {
+		conn := dialGraphQLWS(t, newSubscriptionTestHandler(t, testutil.NewMockSubscriptionDB()), graphqlWSProtocol)
+		require.NoError(t, conn.WriteJSON(graphqlWSMessage{Type: graphqlWSConnectionInit}))
+		assert.Equal(t, graphqlWSConnectionAck, readGraphQLWS(t, conn).Type)
+		require.NoError(t, conn.WriteJSON(graphqlWSMessage{Type: graphqlWSConnectionInit}))
+		expectGraphQLWSClose(t, conn, graphqlWSCloseTooManyInitRequest)
+	})
+
+	t.Run("token without auth service", func(t *testing.T) {
+		conn := dialGraphQLWS(t, newSubscriptionTestHandler(t, testutil.NewMockSubscriptionDB()), graphqlWSProtocol)
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{
+			"type":    graphqlWSConnectionInit,
+			"payload": map[string]interface{}{"authorization": "Bearer token"},
+		}))
+		expectGraphQLWSClose(t, conn, graphqlWSCloseForbidden)
+	})
+
+	t.Run("missing subprotocol", func(t *testing.T) {
+		conn := dialGraphQLWS(t, newSubscriptionTestHandler(t, testutil.NewMockSubscriptionDB()))
+		expectGraphQLWSClose(t, conn, graphqlWSCloseBadProtocol)
+	})
+}
+
+func TestGraphQLOperationType(t *testing.T) {
+	assert.Equal(t, "query", graphqlOperationType(`{ posts { id } }`, ""))
+	assert.Equal(t, "subscription", graphqlOperationType(`subscription { postsChanges { type } }`, ""))
+	assert.Equal(t, "mutation", graphqlOperationType(`query A { posts { id } } mutation B { deletePosts(id: 1) { id } }`, "B"))
+	assert.Equal(t, "", graphqlOperationType(`{`, ""))
+}
+
+func TestBuildChangeFilterSQL(t *testing.T) {
+	sql, args := buildChangeFilterSQL("public", "posts", []Filter{
+		{Column: "title", Operator: OpILike, Value: "%go%"},
+		{Column: "id", Operator: OpGreaterThan, Value: 10, OrGroupID: 1},
+		{Column: "id", Operator: OpEqual, Value: 1, OrGroupID: 1},
+	})
+	assert.Equal(t, `SELECT EXISTS (SELECT 1 FROM jsonb_populate_record(NULL::"public"."posts", $1::jsonb) WHERE "title" ILIKE $2 AND ("id" > $3 OR "id" = $4))`, sql)
+	assert.Equal(t, []interface{}{"%go%", 10, 1}, args)
+
+	sql, args = buildChangeFilterSQL("public", "posts", nil)
+	assert.Empty(t, sql)
+	assert.Nil(t, args)
+}
+
+func TestGraphQLSubscriptionBroker_UnsubscribesWithContext(t *testing.T) {
+	b := newGraphQLSubscriptionBroker(realtime.NewSubscriptionManager(nil), nil)
+	defer b.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &graphqlSubscription{
+		sub: &realtime.Subscription{ID: "s1", Schema: "public", Table: "posts", Event: "*"},
+		out: make(chan interface{}, 1),
+	}
+	b.subscribe(ctx, s)
+
+	b.dispatch(&realtime.ChangeEvent{Type: "DELETE", Schema: "public", Table: "posts", OldRecord: map[string]interface{}{"id": 1}})
+	payload := <-s.out
+	assert.Equal(t, map[string]interface{}{"type": "DELETE", "record": map[string]interface{}(nil), "oldRecord": map[string]interface{}{"id": 1}}, payload)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+		return len(b.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
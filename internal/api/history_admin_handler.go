@@ -0,0 +1,530 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// HistoryAdminHandler handles row history enablement for user tables
+type HistoryAdminHandler struct {
+	db *database.Connection
+}
+
+// NewHistoryAdminHandler creates a new row history admin handler
+func NewHistoryAdminHandler(db *database.Connection) *HistoryAdminHandler {
+	return &HistoryAdminHandler{db: db}
+}
+
+// EnableHistoryRequest represents a request to enable row history on a table
+type EnableHistoryRequest struct {
+	Schema  string   `json:"schema"`
+	Table   string   `json:"table"`
+	Exclude []string `json:"exclude,omitempty"` // Columns to omit from recorded values
+}
+
+// EnableHistoryResponse represents the response after enabling row history
+type EnableHistoryResponse struct {
+	Schema      string   `json:"schema"`
+	Table       string   `json:"table"`
+	TriggerName string   `json:"trigger_name"`
+	PrimaryKey  []string `json:"primary_key"`
+	Exclude     []string `json:"exclude,omitempty"`
+	EnabledAt   string   `json:"enabled_at"`
+}
+
+// HistoryTableStatus represents the status of a history-enabled table
+type HistoryTableStatus struct {
+	ID              int      `json:"id"`
+	Schema          string   `json:"schema"`
+	Table           string   `json:"table"`
+	HistoryEnabled  bool     `json:"history_enabled"`
+	PrimaryKey      []string `json:"primary_key"`
+	ExcludedColumns []string `json:"excluded_columns,omitempty"`
+	EnabledAt       string   `json:"enabled_at,omitempty"`
+	CreatedAt       string   `json:"created_at,omitempty"`
+	UpdatedAt       string   `json:"updated_at,omitempty"`
+}
+
+// historySystemSchemas are schemas whose tables can't have row history
+var historySystemSchemas = map[string]bool{
+	"pg_catalog":         true,
+	"information_schema": true,
+	"dashboard":          true,
+	"auth":               true,
+	"realtime":           true,
+	"api":                true, // the history itself lives here
+}
+
+// historyTriggerName returns the name of the history trigger on a table
+func historyTriggerName(table string) string {
+	return fmt.Sprintf("%s_row_history", table)
+}
+
+// validateHistoryExclude checks excluded columns; primary key columns identify rows and can't be excluded
+func validateHistoryExclude(exclude, primaryKey []string) error {
+	for _, col := range exclude {
+		if err := validateIdentifier(col, "column"); err != nil {
+			return fmt.Errorf("invalid excluded column: %w", err)
+		}
+		for _, pk := range primaryKey {
+			if col == pk {
+				return fmt.Errorf("primary key column '%s' cannot be excluded", col)
+			}
+		}
+	}
+	return nil
+}
+
+// HandleEnableHistory enables row history on a table.
+// Existing rows are recorded as SNAPSHOT entries so as_of reads are complete from the moment history is enabled.
+func (h *HistoryAdminHandler) HandleEnableHistory(c *fiber.Ctx) error {
+	var req EnableHistoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Default schema to public
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+
+	if err := validateIdentifier(req.Schema, "schema"); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.Table == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Table name is required",
+		})
+	}
+	if err := validateIdentifier(req.Table, "table"); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if historySystemSchemas[req.Schema] {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Cannot enable row history on system schema '%s'", req.Schema),
+		})
+	}
+	if req.Exclude == nil {
+		req.Exclude = []string{}
+	}
+
+	ctx := c.Context()
+
+	exists, err := h.tableExists(ctx, req.Schema, req.Table)
+	if err != nil {
+		log.Error().Err(err).Str("table", req.Schema+"."+req.Table).Msg("Failed to check table existence")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check table existence",
+		})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' does not exist", req.Schema, req.Table),
+		})
+	}
+
+	primaryKey, err := h.primaryKey(ctx, req.Schema, req.Table)
+	if err != nil {
+		log.Error().Err(err).Str("table", req.Schema+"."+req.Table).Msg("Failed to look up primary key")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to look up primary key",
+		})
+	}
+	if len(primaryKey) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' has no primary key; row history needs one to identify rows", req.Schema, req.Table),
+		})
+	}
+	if err := validateHistoryExclude(req.Exclude, primaryKey); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	triggerName := historyTriggerName(req.Table)
+	var enabledAt interface{}
+
+	// Execute all DDL in a transaction with admin role
+	err = h.db.ExecuteWithAdminRole(ctx, func(conn *pgx.Conn) error {
+		tx, txErr := conn.Begin(ctx)
+		if txErr != nil {
+			return fmt.Errorf("failed to begin transaction: %w", txErr)
+		}
+		defer tx.Rollback(ctx) //nolint:errcheck
+
+		// 1. Check whether history is already being recorded (re-enabling only updates settings)
+		var alreadyEnabled bool
+		checkErr := tx.QueryRow(ctx, `
+SELECT history_enabled FROM api.history_registry
+WHERE schema_name = $1 AND table_name = $2
+FOR UPDATE`, req.Schema, req.Table).Scan(&alreadyEnabled)
+		if checkErr != nil && !errors.Is(checkErr, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to read history registry: %w", checkErr)
+		}
+
+		// 2. Upsert into api.history_registry, restarting the history window when newly enabled
+		upsertQuery := `
+INSERT INTO api.history_registry (schema_name, table_name, history_enabled, primary_key, excluded_columns)
+VALUES ($1, $2, true, $3, $4)
+ON CONFLICT (schema_name, table_name) DO UPDATE
+SET history_enabled = true,
+    primary_key = EXCLUDED.primary_key,
+    excluded_columns = EXCLUDED.excluded_columns,
+    enabled_at = CASE WHEN api.history_registry.history_enabled THEN api.history_registry.enabled_at ELSE NOW() END,
+    updated_at = NOW()
+RETURNING enabled_at`
+		log.Debug().Str("query", upsertQuery).Msg("Upserting history registry")
+		if execErr := tx.QueryRow(ctx, upsertQuery, req.Schema, req.Table, primaryKey, req.Exclude).Scan(&enabledAt); execErr != nil {
+			return fmt.Errorf("failed to update history registry: %w", execErr)
+		}
+
+		// 3. Recreate the trigger
+		dropQuery := fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s.%s",
+			quoteIdentifier(triggerName), quoteIdentifier(req.Schema), quoteIdentifier(req.Table))
+		log.Debug().Str("query", dropQuery).Msg("Dropping existing history trigger")
+		if _, execErr := tx.Exec(ctx, dropQuery); execErr != nil {
+			return fmt.Errorf("failed to drop existing trigger: %w", execErr)
+		}
+
+		triggerQuery := fmt.Sprintf(`CREATE TRIGGER %s
+AFTER INSERT OR UPDATE OR DELETE ON %s.%s
+FOR EACH ROW EXECUTE FUNCTION api.record_row_history()`,
+			quoteIdentifier(triggerName), quoteIdentifier(req.Schema), quoteIdentifier(req.Table))
+		log.Debug().Str("query", triggerQuery).Msg("Creating history trigger")
+		if _, execErr := tx.Exec(ctx, triggerQuery); execErr != nil {
+			return fmt.Errorf("failed to create trigger: %w", execErr)
+		}
+
+		// 4. Snapshot existing rows as the baseline for as_of reads
+		if !alreadyEnabled {
+			snapshotQuery := fmt.Sprintf(`
+INSERT INTO api.row_history (schema_name, table_name, row_pk, operation, new_data)
+SELECT $1, $2, (SELECT jsonb_object_agg(k, r -> k) FROM unnest($3::text[]) AS k), 'SNAPSHOT', r - $4::text[]
+FROM (SELECT to_jsonb(t) AS r FROM %s.%s t) s`,
+				quoteIdentifier(req.Schema), quoteIdentifier(req.Table))
+			log.Debug().Str("query", snapshotQuery).Msg("Snapshotting existing rows")
+			if _, execErr := tx.Exec(ctx, snapshotQuery, req.Schema, req.Table, primaryKey, req.Exclude); execErr != nil {
+				return fmt.Errorf("failed to snapshot existing rows: %w", execErr)
+			}
+		}
+
+		return tx.Commit(ctx)
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("table", req.Schema+"."+req.Table).Msg("Failed to enable row history")
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to enable row history: %v", err),
+		})
+	}
+
+	log.Info().
+		Str("schema", req.Schema).
+		Str("table", req.Table).
+		Strs("primary_key", primaryKey).
+		Strs("exclude", req.Exclude).
+		Msg("Row history enabled on table")
+
+	return c.Status(201).JSON(EnableHistoryResponse{
+		Schema:      req.Schema,
+		Table:       req.Table,
+		TriggerName: triggerName,
+		PrimaryKey:  primaryKey,
+		Exclude:     req.Exclude,
+		EnabledAt:   fmt.Sprintf("%v", enabledAt),
+	})
+}
+
+// HandleDisableHistory disables row history on a table.
+// Recorded history is kept unless ?purge=true is given.
+func (h *HistoryAdminHandler) HandleDisableHistory(c *fiber.Ctx) error {
+	schema := c.Params("schema")
+	table := c.Params("table")
+
+	if err := validateIdentifier(schema, "schema"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+	if err := validateIdentifier(table, "table"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+
+	purge := c.Query("purge") == "true"
+	ctx := c.Context()
+
+	exists, err := h.tableExists(ctx, schema, table)
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to check table existence")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check table existence",
+		})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' does not exist", schema, table),
+		})
+	}
+
+	triggerName := historyTriggerName(table)
+
+	err = h.db.ExecuteWithAdminRole(ctx, func(conn *pgx.Conn) error {
+		tx, txErr := conn.Begin(ctx)
+		if txErr != nil {
+			return fmt.Errorf("failed to begin transaction: %w", txErr)
+		}
+		defer tx.Rollback(ctx) //nolint:errcheck
+
+		// 1. Drop the trigger
+		dropQuery := fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s.%s",
+			quoteIdentifier(triggerName), quoteIdentifier(schema), quoteIdentifier(table))
+		log.Debug().Str("query", dropQuery).Msg("Dropping history trigger")
+		if _, execErr := tx.Exec(ctx, dropQuery); execErr != nil {
+			return fmt.Errorf("failed to drop trigger: %w", execErr)
+		}
+
+		// 2. Update registry (keep the record so recorded history stays attributable)
+		if _, execErr := tx.Exec(ctx, `
+UPDATE api.history_registry
+SET history_enabled = false, updated_at = NOW()
+WHERE schema_name = $1 AND table_name = $2`, schema, table); execErr != nil {
+			return fmt.Errorf("failed to update history registry: %w", execErr)
+		}
+
+		// 3. Optionally delete the recorded history
+		if purge {
+			if _, execErr := tx.Exec(ctx, `
+DELETE FROM api.row_history WHERE schema_name = $1 AND table_name = $2`, schema, table); execErr != nil {
+				return fmt.Errorf("failed to purge row history: %w", execErr)
+			}
+		}
+
+		return tx.Commit(ctx)
+	})
+
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to disable row history")
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to disable row history: %v", err),
+		})
+	}
+
+	log.Info().Str("schema", schema).Str("table", table).Bool("purged", purge).Msg("Row history disabled on table")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Row history disabled on table '%s.%s'", schema, table),
+	})
+}
+
+// HandleListHistoryTables lists all history-enabled tables
+func (h *HistoryAdminHandler) HandleListHistoryTables(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	enabledOnly := c.Query("enabled", "true") == "true"
+
+	query := `
+SELECT id, schema_name, table_name, history_enabled, primary_key, excluded_columns,
+       enabled_at, created_at, updated_at
+FROM api.history_registry`
+
+	if enabledOnly {
+		query += " WHERE history_enabled = true"
+	}
+
+	query += " ORDER BY schema_name, table_name"
+
+	rows, err := h.db.Pool().Query(ctx, query)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list history tables")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list history tables",
+		})
+	}
+	defer rows.Close()
+
+	tables := []HistoryTableStatus{}
+	for rows.Next() {
+		t, err := scanHistoryTableStatus(rows)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to scan history table row")
+			continue
+		}
+		tables = append(tables, t)
+	}
+
+	return c.JSON(fiber.Map{
+		"tables": tables,
+		"count":  len(tables),
+	})
+}
+
+// HandleGetHistoryStatus gets the row history status for a specific table
+func (h *HistoryAdminHandler) HandleGetHistoryStatus(c *fiber.Ctx) error {
+	schema := c.Params("schema")
+	table := c.Params("table")
+
+	if err := validateIdentifier(schema, "schema"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+	if err := validateIdentifier(table, "table"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+
+	ctx := c.Context()
+
+	row := h.db.Pool().QueryRow(ctx, `
+SELECT id, schema_name, table_name, history_enabled, primary_key, excluded_columns,
+       enabled_at, created_at, updated_at
+FROM api.history_registry
+WHERE schema_name = $1 AND table_name = $2`, schema, table)
+
+	t, err := scanHistoryTableStatus(row)
+	if err == pgx.ErrNoRows {
+		exists, checkErr := h.tableExists(ctx, schema, table)
+		if checkErr != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to check table existence",
+			})
+		}
+		if !exists {
+			return c.Status(404).JSON(fiber.Map{
+				"error": fmt.Sprintf("Table '%s.%s' does not exist", schema, table),
+			})
+		}
+		// Table exists but history was never enabled
+		return c.JSON(HistoryTableStatus{
+			Schema:          schema,
+			Table:           table,
+			HistoryEnabled:  false,
+			PrimaryKey:      []string{},
+			ExcludedColumns: []string{},
+		})
+	}
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to get row history status")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get row history status",
+		})
+	}
+
+	return c.JSON(t)
+}
+
+// HandleUpdateHistoryConfig updates the excluded columns of a history-enabled table
+func (h *HistoryAdminHandler) HandleUpdateHistoryConfig(c *fiber.Ctx) error {
+	schema := c.Params("schema")
+	table := c.Params("table")
+
+	if err := validateIdentifier(schema, "schema"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+	if err := validateIdentifier(table, "table"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeValidationFailed)
+	}
+
+	var req struct {
+		Exclude []string `json:"exclude"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return SendInvalidBody(c)
+	}
+	if req.Exclude == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No updates provided",
+		})
+	}
+
+	ctx := c.Context()
+
+	var primaryKey []string
+	err := h.db.Pool().QueryRow(ctx, `
+SELECT primary_key FROM api.history_registry
+WHERE schema_name = $1 AND table_name = $2 AND history_enabled = true`, schema, table).Scan(&primaryKey)
+	if err == pgx.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Row history not enabled on table '%s.%s'", schema, table),
+		})
+	}
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to read row history config")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update row history configuration",
+		})
+	}
+	if err := validateHistoryExclude(req.Exclude, primaryKey); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if _, err := h.db.Pool().Exec(ctx, `
+UPDATE api.history_registry
+SET excluded_columns = $3, updated_at = NOW()
+WHERE schema_name = $1 AND table_name = $2`, schema, table, req.Exclude); err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to update row history config")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update row history configuration",
+		})
+	}
+
+	log.Info().Str("schema", schema).Str("table", table).Msg("Row history config updated")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Row history configuration updated for '%s.%s'", schema, table),
+	})
+}
+
+// scanHistoryTableStatus scans a history registry row
+func scanHistoryTableStatus(row pgx.Row) (HistoryTableStatus, error) {
+	var t HistoryTableStatus
+	var enabledAt, createdAt, updatedAt interface{}
+	if err := row.Scan(&t.ID, &t.Schema, &t.Table, &t.HistoryEnabled, &t.PrimaryKey, &t.ExcludedColumns, &enabledAt, &createdAt, &updatedAt); err != nil {
+		return t, err
+	}
+	if enabledAt != nil {
+		t.EnabledAt = fmt.Sprintf("%v", enabledAt)
+	}
+	if createdAt != nil {
+		t.CreatedAt = fmt.Sprintf("%v", createdAt)
+	}
+	if updatedAt != nil {
+		t.UpdatedAt = fmt.Sprintf("%v", updatedAt)
+	}
+	return t, nil
+}
+
+// tableExists checks if a base table exists (history triggers can't be created on views)
+func (h *HistoryAdminHandler) tableExists(ctx context.Context, schema, table string) (bool, error) {
+	var exists bool
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = $1 AND table_name = $2 AND table_type = 'BASE TABLE'
+		)
+	`, schema, table).Scan(&exists)
+	return exists, err
+}
+
+// primaryKey returns the primary key columns of a table in key order
+func (h *HistoryAdminHandler) primaryKey(ctx context.Context, schema, table string) ([]string, error) {
+	var columns []string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT COALESCE(array_agg(a.attname::text ORDER BY array_position(i.indkey::int2[], a.attnum)), '{}')
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = format('%I.%I', $1::text, $2::text)::regclass AND i.indisprimary
+	`, schema, table).Scan(&columns)
+	return columns, err
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHistoryExclude(t *testing.T) {
+	assert.NoError(t, validateHistoryExclude(nil, []string{"id"}))
+	assert.NoError(t, validateHistoryExclude([]string{"password_hash"}, []string{"id"}))
+	assert.ErrorContains(t, validateHistoryExclude([]string{"id"}, []string{"id"}), "primary key column 'id' cannot be excluded")
+	assert.ErrorContains(t, validateHistoryExclude([]string{"bad-name"}, []string{"id"}), "invalid excluded column")
+}
+
+func TestHistoryTriggerName(t *testing.T) {
+	assert.Equal(t, "orders_row_history", historyTriggerName("orders"))
+}
+
+func TestHandleEnableHistory_InvalidRequests(t *testing.T) {
+	handler := NewHistoryAdminHandler(nil)
+	app := fiber.New()
+	app.Post("/history/tables", handler.HandleEnableHistory)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{invalid`},
+		{name: "missing table", body: `{"schema": "public"}`},
+		{name: "invalid table", body: `{"table": "orders; drop"}`},
+		{name: "system schema", body: `{"schema": "auth", "table": "users"}`},
+		{name: "history schema", body: `{"schema": "api", "table": "row_history"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/history/tables", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// JSONSchemaHandler manages the JSON Schemas attached to json and jsonb columns
+type JSONSchemaHandler struct {
+	db          *database.Connection
+	schemaCache *database.SchemaCache
+}
+
+// NewJSONSchemaHandler creates a new column JSON Schema handler
+func NewJSONSchemaHandler(db *database.Connection, schemaCache *database.SchemaCache) *JSONSchemaHandler {
+	return &JSONSchemaHandler{db: db, schemaCache: schemaCache}
+}
+
+// SetJSONSchemaRequest represents a request to attach a JSON Schema to a column
+type SetJSONSchemaRequest struct {
+	JSONSchema json.RawMessage `json:"json_schema"`
+}
+
+// JSONSchemaResponse represents a stored column JSON Schema
+type JSONSchemaResponse struct {
+	Schema     string          `json:"schema"`
+	Table      string          `json:"table"`
+	Column     string          `json:"column"`
+	JSONSchema json.RawMessage `json:"json_schema"`
+	CreatedAt  string          `json:"created_at"`
+	UpdatedAt  string          `json:"updated_at"`
+}
+
+// HandleSetJSONSchema attaches or replaces the JSON Schema of a json or jsonb column
+func (h *JSONSchemaHandler) HandleSetJSONSchema(c *fiber.Ctx) error {
+	schema, table, column := c.Params("schema"), c.Params("table"), c.Params("column")
+	if err := validateIdentifier(schema, "schema"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+	}
+	if err := validateIdentifier(table, "table"); err != nil {
+		return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+	}
+
+	var req SetJSONSchemaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendInvalidBody(c)
+	}
+	if len(req.JSONSchema) == 0 || string(req.JSONSchema) == "null" {
+		return SendMissingField(c, "json_schema")
+	}
+
+	// Reject schemas that can't be used for validation before storing them
+	if _, err := database.NewColumnJSONSchema(schema, table, column, req.JSONSchema); err != nil {
+		return SendErrorWithDetails(c, fiber.StatusBadRequest, "Invalid JSON Schema", ErrCodeValidationFailed, err.Error(), "", nil)
+	}
+
+	ctx := c.Context()
+
+	info, exists, err := h.schemaCache.GetTable(ctx, schema, table)
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Msg("Failed to lookup table")
+		return SendInternalError(c, "Failed to lookup table metadata")
+	}
+	if !exists {
+		return SendErrorWithCode(c, fiber.StatusNotFound, fmt.Sprintf("Table '%s.%s' not found", schema, table), ErrCodeNotFound)
+	}
+	col := info.GetColumn(column)
+	if col == nil {
+		return SendErrorWithCode(c, fiber.StatusNotFound, fmt.Sprintf("Column '%s' not found in table '%s.%s'", column, schema, table), ErrCodeNotFound)
+	}
+	if dataType := strings.ToLower(col.DataType); dataType != "json" && dataType != "jsonb" {
+		return SendBadRequest(c, fmt.Sprintf("Column '%s' has type %s; JSON Schemas can only be attached to json and jsonb columns", column, col.DataType), ErrCodeInvalidInput)
+	}
+
+	var createdAt, updatedAt interface{}
+	err = h.db.Pool().QueryRow(ctx, `
+INSERT INTO api.column_json_schemas (schema_name, table_name, column_name, json_schema)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (schema_name, table_name, column_name) DO UPDATE
+SET json_schema = EXCLUDED.json_schema,
+    updated_at = NOW()
+RETURNING created_at, updated_at`,
+		schema, table, column, string(req.JSONSchema),
+	).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Str("column", column).Msg("Failed to save column JSON schema")
+		return SendInternalError(c, "Failed to save column JSON schema")
+	}
+
+	// Schemas are served from the schema cache on every instance
+	h.schemaCache.InvalidateAll(ctx)
+
+	log.Info().Str("table", schema+"."+table).Str("column", column).Msg("Column JSON schema saved")
+
+	return c.JSON(JSONSchemaResponse{
+		Schema:     schema,
+		Table:      table,
+		Column:     column,
+		JSONSchema: req.JSONSchema,
+		CreatedAt:  fmt.Sprintf("%v", createdAt),
+		UpdatedAt:  fmt.Sprintf("%v", updatedAt),
+	})
+}
+
+// HandleListJSONSchemas lists column JSON Schemas, optionally filtered by ?schema= and ?table=
+func (h *JSONSchemaHandler) HandleListJSONSchemas(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	query := `
+SELECT schema_name, table_name, column_name, json_schema, created_at, updated_at
+FROM api.column_json_schemas`
+
+	var conditions []string
+	var args []interface{}
+	for _, filter := range []struct{ param, column string }{
+		{"schema", "schema_name"},
+		{"table", "table_name"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", filter.column, len(args)))
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY schema_name, table_name, column_name"
+
+	rows, err := h.db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list column JSON schemas")
+		return SendInternalError(c, "Failed to list column JSON schemas")
+	}
+	defer rows.Close()
+
+	schemas := []JSONSchemaResponse{}
+	for rows.Next() {
+		var s JSONSchemaResponse
+		var definition []byte
+		var createdAt, updatedAt interface{}
+		if err := rows.Scan(&s.Schema, &s.Table, &s.Column, &definition, &createdAt, &updatedAt); err != nil {
+			log.Error().Err(err).Msg("Failed to scan column JSON schema row")
+			continue
+		}
+		s.JSONSchema = definition
+		s.CreatedAt = fmt.Sprintf("%v", createdAt)
+		s.UpdatedAt = fmt.Sprintf("%v", updatedAt)
+		schemas = append(schemas, s)
+	}
+
+	return c.JSON(fiber.Map{
+		"schemas": schemas,
+		"count":   len(schemas),
+	})
+}
+
+// HandleDeleteJSONSchema removes the JSON Schema of a column
+func (h *JSONSchemaHandler) HandleDeleteJSONSchema(c *fiber.Ctx) error {
+	schema, table, column := c.Params("schema"), c.Params("table"), c.Params("column")
+	ctx := c.Context()
+
+	var id int
+	err := h.db.Pool().QueryRow(ctx, `
+DELETE FROM api.column_json_schemas
+WHERE schema_name = $1 AND table_name = $2 AND column_name = $3
+RETURNING id`, schema, table, column).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return SendErrorWithCode(c, fiber.StatusNotFound, "Column JSON schema not found", ErrCodeNotFound)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("table", schema+"."+table).Str("column", column).Msg("Failed to delete column JSON schema")
+		return SendInternalError(c, "Failed to delete column JSON schema")
+	}
+
+	h.schemaCache.InvalidateAll(ctx)
+
+	log.Info().Str("table", schema+"."+table).Str("column", column).Msg("Column JSON schema deleted")
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Column JSON schema deleted",
+	})
+}
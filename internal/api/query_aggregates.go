@@ -0,0 +1,457 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TimeBucket groups a timestamp column into intervals: group_by=created_at.bucket(1h)
+type TimeBucket struct {
+	Column   string
+	Interval string // Fixed-width interval binned with date_bin, e.g. "15 minute"
+	Unit     string // Calendar unit truncated with date_trunc, e.g. "month"
+}
+
+// HavingFilter filters grouped results on an aggregate: having=sum(amount).gt.100
+type HavingFilter struct {
+	Aggregation Aggregation
+	Operator    FilterOperator
+	Value       interface{}
+}
+
+// WindowFunction is a window function returned as an extra column:
+// window=running_total:sum(amount).over(partition=region,order=created_at)
+type WindowFunction struct {
+	Alias       string
+	Function    string       // Aggregate or ranking function name
+	Column      string       // Argument column, "*" for count(*), "" for ranking functions
+	Aggregate   *Aggregation // Aggregate argument in grouped queries, e.g. sum(count(*))
+	PartitionBy []string
+	OrderBy     []WindowOrder
+}
+
+// WindowOrder is an ORDER BY entry of a window: a column or, over grouped results, an aggregate
+type WindowOrder struct {
+	OrderBy
+	Aggregate *Aggregation // e.g. rank().over(order=sum(amount).desc)
+}
+
+// bucketIntervalRegex matches fixed-width bucket sizes: 30s, 15m, 1h, 1d, 1w
+var bucketIntervalRegex = regexp.MustCompile(`^([1-9][0-9]{0,5})(s|m|h|d|w)$`)
+
+var bucketIntervalUnits = map[string]string{
+	"s": "second",
+	"m": "minute",
+	"h": "hour",
+	"d": "day",
+	"w": "week",
+}
+
+// bucketTruncUnits are the calendar units accepted by bucket(unit)
+var bucketTruncUnits = map[string]bool{
+	"minute":  true,
+	"hour":    true,
+	"day":     true,
+	"week":    true,
+	"month":   true,
+	"quarter": true,
+	"year":    true,
+}
+
+// windowFunctions maps the supported window functions to whether they take a column argument
+var windowFunctions = map[string]bool{
+	"sum":          true,
+	"avg":          true,
+	"min":          true,
+	"max":          true,
+	"count":        true,
+	"first_value":  true,
+	"last_value":   true,
+	"lag":          true,
+	"lead":         true,
+	"row_number":   false,
+	"rank":         false,
+	"dense_rank":   false,
+	"percent_rank": false,
+	"cume_dist":    false,
+}
+
+// havingOperators are the comparisons supported on aggregates
+var havingOperators = map[FilterOperator]string{
+	OpEqual:          "=",
+	OpNotEqual:       "!=",
+	OpGreaterThan:    ">",
+	OpGreaterOrEqual: ">=",
+	OpLessThan:       "<",
+	OpLessOrEqual:    "<=",
+}
+
+// parseGroupByColumn parses a group_by entry: column or column.bucket(size)
+func parseGroupByColumn(value string) (string, *TimeBucket, error) {
+	idx := strings.Index(value, ".bucket(")
+	if idx < 0 {
+		if !isValidIdentifier(value) {
+			return "", nil, fmt.Errorf("invalid group_by column name: %s", value)
+		}
+		return value, nil, nil
+	}
+
+	column := value[:idx]
+	if !isValidIdentifier(column) {
+		return "", nil, fmt.Errorf("invalid group_by column name: %s", column)
+	}
+	if !strings.HasSuffix(value, ")") {
+		return "", nil, fmt.Errorf("invalid bucket: %s", value)
+	}
+	size := strings.ToLower(strings.TrimSpace(value[idx+len(".bucket(") : len(value)-1]))
+
+	bucket := &TimeBucket{Column: column}
+	if m := bucketIntervalRegex.FindStringSubmatch(size); m != nil {
+		bucket.Interval = m[1] + " " + bucketIntervalUnits[m[2]]
+	} else if bucketTruncUnits[size] {
+		bucket.Unit = size
+	} else {
+		return "", nil, fmt.Errorf("invalid bucket size '%s': use a number with s, m, h, d or w (e.g. 15m) or minute, hour, day, week, month, quarter or year", size)
+	}
+	return column, bucket, nil
+}
+
+// parseGroupBy parses group_by=category,created_at.bucket(1h) into params
+func parseGroupBy(value string, params *QueryParams) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		column, bucket, err := parseGroupByColumn(entry)
+		if err != nil {
+			return err
+		}
+		params.GroupBy = append(params.GroupBy, column)
+		if bucket != nil {
+			params.Buckets = append(params.Buckets, *bucket)
+		}
+	}
+	return nil
+}
+
+// expression returns the SQL of the bucket start for a row
+func (b TimeBucket) expression() string {
+	col := quoteIdentifier(b.Column)
+	if b.Unit != "" {
+		return fmt.Sprintf("date_trunc('%s', %s)", b.Unit, col)
+	}
+	// Weekly buckets start on Mondays; 2000-01-03 was one
+	return fmt.Sprintf("date_bin('%s'::interval, %s, '2000-01-03')", b.Interval, col)
+}
+
+// bucketFor returns the time bucket of a grouped column
+func (params *QueryParams) bucketFor(column string) (TimeBucket, bool) {
+	for _, b := range params.Buckets {
+		if b.Column == column {
+			return b, true
+		}
+	}
+	return TimeBucket{}, false
+}
+
+// groupExpression returns a column as it's grouped: its bucket expression or the quoted column
+func (params *QueryParams) groupExpression(column string) string {
+	if b, ok := params.bucketFor(column); ok {
+		return b.expression()
+	}
+	return quoteIdentifier(column)
+}
+
+// parseHaving parses having=count(*).gt.5 and appends the filter to params
+func (qp *QueryParser) parseHaving(value string, params *QueryParams) error {
+	closeIdx := strings.Index(value, ").")
+	if closeIdx < 0 {
+		return fmt.Errorf("invalid having format: %s (expected function(column).operator.value)", value)
+	}
+	agg := qp.parseAggregation(value[:closeIdx+1])
+	if agg == nil || (agg.Function != AggCountAll && !isValidIdentifier(agg.Column)) {
+		return fmt.Errorf("invalid having aggregate: %s", value[:closeIdx+1])
+	}
+
+	rest := value[closeIdx+2:]
+	dot := strings.Index(rest, ".")
+	if dot <= 0 {
+		return fmt.Errorf("invalid having format: %s (expected function(column).operator.value)", value)
+	}
+	op := FilterOperator(rest[:dot])
+	if _, ok := havingOperators[op]; !ok {
+		return fmt.Errorf("unsupported having operator: %s", op)
+	}
+
+	params.Having = append(params.Having, HavingFilter{
+		Aggregation: *agg,
+		Operator:    op,
+		Value:       rest[dot+1:],
+	})
+	return nil
+}
+
+// buildHavingClause builds the HAVING conditions, without the keyword
+func (params *QueryParams) buildHavingClause(argCounter *int) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, h := range params.Having {
+		expr := h.Aggregation.expression()
+		op, ok := havingOperators[h.Operator]
+		if expr == "" || !ok {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", expr, op, *argCounter))
+		args = append(args, h.Value)
+		*argCounter++
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// splitTopLevel splits a comma-separated list, ignoring commas inside parentheses
+func splitTopLevel(value string) []string {
+	var parts []string
+	var current strings.Builder
+	depth := 0
+	for _, ch := range value {
+		switch {
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			if s := strings.TrimSpace(current.String()); s != "" {
+				parts = append(parts, s)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(ch)
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// parseWindows parses window=alias:fn(arg).over(partition=col,order=col.desc),... into params
+func (qp *QueryParser) parseWindows(value string, params *QueryParams) error {
+	for _, expr := range splitTopLevel(value) {
+		w, err := qp.parseWindowFunction(expr)
+		if err != nil {
+			return err
+		}
+		params.Windows = append(params.Windows, w)
+	}
+	return nil
+}
+
+// parseWindowFunction parses a single window function expression
+func (qp *QueryParser) parseWindowFunction(expr string) (WindowFunction, error) {
+	var w WindowFunction
+
+	if colon := strings.Index(expr, ":"); colon > 0 && colon < strings.Index(expr, "(") {
+		w.Alias = strings.TrimSpace(expr[:colon])
+		expr = strings.TrimSpace(expr[colon+1:])
+		if !isValidIdentifier(w.Alias) {
+			return w, fmt.Errorf("invalid window alias: %s", w.Alias)
+		}
+	}
+
+	overIdx := strings.Index(expr, ".over(")
+	if overIdx < 0 || !strings.HasSuffix(expr, ")") {
+		return w, fmt.Errorf("invalid window function: %s (expected function(column).over(...))", expr)
+	}
+	call, spec := expr[:overIdx], expr[overIdx+len(".over("):len(expr)-1]
+
+	open := strings.Index(call, "(")
+	if open <= 0 || !strings.HasSuffix(call, ")") {
+		return w, fmt.Errorf("invalid window function: %s", call)
+	}
+	w.Function = strings.ToLower(call[:open])
+	takesArg, ok := windowFunctions[w.Function]
+	if !ok {
+		return w, fmt.Errorf("unsupported window function: %s", w.Function)
+	}
+
+	arg := strings.TrimSpace(call[open+1 : len(call)-1])
+	switch {
+	case !takesArg:
+		if arg != "" {
+			return w, fmt.Errorf("window function %s takes no argument", w.Function)
+		}
+	case arg == "*" && w.Function == "count":
+		w.Column = "*"
+	case strings.Contains(arg, "("):
+		// Aggregate argument for windows over grouped results: sum(count(*))
+		agg := qp.parseAggregation(arg)
+		if agg == nil || (agg.Function != AggCountAll && !isValidIdentifier(agg.Column)) {
+			return w, fmt.Errorf("invalid window argument: %s", arg)
+		}
+		w.Aggregate = agg
+	case isValidIdentifier(arg):
+		w.Column = arg
+	default:
+		return w, fmt.Errorf("invalid window argument: %s", arg)
+	}
+
+	for _, entry := range splitTopLevel(spec) {
+		key, val, found := strings.Cut(entry, "=")
+		if !found {
+			return w, fmt.Errorf("invalid window specification: %s (expected partition=column or order=column.direction)", entry)
+		}
+		switch strings.TrimSpace(key) {
+		case "partition":
+			col := strings.TrimSpace(val)
+			if !isValidIdentifier(col) {
+				return w, fmt.Errorf("invalid window partition column: %s", col)
+			}
+			w.PartitionBy = append(w.PartitionBy, col)
+		case "order":
+			val = strings.TrimSpace(val)
+			var order WindowOrder
+			if closeIdx := strings.Index(val, ")"); closeIdx > 0 {
+				agg := qp.parseAggregation(val[:closeIdx+1])
+				if agg == nil || (agg.Function != AggCountAll && !isValidIdentifier(agg.Column)) {
+					return w, fmt.Errorf("invalid window order aggregate: %s", val[:closeIdx+1])
+				}
+				order.Aggregate = agg
+				val = "_" + val[closeIdx+1:]
+			} else if column, _, _ := strings.Cut(val, "."); !isValidIdentifier(column) {
+				return w, fmt.Errorf("invalid window order column: %s", column)
+			}
+			parts := strings.Split(val, ".")
+			if order.Aggregate == nil {
+				order.Column = parts[0]
+			}
+			if len(parts) > 1 {
+				order.Desc = parts[1] == "desc"
+			}
+			if len(parts) > 2 {
+				switch parts[2] {
+				case "nullsfirst":
+					order.Nulls = "first"
+				case "nullslast":
+					order.Nulls = "last"
+				}
+			}
+			w.OrderBy = append(w.OrderBy, order)
+		default:
+			return w, fmt.Errorf("unknown window specification key: %s", key)
+		}
+	}
+
+	if w.Alias == "" {
+		w.Alias = w.defaultAlias()
+	}
+	return w, nil
+}
+
+// defaultAlias names a window column after its function and argument, e.g. sum_amount
+func (w WindowFunction) defaultAlias() string {
+	switch {
+	case w.Aggregate != nil:
+		if w.Aggregate.Function == AggCountAll {
+			return w.Function + "_count"
+		}
+		return w.Function + "_" + string(w.Aggregate.Function) + "_" + w.Aggregate.Column
+	case w.Column == "*":
+		return w.Function
+	case w.Column != "":
+		return w.Function + "_" + w.Column
+	default:
+		return w.Function
+	}
+}
+
+// columns returns the table columns a window function reads
+func (w WindowFunction) columns() []string {
+	var cols []string
+	if w.Column != "" && w.Column != "*" {
+		cols = append(cols, w.Column)
+	}
+	if w.Aggregate != nil && w.Aggregate.Column != "" {
+		cols = append(cols, w.Aggregate.Column)
+	}
+	cols = append(cols, w.PartitionBy...)
+	for _, o := range w.OrderBy {
+		if o.Aggregate != nil {
+			if o.Aggregate.Column != "" {
+				cols = append(cols, o.Aggregate.Column)
+			}
+			continue
+		}
+		cols = append(cols, o.Column)
+	}
+	return cols
+}
+
+// toSQL renders the window function as a select expression. Partition and order columns
+// that are time-bucketed refer to their bucket, so windows over grouped results work.
+func (w WindowFunction) toSQL(params *QueryParams) string {
+	var arg string
+	switch {
+	case w.Aggregate != nil:
+		arg = w.Aggregate.expression()
+	case w.Column == "*":
+		arg = "*"
+	case w.Column != "":
+		arg = quoteIdentifier(w.Column)
+	}
+
+	var over []string
+	if len(w.PartitionBy) > 0 {
+		partition := make([]string, len(w.PartitionBy))
+		for i, col := range w.PartitionBy {
+			partition[i] = params.groupExpression(col)
+		}
+		over = append(over, "PARTITION BY "+strings.Join(partition, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		order := make([]string, len(w.OrderBy))
+		for i, o := range w.OrderBy {
+			if o.Aggregate != nil {
+				order[i] = o.Aggregate.expression()
+			} else {
+				order[i] = params.groupExpression(o.Column)
+			}
+			if o.Desc {
+				order[i] += " DESC"
+			} else {
+				order[i] += " ASC"
+			}
+			if o.Nulls != "" {
+				order[i] += " NULLS " + strings.ToUpper(o.Nulls)
+			}
+		}
+		over = append(over, "ORDER BY "+strings.Join(order, ", "))
+	}
+
+	return fmt.Sprintf("%s(%s) OVER (%s) AS %s", strings.ToUpper(w.Function), arg, strings.Join(over, " "), quoteIdentifier(w.Alias))
+}
+
+// buildWindowColumns renders the window functions of a query as select expressions
+func (params *QueryParams) buildWindowColumns() []string {
+	columns := make([]string, 0, len(params.Windows))
+	for _, w := range params.Windows {
+		columns = append(columns, w.toSQL(params))
+	}
+	return columns
+}
+
+// havingValueText converts a POST body value to text, like values in the URL grammar,
+// so PostgreSQL parses it as the aggregate's type
+func havingValueText(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analyticsTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema: "public",
+		Name:   "orders",
+		Type:   "table",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "region", DataType: "text"},
+			{Name: "amount", DataType: "numeric"},
+			{Name: "created_at", DataType: "timestamp with time zone"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+}
+
+func TestParseGroupByColumn(t *testing.T) {
+	tests := []struct {
+		value   string
+		column  string
+		bucket  *TimeBucket
+		wantErr bool
+	}{
+		{value: "region", column: "region"},
+		{value: "created_at.bucket(1h)", column: "created_at", bucket: &TimeBucket{Column: "created_at", Interval: "1 hour"}},
+		{value: "created_at.bucket(15m)", column: "created_at", bucket: &TimeBucket{Column: "created_at", Interval: "15 minute"}},
+		{value: "created_at.bucket(Month)", column: "created_at", bucket: &TimeBucket{Column: "created_at", Unit: "month"}},
+		{value: "created_at.bucket(0h)", wantErr: true},
+		{value: "created_at.bucket(1 hour')", wantErr: true},
+		{value: "created_at.bucket(1h", wantErr: true},
+		{value: "bad;col", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			column, bucket, err := parseGroupByColumn(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.column, column)
+			assert.Equal(t, tt.bucket, bucket)
+		})
+	}
+}
+
+func TestQueryParser_Having(t *testing.T) {
+	parser := NewQueryParser(testConfig())
+
+	params, err := parser.Parse(url.Values{
+		"select":   {"region,count(*),sum(amount)"},
+		"group_by": {"region"},
+		"having":   {"count(*).gt.5", "sum(amount).lte.1000.50"},
+	})
+	require.NoError(t, err)
+	require.Len(t, params.Having, 2)
+	assert.Equal(t, HavingFilter{Aggregation: Aggregation{Function: AggCountAll}, Operator: OpGreaterThan, Value: "5"}, params.Having[0])
+	assert.Equal(t, HavingFilter{Aggregation: Aggregation{Function: AggSum, Column: "amount"}, Operator: OpLessOrEqual, Value: "1000.50"}, params.Having[1])
+
+	for _, value := range []string{"count(*)", "sum(amount).like.5", "median(amount).gt.1", "sum(a;b).gt.1"} {
+		_, err := parser.Parse(url.Values{"having": {value}})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestQueryParser_Windows(t *testing.T) {
+	parser := NewQueryParser(testConfig())
+
+	params, err := parser.Parse(url.Values{
+		"window": {"running_total:sum(amount).over(partition=region,order=created_at),rank().over(order=amount.desc.nullslast)"},
+	})
+	require.NoError(t, err)
+	require.Len(t, params.Windows, 2)
+	assert.Equal(t, WindowFunction{
+		Alias:       "running_total",
+		Function:    "sum",
+		Column:      "amount",
+		PartitionBy: []string{"region"},
+		OrderBy:     []WindowOrder{{OrderBy: OrderBy{Column: "created_at"}}},
+	}, params.Windows[0])
+	assert.Equal(t, WindowFunction{
+		Alias:    "rank",
+		Function: "rank",
+		OrderBy:  []WindowOrder{{OrderBy: OrderBy{Column: "amount", Desc: true, Nulls: "last"}}},
+	}, params.Windows[1])
+
+	params, err = parser.Parse(url.Values{"window": {"cumulative:sum(count(*)).over(order=created_at),rank().over(order=sum(amount).desc)"}})
+	require.NoError(t, err)
+	assert.Equal(t, &Aggregation{Function: AggCountAll}, params.Windows[0].Aggregate)
+	assert.Equal(t, &Aggregation{Function: AggSum, Column: "amount"}, params.Windows[1].OrderBy[0].Aggregate)
+	assert.True(t, params.Windows[1].OrderBy[0].Desc)
+
+	for _, value := range []string{
+		"sum(amount)",
+		"median(amount).over()",
+		"rank(amount).over()",
+		"sum(amount).over(frame=rows)",
+		"x-y:sum(amount).over()",
+		"sum(amount).over(order=a;b)",
+	} {
+		_, err := parser.Parse(url.Values{"window": {value}})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestBuildSelectQuery_Analytics(t *testing.T) {
+	h := &RESTHandler{}
+	table := analyticsTestTable()
+	parser := NewQueryParser(testConfig())
+
+	t.Run("bucketed group by with having", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{
+			"select":   {"region,count(*),sum(amount)"},
+			"group_by": {"created_at.bucket(1h),region"},
+			"having":   {"count(*).gt.5"},
+			"status":   {"eq.paid"},
+			"order":    {"created_at.asc"},
+			"limit":    {"100"},
+		})
+		require.NoError(t, err)
+
+		query, args := h.buildSelectQuery(table, params)
+		assert.Equal(t, `SELECT date_bin('1 hour'::interval, "created_at", '2000-01-03') AS "created_at", "region", COUNT(*) AS "count", SUM("amount") AS "sum_amount" `+
+			`FROM public.orders WHERE "status" = $1 `+
+			`GROUP BY date_bin('1 hour'::interval, "created_at", '2000-01-03'), "region" `+
+			`HAVING COUNT(*) > $2 ORDER BY "created_at" ASC LIMIT $3`, query)
+		assert.Equal(t, []interface{}{"paid", "5", 100}, args)
+	})
+
+	t.Run("calendar bucket", func(t *testing.T) {
+		params := &QueryParams{Select: []string{"created_at"}, Aggregations: []Aggregation{{Function: AggCountAll}}}
+		require.NoError(t, parseGroupBy("created_at.bucket(month)", params))
+		query, _ := h.buildSelectQuery(table, params)
+		assert.Equal(t, `SELECT date_trunc('month', "created_at") AS "created_at", COUNT(*) AS "count" FROM public.orders GROUP BY date_trunc('month', "created_at")`, query)
+	})
+
+	t.Run("window over grouped results", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{
+			"select":   {"sum(amount)"},
+			"group_by": {"created_at.bucket(1d)"},
+			"window":   {"cumulative:sum(sum(amount)).over(order=created_at)"},
+			"limit":    {"10"},
+		})
+		require.NoError(t, err)
+		query, _ := h.buildSelectQuery(table, params)
+		assert.Contains(t, query, `SUM(SUM("amount")) OVER (ORDER BY date_bin('1 day'::interval, "created_at", '2000-01-03') ASC) AS "cumulative"`)
+	})
+
+	t.Run("window over rows", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{
+			"select": {"id,amount"},
+			"window": {"running_total:sum(amount).over(partition=region,order=created_at),position:row_number().over(order=amount.desc)"},
+		})
+		require.NoError(t, err)
+		query, _ := h.buildSelectQuery(table, params)
+		assert.Contains(t, query, `SELECT "id", "amount", SUM("amount") OVER (PARTITION BY "region" ORDER BY "created_at" ASC) AS "running_total", ROW_NUMBER() OVER (ORDER BY "amount" DESC) AS "position" FROM public.orders`)
+	})
+}
+
+func TestConvertPostQueryToParams_Analytics(t *testing.T) {
+	h := &RESTHandler{parser: NewQueryParser(testConfig())}
+
+	params, err := h.convertPostQueryToParams(&PostQueryRequest{
+		Select:  "count(*)",
+		GroupBy: []string{"created_at.bucket(15m)", "region"},
+		Having:  []PostQueryHaving{{Aggregate: "count(*)", Operator: "gte", Value: float64(10)}, {Aggregate: "avg(amount)", Operator: "lt", Value: 2.5}},
+		Window:  []string{"rank().over(order=count(*).desc)"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at", "region"}, params.GroupBy)
+	assert.Equal(t, []TimeBucket{{Column: "created_at", Interval: "15 minute"}}, params.Buckets)
+	require.Len(t, params.Having, 2)
+	assert.Equal(t, "10", params.Having[0].Value)
+	assert.Equal(t, "2.5", params.Having[1].Value)
+	require.Len(t, params.Windows, 1)
+
+	_, err = h.convertPostQueryToParams(&PostQueryRequest{GroupBy: []string{"created_at.bucket(1y)"}})
+	assert.Error(t, err)
+}
+
+func TestCheckProtectedColumns_Analytics(t *testing.T) {
+	table := analyticsTestTable()
+	table.ColumnPolicies = []database.ColumnPolicy{{Column: "amount", Role: "support", Mode: database.ColumnPolicyMask}}
+
+	err := checkProtectedColumns(table, "support", &QueryParams{Having: []HavingFilter{{Aggregation: Aggregation{Function: AggSum, Column: "amount"}, Operator: OpGreaterThan, Value: "1"}}})
+	assert.EqualError(t, err, "column 'amount' cannot be used in having")
+
+	err = checkProtectedColumns(table, "support", &QueryParams{Windows: []WindowFunction{{Function: "rank", OrderBy: []WindowOrder{{OrderBy: OrderBy{Column: "amount"}}}}}})
+	assert.EqualError(t, err, "column 'amount' cannot be used in window functions")
+
+	err = checkProtectedColumns(table, "support", &QueryParams{Windows: []WindowFunction{{Function: "sum", Column: "id", PartitionBy: []string{"region"}}}})
+	assert.NoError(t, err)
+}
@@ -53,6 +53,14 @@ type QueryBuilder struct {
 	argCounter   int
 	cursorData   *CursorData // Cursor for keyset pagination
 	cursorColumn string      // Column override for cursor
+	keysets      []keyset    // Multi-column keyset positions
+}
+
+// keyset is a position in an ordering: the values of the ordering's columns for a row
+type keyset struct {
+	order   []OrderBy
+	values  []interface{}
+	notNull map[string]bool // Columns known not to hold NULLs, which need no NULL handling
 }
 
 // NewQueryBuilder creates a new QueryBuilder for the given schema and table.
@@ -124,6 +132,16 @@ func (qb *QueryBuilder) WithCursor(cursor string, cursorColumn string) error {
 	return nil
 }
 
+// WithKeyset restricts results to rows strictly after a position in the given ordering,
+// values holding the ordering's columns at that position. Unlike WithCursor it pages over
+// several columns; the ordering must end in a unique column such as the primary key.
+// Rows before a position are those after it in the reversed ordering.
+// notNull lists the NOT NULL columns, sparing them conditions that defeat index scans.
+func (qb *QueryBuilder) WithKeyset(order []OrderBy, values []interface{}, notNull map[string]bool) *QueryBuilder {
+	qb.keysets = append(qb.keysets, keyset{order: order, values: values, notNull: notNull})
+	return qb
+}
+
 // BuildSelect builds a SELECT query and returns the SQL string and arguments.
 func (qb *QueryBuilder) BuildSelect() (string, []interface{}) {
 	// Build SELECT clause
@@ -166,6 +184,11 @@ func (qb *QueryBuilder) BuildSelect() (string, []interface{}) {
 			args = append(args, cursorArg)
 		}
 	}
+	for _, ks := range qb.keysets {
+		keysetClause, keysetArgs := qb.buildKeysetCondition(ks)
+		whereClauses = append(whereClauses, keysetClause)
+		args = append(args, keysetArgs...)
+	}
 
 	// Combine WHERE clauses
 	if len(whereClauses) > 0 {
@@ -531,3 +554,67 @@ func (qb *QueryBuilder) buildCursorCondition() (string, interface{}) {
 
 	return condition, qb.cursorData.Value
 }
+
+// buildKeysetCondition builds a multi-column keyset pagination condition: a row is after the
+// position if it ties on the first n columns and comes after it on the next one.
+// NULLs sort last ascending and first descending, unless placed explicitly.
+func (qb *QueryBuilder) buildKeysetCondition(ks keyset) (string, []interface{}) {
+	var args []interface{}
+	placeholder := func(value interface{}) string {
+		p := fmt.Sprintf("$%d", qb.argCounter)
+		qb.argCounter++
+		args = append(args, value)
+		return p
+	}
+
+	var disjuncts []string
+	for i, order := range ks.order {
+		if i >= len(ks.values) {
+			break
+		}
+		after := keysetAfter(order, ks.values[i], ks.notNull[order.Column], placeholder)
+		if after == "" {
+			continue // Nothing sorts after a NULL placed last
+		}
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			quoted := quoteIdentifier(ks.order[j].Column)
+			if ks.values[j] == nil {
+				parts = append(parts, quoted+" IS NULL")
+			} else {
+				parts = append(parts, quoted+" = "+placeholder(ks.values[j]))
+			}
+		}
+		parts = append(parts, after)
+		disjuncts = append(disjuncts, strings.Join(parts, " AND "))
+	}
+
+	if len(disjuncts) == 0 {
+		return "FALSE", nil
+	}
+	return "(" + strings.Join(disjuncts, " OR ") + ")", args
+}
+
+// keysetAfter returns the condition for a column coming after a value in an ordering,
+// or "" if no value does
+func keysetAfter(order OrderBy, value interface{}, notNull bool, placeholder func(interface{}) string) string {
+	quoted := quoteIdentifier(order.Column)
+	nullsFirst := order.Nulls == "first" || (order.Nulls == "" && order.Desc)
+
+	if value == nil {
+		if nullsFirst {
+			return quoted + " IS NOT NULL"
+		}
+		return ""
+	}
+
+	op := ">"
+	if order.Desc {
+		op = "<"
+	}
+	condition := fmt.Sprintf("%s %s %s", quoted, op, placeholder(value))
+	if !nullsFirst && !notNull {
+		return fmt.Sprintf("(%s OR %s IS NULL)", condition, quoted)
+	}
+	return condition
+}
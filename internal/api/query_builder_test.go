@@ -596,3 +596,41 @@ func TestQueryBuilder_WithCursor(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestQueryBuilder_WithKeyset(t *testing.T) {
+	t.Run("pages after a position", func(t *testing.T) {
+		order := []OrderBy{{Column: "created_at", Desc: true, Nulls: "last"}, {Column: "id"}}
+
+		qb := NewQueryBuilder("public", "posts").
+			WithFilters([]Filter{{Column: "status", Operator: OpEqual, Value: "published"}}).
+			WithKeyset(order, []interface{}{"2025-01-01T00:00:00Z", "42"}, map[string]bool{"id": true})
+
+		sql, args := qb.BuildSelect()
+		assert.Equal(t, `SELECT * FROM "public"."posts" WHERE "status" = $1 AND `+
+			`(("created_at" < $2 OR "created_at" IS NULL) OR "created_at" = $4 AND "id" > $3)`, sql)
+		assert.Equal(t, []interface{}{"published", "2025-01-01T00:00:00Z", "42", "2025-01-01T00:00:00Z"}, args)
+	})
+
+	t.Run("null values", func(t *testing.T) {
+		// NULLs sort last ascending: nothing follows on that column, ties continue
+		qb := NewQueryBuilder("public", "posts").
+			WithKeyset([]OrderBy{{Column: "title"}, {Column: "id"}}, []interface{}{nil, "7"}, map[string]bool{"id": true})
+		sql, args := qb.BuildSelect()
+		assert.Equal(t, `SELECT * FROM "public"."posts" WHERE ("title" IS NULL AND "id" > $1)`, sql)
+		assert.Equal(t, []interface{}{"7"}, args)
+
+		// and first descending
+		qb = NewQueryBuilder("public", "posts").
+			WithKeyset([]OrderBy{{Column: "title", Desc: true}}, []interface{}{nil}, nil)
+		sql, args = qb.BuildSelect()
+		assert.Equal(t, `SELECT * FROM "public"."posts" WHERE ("title" IS NOT NULL)`, sql)
+		assert.Nil(t, args)
+	})
+
+	t.Run("nothing after the last position", func(t *testing.T) {
+		qb := NewQueryBuilder("public", "posts").
+			WithKeyset([]OrderBy{{Column: "title", Nulls: "last"}}, []interface{}{nil}, nil)
+		sql, _ := qb.BuildSelect()
+		assert.Equal(t, `SELECT * FROM "public"."posts" WHERE FALSE`, sql)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fluxbase-eu/fluxbase/internal/config"
 	"github.com/fluxbase-eu/fluxbase/internal/query"
@@ -45,7 +46,21 @@ type QueryParams struct {
 	Aggregations   []Aggregation      // Aggregation functions
 	GroupBy        []string           // GROUP BY columns
 	TruncateLength *int               // Truncate text columns to this length (for table browsing)
-	orGroupCounter int                // Counter for assigning OR group IDs
+	AsOf           *time.Time         // Read rows as they were at this time (requires row history)
+
+	// Full-text search
+	TextSearchLanguage string   // Default text search configuration of fts, plfts and wfts filters
+	Headlines          []string // Columns returned with ts_headline snippets as <column>_headline
+	HeadlineOptions    string   // ts_headline options, e.g. "MaxWords=20, StartSel=<b>, StopSel=</b>"
+
+	// Analytics: time buckets of GROUP BY columns, HAVING conditions and window functions
+	Buckets []TimeBucket
+	Having  []HavingFilter
+	Windows []WindowFunction
+
+	orGroupCounter  int               // Counter for assigning OR group IDs
+	textSearches    []textSearchQuery // tsqueries of the text search filters, recorded by buildWhereClause
+	tsvectorColumns map[string]bool   // tsvector columns of the table, set by bindTextSearchColumns
 }
 
 // Filter is an alias for query.Filter for backward compatibility
@@ -104,9 +119,27 @@ type OrderBy = query.OrderBy
 
 // EmbeddedRelation represents a relation to embed
 type EmbeddedRelation struct {
-	Name    string   // Relation name
-	Select  []string // Fields to select from relation
-	Filters []Filter // Filters for the relation
+	Name     string             // Relation name (target table or foreign key column)
+	Alias    string             // Output key for the embedded resource (alias:relation)
+	Hint     string             // Disambiguation hint: FK constraint, FK column or junction table (relation!hint)
+	Inner    bool               // Only return parent rows that have a matching related row (relation!inner)
+	Select   []string           // Fields to select from relation
+	Filters  []Filter           // Filters for the relation
+	Order    []OrderBy          // ORDER BY for the relation
+	Limit    *int               // LIMIT for the relation
+	Offset   *int               // OFFSET for the relation
+	Embedded []EmbeddedRelation // Nested relations to embed
+
+	relationship   *embedRelationship // Resolved from the schema cache before building SQL
+	orGroupCounter int                // Counter for assigning OR group IDs
+}
+
+// OutputName returns the key under which the embedded resource is returned
+func (r *EmbeddedRelation) OutputName() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	return r.Name
 }
 
 // CountType represents row count preferences
@@ -169,13 +202,28 @@ func (qp *QueryParser) ParseWithOptions(values url.Values, opts ParseOptions) (*
 		Order:   []OrderBy{},
 	}
 
+	// Parse select first so that embedded relation parameters
+	// (e.g. author.name=eq.x, posts.order=id.desc) can be attributed to their relation
+	if vals, ok := values["select"]; ok && len(vals) > 0 {
+		if err := qp.parseSelect(vals[0], params); err != nil {
+			return nil, fmt.Errorf("invalid select parameter: %w", err)
+		}
+	}
+
 	// Parse each parameter type
 	for key, vals := range values {
+		if rel, subKey := findEmbeddedRelationForKey(params.Embedded, key); rel != nil {
+			for _, val := range vals {
+				if err := qp.parseEmbeddedParam(rel, subKey, val); err != nil {
+					return nil, fmt.Errorf("invalid parameter %s: %w", key, err)
+				}
+			}
+			continue
+		}
+
 		switch key {
 		case "select":
-			if err := qp.parseSelect(vals[0], params); err != nil {
-				return nil, fmt.Errorf("invalid select parameter: %w", err)
-			}
+			// Already parsed above
 
 		case "order":
 			if err := qp.parseOrder(vals[0], params); err != nil {
@@ -237,20 +285,51 @@ func (qp *QueryParser) ParseWithOptions(values url.Values, opts ParseOptions) (*
 			}
 			params.TruncateLength = &truncateLen
 
+		case "as_of":
+			// Point-in-time read from row history: as_of=2025-01-01T12:00:00Z
+			asOf, err := parseAsOf(vals[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid as_of parameter: %w", err)
+			}
+			params.AsOf = &asOf
+
+		case "fts_language":
+			// Text search configuration for fts/plfts/wfts filters: fts_language=english
+			params.TextSearchLanguage = vals[0]
+
+		case "fts_headline":
+			// Highlighted snippets of matching text: fts_headline=title,body
+			headlines, err := parseHeadlineColumns(vals[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid fts_headline parameter: %w", err)
+			}
+			params.Headlines = headlines
+
+		case "fts_headline_options":
+			// Passed to ts_headline as-is: fts_headline_options=MaxWords=20,StartSel=<b>,StopSel=</b>
+			params.HeadlineOptions = vals[0]
+
 		case "group_by":
 			// Parse GROUP BY columns: group_by=category,status
-			columns := strings.Split(vals[0], ",")
-			for _, col := range columns {
-				col = strings.TrimSpace(col)
-				if col != "" {
-					// Validate column name to prevent SQL injection
-					if !isValidIdentifier(col) {
-						return nil, fmt.Errorf("invalid group_by column name: %s", col)
-					}
-					params.GroupBy = append(params.GroupBy, col)
+			// Timestamps can be grouped into buckets: group_by=created_at.bucket(1h)
+			if err := parseGroupBy(vals[0], params); err != nil {
+				return nil, err
+			}
+
+		case "having":
+			// Filter groups on aggregates: having=count(*).gt.5&having=sum(amount).lte.1000
+			for _, val := range vals {
+				if err := qp.parseHaving(val, params); err != nil {
+					return nil, fmt.Errorf("invalid having parameter: %w", err)
 				}
 			}
 
+		case "window":
+			// Window functions as extra columns: window=total:sum(amount).over(order=created_at)
+			if err := qp.parseWindows(vals[0], params); err != nil {
+				return nil, fmt.Errorf("invalid window parameter: %w", err)
+			}
+
 		default:
 			// Check if it's a filter parameter
 			// PostgREST format: column=operator.value (dot in value)
@@ -267,6 +346,11 @@ func (qp *QueryParser) ParseWithOptions(values url.Values, opts ParseOptions) (*
 		}
 	}
 
+	// Resolve text search languages (fts(english).term and fts_language)
+	if err := params.normalizeTextSearch(); err != nil {
+		return nil, err
+	}
+
 	// Apply default limit if none specified (unless default is -1)
 	if params.Limit == nil && qp.config.API.DefaultPageSize > 0 {
 		defaultLimit := qp.config.API.DefaultPageSize
@@ -328,21 +412,15 @@ func (qp *QueryParser) parseSelect(value string, params *QueryParams) error {
 	}
 
 	params.Select = regularFields
-
-	for name, subSelect := range embedded {
-		params.Embedded = append(params.Embedded, EmbeddedRelation{
-			Name:   name,
-			Select: subSelect,
-		})
-	}
+	params.Embedded = embedded
 
 	return nil
 }
 
 // parseSelectFields parses select fields and embedded relations
-func (qp *QueryParser) parseSelectFields(value string) ([]string, map[string][]string) {
+func (qp *QueryParser) parseSelectFields(value string) ([]string, []EmbeddedRelation) {
 	fields := []string{}
-	embedded := make(map[string][]string)
+	var embedded []EmbeddedRelation
 
 	// Known aggregation function names
 	aggFuncs := map[string]bool{
@@ -385,12 +463,12 @@ func (qp *QueryParser) parseSelectFields(value string) ([]string, map[string][]s
 		case ')':
 			depth--
 			if depth == 0 && inRelation && !isAggregation {
-				// End of relation fields
-				subFields := strings.Split(current.String(), ",")
-				for j := range subFields {
-					subFields[j] = strings.TrimSpace(subFields[j])
-				}
-				embedded[relationName] = subFields
+				// End of relation fields - parse recursively to support nested relations
+				subFields, subEmbedded := qp.parseSelectFields(current.String())
+				rel := parseEmbeddedRelationName(relationName)
+				rel.Select = subFields
+				rel.Embedded = subEmbedded
+				embedded = append(embedded, rel)
 				current.Reset()
 				inRelation = false
 			} else if depth == 0 && isAggregation {
@@ -424,6 +502,102 @@ func (qp *QueryParser) parseSelectFields(value string) ([]string, map[string][]s
 	return fields, embedded
 }
 
+// parseEmbeddedRelationName parses an embedded relation token from a select list.
+// Format: [alias:]relation[!hint][!inner]
+// Examples: author, writer:author, author!posts_author_id_fkey, author!inner
+func parseEmbeddedRelationName(token string) EmbeddedRelation {
+	rel := EmbeddedRelation{}
+
+	if idx := strings.Index(token, ":"); idx > 0 {
+		rel.Alias = strings.TrimSpace(token[:idx])
+		token = token[idx+1:]
+	}
+
+	parts := strings.Split(token, "!")
+	rel.Name = strings.TrimSpace(parts[0])
+	for _, modifier := range parts[1:] {
+		switch modifier = strings.TrimSpace(modifier); modifier {
+		case "inner":
+			rel.Inner = true
+		case "left", "":
+			// Left join is the default behavior
+		default:
+			rel.Hint = modifier
+		}
+	}
+
+	return rel
+}
+
+// findEmbeddedRelationForKey finds the embedded relation a dotted query parameter key refers to.
+// For example "author.name" refers to the "author" relation with sub-key "name".
+// Returns nil if the key does not target an embedded relation.
+func findEmbeddedRelationForKey(relations []EmbeddedRelation, key string) (*EmbeddedRelation, string) {
+	dotIndex := strings.Index(key, ".")
+	if dotIndex <= 0 {
+		return nil, ""
+	}
+
+	prefix := key[:dotIndex]
+	for i := range relations {
+		if relations[i].OutputName() == prefix {
+			return &relations[i], key[dotIndex+1:]
+		}
+	}
+
+	return nil, ""
+}
+
+// parseEmbeddedParam applies a query parameter scoped to an embedded relation.
+// Supports filters (author.name=eq.x), logical groups (posts.or=(...)),
+// ordering (posts.order=created_at.desc), limit and offset, and nested relations
+// (posts.comments.limit=5).
+func (qp *QueryParser) parseEmbeddedParam(rel *EmbeddedRelation, subKey, value string) error {
+	if nested, nestedKey := findEmbeddedRelationForKey(rel.Embedded, subKey); nested != nil {
+		return qp.parseEmbeddedParam(nested, nestedKey, value)
+	}
+
+	switch subKey {
+	case "order":
+		tmp := &QueryParams{}
+		if err := qp.parseOrder(value, tmp); err != nil {
+			return err
+		}
+		rel.Order = append(rel.Order, tmp.Order...)
+
+	case "limit":
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid limit: %w", err)
+		}
+		if limit < 0 {
+			return fmt.Errorf("limit must be a non-negative integer")
+		}
+		rel.Limit = &limit
+
+	case "offset":
+		offset, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid offset: %w", err)
+		}
+		if offset < 0 {
+			return fmt.Errorf("offset must be a non-negative integer")
+		}
+		rel.Offset = &offset
+
+	default:
+		// Parse as a filter into a scratch QueryParams, preserving the relation's OR group counter
+		tmp := &QueryParams{orGroupCounter: rel.orGroupCounter}
+		if err := qp.parseFilter(subKey, value, tmp); err != nil {
+			return err
+		}
+		rel.Filters = append(rel.Filters, tmp.Filters...)
+		rel.orGroupCounter = tmp.orGroupCounter
+	}
+
+	return nil
+}
+
 // parseAggregation parses aggregation functions from a select field
 // Examples: count(*), sum(price), avg(rating), count(id), min(created_at), max(updated_at)
 func (qp *QueryParser) parseAggregation(field string) *Aggregation {
@@ -495,6 +669,7 @@ func (qp *QueryParser) parseOrder(value string, params *QueryParams) error {
 		}
 
 		// Standard ordering: column.direction.nulls
+		// Relevance ordering: column.rank.direction.nulls
 		parts := strings.Split(order, ".")
 		if len(parts) < 2 {
 			return fmt.Errorf("invalid order format: %s", order)
@@ -506,10 +681,16 @@ func (qp *QueryParser) parseOrder(value string, params *QueryParams) error {
 			return fmt.Errorf("invalid order column name: %s", colName)
 		}
 
-		orderBy := OrderBy{
-			Column: colName,
-			Desc:   parts[1] == "desc",
+		orderBy := OrderBy{Column: colName}
+		if parts[1] == "rank" {
+			orderBy.Rank = true
+			parts = parts[1:]
+			if len(parts) < 2 {
+				// Most relevant first unless asked otherwise
+				parts = append(parts, "desc")
+			}
 		}
+		orderBy.Desc = parts[1] == "desc"
 
 		// Check for nulls first/last
 		if len(parts) > 2 {
@@ -941,6 +1122,18 @@ func (params *QueryParams) ToSQL(tableName string) (string, []interface{}) {
 		}
 	}
 
+	// Build GROUP BY and HAVING clauses
+	if groupByClause := params.BuildGroupByClause(); groupByClause != "" {
+		sqlParts = append(sqlParts, strings.TrimPrefix(groupByClause, " "))
+	}
+	if len(params.Having) > 0 {
+		havingClause, havingArgs := params.buildHavingClause(&argCounter)
+		if havingClause != "" {
+			sqlParts = append(sqlParts, "HAVING "+havingClause)
+			args = append(args, havingArgs...)
+		}
+	}
+
 	// Build ORDER BY clause
 	if len(params.Order) > 0 {
 		orderClause := params.buildOrderClause()
@@ -968,6 +1161,17 @@ func (params *QueryParams) ToSQL(tableName string) (string, []interface{}) {
 func (params *QueryParams) BuildSelectClause(tableName string) string {
 	var parts []string
 
+	// Bucketed columns are returned as the bucket start, under the column name
+	for _, b := range params.Buckets {
+		selected := false
+		for _, field := range params.Select {
+			selected = selected || field == b.Column
+		}
+		if !selected {
+			parts = append(parts, b.expression()+" AS "+quoteIdentifier(b.Column))
+		}
+	}
+
 	// Add regular select fields - quote identifiers for safety
 	if len(params.Select) > 0 {
 		for _, field := range params.Select {
@@ -975,6 +1179,10 @@ func (params *QueryParams) BuildSelectClause(tableName string) string {
 			if field == "" {
 				continue
 			}
+			if b, ok := params.bucketFor(field); ok {
+				parts = append(parts, b.expression()+" AS "+quoteIdentifier(field))
+				continue
+			}
 			// Check if it's already a complex expression (contains operators or functions)
 			// In which case, assume it's been validated elsewhere
 			if strings.ContainsAny(field, "()+-*/ ") {
@@ -1008,14 +1216,31 @@ func (params *QueryParams) BuildGroupByClause() string {
 	if len(params.GroupBy) == 0 {
 		return ""
 	}
-	// Quote all identifiers for safety
+	// Quote all identifiers for safety; bucketed columns are grouped by their bucket
 	quotedCols := make([]string, len(params.GroupBy))
 	for i, col := range params.GroupBy {
-		quotedCols[i] = quoteIdentifier(col)
+		quotedCols[i] = params.groupExpression(col)
 	}
 	return " GROUP BY " + strings.Join(quotedCols, ", ")
 }
 
+// expression returns the aggregate call without an alias, or "" for an invalid column
+func (agg *Aggregation) expression() string {
+	if agg.Function == AggCountAll {
+		return "COUNT(*)"
+	}
+	quotedCol := quoteIdentifier(agg.Column)
+	if quotedCol == "" {
+		return ""
+	}
+	switch agg.Function {
+	case AggCount, AggSum, AggAvg, AggMin, AggMax:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(string(agg.Function)), quotedCol)
+	default:
+		return ""
+	}
+}
+
 // ToSQL converts an Aggregation to SQL
 func (agg *Aggregation) ToSQL() string {
 	alias := agg.Alias
@@ -1033,43 +1258,10 @@ func (agg *Aggregation) ToSQL() string {
 		alias = "result"
 	}
 
-	var funcSQL string
-	switch agg.Function {
-	case AggCountAll:
-		funcSQL = "COUNT(*)"
-	case AggCount:
-		// Validate column name to prevent injection
-		quotedCol := quoteIdentifier(agg.Column)
-		if quotedCol == "" {
-			return "NULL AS " + quoteIdentifier(alias)
-		}
-		funcSQL = fmt.Sprintf("COUNT(%s)", quotedCol)
-	case AggSum:
-		quotedCol := quoteIdentifier(agg.Column)
-		if quotedCol == "" {
-			return "NULL AS " + quoteIdentifier(alias)
-		}
-		funcSQL = fmt.Sprintf("SUM(%s)", quotedCol)
-	case AggAvg:
-		quotedCol := quoteIdentifier(agg.Column)
-		if quotedCol == "" {
-			return "NULL AS " + quoteIdentifier(alias)
-		}
-		funcSQL = fmt.Sprintf("AVG(%s)", quotedCol)
-	case AggMin:
-		quotedCol := quoteIdentifier(agg.Column)
-		if quotedCol == "" {
-			return "NULL AS " + quoteIdentifier(alias)
-		}
-		funcSQL = fmt.Sprintf("MIN(%s)", quotedCol)
-	case AggMax:
-		quotedCol := quoteIdentifier(agg.Column)
-		if quotedCol == "" {
-			return "NULL AS " + quoteIdentifier(alias)
-		}
-		funcSQL = fmt.Sprintf("MAX(%s)", quotedCol)
-	default:
-		funcSQL = "NULL"
+	funcSQL := agg.expression()
+	if funcSQL == "" {
+		// Unknown function or invalid column
+		return "NULL AS " + quoteIdentifier(alias)
 	}
 
 	return fmt.Sprintf("%s AS %s", funcSQL, quoteIdentifier(alias))
@@ -1085,8 +1277,13 @@ func (params *QueryParams) buildWhereClause(argCounter *int) (string, []interfac
 		filter    Filter
 	}
 	filterSQLs := make([]filterSQL, len(params.Filters))
+	params.textSearches = nil
 
 	for i, filter := range params.Filters {
+		if isTextSearchOperator(filter.Operator) {
+			ts, _ := newTextSearchQuery(filter, *argCounter)
+			params.textSearches = append(params.textSearches, ts)
+		}
 		condition, arg := filterToSQL(filter, argCounter)
 		filterSQLs[i] = filterSQL{condition: condition, filter: filter}
 		if arg != nil {
@@ -1187,6 +1384,12 @@ func (params *QueryParams) buildOrderClause() string {
 
 			vectorVal := formatVectorValue(order.VectorValue)
 			part = fmt.Sprintf("%s %s '%s'::vector", quotedCol, opSQL, vectorVal)
+		} else if order.Rank {
+			// Full-text search relevance of the column for the query's search terms
+			part = params.rankExpression(order.Column)
+			if part == "" {
+				continue
+			}
 		} else {
 			// Standard column ordering
 			part = quotedCol
@@ -1411,20 +1614,14 @@ func filterToSQL(f Filter, argCounter *int) (string, interface{}) {
 		*argCounter++
 		return sql, f.Value
 
-	case OpTextSearch:
-		sql := fmt.Sprintf("%s @@ plainto_tsquery($%d)", colExpr, *argCounter)
-		*argCounter++
-		return sql, f.Value
-
-	case OpPhraseSearch:
-		sql := fmt.Sprintf("%s @@ phraseto_tsquery($%d)", colExpr, *argCounter)
-		*argCounter++
-		return sql, f.Value
-
-	case OpWebSearch:
-		sql := fmt.Sprintf("%s @@ websearch_to_tsquery($%d)", colExpr, *argCounter)
-		*argCounter++
-		return sql, f.Value
+	case OpTextSearch, OpPhraseSearch, OpWebSearch:
+		// plainto_tsquery, phraseto_tsquery or websearch_to_tsquery, with the language if set
+		ts, args := newTextSearchQuery(f, *argCounter)
+		*argCounter += len(args)
+		if len(args) == 1 {
+			return colExpr + " @@ " + ts.query, args[0]
+		}
+		return colExpr + " @@ " + ts.query, args
 
 	case OpNot:
 		// NOT operator - negates the condition
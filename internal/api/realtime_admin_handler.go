@@ -13,7 +13,8 @@ import (
 
 // RealtimeAdminHandler handles realtime enablement for user tables
 type RealtimeAdminHandler struct {
-	db *database.Connection
+	db          *database.Connection
+	publication string // Publication of the realtime tables when changes come from logical replication
 }
 
 // NewRealtimeAdminHandler creates a new realtime admin handler
@@ -21,6 +22,12 @@ func NewRealtimeAdminHandler(db *database.Connection) *RealtimeAdminHandler {
 	return &RealtimeAdminHandler{db: db}
 }
 
+// SetPublication makes the handler add realtime tables to a logical replication publication
+// instead of creating notify triggers on them
+func (h *RealtimeAdminHandler) SetPublication(publication string) {
+	h.publication = publication
+}
+
 // EnableRealtimeRequest represents a request to enable realtime on a table
 type EnableRealtimeRequest struct {
 	Schema  string   `json:"schema"`
@@ -34,7 +41,7 @@ type EnableRealtimeResponse struct {
 	Schema      string   `json:"schema"`
 	Table       string   `json:"table"`
 	Events      []string `json:"events"`
-	TriggerName string   `json:"trigger_name"`
+	TriggerName string   `json:"trigger_name,omitempty"` // Empty when changes come from logical replication
 	Exclude     []string `json:"exclude,omitempty"`
 }
 
@@ -136,6 +143,9 @@ func (h *RealtimeAdminHandler) HandleEnableRealtime(c *fiber.Ctx) error {
 	}
 
 	triggerName := fmt.Sprintf("%s_realtime_notify", req.Table)
+	if h.publication != "" {
+		triggerName = ""
+	}
 
 	// Execute all DDL in a transaction with admin role
 	err = h.db.ExecuteWithAdminRole(ctx, func(conn *pgx.Conn) error {
@@ -162,14 +172,20 @@ func (h *RealtimeAdminHandler) HandleEnableRealtime(c *fiber.Ctx) error {
 			return fmt.Errorf("failed to drop existing trigger: %w", execErr)
 		}
 
-		// 3. Create trigger
-		triggerQuery := fmt.Sprintf(`CREATE TRIGGER %s
+		// 3. Create trigger, or publish the table when changes come from logical replication
+		if h.publication != "" {
+			if execErr := h.setTablePublished(ctx, tx, req.Schema, req.Table, true); execErr != nil {
+				return execErr
+			}
+		} else {
+			triggerQuery := fmt.Sprintf(`CREATE TRIGGER %s
 AFTER INSERT OR UPDATE OR DELETE ON %s.%s
 FOR EACH ROW EXECUTE FUNCTION public.notify_realtime_change()`,
-			quoteIdentifier(triggerName), quoteIdentifier(req.Schema), quoteIdentifier(req.Table))
-		log.Debug().Str("query", triggerQuery).Msg("Creating realtime trigger")
-		if _, execErr := tx.Exec(ctx, triggerQuery); execErr != nil {
-			return fmt.Errorf("failed to create trigger: %w", execErr)
+				quoteIdentifier(triggerName), quoteIdentifier(req.Schema), quoteIdentifier(req.Table))
+			log.Debug().Str("query", triggerQuery).Msg("Creating realtime trigger")
+			if _, execErr := tx.Exec(ctx, triggerQuery); execErr != nil {
+				return fmt.Errorf("failed to create trigger: %w", execErr)
+			}
 		}
 
 		// 4. Upsert into realtime.schema_registry
@@ -259,6 +275,12 @@ func (h *RealtimeAdminHandler) HandleDisableRealtime(c *fiber.Ctx) error {
 			return fmt.Errorf("failed to drop trigger: %w", execErr)
 		}
 
+		if h.publication != "" {
+			if execErr := h.setTablePublished(ctx, tx, schema, table, false); execErr != nil {
+				return execErr
+			}
+		}
+
 		// 2. Update registry (set realtime_enabled = false, keep record for history)
 		updateQuery := `
 UPDATE realtime.schema_registry
@@ -492,6 +514,34 @@ RETURNING id`, strings.Join(updates, ", "))
 	})
 }
 
+// setTablePublished adds a table to the realtime publication or removes it. The publication is
+// created by the realtime listener, which publishes the registered tables when it does.
+func (h *RealtimeAdminHandler) setTablePublished(ctx context.Context, tx pgx.Tx, schema, table string, published bool) error {
+	var exists, member bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM pg_publication WHERE pubname = $1),
+		       EXISTS(SELECT 1 FROM pg_publication_tables WHERE pubname = $1 AND schemaname = $2 AND tablename = $3)
+	`, h.publication, schema, table).Scan(&exists, &member)
+	if err != nil {
+		return fmt.Errorf("failed to check publication: %w", err)
+	}
+	if !exists || member == published {
+		return nil
+	}
+
+	action := "DROP"
+	if published {
+		action = "ADD"
+	}
+	query := fmt.Sprintf("ALTER PUBLICATION %s %s TABLE %s.%s",
+		quoteIdentifier(h.publication), action, quoteIdentifier(schema), quoteIdentifier(table))
+	log.Debug().Str("query", query).Msg("Updating realtime publication")
+	if _, err := tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to update publication: %w", err)
+	}
+	return nil
+}
+
 // tableExists checks if a table exists in the database
 func (h *RealtimeAdminHandler) tableExists(ctx context.Context, schema, table string) (bool, error) {
 	var exists bool
@@ -22,6 +22,11 @@ func (h *RESTHandler) batchInsert(ctx context.Context, c *fiber.Ctx, table datab
 		})
 	}
 
+	// Reject json documents that don't match their column's JSON Schema
+	if verr := validateJSONColumns(table, dataArray, true); verr != nil {
+		return sendJSONSchemaError(c, verr, nil)
+	}
+
 	// Get all unique columns from the first record
 	firstRecord := dataArray[0]
 	columns := make([]string, 0, len(firstRecord))     // Quoted column names for SQL
@@ -78,99 +83,13 @@ func (h *RESTHandler) batchInsert(ctx context.Context, c *fiber.Ctx, table datab
 
 	// Add ON CONFLICT clause for upsert
 	if isUpsert {
-		// Use custom conflict target if provided, otherwise auto-detect
-		var conflictTarget string
-		var conflictTargetColumns []string
-
-		if onConflict != "" {
-			// Validate and quote custom conflict target columns
-			conflictCols := strings.Split(onConflict, ",")
-			quotedConflictCols := make([]string, 0, len(conflictCols))
-			for _, col := range conflictCols {
-				col = strings.TrimSpace(col)
-				if !h.columnExists(table, col) {
-					return c.Status(400).JSON(fiber.Map{
-						"error": fmt.Sprintf("Unknown column in on_conflict: %s", col),
-					})
-				}
-				quotedConflictCols = append(quotedConflictCols, quoteIdentifier(col))
-				conflictTargetColumns = append(conflictTargetColumns, col)
-			}
-			conflictTarget = strings.Join(quotedConflictCols, ", ")
-		} else {
-			conflictTarget = h.getConflictTarget(table)
-			conflictTargetColumns = h.getConflictTargetUnquoted(table)
-		}
-
-		if conflictTarget == "" {
+		conflictClause, err := h.buildConflictClause(table, columnNames, ignoreDuplicates, defaultToNull, onConflict)
+		if err != nil {
 			return c.Status(400).JSON(fiber.Map{
-				"error": "Cannot perform upsert: table has no primary key or unique constraint",
+				"error": err.Error(),
 			})
 		}
-
-		// Handle ignore duplicates (DO NOTHING)
-		if ignoreDuplicates {
-			query += fmt.Sprintf(
-				" ON CONFLICT (%s) DO NOTHING",
-				conflictTarget,
-			)
-		} else {
-			// Build UPDATE SET clause (all columns except conflict target)
-			updateClauses := make([]string, 0)
-
-			// If defaultToNull is true, we need to update ALL columns in the table, not just the ones provided
-			if defaultToNull {
-				// Get all columns from table and set them either to EXCLUDED.column or NULL
-				for _, tableCol := range table.Columns {
-					colName := tableCol.Name
-					// Skip columns that are part of the conflict target
-					if h.isInConflictTarget(colName, conflictTargetColumns) {
-						continue
-					}
-
-					quotedColName := quoteIdentifier(colName)
-
-					// Check if column was provided in the data
-					columnProvided := false
-					for _, providedCol := range columnNames {
-						if providedCol == colName {
-							columnProvided = true
-							break
-						}
-					}
-
-					if columnProvided {
-						// Use the provided value
-						updateClauses = append(updateClauses, fmt.Sprintf("%s = EXCLUDED.%s", quotedColName, quotedColName))
-					} else {
-						// Set to NULL (missing column)
-						updateClauses = append(updateClauses, fmt.Sprintf("%s = NULL", quotedColName))
-					}
-				}
-			} else {
-				// Only update columns that were provided
-				for i, col := range columns {
-					// Skip columns that are part of the conflict target (use unquoted name for comparison)
-					if !h.isInConflictTarget(columnNames[i], conflictTargetColumns) {
-						// col is already quoted
-						updateClauses = append(updateClauses, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-					}
-				}
-			}
-
-			if len(updateClauses) > 0 {
-				query += fmt.Sprintf(
-					" ON CONFLICT (%s) DO UPDATE SET %s",
-					conflictTarget,
-					strings.Join(updateClauses, ", "),
-				)
-			} else {
-				query += fmt.Sprintf(
-					" ON CONFLICT (%s) DO NOTHING",
-					conflictTarget,
-				)
-			}
-		}
+		query += conflictClause
 	}
 
 	query += buildReturningClause(table)
@@ -207,10 +126,113 @@ func (h *RESTHandler) batchInsert(ctx context.Context, c *fiber.Ctx, table datab
 		return c.Status(201).JSON(fiber.Map{"affected": affectedCount})
 	default:
 		// return=representation or no preference - return full records
+		applyColumnPolicies(table, columnPolicyRole(c), nil, results)
 		return c.Status(201).JSON(results)
 	}
 }
 
+// buildConflictClause builds the ON CONFLICT clause of an upsert of the given columns
+func (h *RESTHandler) buildConflictClause(table database.TableInfo, columnNames []string, ignoreDuplicates bool, defaultToNull bool, onConflict string) (string, error) {
+	columns := make([]string, len(columnNames)) // Quoted column names for SQL
+	for i, col := range columnNames {
+		columns[i] = quoteIdentifier(col)
+	}
+
+	var clause string
+
+	// Use custom conflict target if provided, otherwise auto-detect
+	var conflictTarget string
+	var conflictTargetColumns []string
+
+	if onConflict != "" {
+		// Validate and quote custom conflict target columns
+		conflictCols := strings.Split(onConflict, ",")
+		quotedConflictCols := make([]string, 0, len(conflictCols))
+		for _, col := range conflictCols {
+			col = strings.TrimSpace(col)
+			if !h.columnExists(table, col) {
+				return "", fmt.Errorf("Unknown column in on_conflict: %s", col)
+			}
+			quotedConflictCols = append(quotedConflictCols, quoteIdentifier(col))
+			conflictTargetColumns = append(conflictTargetColumns, col)
+		}
+		conflictTarget = strings.Join(quotedConflictCols, ", ")
+	} else {
+		conflictTarget = h.getConflictTarget(table)
+		conflictTargetColumns = h.getConflictTargetUnquoted(table)
+	}
+
+	if conflictTarget == "" {
+		return "", fmt.Errorf("Cannot perform upsert: table has no primary key or unique constraint")
+	}
+
+	// Handle ignore duplicates (DO NOTHING)
+	if ignoreDuplicates {
+		clause = fmt.Sprintf(
+			" ON CONFLICT (%s) DO NOTHING",
+			conflictTarget,
+		)
+	} else {
+		// Build UPDATE SET clause (all columns except conflict target)
+		updateClauses := make([]string, 0)
+
+		// If defaultToNull is true, we need to update ALL columns in the table, not just the ones provided
+		if defaultToNull {
+			// Get all columns from table and set them either to EXCLUDED.column or NULL
+			for _, tableCol := range table.Columns {
+				colName := tableCol.Name
+				// Skip columns that are part of the conflict target
+				if h.isInConflictTarget(colName, conflictTargetColumns) {
+					continue
+				}
+
+				quotedColName := quoteIdentifier(colName)
+
+				// Check if column was provided in the data
+				columnProvided := false
+				for _, providedCol := range columnNames {
+					if providedCol == colName {
+						columnProvided = true
+						break
+					}
+				}
+
+				if columnProvided {
+					// Use the provided value
+					updateClauses = append(updateClauses, fmt.Sprintf("%s = EXCLUDED.%s", quotedColName, quotedColName))
+				} else {
+					// Set to NULL (missing column)
+					updateClauses = append(updateClauses, fmt.Sprintf("%s = NULL", quotedColName))
+				}
+			}
+		} else {
+			// Only update columns that were provided
+			for i, col := range columns {
+				// Skip columns that are part of the conflict target (use unquoted name for comparison)
+				if !h.isInConflictTarget(columnNames[i], conflictTargetColumns) {
+					// col is already quoted
+					updateClauses = append(updateClauses, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+				}
+			}
+		}
+
+		if len(updateClauses) > 0 {
+			clause = fmt.Sprintf(
+				" ON CONFLICT (%s) DO UPDATE SET %s",
+				conflictTarget,
+				strings.Join(updateClauses, ", "),
+			)
+		} else {
+			clause = fmt.Sprintf(
+				" ON CONFLICT (%s) DO NOTHING",
+				conflictTarget,
+			)
+		}
+	}
+
+	return clause, nil
+}
+
 // makeBatchPatchHandler creates a PATCH handler for batch updates with filters
 func (h *RESTHandler) makeBatchPatchHandler(table database.TableInfo) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -230,6 +252,11 @@ func (h *RESTHandler) makeBatchPatchHandler(table database.TableInfo) fiber.Hand
 			})
 		}
 
+		// Reject json documents that don't match their column's JSON Schema
+		if verr := validateJSONColumns(table, []map[string]interface{}{data}, false); verr != nil {
+			return sendJSONSchemaError(c, verr, nil)
+		}
+
 		// Parse raw query string to preserve multiple values for the same key
 		rawQuery := string(c.Request().URI().QueryString())
 		urlValues, err := url.ParseQuery(rawQuery)
@@ -246,6 +273,11 @@ func (h *RESTHandler) makeBatchPatchHandler(table database.TableInfo) fiber.Hand
 			})
 		}
 
+		// Hidden and masked columns can't be used to find the rows to update
+		if err := checkProtectedColumns(table, columnPolicyRole(c), params); err != nil {
+			return sendProtectedColumnError(c, err)
+		}
+
 		// Build SET clause
 		setClauses := make([]string, 0, len(data))
 		values := make([]interface{}, 0, len(data))
@@ -326,6 +358,7 @@ func (h *RESTHandler) makeBatchPatchHandler(table database.TableInfo) fiber.Hand
 			return c.JSON(fiber.Map{"affected": affectedCount})
 		default:
 			// return=representation or no preference - return full records
+			applyColumnPolicies(table, columnPolicyRole(c), nil, results)
 			return c.JSON(results)
 		}
 	}
@@ -352,6 +385,11 @@ func (h *RESTHandler) makeBatchDeleteHandler(table database.TableInfo) fiber.Han
 			})
 		}
 
+		// Hidden and masked columns can't be used to find the rows to delete
+		if err := checkProtectedColumns(table, columnPolicyRole(c), params); err != nil {
+			return sendProtectedColumnError(c, err)
+		}
+
 		// Require at least one filter for safety
 		if len(params.Filters) == 0 {
 			return c.Status(400).JSON(fiber.Map{
@@ -401,6 +439,7 @@ func (h *RESTHandler) makeBatchDeleteHandler(table database.TableInfo) fiber.Han
 			return c.Status(200).JSON(fiber.Map{"affected": affectedCount})
 		default:
 			// return=representation or no preference - return deleted records with count
+			applyColumnPolicies(table, columnPolicyRole(c), nil, results)
 			return c.Status(200).JSON(fiber.Map{
 				"affected": affectedCount,
 				"records":  results,
@@ -0,0 +1,895 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// bulkProgressEvery is the number of rows read between progress reports
+	bulkProgressEvery = 10000
+
+	// bulkMaxReportedErrors caps the rejected rows listed in a bulk load result; all are counted
+	bulkMaxReportedErrors = 1000
+
+	// bulkMaxLineSize is the longest NDJSON line accepted by a bulk load
+	bulkMaxLineSize = 16 * 1024 * 1024
+
+	// bulkInsertChunk is the number of lines inserted per statement. A chunk failing because of
+	// a row is split in halves until the row is found, so the other rows are still loaded.
+	bulkInsertChunk = 10000
+
+	// bulkStagingTable receives the COPY stream. COPY FROM isn't allowed on tables with row level
+	// security, so rows are staged as text and moved with INSERT ... SELECT, which applies the
+	// table's policies, defaults, constraints and triggers.
+	bulkStagingTable = "fluxbase_bulk_load"
+
+	// bulkInputCheckVersion is the server_version_num from which staged values are type checked
+	// with pg_input_is_valid before the insert
+	bulkInputCheckVersion = 160000
+)
+
+// errBulkLoadRejected aborts a bulk load with on_error=abort when any row was rejected
+var errBulkLoadRejected = errors.New("rows were rejected")
+
+// BulkLoadError is a row rejected by a bulk load
+type BulkLoadError struct {
+	Line   int    `json:"line"` // Line of the row in CSV and NDJSON bodies, position of the element in JSON arrays
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// BulkLoadResult summarizes a bulk load
+type BulkLoadResult struct {
+	RowsRead        int             `json:"rows_read"`
+	RowsLoaded      int64           `json:"rows_loaded"`
+	RowsRejected    int             `json:"rows_rejected"`
+	Errors          []BulkLoadError `json:"errors"`
+	ErrorsTruncated bool            `json:"errors_truncated,omitempty"`
+}
+
+// reject records a rejected row
+func (r *BulkLoadResult) reject(e BulkLoadError) {
+	r.RowsRejected++
+	if len(r.Errors) < bulkMaxReportedErrors {
+		r.Errors = append(r.Errors, e)
+	} else {
+		r.ErrorsTruncated = true
+	}
+}
+
+// bulkRecord is one record of a bulk load body
+type bulkRecord struct {
+	line   int
+	values map[string]interface{}
+	err    error // set when the record couldn't be decoded; the row is rejected
+}
+
+// bulkDecoder reads the records of a bulk load body one at a time
+type bulkDecoder interface {
+	// Next returns the next record, or io.EOF after the last one. Other errors are fatal.
+	Next() (bulkRecord, error)
+}
+
+// bulkBodyError is a fatal error in a bulk load body
+type bulkBodyError struct {
+	err error
+}
+
+func (e *bulkBodyError) Error() string { return e.err.Error() }
+func (e *bulkBodyError) Unwrap() error { return e.err }
+
+// csvBulkDecoder decodes CSV bodies whose first line holds the column names.
+// Empty fields are loaded as NULL.
+type csvBulkDecoder struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVBulkDecoder(r io.Reader) (*csvBulkDecoder, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	columns, err := readCSVHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	reader.FieldsPerRecord = len(columns)
+	return &csvBulkDecoder{reader: reader, columns: columns}, nil
+}
+
+func (d *csvBulkDecoder) Next() (bulkRecord, error) {
+	fields, err := d.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return bulkRecord{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+		return bulkRecord{}, err
+	}
+
+	line, _ := d.reader.FieldPos(0)
+	record := make(map[string]interface{}, len(d.columns))
+	for i, col := range d.columns {
+		if fields[i] == "" {
+			record[col] = nil
+		} else {
+			record[col] = fields[i]
+		}
+	}
+	return bulkRecord{line: line, values: record}, nil
+}
+
+// ndjsonBulkDecoder decodes bodies with one JSON object per line. Blank lines are skipped.
+type ndjsonBulkDecoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONBulkDecoder(r io.Reader) *ndjsonBulkDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), bulkMaxLineSize)
+	return &ndjsonBulkDecoder{scanner: scanner}
+}
+
+func (d *ndjsonBulkDecoder) Next() (bulkRecord, error) {
+	for d.scanner.Scan() {
+		d.line++
+		text := bytes.TrimSpace(d.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		record, err := decodeBulkObject(text)
+		return bulkRecord{line: d.line, values: record, err: err}, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return bulkRecord{}, fmt.Errorf("line %d: %w", d.line+1, err)
+	}
+	return bulkRecord{}, io.EOF
+}
+
+// jsonArrayBulkDecoder decodes a JSON array of objects element by element
+type jsonArrayBulkDecoder struct {
+	decoder *json.Decoder
+	index   int
+	started bool
+}
+
+func newJSONArrayBulkDecoder(r io.Reader) *jsonArrayBulkDecoder {
+	return &jsonArrayBulkDecoder{decoder: json.NewDecoder(r)}
+}
+
+func (d *jsonArrayBulkDecoder) Next() (bulkRecord, error) {
+	if !d.started {
+		token, err := d.decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return bulkRecord{}, fmt.Errorf("expected a JSON array")
+			}
+			return bulkRecord{}, err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return bulkRecord{}, fmt.Errorf("expected a JSON array")
+		}
+		d.started = true
+	}
+
+	if !d.decoder.More() {
+		if _, err := d.decoder.Token(); err != nil {
+			return bulkRecord{}, err
+		}
+		return bulkRecord{}, io.EOF
+	}
+
+	// A syntax error can't be skipped since the end of the element is unknown
+	var raw json.RawMessage
+	if err := d.decoder.Decode(&raw); err != nil {
+		return bulkRecord{}, fmt.Errorf("element %d: %w", d.index+1, err)
+	}
+	d.index++
+	record, err := decodeBulkObject(raw)
+	return bulkRecord{line: d.index, values: record, err: err}, nil
+}
+
+// decodeBulkObject decodes a JSON object, keeping numbers as written
+func decodeBulkObject(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON object")
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	return record, nil
+}
+
+// newBulkDecoder creates the decoder of a bulk load body and returns the loaded columns: those of
+// the columns parameter (other fields are ignored), else the CSV header, else the keys of the first
+// JSON object. Records read ahead to find the columns are replayed by the returned decoder.
+func newBulkDecoder(format dataFormat, body io.Reader, columnsParam string) (bulkDecoder, []string, error) {
+	var columns []string
+	if columnsParam != "" {
+		for _, col := range strings.Split(columnsParam, ",") {
+			columns = append(columns, strings.TrimSpace(col))
+		}
+	}
+
+	switch format {
+	case formatCSV:
+		decoder, err := newCSVBulkDecoder(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if columns == nil {
+			columns = decoder.columns
+		}
+		return decoder, columns, nil
+	case formatNDJSON, formatJSON:
+		var decoder bulkDecoder
+		if format == formatNDJSON {
+			decoder = newNDJSONBulkDecoder(body)
+		} else {
+			decoder = newJSONArrayBulkDecoder(body)
+		}
+		if columns != nil {
+			return decoder, columns, nil
+		}
+
+		replay := &replayBulkDecoder{bulkDecoder: decoder}
+		for {
+			record, err := decoder.Next()
+			if errors.Is(err, io.EOF) {
+				return replay, nil, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			replay.buffered = append(replay.buffered, record)
+			if record.err == nil {
+				for col := range record.values {
+					columns = append(columns, col)
+				}
+				sort.Strings(columns)
+				return replay, columns, nil
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported body format: %s", format)
+	}
+}
+
+// replayBulkDecoder returns buffered records before reading on
+type replayBulkDecoder struct {
+	bulkDecoder
+	buffered []bulkRecord
+}
+
+func (d *replayBulkDecoder) Next() (bulkRecord, error) {
+	if len(d.buffered) > 0 {
+		record := d.buffered[0]
+		d.buffered = d.buffered[1:]
+		return record, nil
+	}
+	return d.bulkDecoder.Next()
+}
+
+// bulkText renders a decoded value as COPY text; nil is loaded as NULL.
+// JSON arrays loaded into array columns are rendered as PostgreSQL array literals.
+func bulkText(value interface{}, arrayColumn bool) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		if arrayColumn {
+			return pgArrayLiteral(v)
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// pgArrayLiteral renders a JSON array as a PostgreSQL array literal, e.g. {"a","b"} or {{1,2},{3,4}}
+func pgArrayLiteral(values []interface{}) (string, error) {
+	elements := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			elements[i] = "NULL"
+		case []interface{}:
+			nested, err := pgArrayLiteral(v)
+			if err != nil {
+				return "", err
+			}
+			elements[i] = nested
+		default:
+			text, err := bulkText(v, false)
+			if err != nil {
+				return "", err
+			}
+			escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text.(string))
+			elements[i] = `"` + escaped + `"`
+		}
+	}
+	return "{" + strings.Join(elements, ",") + "}", nil
+}
+
+// bulkColumn is a loaded column with its type
+type bulkColumn struct {
+	name     string
+	dataType string // As rendered by format_type, e.g. numeric(10,2)
+	notNull  bool
+}
+
+// isArray reports whether the column is an array type
+func (c bulkColumn) isArray() bool {
+	return strings.HasSuffix(c.dataType, "]")
+}
+
+// isSpatial reports whether the column is a PostGIS geometry or geography, which also accept GeoJSON
+func (c bulkColumn) isSpatial() bool {
+	return strings.HasPrefix(c.dataType, "geometry") || strings.HasPrefix(c.dataType, "geography")
+}
+
+// bulkLoad loads the records of a body into a table
+type bulkLoad struct {
+	table        database.TableInfo
+	columns      []string
+	decoder      bulkDecoder
+	conflict     string // ON CONFLICT clause of upserts
+	abortOnError bool
+	ignoreExtra  bool // Ignore record keys that aren't loaded columns instead of rejecting the row
+	progress     func(stage string, result *BulkLoadResult)
+
+	result BulkLoadResult
+}
+
+// run stages the body with COPY, rejects rows whose values don't fit their columns, and inserts
+// the remaining rows into the table, rejecting those the table refuses, all inside the
+// caller's transaction
+func (l *bulkLoad) run(ctx context.Context, tx pgx.Tx) error {
+	columns, err := l.columnTypes(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	staging := []string{"_line bigint"}
+	copyColumns := []string{"_line"}
+	for i := range columns {
+		staging = append(staging, fmt.Sprintf("c%d text", i+1))
+		copyColumns = append(copyColumns, fmt.Sprintf("c%d", i+1))
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE TEMP TABLE %s (%s) ON COMMIT DROP",
+		bulkStagingTable, strings.Join(staging, ", "))); err != nil {
+		return err
+	}
+
+	source := &bulkCopySource{load: l, columns: columns}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"pg_temp", bulkStagingTable}, copyColumns, source); err != nil {
+		if source.err != nil {
+			return source.err
+		}
+		return err
+	}
+	l.report("validate")
+
+	rejected, err := l.validate(ctx, tx, columns)
+	if err != nil {
+		return err
+	}
+	if l.result.RowsRejected > 0 && l.abortOnError {
+		return errBulkLoadRejected
+	}
+	if len(rejected) > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM pg_temp.%s WHERE _line = ANY($1)", bulkStagingTable), rejected); err != nil {
+			return err
+		}
+	}
+	l.report("insert")
+
+	quoted := make([]string, len(columns))
+	exprs := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(col.name)
+		staged := fmt.Sprintf("c%d", i+1)
+		if col.isSpatial() {
+			exprs[i] = fmt.Sprintf("CASE WHEN %s LIKE '{%%' THEN ST_GeomFromGeoJSON(%s)::%s ELSE %s::%s END",
+				staged, staged, col.dataType, staged, col.dataType)
+		} else {
+			exprs[i] = fmt.Sprintf("%s::%s", staged, col.dataType)
+		}
+	}
+	query := fmt.Sprintf(`INSERT INTO "%s"."%s" (%s) SELECT %s FROM pg_temp.%s WHERE _line BETWEEN $1 AND $2 ORDER BY _line%s`,
+		l.table.Schema, l.table.Name, strings.Join(quoted, ", "), strings.Join(exprs, ", "), bulkStagingTable, l.conflict)
+
+	var first, last int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT COALESCE(min(_line), 0), COALESCE(max(_line), -1) FROM pg_temp.%s",
+		bulkStagingTable)).Scan(&first, &last); err != nil {
+		return err
+	}
+	for from := first; from <= last; from += bulkInsertChunk {
+		if err := l.insert(ctx, tx, query, from, min(from+bulkInsertChunk-1, last)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert inserts the staged rows of a range of lines under a savepoint. When the values of a
+// row make the insert fail, like a unique, foreign key or check violation, a row level security
+// check or a trigger error, the range is split until the row is found and rejected.
+func (l *bulkLoad) insert(ctx context.Context, tx pgx.Tx, query string, from, to int64) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	tag, err := savepoint.Exec(ctx, query, from, to)
+	if err == nil {
+		if err := savepoint.Commit(ctx); err != nil {
+			return err
+		}
+		l.result.RowsLoaded += tag.RowsAffected()
+		return nil
+	}
+	if rollbackErr := savepoint.Rollback(ctx); rollbackErr != nil {
+		return rollbackErr
+	}
+
+	pgErr := bulkRowError(err)
+	if pgErr == nil {
+		log.Error().Err(err).Str("query", query).Msg("Failed to insert bulk loaded records")
+		return err
+	}
+	if from == to {
+		l.result.reject(BulkLoadError{Line: int(from), Column: pgErr.ColumnName, Error: pgErr.Message})
+		if l.abortOnError {
+			return errBulkLoadRejected
+		}
+		return nil
+	}
+
+	mid := from + (to-from)/2
+	if err := l.insert(ctx, tx, query, from, mid); err != nil {
+		return err
+	}
+	return l.insert(ctx, tx, query, mid+1, to)
+}
+
+// bulkRowError returns the database error of an insert when the values of a row may have caused
+// it, so the row can be rejected alone. Other errors fail the load.
+func bulkRowError(err error) *pgconn.PgError {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(pgErr.Code, "22"), // Data exception
+		strings.HasPrefix(pgErr.Code, "23"), // Integrity constraint violation
+		pgErr.Code == "21000",               // Upsert affecting a row twice
+		pgErr.Code == "P0001":               // Raised by a trigger
+		return pgErr
+	case pgErr.Code == "42501" && strings.Contains(pgErr.Message, "row-level security"):
+		return pgErr
+	}
+	return nil
+}
+
+// columnTypes reads the exact types of the loaded columns from the catalog
+func (l *bulkLoad) columnTypes(ctx context.Context, tx pgx.Tx) ([]bulkColumn, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT attname, format_type(atttypid, atttypmod), attnotnull
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`,
+		quoteIdentifier(l.table.Schema)+"."+quoteIdentifier(l.table.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]bulkColumn)
+	for rows.Next() {
+		var col bulkColumn
+		if err := rows.Scan(&col.name, &col.dataType, &col.notNull); err != nil {
+			return nil, err
+		}
+		types[col.name] = col
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	columns := make([]bulkColumn, len(l.columns))
+	for i, name := range l.columns {
+		col, ok := types[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		columns[i] = col
+	}
+	return columns, nil
+}
+
+// validate rejects staged rows with values that can't be converted to their column's type,
+// or nulls in NOT NULL columns. Returns the lines of the rejected rows.
+func (l *bulkLoad) validate(ctx context.Context, tx pgx.Tx, columns []bulkColumn) ([]int64, error) {
+	// pg_input_is_valid needs PostgreSQL 16. Older servers only check nulls here; rows whose
+	// values can't be cast are then rejected by insert, as cast failures are data exceptions.
+	var version int
+	if err := tx.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version); err != nil {
+		return nil, err
+	}
+	checkTypes := version >= bulkInputCheckVersion
+
+	var checks []string
+	var args []interface{}
+	for i, col := range columns {
+		staged := fmt.Sprintf("c%d", i+1)
+		args = append(args, col.name)
+		nameArg := len(args)
+		if col.notNull {
+			checks = append(checks, fmt.Sprintf(
+				"SELECT _line, $%d::text, 'null value violates not-null constraint' FROM pg_temp.%s WHERE %s IS NULL",
+				nameArg, bulkStagingTable, staged))
+		}
+		if col.dataType == "text" || !checkTypes {
+			continue
+		}
+		args = append(args, col.dataType)
+		typeArg := len(args)
+		condition := fmt.Sprintf("%s IS NOT NULL AND NOT pg_input_is_valid(%s, $%d)", staged, staged, typeArg)
+		if col.isSpatial() {
+			condition += fmt.Sprintf(" AND %s NOT LIKE '{%%'", staged)
+		}
+		checks = append(checks, fmt.Sprintf(
+			"SELECT _line, $%d::text, (pg_input_error_info(%s, $%d)).message FROM pg_temp.%s WHERE %s",
+			nameArg, staged, typeArg, bulkStagingTable, condition))
+	}
+	if len(checks) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, strings.Join(checks, " UNION ALL ")+" ORDER BY 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rejected []int64
+	for rows.Next() {
+		var line int64
+		var e BulkLoadError
+		if err := rows.Scan(&line, &e.Column, &e.Error); err != nil {
+			return nil, err
+		}
+		// A row is rejected once, with its first invalid column
+		if len(rejected) > 0 && rejected[len(rejected)-1] == line {
+			continue
+		}
+		e.Line = int(line)
+		l.result.reject(e)
+		rejected = append(rejected, line)
+	}
+	return rejected, rows.Err()
+}
+
+// report sends a progress report
+func (l *bulkLoad) report(stage string) {
+	if l.progress != nil {
+		l.progress(stage, &l.result)
+	}
+}
+
+// bulkCopySource feeds the decoded records to COPY, rejecting those that can't be loaded
+type bulkCopySource struct {
+	load    *bulkLoad
+	columns []bulkColumn
+	index   map[string]bool // Names of the loaded columns
+	values  []interface{}
+	err     error
+}
+
+func (s *bulkCopySource) Next() bool {
+	l := s.load
+	for {
+		record, err := l.decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return false
+		}
+		if err != nil {
+			s.err = &bulkBodyError{err: err}
+			return false
+		}
+
+		l.result.RowsRead++
+		if l.result.RowsRead%bulkProgressEvery == 0 {
+			l.report("copy")
+		}
+
+		if record.err != nil {
+			l.result.reject(BulkLoadError{Line: record.line, Error: record.err.Error()})
+			continue
+		}
+		values, rejection := s.rowValues(record)
+		if rejection != nil {
+			rejection.Line = record.line
+			l.result.reject(*rejection)
+			continue
+		}
+		s.values = values
+		return true
+	}
+}
+
+// rowValues converts a record to the staged row, or explains why it's rejected
+func (s *bulkCopySource) rowValues(record bulkRecord) ([]interface{}, *BulkLoadError) {
+	if s.index == nil {
+		s.index = make(map[string]bool, len(s.columns))
+		for _, col := range s.columns {
+			s.index[col.name] = true
+		}
+	}
+	for key := range record.values {
+		if !s.index[key] && !s.load.ignoreExtra {
+			return nil, &BulkLoadError{Column: key, Error: fmt.Sprintf("column %s is not one of the loaded columns", key)}
+		}
+	}
+
+	values := make([]interface{}, len(s.columns)+1)
+	values[0] = int64(record.line)
+	texts := make(map[string]interface{}, len(s.columns))
+	for i, col := range s.columns {
+		text, err := bulkText(record.values[col.name], col.isArray())
+		if err != nil {
+			return nil, &BulkLoadError{Column: col.name, Error: err.Error()}
+		}
+		values[i+1] = text
+		texts[col.name] = text
+	}
+
+	// json documents are validated as the text that is stored
+	for _, v := range s.load.table.ValidateJSONColumns(texts) {
+		return nil, &BulkLoadError{Column: v.Column, Error: fmt.Sprintf("%s%s: %s", v.Column, v.Path, v.Message)}
+	}
+	return values, nil
+}
+
+func (s *bulkCopySource) Values() ([]interface{}, error) {
+	return s.values, nil
+}
+
+func (s *bulkCopySource) Err() error {
+	return s.err
+}
+
+// HandleBulkLoad streams a CSV, NDJSON or JSON array body into a table with COPY, inside the
+// caller's RLS transaction. Rows that can't be loaded are skipped and reported, or abort the
+// load with on_error=abort. With Accept: application/x-ndjson the response streams progress
+// reports while the body is loaded, followed by the result.
+func (h *RESTHandler) HandleBulkLoad(c *fiber.Ctx) error {
+	ctx := c.Context()
+	schema, tableName := h.parseTableFromPath(c)
+
+	tableInfo, exists, err := h.schemaCache.GetTable(ctx, schema, tableName)
+	if err != nil {
+		log.Error().Err(err).Str("schema", schema).Str("table", tableName).Msg("Failed to lookup table")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to lookup table metadata",
+		})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' not found", schema, tableName),
+		})
+	}
+	isWritable, err := h.schemaCache.IsTableWritable(ctx, schema, tableName)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check table permissions",
+		})
+	}
+	if !isWritable {
+		return c.Status(405).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
+		})
+	}
+
+	return h.makeBulkLoadHandler(*tableInfo)(c)
+}
+
+// makeBulkLoadHandler creates a POST handler loading the request body into a table
+func (h *RESTHandler) makeBulkLoadHandler(table database.TableInfo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		onError := c.Query("on_error", "skip")
+		if onError != "skip" && onError != "abort" {
+			return SendBadRequest(c, "on_error must be skip or abort", ErrCodeInvalidInput)
+		}
+
+		// The body is read as it arrives; the raw body is only used when it wasn't streamed
+		var body io.Reader
+		if stream := c.Context().RequestBodyStream(); stream != nil {
+			body = stream
+		} else {
+			body = bytes.NewReader(c.Request().Body())
+		}
+		if strings.EqualFold(c.Get(fiber.HeaderContentEncoding), "gzip") {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				return SendBadRequest(c, fmt.Sprintf("Invalid gzip body: %v", err), ErrCodeInvalidBody)
+			}
+			body = gz
+		}
+
+		format := requestBodyFormat(c.Get(fiber.HeaderContentType))
+		decoder, columns, err := newBulkDecoder(format, body, c.Query("columns"))
+		if err != nil {
+			return SendBadRequest(c, fmt.Sprintf("Invalid %s body: %v", strings.ToUpper(string(format)), err), ErrCodeInvalidBody)
+		}
+		if len(columns) == 0 {
+			return SendBadRequest(c, "No rows to load", ErrCodeInvalidBody)
+		}
+		seen := make(map[string]bool, len(columns))
+		for _, col := range columns {
+			if !h.columnExists(table, col) {
+				return SendBadRequest(c, fmt.Sprintf("Unknown column: %s", col), ErrCodeInvalidInput)
+			}
+			if seen[col] {
+				return SendBadRequest(c, fmt.Sprintf("Duplicate column: %s", col), ErrCodeInvalidInput)
+			}
+			seen[col] = true
+		}
+
+		// Upsert preferences are the same as for POST
+		preferHeader := c.Get("Prefer", "")
+		var conflict string
+		if strings.Contains(preferHeader, "resolution=merge-duplicates") || strings.Contains(preferHeader, "resolution=ignore-duplicates") {
+			conflict, err = h.buildConflictClause(table, columns,
+				strings.Contains(preferHeader, "resolution=ignore-duplicates"),
+				strings.Contains(preferHeader, "missing=default"),
+				c.Query("on_conflict", ""))
+			if err != nil {
+				return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+			}
+		}
+
+		load := &bulkLoad{
+			table:        table,
+			columns:      columns,
+			decoder:      decoder,
+			conflict:     conflict,
+			abortOnError: onError == "abort",
+			ignoreExtra:  c.Query("columns") != "",
+		}
+		tableName := fmt.Sprintf("%s.%s", table.Schema, table.Name)
+
+		if negotiateResponseFormat(c.Get(fiber.HeaderAccept)) == formatNDJSON {
+			return h.streamBulkLoad(c, load)
+		}
+
+		load.progress = func(stage string, result *BulkLoadResult) {
+			log.Info().Str("table", tableName).Str("stage", stage).
+				Int("rows_read", result.RowsRead).Int("rows_rejected", result.RowsRejected).
+				Msg("Bulk load progress")
+		}
+		ctx := c.Context()
+		err = middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
+			return load.run(ctx, tx)
+		})
+		if err != nil {
+			return h.sendBulkLoadError(c, load, err)
+		}
+
+		if h.responseCache != nil {
+			h.responseCache.Invalidate(ctx, table.Schema, table.Name)
+		}
+		log.Info().Str("table", tableName).Int("rows_read", load.result.RowsRead).
+			Int64("rows_loaded", load.result.RowsLoaded).Int("rows_rejected", load.result.RowsRejected).
+			Msg("Bulk load completed")
+
+		c.Set("X-Affected-Count", strconv.FormatInt(load.result.RowsLoaded, 10))
+		if load.result.Errors == nil {
+			load.result.Errors = []BulkLoadError{}
+		}
+		return c.Status(201).JSON(load.result)
+	}
+}
+
+// sendBulkLoadError responds to a failed bulk load
+func (h *RESTHandler) sendBulkLoadError(c *fiber.Ctx, load *bulkLoad, err error) error {
+	var bodyErr *bulkBodyError
+	switch {
+	case errors.Is(err, errBulkLoadRejected):
+		return SendErrorWithDetails(c, fiber.StatusUnprocessableEntity, "Bulk load rejected", ErrCodeValidationFailed,
+			fmt.Sprintf("%d rows were rejected", load.result.RowsRejected),
+			"Fix the listed rows, or load with on_error=skip to skip them",
+			fiber.Map{"result": load.result})
+	case errors.As(err, &bodyErr):
+		return SendErrorWithDetails(c, 400, "Invalid request body", ErrCodeInvalidBody, bodyErr.Error(), "",
+			fiber.Map{"rows_read": load.result.RowsRead})
+	default:
+		return handleDatabaseError(c, err, "bulk load records")
+	}
+}
+
+// streamBulkLoad runs the load while streaming NDJSON progress reports, then the result.
+// The status is sent before the load starts, so the last line tells whether it succeeded.
+func (h *RESTHandler) streamBulkLoad(c *fiber.Ctx, load *bulkLoad) error {
+	// The request context is recycled once the handler returns, so the load gets its own
+	loadCtx, cancel := context.WithCancel(context.Background())
+
+	tx, err := middleware.BeginWithRLS(loadCtx, h.db, c)
+	if err != nil {
+		cancel()
+		return handleDatabaseError(c, err, "bulk load records")
+	}
+
+	table := load.table
+	cache := h.responseCache
+	c.Set(fiber.HeaderContentType, mimeNDJSON)
+	c.Status(200)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer func() { _ = tx.Rollback(loadCtx) }()
+
+		encoder := json.NewEncoder(w)
+		write := func(event fiber.Map) {
+			_ = encoder.Encode(event)
+			_ = w.Flush()
+		}
+		load.progress = func(stage string, result *BulkLoadResult) {
+			write(fiber.Map{
+				"type":          "progress",
+				"stage":         stage,
+				"rows_read":     result.RowsRead,
+				"rows_rejected": result.RowsRejected,
+			})
+		}
+
+		err := load.run(loadCtx, tx)
+		if err == nil {
+			err = tx.Commit(loadCtx)
+		}
+		if load.result.Errors == nil {
+			load.result.Errors = []BulkLoadError{}
+		}
+		if err != nil {
+			log.Error().Err(err).Str("table", table.Schema+"."+table.Name).Msg("Bulk load failed")
+			write(fiber.Map{"type": "error", "error": err.Error(), "result": load.result})
+			return
+		}
+		if cache != nil {
+			cache.Invalidate(loadCtx, table.Schema, table.Name)
+		}
+		write(fiber.Map{"type": "result", "result": load.result})
+	})
+	return nil
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBulkRecords reads every record of a decoder
+func readBulkRecords(t *testing.T, decoder bulkDecoder) []bulkRecord {
+	t.Helper()
+	var records []bulkRecord
+	for {
+		record, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestCSVBulkDecoder(t *testing.T) {
+	body := "sku,name,price\nA1,Chair,10\nA2,,12\nA3,Table\nA4,\"Desk\nwith drawer\",99\n"
+	decoder, columns, err := newBulkDecoder(formatCSV, strings.NewReader(body), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sku", "name", "price"}, columns)
+
+	records := readBulkRecords(t, decoder)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, 2, records[0].line)
+	assert.Equal(t, map[string]interface{}{"sku": "A1", "name": "Chair", "price": "10"}, records[0].values)
+
+	assert.Equal(t, 3, records[1].line)
+	assert.Nil(t, records[1].values["name"], "empty fields are NULL")
+
+	assert.Equal(t, 4, records[2].line)
+	assert.Error(t, records[2].err, "wrong field count rejects the row")
+
+	assert.Equal(t, 5, records[3].line)
+	assert.NoError(t, records[3].err)
+	assert.Equal(t, "Desk\nwith drawer", records[3].values["name"])
+}
+
+func TestNDJSONBulkDecoder(t *testing.T) {
+	body := "{\"sku\":\"A1\",\"price\":10.50}\n\n[1,2]\n{not json}\n{\"sku\":\"A2\",\"price\":12}\n"
+	decoder, columns, err := newBulkDecoder(formatNDJSON, strings.NewReader(body), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"price", "sku"}, columns)
+
+	records := readBulkRecords(t, decoder)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, 1, records[0].line)
+	assert.Equal(t, json.Number("10.50"), records[0].values["price"], "numbers are kept as written")
+
+	assert.Equal(t, 3, records[1].line)
+	assert.Error(t, records[1].err)
+	assert.Equal(t, 4, records[2].line)
+	assert.Error(t, records[2].err)
+	assert.Equal(t, 5, records[3].line)
+	assert.NoError(t, records[3].err)
+}
+
+func TestJSONArrayBulkDecoder(t *testing.T) {
+	t.Run("elements are numbered", func(t *testing.T) {
+		decoder, columns, err := newBulkDecoder(formatJSON, strings.NewReader(`["x", {"sku":"A1"}, {"sku":"A2","name":null}]`), "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sku"}, columns, "columns come from the first object")
+
+		records := readBulkRecords(t, decoder)
+		require.Len(t, records, 3)
+		assert.Equal(t, 1, records[0].line)
+		assert.Error(t, records[0].err, "elements must be objects")
+		assert.Equal(t, 2, records[1].line)
+		assert.Equal(t, "A1", records[1].values["sku"])
+		assert.Equal(t, 3, records[2].line)
+	})
+
+	t.Run("body must be an array", func(t *testing.T) {
+		_, _, err := newBulkDecoder(formatJSON, strings.NewReader(`{"sku":"A1"}`), "")
+		assert.Error(t, err)
+	})
+
+	t.Run("syntax errors are fatal", func(t *testing.T) {
+		decoder, _, err := newBulkDecoder(formatJSON, strings.NewReader(`[{"sku":"A1"}, {"sku": }]`), "")
+		require.NoError(t, err)
+		_, err = decoder.Next()
+		require.NoError(t, err)
+		_, err = decoder.Next()
+		assert.Error(t, err)
+	})
+
+	t.Run("columns parameter", func(t *testing.T) {
+		_, columns, err := newBulkDecoder(formatJSON, strings.NewReader(`[{"sku":"A1","name":"Chair"}]`), "sku, name")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sku", "name"}, columns)
+	})
+}
+
+func TestBulkText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		array bool
+		want  interface{}
+	}{
+		{"null", nil, false, nil},
+		{"string", "Chair", false, "Chair"},
+		{"number", json.Number("12345678901234567890"), false, "12345678901234567890"},
+		{"bool", true, false, "true"},
+		{"object", map[string]interface{}{"a": json.Number("1")}, false, `{"a":1}`},
+		{"array as json", []interface{}{"a", "b"}, false, `["a","b"]`},
+		{"array column", []interface{}{"a", `q"uote`, nil}, true, `{"a","q\"uote",NULL}`},
+		{"nested array column", []interface{}{[]interface{}{json.Number("1"), json.Number("2")}}, true, `{{"1","2"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bulkText(tt.value, tt.array)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBulkCopySource(t *testing.T) {
+	load := &bulkLoad{
+		decoder: &replayBulkDecoder{bulkDecoder: newNDJSONBulkDecoder(strings.NewReader(
+			"{\"sku\":\"A1\",\"tags\":[\"x\"]}\n{\"sku\":\"A2\",\"color\":\"red\"}\nnope\n{\"sku\":\"A3\"}\n"))},
+	}
+	source := &bulkCopySource{load: load, columns: []bulkColumn{
+		{name: "sku", dataType: "text"},
+		{name: "tags", dataType: "text[]"},
+	}}
+
+	var rows [][]interface{}
+	for source.Next() {
+		values, err := source.Values()
+		require.NoError(t, err)
+		rows = append(rows, values)
+	}
+	require.NoError(t, source.Err())
+
+	assert.Equal(t, [][]interface{}{
+		{int64(1), "A1", `{"x"}`},
+		{int64(4), "A3", nil},
+	}, rows)
+	assert.Equal(t, 4, load.result.RowsRead)
+	assert.Equal(t, 2, load.result.RowsRejected)
+	require.Len(t, load.result.Errors, 2)
+	assert.Equal(t, 2, load.result.Errors[0].Line)
+	assert.Equal(t, "color", load.result.Errors[0].Column)
+	assert.Equal(t, 3, load.result.Errors[1].Line)
+
+	t.Run("extra keys are ignored with the columns parameter", func(t *testing.T) {
+		load := &bulkLoad{
+			decoder:     newNDJSONBulkDecoder(strings.NewReader("{\"sku\":\"A2\",\"color\":\"red\"}\n")),
+			ignoreExtra: true,
+		}
+		source := &bulkCopySource{load: load, columns: []bulkColumn{{name: "sku", dataType: "text"}}}
+		require.True(t, source.Next())
+		values, _ := source.Values()
+		assert.Equal(t, []interface{}{int64(1), "A2"}, values)
+		assert.Equal(t, 0, load.result.RowsRejected)
+	})
+}
+
+// uniqueSKUTx inserts staged rows into a table with a unique sku column, with savepoints
+type uniqueSKUTx struct {
+	pgx.Tx
+	staged map[int64]string // SKU by line
+	skus   map[string]bool  // Inserted SKUs
+	parent *uniqueSKUTx
+	err    error // Fails every insert
+}
+
+func (tx *uniqueSKUTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	skus := make(map[string]bool, len(tx.skus))
+	for sku := range tx.skus {
+		skus[sku] = true
+	}
+	return &uniqueSKUTx{staged: tx.staged, skus: skus, parent: tx, err: tx.err}, nil
+}
+
+func (tx *uniqueSKUTx) Commit(ctx context.Context) error {
+	tx.parent.skus = tx.skus
+	return nil
+}
+
+func (tx *uniqueSKUTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+func (tx *uniqueSKUTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if tx.err != nil {
+		return pgconn.CommandTag{}, tx.err
+	}
+	inserted := 0
+	for line := args[0].(int64); line <= args[1].(int64); line++ {
+		sku, ok := tx.staged[line]
+		if !ok {
+			continue
+		}
+		if tx.skus[sku] {
+			return pgconn.CommandTag{}, &pgconn.PgError{
+				Code:    "23505",
+				Message: `duplicate key value violates unique constraint "items_sku_key"`,
+			}
+		}
+		tx.skus[sku] = true
+		inserted++
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("INSERT 0 %d", inserted)), nil
+}
+
+func TestBulkLoadInsert_RejectsDuplicateKeys(t *testing.T) {
+	// Line 4 was rejected before the insert
+	staged := map[int64]string{1: "A", 2: "B", 3: "A", 5: "B", 6: "C"}
+
+	load := &bulkLoad{}
+	tx := &uniqueSKUTx{staged: staged, skus: map[string]bool{}}
+	require.NoError(t, load.insert(context.Background(), tx, "", 1, 6))
+	assert.Equal(t, int64(3), load.result.RowsLoaded)
+	assert.Equal(t, map[string]bool{"A": true, "B": true, "C": true}, tx.skus)
+	assert.Equal(t, []BulkLoadError{
+		{Line: 3, Error: `duplicate key value violates unique constraint "items_sku_key"`},
+		{Line: 5, Error: `duplicate key value violates unique constraint "items_sku_key"`},
+	}, load.result.Errors)
+
+	t.Run("abort stops at the first rejected row", func(t *testing.T) {
+		load := &bulkLoad{abortOnError: true}
+		err := load.insert(context.Background(), &uniqueSKUTx{staged: staged, skus: map[string]bool{}}, "", 1, 6)
+		assert.ErrorIs(t, err, errBulkLoadRejected)
+		require.Len(t, load.result.Errors, 1)
+		assert.Equal(t, 3, load.result.Errors[0].Line)
+	})
+
+	t.Run("errors not caused by rows fail the load", func(t *testing.T) {
+		load := &bulkLoad{}
+		tx := &uniqueSKUTx{staged: staged, err: &pgconn.PgError{Code: "42501", Message: "permission denied for table items"}}
+		err := load.insert(context.Background(), tx, "", 1, 6)
+		assert.ErrorContains(t, err, "permission denied")
+		assert.Zero(t, load.result.RowsRejected)
+	})
+}
+
+func TestBulkLoadResult_CapsReportedErrors(t *testing.T) {
+	var result BulkLoadResult
+	for i := 0; i < bulkMaxReportedErrors+5; i++ {
+		result.reject(BulkLoadError{Line: i, Error: "bad"})
+	}
+	assert.Equal(t, bulkMaxReportedErrors+5, result.RowsRejected)
+	assert.Len(t, result.Errors, bulkMaxReportedErrors)
+	assert.True(t, result.ErrorsTruncated)
+}
+
+func TestMakeBulkLoadHandler_Validation(t *testing.T) {
+	app := fiber.New()
+	handler := &RESTHandler{}
+	table := database.TableInfo{
+		Schema:     "public",
+		Name:       "items",
+		PrimaryKey: []string{"id"},
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "uuid"},
+			{Name: "name", DataType: "text"},
+		},
+	}
+	app.Post("/items/bulk", handler.makeBulkLoadHandler(table))
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantBody    string
+	}{
+		{"invalid on_error", "/items/bulk?on_error=retry", "text/csv", "name\nx\n", "on_error must be skip or abort"},
+		{"unknown CSV column", "/items/bulk", "text/csv", "name,color\nx,red\n", "Unknown column: color"},
+		{"missing CSV header", "/items/bulk", "text/csv", "", "missing header row"},
+		{"unknown JSON column", "/items/bulk", "application/json", `[{"color":"red"}]`, "Unknown column: color"},
+		{"not an array", "/items/bulk", "application/json", `{"name":"x"}`, "expected a JSON array"},
+		{"empty array", "/items/bulk", "application/json", `[]`, "No rows to load"},
+		{"upsert on unknown conflict column", "/items/bulk?on_conflict=color", "text/csv", "name\nx\n", "Unknown column in on_conflict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if strings.Contains(tt.target, "on_conflict") {
+				req.Header.Set("Prefer", "resolution=merge-duplicates")
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, 400, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Contains(t, string(body), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+)
+
+// columnPolicyRole returns the application role that column policies are matched against
+func columnPolicyRole(c *fiber.Ctx) string {
+	role, _ := c.Locals("user_role").(string)
+	if role == "" {
+		return "anon"
+	}
+	return role
+}
+
+// checkProtectedColumns rejects filters, ordering, grouping and aggregates on columns that
+// are hidden or masked for the role, since they would reveal the protected values
+func checkProtectedColumns(table database.TableInfo, role string, params *QueryParams) error {
+	protected := table.ProtectedColumns(role)
+	if len(protected) > 0 {
+		for _, f := range params.Filters {
+			if protected[baseColumnName(f.Column)] {
+				return fmt.Errorf("column '%s' cannot be used in filters", baseColumnName(f.Column))
+			}
+		}
+		for _, o := range params.Order {
+			if protected[baseColumnName(o.Column)] {
+				return fmt.Errorf("column '%s' cannot be used for ordering", baseColumnName(o.Column))
+			}
+		}
+		for _, col := range params.GroupBy {
+			if protected[col] {
+				return fmt.Errorf("column '%s' cannot be used for grouping", col)
+			}
+		}
+		for _, agg := range params.Aggregations {
+			if protected[agg.Column] {
+				return fmt.Errorf("column '%s' cannot be aggregated", agg.Column)
+			}
+		}
+		for _, col := range params.Headlines {
+			if protected[col] {
+				return fmt.Errorf("column '%s' cannot be highlighted", col)
+			}
+		}
+		for _, h := range params.Having {
+			if protected[h.Aggregation.Column] {
+				return fmt.Errorf("column '%s' cannot be used in having", h.Aggregation.Column)
+			}
+		}
+		for _, w := range params.Windows {
+			for _, col := range w.columns() {
+				if protected[col] {
+					return fmt.Errorf("column '%s' cannot be used in window functions", col)
+				}
+			}
+		}
+	}
+	return checkEmbeddedProtectedColumns(params.Embedded, role)
+}
+
+// checkEmbeddedProtectedColumns applies checkProtectedColumns to resolved embedded relations
+func checkEmbeddedProtectedColumns(relations []EmbeddedRelation, role string) error {
+	for i := range relations {
+		rel := &relations[i]
+		if rel.relationship == nil {
+			continue
+		}
+		nested := &QueryParams{Filters: rel.Filters, Order: rel.Order, Embedded: rel.Embedded}
+		if err := checkProtectedColumns(rel.relationship.Target, role, nested); err != nil {
+			return fmt.Errorf("%s: %w", rel.OutputName(), err)
+		}
+	}
+	return nil
+}
+
+// sendProtectedColumnError sends a 403 for a query that uses a protected column
+func sendProtectedColumnError(c *fiber.Ctx, err error) error {
+	return SendErrorWithDetails(c, fiber.StatusForbidden, "Column access denied", ErrCodeAccessDenied,
+		err.Error(), "The column is hidden or masked for your role", nil)
+}
+
+// applyColumnPolicies masks result rows for the role, including embedded resources
+func applyColumnPolicies(table database.TableInfo, role string, embedded []EmbeddedRelation, rows []map[string]interface{}) {
+	if len(table.ColumnPolicies) == 0 && len(embedded) == 0 {
+		return
+	}
+	for _, row := range rows {
+		applyRowColumnPolicies(table, role, embedded, row)
+	}
+}
+
+// applyRowColumnPolicies masks a single row in place, descending into embedded resources
+func applyRowColumnPolicies(table database.TableInfo, role string, embedded []EmbeddedRelation, row map[string]interface{}) {
+	table.ApplyColumnPolicies(role, row)
+
+	for i := range embedded {
+		rel := &embedded[i]
+		if rel.relationship == nil {
+			continue
+		}
+		switch value := row[rel.OutputName()].(type) {
+		case map[string]interface{}:
+			applyRowColumnPolicies(rel.relationship.Target, role, rel.Embedded, value)
+		case []interface{}:
+			for _, item := range value {
+				if nested, ok := item.(map[string]interface{}); ok {
+					applyRowColumnPolicies(rel.relationship.Target, role, rel.Embedded, nested)
+				}
+			}
+		}
+	}
+}
+
+// policyRowSource applies column policies to streamed rows, dropping hidden columns
+type policyRowSource struct {
+	rowSource
+	table    database.TableInfo
+	role     string
+	embedded []EmbeddedRelation
+	columns  []string // Columns of the underlying rows
+	visible  []string // Columns remaining after hidden ones are dropped
+}
+
+// newPolicyRowSource wraps a row source with the role's column policies.
+// It returns the source unchanged when no policy can apply.
+func newPolicyRowSource(rows rowSource, columns []string, table database.TableInfo, role string, embedded []EmbeddedRelation) (rowSource, []string) {
+	policies := table.ColumnPoliciesForRole(role)
+	if len(policies) == 0 && len(embedded) == 0 {
+		return rows, columns
+	}
+
+	visible := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if policy, ok := policies[col]; ok && policy.Mode == database.ColumnPolicyHide {
+			continue
+		}
+		visible = append(visible, col)
+	}
+
+	return &policyRowSource{
+		rowSource: rows,
+		table:     table,
+		role:      role,
+		embedded:  embedded,
+		columns:   columns,
+		visible:   visible,
+	}, visible
+}
+
+// Values returns the current row with column policies applied
+func (s *policyRowSource) Values() ([]interface{}, error) {
+	values, err := s.rowSource.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(s.columns))
+	for i, col := range s.columns {
+		if i < len(values) {
+			row[col] = convertPgxValue(values[i])
+		}
+	}
+	applyRowColumnPolicies(s.table, s.role, s.embedded, row)
+
+	masked := make([]interface{}, len(s.visible))
+	for i, col := range s.visible {
+		masked[i] = row[col]
+	}
+	return masked, nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func columnPolicyTestTables() (database.TableInfo, database.TableInfo) {
+	customers := database.TableInfo{
+		Schema: "public",
+		Name:   "customers",
+		ColumnPolicies: []database.ColumnPolicy{
+			{Column: "ssn", Role: "support", Mode: database.ColumnPolicyHide},
+			{Column: "card", Role: "support", Mode: database.ColumnPolicyMask, MaskVisible: 4},
+		},
+	}
+	orders := database.TableInfo{
+		Schema: "public",
+		Name:   "orders",
+		ColumnPolicies: []database.ColumnPolicy{
+			{Column: "notes", Role: "support", Mode: database.ColumnPolicyNull},
+		},
+	}
+	return customers, orders
+}
+
+func TestCheckProtectedColumns(t *testing.T) {
+	customers, orders := columnPolicyTestTables()
+
+	tests := []struct {
+		name    string
+		role    string
+		params  *QueryParams
+		wantErr string
+	}{
+		{name: "unprotected filter", role: "support", params: &QueryParams{Filters: []Filter{{Column: "name", Operator: OpEqual, Value: "x"}}}},
+		{name: "protected filter", role: "support", params: &QueryParams{Filters: []Filter{{Column: "ssn", Operator: OpEqual, Value: "x"}}}, wantErr: "column 'ssn' cannot be used in filters"},
+		{name: "protected json path filter", role: "support", params: &QueryParams{Filters: []Filter{{Column: "card->>number", Operator: OpEqual, Value: "x"}}}, wantErr: "column 'card' cannot be used in filters"},
+		{name: "protected order", role: "support", params: &QueryParams{Order: []OrderBy{{Column: "card"}}}, wantErr: "column 'card' cannot be used for ordering"},
+		{name: "protected group by", role: "support", params: &QueryParams{GroupBy: []string{"ssn"}}, wantErr: "column 'ssn' cannot be used for grouping"},
+		{name: "protected aggregate", role: "support", params: &QueryParams{Aggregations: []Aggregation{{Function: AggCount, Column: "ssn"}}}, wantErr: "column 'ssn' cannot be aggregated"},
+		{name: "other role", role: "admin", params: &QueryParams{Filters: []Filter{{Column: "ssn", Operator: OpEqual, Value: "x"}}}},
+		{
+			name: "protected embedded filter",
+			role: "support",
+			params: &QueryParams{Embedded: []EmbeddedRelation{{
+				Name:         "orders",
+				Filters:      []Filter{{Column: "notes", Operator: OpEqual, Value: "x"}},
+				relationship: &embedRelationship{Target: orders},
+			}}},
+			wantErr: "orders: column 'notes' cannot be used in filters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkProtectedColumns(customers, tt.role, tt.params)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func TestApplyColumnPolicies_Embedded(t *testing.T) {
+	customers, orders := columnPolicyTestTables()
+	embedded := []EmbeddedRelation{{Name: "orders", relationship: &embedRelationship{Target: orders}}}
+
+	rows := []map[string]interface{}{{
+		"id":   1,
+		"ssn":  "123-45-6789",
+		"card": "4111111111111234",
+		"orders": []interface{}{
+			map[string]interface{}{"id": 10, "notes": "call back"},
+		},
+	}}
+
+	applyColumnPolicies(customers, "support", embedded, rows)
+
+	assert.NotContains(t, rows[0], "ssn")
+	assert.Equal(t, "****1234", rows[0]["card"])
+	order := rows[0]["orders"].([]interface{})[0].(map[string]interface{})
+	assert.Nil(t, order["notes"])
+	assert.Equal(t, 10, order["id"])
+}
+
+func TestPolicyRowSource(t *testing.T) {
+	customers, _ := columnPolicyTestTables()
+
+	t.Run("drops hidden columns and masks values", func(t *testing.T) {
+		rows := &fakeRows{rows: [][]interface{}{{1, "123-45-6789", "4111111111111234"}}}
+		source, columns := newPolicyRowSource(rows, []string{"id", "ssn", "card"}, customers, "support", nil)
+
+		assert.Equal(t, []string{"id", "card"}, columns)
+		require.True(t, source.Next())
+		values, err := source.Values()
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{1, "****1234"}, values)
+		assert.False(t, source.Next())
+	})
+
+	t.Run("returns the source unchanged without policies", func(t *testing.T) {
+		rows := &fakeRows{}
+		source, columns := newPolicyRowSource(rows, []string{"id", "ssn"}, customers, "admin", nil)
+
+		assert.Same(t, rows, source)
+		assert.Equal(t, []string{"id", "ssn"}, columns)
+	})
+}
+
+func TestColumnPolicyRequest_Validate(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		req := ColumnPolicyRequest{Table: "customers", Column: "card", Role: " support ", Mode: "mask"}
+		require.NoError(t, req.validate())
+		assert.Equal(t, "public", req.Schema)
+		assert.Equal(t, "support", req.Role)
+		require.NotNil(t, req.MaskVisible)
+		assert.Equal(t, database.DefaultMaskVisible, *req.MaskVisible)
+	})
+
+	negative := -1
+	tests := []struct {
+		name string
+		req  ColumnPolicyRequest
+	}{
+		{name: "missing table", req: ColumnPolicyRequest{Column: "card", Role: "support", Mode: "mask"}},
+		{name: "missing column", req: ColumnPolicyRequest{Table: "customers", Role: "support", Mode: "mask"}},
+		{name: "missing role", req: ColumnPolicyRequest{Table: "customers", Column: "card", Mode: "mask"}},
+		{name: "role with spaces", req: ColumnPolicyRequest{Table: "customers", Column: "card", Role: "sup port", Mode: "mask"}},
+		{name: "invalid mode", req: ColumnPolicyRequest{Table: "customers", Column: "card", Role: "support", Mode: "redact"}},
+		{name: "negative mask_visible", req: ColumnPolicyRequest{Table: "customers", Column: "card", Role: "support", Mode: "mask", MaskVisible: &negative}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.req.validate())
+		})
+	}
+}
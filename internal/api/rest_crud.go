@@ -35,6 +35,13 @@ func (h *RESTHandler) makeGetHandler(table database.TableInfo) fiber.Handler {
 			})
 		}
 
+		// geom_column picks the feature geometry of GeoJSON responses
+		format := negotiateResponseFormat(c.Get(fiber.HeaderAccept))
+		geomName := urlValues.Get("geom_column")
+		if format == formatGeoJSON {
+			urlValues.Del("geom_column")
+		}
+
 		// Parse query parameters
 		// Admin users bypass max_total_results to allow browsing all data
 		opts := ParseOptions{BypassMaxTotalResults: isAdminUser(c)}
@@ -45,9 +52,51 @@ func (h *RESTHandler) makeGetHandler(table database.TableInfo) fiber.Handler {
 			})
 		}
 
+		// Resolve embedded relations (select=*,author(name)) from foreign key metadata
+		if err := h.resolveEmbeddedRelations(ctx, table, params); err != nil {
+			return SendErrorWithDetails(c, 400, "Invalid embedded resource", ErrCodeInvalidInput, err.Error(), "", nil)
+		}
+
+		// Hidden and masked columns can't be filtered, sorted or aggregated on
+		role := columnPolicyRole(c)
+		if err := checkProtectedColumns(table, role, params); err != nil {
+			return sendProtectedColumnError(c, err)
+		}
+		if err := validateTextSearch(table, params); err != nil {
+			return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+		}
+
+		var geom database.ColumnInfo
+		if format == formatGeoJSON {
+			if geom, err = prepareFeatureCollection(table, params, geomName); err != nil {
+				return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+			}
+			if table.ProtectedColumns(role)[geom.Name] {
+				return sendProtectedColumnError(c, fmt.Errorf("column '%s' cannot be used as feature geometry", geom.Name))
+			}
+		}
+
+		// Read from row history instead of the live table
+		if params.AsOf != nil {
+			return h.selectAsOf(c, table, params)
+		}
+
 		// Build SELECT query using fresh metadata
 		query, args := h.buildSelectQuery(table, params)
 
+		// Return the query plan instead of rows when requested (admins and service role only)
+		if opts, ok, err := parsePlanAccept(c.Get(fiber.HeaderAccept)); ok {
+			if err != nil {
+				return SendErrorWithDetails(c, fiber.StatusBadRequest, "Invalid query plan options", ErrCodeInvalidInput, err.Error(), "Supported options are analyze, verbose, buffers and settings", nil)
+			}
+			return h.explainSelect(c, table, query, args, opts)
+		}
+
+		// Stream CSV, NDJSON and XLSX responses negotiated from the Accept header
+		if format.isStreamed() {
+			return h.streamSelect(c, table, params, query, args, format)
+		}
+
 		// Execute query with RLS context
 		var results []map[string]interface{}
 		err = middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
@@ -81,6 +130,12 @@ func (h *RESTHandler) makeGetHandler(table database.TableInfo) fiber.Handler {
 			}
 		}
 
+		applyColumnPolicies(table, role, params.Embedded, results)
+
+		if format == formatGeoJSON {
+			return c.JSON(toFeatureCollection(table, geom.Name, results), mimeGeoJSON)
+		}
+
 		return c.JSON(results)
 	}
 }
@@ -98,9 +153,10 @@ func (h *RESTHandler) makeGetByIdHandler(table database.TableInfo) fiber.Handler
 		}
 
 		// Build query - quote identifiers to prevent SQL injection
+		// The row version is selected alongside the record to produce its ETag
 		query := fmt.Sprintf(
-			`SELECT * FROM "%s"."%s" WHERE "%s" = $1`,
-			table.Schema, table.Name, pkColumn,
+			`SELECT *%s FROM "%s"."%s" WHERE "%s" = $1`,
+			h.rowVersionSelect(table), table.Schema, table.Name, pkColumn,
 		)
 
 		// Execute query with RLS context
@@ -128,7 +184,13 @@ func (h *RESTHandler) makeGetByIdHandler(table database.TableInfo) fiber.Handler
 			})
 		}
 
-		return c.JSON(results[0])
+		record := results[0]
+		if etag := popRowETag(record); etag != "" {
+			c.Set(fiber.HeaderETag, etag)
+		}
+		table.ApplyColumnPolicies(columnPolicyRole(c), record)
+
+		return c.JSON(record)
 	}
 }
 
@@ -146,6 +208,17 @@ func (h *RESTHandler) makePostHandler(table database.TableInfo) fiber.Handler {
 		// Get custom conflict target from query parameter
 		onConflict := c.Query("on_conflict", "")
 
+		// CSV and NDJSON uploads are always inserted as a batch
+		if format := requestBodyFormat(c.Get(fiber.HeaderContentType)); format != formatJSON {
+			records, err := parseBulkBody(format, c.Body())
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": fmt.Sprintf("Invalid %s body: %v", strings.ToUpper(string(format)), err),
+				})
+			}
+			return h.batchInsert(ctx, c, table, records, isUpsert, ignoreDuplicates, defaultToNull, onConflict)
+		}
+
 		// Try to parse as array first (batch insert)
 		var dataArray []map[string]interface{}
 		if err := c.BodyParser(&dataArray); err == nil && len(dataArray) > 0 {
@@ -161,6 +234,11 @@ func (h *RESTHandler) makePostHandler(table database.TableInfo) fiber.Handler {
 			})
 		}
 
+		// Reject json documents that don't match their column's JSON Schema
+		if verr := validateJSONColumns(table, []map[string]interface{}{data}, false); verr != nil {
+			return sendJSONSchemaError(c, verr, nil)
+		}
+
 		// Build INSERT query
 		columns := make([]string, 0, len(data))     // Quoted column names for SQL
 		columnNames := make([]string, 0, len(data)) // Unquoted column names for conflict checking
@@ -297,6 +375,8 @@ func (h *RESTHandler) makePostHandler(table database.TableInfo) fiber.Handler {
 			return h.handleRLSViolation(c, "INSERT", fmt.Sprintf("%s.%s", table.Schema, table.Name))
 		}
 
+		table.ApplyColumnPolicies(columnPolicyRole(c), results[0])
+
 		return c.Status(201).JSON(results[0])
 	}
 }
@@ -315,12 +395,24 @@ func (h *RESTHandler) makePutHandler(table database.TableInfo) fiber.Handler {
 			})
 		}
 
+		// Reject json documents that don't match their column's JSON Schema
+		if verr := validateJSONColumns(table, []map[string]interface{}{data}, false); verr != nil {
+			return sendJSONSchemaError(c, verr, nil)
+		}
+
 		// Determine primary key column
 		pkColumn := "id"
 		if len(table.PrimaryKey) > 0 {
 			pkColumn = table.PrimaryKey[0]
 		}
 
+		// Optimistic concurrency: If-Match restricts the update to the row versions it names
+		precondition := parseIfMatch(c.Get(fiber.HeaderIfMatch))
+		versionExpr := h.rowVersionExpr(table)
+		if precondition != nil && (versionExpr == "" || (!precondition.Any && len(precondition.Versions) == 0)) {
+			return sendPreconditionFailed(c)
+		}
+
 		// Build UPDATE query
 		setClauses := make([]string, 0, len(data))
 		values := make([]interface{}, 0, len(data)+1)
@@ -358,17 +450,26 @@ func (h *RESTHandler) makePutHandler(table database.TableInfo) fiber.Handler {
 			i++
 		}
 
+		// Increment an integer version column unless the client sets it
+		if bump := h.rowVersionBump(table, data); bump != "" {
+			setClauses = append(setClauses, bump)
+		}
+
 		values = append(values, id)
 
+		versionSQL, versionArgs := precondition.whereClause(versionExpr, i+1)
+		values = append(values, versionArgs...)
+
 		query := fmt.Sprintf(
-			`UPDATE "%s"."%s" SET %s WHERE %s = $%d`,
+			`UPDATE "%s"."%s" SET %s WHERE %s = $%d%s`,
 			table.Schema, table.Name,
 			strings.Join(setClauses, ", "),
-			quoteIdentifier(pkColumn), i,
-		) + buildReturningClause(table)
+			quoteIdentifier(pkColumn), i, versionSQL,
+		) + buildReturningClause(table) + h.rowVersionSelect(table)
 
 		// Execute query with RLS context
 		var results []map[string]interface{}
+		preconditionFailed := false
 		err := middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
 			rows, err := tx.Query(ctx, query, values...)
 			if err != nil {
@@ -379,19 +480,35 @@ func (h *RESTHandler) makePutHandler(table database.TableInfo) fiber.Handler {
 
 			// Convert to JSON
 			results, err = pgxRowsToJSON(rows)
+			if err != nil || len(results) > 0 || precondition == nil {
+				return err
+			}
+
+			// No row updated: a visible row means the version didn't match
+			preconditionFailed, err = rowExists(ctx, tx, table, pkColumn, id)
 			return err
 		})
 		if err != nil {
 			return handleDatabaseError(c, err, "update record")
 		}
 
+		if preconditionFailed {
+			return sendPreconditionFailed(c)
+		}
+
 		if len(results) == 0 {
 			// UPDATE with RETURNING 0 rows could be either RLS blocking or record doesn't exist
 			// For authenticated users, assume RLS issue for better debugging (403 vs 404)
 			return h.handleRLSViolation(c, "UPDATE", fmt.Sprintf("%s.%s", table.Schema, table.Name))
 		}
 
-		return c.JSON(results[0])
+		record := results[0]
+		if etag := popRowETag(record); etag != "" {
+			c.Set(fiber.HeaderETag, etag)
+		}
+		table.ApplyColumnPolicies(columnPolicyRole(c), record)
+
+		return c.JSON(record)
 	}
 }
 
@@ -413,16 +530,26 @@ func (h *RESTHandler) makeDeleteHandler(table database.TableInfo) fiber.Handler
 			pkColumn = table.PrimaryKey[0]
 		}
 
+		// Optimistic concurrency: If-Match restricts the delete to the row versions it names
+		precondition := parseIfMatch(c.Get(fiber.HeaderIfMatch))
+		versionExpr := h.rowVersionExpr(table)
+		if precondition != nil && (versionExpr == "" || (!precondition.Any && len(precondition.Versions) == 0)) {
+			return sendPreconditionFailed(c)
+		}
+		versionSQL, versionArgs := precondition.whereClause(versionExpr, 2)
+		args := append([]interface{}{id}, versionArgs...)
+
 		// Build DELETE query - quote identifiers to prevent SQL injection
 		query := fmt.Sprintf(
-			`DELETE FROM "%s"."%s" WHERE "%s" = $1`,
-			table.Schema, table.Name, pkColumn,
+			`DELETE FROM "%s"."%s" WHERE "%s" = $1%s`,
+			table.Schema, table.Name, pkColumn, versionSQL,
 		) + buildReturningClause(table)
 
 		// Execute query with RLS context
 		var results []map[string]interface{}
+		preconditionFailed := false
 		err := middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
-			rows, err := tx.Query(ctx, query, id)
+			rows, err := tx.Query(ctx, query, args...)
 			if err != nil {
 				return err
 			}
@@ -430,12 +557,22 @@ func (h *RESTHandler) makeDeleteHandler(table database.TableInfo) fiber.Handler
 
 			// Convert to JSON to check if record existed
 			results, err = pgxRowsToJSON(rows)
+			if err != nil || len(results) > 0 || precondition == nil {
+				return err
+			}
+
+			// No row deleted: a visible row means the version didn't match
+			preconditionFailed, err = rowExists(ctx, tx, table, pkColumn, id)
 			return err
 		})
 		if err != nil {
 			return handleDatabaseError(c, err, "delete record")
 		}
 
+		if preconditionFailed {
+			return sendPreconditionFailed(c)
+		}
+
 		if len(results) == 0 {
 			// DELETE with RETURNING 0 rows could be either RLS blocking or record doesn't exist
 			// For authenticated users, assume RLS issue for better debugging (403 vs 404)
@@ -0,0 +1,482 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// embedRelationshipType describes how an embedded resource relates to its parent
+type embedRelationshipType string
+
+const (
+	embedManyToOne  embedRelationshipType = "many-to-one"
+	embedOneToMany  embedRelationshipType = "one-to-many"
+	embedManyToMany embedRelationshipType = "many-to-many"
+)
+
+// embedRelationship is a relationship between two tables resolved from foreign key metadata
+type embedRelationship struct {
+	Type       embedRelationshipType
+	Target     database.TableInfo
+	Constraint string // Foreign key constraint name (the target side for many-to-many)
+
+	// For many-to-one and one-to-many: target.TargetColumns[i] = parent.ParentColumns[i]
+	// For many-to-many: junction.JunctionParentColumns[i] = parent.ParentColumns[i]
+	// and junction.JunctionTargetColumns[i] = target.TargetColumns[i]
+	ParentColumns []string
+	TargetColumns []string
+
+	Junction              *database.TableInfo
+	JunctionParentColumns []string
+	JunctionTargetColumns []string
+}
+
+// isToOne returns true if the relationship yields a single object instead of an array
+func (r *embedRelationship) isToOne() bool {
+	return r.Type == embedManyToOne
+}
+
+// describe returns a human-readable description used in ambiguity errors
+func (r *embedRelationship) describe() string {
+	if r.Type == embedManyToMany && r.Junction != nil {
+		return fmt.Sprintf("%s.%s (%s via %s.%s)", r.Target.Schema, r.Target.Name, r.Type, r.Junction.Schema, r.Junction.Name)
+	}
+	return fmt.Sprintf("%s.%s (%s using %s)", r.Target.Schema, r.Target.Name, r.Type, r.Constraint)
+}
+
+// foreignKeyConstraint groups the per-column ForeignKey entries of a single constraint
+type foreignKeyConstraint struct {
+	Name              string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// references checks if the constraint points at the given table
+func (fk foreignKeyConstraint) references(table database.TableInfo) bool {
+	return fk.ReferencedSchema == table.Schema && fk.ReferencedTable == table.Name
+}
+
+// matchesHint checks if a disambiguation hint names this constraint or its single column
+func (fk foreignKeyConstraint) matchesHint(hint string) bool {
+	return fk.Name == hint || (len(fk.Columns) == 1 && fk.Columns[0] == hint)
+}
+
+// groupForeignKeys groups per-column foreign key entries by constraint name,
+// preserving the order in which constraints were first seen
+func groupForeignKeys(fks []database.ForeignKey) []foreignKeyConstraint {
+	var constraints []foreignKeyConstraint
+	index := make(map[string]int)
+
+	for _, fk := range fks {
+		refSchema, refTable := "public", fk.ReferencedTable
+		if dotIndex := strings.Index(fk.ReferencedTable, "."); dotIndex > 0 {
+			refSchema = fk.ReferencedTable[:dotIndex]
+			refTable = fk.ReferencedTable[dotIndex+1:]
+		}
+
+		i, exists := index[fk.Name]
+		if !exists {
+			constraints = append(constraints, foreignKeyConstraint{
+				Name:             fk.Name,
+				ReferencedSchema: refSchema,
+				ReferencedTable:  refTable,
+			})
+			i = len(constraints) - 1
+			index[fk.Name] = i
+		}
+
+		// Entries of composite keys come paired and in key order
+		constraints[i].Columns = append(constraints[i].Columns, fk.ColumnName)
+		constraints[i].ReferencedColumns = append(constraints[i].ReferencedColumns, fk.ReferencedColumn)
+	}
+
+	return constraints
+}
+
+// findTableInfo looks up a table by schema and name
+func findTableInfo(tables []database.TableInfo, schema, name string) *database.TableInfo {
+	for i := range tables {
+		if tables[i].Schema == schema && tables[i].Name == name {
+			return &tables[i]
+		}
+	}
+	return nil
+}
+
+// resolveEmbedRelationship finds the relationship between parent and the embedded relation.
+// Direct relationships (many-to-one, one-to-many) take precedence over many-to-many
+// relationships through junction tables. If more than one candidate remains, the request
+// must disambiguate with a hint (relation!constraint_name).
+func resolveEmbedRelationship(parent database.TableInfo, rel *EmbeddedRelation, tables []database.TableInfo) (*embedRelationship, error) {
+	var direct, junction []*embedRelationship
+
+	// Many-to-one: the parent holds a foreign key to the target
+	for _, fk := range groupForeignKeys(parent.ForeignKeys) {
+		target := findTableInfo(tables, fk.ReferencedSchema, fk.ReferencedTable)
+		if target == nil {
+			continue
+		}
+		if target.Name != rel.Name && !fk.matchesHint(rel.Name) {
+			continue
+		}
+		if rel.Hint != "" && !fk.matchesHint(rel.Hint) {
+			continue
+		}
+		direct = append(direct, &embedRelationship{
+			Type:          embedManyToOne,
+			Target:        *target,
+			Constraint:    fk.Name,
+			ParentColumns: fk.Columns,
+			TargetColumns: fk.ReferencedColumns,
+		})
+	}
+
+	for i := range tables {
+		target := tables[i]
+		if target.Name != rel.Name {
+			continue
+		}
+
+		// One-to-many: the target holds a foreign key to the parent
+		targetFKs := groupForeignKeys(target.ForeignKeys)
+		for _, fk := range targetFKs {
+			if !fk.references(parent) {
+				continue
+			}
+			if rel.Hint != "" && !fk.matchesHint(rel.Hint) {
+				continue
+			}
+			direct = append(direct, &embedRelationship{
+				Type:          embedOneToMany,
+				Target:        target,
+				Constraint:    fk.Name,
+				ParentColumns: fk.ReferencedColumns,
+				TargetColumns: fk.Columns,
+			})
+		}
+
+		// Many-to-many: a junction table holds foreign keys to both the parent and the target
+		for j := range tables {
+			junctionTable := tables[j]
+			if junctionTable.Schema == parent.Schema && junctionTable.Name == parent.Name {
+				continue
+			}
+			if junctionTable.Schema == target.Schema && junctionTable.Name == target.Name {
+				continue
+			}
+			if rel.Hint != "" && junctionTable.Name != rel.Hint {
+				continue
+			}
+
+			junctionFKs := groupForeignKeys(junctionTable.ForeignKeys)
+			for _, parentFK := range junctionFKs {
+				if !parentFK.references(parent) {
+					continue
+				}
+				for _, targetFK := range junctionFKs {
+					if targetFK.Name == parentFK.Name || !targetFK.references(target) {
+						continue
+					}
+					junction = append(junction, &embedRelationship{
+						Type:                  embedManyToMany,
+						Target:                target,
+						Constraint:            targetFK.Name,
+						ParentColumns:         parentFK.ReferencedColumns,
+						TargetColumns:         targetFK.ReferencedColumns,
+						Junction:              &tables[j],
+						JunctionParentColumns: parentFK.Columns,
+						JunctionTargetColumns: targetFK.Columns,
+					})
+				}
+			}
+		}
+	}
+
+	candidates := direct
+	if len(candidates) == 0 {
+		candidates = junction
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("could not find a relationship between '%s.%s' and '%s'", parent.Schema, parent.Name, rel.Name)
+	case 1:
+		return candidates[0], nil
+	default:
+		descriptions := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			descriptions[i] = candidate.describe()
+		}
+		return nil, fmt.Errorf("more than one relationship found between '%s.%s' and '%s': %s; use '%s!<constraint_or_junction>' to disambiguate",
+			parent.Schema, parent.Name, rel.Name, strings.Join(descriptions, ", "), rel.Name)
+	}
+}
+
+// resolveEmbeddedRelations resolves all embedded relations (recursively) against the schema cache
+// and validates their selected and filtered columns
+func (h *RESTHandler) resolveEmbeddedRelations(ctx context.Context, table database.TableInfo, params *QueryParams) error {
+	if len(params.Embedded) == 0 {
+		return nil
+	}
+
+	if len(params.Aggregations) > 0 || len(params.GroupBy) > 0 || len(params.Having) > 0 || len(params.Windows) > 0 {
+		return fmt.Errorf("embedded resources cannot be combined with aggregations, group_by, having or window functions")
+	}
+
+	tables, err := h.schemaCache.GetAllTables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load table metadata: %w", err)
+	}
+
+	return resolveEmbeddedRelationsFor(table, params.Embedded, tables)
+}
+
+// resolveEmbeddedRelationsFor resolves a list of embedded relations for the given parent table
+func resolveEmbeddedRelationsFor(parent database.TableInfo, relations []EmbeddedRelation, tables []database.TableInfo) error {
+	seen := make(map[string]bool, len(relations))
+
+	for i := range relations {
+		rel := &relations[i]
+
+		if !isValidIdentifier(rel.Name) {
+			return fmt.Errorf("invalid embedded relation name: %s", rel.Name)
+		}
+		if rel.Alias != "" && !isValidIdentifier(rel.Alias) {
+			return fmt.Errorf("invalid embedded relation alias: %s", rel.Alias)
+		}
+		if seen[rel.OutputName()] {
+			return fmt.Errorf("embedded relation '%s' is selected more than once; use an alias", rel.OutputName())
+		}
+		seen[rel.OutputName()] = true
+
+		relationship, err := resolveEmbedRelationship(parent, rel, tables)
+		if err != nil {
+			return err
+		}
+		rel.relationship = relationship
+
+		target := relationship.Target
+		for _, col := range rel.Select {
+			if col != "*" && !target.HasColumn(col) {
+				return fmt.Errorf("unknown column '%s' in embedded relation '%s'", col, rel.OutputName())
+			}
+		}
+		for _, f := range rel.Filters {
+			if !target.HasColumn(baseColumnName(f.Column)) {
+				return fmt.Errorf("unknown filter column '%s' in embedded relation '%s'", f.Column, rel.OutputName())
+			}
+		}
+		for _, o := range rel.Order {
+			if !target.HasColumn(o.Column) {
+				return fmt.Errorf("unknown order column '%s' in embedded relation '%s'", o.Column, rel.OutputName())
+			}
+		}
+
+		if err := resolveEmbeddedRelationsFor(target, rel.Embedded, tables); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// baseColumnName strips any JSONB path operators from a filter column (data->>key -> data)
+func baseColumnName(column string) string {
+	if idx := strings.Index(column, "->"); idx > 0 {
+		return column[:idx]
+	}
+	return column
+}
+
+// embedSQLBuilder generates correlated subqueries for embedded relations.
+// It shares the placeholder counter with the enclosing query so that
+// arguments can be appended in the order the placeholders are allocated.
+type embedSQLBuilder struct {
+	argCounter *int
+	args       []interface{}
+	aliasCount int
+}
+
+// newEmbedSQLBuilder creates a builder that allocates placeholders starting at *argCounter
+func newEmbedSQLBuilder(argCounter *int) *embedSQLBuilder {
+	return &embedSQLBuilder{argCounter: argCounter}
+}
+
+// nextAlias returns a unique table alias so that self-referencing relations don't collide
+func (b *embedSQLBuilder) nextAlias() string {
+	b.aliasCount++
+	return fmt.Sprintf("_fb_e%d", b.aliasCount)
+}
+
+// addArg allocates a placeholder for the given value
+func (b *embedSQLBuilder) addArg(value interface{}) string {
+	placeholder := fmt.Sprintf("$%d", *b.argCounter)
+	*b.argCounter++
+	b.args = append(b.args, value)
+	return placeholder
+}
+
+// selectExpressions builds one SELECT list expression per embedded relation of parentRef
+func (b *embedSQLBuilder) selectExpressions(relations []EmbeddedRelation, parentRef string) []string {
+	var expressions []string
+
+	for i := range relations {
+		rel := &relations[i]
+		if rel.relationship == nil {
+			continue
+		}
+
+		alias := b.nextAlias()
+		targetRef := quoteIdentifier(alias)
+		rowAlias := quoteIdentifier(alias + "_row")
+
+		columns := embedColumnList(rel.relationship.Target, rel.Select, targetRef, len(rel.Embedded) > 0)
+		columns = append(columns, b.selectExpressions(rel.Embedded, targetRef)...)
+
+		conditions := b.relationConditions(rel, alias, targetRef, parentRef)
+
+		subquery := fmt.Sprintf("SELECT %s FROM %s AS %s WHERE %s",
+			strings.Join(columns, ", "),
+			qualifiedTableName(rel.relationship.Target),
+			targetRef,
+			strings.Join(conditions, " AND "))
+
+		if rel.relationship.isToOne() {
+			subquery += " LIMIT 1"
+			expressions = append(expressions, fmt.Sprintf("(SELECT row_to_json(%s) FROM (%s) AS %s) AS %s",
+				rowAlias, subquery, rowAlias, quoteIdentifier(rel.OutputName())))
+			continue
+		}
+
+		if len(rel.Order) > 0 {
+			orderParams := &QueryParams{Order: rel.Order}
+			if orderClause := orderParams.buildOrderClause(); orderClause != "" {
+				subquery += " ORDER BY " + orderClause
+			}
+		}
+		if rel.Limit != nil {
+			subquery += " LIMIT " + b.addArg(*rel.Limit)
+		}
+		if rel.Offset != nil {
+			subquery += " OFFSET " + b.addArg(*rel.Offset)
+		}
+
+		expressions = append(expressions, fmt.Sprintf("COALESCE((SELECT json_agg(%s) FROM (%s) AS %s), '[]'::json) AS %s",
+			rowAlias, subquery, rowAlias, quoteIdentifier(rel.OutputName())))
+	}
+
+	return expressions
+}
+
+// innerConditions builds EXISTS conditions for embedded relations marked !inner,
+// which remove parent rows that have no matching related row
+func (b *embedSQLBuilder) innerConditions(relations []EmbeddedRelation, parentRef string) []string {
+	var conditions []string
+
+	for i := range relations {
+		rel := &relations[i]
+		if rel.relationship == nil || !rel.Inner {
+			continue
+		}
+
+		alias := b.nextAlias()
+		targetRef := quoteIdentifier(alias)
+		relConditions := b.relationConditions(rel, alias, targetRef, parentRef)
+
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS %s WHERE %s)",
+			qualifiedTableName(rel.relationship.Target),
+			targetRef,
+			strings.Join(relConditions, " AND ")))
+	}
+
+	return conditions
+}
+
+// relationConditions builds the join condition, relation filters and nested !inner
+// conditions that apply to an embedded relation's rows
+func (b *embedSQLBuilder) relationConditions(rel *EmbeddedRelation, alias, targetRef, parentRef string) []string {
+	conditions := []string{rel.relationship.joinCondition(alias, targetRef, parentRef)}
+
+	if len(rel.Filters) > 0 {
+		filterParams := &QueryParams{Filters: rel.Filters}
+		whereClause, whereArgs := filterParams.buildWhereClause(b.argCounter)
+		if whereClause != "" {
+			conditions = append(conditions, whereClause)
+			b.args = append(b.args, whereArgs...)
+		}
+	}
+
+	return append(conditions, b.innerConditions(rel.Embedded, targetRef)...)
+}
+
+// joinCondition builds the SQL condition linking target rows to the parent row
+func (r *embedRelationship) joinCondition(alias, targetRef, parentRef string) string {
+	if r.Type == embedManyToMany && r.Junction != nil {
+		junctionRef := quoteIdentifier(alias + "_j")
+		parts := make([]string, 0, len(r.JunctionTargetColumns)+len(r.JunctionParentColumns))
+		for i := range r.JunctionTargetColumns {
+			parts = append(parts, fmt.Sprintf("%s.%s = %s.%s",
+				junctionRef, quoteIdentifier(r.JunctionTargetColumns[i]), targetRef, quoteIdentifier(r.TargetColumns[i])))
+		}
+		for i := range r.JunctionParentColumns {
+			parts = append(parts, fmt.Sprintf("%s.%s = %s.%s",
+				junctionRef, quoteIdentifier(r.JunctionParentColumns[i]), parentRef, quoteIdentifier(r.ParentColumns[i])))
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS %s WHERE %s)",
+			qualifiedTableName(*r.Junction), junctionRef, strings.Join(parts, " AND "))
+	}
+
+	parts := make([]string, 0, len(r.TargetColumns))
+	for i := range r.TargetColumns {
+		parts = append(parts, fmt.Sprintf("%s.%s = %s.%s",
+			targetRef, quoteIdentifier(r.TargetColumns[i]), parentRef, quoteIdentifier(r.ParentColumns[i])))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// embedColumnList builds the qualified column list for an embedded relation.
+// Geometry columns are converted to GeoJSON. If no columns are selected and the
+// relation has no nested embeds, all columns are returned.
+func embedColumnList(table database.TableInfo, selectCols []string, tableRef string, hasNested bool) []string {
+	var names []string
+	for _, col := range selectCols {
+		if col == "*" {
+			names = nil
+			for _, c := range table.Columns {
+				names = append(names, c.Name)
+			}
+			break
+		}
+		names = append(names, col)
+	}
+	if len(names) == 0 && len(selectCols) == 0 && !hasNested {
+		for _, c := range table.Columns {
+			names = append(names, c.Name)
+		}
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		quotedName := quoteIdentifier(name)
+		if quotedName == "" {
+			continue
+		}
+		col := table.GetColumn(name)
+		if col != nil && isGeometryColumn(col.DataType) {
+			columns = append(columns, fmt.Sprintf("ST_AsGeoJSON(%s.%s)::jsonb AS %s", tableRef, quotedName, quotedName))
+		} else {
+			columns = append(columns, tableRef+"."+quotedName)
+		}
+	}
+	return columns
+}
+
+// qualifiedTableName returns the quoted schema-qualified table name
+func qualifiedTableName(table database.TableInfo) string {
+	return fmt.Sprintf(`"%s"."%s"`, table.Schema, table.Name)
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// embedTestTables returns a small blog schema:
+// authors <- posts (author_id), posts <- comments (post_id), posts <-> tags via post_tags
+func embedTestTables() []database.TableInfo {
+	return []database.TableInfo{
+		{
+			Schema:     "public",
+			Name:       "authors",
+			PrimaryKey: []string{"id"},
+			Columns: []database.ColumnInfo{
+				{Name: "id", DataType: "uuid"},
+				{Name: "name", DataType: "text"},
+				{Name: "email", DataType: "text"},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "posts",
+			PrimaryKey: []string{"id"},
+			Columns: []database.ColumnInfo{
+				{Name: "id", DataType: "uuid"},
+				{Name: "title", DataType: "text"},
+				{Name: "author_id", DataType: "uuid"},
+				{Name: "location", DataType: "geometry"},
+			},
+			ForeignKeys: []database.ForeignKey{
+				{Name: "posts_author_id_fkey", ColumnName: "author_id", ReferencedTable: "public.authors", ReferencedColumn: "id"},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "comments",
+			PrimaryKey: []string{"id"},
+			Columns: []database.ColumnInfo{
+				{Name: "id", DataType: "uuid"},
+				{Name: "post_id", DataType: "uuid"},
+				{Name: "body", DataType: "text"},
+				{Name: "created_at", DataType: "timestamp with time zone"},
+			},
+			ForeignKeys: []database.ForeignKey{
+				{Name: "comments_post_id_fkey", ColumnName: "post_id", ReferencedTable: "public.posts", ReferencedColumn: "id"},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "tags",
+			PrimaryKey: []string{"id"},
+			Columns: []database.ColumnInfo{
+				{Name: "id", DataType: "integer"},
+				{Name: "label", DataType: "text"},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "post_tags",
+			PrimaryKey: []string{"post_id", "tag_id"},
+			Columns: []database.ColumnInfo{
+				{Name: "post_id", DataType: "uuid"},
+				{Name: "tag_id", DataType: "integer"},
+			},
+			ForeignKeys: []database.ForeignKey{
+				{Name: "post_tags_post_id_fkey", ColumnName: "post_id", ReferencedTable: "public.posts", ReferencedColumn: "id"},
+				{Name: "post_tags_tag_id_fkey", ColumnName: "tag_id", ReferencedTable: "public.tags", ReferencedColumn: "id"},
+			},
+		},
+	}
+}
+
+func parseEmbedQuery(t *testing.T, query string) *QueryParams {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	params, err := NewQueryParser(testConfig()).Parse(values)
+	require.NoError(t, err)
+	return params
+}
+
+func TestQueryParser_ParseEmbeddedRelations(t *testing.T) {
+	t.Run("nested relations keep their structure", func(t *testing.T) {
+		params := parseEmbedQuery(t, "select=id,title,author(name,email),comments(body,author(name))")
+
+		assert.Equal(t, []string{"id", "title"}, params.Select)
+		require.Len(t, params.Embedded, 2)
+		assert.Equal(t, "author", params.Embedded[0].Name)
+		assert.Equal(t, []string{"name", "email"}, params.Embedded[0].Select)
+		assert.Equal(t, "comments", params.Embedded[1].Name)
+		assert.Equal(t, []string{"body"}, params.Embedded[1].Select)
+		require.Len(t, params.Embedded[1].Embedded, 1)
+		assert.Equal(t, "author", params.Embedded[1].Embedded[0].Name)
+		assert.Equal(t, []string{"name"}, params.Embedded[1].Embedded[0].Select)
+	})
+
+	t.Run("alias, hint and inner modifiers", func(t *testing.T) {
+		params := parseEmbedQuery(t, "select=*,writer:authors!posts_author_id_fkey!inner(name)")
+
+		require.Len(t, params.Embedded, 1)
+		rel := params.Embedded[0]
+		assert.Equal(t, "authors", rel.Name)
+		assert.Equal(t, "writer", rel.Alias)
+		assert.Equal(t, "posts_author_id_fkey", rel.Hint)
+		assert.True(t, rel.Inner)
+		assert.Equal(t, "writer", rel.OutputName())
+	})
+
+	t.Run("scoped filters, order, limit and offset", func(t *testing.T) {
+		params := parseEmbedQuery(t, "select=*,author(name),comments(body)&author.name=eq.Alice&comments.order=created_at.desc&comments.limit=5&comments.offset=10&title=eq.Hello")
+
+		require.Len(t, params.Filters, 1)
+		assert.Equal(t, "title", params.Filters[0].Column)
+
+		author := params.Embedded[0]
+		require.Len(t, author.Filters, 1)
+		assert.Equal(t, "name", author.Filters[0].Column)
+		assert.Equal(t, OpEqual, author.Filters[0].Operator)
+		assert.Equal(t, "Alice", author.Filters[0].Value)
+
+		comments := params.Embedded[1]
+		require.Len(t, comments.Order, 1)
+		assert.Equal(t, "created_at", comments.Order[0].Column)
+		assert.True(t, comments.Order[0].Desc)
+		require.NotNil(t, comments.Limit)
+		assert.Equal(t, 5, *comments.Limit)
+		require.NotNil(t, comments.Offset)
+		assert.Equal(t, 10, *comments.Offset)
+	})
+
+	t.Run("nested scoped parameters", func(t *testing.T) {
+		params := parseEmbedQuery(t, "select=*,comments(body,author(name))&comments.author.name=like.A*")
+
+		nested := params.Embedded[0].Embedded[0]
+		require.Len(t, nested.Filters, 1)
+		assert.Equal(t, "name", nested.Filters[0].Column)
+		assert.Equal(t, OpLike, nested.Filters[0].Operator)
+	})
+
+	t.Run("scoped logical filters", func(t *testing.T) {
+		params := parseEmbedQuery(t, "select=*,comments(body)&comments.or=(body.eq.a,body.eq.b)")
+
+		comments := params.Embedded[0]
+		require.Len(t, comments.Filters, 2)
+		assert.True(t, comments.Filters[0].IsOr)
+		assert.True(t, comments.Filters[1].IsOr)
+	})
+
+	t.Run("invalid scoped limit", func(t *testing.T) {
+		values, _ := url.ParseQuery("select=*,comments(body)&comments.limit=abc")
+		_, err := NewQueryParser(testConfig()).Parse(values)
+		assert.Error(t, err)
+	})
+}
+
+func TestResolveEmbedRelationship(t *testing.T) {
+	tables := embedTestTables()
+	posts := *findTableInfo(tables, "public", "posts")
+	authors := *findTableInfo(tables, "public", "authors")
+
+	t.Run("many-to-one by table name", func(t *testing.T) {
+		rel, err := resolveEmbedRelationship(posts, &EmbeddedRelation{Name: "authors"}, tables)
+		require.NoError(t, err)
+		assert.Equal(t, embedManyToOne, rel.Type)
+		assert.Equal(t, "authors", rel.Target.Name)
+		assert.Equal(t, []string{"author_id"}, rel.ParentColumns)
+		assert.Equal(t, []string{"id"}, rel.TargetColumns)
+	})
+
+	t.Run("many-to-one by foreign key column", func(t *testing.T) {
+		rel, err := resolveEmbedRelationship(posts, &EmbeddedRelation{Name: "author_id"}, tables)
+		require.NoError(t, err)
+		assert.Equal(t, embedManyToOne, rel.Type)
+		assert.Equal(t, "authors", rel.Target.Name)
+	})
+
+	t.Run("one-to-many", func(t *testing.T) {
+		rel, err := resolveEmbedRelationship(posts, &EmbeddedRelation{Name: "comments"}, tables)
+		require.NoError(t, err)
+		assert.Equal(t, embedOneToMany, rel.Type)
+		assert.Equal(t, []string{"id"}, rel.ParentColumns)
+		assert.Equal(t, []string{"post_id"}, rel.TargetColumns)
+
+		rel, err = resolveEmbedRelationship(authors, &EmbeddedRelation{Name: "posts"}, tables)
+		require.NoError(t, err)
+		assert.Equal(t, embedOneToMany, rel.Type)
+	})
+
+	t.Run("many-to-many via junction table", func(t *testing.T) {
+		rel, err := resolveEmbedRelationship(posts, &EmbeddedRelation{Name: "tags"}, tables)
+		require.NoError(t, err)
+		assert.Equal(t, embedManyToMany, rel.Type)
+		require.NotNil(t, rel.Junction)
+		assert.Equal(t, "post_tags", rel.Junction.Name)
+		assert.Equal(t, []string{"post_id"}, rel.JunctionParentColumns)
+		assert.Equal(t, []string{"tag_id"}, rel.JunctionTargetColumns)
+	})
+
+	t.Run("unknown relation", func(t *testing.T) {
+		_, err := resolveEmbedRelationship(posts, &EmbeddedRelation{Name: "nope"}, tables)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not find a relationship")
+	})
+
+	t.Run("ambiguous relation requires hint", func(t *testing.T) {
+		ambiguous := embedTestTables()
+		for i := range ambiguous {
+			if ambiguous[i].Name == "posts" {
+				ambiguous[i].Columns = append(ambiguous[i].Columns, database.ColumnInfo{Name: "editor_id", DataType: "uuid"})
+				ambiguous[i].ForeignKeys = append(ambiguous[i].ForeignKeys, database.ForeignKey{
+					Name: "posts_editor_id_fkey", ColumnName: "editor_id", ReferencedTable: "public.authors", ReferencedColumn: "id",
+				})
+			}
+		}
+		parent := *findTableInfo(ambiguous, "public", "posts")
+
+		_, err := resolveEmbedRelationship(parent, &EmbeddedRelation{Name: "authors"}, ambiguous)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "more than one relationship")
+
+		rel, err := resolveEmbedRelationship(parent, &EmbeddedRelation{Name: "authors", Hint: "posts_editor_id_fkey"}, ambiguous)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"editor_id"}, rel.ParentColumns)
+
+		rel, err = resolveEmbedRelationship(parent, &EmbeddedRelation{Name: "authors", Hint: "author_id"}, ambiguous)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"author_id"}, rel.ParentColumns)
+	})
+}
+
+func TestResolveEmbeddedRelationsFor_Validation(t *testing.T) {
+	tables := embedTestTables()
+	posts := *findTableInfo(tables, "public", "posts")
+
+	tests := []struct {
+		name    string
+		rel     EmbeddedRelation
+		wantErr string
+	}{
+		{
+			name:    "unknown select column",
+			rel:     EmbeddedRelation{Name: "authors", Select: []string{"password"}},
+			wantErr: "unknown column",
+		},
+		{
+			name:    "unknown filter column",
+			rel:     EmbeddedRelation{Name: "authors", Filters: []Filter{{Column: "password", Operator: OpEqual, Value: "x"}}},
+			wantErr: "unknown filter column",
+		},
+		{
+			name:    "unknown order column",
+			rel:     EmbeddedRelation{Name: "comments", Order: []OrderBy{{Column: "password"}}},
+			wantErr: "unknown order column",
+		},
+		{
+			name:    "invalid alias",
+			rel:     EmbeddedRelation{Name: "authors", Alias: "a;drop"},
+			wantErr: "invalid embedded relation alias",
+		},
+		{
+			name:    "invalid relation name",
+			rel:     EmbeddedRelation{Name: "authors\"--"},
+			wantErr: "invalid embedded relation name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolveEmbeddedRelationsFor(posts, []EmbeddedRelation{tt.rel}, tables)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("duplicate output names", func(t *testing.T) {
+		err := resolveEmbeddedRelationsFor(posts, []EmbeddedRelation{{Name: "authors"}, {Name: "authors"}}, tables)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "more than once")
+	})
+
+	t.Run("JSONB path filter on existing column", func(t *testing.T) {
+		err := resolveEmbeddedRelationsFor(posts, []EmbeddedRelation{
+			{Name: "comments", Filters: []Filter{{Column: "body->>lang", Operator: OpEqual, Value: "en"}}},
+		}, tables)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRESTHandler_BuildSelectQuery_Embedded(t *testing.T) {
+	handler := NewRESTHandler(nil, nil, nil)
+	tables := embedTestTables()
+	posts := *findTableInfo(tables, "public", "posts")
+
+	build := func(t *testing.T, query string) (string, []interface{}) {
+		t.Helper()
+		params := parseEmbedQuery(t, query)
+		require.NoError(t, resolveEmbeddedRelationsFor(posts, params.Embedded, tables))
+		return handler.buildSelectQuery(posts, params)
+	}
+
+	t.Run("many-to-one embeds a single object", func(t *testing.T) {
+		sql, args := build(t, "select=id,title,authors(name)")
+
+		assert.Contains(t, sql, `SELECT "id", "title", (SELECT row_to_json("_fb_e1_row") FROM (SELECT "_fb_e1"."name" FROM "public"."authors" AS "_fb_e1" WHERE "_fb_e1"."id" = "public"."posts"."author_id" LIMIT 1) AS "_fb_e1_row") AS "authors"`)
+		assert.Contains(t, sql, `FROM "public"."posts"`)
+		assert.Empty(t, args)
+	})
+
+	t.Run("one-to-many embeds an array with order and limit", func(t *testing.T) {
+		sql, args := build(t, "select=id,comments(body)&comments.order=created_at.desc&comments.limit=3&limit=10")
+
+		assert.Contains(t, sql, `COALESCE((SELECT json_agg("_fb_e1_row") FROM (SELECT "_fb_e1"."body" FROM "public"."comments" AS "_fb_e1" WHERE "_fb_e1"."post_id" = "public"."posts"."id" ORDER BY "created_at" DESC LIMIT $1) AS "_fb_e1_row"), '[]'::json) AS "comments"`)
+		assert.True(t, strings.HasSuffix(sql, "LIMIT $2"))
+		assert.Equal(t, []interface{}{3, 10}, args)
+	})
+
+	t.Run("many-to-many joins through the junction table", func(t *testing.T) {
+		sql, _ := build(t, "select=id,tags(label)")
+
+		assert.Contains(t, sql, `FROM "public"."tags" AS "_fb_e1" WHERE EXISTS (SELECT 1 FROM "public"."post_tags" AS "_fb_e1_j" WHERE "_fb_e1_j"."tag_id" = "_fb_e1"."id" AND "_fb_e1_j"."post_id" = "public"."posts"."id")`)
+	})
+
+	t.Run("embedded filters do not filter parent rows", func(t *testing.T) {
+		sql, args := build(t, "select=id,authors(name)&authors.name=eq.Alice&title=eq.Hello")
+
+		assert.Contains(t, sql, `"_fb_e1"."id" = "public"."posts"."author_id" AND "name" = $1 LIMIT 1`)
+		assert.Contains(t, sql, `WHERE "title" = $2`)
+		assert.NotContains(t, sql, "EXISTS")
+		assert.Equal(t, []interface{}{"Alice", "Hello"}, args)
+	})
+
+	t.Run("inner embeds filter parent rows", func(t *testing.T) {
+		sql, args := build(t, "select=id,authors!inner(name)&authors.name=eq.Alice")
+
+		assert.Contains(t, sql, `WHERE EXISTS (SELECT 1 FROM "public"."authors" AS "_fb_e1" WHERE "_fb_e1"."id" = "public"."posts"."author_id" AND "name" = $2)`)
+		assert.Equal(t, []interface{}{"Alice", "Alice"}, args)
+	})
+
+	t.Run("nested embeds reference their parent alias", func(t *testing.T) {
+		sql, _ := build(t, "select=id,comments(body,posts(title))")
+
+		assert.Contains(t, sql, `FROM "public"."posts" AS "_fb_e2" WHERE "_fb_e2"."id" = "_fb_e1"."post_id"`)
+	})
+
+	t.Run("geometry columns are converted to GeoJSON", func(t *testing.T) {
+		comments := *findTableInfo(tables, "public", "comments")
+		params := parseEmbedQuery(t, "select=id,posts(location)")
+		require.NoError(t, resolveEmbeddedRelationsFor(comments, params.Embedded, tables))
+		sql, _ := handler.buildSelectQuery(comments, params)
+
+		assert.Contains(t, sql, `ST_AsGeoJSON("_fb_e1"."location")::jsonb AS "location"`)
+	})
+
+	t.Run("alias names the output key", func(t *testing.T) {
+		sql, _ := build(t, "select=id,writer:authors(name)")
+
+		assert.Contains(t, sql, `AS "writer"`)
+	})
+}
+
+func TestRESTHandler_BuildSelectQuery_CompositeForeignKey(t *testing.T) {
+	handler := NewRESTHandler(nil, nil, nil)
+	shipments := database.TableInfo{
+		Schema:     "public",
+		Name:       "shipments",
+		PrimaryKey: []string{"id"},
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "order_region", DataType: "text"},
+			{Name: "order_no", DataType: "integer"},
+		},
+		// One entry per column pair, in key order
+		ForeignKeys: []database.ForeignKey{
+			{Name: "shipments_order_fkey", ColumnName: "order_region", ReferencedTable: "public.orders", ReferencedColumn: "region"},
+			{Name: "shipments_order_fkey", ColumnName: "order_no", ReferencedTable: "public.orders", ReferencedColumn: "no"},
+		},
+	}
+	orders := database.TableInfo{
+		Schema:     "public",
+		Name:       "orders",
+		PrimaryKey: []string{"region", "no"},
+		Columns: []database.ColumnInfo{
+			{Name: "region", DataType: "text"},
+			{Name: "no", DataType: "integer"},
+			{Name: "customer", DataType: "text"},
+		},
+	}
+	tables := []database.TableInfo{shipments, orders}
+
+	rel, err := resolveEmbedRelationship(shipments, &EmbeddedRelation{Name: "orders"}, tables)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order_region", "order_no"}, rel.ParentColumns)
+	assert.Equal(t, []string{"region", "no"}, rel.TargetColumns)
+
+	params := parseEmbedQuery(t, "select=id,orders(customer)")
+	require.NoError(t, resolveEmbeddedRelationsFor(shipments, params.Embedded, tables))
+	sql, _ := handler.buildSelectQuery(shipments, params)
+	assert.Contains(t, sql, `WHERE "_fb_e1"."region" = "public"."shipments"."order_region" AND "_fb_e1"."no" = "public"."shipments"."order_no" LIMIT 1`)
+}
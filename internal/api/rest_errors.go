@@ -38,12 +38,13 @@ const (
 	ErrCodeInvalidFormat    = "INVALID_FORMAT"
 	ErrCodeValidationFailed = "VALIDATION_FAILED"
 
-	// Resource errors (404, 409)
+	// Resource errors (404, 409, 412)
 	ErrCodeNotFound            = "NOT_FOUND"
 	ErrCodeAlreadyExists       = "ALREADY_EXISTS"
 	ErrCodeDuplicateKey        = "DUPLICATE_KEY"
 	ErrCodeConflict            = "CONFLICT"
 	ErrCodeForeignKeyViolation = "FOREIGN_KEY_VIOLATION"
+	ErrCodePreconditionFailed  = "PRECONDITION_FAILED"
 
 	// Constraint errors (400)
 	ErrCodeNotNullViolation = "NOT_NULL_VIOLATION"
@@ -0,0 +1,527 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// dataFormat identifies a representation of table rows in request or response bodies
+type dataFormat string
+
+const (
+	formatJSON   dataFormat = "json"
+	formatCSV    dataFormat = "csv"
+	formatNDJSON dataFormat = "ndjson"
+	formatXLSX   dataFormat = "xlsx"
+
+	// formatGeoJSON is a response-only format: rows as a GeoJSON FeatureCollection
+	formatGeoJSON dataFormat = "geojson"
+)
+
+// Media types accepted for the non-JSON formats
+const (
+	mimeTextCSV     = "text/csv"
+	mimeNDJSON      = "application/x-ndjson"
+	mimeNDJSONAlt   = "application/ndjson"
+	mimeSpreadsheet = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeGeoJSON     = "application/geo+json"
+)
+
+// streamFlushEvery is the number of rows written between explicit flushes of a streamed response
+const streamFlushEvery = 500
+
+// formatFromMediaType maps a media type (without parameters) to a data format
+func formatFromMediaType(mediaType string) (dataFormat, bool) {
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case fiber.MIMEApplicationJSON, "*/*", "application/*":
+		return formatJSON, true
+	case mimeTextCSV:
+		return formatCSV, true
+	case mimeNDJSON, mimeNDJSONAlt:
+		return formatNDJSON, true
+	case mimeSpreadsheet:
+		return formatXLSX, true
+	case mimeGeoJSON:
+		return formatGeoJSON, true
+	default:
+		return "", false
+	}
+}
+
+// contentType returns the Content-Type header value used when responding with the format
+func (f dataFormat) contentType() string {
+	switch f {
+	case formatCSV:
+		return mimeTextCSV + "; charset=utf-8"
+	case formatNDJSON:
+		return mimeNDJSON
+	case formatXLSX:
+		return mimeSpreadsheet
+	case formatGeoJSON:
+		return mimeGeoJSON
+	default:
+		return fiber.MIMEApplicationJSONCharsetUTF8
+	}
+}
+
+// isStreamed reports whether responses in the format are streamed row by row
+func (f dataFormat) isStreamed() bool {
+	return f == formatCSV || f == formatNDJSON || f == formatXLSX
+}
+
+// negotiateResponseFormat picks the response format from an Accept header.
+// The supported media type with the highest quality wins; ties are resolved by order.
+// JSON is returned when the header is empty or names no supported type.
+func negotiateResponseFormat(accept string) dataFormat {
+	best := formatJSON
+	bestQ := -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, mediaParams, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		format, ok := formatFromMediaType(mediaType)
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if qs, ok := mediaParams["q"]; ok {
+			parsed, err := strconv.ParseFloat(qs, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q > 0 && q > bestQ {
+			best = format
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// requestBodyFormat returns the format of a request body from its Content-Type header
+func requestBodyFormat(contentType string) dataFormat {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return formatJSON
+	}
+	switch format, _ := formatFromMediaType(mediaType); format {
+	case formatCSV, formatNDJSON:
+		return format
+	default:
+		return formatJSON
+	}
+}
+
+// parseBulkBody parses a CSV or NDJSON request body into records for batchInsert
+func parseBulkBody(format dataFormat, body []byte) ([]map[string]interface{}, error) {
+	switch format {
+	case formatCSV:
+		return parseCSVRecords(body)
+	case formatNDJSON:
+		return parseNDJSONRecords(body)
+	default:
+		return nil, fmt.Errorf("unsupported body format: %s", format)
+	}
+}
+
+// parseCSVRecords parses a CSV body whose first line holds the column names.
+// Empty fields are inserted as NULL; all other values are sent as text and cast by PostgreSQL.
+func parseCSVRecords(body []byte) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+
+	columns, err := readCSVHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if fields[i] == "" {
+				record[col] = nil
+			} else {
+				record[col] = fields[i]
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// readCSVHeader reads the column names from the first line of a CSV body
+func readCSVHeader(reader *csv.Reader) ([]string, error) {
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("missing header row")
+		}
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		// Strip a UTF-8 byte order mark written by spreadsheet applications
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty column name in header at position %d", i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column in header: %s", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	return columns, nil
+}
+
+// parseNDJSONRecords parses a body with one JSON object per line. Blank lines are skipped.
+func parseNDJSONRecords(body []byte) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(text, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record == nil {
+			return nil, fmt.Errorf("line %d: expected a JSON object", line)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// rowSource is the subset of pgx.Rows used when streaming rows to a client
+type rowSource interface {
+	Next() bool
+	Values() ([]interface{}, error)
+	Err() error
+}
+
+// writeRows writes every row of the source to w in the given format.
+// CSV and NDJSON are flushed periodically so clients receive rows as they are read.
+func writeRows(w io.Writer, format dataFormat, columns []string, rows rowSource) error {
+	switch format {
+	case formatCSV:
+		return writeCSVRows(w, columns, rows)
+	case formatNDJSON:
+		return writeNDJSONRows(w, columns, rows)
+	case formatXLSX:
+		return writeXLSXRows(w, columns, rows)
+	default:
+		return fmt.Errorf("unsupported response format: %s", format)
+	}
+}
+
+// flushWriter flushes w if it buffers output
+func flushWriter(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func writeCSVRows(w io.Writer, columns []string, rows rowSource) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	count := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		for i := range record {
+			record[i] = formatCSVValue(convertPgxValue(values[i]))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+
+		count++
+		if count%streamFlushEvery == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			if err := flushWriter(w); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeNDJSONRows(w io.Writer, columns []string, rows rowSource) error {
+	// Pre-encode the keys so every line keeps the column order of the query
+	keys := make([][]byte, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	var buf bytes.Buffer
+	count := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+
+		buf.Reset()
+		buf.WriteByte('{')
+		for i := range columns {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			value, err := json.Marshal(convertPgxValue(values[i]))
+			if err != nil {
+				return fmt.Errorf("failed to encode column %s: %w", columns[i], err)
+			}
+			buf.Write(keys[i])
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteString("}\n")
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+
+		count++
+		if count%streamFlushEvery == 0 {
+			if err := flushWriter(w); err != nil {
+				return err
+			}
+		}
+	}
+
+	return rows.Err()
+}
+
+// writeXLSXRows writes the rows to a single-sheet workbook. The sheet is built with
+// excelize's stream writer, which spills to disk for large results, and the finished
+// workbook is written once all rows have been read.
+func writeXLSXRows(w io.Writer, columns []string, rows rowSource) error {
+	f := excelize.NewFile()
+	defer func() { _ = f.Close() }()
+
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	rowNum := 1
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+
+		cells := make([]interface{}, len(values))
+		for i, v := range values {
+			cells[i] = xlsxCellValue(convertPgxValue(v))
+		}
+
+		rowNum++
+		cell, err := excelize.CoordinatesToCellName(1, rowNum)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// formatCSVValue renders a converted column value as a CSV field.
+// NULL becomes an empty field, and objects and arrays are written as JSON.
+func formatCSVValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	// Scalars that marshal to JSON strings (timestamps, numerics, ...) are written unquoted
+	var s string
+	if err := json.Unmarshal(encoded, &s); err == nil {
+		return s
+	}
+	return string(encoded)
+}
+
+// xlsxCellValue keeps numbers and booleans native so spreadsheets can compute with them
+func xlsxCellValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, string,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return val
+	case pgtype.Numeric:
+		if f, err := val.Float64Value(); err == nil && f.Valid {
+			return f.Float64
+		}
+	}
+	return formatCSVValue(v)
+}
+
+// streamSelect runs a SELECT under RLS and streams the rows to the client in a non-JSON format.
+// The RLS transaction is started here so query errors still produce a proper error response;
+// it stays open until the body stream writer has consumed every row.
+func (h *RESTHandler) streamSelect(c *fiber.Ctx, table database.TableInfo, params *QueryParams, query string, args []interface{}, format dataFormat) error {
+	// Counts are computed up front since the number of streamed rows isn't known in advance
+	if params.Count != CountNone && params.Count != "" {
+		count, err := h.getCount(c.Context(), c, table, params)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to get count")
+		} else {
+			c.Set("Content-Range", fmt.Sprintf("*/%d", count))
+		}
+	}
+
+	// The request context is recycled once the handler returns, so the stream gets its own
+	streamCtx, cancel := context.WithCancel(context.Background())
+
+	tx, err := middleware.BeginWithRLS(streamCtx, h.db, c)
+	if err != nil {
+		cancel()
+		log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to start export transaction")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch records",
+		})
+	}
+
+	log.Debug().Str("query", query).Interface("args", args).Str("format", string(format)).Msg("Executing streamed SELECT query")
+	rows, err := tx.Query(streamCtx, query, args...)
+	if err != nil {
+		_ = tx.Rollback(streamCtx)
+		cancel()
+		log.Error().Err(err).Str("query", query).Msg("Failed to execute query")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch records",
+		})
+	}
+
+	fields := rows.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+	source, columns := newPolicyRowSource(rows, columns, table, columnPolicyRole(c), params.Embedded)
+
+	c.Set(fiber.HeaderContentType, format.contentType())
+	if format == formatCSV || format == formatXLSX {
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, table.Name, format))
+	}
+
+	tableName := fmt.Sprintf("%s.%s", table.Schema, table.Name)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer func() { _ = tx.Rollback(streamCtx) }()
+		defer rows.Close()
+
+		if err := writeRows(w, format, columns, source); err != nil {
+			// Headers are already sent, so the error can only be logged
+			log.Error().Err(err).Str("table", tableName).Str("format", string(format)).Msg("Failed to stream records")
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Debug().Err(err).Str("table", tableName).Msg("Client disconnected during export")
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// fakeRows is an in-memory rowSource for the stream writers
+type fakeRows struct {
+	rows [][]interface{}
+	pos  int
+}
+
+func (r *fakeRows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeRows) Values() ([]interface{}, error) {
+	return r.rows[r.pos-1], nil
+}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+func TestNegotiateResponseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected dataFormat
+	}{
+		{name: "empty header", accept: "", expected: formatJSON},
+		{name: "wildcard", accept: "*/*", expected: formatJSON},
+		{name: "json", accept: "application/json", expected: formatJSON},
+		{name: "csv", accept: "text/csv", expected: formatCSV},
+		{name: "csv with charset", accept: "text/csv; charset=utf-8", expected: formatCSV},
+		{name: "ndjson", accept: "application/x-ndjson", expected: formatNDJSON},
+		{name: "ndjson alternative", accept: "application/ndjson", expected: formatNDJSON},
+		{name: "xlsx", accept: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", expected: formatXLSX},
+		{name: "geojson", accept: "application/geo+json", expected: formatGeoJSON},
+		{name: "unsupported falls back to json", accept: "text/html", expected: formatJSON},
+		{name: "first supported wins on tie", accept: "text/html, text/csv, application/json", expected: formatCSV},
+		{name: "quality preferred", accept: "application/json;q=0.5, application/x-ndjson;q=0.9", expected: formatNDJSON},
+		{name: "zero quality ignored", accept: "text/csv;q=0", expected: formatJSON},
+		{name: "case insensitive", accept: "Text/CSV", expected: formatCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateResponseFormat(tt.accept))
+		})
+	}
+}
+
+func TestRequestBodyFormat(t *testing.T) {
+	assert.Equal(t, formatCSV, requestBodyFormat("text/csv"))
+	assert.Equal(t, formatCSV, requestBodyFormat("text/csv; charset=utf-8"))
+	assert.Equal(t, formatNDJSON, requestBodyFormat("application/x-ndjson"))
+	assert.Equal(t, formatJSON, requestBodyFormat("application/json"))
+	assert.Equal(t, formatJSON, requestBodyFormat(""))
+	// Spreadsheets are only supported as a response format
+	assert.Equal(t, formatJSON, requestBodyFormat("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"))
+	assert.Equal(t, formatJSON, requestBodyFormat("application/geo+json"))
+}
+
+func TestParseCSVRecords(t *testing.T) {
+	t.Run("header and rows", func(t *testing.T) {
+		body := "\ufeffname,age,bio\nAlice,30,\"likes, commas\"\nBob,,\n"
+		records, err := parseCSVRecords([]byte(body))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		assert.Equal(t, map[string]interface{}{"name": "Alice", "age": "30", "bio": "likes, commas"}, records[0])
+		assert.Equal(t, map[string]interface{}{"name": "Bob", "age": nil, "bio": nil}, records[1])
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		_, err := parseCSVRecords(nil)
+		assert.ErrorContains(t, err, "missing header row")
+	})
+
+	t.Run("duplicate header", func(t *testing.T) {
+		_, err := parseCSVRecords([]byte("a,a\n1,2\n"))
+		assert.ErrorContains(t, err, "duplicate column")
+	})
+
+	t.Run("empty header column", func(t *testing.T) {
+		_, err := parseCSVRecords([]byte("a,\n1,2\n"))
+		assert.ErrorContains(t, err, "empty column name")
+	})
+
+	t.Run("ragged row", func(t *testing.T) {
+		_, err := parseCSVRecords([]byte("a,b\n1\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestParseNDJSONRecords(t *testing.T) {
+	t.Run("objects with blank lines", func(t *testing.T) {
+		body := "{\"name\":\"Alice\",\"age\":30}\n\n{\"name\":\"Bob\",\"tags\":[\"a\"]}\r\n"
+		records, err := parseNDJSONRecords([]byte(body))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		assert.Equal(t, "Alice", records[0]["name"])
+		assert.Equal(t, float64(30), records[0]["age"])
+		assert.Equal(t, []interface{}{"a"}, records[1]["tags"])
+	})
+
+	t.Run("invalid line reports line number", func(t *testing.T) {
+		_, err := parseNDJSONRecords([]byte("{\"a\":1}\n{bad\n"))
+		assert.ErrorContains(t, err, "line 2")
+	})
+
+	t.Run("non-object line", func(t *testing.T) {
+		_, err := parseNDJSONRecords([]byte("null\n"))
+		assert.ErrorContains(t, err, "expected a JSON object")
+	})
+}
+
+func TestFormatCSVValue(t *testing.T) {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: ""},
+		{name: "string", value: "hello", expected: "hello"},
+		{name: "int", value: int64(42), expected: "42"},
+		{name: "float", value: 1.5, expected: "1.5"},
+		{name: "bool", value: true, expected: "true"},
+		{name: "timestamp", value: ts, expected: "2025-01-02T03:04:05Z"},
+		{name: "object", value: map[string]interface{}{"a": float64(1)}, expected: `{"a":1}`},
+		{name: "array", value: []interface{}{"x", "y"}, expected: `["x","y"]`},
+		{name: "numeric", value: pgtype.Numeric{Int: big.NewInt(12345), Exp: -2, Valid: true}, expected: "123.45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatCSVValue(tt.value))
+		})
+	}
+}
+
+func TestWriteRows(t *testing.T) {
+	columns := []string{"id", "name", "meta"}
+	newRows := func() *fakeRows {
+		return &fakeRows{rows: [][]interface{}{
+			{int32(1), "Alice", map[string]interface{}{"admin": true}},
+			{int32(2), nil, nil},
+		}}
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeRows(&buf, formatCSV, columns, newRows()))
+		assert.Equal(t, "id,name,meta\n1,Alice,\"{\"\"admin\"\":true}\"\n2,,\n", buf.String())
+	})
+
+	t.Run("ndjson keeps column order", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeRows(&buf, formatNDJSON, []string{"name", "id", "meta"}, &fakeRows{rows: [][]interface{}{
+			{"Alice", int32(1), nil},
+		}}))
+		assert.Equal(t, "{\"name\":\"Alice\",\"id\":1,\"meta\":null}\n", buf.String())
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeRows(&buf, formatXLSX, columns, newRows()))
+
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+
+		rows, err := f.GetRows("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, columns, rows[0])
+		assert.Equal(t, []string{"1", "Alice", `{"admin":true}`}, rows[1])
+		assert.Equal(t, []string{"2"}, rows[2])
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, writeRows(&buf, formatJSON, columns, newRows()))
+	})
+}
@@ -102,3 +102,58 @@ func buildSelectColumnsWithTruncation(table database.TableInfo, truncateLength *
 func buildReturningClause(table database.TableInfo) string {
 	return " RETURNING " + buildSelectColumns(table)
 }
+
+// prepareFeatureCollection resolves the geometry column of a GeoJSON FeatureCollection
+// response and makes sure an explicit select list includes it
+func prepareFeatureCollection(table database.TableInfo, params *QueryParams, requested string) (database.ColumnInfo, error) {
+	if len(params.Aggregations) > 0 || len(params.GroupBy) > 0 || len(params.Having) > 0 || params.AsOf != nil {
+		return database.ColumnInfo{}, fmt.Errorf("GeoJSON responses don't support aggregates or as_of")
+	}
+
+	geom, err := resolveGeometryColumn(table, requested)
+	if err != nil {
+		return geom, err
+	}
+
+	if len(params.Select) > 0 && !(len(params.Select) == 1 && params.Select[0] == "*") {
+		for _, name := range params.Select {
+			if name == geom.Name {
+				return geom, nil
+			}
+		}
+		params.Select = append(params.Select, geom.Name)
+	}
+	return geom, nil
+}
+
+// toFeatureCollection converts result rows into a GeoJSON FeatureCollection. The geometry
+// column becomes the feature geometry, single-column primary keys the feature id and the
+// remaining columns the feature properties.
+func toFeatureCollection(table database.TableInfo, geomColumn string, rows []map[string]interface{}) map[string]interface{} {
+	features := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		properties := make(map[string]interface{}, len(row))
+		for key, value := range row {
+			if key != geomColumn {
+				properties[key] = value
+			}
+		}
+
+		feature := map[string]interface{}{
+			"type":       "Feature",
+			"geometry":   row[geomColumn],
+			"properties": properties,
+		}
+		if len(table.PrimaryKey) == 1 {
+			if id, ok := row[table.PrimaryKey[0]]; ok && id != nil {
+				feature["id"] = id
+			}
+		}
+		features = append(features, feature)
+	}
+
+	return map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+}
@@ -7,15 +7,17 @@ import (
 	"github.com/fluxbase-eu/fluxbase/internal/auth"
 	"github.com/fluxbase-eu/fluxbase/internal/database"
 	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/fluxbase-eu/fluxbase/internal/responsecache"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 )
 
 // RESTHandler handles dynamic REST API endpoints
 type RESTHandler struct {
-	db          *database.Connection
-	parser      *QueryParser
-	schemaCache *database.SchemaCache
+	db            *database.Connection
+	parser        *QueryParser
+	schemaCache   *database.SchemaCache
+	responseCache *responsecache.Cache // nil unless the response cache is enabled
 }
 
 // NewRESTHandler creates a new REST handler
@@ -92,7 +94,7 @@ func (h *RESTHandler) HandleDynamicTable(c *fiber.Ctx) error {
 	switch c.Method() {
 	case "GET":
 		// Scope check is handled by middleware before this handler
-		return h.makeGetHandler(*tableInfo)(c)
+		return h.serveCached(c, *tableInfo, h.makeGetHandler(*tableInfo))
 	case "POST":
 		// Check for /query suffix (POST-based query)
 		if strings.HasSuffix(c.Path(), "/query") {
@@ -103,21 +105,21 @@ func (h *RESTHandler) HandleDynamicTable(c *fiber.Ctx) error {
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makePostHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makePostHandler(*tableInfo))
 	case "PATCH":
 		if !isWritable {
 			return c.Status(405).JSON(fiber.Map{
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makeBatchPatchHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makeBatchPatchHandler(*tableInfo))
 	case "DELETE":
 		if !isWritable {
 			return c.Status(405).JSON(fiber.Map{
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makeBatchDeleteHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makeBatchDeleteHandler(*tableInfo))
 	default:
 		return c.Status(405).JSON(fiber.Map{
 			"error": fmt.Sprintf("Method %s not allowed", c.Method()),
@@ -156,28 +158,28 @@ func (h *RESTHandler) HandleDynamicTableById(c *fiber.Ctx) error {
 	// Dispatch based on HTTP method
 	switch c.Method() {
 	case "GET":
-		return h.makeGetByIdHandler(*tableInfo)(c)
+		return h.serveCached(c, *tableInfo, h.makeGetByIdHandler(*tableInfo))
 	case "PUT":
 		if !isWritable {
 			return c.Status(405).JSON(fiber.Map{
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makePutHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makePutHandler(*tableInfo))
 	case "PATCH":
 		if !isWritable {
 			return c.Status(405).JSON(fiber.Map{
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makePatchHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makePatchHandler(*tableInfo))
 	case "DELETE":
 		if !isWritable {
 			return c.Status(405).JSON(fiber.Map{
 				"error": fmt.Sprintf("Table '%s.%s' is read-only (view or materialized view)", schema, tableName),
 			})
 		}
-		return h.makeDeleteHandler(*tableInfo)(c)
+		return h.invalidateAfterWrite(c, *tableInfo, h.makeDeleteHandler(*tableInfo))
 	default:
 		return c.Status(405).JSON(fiber.Map{
 			"error": fmt.Sprintf("Method %s not allowed", c.Method()),
@@ -222,12 +224,15 @@ func (h *RESTHandler) RegisterTableRoutes(router fiber.Router, table database.Ta
 	// Read operations require read:tables scope
 	router.Get(basePath, middleware.RequireScope(auth.ScopeTablesRead), h.makeGetHandler(table))
 	router.Get(basePath+"/:id", middleware.RequireScope(auth.ScopeTablesRead), h.makeGetByIdHandler(table))
+	router.Get(basePath+"/:id/history", middleware.RequireScope(auth.ScopeTablesRead), h.makeRowHistoryHandler(table))
+	router.Get(basePath+"/tiles/:z/:x/:y.mvt", middleware.RequireScope(auth.ScopeTablesRead), h.makeTileHandler(table))
 
 	// POST-based query endpoint for complex filters (avoids URL length limits)
 	router.Post(basePath+"/query", middleware.RequireScope(auth.ScopeTablesRead), h.makePostQueryHandler(table))
 
 	// Write operations require write:tables scope
 	router.Post(basePath, middleware.RequireScope(auth.ScopeTablesWrite), h.makePostHandler(table))
+	router.Post(basePath+"/bulk", middleware.RequireScope(auth.ScopeTablesWrite), h.makeBulkLoadHandler(table))
 	router.Put(basePath+"/:id", middleware.RequireScope(auth.ScopeTablesWrite), h.makePutHandler(table))
 	router.Patch(basePath+"/:id", middleware.RequireScope(auth.ScopeTablesWrite), h.makePatchHandler(table))   // Single record update
 	router.Patch(basePath, middleware.RequireScope(auth.ScopeTablesWrite), h.makeBatchPatchHandler(table))     // Batch update with filters
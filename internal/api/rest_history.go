@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultRowHistoryLimit is the page size of a row history listing
+	defaultRowHistoryLimit = 100
+	// maxRowHistoryLimit caps the page size of a row history listing
+	maxRowHistoryLimit = 1000
+)
+
+// asOfLayouts are the accepted as_of formats; values without a zone are read as UTC
+var asOfLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// RowHistoryEntry is a recorded change of a single row
+type RowHistoryEntry struct {
+	ID            int64                  `json:"id"`
+	Operation     string                 `json:"operation"`
+	OldData       map[string]interface{} `json:"old_data"`
+	NewData       map[string]interface{} `json:"new_data"`
+	ChangedBy     *string                `json:"changed_by"`
+	ChangedByRole *string                `json:"changed_by_role"`
+	ChangedAt     time.Time              `json:"changed_at"`
+}
+
+// parseAsOf parses an as_of timestamp
+func parseAsOf(value string) (time.Time, error) {
+	for _, layout := range asOfLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not an RFC 3339 timestamp or date", value)
+}
+
+// canReadRowHistory checks if the caller may read recorded row history (admins and service role)
+func canReadRowHistory(c *fiber.Ctx) bool {
+	role, _ := c.Locals("user_role").(string)
+	return role == "admin" || role == "dashboard_admin" || role == "service_role"
+}
+
+// historySnapshotSQL returns a FROM source reproducing the table at a point in time from
+// the latest recorded version of each row. Only entries since history was (re-)enabled are
+// used, as the snapshot taken at that moment is the baseline. Deleted rows are dropped.
+// The four parameters starting at argN are the schema, table, enabled_at and as_of.
+func historySnapshotSQL(table database.TableInfo, argN int) string {
+	return fmt.Sprintf(
+		`(SELECT (jsonb_populate_record(NULL::%s, h.new_data)).* FROM (`+
+			`SELECT DISTINCT ON (row_pk) operation, new_data FROM api.row_history `+
+			`WHERE schema_name = $%d AND table_name = $%d AND changed_at >= $%d AND changed_at <= $%d `+
+			`ORDER BY row_pk, changed_at DESC, id DESC) h WHERE h.operation <> 'DELETE') AS %s`,
+		qualifiedTableName(table), argN, argN+1, argN+2, argN+3, quoteIdentifier(table.Name),
+	)
+}
+
+// buildAsOfQuery builds a SELECT over the table as it was at params.AsOf
+func (h *RESTHandler) buildAsOfQuery(table database.TableInfo, params *QueryParams, enabledAt time.Time) (string, []interface{}) {
+	params.bindTextSearchColumns(table)
+	whereAndMore, args := params.ToSQL(table.Name)
+
+	selectClause := h.buildSelectList(table, params)
+	snapshot := historySnapshotSQL(table, len(args)+1)
+	args = append(args, table.Schema, table.Name, enabledAt, *params.AsOf)
+	if headlines, headlineArgs := params.buildHeadlineColumns(len(args) + 1); len(headlines) > 0 {
+		selectClause += ", " + strings.Join(headlines, ", ")
+		args = append(args, headlineArgs...)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", selectClause, snapshot)
+
+	if whereAndMore != "" {
+		query += " " + whereAndMore
+	}
+
+	return query, args
+}
+
+// buildAsOfCountQuery builds the row count for an as_of read
+func buildAsOfCountQuery(table database.TableInfo, params *QueryParams, enabledAt time.Time) (string, []interface{}) {
+	var args []interface{}
+	var whereClause string
+	argCounter := 1
+	if len(params.Filters) > 0 {
+		whereClause, args = params.buildWhereClause(&argCounter)
+	}
+
+	query := "SELECT COUNT(*) FROM " + historySnapshotSQL(table, argCounter)
+	args = append(args, table.Schema, table.Name, enabledAt, *params.AsOf)
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
+
+	return query, args
+}
+
+// historyEnabledAt returns when history recording started for a table, if it is enabled
+func historyEnabledAt(ctx context.Context, tx pgx.Tx, table database.TableInfo) (time.Time, bool, error) {
+	var enabledAt time.Time
+	err := tx.QueryRow(ctx, `
+SELECT enabled_at FROM api.history_registry
+WHERE schema_name = $1 AND table_name = $2 AND history_enabled = true`,
+		table.Schema, table.Name,
+	).Scan(&enabledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return enabledAt, true, nil
+}
+
+// selectAsOf answers a read with the table's rows as they were at params.AsOf.
+// History bypasses the table's own RLS policies, so it is limited to admins and the service role.
+func (h *RESTHandler) selectAsOf(c *fiber.Ctx, table database.TableInfo, params *QueryParams) error {
+	if !canReadRowHistory(c) {
+		return SendErrorWithCode(c, fiber.StatusForbidden, "Row history is only available to admins and the service role", ErrCodeAdminRequired)
+	}
+	if table.Type != "table" {
+		return SendErrorWithCode(c, fiber.StatusBadRequest, "as_of is only supported for tables", ErrCodeInvalidInput)
+	}
+	if len(params.Embedded) > 0 {
+		return SendErrorWithDetails(c, fiber.StatusBadRequest, "as_of cannot be combined with embedded resources", ErrCodeInvalidInput,
+			"Embedded resources are read from live data", "Query the related table with as_of separately", nil)
+	}
+
+	ctx := c.Context()
+	tx, err := middleware.BeginWithRLS(ctx, h.db, c)
+	if err != nil {
+		log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to start transaction for as_of read")
+		return SendInternalError(c, "Failed to fetch records")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	enabledAt, enabled, err := historyEnabledAt(ctx, tx, table)
+	if err != nil {
+		log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to look up row history configuration")
+		return SendInternalError(c, "Failed to fetch records")
+	}
+	if !enabled {
+		return SendErrorWithDetails(c, fiber.StatusBadRequest, "Row history is not enabled", ErrCodeInvalidInput,
+			fmt.Sprintf("Row history is not enabled for table '%s.%s'", table.Schema, table.Name),
+			"An admin can enable it with POST /api/v1/admin/history/tables", nil)
+	}
+	if params.AsOf.Before(enabledAt) {
+		return SendErrorWithDetails(c, fiber.StatusBadRequest, "as_of is before row history was enabled", ErrCodeInvalidInput,
+			fmt.Sprintf("History for '%s.%s' starts at %s", table.Schema, table.Name, enabledAt.Format(time.RFC3339Nano)),
+			"", map[string]interface{}{"history_enabled_at": enabledAt})
+	}
+
+	query, args := h.buildAsOfQuery(table, params, enabledAt)
+	log.Debug().Str("query", query).Interface("args", args).Msg("Executing as_of query")
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Str("query", query).Msg("Failed to execute as_of query")
+		return handleDatabaseError(c, err, "read row history")
+	}
+	results, err := pgxRowsToJSON(rows)
+	rows.Close()
+	if err != nil {
+		return handleDatabaseError(c, err, "read row history")
+	}
+
+	if params.Count != CountNone && params.Count != "" {
+		countQuery, countArgs := buildAsOfCountQuery(table, params, enabledAt)
+		var count int
+		if err := tx.QueryRow(ctx, countQuery, countArgs...).Scan(&count); err != nil {
+			log.Warn().Err(err).Msg("Failed to get as_of count")
+		} else {
+			start := 0
+			if params.Offset != nil {
+				start = *params.Offset
+			}
+			c.Set("Content-Range", fmt.Sprintf("%d-%d/%d", start, start+len(results)-1, count))
+		}
+	}
+
+	applyColumnPolicies(table, columnPolicyRole(c), params.Embedded, results)
+
+	return c.JSON(results)
+}
+
+// HandleRowHistory lists the recorded changes of a single row for any table via dynamic lookup
+func (h *RESTHandler) HandleRowHistory(c *fiber.Ctx) error {
+	ctx := c.Context()
+	schema, tableName := h.parseTableFromPath(c)
+
+	tableInfo, exists, err := h.schemaCache.GetTable(ctx, schema, tableName)
+	if err != nil {
+		log.Error().Err(err).Str("schema", schema).Str("table", tableName).Msg("Failed to lookup table")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to lookup table metadata",
+		})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' not found", schema, tableName),
+		})
+	}
+
+	return h.makeRowHistoryHandler(*tableInfo)(c)
+}
+
+// makeRowHistoryHandler creates a GET handler listing a record's history, newest first
+func (h *RESTHandler) makeRowHistoryHandler(table database.TableInfo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !canReadRowHistory(c) {
+			return SendErrorWithCode(c, fiber.StatusForbidden, "Row history is only available to admins and the service role", ErrCodeAdminRequired)
+		}
+
+		limit := c.QueryInt("limit", defaultRowHistoryLimit)
+		if limit <= 0 || limit > maxRowHistoryLimit {
+			return SendErrorWithCode(c, fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxRowHistoryLimit), ErrCodeInvalidInput)
+		}
+		offset := c.QueryInt("offset", 0)
+		if offset < 0 {
+			return SendErrorWithCode(c, fiber.StatusBadRequest, "offset must be a non-negative integer", ErrCodeInvalidInput)
+		}
+
+		// Rows are identified by their first primary key column, like the other by-id endpoints
+		pkColumn := "id"
+		if len(table.PrimaryKey) > 0 {
+			pkColumn = table.PrimaryKey[0]
+		}
+
+		query := `
+SELECT id, operation, old_data, new_data, changed_by::text, changed_by_role, changed_at
+FROM api.row_history
+WHERE schema_name = $1 AND table_name = $2 AND row_pk ->> $3 = $4
+ORDER BY changed_at DESC, id DESC
+LIMIT $5 OFFSET $6`
+
+		ctx := c.Context()
+		entries := []RowHistoryEntry{}
+		err := middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
+			rows, err := tx.Query(ctx, query, table.Schema, table.Name, pkColumn, c.Params("id"), limit, offset)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var e RowHistoryEntry
+				if err := rows.Scan(&e.ID, &e.Operation, &e.OldData, &e.NewData, &e.ChangedBy, &e.ChangedByRole, &e.ChangedAt); err != nil {
+					return err
+				}
+				entries = append(entries, e)
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to fetch row history")
+			return handleDatabaseError(c, err, "read row history")
+		}
+
+		return c.JSON(entries)
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema: "public",
+		Name:   "orders",
+		Type:   "table",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "status", DataType: "text"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	ts, err := parseAsOf("2025-03-01T10:30:00+02:00")
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC)))
+
+	ts, err = parseAsOf("2025-03-01T10:30:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC), ts)
+
+	ts, err = parseAsOf("2025-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), ts)
+
+	_, err = parseAsOf("yesterday")
+	assert.Error(t, err)
+}
+
+func TestQueryParser_AsOf(t *testing.T) {
+	parser := NewQueryParser(testConfig())
+
+	params, err := parser.Parse(url.Values{"as_of": {"2025-03-01T00:00:00Z"}})
+	require.NoError(t, err)
+	require.NotNil(t, params.AsOf)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *params.AsOf)
+
+	_, err = parser.Parse(url.Values{"as_of": {"soon"}})
+	assert.ErrorContains(t, err, "invalid as_of parameter")
+}
+
+func TestBuildAsOfQuery(t *testing.T) {
+	handler := &RESTHandler{parser: NewQueryParser(testConfig())}
+	table := historyTestTable()
+	asOf := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	enabledAt := asOf.Add(-time.Hour)
+	limit := 10
+
+	params := &QueryParams{
+		Filters: []Filter{{Column: "status", Operator: OpEqual, Value: "paid"}},
+		Limit:   &limit,
+		AsOf:    &asOf,
+	}
+
+	query, args := handler.buildAsOfQuery(table, params, enabledAt)
+	assert.Contains(t, query, `jsonb_populate_record(NULL::"public"."orders", h.new_data)`)
+	assert.Contains(t, query, `WHERE schema_name = $3 AND table_name = $4 AND changed_at >= $5 AND changed_at <= $6`)
+	assert.Contains(t, query, `h.operation <> 'DELETE') AS "orders" WHERE "status" = $1 LIMIT $2`)
+	assert.Equal(t, []interface{}{"paid", 10, "public", "orders", enabledAt, asOf}, args)
+
+	countQuery, countArgs := buildAsOfCountQuery(table, params, enabledAt)
+	assert.True(t, strings.HasPrefix(countQuery, "SELECT COUNT(*) FROM (SELECT"), countQuery)
+	assert.Contains(t, countQuery, `table_name = $3 AND changed_at >= $4 AND changed_at <= $5`)
+	assert.True(t, strings.HasSuffix(countQuery, `AS "orders" WHERE "status" = $1`), countQuery)
+	assert.Equal(t, []interface{}{"paid", "public", "orders", enabledAt, asOf}, countArgs)
+}
+
+func TestAsOf_RequiresAdmin(t *testing.T) {
+	handler := &RESTHandler{parser: NewQueryParser(testConfig())}
+	table := historyTestTable()
+
+	app := fiber.New()
+	app.Get("/orders", func(c *fiber.Ctx) error {
+		c.Locals("user_role", "authenticated")
+		return handler.makeGetHandler(table)(c)
+	})
+	app.Get("/orders/:id/history", func(c *fiber.Ctx) error {
+		c.Locals("user_role", "authenticated")
+		return handler.makeRowHistoryHandler(table)(c)
+	})
+
+	for _, path := range []string{"/orders?as_of=2025-03-01", "/orders/1/history"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, path)
+	}
+}
+
+func TestAsOf_InvalidRequests(t *testing.T) {
+	handler := &RESTHandler{parser: NewQueryParser(testConfig())}
+	table := historyTestTable()
+
+	app := fiber.New()
+	app.Get("/orders", func(c *fiber.Ctx) error {
+		c.Locals("user_role", "service_role")
+		return handler.makeGetHandler(table)(c)
+	})
+	app.Get("/orders/:id/history", func(c *fiber.Ctx) error {
+		c.Locals("user_role", "service_role")
+		return handler.makeRowHistoryHandler(table)(c)
+	})
+
+	for _, path := range []string{
+		"/orders?as_of=not-a-time",
+		"/orders/1/history?limit=0",
+		"/orders/1/history?limit=5000",
+		"/orders/1/history?offset=-1",
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, path)
+	}
+
+	view := table
+	view.Type = "view"
+	viewApp := fiber.New()
+	viewApp.Get("/orders", func(c *fiber.Ctx) error {
+		c.Locals("user_role", "service_role")
+		return handler.makeGetHandler(view)(c)
+	})
+	resp, err := viewApp.Test(httptest.NewRequest("GET", "/orders?as_of=2025-03-01", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JSONSchemaRowViolation locates a JSON Schema violation in a request body
+type JSONSchemaRowViolation struct {
+	Row *int `json:"row,omitempty"` // Index of the record in a batch; omitted for single records
+	database.JSONSchemaViolation
+}
+
+// jsonSchemaError is returned when documents written to json columns don't match their JSON Schemas
+type jsonSchemaError struct {
+	Violations []JSONSchemaRowViolation
+}
+
+func (e *jsonSchemaError) Error() string {
+	if len(e.Violations) == 0 {
+		return "JSON schema validation failed"
+	}
+	msg := e.Violations[0].describe()
+	if len(e.Violations) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Violations)-1)
+	}
+	return msg
+}
+
+// Extensions exposes the violations in GraphQL error responses
+func (e *jsonSchemaError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       ErrCodeValidationFailed,
+		"violations": e.Violations,
+	}
+}
+
+// describe renders a violation as column/path: message
+func (v JSONSchemaRowViolation) describe() string {
+	location := v.Column + v.Path
+	if v.Row != nil {
+		location = fmt.Sprintf("[%d].%s", *v.Row, location)
+	}
+	return fmt.Sprintf("%s: %s", location, v.Message)
+}
+
+// validateJSONColumns validates the json columns of each record against the table's JSON Schemas.
+// Row indexes are reported only when batch is true. Returns nil when every document is valid.
+func validateJSONColumns(table database.TableInfo, records []map[string]interface{}, batch bool) *jsonSchemaError {
+	if len(table.JSONSchemas) == 0 {
+		return nil
+	}
+
+	var violations []JSONSchemaRowViolation
+	for i, record := range records {
+		for _, v := range table.ValidateJSONColumns(record) {
+			violation := JSONSchemaRowViolation{JSONSchemaViolation: v}
+			if batch {
+				row := i
+				violation.Row = &row
+			}
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &jsonSchemaError{Violations: violations}
+}
+
+// sendJSONSchemaError sends a 422 listing every JSON Schema violation
+func sendJSONSchemaError(c *fiber.Ctx, err *jsonSchemaError, details fiber.Map) error {
+	if details == nil {
+		details = fiber.Map{}
+	}
+	details["violations"] = err.Violations
+	return SendErrorWithDetails(c, fiber.StatusUnprocessableEntity, "JSON schema validation failed", ErrCodeValidationFailed,
+		err.Error(), "Each violation lists the column and the JSON Pointer path of the invalid value", details)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonSchemaTestTable(t *testing.T) database.TableInfo {
+	t.Helper()
+	metadata, err := database.NewColumnJSONSchema("public", "orders", "metadata", json.RawMessage(`{
+		"type": "object",
+		"properties": {"source": {"type": "string"}, "items": {"type": "array", "items": {"type": "integer"}}}
+	}`))
+	require.NoError(t, err)
+	return database.TableInfo{Schema: "public", Name: "orders", JSONSchemas: []database.ColumnJSONSchema{metadata}}
+}
+
+func TestValidateJSONColumns(t *testing.T) {
+	table := jsonSchemaTestTable(t)
+
+	t.Run("no schemas", func(t *testing.T) {
+		assert.Nil(t, validateJSONColumns(database.TableInfo{}, []map[string]interface{}{{"metadata": 1}}, false))
+	})
+
+	t.Run("valid records", func(t *testing.T) {
+		records := []map[string]interface{}{{"metadata": map[string]interface{}{"source": "app"}}}
+		assert.Nil(t, validateJSONColumns(table, records, false))
+	})
+
+	t.Run("single record omits row", func(t *testing.T) {
+		records := []map[string]interface{}{{"metadata": map[string]interface{}{"source": 5}}}
+		verr := validateJSONColumns(table, records, false)
+		require.NotNil(t, verr)
+		require.Len(t, verr.Violations, 1)
+		assert.Nil(t, verr.Violations[0].Row)
+		assert.Equal(t, "metadata/source: expected string, got integer", verr.Error())
+	})
+
+	t.Run("batch reports rows", func(t *testing.T) {
+		records := []map[string]interface{}{
+			{"metadata": map[string]interface{}{"source": "app"}},
+			{"metadata": map[string]interface{}{"items": []interface{}{1, "two", "three"}}},
+		}
+		verr := validateJSONColumns(table, records, true)
+		require.NotNil(t, verr)
+		require.Len(t, verr.Violations, 2)
+		require.NotNil(t, verr.Violations[0].Row)
+		assert.Equal(t, 1, *verr.Violations[0].Row)
+		assert.Equal(t, "/items/1", verr.Violations[0].Path)
+		assert.Equal(t, "[1].metadata/items/1: expected integer, got string (and 1 more)", verr.Error())
+		assert.Equal(t, ErrCodeValidationFailed, verr.Extensions()["code"])
+	})
+}
+
+func TestSendJSONSchemaError(t *testing.T) {
+	table := jsonSchemaTestTable(t)
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		verr := validateJSONColumns(table, []map[string]interface{}{{"metadata": "[]"}}, false)
+		require.NotNil(t, verr)
+		return sendJSONSchemaError(c, verr, nil)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var errResp struct {
+		Code    string `json:"code"`
+		Details struct {
+			Violations []map[string]interface{} `json:"violations"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(body, &errResp))
+	assert.Equal(t, ErrCodeValidationFailed, errResp.Code)
+	require.Len(t, errResp.Details.Violations, 1)
+	assert.Equal(t, "metadata", errResp.Details.Violations[0]["column"])
+	assert.Equal(t, "type", errResp.Details.Violations[0]["keyword"])
+	assert.NotContains(t, errResp.Details.Violations[0], "row")
+}
+
+func TestWithoutTransactionRefs(t *testing.T) {
+	records := []map[string]interface{}{{
+		"metadata": map[string]interface{}{"$ref": "order.0.metadata"},
+		"note":     "x",
+	}}
+	out := withoutTransactionRefs(records)
+	assert.Equal(t, []map[string]interface{}{{"note": "x"}}, out)
+	assert.Contains(t, records[0], "metadata")
+}
+
+func TestHandleSetJSONSchema_InvalidRequests(t *testing.T) {
+	handler := NewJSONSchemaHandler(nil, nil)
+	app := fiber.New()
+	app.Put("/json-schemas/:schema/:table/:column", handler.HandleSetJSONSchema)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "invalid json", path: "/json-schemas/public/orders/metadata", body: `{invalid`},
+		{name: "missing schema", path: "/json-schemas/public/orders/metadata", body: `{}`},
+		{name: "invalid schema", path: "/json-schemas/public/orders/metadata", body: `{"json_schema": {"type": "dictionary"}}`},
+		{name: "invalid table", path: "/json-schemas/public/or-ders/metadata", body: `{"json_schema": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
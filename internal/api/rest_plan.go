@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Media types for query plan requests. Clients send
+// Accept: application/vnd.pgrst.plan+json; options=analyze|buffers
+const (
+	mimeQueryPlan     = "application/vnd.pgrst.plan"
+	mimeQueryPlanJSON = "application/vnd.pgrst.plan+json"
+)
+
+// explainOptions are the EXPLAIN options requested through the Accept header
+type explainOptions struct {
+	Analyze  bool `json:"analyze"`
+	Verbose  bool `json:"verbose"`
+	Buffers  bool `json:"buffers"`
+	Settings bool `json:"settings"`
+}
+
+// QueryPlanResponse is returned instead of rows when a query plan is requested
+type QueryPlanResponse struct {
+	Query   string          `json:"query"`
+	Params  []interface{}   `json:"params"`
+	Options explainOptions  `json:"options"`
+	Plan    json.RawMessage `json:"plan"`
+}
+
+// parsePlanAccept reports whether the Accept header asks for a query plan and
+// returns the EXPLAIN options from its options parameter (pipe separated)
+func parsePlanAccept(accept string) (explainOptions, bool, error) {
+	var opts explainOptions
+
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, mediaParams, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		mediaType = strings.ToLower(mediaType)
+		if mediaType != mimeQueryPlan && mediaType != mimeQueryPlanJSON {
+			continue
+		}
+
+		if raw := mediaParams["options"]; raw != "" {
+			for _, opt := range strings.Split(raw, "|") {
+				switch strings.ToLower(strings.TrimSpace(opt)) {
+				case "analyze":
+					opts.Analyze = true
+				case "verbose":
+					opts.Verbose = true
+				case "buffers":
+					opts.Buffers = true
+				case "settings":
+					opts.Settings = true
+				case "":
+				default:
+					return opts, true, fmt.Errorf("unknown plan option: %s", opt)
+				}
+			}
+		}
+		return opts, true, nil
+	}
+
+	return opts, false, nil
+}
+
+// explainPrefix builds the EXPLAIN statement prefix for the options
+func (o explainOptions) explainPrefix() string {
+	parts := make([]string, 0, 5)
+	if o.Analyze {
+		parts = append(parts, "ANALYZE")
+	}
+	if o.Verbose {
+		parts = append(parts, "VERBOSE")
+	}
+	if o.Buffers {
+		parts = append(parts, "BUFFERS")
+	}
+	if o.Settings {
+		parts = append(parts, "SETTINGS")
+	}
+	parts = append(parts, "FORMAT JSON")
+	return fmt.Sprintf("EXPLAIN (%s) ", strings.Join(parts, ", "))
+}
+
+// canViewQueryPlan checks if the caller may request query plans (admins and service role)
+func canViewQueryPlan(c *fiber.Ctx) bool {
+	role, _ := c.Locals("user_role").(string)
+	return role == "admin" || role == "dashboard_admin" || role == "service_role"
+}
+
+// explainSelect runs EXPLAIN for a generated SELECT under the caller's RLS context
+// and returns the plan together with the SQL text and its parameters.
+// The transaction is always rolled back so EXPLAIN ANALYZE has no lasting effects.
+func (h *RESTHandler) explainSelect(c *fiber.Ctx, table database.TableInfo, query string, args []interface{}, opts explainOptions) error {
+	if !canViewQueryPlan(c) {
+		return SendErrorWithCode(c, fiber.StatusForbidden, "Query plans are only available to admins and the service role", ErrCodeAdminRequired)
+	}
+
+	ctx := c.Context()
+	tx, err := middleware.BeginWithRLS(ctx, h.db, c)
+	if err != nil {
+		log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to start transaction for query plan")
+		return SendInternalError(c, "Failed to explain query")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var plan []byte
+	if err := tx.QueryRow(ctx, opts.explainPrefix()+query, args...).Scan(&plan); err != nil {
+		log.Error().Err(err).Str("query", query).Msg("Failed to explain query")
+		return handleDatabaseError(c, err, "explain query")
+	}
+
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	if err := c.JSON(QueryPlanResponse{
+		Query:   query,
+		Params:  args,
+		Options: opts,
+		Plan:    plan,
+	}); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, mimeQueryPlanJSON+"; charset=utf-8")
+	return nil
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanAccept(t *testing.T) {
+	tests := []struct {
+		name      string
+		accept    string
+		wantPlan  bool
+		wantOpts  explainOptions
+		wantError bool
+	}{
+		{name: "json", accept: "application/json", wantPlan: false},
+		{name: "empty", accept: "", wantPlan: false},
+		{name: "plan", accept: "application/vnd.pgrst.plan", wantPlan: true},
+		{name: "plan+json", accept: "application/vnd.pgrst.plan+json", wantPlan: true},
+		{
+			name:     "analyze option",
+			accept:   `application/vnd.pgrst.plan+json; options=analyze`,
+			wantPlan: true,
+			wantOpts: explainOptions{Analyze: true},
+		},
+		{
+			name:     "multiple options",
+			accept:   `application/vnd.pgrst.plan+json; options="analyze|verbose|buffers|settings"`,
+			wantPlan: true,
+			wantOpts: explainOptions{Analyze: true, Verbose: true, Buffers: true, Settings: true},
+		},
+		{
+			name:     "plan among other types",
+			accept:   "application/json;q=0.5, application/vnd.pgrst.plan; options=buffers",
+			wantPlan: true,
+			wantOpts: explainOptions{Buffers: true},
+		},
+		{
+			name:      "unknown option",
+			accept:    "application/vnd.pgrst.plan; options=wal",
+			wantPlan:  true,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, ok, err := parsePlanAccept(tt.accept)
+			assert.Equal(t, tt.wantPlan, ok)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOpts, opts)
+		})
+	}
+}
+
+func TestExplainOptions_ExplainPrefix(t *testing.T) {
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) ", explainOptions{}.explainPrefix())
+	assert.Equal(t, "EXPLAIN (ANALYZE, FORMAT JSON) ", explainOptions{Analyze: true}.explainPrefix())
+	assert.Equal(t,
+		"EXPLAIN (ANALYZE, VERBOSE, BUFFERS, SETTINGS, FORMAT JSON) ",
+		explainOptions{Analyze: true, Verbose: true, Buffers: true, Settings: true}.explainPrefix(),
+	)
+}
+
+func TestQueryPlan_RequiresAdmin(t *testing.T) {
+	handler := &RESTHandler{parser: NewQueryParser(testConfig())}
+	table := database.TableInfo{
+		Schema: "public",
+		Name:   "items",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+
+	for _, role := range []string{"", "authenticated", "anon"} {
+		t.Run("role "+role, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/items", func(c *fiber.Ctx) error {
+				if role != "" {
+					c.Locals("user_role", role)
+				}
+				return handler.makeGetHandler(table)(c)
+			})
+
+			req := httptest.NewRequest("GET", "/items?id=eq.1", nil)
+			req.Header.Set("Accept", "application/vnd.pgrst.plan+json; options=analyze")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			var errResp ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errResp))
+			assert.Equal(t, ErrCodeAdminRequired, errResp.Code)
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		app := fiber.New()
+		app.Get("/items", handler.makeGetHandler(table))
+
+		req := httptest.NewRequest("GET", "/items", nil)
+		req.Header.Set("Accept", "application/vnd.pgrst.plan; options=bogus")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
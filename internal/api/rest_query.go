@@ -23,7 +23,16 @@ type PostQueryRequest struct {
 	Limit          *int                     `json:"limit,omitempty"`
 	Offset         *int                     `json:"offset,omitempty"`
 	Count          string                   `json:"count,omitempty"`
-	GroupBy        []string                 `json:"groupBy,omitempty"`
+	GroupBy        []string                 `json:"groupBy,omitempty"` // Columns or buckets, e.g. "created_at.bucket(1h)"
+	Having         []PostQueryHaving        `json:"having,omitempty"`
+	Window         []string                 `json:"window,omitempty"` // e.g. "total:sum(amount).over(order=created_at)"
+	AsOf           string                   `json:"asOf,omitempty"`
+	GeomColumn     string                   `json:"geomColumn,omitempty"` // Feature geometry of GeoJSON responses
+
+	// Full-text search: default language, ts_headline columns and ts_headline options
+	Language        string   `json:"language,omitempty"`
+	Headline        []string `json:"headline,omitempty"`
+	HeadlineOptions string   `json:"headlineOptions,omitempty"`
 }
 
 // PostQueryFilter represents a single filter in the POST body
@@ -31,6 +40,7 @@ type PostQueryFilter struct {
 	Column   string      `json:"column"`
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
+	Language string      `json:"language,omitempty"` // Text search configuration for fts, plfts and wfts
 }
 
 // PostQueryBetweenFilter represents a between filter in the POST body
@@ -41,11 +51,19 @@ type PostQueryBetweenFilter struct {
 	Negated bool        `json:"negated"`
 }
 
+// PostQueryHaving represents a condition on an aggregate in the POST body
+type PostQueryHaving struct {
+	Aggregate string      `json:"aggregate"` // e.g. "count(*)" or "sum(amount)"
+	Operator  string      `json:"operator"`
+	Value     interface{} `json:"value"`
+}
+
 // PostQueryOrderBy represents an order clause in the POST body
 type PostQueryOrderBy struct {
 	Column    string `json:"column"`
 	Direction string `json:"direction"`
 	Nulls     string `json:"nulls,omitempty"`
+	Rank      bool   `json:"rank,omitempty"` // Order by full-text search relevance
 }
 
 // makePostQueryHandler creates a handler for POST-based queries
@@ -72,9 +90,52 @@ func (h *RESTHandler) makePostQueryHandler(table database.TableInfo) fiber.Handl
 			})
 		}
 
+		// Resolve embedded relations from foreign key metadata
+		if err := h.resolveEmbeddedRelations(ctx, table, params); err != nil {
+			return SendErrorWithDetails(c, fiber.StatusBadRequest, "Invalid embedded resource", ErrCodeInvalidInput, err.Error(), "", nil)
+		}
+
+		// Hidden and masked columns can't be filtered, sorted or aggregated on
+		role := columnPolicyRole(c)
+		if err := checkProtectedColumns(table, role, params); err != nil {
+			return sendProtectedColumnError(c, err)
+		}
+		if err := validateTextSearch(table, params); err != nil {
+			return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+		}
+
+		format := negotiateResponseFormat(c.Get(fiber.HeaderAccept))
+		var geom database.ColumnInfo
+		if format == formatGeoJSON {
+			if geom, err = prepareFeatureCollection(table, params, req.GeomColumn); err != nil {
+				return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+			}
+			if table.ProtectedColumns(role)[geom.Name] {
+				return sendProtectedColumnError(c, fmt.Errorf("column '%s' cannot be used as feature geometry", geom.Name))
+			}
+		}
+
+		// Read from row history instead of the live table
+		if params.AsOf != nil {
+			return h.selectAsOf(c, table, params)
+		}
+
 		// Build and execute query (reuse existing logic from GET handler)
 		query, args := h.buildSelectQuery(table, params)
 
+		// Return the query plan instead of rows when requested (admins and service role only)
+		if opts, ok, err := parsePlanAccept(c.Get(fiber.HeaderAccept)); ok {
+			if err != nil {
+				return SendErrorWithDetails(c, fiber.StatusBadRequest, "Invalid query plan options", ErrCodeInvalidInput, err.Error(), "Supported options are analyze, verbose, buffers and settings", nil)
+			}
+			return h.explainSelect(c, table, query, args, opts)
+		}
+
+		// Stream CSV, NDJSON and XLSX responses negotiated from the Accept header
+		if format.isStreamed() {
+			return h.streamSelect(c, table, params, query, args, format)
+		}
+
 		// Execute query with RLS context
 		var results []map[string]interface{}
 		err = middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
@@ -115,6 +176,12 @@ func (h *RESTHandler) makePostQueryHandler(table database.TableInfo) fiber.Handl
 			c.Set("Content-Range", fmt.Sprintf("%d-%d/%d", start, end, count))
 		}
 
+		applyColumnPolicies(table, role, params.Embedded, results)
+
+		if format == formatGeoJSON {
+			return c.JSON(toFeatureCollection(table, geom.Name, results), mimeGeoJSON)
+		}
+
 		return c.JSON(results)
 	}
 }
@@ -126,11 +193,10 @@ func (h *RESTHandler) convertPostQueryToParams(req *PostQueryRequest) (*QueryPar
 		Order:   []OrderBy{},
 	}
 
-	// Parse select
+	// Parse select (including embedded relations like "*,author(name,email)")
 	if req.Select != "" {
-		params.Select = strings.Split(req.Select, ",")
-		for i := range params.Select {
-			params.Select[i] = strings.TrimSpace(params.Select[i])
+		if err := h.parser.parseSelect(req.Select, params); err != nil {
+			return nil, fmt.Errorf("invalid select: %w", err)
 		}
 	}
 
@@ -141,6 +207,7 @@ func (h *RESTHandler) convertPostQueryToParams(req *PostQueryRequest) (*QueryPar
 			Operator: FilterOperator(f.Operator),
 			Value:    f.Value,
 			IsOr:     false,
+			Language: f.Language,
 		})
 	}
 
@@ -228,6 +295,7 @@ func (h *RESTHandler) convertPostQueryToParams(req *PostQueryRequest) (*QueryPar
 			Column: o.Column,
 			Desc:   strings.ToLower(o.Direction) == "desc",
 			Nulls:  strings.ToLower(o.Nulls),
+			Rank:   o.Rank,
 		}
 		params.Order = append(params.Order, orderBy)
 	}
@@ -241,14 +309,126 @@ func (h *RESTHandler) convertPostQueryToParams(req *PostQueryRequest) (*QueryPar
 		params.Count = CountType(req.Count)
 	}
 
-	// Set group by
-	params.GroupBy = req.GroupBy
+	// Set group by, having and window functions
+	for _, entry := range req.GroupBy {
+		if err := parseGroupBy(entry, params); err != nil {
+			return nil, err
+		}
+	}
+	for _, having := range req.Having {
+		expr := fmt.Sprintf("%s.%s.%s", having.Aggregate, having.Operator, havingValueText(having.Value))
+		if err := h.parser.parseHaving(expr, params); err != nil {
+			return nil, fmt.Errorf("invalid having: %w", err)
+		}
+	}
+	for _, window := range req.Window {
+		if err := h.parser.parseWindows(window, params); err != nil {
+			return nil, fmt.Errorf("invalid window: %w", err)
+		}
+	}
+
+	// Point-in-time read from row history
+	if req.AsOf != "" {
+		asOf, err := parseAsOf(req.AsOf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asOf: %w", err)
+		}
+		params.AsOf = &asOf
+	}
+
+	// Full-text search ranking and headlines
+	params.TextSearchLanguage = req.Language
+	for _, col := range req.Headline {
+		if !isValidIdentifier(col) {
+			return nil, fmt.Errorf("invalid headline column name: %s", col)
+		}
+	}
+	params.Headlines = req.Headline
+	params.HeadlineOptions = req.HeadlineOptions
+	if err := params.normalizeTextSearch(); err != nil {
+		return nil, err
+	}
 
 	return params, nil
 }
 
 // buildSelectQuery builds a SELECT query from parameters
 func (h *RESTHandler) buildSelectQuery(table database.TableInfo, params *QueryParams) (string, []interface{}) {
+	selectClause := h.buildSelectList(table, params)
+	params.bindTextSearchColumns(table)
+
+	if len(params.Embedded) == 0 {
+		// Add WHERE, ORDER BY, LIMIT, OFFSET
+		whereAndMore, args := params.ToSQL(table.Name)
+
+		// Headlines reuse the bind parameters of the text search filters
+		if headlines, headlineArgs := params.buildHeadlineColumns(len(args) + 1); len(headlines) > 0 {
+			selectClause += ", " + strings.Join(headlines, ", ")
+			args = append(args, headlineArgs...)
+		}
+
+		query := fmt.Sprintf("SELECT %s FROM %s.%s", selectClause, table.Schema, table.Name)
+		if whereAndMore != "" {
+			query += " " + whereAndMore
+		}
+
+		return query, args
+	}
+
+	// Embedded relations are selected as correlated JSON subqueries referencing the parent table
+	parentRef := qualifiedTableName(table)
+	argCounter := 1
+	embeds := newEmbedSQLBuilder(&argCounter)
+	if embedColumns := embeds.selectExpressions(params.Embedded, parentRef); len(embedColumns) > 0 {
+		selectClause += ", " + strings.Join(embedColumns, ", ")
+	}
+	args := embeds.args
+
+	var conditions []string
+	if len(params.Filters) > 0 {
+		whereClause, whereArgs := params.buildWhereClause(&argCounter)
+		if whereClause != "" {
+			conditions = append(conditions, whereClause)
+			args = append(args, whereArgs...)
+		}
+	}
+
+	// !inner relations remove parent rows without a matching related row
+	innerEmbeds := newEmbedSQLBuilder(&argCounter)
+	conditions = append(conditions, innerEmbeds.innerConditions(params.Embedded, parentRef)...)
+	args = append(args, innerEmbeds.args...)
+
+	if headlines, headlineArgs := params.buildHeadlineColumns(argCounter); len(headlines) > 0 {
+		selectClause += ", " + strings.Join(headlines, ", ")
+		args = append(args, headlineArgs...)
+		argCounter += len(headlineArgs)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", selectClause, parentRef)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if len(params.Order) > 0 {
+		query += " ORDER BY " + params.buildOrderClause()
+	}
+
+	if params.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCounter)
+		args = append(args, *params.Limit)
+		argCounter++
+	}
+
+	if params.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCounter)
+		args = append(args, *params.Offset)
+	}
+
+	return query, args
+}
+
+// buildSelectList builds the select list for a table, converting geometry columns and truncating text
+func (h *RESTHandler) buildSelectList(table database.TableInfo, params *QueryParams) string {
 	var selectClause string
 
 	// If we have aggregations, use BuildSelectClause (handles aggregations)
@@ -284,22 +464,12 @@ func (h *RESTHandler) buildSelectQuery(table database.TableInfo, params *QueryPa
 		selectClause = buildSelectColumnsWithTruncation(table, params.TruncateLength)
 	}
 
-	// Start building query
-	query := fmt.Sprintf("SELECT %s FROM %s.%s", selectClause, table.Schema, table.Name)
-
-	// Add WHERE, ORDER BY, LIMIT, OFFSET
-	whereAndMore, args := params.ToSQL(table.Name)
-	if whereAndMore != "" {
-		query += " " + whereAndMore
-	}
-
-	// Add GROUP BY clause
-	groupByClause := params.BuildGroupByClause()
-	if groupByClause != "" {
-		query += groupByClause
+	// Window functions are returned as extra columns
+	if len(params.Windows) > 0 {
+		selectClause += ", " + strings.Join(params.buildWindowColumns(), ", ")
 	}
 
-	return query, args
+	return selectClause
 }
 
 // columnExists checks if a column exists in the table using O(1) lookup
@@ -314,15 +484,25 @@ func (h *RESTHandler) getCount(ctx context.Context, c *fiber.Ctx, table database
 
 	// Build WHERE clause
 	var args []interface{}
+	var conditions []string
+	argCounter := 1
 	if len(params.Filters) > 0 {
-		argCounter := 1
 		whereClause, whereArgs := params.buildWhereClause(&argCounter)
 		if whereClause != "" {
-			query += " WHERE " + whereClause
+			conditions = append(conditions, whereClause)
 			args = whereArgs
 		}
 	}
 
+	// !inner embedded relations also restrict the counted rows
+	innerEmbeds := newEmbedSQLBuilder(&argCounter)
+	conditions = append(conditions, innerEmbeds.innerConditions(params.Embedded, qualifiedTableName(table))...)
+	args = append(args, innerEmbeds.args...)
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
 	// Execute count query with RLS context
 	var count int
 	err := middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/auth"
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/fluxbase-eu/fluxbase/internal/responsecache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// cachedResponseHeaders are the response headers stored with cached responses
+var cachedResponseHeaders = []string{
+	fiber.HeaderContentType,
+	fiber.HeaderETag,
+	"Content-Range",
+}
+
+// volatileClaims are JWT claims that change with every token but don't affect what a token may read
+var volatileClaims = map[string]bool{
+	"exp": true,
+	"iat": true,
+	"nbf": true,
+	"jti": true,
+}
+
+// SetResponseCache enables caching of GET responses for the cache's tables
+func (h *RESTHandler) SetResponseCache(cache *responsecache.Cache) {
+	h.responseCache = cache
+}
+
+// cacheableRequest reports whether a GET on a table may be answered from the response cache.
+// Only anonymous and authenticated reads are cached, and not those embedding other tables,
+// whose changes wouldn't invalidate the entry.
+func (h *RESTHandler) cacheableRequest(c *fiber.Ctx, table database.TableInfo) bool {
+	if h.responseCache == nil || isAdminUser(c) {
+		return false
+	}
+	if role := middleware.GetRLSContext(c).Role; role != "anon" && role != "authenticated" {
+		return false
+	}
+	if strings.Contains(c.Query("select"), "(") {
+		values, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err != nil {
+			return false
+		}
+		params, err := h.parser.Parse(values)
+		if err != nil || len(params.Embedded) > 0 {
+			return false
+		}
+	}
+	return h.responseCache.Cacheable(c.Context(), table.Schema, table.Name)
+}
+
+// requestFingerprint identifies everything a cached response depends on: who asks (role, user and
+// claims, which RLS policies and column policies see) and what (path, normalized query, and the
+// headers selecting the format and the count)
+func requestFingerprint(c *fiber.Ctx) (string, error) {
+	rls := middleware.GetRLSContext(c)
+	userRole, _ := c.Locals("user_role").(string)
+
+	claims := map[string]interface{}{}
+	if tc, ok := c.Locals("jwt_claims").(*auth.TokenClaims); ok && tc != nil {
+		for k, v := range tc.RawClaims {
+			if !volatileClaims[k] {
+				claims[k] = v
+			}
+		}
+	}
+	claimsJSON, err := json.Marshal(claims) // map keys are sorted
+	if err != nil {
+		return "", err
+	}
+
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{
+		rls.Role,
+		userRole,
+		fmt.Sprintf("%v", rls.UserID),
+		string(claimsJSON),
+		c.Path(),
+		query.Encode(), // sorted by key, repeated keys keep their order
+		c.Get(fiber.HeaderAccept),
+		c.Get("Prefer"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// serveCached answers a GET from the response cache, or runs next and caches its response
+func (h *RESTHandler) serveCached(c *fiber.Ctx, table database.TableInfo, next fiber.Handler) error {
+	if !h.cacheableRequest(c, table) {
+		return next(c)
+	}
+	ctx := c.Context()
+	fingerprint, err := requestFingerprint(c)
+	if err != nil {
+		return next(c)
+	}
+	key, ok := h.responseCache.Key(ctx, table.Schema, table.Name, fingerprint)
+	if !ok {
+		return next(c)
+	}
+
+	if entry, ok := h.responseCache.Get(ctx, table.Schema, table.Name, key); ok {
+		for name, value := range entry.Headers {
+			c.Set(name, value)
+		}
+		c.Set("X-Cache", "HIT")
+		return c.Status(entry.Status).Send(entry.Body)
+	}
+
+	if err := next(c); err != nil {
+		return err
+	}
+	c.Set("X-Cache", "MISS")
+
+	resp := c.Response()
+	if resp.StatusCode() != fiber.StatusOK || resp.IsBodyStream() {
+		return nil
+	}
+	entry := &responsecache.Entry{
+		Status:  resp.StatusCode(),
+		Headers: make(map[string]string),
+		Body:    append([]byte(nil), resp.Body()...),
+	}
+	for _, name := range cachedResponseHeaders {
+		if value := c.GetRespHeader(name); value != "" {
+			entry.Headers[name] = value
+		}
+	}
+	h.responseCache.Set(ctx, table.Schema, table.Name, key, entry)
+	return nil
+}
+
+// invalidateAfterWrite drops the cached responses of a table once a write through the REST API
+// succeeded, so the writer reads its own change without waiting for the change notification
+func (h *RESTHandler) invalidateAfterWrite(c *fiber.Ctx, table database.TableInfo, next fiber.Handler) error {
+	err := next(c)
+	if h.responseCache != nil && err == nil && c.Response().StatusCode() < 400 {
+		h.responseCache.Invalidate(c.Context(), table.Schema, table.Name)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/auth"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fingerprintOf computes the request fingerprint of a GET with the given role and claims
+func fingerprintOf(t *testing.T, target, accept, role string, claims map[string]interface{}) string {
+	t.Helper()
+	var fingerprint string
+	app := fiber.New()
+	app.Get("/api/v1/tables/:table", func(c *fiber.Ctx) error {
+		c.Locals("rls_role", role)
+		if claims != nil {
+			c.Locals("rls_user_id", claims["sub"])
+			c.Locals("jwt_claims", &auth.TokenClaims{RawClaims: claims})
+		}
+		var err error
+		fingerprint, err = requestFingerprint(c)
+		return err
+	})
+
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	return fingerprint
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	base := fingerprintOf(t, "/api/v1/tables/products?select=id,name&price=gt.10&order=name", "", "anon", nil)
+
+	t.Run("query parameter order is normalized", func(t *testing.T) {
+		assert.Equal(t, base, fingerprintOf(t, "/api/v1/tables/products?order=name&price=gt.10&select=id,name", "", "anon", nil))
+	})
+
+	t.Run("repeated filters keep their meaning", func(t *testing.T) {
+		a := fingerprintOf(t, "/api/v1/tables/products?price=gt.10&price=lt.20", "", "anon", nil)
+		b := fingerprintOf(t, "/api/v1/tables/products?price=lt.20&price=gt.10", "", "anon", nil)
+		assert.NotEqual(t, a, fingerprintOf(t, "/api/v1/tables/products?price=gt.10", "", "anon", nil))
+		assert.NotEqual(t, a, b)
+	})
+
+	t.Run("query, path and format change the fingerprint", func(t *testing.T) {
+		assert.NotEqual(t, base, fingerprintOf(t, "/api/v1/tables/products?select=id,name&price=gt.20&order=name", "", "anon", nil))
+		assert.NotEqual(t, base, fingerprintOf(t, "/api/v1/tables/categories?select=id,name&price=gt.10&order=name", "", "anon", nil))
+		assert.NotEqual(t, base, fingerprintOf(t, "/api/v1/tables/products?select=id,name&price=gt.10&order=name", "text/csv", "anon", nil))
+	})
+
+	t.Run("role and claims change the fingerprint", func(t *testing.T) {
+		alice := map[string]interface{}{"sub": "alice", "role": "authenticated", "exp": 100.0, "iat": 50.0}
+		bob := map[string]interface{}{"sub": "bob", "role": "authenticated", "exp": 100.0, "iat": 50.0}
+		target := "/api/v1/tables/products?select=id,name&price=gt.10&order=name"
+
+		a := fingerprintOf(t, target, "", "authenticated", alice)
+		assert.NotEqual(t, base, a)
+		assert.NotEqual(t, a, fingerprintOf(t, target, "", "authenticated", bob))
+
+		// A refreshed token of the same user shares the entries
+		refreshed := map[string]interface{}{"sub": "alice", "role": "authenticated", "exp": 200.0, "iat": 150.0}
+		assert.Equal(t, a, fingerprintOf(t, target, "", "authenticated", refreshed))
+	})
+}
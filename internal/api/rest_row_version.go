@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// rowVersionAlias is the result column carrying the row version; it is removed before responding
+const rowVersionAlias = "__fb_row_version"
+
+// rowVersionColumn returns the configured version column if the table has it
+func (h *RESTHandler) rowVersionColumn(table database.TableInfo) string {
+	if h.parser == nil || h.parser.config == nil {
+		return ""
+	}
+	col := h.parser.config.API.RowVersionColumn
+	if col != "" && table.HasColumn(col) {
+		return col
+	}
+	return ""
+}
+
+// rowVersionExpr returns the SQL expression used as a row's version, or "" if the
+// relation has none (views without the configured version column have no xmin)
+func (h *RESTHandler) rowVersionExpr(table database.TableInfo) string {
+	if col := h.rowVersionColumn(table); col != "" {
+		return quoteIdentifier(col) + "::text"
+	}
+	if table.Type == "view" {
+		return ""
+	}
+	return "xmin::text"
+}
+
+// rowVersionSelect returns the extra select/RETURNING item that exposes the row version
+func (h *RESTHandler) rowVersionSelect(table database.TableInfo) string {
+	expr := h.rowVersionExpr(table)
+	if expr == "" {
+		return ""
+	}
+	return fmt.Sprintf(", %s AS %s", expr, quoteIdentifier(rowVersionAlias))
+}
+
+// rowVersionBump returns a SET clause incrementing an integer version column, if configured
+func (h *RESTHandler) rowVersionBump(table database.TableInfo, data map[string]interface{}) string {
+	col := h.rowVersionColumn(table)
+	if col == "" {
+		return ""
+	}
+	if _, provided := data[col]; provided {
+		return ""
+	}
+	column := table.GetColumn(col)
+	if column == nil {
+		return ""
+	}
+	switch strings.ToLower(column.DataType) {
+	case "integer", "bigint", "smallint", "int", "int2", "int4", "int8":
+		quoted := quoteIdentifier(col)
+		return fmt.Sprintf("%s = %s + 1", quoted, quoted)
+	default:
+		return ""
+	}
+}
+
+// rowETag builds a strong ETag from a row version
+func rowETag(version string) string {
+	return `"` + base64.RawURLEncoding.EncodeToString([]byte(version)) + `"`
+}
+
+// popRowETag removes the version column from a result row and returns the row's ETag
+func popRowETag(row map[string]interface{}) string {
+	version, ok := row[rowVersionAlias]
+	if !ok {
+		return ""
+	}
+	delete(row, rowVersionAlias)
+	if version == nil {
+		return ""
+	}
+	return rowETag(fmt.Sprintf("%v", version))
+}
+
+// rowPrecondition is a parsed If-Match header
+type rowPrecondition struct {
+	Any      bool     // If-Match: * (the row only has to exist)
+	Versions []string // Acceptable row versions
+}
+
+// parseIfMatch parses an If-Match header into the row versions it accepts.
+// If-Match uses strong comparison, so weak and malformed ETags never match.
+// Returns nil when the header is absent.
+func parseIfMatch(header string) *rowPrecondition {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	if header == "*" {
+		return &rowPrecondition{Any: true}
+	}
+
+	precondition := &rowPrecondition{Versions: []string{}}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") || len(candidate) < 2 || !strings.HasPrefix(candidate, `"`) || !strings.HasSuffix(candidate, `"`) {
+			continue
+		}
+		version, err := base64.RawURLEncoding.DecodeString(candidate[1 : len(candidate)-1])
+		if err != nil {
+			continue
+		}
+		precondition.Versions = append(precondition.Versions, string(version))
+	}
+	return precondition
+}
+
+// whereClause returns the condition restricting a write to the accepted versions
+func (p *rowPrecondition) whereClause(versionExpr string, argCounter int) (string, []interface{}) {
+	if p == nil || p.Any {
+		return "", nil
+	}
+	return fmt.Sprintf(" AND %s = ANY($%d)", versionExpr, argCounter), []interface{}{p.Versions}
+}
+
+// rowExists checks whether the caller can see the row, to tell version mismatches from RLS denials
+func rowExists(ctx context.Context, tx pgx.Tx, table database.TableInfo, pkColumn string, id interface{}) (bool, error) {
+	query := fmt.Sprintf(
+		`SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)`,
+		qualifiedTableName(table), quoteIdentifier(pkColumn),
+	)
+	var exists bool
+	err := tx.QueryRow(ctx, query, id).Scan(&exists)
+	return exists, err
+}
+
+// sendPreconditionFailed sends a 412 for a stale or unmatched If-Match header
+func sendPreconditionFailed(c *fiber.Ctx) error {
+	return SendErrorWithDetails(c, fiber.StatusPreconditionFailed, "Precondition failed", ErrCodePreconditionFailed,
+		"The record has been modified since it was read",
+		"Fetch the record again and retry with its current ETag",
+		nil)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rowVersionTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema: "public",
+		Name:   "documents",
+		Type:   "table",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "title", DataType: "text"},
+			{Name: "version", DataType: "integer"},
+			{Name: "updated_at", DataType: "timestamp with time zone"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+}
+
+func rowVersionHandler(column string) *RESTHandler {
+	cfg := testConfig()
+	cfg.API = config.APIConfig{MaxPageSize: -1, MaxTotalResults: -1, DefaultPageSize: -1, RowVersionColumn: column}
+	return &RESTHandler{parser: NewQueryParser(cfg)}
+}
+
+func TestRowVersionExpr(t *testing.T) {
+	table := rowVersionTestTable()
+
+	assert.Equal(t, "xmin::text", rowVersionHandler("").rowVersionExpr(table))
+	assert.Equal(t, `"version"::text`, rowVersionHandler("version").rowVersionExpr(table))
+	// Configured column missing from the table falls back to xmin
+	assert.Equal(t, "xmin::text", rowVersionHandler("revision").rowVersionExpr(table))
+
+	view := table
+	view.Type = "view"
+	view.Columns = []database.ColumnInfo{{Name: "id", DataType: "integer"}}
+	assert.Equal(t, "", rowVersionHandler("").rowVersionExpr(view))
+	assert.Equal(t, "", rowVersionHandler("").rowVersionSelect(view))
+
+	assert.Equal(t, `, xmin::text AS "__fb_row_version"`, rowVersionHandler("").rowVersionSelect(table))
+}
+
+func TestRowVersionBump(t *testing.T) {
+	table := rowVersionTestTable()
+
+	assert.Equal(t, `"version" = "version" + 1`, rowVersionHandler("version").rowVersionBump(table, map[string]interface{}{"title": "x"}))
+	// Explicitly provided versions are left alone
+	assert.Equal(t, "", rowVersionHandler("version").rowVersionBump(table, map[string]interface{}{"version": 3}))
+	// Non-integer version columns are maintained by the table
+	assert.Equal(t, "", rowVersionHandler("updated_at").rowVersionBump(table, map[string]interface{}{"title": "x"}))
+	assert.Equal(t, "", rowVersionHandler("").rowVersionBump(table, map[string]interface{}{"title": "x"}))
+}
+
+func TestPopRowETag(t *testing.T) {
+	row := map[string]interface{}{"id": 1, rowVersionAlias: "12345"}
+	etag := popRowETag(row)
+
+	assert.Equal(t, rowETag("12345"), etag)
+	assert.NotContains(t, row, rowVersionAlias)
+	assert.Equal(t, "", popRowETag(map[string]interface{}{"id": 1}))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	assert.Nil(t, parseIfMatch(""))
+	assert.Equal(t, &rowPrecondition{Any: true}, parseIfMatch("*"))
+
+	precondition := parseIfMatch(rowETag("100") + ", " + rowETag("101"))
+	require.NotNil(t, precondition)
+	assert.Equal(t, []string{"100", "101"}, precondition.Versions)
+
+	// Weak and malformed ETags never match
+	precondition = parseIfMatch(`W/` + rowETag("100") + `, garbage, "!!!"`)
+	require.NotNil(t, precondition)
+	assert.False(t, precondition.Any)
+	assert.Empty(t, precondition.Versions)
+}
+
+func TestRowPrecondition_WhereClause(t *testing.T) {
+	var none *rowPrecondition
+	sql, args := none.whereClause("xmin::text", 3)
+	assert.Equal(t, "", sql)
+	assert.Nil(t, args)
+
+	sql, args = (&rowPrecondition{Any: true}).whereClause("xmin::text", 3)
+	assert.Equal(t, "", sql)
+	assert.Nil(t, args)
+
+	sql, args = (&rowPrecondition{Versions: []string{"7"}}).whereClause("xmin::text", 3)
+	assert.Equal(t, " AND xmin::text = ANY($3)", sql)
+	assert.Equal(t, []interface{}{[]string{"7"}}, args)
+}
+
+func TestIfMatch_UnusableETagReturns412(t *testing.T) {
+	handler := rowVersionHandler("")
+	table := rowVersionTestTable()
+
+	app := fiber.New()
+	app.Patch("/documents/:id", handler.makePatchHandler(table))
+	app.Delete("/documents/:id", handler.makeDeleteHandler(table))
+
+	for _, method := range []string{"PATCH", "DELETE"} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/documents/1", strings.NewReader(`{"title":"new"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `W/"weak"`)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// textSearchQuery is the tsquery of a full-text search filter. It is recorded while the
+// WHERE clause is built so ranking and headlines can reuse the filter's bind parameters.
+type textSearchQuery struct {
+	column string
+	query  string // e.g. websearch_to_tsquery($3::regconfig, $4)
+	config string // e.g. $3::regconfig, empty for default_text_search_config
+}
+
+// isTextSearchOperator reports whether an operator is one of the full-text search operators
+func isTextSearchOperator(op FilterOperator) bool {
+	return op == OpTextSearch || op == OpPhraseSearch || op == OpWebSearch
+}
+
+// newTextSearchQuery builds the tsquery of a text search filter whose arguments start at argN.
+// The language, when set, is passed as the first argument and the search term as the last.
+func newTextSearchQuery(f Filter, argN int) (textSearchQuery, []interface{}) {
+	fn := "plainto_tsquery"
+	switch f.Operator {
+	case OpPhraseSearch:
+		fn = "phraseto_tsquery"
+	case OpWebSearch:
+		fn = "websearch_to_tsquery"
+	}
+
+	ts := textSearchQuery{column: f.Column}
+	if f.Language == "" {
+		ts.query = fmt.Sprintf("%s($%d)", fn, argN)
+		return ts, []interface{}{f.Value}
+	}
+	ts.config = fmt.Sprintf("$%d::regconfig", argN)
+	ts.query = fmt.Sprintf("%s(%s, $%d)", fn, ts.config, argN+1)
+	return ts, []interface{}{f.Language, f.Value}
+}
+
+// normalizeTextSearchFilters splits the PostgREST language form of the text search operators
+// (fts(english), plfts(german), wfts(simple)) into operator and language, and applies the
+// request's default language to text search filters that don't name one
+func normalizeTextSearchFilters(filters []Filter, language string) error {
+	for i := range filters {
+		f := &filters[i]
+		op := string(f.Operator)
+		if open := strings.Index(op, "("); open > 0 && strings.HasSuffix(op, ")") {
+			base := FilterOperator(op[:open])
+			if !isTextSearchOperator(base) {
+				continue
+			}
+			lang := op[open+1 : len(op)-1]
+			if !isValidIdentifier(lang) {
+				return fmt.Errorf("invalid text search language: %s", lang)
+			}
+			f.Operator = base
+			f.Language = lang
+		}
+		if isTextSearchOperator(f.Operator) && f.Language == "" {
+			f.Language = language
+		}
+	}
+	return nil
+}
+
+// normalizeTextSearch normalizes the text search filters of a query and its embedded relations
+func (params *QueryParams) normalizeTextSearch() error {
+	if params.TextSearchLanguage != "" && !isValidIdentifier(params.TextSearchLanguage) {
+		return fmt.Errorf("invalid text search language: %s", params.TextSearchLanguage)
+	}
+	if err := normalizeTextSearchFilters(params.Filters, params.TextSearchLanguage); err != nil {
+		return err
+	}
+	return normalizeEmbeddedTextSearch(params.Embedded, params.TextSearchLanguage)
+}
+
+func normalizeEmbeddedTextSearch(relations []EmbeddedRelation, language string) error {
+	for i := range relations {
+		if err := normalizeTextSearchFilters(relations[i].Filters, language); err != nil {
+			return err
+		}
+		if err := normalizeEmbeddedTextSearch(relations[i].Embedded, language); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseHeadlineColumns parses fts_headline=title,body
+func parseHeadlineColumns(value string) ([]string, error) {
+	var columns []string
+	for _, col := range strings.Split(value, ",") {
+		col = strings.TrimSpace(col)
+		if col == "" {
+			continue
+		}
+		if !isValidIdentifier(col) {
+			return nil, fmt.Errorf("invalid headline column name: %s", col)
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// validateTextSearch checks relevance ordering and headlines against the table. Both need
+// a text search filter to take the search terms from, and headlines need a text column.
+func validateTextSearch(table database.TableInfo, params *QueryParams) error {
+	ranked := false
+	for _, o := range params.Order {
+		if o.Rank {
+			ranked = true
+			if !table.HasColumn(o.Column) {
+				return fmt.Errorf("unknown rank column: %s", o.Column)
+			}
+		}
+	}
+	if !ranked && len(params.Headlines) == 0 {
+		return nil
+	}
+
+	hasSearch := false
+	for _, f := range params.Filters {
+		if isTextSearchOperator(f.Operator) {
+			hasSearch = true
+			break
+		}
+	}
+	if !hasSearch {
+		return fmt.Errorf("ranking and headlines require an fts, plfts or wfts filter")
+	}
+
+	if len(params.Headlines) > 0 && (len(params.Aggregations) > 0 || len(params.GroupBy) > 0) {
+		return fmt.Errorf("headlines can't be combined with aggregates")
+	}
+	for _, name := range params.Headlines {
+		col := table.GetColumn(name)
+		if col == nil {
+			return fmt.Errorf("unknown headline column: %s", name)
+		}
+		if strings.EqualFold(col.DataType, "tsvector") {
+			return fmt.Errorf("headline column '%s' is a tsvector; highlight the source text column instead", name)
+		}
+	}
+	return nil
+}
+
+// bindTextSearchColumns records which table columns are tsvectors, which are ranked as they are
+// instead of through to_tsvector
+func (params *QueryParams) bindTextSearchColumns(table database.TableInfo) {
+	params.tsvectorColumns = nil
+	for _, col := range table.Columns {
+		if strings.EqualFold(col.DataType, "tsvector") {
+			if params.tsvectorColumns == nil {
+				params.tsvectorColumns = make(map[string]bool)
+			}
+			params.tsvectorColumns[col.Name] = true
+		}
+	}
+}
+
+// textSearchFor returns the tsquery used to rank or highlight a column: the one of the
+// column's own text search filter, or else the first text search filter of the query
+func (params *QueryParams) textSearchFor(column string) (textSearchQuery, bool) {
+	for _, ts := range params.textSearches {
+		if ts.column == column {
+			return ts, true
+		}
+	}
+	if len(params.textSearches) > 0 {
+		return params.textSearches[0], true
+	}
+	return textSearchQuery{}, false
+}
+
+// rankExpression returns the ts_rank_cd expression for ordering by a column's relevance
+func (params *QueryParams) rankExpression(column string) string {
+	ts, ok := params.textSearchFor(column)
+	if !ok {
+		return ""
+	}
+	document := quoteIdentifier(column)
+	if !params.tsvectorColumns[column] {
+		if ts.config != "" {
+			document = fmt.Sprintf("to_tsvector(%s, %s::text)", ts.config, document)
+		} else {
+			document = fmt.Sprintf("to_tsvector(%s::text)", document)
+		}
+	}
+	return fmt.Sprintf("ts_rank_cd(%s, %s)", document, ts.query)
+}
+
+// buildHeadlineColumns builds the ts_headline select expressions, returned as <column>_headline.
+// Must be called after buildWhereClause; the headline options argument, if any, is argN.
+func (params *QueryParams) buildHeadlineColumns(argN int) ([]string, []interface{}) {
+	var options string
+	var args []interface{}
+	if params.HeadlineOptions != "" {
+		options = fmt.Sprintf(", $%d", argN)
+		args = append(args, params.HeadlineOptions)
+	}
+
+	var columns []string
+	for _, col := range params.Headlines {
+		ts, ok := params.textSearchFor(col)
+		quoted := quoteIdentifier(col)
+		if !ok || quoted == "" {
+			continue
+		}
+		config := ""
+		if ts.config != "" {
+			config = ts.config + ", "
+		}
+		columns = append(columns, fmt.Sprintf("ts_headline(%s%s::text, %s%s) AS %s",
+			config, quoted, ts.query, options, quoteIdentifier(col+"_headline")))
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	return columns, args
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textSearchTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema: "public",
+		Name:   "articles",
+		Type:   "table",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "title", DataType: "text"},
+			{Name: "body", DataType: "text"},
+			{Name: "document", DataType: "tsvector"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+}
+
+func TestQueryParser_TextSearch(t *testing.T) {
+	parser := NewQueryParser(testConfig())
+
+	t.Run("language in operator", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{"title": {"fts(german).haus"}})
+		require.NoError(t, err)
+		require.Len(t, params.Filters, 1)
+		assert.Equal(t, OpTextSearch, params.Filters[0].Operator)
+		assert.Equal(t, "german", params.Filters[0].Language)
+		assert.Equal(t, "haus", params.Filters[0].Value)
+	})
+
+	t.Run("request language applies to filters without one", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{
+			"title":        {"wfts.cats"},
+			"body":         {"plfts(simple).dogs"},
+			"fts_language": {"english"},
+		})
+		require.NoError(t, err)
+		languages := map[string]string{}
+		for _, f := range params.Filters {
+			languages[f.Column] = f.Language
+		}
+		assert.Equal(t, map[string]string{"title": "english", "body": "simple"}, languages)
+	})
+
+	t.Run("invalid language", func(t *testing.T) {
+		_, err := parser.Parse(url.Values{"title": {"fts(english;drop).x"}})
+		assert.Error(t, err)
+		_, err = parser.Parse(url.Values{"fts_language": {"pg_catalog.english"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("rank ordering", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{"order": {"title.rank.desc,id.asc,body.rank"}})
+		require.NoError(t, err)
+		require.Len(t, params.Order, 3)
+		assert.Equal(t, OrderBy{Column: "title", Rank: true, Desc: true}, params.Order[0])
+		assert.Equal(t, OrderBy{Column: "id"}, params.Order[1])
+		assert.Equal(t, OrderBy{Column: "body", Rank: true, Desc: true}, params.Order[2])
+	})
+
+	t.Run("headlines", func(t *testing.T) {
+		params, err := parser.Parse(url.Values{
+			"fts_headline":         {"title,body"},
+			"fts_headline_options": {"MaxWords=20, StartSel=<b>, StopSel=</b>"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"title", "body"}, params.Headlines)
+		assert.Equal(t, "MaxWords=20, StartSel=<b>, StopSel=</b>", params.HeadlineOptions)
+
+		_, err = parser.Parse(url.Values{"fts_headline": {"title;"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateTextSearch(t *testing.T) {
+	table := textSearchTestTable()
+	search := []Filter{{Column: "document", Operator: OpWebSearch, Value: "cats"}}
+
+	tests := []struct {
+		name    string
+		params  *QueryParams
+		wantErr string
+	}{
+		{name: "plain query", params: &QueryParams{Order: []OrderBy{{Column: "title"}}}},
+		{name: "rank with search", params: &QueryParams{Filters: search, Order: []OrderBy{{Column: "document", Rank: true}}}},
+		{name: "rank without search", params: &QueryParams{Order: []OrderBy{{Column: "title", Rank: true}}}, wantErr: "require an fts, plfts or wfts filter"},
+		{name: "rank unknown column", params: &QueryParams{Filters: search, Order: []OrderBy{{Column: "nope", Rank: true}}}, wantErr: "unknown rank column: nope"},
+		{name: "headline with search", params: &QueryParams{Filters: search, Headlines: []string{"body"}}},
+		{name: "headline without search", params: &QueryParams{Headlines: []string{"body"}}, wantErr: "require an fts"},
+		{name: "headline on tsvector", params: &QueryParams{Filters: search, Headlines: []string{"document"}}, wantErr: "is a tsvector"},
+		{name: "headline unknown column", params: &QueryParams{Filters: search, Headlines: []string{"nope"}}, wantErr: "unknown headline column: nope"},
+		{name: "headline with aggregates", params: &QueryParams{Filters: search, Headlines: []string{"body"}, GroupBy: []string{"title"}}, wantErr: "can't be combined with aggregates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTextSearch(table, tt.params)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildSelectQuery_TextSearch(t *testing.T) {
+	h := &RESTHandler{}
+	table := textSearchTestTable()
+	limit := 10
+
+	t.Run("rank and headline reuse the filter parameters", func(t *testing.T) {
+		params := &QueryParams{
+			Select:          []string{"id", "title"},
+			Filters:         []Filter{{Column: "document", Operator: OpWebSearch, Value: "cats", Language: "english"}},
+			Order:           []OrderBy{{Column: "document", Rank: true, Desc: true}, {Column: "title", Rank: true, Desc: true}},
+			Headlines:       []string{"body"},
+			HeadlineOptions: "MaxWords=10",
+			Limit:           &limit,
+		}
+		query, args := h.buildSelectQuery(table, params)
+
+		assert.Equal(t, `SELECT "id", "title", ts_headline($1::regconfig, "body"::text, websearch_to_tsquery($1::regconfig, $2), $4) AS "body_headline" FROM public.articles `+
+			`WHERE "document" @@ websearch_to_tsquery($1::regconfig, $2) `+
+			`ORDER BY ts_rank_cd("document", websearch_to_tsquery($1::regconfig, $2)) DESC, `+
+			`ts_rank_cd(to_tsvector($1::regconfig, "title"::text), websearch_to_tsquery($1::regconfig, $2)) DESC `+
+			`LIMIT $3`, query)
+		assert.Equal(t, []interface{}{"english", "cats", 10, "MaxWords=10"}, args)
+	})
+
+	t.Run("default language", func(t *testing.T) {
+		params := &QueryParams{
+			Filters: []Filter{
+				{Column: "id", Operator: OpGreaterThan, Value: "5"},
+				{Column: "title", Operator: OpTextSearch, Value: "cats"},
+			},
+			Order:     []OrderBy{{Column: "title", Rank: true, Desc: true}},
+			Headlines: []string{"title"},
+		}
+		query, args := h.buildSelectQuery(table, params)
+
+		assert.Contains(t, query, `ts_headline("title"::text, plainto_tsquery($2)) AS "title_headline"`)
+		assert.Contains(t, query, `"title" @@ plainto_tsquery($2)`)
+		assert.Contains(t, query, `ORDER BY ts_rank_cd(to_tsvector("title"::text), plainto_tsquery($2)) DESC`)
+		assert.Equal(t, []interface{}{"5", "cats"}, args)
+	})
+}
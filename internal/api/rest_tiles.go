@@ -0,0 +1,269 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// mimeMVT is the media type of Mapbox vector tiles
+	mimeMVT = "application/vnd.mapbox-vector-tile"
+
+	// maxTileZoom is the highest zoom level served by the tile endpoint
+	maxTileZoom = 30
+
+	// mvtExtent and mvtBuffer are the tile coordinate space and clipping buffer passed to ST_AsMVTGeom
+	mvtExtent = 4096
+	mvtBuffer = 64
+)
+
+// tileCoords addresses a tile in the XYZ (slippy map) scheme
+type tileCoords struct {
+	Z, X, Y int
+}
+
+// parseTileCoords parses and range-checks z/x/y path parameters
+func parseTileCoords(z, x, y string) (tileCoords, error) {
+	var t tileCoords
+	var err error
+	if t.Z, err = strconv.Atoi(z); err != nil || t.Z < 0 || t.Z > maxTileZoom {
+		return t, fmt.Errorf("zoom must be an integer between 0 and %d", maxTileZoom)
+	}
+	limit := 1 << t.Z
+	if t.X, err = strconv.Atoi(x); err != nil || t.X < 0 || t.X >= limit {
+		return t, fmt.Errorf("x must be an integer between 0 and %d at zoom %d", limit-1, t.Z)
+	}
+	if t.Y, err = strconv.Atoi(strings.TrimSuffix(y, ".mvt")); err != nil || t.Y < 0 || t.Y >= limit {
+		return t, fmt.Errorf("y must be an integer between 0 and %d at zoom %d", limit-1, t.Z)
+	}
+	return t, nil
+}
+
+// geometryColumns returns the table's PostGIS geometry and geography columns in table order
+func geometryColumns(table database.TableInfo) []database.ColumnInfo {
+	var cols []database.ColumnInfo
+	for _, col := range table.Columns {
+		if isGeometryColumn(col.DataType) {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// resolveGeometryColumn returns the requested geometry column, or the table's first one
+func resolveGeometryColumn(table database.TableInfo, requested string) (database.ColumnInfo, error) {
+	if requested != "" {
+		col := table.GetColumn(requested)
+		if col == nil {
+			return database.ColumnInfo{}, fmt.Errorf("unknown column: %s", requested)
+		}
+		if !isGeometryColumn(col.DataType) {
+			return database.ColumnInfo{}, fmt.Errorf("column '%s' is not a geometry or geography column", requested)
+		}
+		return *col, nil
+	}
+
+	cols := geometryColumns(table)
+	if len(cols) == 0 {
+		return database.ColumnInfo{}, fmt.Errorf("table '%s.%s' has no geometry column", table.Schema, table.Name)
+	}
+	return cols[0], nil
+}
+
+// tileAttributeColumns returns the columns encoded as feature properties: the selected
+// (or all) non-geometry columns, without columns that have a column policy for the role
+func tileAttributeColumns(table database.TableInfo, params *QueryParams, role string) []string {
+	protected := table.ProtectedColumns(role)
+	include := func(col *database.ColumnInfo) bool {
+		return col != nil && !isGeometryColumn(col.DataType) && !protected[col.Name]
+	}
+
+	var attrs []string
+	if len(params.Select) > 0 && !(len(params.Select) == 1 && params.Select[0] == "*") {
+		for _, name := range params.Select {
+			if col := table.GetColumn(name); include(col) {
+				attrs = append(attrs, col.Name)
+			}
+		}
+		return attrs
+	}
+	for i := range table.Columns {
+		if include(&table.Columns[i]) {
+			attrs = append(attrs, table.Columns[i].Name)
+		}
+	}
+	return attrs
+}
+
+// buildTileQuery builds the ST_AsMVT query for one tile. The first arguments are z, x, y,
+// the SRID of the geometry column, the layer name and the geometry column name; filter
+// and limit arguments follow.
+func buildTileQuery(table database.TableInfo, geom database.ColumnInfo, attrs []string, layer string, params *QueryParams, tile tileCoords, srid int) (string, []interface{}) {
+	geomExpr := quoteIdentifier(geom.Name)
+	if strings.Contains(strings.ToLower(geom.DataType), "geography") {
+		geomExpr += "::geometry"
+	}
+
+	columns := []string{fmt.Sprintf(
+		"ST_AsMVTGeom(ST_Transform(%s, 3857), (SELECT envelope FROM fluxbase_tile_bounds), %d, %d, true) AS %s",
+		geomExpr, mvtExtent, mvtBuffer, quoteIdentifier(geom.Name),
+	)}
+	for _, attr := range attrs {
+		columns = append(columns, quoteIdentifier(attr))
+	}
+
+	args := []interface{}{tile.Z, tile.X, tile.Y, srid, layer, geom.Name}
+	argCounter := len(args) + 1
+	params.bindTextSearchColumns(table)
+
+	conditions := []string{fmt.Sprintf("%s && (SELECT source_envelope FROM fluxbase_tile_bounds)", geomExpr)}
+	if len(params.Filters) > 0 {
+		whereClause, whereArgs := params.buildWhereClause(&argCounter)
+		if whereClause != "" {
+			conditions = append(conditions, whereClause)
+			args = append(args, whereArgs...)
+		}
+	}
+
+	inner := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(columns, ", "), qualifiedTableName(table), strings.Join(conditions, " AND "))
+	if len(params.Order) > 0 {
+		inner += " ORDER BY " + params.buildOrderClause()
+	}
+	if params.Limit != nil {
+		inner += fmt.Sprintf(" LIMIT $%d", argCounter)
+		args = append(args, *params.Limit)
+	}
+
+	query := fmt.Sprintf(`WITH fluxbase_tile_bounds AS (
+	SELECT ST_TileEnvelope($1, $2, $3) AS envelope,
+	       ST_Transform(ST_TileEnvelope($1, $2, $3), $4::int) AS source_envelope
+)
+SELECT ST_AsMVT(tile.*, $5::text, %d, $6::text) FROM (%s) AS tile`,
+		mvtExtent, inner)
+
+	return query, args
+}
+
+// geometrySRID returns the SRID declared for a geometry column, defaulting to WGS 84
+// for geography and unconstrained geometry columns
+func geometrySRID(c *fiber.Ctx, tx pgx.Tx, table database.TableInfo, geom database.ColumnInfo) int {
+	if strings.Contains(strings.ToLower(geom.DataType), "geography") {
+		return 4326
+	}
+	var srid int
+	err := tx.QueryRow(c.Context(), `
+		SELECT srid FROM geometry_columns
+		WHERE f_table_schema = $1 AND f_table_name = $2 AND f_geometry_column = $3`,
+		table.Schema, table.Name, geom.Name,
+	).Scan(&srid)
+	if err != nil || srid <= 0 {
+		return 4326
+	}
+	return srid
+}
+
+// HandleTile serves a Mapbox vector tile for a table
+// Routes: /tables/:schema/:table/tiles/:z/:x/:y.mvt and /tables/:table/tiles/:z/:x/:y.mvt
+func (h *RESTHandler) HandleTile(c *fiber.Ctx) error {
+	ctx := c.Context()
+	schema, tableName := h.parseTableFromPath(c)
+
+	tableInfo, exists, err := h.schemaCache.GetTable(ctx, schema, tableName)
+	if err != nil {
+		log.Error().Err(err).Str("schema", schema).Str("table", tableName).Msg("Failed to lookup table")
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to lookup table metadata",
+		})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Table '%s.%s' not found", schema, tableName),
+		})
+	}
+
+	return h.makeTileHandler(*tableInfo)(c)
+}
+
+// makeTileHandler creates a GET handler serving vector tiles of a table's geometry column.
+// Features are read under the caller's RLS context and can be narrowed with the regular
+// select, filter, order and limit parameters. geom_column picks the geometry column and
+// layer names the tile layer (default: the table name).
+func (h *RESTHandler) makeTileHandler(table database.TableInfo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+
+		tile, err := parseTileCoords(c.Params("z"), c.Params("x"), c.Params("y"))
+		if err != nil {
+			return SendBadRequest(c, fmt.Sprintf("Invalid tile coordinates: %v", err), ErrCodeInvalidInput)
+		}
+
+		urlValues, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid query string: %v", err),
+			})
+		}
+		geomName := urlValues.Get("geom_column")
+		layer := urlValues.Get("layer")
+		urlValues.Del("geom_column")
+		urlValues.Del("layer")
+		if layer == "" {
+			layer = table.Name
+		}
+
+		params, err := h.parser.Parse(urlValues)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid query parameters: %v", err),
+			})
+		}
+		if len(params.Embedded) > 0 || len(params.Aggregations) > 0 || len(params.GroupBy) > 0 || len(params.Having) > 0 ||
+			len(params.Windows) > 0 || params.AsOf != nil || len(params.Headlines) > 0 {
+			return SendBadRequest(c, "Vector tiles don't support embedded resources, aggregates, window functions, headlines or as_of", ErrCodeInvalidInput)
+		}
+
+		geom, err := resolveGeometryColumn(table, geomName)
+		if err != nil {
+			return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+		}
+
+		// Hidden and masked columns can't be filtered, sorted or drawn
+		role := columnPolicyRole(c)
+		if err := checkProtectedColumns(table, role, params); err != nil {
+			return sendProtectedColumnError(c, err)
+		}
+		if err := validateTextSearch(table, params); err != nil {
+			return SendBadRequest(c, err.Error(), ErrCodeInvalidInput)
+		}
+		if table.ProtectedColumns(role)[geom.Name] {
+			return sendProtectedColumnError(c, fmt.Errorf("column '%s' cannot be rendered as tiles", geom.Name))
+		}
+
+		var mvt []byte
+		err = middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
+			srid := geometrySRID(c, tx, table, geom)
+			query, args := buildTileQuery(table, geom, tileAttributeColumns(table, params, role), layer, params, tile, srid)
+			log.Debug().Str("query", query).Interface("args", args).Msg("Executing tile query")
+			return tx.QueryRow(ctx, query, args...).Scan(&mvt)
+		})
+		if err != nil {
+			log.Error().Err(err).Str("table", fmt.Sprintf("%s.%s", table.Schema, table.Name)).Msg("Failed to render tile")
+			return handleDatabaseError(c, err, "render tile")
+		}
+
+		c.Set(fiber.HeaderContentType, mimeMVT)
+		if len(mvt) == 0 {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		return c.Send(mvt)
+	}
+}
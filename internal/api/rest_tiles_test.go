@@ -0,0 +1,182 @@
+package api
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tileTestTable() database.TableInfo {
+	return database.TableInfo{
+		Schema: "public",
+		Name:   "parcels",
+		Type:   "table",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "name", DataType: "text"},
+			{Name: "owner", DataType: "text"},
+			{Name: "boundary", DataType: "geometry"},
+			{Name: "centroid", DataType: "geography"},
+		},
+		PrimaryKey: []string{"id"},
+		ColumnPolicies: []database.ColumnPolicy{
+			{Column: "owner", Role: "viewer", Mode: database.ColumnPolicyHide},
+			{Column: "centroid", Role: "viewer", Mode: database.ColumnPolicyNull},
+		},
+	}
+}
+
+func TestParseTileCoords(t *testing.T) {
+	tile, err := parseTileCoords("3", "4", "2.mvt")
+	require.NoError(t, err)
+	assert.Equal(t, tileCoords{Z: 3, X: 4, Y: 2}, tile)
+
+	tile, err = parseTileCoords("0", "0", "0")
+	require.NoError(t, err)
+	assert.Equal(t, tileCoords{}, tile)
+
+	tests := []struct {
+		name    string
+		z, x, y string
+		wantErr string
+	}{
+		{name: "negative zoom", z: "-1", x: "0", y: "0", wantErr: "zoom must be"},
+		{name: "zoom too high", z: "31", x: "0", y: "0", wantErr: "zoom must be"},
+		{name: "x outside grid", z: "2", x: "4", y: "0", wantErr: "x must be an integer between 0 and 3 at zoom 2"},
+		{name: "y outside grid", z: "2", x: "0", y: "4.mvt", wantErr: "y must be an integer between 0 and 3 at zoom 2"},
+		{name: "not a number", z: "1", x: "a", y: "0", wantErr: "x must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTileCoords(tt.z, tt.x, tt.y)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestResolveGeometryColumn(t *testing.T) {
+	table := tileTestTable()
+
+	col, err := resolveGeometryColumn(table, "")
+	require.NoError(t, err)
+	assert.Equal(t, "boundary", col.Name)
+
+	col, err = resolveGeometryColumn(table, "centroid")
+	require.NoError(t, err)
+	assert.Equal(t, "centroid", col.Name)
+
+	_, err = resolveGeometryColumn(table, "name")
+	assert.EqualError(t, err, "column 'name' is not a geometry or geography column")
+
+	_, err = resolveGeometryColumn(table, "missing")
+	assert.EqualError(t, err, "unknown column: missing")
+
+	_, err = resolveGeometryColumn(historyTestTable(), "")
+	assert.EqualError(t, err, "table 'public.orders' has no geometry column")
+}
+
+func TestTileAttributeColumns(t *testing.T) {
+	table := tileTestTable()
+
+	assert.Equal(t, []string{"id", "name", "owner"}, tileAttributeColumns(table, &QueryParams{}, "authenticated"))
+	assert.Equal(t, []string{"id", "name"}, tileAttributeColumns(table, &QueryParams{Select: []string{"*"}}, "viewer"))
+	assert.Equal(t, []string{"name"}, tileAttributeColumns(table, &QueryParams{Select: []string{"name", "boundary", "owner"}}, "viewer"))
+}
+
+func TestBuildTileQuery(t *testing.T) {
+	table := tileTestTable()
+	limit := 500
+	params := &QueryParams{
+		Filters: []Filter{{Column: "name", Operator: OpEqual, Value: "north"}},
+		Limit:   &limit,
+	}
+
+	geom, err := resolveGeometryColumn(table, "")
+	require.NoError(t, err)
+	query, args := buildTileQuery(table, geom, []string{"id", "name"}, "parcels", params, tileCoords{Z: 3, X: 4, Y: 2}, 4326)
+
+	assert.Contains(t, query, `ST_TileEnvelope($1, $2, $3) AS envelope`)
+	assert.Contains(t, query, `ST_Transform(ST_TileEnvelope($1, $2, $3), $4::int) AS source_envelope`)
+	assert.Contains(t, query, `ST_AsMVTGeom(ST_Transform("boundary", 3857), (SELECT envelope FROM fluxbase_tile_bounds), 4096, 64, true) AS "boundary", "id", "name"`)
+	assert.Contains(t, query, `FROM "public"."parcels" WHERE "boundary" && (SELECT source_envelope FROM fluxbase_tile_bounds) AND "name" = $7`)
+	assert.Contains(t, query, `LIMIT $8`)
+	assert.Contains(t, query, `SELECT ST_AsMVT(tile.*, $5::text, 4096, $6::text) FROM (`)
+	assert.Equal(t, []interface{}{3, 4, 2, 4326, "parcels", "boundary", "north", 500}, args)
+
+	geog, err := resolveGeometryColumn(table, "centroid")
+	require.NoError(t, err)
+	query, _ = buildTileQuery(table, geog, nil, "points", &QueryParams{}, tileCoords{}, 4326)
+	assert.Contains(t, query, `ST_Transform("centroid"::geometry, 3857)`)
+	assert.Contains(t, query, `WHERE "centroid"::geometry && (SELECT source_envelope FROM fluxbase_tile_bounds)`)
+}
+
+func TestTileRouteMatching(t *testing.T) {
+	app := fiber.New()
+	app.Get("/:schema/:table/tiles/:z/:x/:y.mvt", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("schema") + "." + c.Params("table") + " " + c.Params("z") + "/" + c.Params("x") + "/" + c.Params("y"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/public/parcels/tiles/3/4/2.mvt", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "public.parcels 3/4/2", string(body))
+}
+
+func TestPrepareFeatureCollection(t *testing.T) {
+	table := tileTestTable()
+
+	params := &QueryParams{Select: []string{"id", "name"}}
+	geom, err := prepareFeatureCollection(table, params, "")
+	require.NoError(t, err)
+	assert.Equal(t, "boundary", geom.Name)
+	assert.Equal(t, []string{"id", "name", "boundary"}, params.Select)
+
+	params = &QueryParams{Select: []string{"*"}}
+	_, err = prepareFeatureCollection(table, params, "centroid")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, params.Select)
+
+	_, err = prepareFeatureCollection(table, &QueryParams{GroupBy: []string{"name"}}, "")
+	assert.Error(t, err)
+
+	_, err = prepareFeatureCollection(table, &QueryParams{}, "name")
+	assert.Error(t, err)
+}
+
+func TestToFeatureCollection(t *testing.T) {
+	point := map[string]interface{}{"type": "Point", "coordinates": []interface{}{13.4, 52.5}}
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "north", "boundary": point},
+		{"id": 2, "name": "south", "boundary": nil},
+	}
+
+	fc := toFeatureCollection(tileTestTable(), "boundary", rows)
+	assert.Equal(t, "FeatureCollection", fc["type"])
+
+	features := fc["features"].([]map[string]interface{})
+	require.Len(t, features, 2)
+	assert.Equal(t, map[string]interface{}{
+		"type":       "Feature",
+		"id":         1,
+		"geometry":   point,
+		"properties": map[string]interface{}{"id": 1, "name": "north"},
+	}, features[0])
+	assert.Nil(t, features[1]["geometry"])
+
+	// Composite keys have no single feature id
+	table := tileTestTable()
+	table.PrimaryKey = []string{"id", "name"}
+	features = toFeatureCollection(table, "boundary", rows)["features"].([]map[string]interface{})
+	assert.NotContains(t, features[0], "id")
+
+	empty := toFeatureCollection(table, "boundary", nil)
+	assert.Empty(t, empty["features"])
+}
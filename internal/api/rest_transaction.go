@@ -0,0 +1,679 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/fluxbase-eu/fluxbase/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// maxTransactionOperations limits the number of operations in a single transaction request
+const maxTransactionOperations = 100
+
+// Supported transaction operation methods
+const (
+	txMethodInsert = "insert"
+	txMethodUpsert = "upsert"
+	txMethodUpdate = "update"
+	txMethodDelete = "delete"
+)
+
+// TransactionRequest is the body of POST /tables/_transaction.
+// Operations run in order inside a single transaction under the caller's RLS role.
+type TransactionRequest struct {
+	Operations []TransactionOperation `json:"operations"`
+}
+
+// TransactionOperation is a single write in a transaction request.
+// Values in Data and Filters may be references to the rows returned by an earlier
+// operation, written as {"$ref": "<id>.<column>"} or {"$ref": "<id>.<row>.<column>"}.
+type TransactionOperation struct {
+	ID         string            `json:"id,omitempty"`
+	Method     string            `json:"method"`
+	Table      string            `json:"table"`
+	Data       interface{}       `json:"data,omitempty"`
+	Filters    []PostQueryFilter `json:"filters,omitempty"`
+	OnConflict string            `json:"on_conflict,omitempty"`
+}
+
+// TransactionResult is the outcome of one operation of a committed transaction
+type TransactionResult struct {
+	ID       string                   `json:"id,omitempty"`
+	Method   string                   `json:"method"`
+	Table    string                   `json:"table"`
+	Affected int                      `json:"affected"`
+	Records  []map[string]interface{} `json:"records"`
+}
+
+// transactionStep is a validated operation ready to be executed
+type transactionStep struct {
+	op      TransactionOperation
+	table   database.TableInfo
+	records []map[string]interface{}
+}
+
+// transactionStepError identifies the operation that made a transaction fail
+type transactionStepError struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e *transactionStepError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("operation %d (%s): %v", e.Index, e.ID, e.Err)
+	}
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *transactionStepError) Unwrap() error {
+	return e.Err
+}
+
+// errInvalidReference marks reference resolution failures, which are client errors
+var errInvalidReference = errors.New("invalid reference")
+
+// HandleTransaction executes an ordered list of insert/upsert/update/delete operations
+// across tables in a single transaction. Later operations can reference the rows
+// returned by earlier ones; any failure rolls back every operation.
+func (h *RESTHandler) HandleTransaction(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var req TransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return SendInvalidBody(c)
+	}
+
+	role := columnPolicyRole(c)
+	steps, err := h.prepareTransaction(ctx, role, req.Operations)
+	if err != nil {
+		var stepErr *transactionStepError
+		var schemaErr *jsonSchemaError
+		if errors.As(err, &stepErr) && errors.As(err, &schemaErr) {
+			c.Set("X-Failed-Operation", strconv.Itoa(stepErr.Index))
+			return sendJSONSchemaError(c, schemaErr, fiber.Map{"operation": stepErr.Index, "id": stepErr.ID})
+		}
+		return sendTransactionError(c, err, "Invalid transaction")
+	}
+
+	results := make([]TransactionResult, 0, len(steps))
+	err = middleware.WrapWithRLS(ctx, h.db, c, func(tx pgx.Tx) error {
+		for i, step := range steps {
+			if err := step.validateResolvedJSON(results); err != nil {
+				return &transactionStepError{Index: i, ID: step.op.ID, Err: err}
+			}
+
+			query, args, err := step.buildSQL(results)
+			if err != nil {
+				return &transactionStepError{Index: i, ID: step.op.ID, Err: err}
+			}
+
+			records, err := executeTransactionStep(ctx, tx, query, args)
+			if err != nil {
+				log.Error().Err(err).Int("operation", i).Str("query", query).Msg("Transaction operation failed")
+				return &transactionStepError{Index: i, ID: step.op.ID, Err: err}
+			}
+
+			// Records are masked before later operations can reference them, so references
+			// can't copy protected values into visible columns
+			applyColumnPolicies(step.table, role, nil, records)
+
+			results = append(results, TransactionResult{
+				ID:       step.op.ID,
+				Method:   step.op.Method,
+				Table:    fmt.Sprintf("%s.%s", step.table.Schema, step.table.Name),
+				Affected: len(records),
+				Records:  records,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		var stepErr *transactionStepError
+		if errors.As(err, &stepErr) {
+			c.Set("X-Failed-Operation", strconv.Itoa(stepErr.Index))
+			var schemaErr *jsonSchemaError
+			if errors.As(err, &schemaErr) {
+				return sendJSONSchemaError(c, schemaErr, fiber.Map{"operation": stepErr.Index, "id": stepErr.ID})
+			}
+			if errors.Is(err, errInvalidReference) {
+				return sendTransactionError(c, err, "Invalid reference")
+			}
+			return handleDatabaseError(c, stepErr.Err, fmt.Sprintf("execute transaction operation %d", stepErr.Index))
+		}
+		return handleDatabaseError(c, err, "execute transaction")
+	}
+
+	if h.responseCache != nil {
+		for _, step := range steps {
+			h.responseCache.Invalidate(ctx, step.table.Schema, step.table.Name)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"results": results,
+	})
+}
+
+// sendTransactionError sends a 400 that identifies the failing operation when known
+func sendTransactionError(c *fiber.Ctx, err error, errMsg string) error {
+	var details interface{}
+	var stepErr *transactionStepError
+	if errors.As(err, &stepErr) {
+		details = fiber.Map{"operation": stepErr.Index, "id": stepErr.ID}
+	}
+	return SendErrorWithDetails(c, fiber.StatusBadRequest, errMsg, ErrCodeInvalidInput, err.Error(), "", details)
+}
+
+// executeTransactionStep runs one statement and returns the affected rows
+func executeTransactionStep(ctx context.Context, tx pgx.Tx, query string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgxRowsToJSON(rows)
+}
+
+// prepareTransaction validates every operation before anything is executed
+func (h *RESTHandler) prepareTransaction(ctx context.Context, role string, ops []TransactionOperation) ([]transactionStep, error) {
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("at least one operation is required")
+	}
+	if len(ops) > maxTransactionOperations {
+		return nil, fmt.Errorf("too many operations: %d (maximum %d)", len(ops), maxTransactionOperations)
+	}
+
+	seenIDs := make(map[string]bool)
+	steps := make([]transactionStep, 0, len(ops))
+
+	for i, op := range ops {
+		step, err := h.prepareTransactionStep(ctx, role, op, seenIDs)
+		if err != nil {
+			return nil, &transactionStepError{Index: i, ID: op.ID, Err: err}
+		}
+		if op.ID != "" {
+			seenIDs[op.ID] = true
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func (h *RESTHandler) prepareTransactionStep(ctx context.Context, role string, op TransactionOperation, seenIDs map[string]bool) (transactionStep, error) {
+	step := transactionStep{op: op}
+
+	if op.ID != "" {
+		if strings.Contains(op.ID, ".") {
+			return step, fmt.Errorf("id must not contain '.'")
+		}
+		if seenIDs[op.ID] {
+			return step, fmt.Errorf("duplicate operation id: %s", op.ID)
+		}
+	}
+
+	switch op.Method {
+	case txMethodInsert, txMethodUpsert, txMethodUpdate, txMethodDelete:
+	default:
+		return step, fmt.Errorf("unsupported method %q (expected insert, upsert, update or delete)", op.Method)
+	}
+
+	schema, tableName := splitTableName(op.Table)
+	if tableName == "" {
+		return step, fmt.Errorf("table is required")
+	}
+
+	tableInfo, exists, err := h.schemaCache.GetTable(ctx, schema, tableName)
+	if err != nil {
+		return step, fmt.Errorf("failed to lookup table %s.%s: %w", schema, tableName, err)
+	}
+	if !exists {
+		return step, fmt.Errorf("table '%s.%s' not found", schema, tableName)
+	}
+	writable, err := h.schemaCache.IsTableWritable(ctx, schema, tableName)
+	if err != nil {
+		return step, fmt.Errorf("failed to check table permissions: %w", err)
+	}
+	if !writable {
+		return step, fmt.Errorf("table '%s.%s' is read-only (view or materialized view)", schema, tableName)
+	}
+
+	return prepareTransactionData(step, *tableInfo, role, seenIDs)
+}
+
+// prepareTransactionData validates the data, filters and references of an operation against its
+// table, for the column policies of the role
+func prepareTransactionData(step transactionStep, table database.TableInfo, role string, seenIDs map[string]bool) (transactionStep, error) {
+	op := step.op
+	step.table = table
+
+	records, err := transactionRecords(op.Data)
+	if err != nil {
+		return step, err
+	}
+
+	switch op.Method {
+	case txMethodInsert, txMethodUpsert:
+		if len(records) == 0 {
+			return step, fmt.Errorf("%s requires data", op.Method)
+		}
+		if len(op.Filters) > 0 {
+			return step, fmt.Errorf("%s does not accept filters", op.Method)
+		}
+	case txMethodUpdate:
+		if len(records) != 1 {
+			return step, fmt.Errorf("update requires data to be a single object")
+		}
+		if len(records[0]) == 0 {
+			return step, fmt.Errorf("no fields to update")
+		}
+	case txMethodDelete:
+		if len(records) > 0 {
+			return step, fmt.Errorf("delete does not accept data")
+		}
+	}
+
+	// Updates and deletes must always be scoped by filters
+	if (op.Method == txMethodUpdate || op.Method == txMethodDelete) && len(op.Filters) == 0 {
+		return step, fmt.Errorf("%s requires at least one filter", op.Method)
+	}
+
+	for _, record := range records {
+		for col, val := range record {
+			if !table.HasColumn(col) {
+				return step, fmt.Errorf("unknown column: %s", col)
+			}
+			if err := checkTransactionRef(val, seenIDs); err != nil {
+				return step, err
+			}
+		}
+	}
+
+	// References are resolved at execution time, so only literal documents are validated here;
+	// validateResolvedJSON checks the referenced values before the operation runs
+	if verr := validateJSONColumns(table, withoutTransactionRefs(records), len(records) > 1); verr != nil {
+		return step, verr
+	}
+
+	filters := make([]Filter, len(op.Filters))
+	for i, f := range op.Filters {
+		if !table.HasColumn(baseColumnName(f.Column)) {
+			return step, fmt.Errorf("unknown filter column: %s", f.Column)
+		}
+		if err := checkTransactionRef(f.Value, seenIDs); err != nil {
+			return step, err
+		}
+		filters[i] = Filter{Column: f.Column}
+	}
+
+	// Hidden and masked columns can't be used to find the rows to update or delete
+	if err := checkProtectedColumns(table, role, &QueryParams{Filters: filters}); err != nil {
+		return step, err
+	}
+
+	if op.OnConflict != "" {
+		if op.Method != txMethodUpsert {
+			return step, fmt.Errorf("on_conflict is only supported for upsert")
+		}
+		for _, col := range strings.Split(op.OnConflict, ",") {
+			if !table.HasColumn(strings.TrimSpace(col)) {
+				return step, fmt.Errorf("unknown column in on_conflict: %s", strings.TrimSpace(col))
+			}
+		}
+	}
+
+	step.records = records
+	return step, nil
+}
+
+// splitTableName splits "schema.table" into its parts; unqualified names use the public schema
+func splitTableName(name string) (string, string) {
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		return schema, table
+	}
+	return "public", name
+}
+
+// transactionRecords normalizes operation data (an object or an array of objects) into records
+func transactionRecords(data interface{}) ([]map[string]interface{}, error) {
+	switch v := data.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		records := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			record, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("data[%d] must be an object", i)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("data must be an object or an array of objects")
+	}
+}
+
+// withoutTransactionRefs returns copies of the records without reference values
+func withoutTransactionRefs(records []map[string]interface{}) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		out[i] = make(map[string]interface{}, len(record))
+		for col, val := range record {
+			if _, isRef, _ := parseTransactionRef(val); !isRef {
+				out[i][col] = val
+			}
+		}
+	}
+	return out
+}
+
+// transactionRef is a parsed {"$ref": "..."} value
+type transactionRef struct {
+	ID     string
+	Row    int
+	Column string
+}
+
+// parseTransactionRef returns the reference held by a value, if it is one
+func parseTransactionRef(value interface{}) (*transactionRef, bool, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, false, nil
+	}
+	raw, ok := obj["$ref"]
+	if !ok {
+		return nil, false, nil
+	}
+
+	path, ok := raw.(string)
+	if !ok {
+		return nil, true, fmt.Errorf("%w: $ref must be a string", errInvalidReference)
+	}
+
+	parts := strings.Split(path, ".")
+	switch len(parts) {
+	case 2:
+		return &transactionRef{ID: parts[0], Column: parts[1]}, true, nil
+	case 3:
+		row, err := strconv.Atoi(parts[1])
+		if err != nil || row < 0 {
+			return nil, true, fmt.Errorf("%w: invalid row index in %q", errInvalidReference, path)
+		}
+		return &transactionRef{ID: parts[0], Row: row, Column: parts[2]}, true, nil
+	default:
+		return nil, true, fmt.Errorf("%w: %q (expected <id>.<column> or <id>.<row>.<column>)", errInvalidReference, path)
+	}
+}
+
+// checkTransactionRef validates that a reference points to an earlier operation
+func checkTransactionRef(value interface{}, seenIDs map[string]bool) error {
+	ref, isRef, err := parseTransactionRef(value)
+	if err != nil || !isRef {
+		return err
+	}
+	if !seenIDs[ref.ID] {
+		return fmt.Errorf("%w: %q does not name an earlier operation", errInvalidReference, ref.ID)
+	}
+	return nil
+}
+
+// resolveTransactionValue replaces a reference with the value from an earlier result
+func resolveTransactionValue(value interface{}, results []TransactionResult) (interface{}, error) {
+	ref, isRef, err := parseTransactionRef(value)
+	if err != nil || !isRef {
+		return value, err
+	}
+
+	for _, result := range results {
+		if result.ID != ref.ID {
+			continue
+		}
+		if ref.Row >= len(result.Records) {
+			return nil, fmt.Errorf("%w: operation %q returned %d row(s), row %d requested", errInvalidReference, ref.ID, len(result.Records), ref.Row)
+		}
+		val, ok := result.Records[ref.Row][ref.Column]
+		if !ok {
+			return nil, fmt.Errorf("%w: operation %q did not return column %q", errInvalidReference, ref.ID, ref.Column)
+		}
+		return val, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operation %q", errInvalidReference, ref.ID)
+}
+
+// validateResolvedJSON validates the json columns that are set by references once the
+// referenced values are known. Literal documents were already validated when preparing.
+func (s transactionStep) validateResolvedJSON(results []TransactionResult) error {
+	if len(s.table.JSONSchemas) == 0 {
+		return nil
+	}
+
+	records := make([]map[string]interface{}, len(s.records))
+	for i, record := range s.records {
+		records[i] = make(map[string]interface{})
+		for col, val := range record {
+			if _, isRef, _ := parseTransactionRef(val); !isRef {
+				continue
+			}
+			resolved, err := resolveTransactionValue(val, results)
+			if err != nil {
+				return err
+			}
+			records[i][col] = resolved
+		}
+	}
+
+	if verr := validateJSONColumns(s.table, records, len(records) > 1); verr != nil {
+		return verr
+	}
+	return nil
+}
+
+// buildSQL builds the statement for a step, resolving references against earlier results
+func (s transactionStep) buildSQL(results []TransactionResult) (string, []interface{}, error) {
+	switch s.op.Method {
+	case txMethodInsert, txMethodUpsert:
+		return s.buildInsertSQL(results)
+	case txMethodUpdate:
+		return s.buildUpdateSQL(results)
+	case txMethodDelete:
+		return s.buildDeleteSQL(results)
+	default:
+		return "", nil, fmt.Errorf("unsupported method %q", s.op.Method)
+	}
+}
+
+// valuePlaceholder appends a resolved value to args and returns its placeholder
+func valuePlaceholder(val interface{}, args *[]interface{}, argCounter *int) (string, error) {
+	placeholder := fmt.Sprintf("$%d", *argCounter)
+	if isGeoJSON(val) {
+		geoJSON, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		val = string(geoJSON)
+		placeholder = fmt.Sprintf("ST_GeomFromGeoJSON($%d)", *argCounter)
+	}
+	*args = append(*args, val)
+	*argCounter++
+	return placeholder, nil
+}
+
+func (s transactionStep) buildInsertSQL(results []TransactionResult) (string, []interface{}, error) {
+	// Use the union of columns across records; missing columns get their DEFAULT
+	columnSet := make(map[string]bool)
+	for _, record := range s.records {
+		for col := range record {
+			columnSet[col] = true
+		}
+	}
+	columnNames := make([]string, 0, len(columnSet))
+	for col := range columnSet {
+		columnNames = append(columnNames, col)
+	}
+	sort.Strings(columnNames)
+
+	columns := make([]string, len(columnNames))
+	for i, col := range columnNames {
+		columns[i] = quoteIdentifier(col)
+	}
+
+	var args []interface{}
+	argCounter := 1
+	valueClauses := make([]string, 0, len(s.records))
+	for _, record := range s.records {
+		placeholders := make([]string, len(columnNames))
+		for i, col := range columnNames {
+			val, exists := record[col]
+			if !exists {
+				placeholders[i] = "DEFAULT"
+				continue
+			}
+			resolved, err := resolveTransactionValue(val, results)
+			if err != nil {
+				return "", nil, err
+			}
+			placeholder, err := valuePlaceholder(resolved, &args, &argCounter)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid GeoJSON for column %s: %w", col, err)
+			}
+			placeholders[i] = placeholder
+		}
+		valueClauses = append(valueClauses, "("+strings.Join(placeholders, ", ")+")")
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES %s`,
+		qualifiedTableName(s.table),
+		strings.Join(columns, ", "),
+		strings.Join(valueClauses, ", "),
+	)
+
+	if s.op.Method == txMethodUpsert {
+		var conflictColumns []string
+		if s.op.OnConflict != "" {
+			for _, col := range strings.Split(s.op.OnConflict, ",") {
+				conflictColumns = append(conflictColumns, strings.TrimSpace(col))
+			}
+		} else {
+			conflictColumns = s.table.PrimaryKey
+		}
+		if len(conflictColumns) == 0 {
+			return "", nil, fmt.Errorf("cannot perform upsert: table has no primary key or unique constraint")
+		}
+
+		quotedConflict := make([]string, len(conflictColumns))
+		isConflictColumn := make(map[string]bool, len(conflictColumns))
+		for i, col := range conflictColumns {
+			quotedConflict[i] = quoteIdentifier(col)
+			isConflictColumn[col] = true
+		}
+
+		var updates []string
+		for _, col := range columnNames {
+			if !isConflictColumn[col] {
+				quoted := quoteIdentifier(col)
+				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted))
+			}
+		}
+
+		if len(updates) > 0 {
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quotedConflict, ", "), strings.Join(updates, ", "))
+		} else {
+			query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(quotedConflict, ", "))
+		}
+	}
+
+	return query + buildReturningClause(s.table), args, nil
+}
+
+func (s transactionStep) buildUpdateSQL(results []TransactionResult) (string, []interface{}, error) {
+	data := s.records[0]
+	columnNames := make([]string, 0, len(data))
+	for col := range data {
+		columnNames = append(columnNames, col)
+	}
+	sort.Strings(columnNames)
+
+	var args []interface{}
+	argCounter := 1
+	setClauses := make([]string, 0, len(columnNames))
+	for _, col := range columnNames {
+		resolved, err := resolveTransactionValue(data[col], results)
+		if err != nil {
+			return "", nil, err
+		}
+		placeholder, err := valuePlaceholder(resolved, &args, &argCounter)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid GeoJSON for column %s: %w", col, err)
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", quoteIdentifier(col), placeholder))
+	}
+
+	whereSQL, whereArgs, err := s.buildWhere(results, &argCounter)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, whereArgs...)
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET %s WHERE %s`,
+		qualifiedTableName(s.table),
+		strings.Join(setClauses, ", "),
+		whereSQL,
+	) + buildReturningClause(s.table)
+
+	return query, args, nil
+}
+
+func (s transactionStep) buildDeleteSQL(results []TransactionResult) (string, []interface{}, error) {
+	argCounter := 1
+	whereSQL, args, err := s.buildWhere(results, &argCounter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf(
+		`DELETE FROM %s WHERE %s`,
+		qualifiedTableName(s.table),
+		whereSQL,
+	) + buildReturningClause(s.table)
+
+	return query, args, nil
+}
+
+// buildWhere converts the operation filters to a WHERE clause using the REST filter builder
+func (s transactionStep) buildWhere(results []TransactionResult, argCounter *int) (string, []interface{}, error) {
+	params := &QueryParams{Filters: make([]Filter, 0, len(s.op.Filters))}
+	for _, f := range s.op.Filters {
+		value, err := resolveTransactionValue(f.Value, results)
+		if err != nil {
+			return "", nil, err
+		}
+		params.Filters = append(params.Filters, Filter{
+			Column:   f.Column,
+			Operator: FilterOperator(f.Operator),
+			Value:    value,
+		})
+	}
+
+	whereSQL, args := params.buildWhereClause(argCounter)
+	if whereSQL == "" {
+		return "", nil, fmt.Errorf("%s requires at least one filter", s.op.Method)
+	}
+	return whereSQL, args, nil
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transactionTestTables() (database.TableInfo, database.TableInfo) {
+	orders := database.TableInfo{
+		Schema: "public",
+		Name:   "orders",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "uuid"},
+			{Name: "customer", DataType: "text"},
+			{Name: "total", DataType: "numeric"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	items := database.TableInfo{
+		Schema: "public",
+		Name:   "order_items",
+		Columns: []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "order_id", DataType: "uuid"},
+			{Name: "sku", DataType: "text"},
+			{Name: "quantity", DataType: "integer"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	return orders, items
+}
+
+func TestSplitTableName(t *testing.T) {
+	schema, table := splitTableName("orders")
+	assert.Equal(t, "public", schema)
+	assert.Equal(t, "orders", table)
+
+	schema, table = splitTableName("sales.orders")
+	assert.Equal(t, "sales", schema)
+	assert.Equal(t, "orders", table)
+}
+
+func TestTransactionRecords(t *testing.T) {
+	records, err := transactionRecords(map[string]interface{}{"a": 1})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	records, err = transactionRecords([]interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	records, err = transactionRecords(nil)
+	require.NoError(t, err)
+	assert.Nil(t, records)
+
+	_, err = transactionRecords([]interface{}{"x"})
+	assert.ErrorContains(t, err, "data[0] must be an object")
+
+	_, err = transactionRecords("x")
+	assert.Error(t, err)
+}
+
+func TestParseTransactionRef(t *testing.T) {
+	ref, ok, err := parseTransactionRef(map[string]interface{}{"$ref": "order.id"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, transactionRef{ID: "order", Row: 0, Column: "id"}, *ref)
+
+	ref, ok, err = parseTransactionRef(map[string]interface{}{"$ref": "items.2.sku"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, transactionRef{ID: "items", Row: 2, Column: "sku"}, *ref)
+
+	_, ok, err = parseTransactionRef("order.id")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Objects with other keys are plain values (e.g. jsonb)
+	_, ok, err = parseTransactionRef(map[string]interface{}{"$ref": "order.id", "other": 1})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = parseTransactionRef(map[string]interface{}{"$ref": "order"})
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, errInvalidReference)
+
+	_, ok, err = parseTransactionRef(map[string]interface{}{"$ref": "order.x.id"})
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, errInvalidReference)
+
+	_, ok, err = parseTransactionRef(map[string]interface{}{"$ref": 5})
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, errInvalidReference)
+}
+
+func TestResolveTransactionValue(t *testing.T) {
+	results := []TransactionResult{
+		{ID: "order", Records: []map[string]interface{}{{"id": "abc", "total": 10}}},
+	}
+
+	val, err := resolveTransactionValue(map[string]interface{}{"$ref": "order.id"}, results)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", val)
+
+	val, err = resolveTransactionValue("plain", results)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", val)
+
+	_, err = resolveTransactionValue(map[string]interface{}{"$ref": "order.1.id"}, results)
+	assert.ErrorIs(t, err, errInvalidReference)
+
+	_, err = resolveTransactionValue(map[string]interface{}{"$ref": "order.missing"}, results)
+	assert.ErrorIs(t, err, errInvalidReference)
+
+	_, err = resolveTransactionValue(map[string]interface{}{"$ref": "other.id"}, results)
+	assert.ErrorIs(t, err, errInvalidReference)
+}
+
+func TestPrepareTransactionData_Validation(t *testing.T) {
+	orders, items := transactionTestTables()
+	seen := map[string]bool{"order": true}
+
+	tests := []struct {
+		name    string
+		table   database.TableInfo
+		op      TransactionOperation
+		wantErr string
+	}{
+		{
+			name:  "valid insert with reference",
+			table: items,
+			op: TransactionOperation{Method: txMethodInsert, Data: []interface{}{
+				map[string]interface{}{"order_id": map[string]interface{}{"$ref": "order.id"}, "sku": "A"},
+			}},
+		},
+		{
+			name:    "insert without data",
+			table:   orders,
+			op:      TransactionOperation{Method: txMethodInsert},
+			wantErr: "insert requires data",
+		},
+		{
+			name:    "unknown column",
+			table:   orders,
+			op:      TransactionOperation{Method: txMethodInsert, Data: map[string]interface{}{"nope": 1}},
+			wantErr: "unknown column: nope",
+		},
+		{
+			name:  "reference to later operation",
+			table: items,
+			op: TransactionOperation{Method: txMethodInsert, Data: map[string]interface{}{
+				"order_id": map[string]interface{}{"$ref": "later.id"},
+			}},
+			wantErr: "does not name an earlier operation",
+		},
+		{
+			name:    "update without filters",
+			table:   orders,
+			op:      TransactionOperation{Method: txMethodUpdate, Data: map[string]interface{}{"total": 5}},
+			wantErr: "update requires at least one filter",
+		},
+		{
+			name:  "update with array data",
+			table: orders,
+			op: TransactionOperation{
+				Method:  txMethodUpdate,
+				Data:    []interface{}{map[string]interface{}{"total": 5}, map[string]interface{}{"total": 6}},
+				Filters: []PostQueryFilter{{Column: "id", Operator: "eq", Value: "x"}},
+			},
+			wantErr: "single object",
+		},
+		{
+			name:    "delete without filters",
+			table:   orders,
+			op:      TransactionOperation{Method: txMethodDelete},
+			wantErr: "delete requires at least one filter",
+		},
+		{
+			name:  "unknown filter column",
+			table: orders,
+			op: TransactionOperation{
+				Method:  txMethodDelete,
+				Filters: []PostQueryFilter{{Column: "nope", Operator: "eq", Value: 1}},
+			},
+			wantErr: "unknown filter column",
+		},
+		{
+			name:  "on_conflict on insert",
+			table: orders,
+			op: TransactionOperation{
+				Method:     txMethodInsert,
+				Data:       map[string]interface{}{"customer": "a"},
+				OnConflict: "customer",
+			},
+			wantErr: "only supported for upsert",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := prepareTransactionData(transactionStep{op: tt.op}, tt.table, "authenticated", seen)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareTransactionData_ProtectedFilter(t *testing.T) {
+	orders, _ := transactionTestTables()
+	orders.ColumnPolicies = []database.ColumnPolicy{{Column: "customer", Role: "support", Mode: database.ColumnPolicyMask}}
+	op := TransactionOperation{
+		Method:  txMethodDelete,
+		Filters: []PostQueryFilter{{Column: "customer", Operator: "like", Value: "a%"}},
+	}
+
+	_, err := prepareTransactionData(transactionStep{op: op}, orders, "support", nil)
+	assert.ErrorContains(t, err, "column 'customer' cannot be used in filters")
+
+	_, err = prepareTransactionData(transactionStep{op: op}, orders, "authenticated", nil)
+	assert.NoError(t, err)
+}
+
+func TestTransactionStep_ValidateResolvedJSON(t *testing.T) {
+	table := jsonSchemaTestTable(t)
+	step := transactionStep{op: TransactionOperation{ID: "copy"}, table: table, records: []map[string]interface{}{
+		{"metadata": map[string]interface{}{"$ref": "src.metadata"}},
+	}}
+
+	valid := []TransactionResult{{ID: "src", Records: []map[string]interface{}{
+		{"metadata": map[string]interface{}{"source": "app"}},
+	}}}
+	assert.NoError(t, step.validateResolvedJSON(valid))
+
+	invalid := []TransactionResult{{ID: "src", Records: []map[string]interface{}{
+		{"metadata": map[string]interface{}{"source": 5}},
+	}}}
+	err := step.validateResolvedJSON(invalid)
+	var schemaErr *jsonSchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "metadata/source: expected string, got integer", schemaErr.Error())
+
+	assert.ErrorIs(t, step.validateResolvedJSON(nil), errInvalidReference)
+}
+
+func TestTransactionStep_BuildSQL(t *testing.T) {
+	orders, items := transactionTestTables()
+	results := []TransactionResult{
+		{ID: "order", Records: []map[string]interface{}{{"id": "order-uuid"}}},
+	}
+
+	t.Run("insert uses DEFAULT for missing columns and resolves references", func(t *testing.T) {
+		step, err := prepareTransactionData(transactionStep{op: TransactionOperation{
+			Method: txMethodInsert,
+			Data: []interface{}{
+				map[string]interface{}{"order_id": map[string]interface{}{"$ref": "order.id"}, "sku": "A", "quantity": float64(2)},
+				map[string]interface{}{"order_id": map[string]interface{}{"$ref": "order.id"}, "sku": "B"},
+			},
+		}}, items, "authenticated", map[string]bool{"order": true})
+		require.NoError(t, err)
+
+		query, args, err := step.buildSQL(results)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(query,
+			`INSERT INTO "public"."order_items" ("order_id", "quantity", "sku") VALUES ($1, $2, $3), ($4, DEFAULT, $5) RETURNING `), query)
+		assert.Equal(t, []interface{}{"order-uuid", float64(2), "A", "order-uuid", "B"}, args)
+	})
+
+	t.Run("upsert defaults to primary key conflict target", func(t *testing.T) {
+		step, err := prepareTransactionData(transactionStep{op: TransactionOperation{
+			Method: txMethodUpsert,
+			Data:   map[string]interface{}{"id": "x", "total": float64(3)},
+		}}, orders, "authenticated", nil)
+		require.NoError(t, err)
+
+		query, _, err := step.buildSQL(nil)
+		require.NoError(t, err)
+		assert.Contains(t, query, `ON CONFLICT ("id") DO UPDATE SET "total" = EXCLUDED."total"`)
+	})
+
+	t.Run("update with referenced filter", func(t *testing.T) {
+		step, err := prepareTransactionData(transactionStep{op: TransactionOperation{
+			Method:  txMethodUpdate,
+			Data:    map[string]interface{}{"total": float64(42)},
+			Filters: []PostQueryFilter{{Column: "id", Operator: "eq", Value: map[string]interface{}{"$ref": "order.id"}}},
+		}}, orders, "authenticated", map[string]bool{"order": true})
+		require.NoError(t, err)
+
+		query, args, err := step.buildSQL(results)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(query, `UPDATE "public"."orders" SET "total" = $1 WHERE "id" = $2 RETURNING `), query)
+		assert.Equal(t, []interface{}{float64(42), "order-uuid"}, args)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		step, err := prepareTransactionData(transactionStep{op: TransactionOperation{
+			Method:  txMethodDelete,
+			Filters: []PostQueryFilter{{Column: "sku", Operator: "eq", Value: "A"}},
+		}}, items, "authenticated", nil)
+		require.NoError(t, err)
+
+		query, args, err := step.buildSQL(nil)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(query, `DELETE FROM "public"."order_items" WHERE "sku" = $1 RETURNING `), query)
+		assert.Equal(t, []interface{}{"A"}, args)
+	})
+}
+
+func TestHandleTransaction_InvalidRequests(t *testing.T) {
+	handler := &RESTHandler{}
+	app := fiber.New()
+	app.Post("/_transaction", handler.HandleTransaction)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{invalid`},
+		{name: "no operations", body: `{"operations": []}`},
+		{name: "unknown method", body: `{"operations": [{"method": "merge", "table": "orders"}]}`},
+		{name: "missing table", body: `{"operations": [{"method": "insert", "data": {"a": 1}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/_transaction", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
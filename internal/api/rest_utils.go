@@ -35,44 +35,7 @@ func pgxRowsToJSON(rows pgx.Rows) ([]map[string]interface{}, error) {
 		// Build the result map
 		row := make(map[string]interface{})
 		for i, field := range fields {
-			columnName := string(field.Name)
-
-			// Handle special types
-			switch v := values[i].(type) {
-			case []byte:
-				// First, try to decode as PostGIS geometry (WKB format)
-				geom, err := wkb.Unmarshal(v)
-				if err == nil {
-					// Successfully decoded as WKB, convert to GeoJSON
-					geoJSON, err := geojson.Marshal(geom)
-					if err == nil {
-						var geoJSONData interface{}
-						if err := json.Unmarshal(geoJSON, &geoJSONData); err == nil {
-							row[columnName] = geoJSONData
-							continue
-						}
-					}
-				}
-
-				// Not WKB geometry, try to parse as JSON
-				var jsonData interface{}
-				if err := json.Unmarshal(v, &jsonData); err == nil {
-					row[columnName] = jsonData
-				} else {
-					// If not JSON, convert to string
-					row[columnName] = string(v)
-				}
-			case [16]byte:
-				// Convert UUID bytes to string
-				uid, err := uuid.FromBytes(v[:])
-				if err == nil {
-					row[columnName] = uid.String()
-				} else {
-					row[columnName] = v
-				}
-			default:
-				row[columnName] = v
-			}
+			row[string(field.Name)] = convertPgxValue(values[i])
 		}
 
 		results = append(results, row)
@@ -85,6 +48,43 @@ func pgxRowsToJSON(rows pgx.Rows) ([]map[string]interface{}, error) {
 	return results, nil
 }
 
+// convertPgxValue converts a scanned pgx value to a JSON-serializable value.
+// PostGIS geometries (WKB) become GeoJSON, JSON bytes are decoded and UUID bytes become strings.
+func convertPgxValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		// First, try to decode as PostGIS geometry (WKB format)
+		geom, err := wkb.Unmarshal(v)
+		if err == nil {
+			// Successfully decoded as WKB, convert to GeoJSON
+			geoJSON, err := geojson.Marshal(geom)
+			if err == nil {
+				var geoJSONData interface{}
+				if err := json.Unmarshal(geoJSON, &geoJSONData); err == nil {
+					return geoJSONData
+				}
+			}
+		}
+
+		// Not WKB geometry, try to parse as JSON
+		var jsonData interface{}
+		if err := json.Unmarshal(v, &jsonData); err == nil {
+			return jsonData
+		}
+		// If not JSON, convert to string
+		return string(v)
+	case [16]byte:
+		// Convert UUID bytes to string
+		uid, err := uuid.FromBytes(v[:])
+		if err == nil {
+			return uid.String()
+		}
+		return v
+	default:
+		return v
+	}
+}
+
 // getConflictTarget determines the conflict target for ON CONFLICT clause
 // Returns the primary key columns as a comma-separated quoted string, or empty string if no PK exists
 func (h *RESTHandler) getConflictTarget(table database.TableInfo) string {
@@ -31,9 +31,12 @@ type TypeScriptExportRequest struct {
 	IncludeFunctions bool     `json:"include_functions"` // Include RPC function types
 	IncludeViews     bool     `json:"include_views"`     // Include view types
 	Format           string   `json:"format"`            // "types" (interfaces only) or "full" (with helpers)
+	Lang             string   `json:"lang"`              // "typescript" (default), "go", "python" or "dart"
+	Package          string   `json:"package"`           // Go package name (default: "types")
 }
 
-// HandleExportTypeScript generates TypeScript type definitions from the database schema
+// HandleExportTypeScript generates type definitions from the database schema, in TypeScript
+// unless another language is selected with lang
 func (h *SchemaExportHandler) HandleExportTypeScript(c *fiber.Ctx) error {
 	ctx := c.Context()
 
@@ -56,6 +59,36 @@ func (h *SchemaExportHandler) HandleExportTypeScript(c *fiber.Ctx) error {
 	if req.Format == "" {
 		req.Format = "types"
 	}
+	if lang := c.Query("lang"); lang != "" {
+		req.Lang = lang
+	}
+	if pkg := c.Query("package"); pkg != "" {
+		req.Package = pkg
+	}
+	lang, ok := exportLangs[strings.ToLower(req.Lang)]
+	if !ok {
+		return SendBadRequest(c, "Unsupported lang: "+req.Lang+" (valid options: typescript, go, python, dart)", ErrCodeInvalidInput)
+	}
+	if lang.name == "go" && req.Package != "" && !goIdentifierRegex.MatchString(req.Package) {
+		return SendBadRequest(c, "Invalid Go package name: "+req.Package, ErrCodeInvalidInput)
+	}
+
+	if lang.name != "typescript" {
+		output, err := h.generateLang(ctx, req, lang)
+		if err != nil {
+			return SendInternalError(c, "Failed to generate "+lang.title+" types: "+err.Error())
+		}
+		if c.Method() == "POST" || strings.Contains(c.Get("Accept"), "application/json") {
+			return c.JSON(fiber.Map{
+				"code":    output,
+				"lang":    lang.name,
+				"schemas": req.Schemas,
+			})
+		}
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		c.Set("Content-Disposition", "inline; filename=\"types."+lang.extension+"\"")
+		return c.SendString(output)
+	}
 
 	// Generate TypeScript
 	output, err := h.generateTypeScript(ctx, req)
@@ -70,6 +103,8 @@ func (h *SchemaExportHandler) HandleExportTypeScript(c *fiber.Ctx) error {
 	if c.Method() == "POST" || strings.Contains(accept, "application/json") {
 		return c.JSON(fiber.Map{
 			"typescript": output,
+			"code":       output,
+			"lang":       lang.name,
 			"schemas":    req.Schemas,
 		})
 	}
@@ -373,31 +408,7 @@ func (h *SchemaExportHandler) generateDatabaseNamespace(tablesBySchema map[strin
 
 // pgTypeToTS converts PostgreSQL data types to TypeScript types
 func pgTypeToTS(pgType string) string {
-	// Normalize the type name (lowercase, strip array suffix temporarily)
-	normalizedType := strings.ToLower(strings.TrimSpace(pgType))
-	isArray := strings.HasSuffix(normalizedType, "[]") || strings.HasPrefix(normalizedType, "array")
-
-	// Handle ARRAY type syntax: ARRAY[type] or type[]
-	baseType := normalizedType
-	if isArray {
-		baseType = strings.TrimSuffix(baseType, "[]")
-		baseType = strings.TrimPrefix(baseType, "array")
-		baseType = strings.Trim(baseType, "[]")
-	}
-
-	// Handle type with precision/scale: numeric(10,2), varchar(255), etc.
-	if idx := strings.Index(baseType, "("); idx > 0 {
-		baseType = baseType[:idx]
-	}
-
-	// Handle character varying -> varchar
-	baseType = strings.ReplaceAll(baseType, "character varying", "varchar")
-	baseType = strings.ReplaceAll(baseType, "character", "char")
-	baseType = strings.ReplaceAll(baseType, "double precision", "float8")
-	baseType = strings.ReplaceAll(baseType, "timestamp without time zone", "timestamp")
-	baseType = strings.ReplaceAll(baseType, "timestamp with time zone", "timestamptz")
-	baseType = strings.ReplaceAll(baseType, "time without time zone", "time")
-	baseType = strings.ReplaceAll(baseType, "time with time zone", "timetz")
+	baseType, isArray := normalizePgType(pgType)
 
 	var tsType string
 	switch baseType {
@@ -476,6 +487,38 @@ func pgTypeToTS(pgType string) string {
 	return tsType
 }
 
+// normalizePgType reduces a PostgreSQL type name to its base type (lowercase, without
+// precision, array suffix or long-form spelling) and reports whether it was an array
+func normalizePgType(pgType string) (string, bool) {
+	// Normalize the type name (lowercase, strip array suffix temporarily)
+	normalizedType := strings.ToLower(strings.TrimSpace(pgType))
+	isArray := strings.HasSuffix(normalizedType, "[]") || strings.HasPrefix(normalizedType, "array")
+
+	// Handle ARRAY type syntax: ARRAY[type] or type[]
+	baseType := normalizedType
+	if isArray {
+		baseType = strings.TrimSuffix(baseType, "[]")
+		baseType = strings.TrimPrefix(baseType, "array")
+		baseType = strings.Trim(baseType, "[]")
+	}
+
+	// Handle type with precision/scale: numeric(10,2), varchar(255), etc.
+	if idx := strings.Index(baseType, "("); idx > 0 {
+		baseType = baseType[:idx]
+	}
+
+	// Handle character varying -> varchar
+	baseType = strings.ReplaceAll(baseType, "character varying", "varchar")
+	baseType = strings.ReplaceAll(baseType, "character", "char")
+	baseType = strings.ReplaceAll(baseType, "double precision", "float8")
+	baseType = strings.ReplaceAll(baseType, "timestamp without time zone", "timestamp")
+	baseType = strings.ReplaceAll(baseType, "timestamp with time zone", "timestamptz")
+	baseType = strings.ReplaceAll(baseType, "time without time zone", "time")
+	baseType = strings.ReplaceAll(baseType, "time with time zone", "timetz")
+
+	return baseType, isArray
+}
+
 // filterBySchema filters tables to only include those in the specified schemas
 func filterBySchema(tables []database.TableInfo, schemas []string) []database.TableInfo {
 	schemaSet := make(map[string]bool)
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// dartReservedNames can't be used as field or enum value names; the last ones clash with
+// members of enums and of the generated classes
+var dartReservedNames = map[string]bool{
+	"abstract": true, "as": true, "assert": true, "async": true, "await": true, "base": true, "break": true,
+	"case": true, "catch": true, "class": true, "const": true, "continue": true, "covariant": true,
+	"default": true, "deferred": true, "do": true, "dynamic": true, "else": true, "enum": true,
+	"export": true, "extends": true, "extension": true, "external": true, "factory": true, "false": true,
+	"final": true, "finally": true, "for": true, "function": true, "get": true, "hide": true, "if": true,
+	"implements": true, "import": true, "in": true, "interface": true, "is": true, "late": true,
+	"library": true, "mixin": true, "new": true, "null": true, "of": true, "on": true, "operator": true,
+	"part": true, "required": true, "rethrow": true, "return": true, "sealed": true, "set": true,
+	"show": true, "static": true, "super": true, "switch": true, "sync": true, "this": true, "throw": true,
+	"true": true, "try": true, "type": true, "typedef": true, "var": true, "void": true, "when": true,
+	"while": true, "with": true, "yield": true,
+	"hashCode": true, "index": true, "json": true, "runtimeType": true, "toJson": true, "toString": true,
+	"value": true, "values": true,
+}
+
+// generateDart generates Dart classes with fromJson and toJson. Request bodies leave out
+// unset fields.
+func generateDart(schema *exportedSchema, _ TypeScriptExportRequest) string {
+	var sb strings.Builder
+
+	sb.WriteString("// Auto-generated Dart types from Fluxbase database schema\n")
+	sb.WriteString("// Schemas: " + strings.Join(schema.schemas, ", ") + "\n\n")
+	sb.WriteString("// ignore_for_file: non_constant_identifier_names\n")
+
+	for _, enum := range schema.enums {
+		if len(enum.Values) > 0 {
+			writeDartEnum(&sb, enum)
+		}
+	}
+
+	for _, table := range schema.tables {
+		typeName := exportTypeName(table.Schema, table.Name)
+		writeDartClass(&sb, typeName+"Row", fmt.Sprintf("%s.%s row type", table.Schema, table.Name), schema.rowFields(table), true)
+		writeDartClass(&sb, typeName+"Insert", fmt.Sprintf("%s.%s insert type", table.Schema, table.Name), schema.insertFields(table), false)
+		writeDartClass(&sb, typeName+"Update", fmt.Sprintf("%s.%s update type", table.Schema, table.Name), schema.updateFields(table), false)
+	}
+
+	for _, view := range schema.views {
+		writeDartClass(&sb, exportTypeName(view.Schema, view.Name)+"Row",
+			fmt.Sprintf("%s.%s %s row type", view.Schema, view.Name, strings.ReplaceAll(view.Type, "_", " ")), schema.rowFields(view), true)
+	}
+
+	for _, fn := range schema.functions {
+		typeName := exportTypeName(fn.Schema, fn.Name)
+		if args := schema.functionArgs(fn); len(args) > 0 {
+			writeDartClass(&sb, typeName+"Args", fmt.Sprintf("Arguments for %s.%s", fn.Schema, fn.Name), args, false)
+		}
+		if columns := schema.functionColumns(fn); columns != nil {
+			writeDartClass(&sb, typeName+"Return", fmt.Sprintf("Row returned by %s.%s", fn.Schema, fn.Name), columns, true)
+		} else if ret := schema.functionReturn(fn); ret.kind != kindVoid {
+			sb.WriteString(fmt.Sprintf("\n/// Return type for %s.%s\n", fn.Schema, fn.Name))
+			sb.WriteString(fmt.Sprintf("typedef %sReturn = %s;\n", typeName, dartType(ret, false)))
+		}
+	}
+
+	return sb.String()
+}
+
+// writeDartEnum writes an enhanced enum holding the label of each value
+func writeDartEnum(sb *strings.Builder, enum database.EnumInfo) {
+	typeName := exportTypeName(enum.Schema, enum.Name)
+	names := make([]string, len(enum.Values))
+	for i, value := range enum.Values {
+		names[i] = dartName(value)
+	}
+	names = uniqueNames(names)
+
+	sb.WriteString(fmt.Sprintf("\n/// %s.%s enum\n", enum.Schema, enum.Name))
+	sb.WriteString(fmt.Sprintf("enum %s {\n", typeName))
+	for i, value := range enum.Values {
+		sep := ","
+		if i == len(enum.Values)-1 {
+			sep = ";"
+		}
+		sb.WriteString(fmt.Sprintf("  %s(%s)%s\n", names[i], dartString(value), sep))
+	}
+	sb.WriteString(fmt.Sprintf("\n  const %s(this.value);\n\n", typeName))
+	sb.WriteString("  final String value;\n\n")
+	sb.WriteString(fmt.Sprintf("  static %s fromJson(String value) =>\n", typeName))
+	sb.WriteString(fmt.Sprintf("      %s.values.firstWhere((e) => e.value == value);\n\n", typeName))
+	sb.WriteString("  String toJson() => value;\n")
+	sb.WriteString("}\n")
+}
+
+// writeDartClass writes an immutable class. Rows are read with fromJson and written in full;
+// request bodies only have toJson, which leaves out the fields that are null.
+func writeDartClass(sb *strings.Builder, typeName, doc string, fields []exportField, isRow bool) {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = dartName(field.name)
+	}
+	names = uniqueNames(names)
+
+	// Fields of request bodies that may be left out are nullable, null meaning unset
+	nullable := make([]bool, len(fields))
+	for i, field := range fields {
+		nullable[i] = field.nullable || field.optional
+	}
+
+	sb.WriteString(fmt.Sprintf("\n/// %s\n", doc))
+	sb.WriteString(fmt.Sprintf("class %s {\n", typeName))
+	if len(fields) == 0 {
+		sb.WriteString(fmt.Sprintf("  const %s();\n", typeName))
+	} else {
+		sb.WriteString(fmt.Sprintf("  const %s({\n", typeName))
+		for i, field := range fields {
+			if isRow || !field.optional {
+				sb.WriteString(fmt.Sprintf("    required this.%s,\n", names[i]))
+			} else {
+				sb.WriteString(fmt.Sprintf("    this.%s,\n", names[i]))
+			}
+		}
+		sb.WriteString("  });\n\n")
+		for i, field := range fields {
+			line := fmt.Sprintf("  final %s %s;", dartType(field.typ, nullable[i]), names[i])
+			if field.comment != "" {
+				line += " // " + field.comment
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	if isRow {
+		sb.WriteString(fmt.Sprintf("\n  factory %s.fromJson(Map<String, dynamic> json) => %s(\n", typeName, typeName))
+		for i, field := range fields {
+			value := "json[" + dartString(field.name) + "]"
+			sb.WriteString(fmt.Sprintf("        %s: %s,\n", names[i], dartFromJSON(field.typ, value, nullable[i])))
+		}
+		sb.WriteString("      );\n")
+	}
+
+	sb.WriteString("\n  Map<String, dynamic> toJson() => {\n")
+	for i, field := range fields {
+		value := dartToJSON(field.typ, names[i], nullable[i])
+		if isRow || !nullable[i] {
+			sb.WriteString(fmt.Sprintf("        %s: %s,\n", dartString(field.name), value))
+		} else {
+			sb.WriteString(fmt.Sprintf("        if (%s != null) %s: %s,\n", names[i], dartString(field.name), value))
+		}
+	}
+	sb.WriteString("      };\n")
+	sb.WriteString("}\n")
+}
+
+// dartType returns the Dart type of a field
+func dartType(t exportType, nullable bool) string {
+	var dartT string
+	switch t.kind {
+	case kindString:
+		dartT = "String"
+	case kindInt, kindBigInt:
+		dartT = "int"
+	case kindFloat:
+		dartT = "double"
+	case kindBool:
+		dartT = "bool"
+	case kindTimestamptz, kindTimestamp, kindDate:
+		dartT = "DateTime"
+	case kindEnum, kindRow:
+		dartT = t.name
+	case kindVoid:
+		dartT = "void"
+	default:
+		dartT = "dynamic"
+	}
+
+	if t.array {
+		dartT = "List<" + dartT + ">"
+	}
+	if nullable && dartT != "dynamic" {
+		return dartT + "?"
+	}
+	return dartT
+}
+
+// dartFromJSON returns the expression converting a decoded JSON value to a field
+func dartFromJSON(t exportType, value string, nullable bool) string {
+	if t.array {
+		elem := t
+		elem.array = false
+		convert := dartFromJSON(elem, "e", false)
+		list := "(" + value + " as List<dynamic>)"
+		if nullable {
+			list = "(" + value + " as List<dynamic>?)?"
+		}
+		if convert == "e" {
+			return strings.TrimSuffix(list, "?")
+		}
+		return fmt.Sprintf("%s.map((e) => %s).toList()", list, convert)
+	}
+
+	optional := ""
+	if nullable {
+		optional = "?"
+	}
+	var convert string
+	switch t.kind {
+	case kindString:
+		return value + " as String" + optional
+	case kindBool:
+		return value + " as bool" + optional
+	case kindInt, kindBigInt:
+		return fmt.Sprintf("(%s as num%s)%s.toInt()", value, optional, optional)
+	case kindFloat:
+		return fmt.Sprintf("(%s as num%s)%s.toDouble()", value, optional, optional)
+	case kindTimestamptz, kindTimestamp, kindDate:
+		convert = "DateTime.parse(%s as String)"
+	case kindEnum:
+		convert = t.name + ".fromJson(%s as String)"
+	case kindRow:
+		convert = t.name + ".fromJson(%s as Map<String, dynamic>)"
+	default:
+		return value
+	}
+	if nullable {
+		return fmt.Sprintf("%s == null ? null : %s", value, fmt.Sprintf(convert, value))
+	}
+	return fmt.Sprintf(convert, value)
+}
+
+// dartToJSON returns the expression converting a field to a JSON encodable value
+func dartToJSON(t exportType, value string, nullable bool) string {
+	var method string
+	switch t.kind {
+	case kindTimestamptz, kindTimestamp, kindDate:
+		method = "toIso8601String()"
+	case kindEnum, kindRow:
+		method = "toJson()"
+	default:
+		return value
+	}
+
+	access := "."
+	if nullable {
+		access = "?."
+	}
+	if t.array {
+		return fmt.Sprintf("%s%smap((e) => e.%s).toList()", value, access, method)
+	}
+	return value + access + method
+}
+
+// dartName converts a column, parameter or label name to a Dart field name
+func dartName(name string) string {
+	result := toCamelCase(nonIdentifierChars.ReplaceAllString(name, "_"))
+	if result == "" || (result[0] >= '0' && result[0] <= '9') {
+		result = "v" + result
+	}
+	if dartReservedNames[result] {
+		result += "_"
+	}
+	return result
+}
+
+// dartString returns a single quoted Dart string literal
+func dartString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return "'" + replacer.Replace(s) + "'"
+}
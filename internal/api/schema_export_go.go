@@ -0,0 +1,200 @@
+package api
+
+import (
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+var goIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// goInitialisms are the words Go spells in upper case in identifiers
+var goInitialisms = map[string]bool{
+	"api": true, "db": true, "html": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "ssl": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// generateGo generates Go structs with json and db tags
+func generateGo(schema *exportedSchema, req TypeScriptExportRequest) string {
+	var body strings.Builder
+	imports := make(map[string]bool)
+
+	for _, enum := range schema.enums {
+		if len(enum.Values) > 0 {
+			writeGoEnum(&body, enum)
+		}
+	}
+
+	for _, table := range schema.tables {
+		typeName := exportTypeName(table.Schema, table.Name)
+		writeGoStruct(&body, imports, typeName+"Row",
+			fmt.Sprintf("is a row of the %s.%s table", table.Schema, table.Name), schema.rowFields(table), true)
+		writeGoStruct(&body, imports, typeName+"Insert",
+			fmt.Sprintf("is the body of an insert into the %s.%s table", table.Schema, table.Name), schema.insertFields(table), true)
+		writeGoStruct(&body, imports, typeName+"Update",
+			fmt.Sprintf("is the body of an update of the %s.%s table", table.Schema, table.Name), schema.updateFields(table), true)
+	}
+
+	for _, view := range schema.views {
+		writeGoStruct(&body, imports, exportTypeName(view.Schema, view.Name)+"Row",
+			fmt.Sprintf("is a row of the %s.%s %s", view.Schema, view.Name, strings.ReplaceAll(view.Type, "_", " ")), schema.rowFields(view), true)
+	}
+
+	for _, fn := range schema.functions {
+		typeName := exportTypeName(fn.Schema, fn.Name)
+		if args := schema.functionArgs(fn); len(args) > 0 {
+			writeGoStruct(&body, imports, typeName+"Args",
+				fmt.Sprintf("are the arguments of the %s.%s function", fn.Schema, fn.Name), args, false)
+		}
+		if columns := schema.functionColumns(fn); columns != nil {
+			writeGoStruct(&body, imports, typeName+"Return",
+				fmt.Sprintf("is a row returned by the %s.%s function", fn.Schema, fn.Name), columns, false)
+		} else if ret := schema.functionReturn(fn); ret.kind != kindVoid {
+			body.WriteString(fmt.Sprintf("// %sReturn is the result of the %s.%s function\n", typeName, fn.Schema, fn.Name))
+			body.WriteString(fmt.Sprintf("type %sReturn = %s\n\n", typeName, goType(ret, false, imports)))
+		}
+	}
+
+	pkg := req.Package
+	if pkg == "" {
+		pkg = "types"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by Fluxbase from the database schema. DO NOT EDIT.\n")
+	sb.WriteString("// Schemas: " + strings.Join(schema.schemas, ", ") + "\n\n")
+	sb.WriteString("package " + pkg + "\n\n")
+	if len(imports) > 0 {
+		sb.WriteString("import (\n")
+		for _, path := range []string{"encoding/json", "time"} {
+			if imports[path] {
+				sb.WriteString("\t" + strconv.Quote(path) + "\n")
+			}
+		}
+		sb.WriteString(")\n\n")
+	}
+	sb.WriteString(body.String())
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		// Unformatted output still shows what was generated
+		return sb.String()
+	}
+	return string(formatted)
+}
+
+// writeGoEnum writes an enum as a string type with a constant per label
+func writeGoEnum(sb *strings.Builder, enum database.EnumInfo) {
+	typeName := exportTypeName(enum.Schema, enum.Name)
+	sb.WriteString(fmt.Sprintf("// %s is the %s.%s enum\n", typeName, enum.Schema, enum.Name))
+	sb.WriteString(fmt.Sprintf("type %s string\n\n", typeName))
+
+	names := make([]string, len(enum.Values))
+	for i, value := range enum.Values {
+		names[i] = typeName + goName(value, "Value")
+	}
+	names = uniqueNames(names)
+
+	sb.WriteString("const (\n")
+	for i, value := range enum.Values {
+		sb.WriteString(fmt.Sprintf("\t%s %s = %s\n", names[i], typeName, strconv.Quote(value)))
+	}
+	sb.WriteString(")\n\n")
+}
+
+// writeGoStruct writes a struct. Optional fields are pointers left out of the JSON when nil;
+// db tags are added for scanning rows.
+func writeGoStruct(sb *strings.Builder, imports map[string]bool, typeName, doc string, fields []exportField, dbTags bool) {
+	sb.WriteString(fmt.Sprintf("// %s %s\n", typeName, doc))
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", typeName))
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = goName(field.name, "Field")
+	}
+	names = uniqueNames(names)
+
+	for i, field := range fields {
+		jsonTag := field.name
+		if field.optional {
+			jsonTag += ",omitempty"
+		}
+		tag := "json:" + strconv.Quote(jsonTag)
+		if dbTags {
+			tag += " db:" + strconv.Quote(field.name)
+		}
+		line := fmt.Sprintf("\t%s %s `%s`", names[i], goType(field.typ, field.nullable || field.optional, imports), tag)
+		if field.comment != "" {
+			line += " // " + field.comment
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("}\n\n")
+}
+
+// goType returns the Go type of a field. Nullable values are pointers, except for the types
+// that already are nil-able.
+func goType(t exportType, nullable bool, imports map[string]bool) string {
+	var goT string
+	switch t.kind {
+	case kindString, kindTimestamp, kindDate:
+		goT = "string"
+	case kindInt:
+		goT = "int32"
+	case kindBigInt:
+		goT = "int64"
+	case kindFloat:
+		goT = "float64"
+	case kindBool:
+		goT = "bool"
+	case kindJSON:
+		imports["encoding/json"] = true
+		goT = "json.RawMessage"
+	case kindTimestamptz:
+		imports["time"] = true
+		goT = "time.Time"
+	case kindEnum, kindRow:
+		goT = t.name
+	case kindVoid:
+		goT = "struct{}"
+	default:
+		goT = "any"
+	}
+
+	if t.array {
+		return "[]" + goT
+	}
+	if nullable && goT != "any" && goT != "json.RawMessage" {
+		return "*" + goT
+	}
+	return goT
+}
+
+// goName converts a column, parameter or label name to an exported Go identifier
+func goName(name, fallback string) string {
+	words := splitWords(nonIdentifierChars.ReplaceAllString(name, "_"))
+	var sb strings.Builder
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		lower := strings.ToLower(word)
+		if goInitialisms[lower] {
+			sb.WriteString(strings.ToUpper(lower))
+		} else {
+			sb.WriteString(strings.ToUpper(lower[:1]) + lower[1:])
+		}
+	}
+	result := sb.String()
+	if result == "" {
+		return fallback
+	}
+	if result[0] >= '0' && result[0] <= '9' {
+		return fallback + result
+	}
+	return result
+}
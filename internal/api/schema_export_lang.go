@@ -0,0 +1,361 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// exportLang is a language type definitions can be generated in
+type exportLang struct {
+	name      string
+	title     string
+	extension string
+	generate  func(schema *exportedSchema, req TypeScriptExportRequest) string
+}
+
+var (
+	langTypeScript = exportLang{name: "typescript", title: "TypeScript", extension: "ts"}
+	langGo         = exportLang{name: "go", title: "Go", extension: "go", generate: generateGo}
+	langPython     = exportLang{name: "python", title: "Python", extension: "py", generate: generatePython}
+	langDart       = exportLang{name: "dart", title: "Dart", extension: "dart", generate: generateDart}
+)
+
+// exportLangs maps the accepted lang values to their language
+var exportLangs = map[string]exportLang{
+	"":           langTypeScript,
+	"typescript": langTypeScript,
+	"ts":         langTypeScript,
+	"go":         langGo,
+	"golang":     langGo,
+	"python":     langPython,
+	"py":         langPython,
+	"dart":       langDart,
+}
+
+// exportedSchema is the part of the database schema type definitions are generated for
+type exportedSchema struct {
+	schemas   []string
+	tables    []database.TableInfo
+	views     []database.TableInfo // views and materialized views
+	enums     []database.EnumInfo
+	functions []database.FunctionInfo
+
+	// enumTypes and rowTypes map lowercase type names, bare and schema-qualified, to
+	// the generated type names
+	enumTypes map[string]string
+	rowTypes  map[string]string
+}
+
+// exportKind is the language independent kind of a column or parameter type
+type exportKind int
+
+const (
+	kindUnknown exportKind = iota
+	kindString
+	kindInt
+	kindBigInt
+	kindFloat
+	kindBool
+	kindJSON
+	kindTimestamptz
+	kindTimestamp // timestamp without time zone
+	kindDate
+	kindEnum
+	kindRow
+	kindVoid
+)
+
+// exportType is a column or parameter type resolved for generation
+type exportType struct {
+	kind  exportKind
+	name  string // generated type name of enums and rows
+	array bool
+}
+
+// exportField is a field of a generated type
+type exportField struct {
+	name     string // column or parameter name
+	typ      exportType
+	nullable bool
+	optional bool // may be left out of an insert or a function call
+	comment  string
+}
+
+// generateLang generates type definitions in a language other than TypeScript
+func (h *SchemaExportHandler) generateLang(ctx context.Context, req TypeScriptExportRequest, lang exportLang) (string, error) {
+	schema, err := h.collectSchema(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return lang.generate(schema, req), nil
+}
+
+// collectSchema gathers the tables, views, enums and functions of the requested schemas
+func (h *SchemaExportHandler) collectSchema(ctx context.Context, req TypeScriptExportRequest) (*exportedSchema, error) {
+	tables, err := h.schemaCache.GetAllTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	var views []database.TableInfo
+	if req.IncludeViews {
+		allViews, err := h.schemaCache.GetAllViews(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get views: %w", err)
+		}
+		matViews, err := h.schemaCache.GetAllMaterializedViews(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get materialized views: %w", err)
+		}
+		views = append(filterBySchema(allViews, req.Schemas), filterBySchema(matViews, req.Schemas)...)
+	}
+
+	enums, err := h.inspector.GetAllEnums(ctx, req.Schemas...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enums: %w", err)
+	}
+
+	var functions []database.FunctionInfo
+	if req.IncludeFunctions {
+		functions, err = h.inspector.GetAllFunctions(ctx, req.Schemas...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get functions: %w", err)
+		}
+	}
+
+	return newExportedSchema(req.Schemas, filterBySchema(tables, req.Schemas), views, enums, functions), nil
+}
+
+// newExportedSchema sorts the schema objects and indexes their type names. Overloaded functions
+// are generated once, as their generated types would collide.
+func newExportedSchema(schemas []string, tables, views []database.TableInfo, enums []database.EnumInfo, functions []database.FunctionInfo) *exportedSchema {
+	s := &exportedSchema{
+		schemas:   schemas,
+		tables:    tables,
+		views:     views,
+		enums:     enums,
+		enumTypes: make(map[string]string),
+		rowTypes:  make(map[string]string),
+	}
+
+	sortTables := func(tables []database.TableInfo) {
+		sort.SliceStable(tables, func(i, j int) bool {
+			if tables[i].Schema != tables[j].Schema {
+				return tables[i].Schema < tables[j].Schema
+			}
+			return tables[i].Name < tables[j].Name
+		})
+	}
+	sortTables(s.tables)
+	sortTables(s.views)
+	sort.SliceStable(s.enums, func(i, j int) bool {
+		if s.enums[i].Schema != s.enums[j].Schema {
+			return s.enums[i].Schema < s.enums[j].Schema
+		}
+		return s.enums[i].Name < s.enums[j].Name
+	})
+
+	seen := make(map[string]bool)
+	for _, fn := range functions {
+		key := fn.Schema + "." + fn.Name
+		if !seen[key] {
+			seen[key] = true
+			s.functions = append(s.functions, fn)
+		}
+	}
+
+	// Bare names resolve to the first schema in order, which puts public before most others
+	index := func(types map[string]string, schema, name, typeName string) {
+		qualified := strings.ToLower(schema + "." + name)
+		types[qualified] = typeName
+		if _, ok := types[strings.ToLower(name)]; !ok || schema == "public" {
+			types[strings.ToLower(name)] = typeName
+		}
+	}
+	for _, enum := range s.enums {
+		if len(enum.Values) > 0 {
+			index(s.enumTypes, enum.Schema, enum.Name, exportTypeName(enum.Schema, enum.Name))
+		}
+	}
+	for _, table := range append(append([]database.TableInfo{}, s.tables...), s.views...) {
+		index(s.rowTypes, table.Schema, table.Name, exportTypeName(table.Schema, table.Name)+"Row")
+	}
+
+	return s
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// exportTypeName returns the generated type name of a schema object, prefixed with its schema
+// outside of public like the TypeScript types
+func exportTypeName(schema, name string) string {
+	typeName := toPascalCase(nonIdentifierChars.ReplaceAllString(name, "_"))
+	if schema != "public" {
+		typeName = toPascalCase(nonIdentifierChars.ReplaceAllString(schema, "_")) + typeName
+	}
+	if typeName == "" || (typeName[0] >= '0' && typeName[0] <= '9') {
+		typeName = "T" + typeName
+	}
+	return typeName
+}
+
+// resolveType resolves a PostgreSQL type name to the type generated for it
+func (s *exportedSchema) resolveType(pgType string) exportType {
+	baseType, isArray := normalizePgType(pgType)
+	baseType = strings.ReplaceAll(strings.TrimPrefix(baseType, "setof "), `"`, "")
+
+	t := exportType{array: isArray}
+	switch baseType {
+	case "text", "varchar", "char", "bpchar", "name", "citext", "uuid",
+		"time", "timetz", "interval", "bytea", "xml", "tsvector", "tsquery",
+		"inet", "cidr", "macaddr", "macaddr8",
+		"point", "line", "lseg", "box", "path", "polygon", "circle",
+		"int4range", "int8range", "numrange", "tsrange", "tstzrange", "daterange":
+		t.kind = kindString
+	case "int2", "int4", "smallint", "integer", "smallserial", "serial", "oid":
+		t.kind = kindInt
+	case "int8", "bigint", "bigserial":
+		t.kind = kindBigInt
+	case "float4", "float8", "real", "numeric", "decimal", "money":
+		t.kind = kindFloat
+	case "vector":
+		t.kind = kindFloat
+		t.array = true
+	case "bool", "boolean":
+		t.kind = kindBool
+	case "json", "jsonb", "record":
+		t.kind = kindJSON
+	case "timestamptz":
+		t.kind = kindTimestamptz
+	case "timestamp":
+		t.kind = kindTimestamp
+	case "date":
+		t.kind = kindDate
+	case "void":
+		t.kind = kindVoid
+	default:
+		if name, ok := s.enumTypes[baseType]; ok {
+			t.kind = kindEnum
+			t.name = name
+		} else if name, ok := s.rowTypes[baseType]; ok {
+			t.kind = kindRow
+			t.name = name
+		}
+	}
+	return t
+}
+
+// rowFields returns the fields of the row type of a table or view
+func (s *exportedSchema) rowFields(table database.TableInfo) []exportField {
+	fields := make([]exportField, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		field := exportField{
+			name:     col.Name,
+			typ:      s.resolveType(col.DataType),
+			nullable: col.IsNullable && !col.IsPrimaryKey,
+		}
+		if col.IsPrimaryKey {
+			field.comment = "Primary key"
+		} else if col.IsForeignKey {
+			field.comment = "Foreign key"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// insertFields returns the fields of the insert type of a table. Columns with a default or
+// accepting NULL may be left out.
+func (s *exportedSchema) insertFields(table database.TableInfo) []exportField {
+	fields := make([]exportField, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		hasDefault := col.DefaultValue != nil && *col.DefaultValue != ""
+		fields = append(fields, exportField{
+			name:     col.Name,
+			typ:      s.resolveType(col.DataType),
+			nullable: col.IsNullable,
+			optional: hasDefault || col.IsNullable,
+		})
+	}
+	return fields
+}
+
+// updateFields returns the fields of the update type of a table, all of which may be left out
+func (s *exportedSchema) updateFields(table database.TableInfo) []exportField {
+	fields := s.insertFields(table)
+	for i := range fields {
+		fields[i].optional = true
+	}
+	return fields
+}
+
+// functionArgs returns the input parameters of a function
+func (s *exportedSchema) functionArgs(fn database.FunctionInfo) []exportField {
+	var fields []exportField
+	for _, param := range fn.Parameters {
+		if param.Mode == "OUT" {
+			continue
+		}
+		fields = append(fields, exportField{
+			name:     functionParamName(param),
+			typ:      s.resolveType(param.Type),
+			optional: param.HasDefault,
+		})
+	}
+	return fields
+}
+
+// functionColumns returns the columns of the rows a function returns, for functions returning
+// TABLE(...) or several OUT parameters. Functions returning a single value return nil.
+func (s *exportedSchema) functionColumns(fn database.FunctionInfo) []exportField {
+	var fields []exportField
+	for _, param := range fn.Parameters {
+		if param.Mode != "OUT" && param.Mode != "INOUT" {
+			continue
+		}
+		fields = append(fields, exportField{
+			name: functionParamName(param),
+			typ:  s.resolveType(param.Type),
+		})
+	}
+	if len(fields) > 1 || (len(fields) == 1 && strings.HasPrefix(strings.ToUpper(fn.ReturnType), "TABLE(")) {
+		return fields
+	}
+	return nil
+}
+
+// functionReturn returns the type a function returning a single value returns
+func (s *exportedSchema) functionReturn(fn database.FunctionInfo) exportType {
+	t := s.resolveType(fn.ReturnType)
+	if fn.IsSetOf && t.kind != kindVoid {
+		t.array = true
+	}
+	return t
+}
+
+// functionParamName returns the name of a function parameter, naming unnamed ones by position
+func functionParamName(param database.FunctionParam) string {
+	if param.Name == "" {
+		return fmt.Sprintf("arg%d", param.Position)
+	}
+	return param.Name
+}
+
+// uniqueNames makes generated member names unique by numbering repeats
+func uniqueNames(names []string) []string {
+	seen := make(map[string]int, len(names))
+	result := make([]string, len(names))
+	for i, name := range names {
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, seen[name])
+		}
+		result[i] = name
+	}
+	return result
+}
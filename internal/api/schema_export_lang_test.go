@@ -0,0 +1,285 @@
+package api
+
+import (
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testExportedSchema returns a schema with an enum, a table, a view and functions
+func testExportedSchema() *exportedSchema {
+	defaultNow := "now()"
+	tables := []database.TableInfo{
+		{
+			Schema: "public",
+			Name:   "orders",
+			Type:   "table",
+			Columns: []database.ColumnInfo{
+				{Name: "id", DataType: "uuid", IsPrimaryKey: true, DefaultValue: &defaultNow},
+				{Name: "user_id", DataType: "uuid", IsForeignKey: true},
+				{Name: "status", DataType: "order_status"},
+				{Name: "total", DataType: "numeric(10,2)", IsNullable: true},
+				{Name: "tags", DataType: "text[]", IsNullable: true},
+				{Name: "metadata", DataType: "jsonb", IsNullable: true},
+				{Name: "created_at", DataType: "timestamp with time zone", DefaultValue: &defaultNow},
+				{Name: "class", DataType: "integer", IsNullable: true},
+			},
+		},
+	}
+	views := []database.TableInfo{
+		{
+			Schema: "reporting",
+			Name:   "daily_totals",
+			Type:   "materialized_view",
+			Columns: []database.ColumnInfo{
+				{Name: "day", DataType: "date", IsNullable: true},
+				{Name: "amount", DataType: "double precision", IsNullable: true},
+			},
+		},
+	}
+	enums := []database.EnumInfo{
+		{Schema: "public", Name: "order_status", Values: []string{"pending", "in progress", "shipped"}},
+	}
+	functions := []database.FunctionInfo{
+		{
+			Schema:     "public",
+			Name:       "order_count",
+			ReturnType: "bigint",
+			Parameters: []database.FunctionParam{
+				{Name: "status", Type: "order_status", Mode: "IN", Position: 1},
+				{Name: "since", Type: "timestamp with time zone", Mode: "IN", HasDefault: true, Position: 2},
+			},
+		},
+		{
+			Schema:     "public",
+			Name:       "recent_orders",
+			ReturnType: "SETOF orders",
+			IsSetOf:    true,
+		},
+		{
+			Schema:     "public",
+			Name:       "order_stats",
+			ReturnType: "TABLE(status order_status, count bigint)",
+			IsSetOf:    true,
+			Parameters: []database.FunctionParam{
+				{Name: "status", Type: "order_status", Mode: "OUT", Position: 1},
+				{Name: "count", Type: "bigint", Mode: "OUT", Position: 2},
+			},
+		},
+		{Schema: "public", Name: "refresh_totals", ReturnType: "void"},
+	}
+	return newExportedSchema([]string{"public", "reporting"}, tables, views, enums, functions)
+}
+
+func TestExportedSchema_ResolveType(t *testing.T) {
+	schema := testExportedSchema()
+
+	tests := []struct {
+		pgType   string
+		expected exportType
+	}{
+		{"text", exportType{kind: kindString}},
+		{"character varying(255)", exportType{kind: kindString}},
+		{"integer", exportType{kind: kindInt}},
+		{"bigint", exportType{kind: kindBigInt}},
+		{"numeric(10,2)", exportType{kind: kindFloat}},
+		{"vector", exportType{kind: kindFloat, array: true}},
+		{"boolean", exportType{kind: kindBool}},
+		{"jsonb", exportType{kind: kindJSON}},
+		{"timestamp with time zone", exportType{kind: kindTimestamptz}},
+		{"timestamp without time zone", exportType{kind: kindTimestamp}},
+		{"date", exportType{kind: kindDate}},
+		{"int4[]", exportType{kind: kindInt, array: true}},
+		{"order_status", exportType{kind: kindEnum, name: "OrderStatus"}},
+		{"public.order_status[]", exportType{kind: kindEnum, name: "OrderStatus", array: true}},
+		{"SETOF orders", exportType{kind: kindRow, name: "OrdersRow"}},
+		{"void", exportType{kind: kindVoid}},
+		{"geometry", exportType{kind: kindUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pgType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, schema.resolveType(tt.pgType))
+		})
+	}
+}
+
+func TestExportedSchema_Functions(t *testing.T) {
+	schema := testExportedSchema()
+	byName := make(map[string]database.FunctionInfo)
+	for _, fn := range schema.functions {
+		byName[fn.Name] = fn
+	}
+
+	args := schema.functionArgs(byName["order_count"])
+	require.Len(t, args, 2)
+	assert.False(t, args[0].optional)
+	assert.True(t, args[1].optional, "parameters with a default may be left out")
+	assert.Nil(t, schema.functionColumns(byName["order_count"]))
+	assert.Equal(t, exportType{kind: kindBigInt}, schema.functionReturn(byName["order_count"]))
+
+	assert.Equal(t, exportType{kind: kindRow, name: "OrdersRow", array: true}, schema.functionReturn(byName["recent_orders"]))
+
+	columns := schema.functionColumns(byName["order_stats"])
+	require.Len(t, columns, 2)
+	assert.Equal(t, "status", columns[0].name)
+	assert.Equal(t, exportType{kind: kindEnum, name: "OrderStatus"}, columns[0].typ)
+}
+
+func TestExportTypeName(t *testing.T) {
+	assert.Equal(t, "Orders", exportTypeName("public", "orders"))
+	assert.Equal(t, "ReportingDailyTotals", exportTypeName("reporting", "daily_totals"))
+	assert.Equal(t, "OrderItems", exportTypeName("public", "order items"))
+	assert.Equal(t, "T2024Data", exportTypeName("public", "2024_data"))
+}
+
+func TestGenerateGo(t *testing.T) {
+	output := generateGo(testExportedSchema(), TypeScriptExportRequest{Package: "db"})
+
+	// The output must be valid Go
+	_, err := parser.ParseFile(token.NewFileSet(), "types.go", output, parser.ParseComments)
+	require.NoError(t, err, output)
+
+	assert.Contains(t, output, "// Code generated by Fluxbase from the database schema. DO NOT EDIT.")
+	assert.Contains(t, output, "package db")
+	assert.Contains(t, output, "\"encoding/json\"")
+	assert.Contains(t, output, "\"time\"")
+
+	assert.Contains(t, output, "type OrderStatus string")
+	assert.Contains(t, output, "OrderStatusInProgress OrderStatus = \"in progress\"")
+
+	assert.Regexp(t, `ID\s+string\s+`+"`"+`json:"id" db:"id"`+"`"+`\s+// Primary key`, output)
+	assert.Regexp(t, `UserID\s+string\s+`+"`"+`json:"user_id" db:"user_id"`, output)
+	assert.Regexp(t, `Total\s+\*float64\s+`, output)
+	assert.Regexp(t, `Tags\s+\[\]string\s+`, output)
+	assert.Regexp(t, `Metadata\s+json.RawMessage\s+`, output)
+	assert.Regexp(t, `CreatedAt\s+time.Time\s+`, output)
+
+	// Insert: columns with defaults or accepting NULL are optional
+	assert.Contains(t, output, "type OrdersInsert struct")
+	assert.Regexp(t, `ID\s+\*string\s+`+"`"+`json:"id,omitempty" db:"id"`, output)
+	assert.Contains(t, output, "type OrdersUpdate struct")
+
+	assert.Contains(t, output, "type ReportingDailyTotalsRow struct")
+	assert.Regexp(t, `Day\s+\*string\s+`, output)
+
+	assert.Contains(t, output, "type OrderCountArgs struct")
+	assert.Regexp(t, `Since\s+\*time.Time\s+`+"`"+`json:"since,omitempty"`+"`", output)
+	assert.Contains(t, output, "type OrderCountReturn = int64")
+	assert.Contains(t, output, "type RecentOrdersReturn = []OrdersRow")
+	assert.Contains(t, output, "type OrderStatsReturn struct")
+	assert.NotContains(t, output, "RefreshTotalsReturn")
+}
+
+func TestGenerateGo_WithoutImports(t *testing.T) {
+	schema := newExportedSchema([]string{"public"}, []database.TableInfo{
+		{Schema: "public", Name: "tags", Columns: []database.ColumnInfo{{Name: "name", DataType: "text"}}},
+	}, nil, nil, nil)
+
+	output := generateGo(schema, TypeScriptExportRequest{})
+	_, err := parser.ParseFile(token.NewFileSet(), "types.go", output, 0)
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "package types")
+	assert.NotContains(t, output, "import")
+}
+
+func TestGeneratePython(t *testing.T) {
+	output := generatePython(testExportedSchema(), TypeScriptExportRequest{})
+
+	assert.Contains(t, output, "class OrderStatus(str, Enum):")
+	assert.Contains(t, output, "    IN_PROGRESS = \"in progress\"")
+
+	assert.Contains(t, output, "@dataclass\nclass OrdersRow:")
+	assert.Contains(t, output, "    id: str  # Primary key\n")
+	assert.Contains(t, output, "    total: float | None\n")
+	assert.Contains(t, output, "    tags: list[str] | None\n")
+	assert.Contains(t, output, "    metadata: Any\n")
+	assert.Contains(t, output, "    class_: int | None\n", "keywords get a trailing underscore")
+	assert.Contains(t, output, "            status=OrderStatus(data[\"status\"]),\n")
+	assert.Contains(t, output, "            class_=data.get(\"class\"),\n")
+
+	assert.Contains(t, output, "OrdersInsert = TypedDict(\"OrdersInsert\", {\n")
+	assert.Contains(t, output, "    \"id\": NotRequired[str],\n")
+	assert.Contains(t, output, "    \"user_id\": str,\n")
+	assert.Contains(t, output, "    \"class\": NotRequired[int | None],\n")
+
+	assert.Contains(t, output, "class ReportingDailyTotalsRow:")
+	assert.Contains(t, output, "OrderCountArgs = TypedDict(")
+	assert.Contains(t, output, "    \"since\": NotRequired[str],\n")
+	assert.Contains(t, output, "OrderCountReturn = int\n")
+	assert.Contains(t, output, "RecentOrdersReturn = list[OrdersRow]\n")
+	assert.Contains(t, output, "class OrderStatsReturn:")
+	assert.NotContains(t, output, "RefreshTotalsReturn")
+}
+
+func TestGenerateDart(t *testing.T) {
+	output := generateDart(testExportedSchema(), TypeScriptExportRequest{})
+
+	assert.Contains(t, output, "enum OrderStatus {\n  pending('pending'),\n  inProgress('in progress'),\n  shipped('shipped');\n")
+
+	assert.Contains(t, output, "class OrdersRow {")
+	assert.Contains(t, output, "  final String id; // Primary key\n")
+	assert.Contains(t, output, "  final double? total;\n")
+	assert.Contains(t, output, "  final List<String>? tags;\n")
+	assert.Contains(t, output, "  final DateTime createdAt;\n")
+	assert.Contains(t, output, "  final int? class_;\n")
+	assert.Contains(t, output, "        status: OrderStatus.fromJson(json['status'] as String),\n")
+	assert.Contains(t, output, "        total: (json['total'] as num?)?.toDouble(),\n")
+	assert.Contains(t, output, "        tags: (json['tags'] as List<dynamic>?)?.map((e) => e as String).toList(),\n")
+	assert.Contains(t, output, "        createdAt: DateTime.parse(json['created_at'] as String),\n")
+	assert.Contains(t, output, "        'created_at': createdAt.toIso8601String(),\n")
+
+	// Insert bodies leave out unset fields
+	assert.Contains(t, output, "class OrdersInsert {")
+	assert.Contains(t, output, "    this.id,\n    required this.userId,\n")
+	assert.Contains(t, output, "        if (createdAt != null) 'created_at': createdAt?.toIso8601String(),\n")
+	assert.Contains(t, output, "        'status': status.toJson(),\n")
+
+	assert.Contains(t, output, "class ReportingDailyTotalsRow {")
+	assert.Contains(t, output, "class OrderCountArgs {")
+	assert.Contains(t, output, "typedef OrderCountReturn = int;")
+	assert.Contains(t, output, "typedef RecentOrdersReturn = List<OrdersRow>;")
+	assert.Contains(t, output, "class OrderStatsReturn {")
+	assert.NotContains(t, output, "RefreshTotalsReturn")
+}
+
+func TestDartString(t *testing.T) {
+	assert.Equal(t, `'it\'s \$5'`, dartString("it's $5"))
+}
+
+func TestHandleExportTypeScript_Validation(t *testing.T) {
+	app := fiber.New()
+	handler := NewSchemaExportHandler(nil, nil)
+	app.Post("/schema/typescript", handler.HandleExportTypeScript)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+	}{
+		{"unsupported lang", `{"lang":"cobol"}`, "Unsupported lang: cobol"},
+		{"invalid Go package", `{"lang":"go","package":"my-types"}`, "Invalid Go package name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/schema/typescript", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, 400, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Contains(t, string(body), tt.wantBody)
+		})
+	}
+}
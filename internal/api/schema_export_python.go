@@ -0,0 +1,203 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fluxbase-eu/fluxbase/internal/database"
+)
+
+// pythonKeywords can't be used as attribute names
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// generatePython generates Python dataclasses for rows and TypedDicts for request bodies.
+// Date and time values are kept as the ISO 8601 strings the REST API sends.
+func generatePython(schema *exportedSchema, _ TypeScriptExportRequest) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Auto-generated Python types from Fluxbase database schema\n")
+	sb.WriteString("# Schemas: " + strings.Join(schema.schemas, ", ") + "\n")
+	sb.WriteString("# Requires Python 3.11 or later\n\n")
+	sb.WriteString("from __future__ import annotations\n\n")
+	sb.WriteString("from dataclasses import dataclass\n")
+	sb.WriteString("from enum import Enum\n")
+	sb.WriteString("from typing import Any, NotRequired, TypedDict\n")
+
+	for _, enum := range schema.enums {
+		if len(enum.Values) > 0 {
+			writePythonEnum(&sb, enum)
+		}
+	}
+
+	for _, table := range schema.tables {
+		typeName := exportTypeName(table.Schema, table.Name)
+		writePythonDataclass(&sb, typeName+"Row", fmt.Sprintf("%s.%s row type", table.Schema, table.Name), schema.rowFields(table))
+		writePythonTypedDict(&sb, typeName+"Insert", fmt.Sprintf("%s.%s insert type", table.Schema, table.Name), schema.insertFields(table))
+		writePythonTypedDict(&sb, typeName+"Update", fmt.Sprintf("%s.%s update type", table.Schema, table.Name), schema.updateFields(table))
+	}
+
+	for _, view := range schema.views {
+		writePythonDataclass(&sb, exportTypeName(view.Schema, view.Name)+"Row",
+			fmt.Sprintf("%s.%s %s row type", view.Schema, view.Name, strings.ReplaceAll(view.Type, "_", " ")), schema.rowFields(view))
+	}
+
+	for _, fn := range schema.functions {
+		typeName := exportTypeName(fn.Schema, fn.Name)
+		if args := schema.functionArgs(fn); len(args) > 0 {
+			writePythonTypedDict(&sb, typeName+"Args", fmt.Sprintf("Arguments for %s.%s", fn.Schema, fn.Name), args)
+		}
+		if columns := schema.functionColumns(fn); columns != nil {
+			writePythonDataclass(&sb, typeName+"Return", fmt.Sprintf("Row returned by %s.%s", fn.Schema, fn.Name), columns)
+		} else if ret := schema.functionReturn(fn); ret.kind != kindVoid {
+			sb.WriteString(fmt.Sprintf("\n\n# Return type for %s.%s\n", fn.Schema, fn.Name))
+			sb.WriteString(fmt.Sprintf("%sReturn = %s\n", typeName, pythonType(ret, false)))
+		}
+	}
+
+	return sb.String()
+}
+
+// writePythonEnum writes an enum as a str Enum, so members compare equal to their labels
+func writePythonEnum(sb *strings.Builder, enum database.EnumInfo) {
+	names := make([]string, len(enum.Values))
+	for i, value := range enum.Values {
+		name := strings.ToUpper(strings.Trim(nonIdentifierChars.ReplaceAllString(value, "_"), "_"))
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "V_" + name
+		}
+		names[i] = name
+	}
+	names = uniqueNames(names)
+
+	sb.WriteString(fmt.Sprintf("\n\nclass %s(str, Enum):\n", exportTypeName(enum.Schema, enum.Name)))
+	sb.WriteString(fmt.Sprintf("    \"\"\"%s.%s enum\"\"\"\n\n", enum.Schema, enum.Name))
+	for i, value := range enum.Values {
+		sb.WriteString(fmt.Sprintf("    %s = %s\n", names[i], strconv.Quote(value)))
+	}
+}
+
+// writePythonDataclass writes a dataclass with a from_dict constructor reading a JSON object
+func writePythonDataclass(sb *strings.Builder, typeName, doc string, fields []exportField) {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = pythonName(field.name)
+	}
+	names = uniqueNames(names)
+
+	sb.WriteString(fmt.Sprintf("\n\n@dataclass\nclass %s:\n", typeName))
+	sb.WriteString(fmt.Sprintf("    \"\"\"%s\"\"\"\n\n", doc))
+	for i, field := range fields {
+		line := fmt.Sprintf("    %s: %s", names[i], pythonType(field.typ, field.nullable))
+		if field.comment != "" {
+			line += "  # " + field.comment
+		}
+		sb.WriteString(line + "\n")
+	}
+	if len(fields) > 0 {
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("    @classmethod\n")
+	sb.WriteString(fmt.Sprintf("    def from_dict(cls, data: dict[str, Any]) -> %s:\n", typeName))
+	sb.WriteString("        return cls(\n")
+	for i, field := range fields {
+		sb.WriteString(fmt.Sprintf("            %s=%s,\n", names[i], pythonFromJSON(field)))
+	}
+	sb.WriteString("        )\n")
+}
+
+// writePythonTypedDict writes a TypedDict for a request body. The functional syntax allows
+// keys that aren't Python identifiers.
+func writePythonTypedDict(sb *strings.Builder, typeName, doc string, fields []exportField) {
+	sb.WriteString(fmt.Sprintf("\n\n# %s\n", doc))
+	if len(fields) == 0 {
+		sb.WriteString(fmt.Sprintf("%s = TypedDict(%s, {})\n", typeName, strconv.Quote(typeName)))
+		return
+	}
+	sb.WriteString(fmt.Sprintf("%s = TypedDict(%s, {\n", typeName, strconv.Quote(typeName)))
+	for _, field := range fields {
+		pyType := pythonType(field.typ, field.nullable)
+		if field.optional {
+			pyType = "NotRequired[" + pyType + "]"
+		}
+		sb.WriteString(fmt.Sprintf("    %s: %s,\n", strconv.Quote(field.name), pyType))
+	}
+	sb.WriteString("})\n")
+}
+
+// pythonType returns the type annotation of a field
+func pythonType(t exportType, nullable bool) string {
+	var pyT string
+	switch t.kind {
+	case kindString, kindTimestamptz, kindTimestamp, kindDate:
+		pyT = "str"
+	case kindInt, kindBigInt:
+		pyT = "int"
+	case kindFloat:
+		pyT = "float"
+	case kindBool:
+		pyT = "bool"
+	case kindEnum, kindRow:
+		pyT = t.name
+	case kindVoid:
+		pyT = "None"
+	default:
+		pyT = "Any"
+	}
+
+	if t.array {
+		pyT = "list[" + pyT + "]"
+	}
+	if nullable && pyT != "Any" {
+		return pyT + " | None"
+	}
+	return pyT
+}
+
+// pythonFromJSON returns the expression reading a field from the data dict of from_dict,
+// converting enum labels and nested rows
+func pythonFromJSON(field exportField) string {
+	key := strconv.Quote(field.name)
+	value := "data[" + key + "]"
+	if field.nullable {
+		value = "data.get(" + key + ")"
+	}
+
+	var convert string
+	switch field.typ.kind {
+	case kindEnum:
+		convert = field.typ.name + "(%s)"
+	case kindRow:
+		convert = field.typ.name + ".from_dict(%s)"
+	default:
+		return value
+	}
+
+	converted := fmt.Sprintf(convert, value)
+	if field.typ.array {
+		converted = fmt.Sprintf("[%s for v in %s]", fmt.Sprintf(convert, "v"), value)
+	}
+	if field.nullable {
+		return fmt.Sprintf("%s if %s is not None else None", converted, value)
+	}
+	return converted
+}
+
+// pythonName converts a column or parameter name to a Python attribute name
+func pythonName(name string) string {
+	result := nonIdentifierChars.ReplaceAllString(name, "_")
+	if result == "" || (result[0] >= '0' && result[0] <= '9') {
+		result = "_" + result
+	}
+	if pythonKeywords[result] {
+		result += "_"
+	}
+	return result
+}
@@ -27,12 +27,14 @@ import (
 	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
 	"github.com/fluxbase-eu/fluxbase/internal/ratelimit"
 	"github.com/fluxbase-eu/fluxbase/internal/realtime"
+	"github.com/fluxbase-eu/fluxbase/internal/responsecache"
 	"github.com/fluxbase-eu/fluxbase/internal/rpc"
 	"github.com/fluxbase-eu/fluxbase/internal/scaling"
 	"github.com/fluxbase-eu/fluxbase/internal/secrets"
 	"github.com/fluxbase-eu/fluxbase/internal/settings"
 	"github.com/fluxbase-eu/fluxbase/internal/storage"
 	"github.com/fluxbase-eu/fluxbase/internal/webhook"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -84,6 +86,9 @@ type Server struct {
 	realtimeHandler        *realtime.RealtimeHandler
 	realtimeListener       realtime.RealtimeListener
 	realtimeAdminHandler   *RealtimeAdminHandler
+	historyAdminHandler    *HistoryAdminHandler
+	columnPolicyHandler    *ColumnPolicyHandler
+	jsonSchemaHandler      *JSONSchemaHandler
 	webhookTriggerService  *webhook.TriggerService
 	aiHandler              *ai.Handler
 	aiChatHandler          *ai.ChatHandler
@@ -298,6 +303,10 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 	invitationHandler := NewInvitationHandler(invitationService, dashboardAuthService, emailService, cfg.GetPublicBaseURL())
 	ddlHandler := NewDDLHandler(db)
 	realtimeAdminHandler := NewRealtimeAdminHandler(db)
+	if cfg.Realtime.ChangeSource == string(realtime.ChangeSourceLogical) {
+		realtimeAdminHandler.SetPublication(cfg.Realtime.ReplicationPublication)
+	}
+	historyAdminHandler := NewHistoryAdminHandler(db)
 	serviceKeyHandler := NewServiceKeyHandler(db.Pool())
 	oauthProviderHandler := NewOAuthProviderHandler(db.Pool(), authService.GetSettingsCache(), cfg.EncryptionKey, cfg.GetPublicBaseURL(), cfg.Auth.OAuthProviders)
 	jwtManager := auth.NewJWTManager(cfg.Auth.JWTSecret, cfg.Auth.JWTExpiry, cfg.Auth.RefreshExpiry)
@@ -602,7 +611,39 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 			TTL:     cfg.Realtime.RLSCacheTTL,
 		},
 	)
+	realtimeSubManager.SetColumnPolicyApplier(schemaCache)
+	if cfg.Realtime.ReplayBufferSize > 0 {
+		realtimeSubManager.SetChangeLog(realtime.NewChangeLog(cfg.Realtime.ReplayBufferSize, cfg.Realtime.ReplayRetention))
+	}
 	realtimeHandler := realtime.NewRealtimeHandler(realtimeManager, realtimeAuthAdapter, realtimeSubManager)
+	if ps != nil {
+		realtimeHandler.SetPresencePubSub(ps, cfg.Realtime.PresenceHeartbeat)
+	}
+	if len(cfg.Realtime.ChannelPolicies) > 0 {
+		policies := make([]realtime.ChannelPolicy, len(cfg.Realtime.ChannelPolicies))
+		for i, p := range cfg.Realtime.ChannelPolicies {
+			policies[i] = realtime.ChannelPolicy{
+				Pattern:    p.Pattern,
+				Check:      p.Check,
+				SendCheck:  p.SendCheck,
+				Table:      p.Table,
+				Match:      p.Match,
+				UserColumn: p.UserColumn,
+			}
+		}
+		channelAuthorizer, err := realtime.NewChannelAuthorizer(
+			realtime.NewPgxSubscriptionDB(db.Pool()),
+			policies,
+			realtime.RLSCacheConfig{
+				MaxSize: cfg.Realtime.RLSCacheSize,
+				TTL:     cfg.Realtime.RLSCacheTTL,
+			},
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize realtime channel policies")
+		}
+		realtimeHandler.SetChannelAuthorizer(channelAuthorizer)
+	}
 	realtimeListener := realtime.NewListenerPool(
 		db.Pool(),
 		realtimeHandler,
@@ -612,6 +653,10 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 			PoolSize:    cfg.Realtime.ListenerPoolSize,
 			WorkerCount: cfg.Realtime.NotificationWorkers,
 			QueueSize:   cfg.Realtime.NotificationQueueSize,
+
+			ChangeSource:    realtime.ChangeSource(cfg.Realtime.ChangeSource),
+			ReplicationSlot: cfg.Realtime.ReplicationSlot,
+			Publication:     cfg.Realtime.ReplicationPublication,
 		},
 	)
 
@@ -642,6 +687,9 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 		invitationHandler:      invitationHandler,
 		ddlHandler:             ddlHandler,
 		realtimeAdminHandler:   realtimeAdminHandler,
+		historyAdminHandler:    historyAdminHandler,
+		columnPolicyHandler:    NewColumnPolicyHandler(db, schemaCache),
+		jsonSchemaHandler:      NewJSONSchemaHandler(db, schemaCache),
 		oauthProviderHandler:   oauthProviderHandler,
 		oauthHandler:           oauthHandler,
 		samlProviderHandler:    samlProviderHandler,
@@ -738,6 +786,18 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 	// Create GraphQL handler (if enabled)
 	if cfg.GraphQL.Enabled {
 		server.graphqlHandler = NewGraphQLHandler(db, schemaCache, &cfg.GraphQL)
+		// Subscriptions are fed by the realtime listener's change events
+		if !cfg.Scaling.DisableRealtime && !cfg.Scaling.WorkerOnly {
+			server.graphqlHandler.EnableSubscriptions(realtimeSubManager, realtimeAuthAdapter)
+			realtimeListener.AddEventObserver(server.graphqlHandler)
+		}
+		// RPC procedures are exposed as fields, which change when procedures are synced
+		if rpcHandler != nil {
+			server.graphqlHandler.EnableProcedures(rpcHandler, func(ctx context.Context) bool {
+				return authService.GetSettingsCache().GetBool(ctx, "app.rpc.enabled", false)
+			})
+			rpcHandler.SetOnChange(server.graphqlHandler.InvalidateSchema)
+		}
 		log.Info().
 			Int("max_depth", cfg.GraphQL.MaxDepth).
 			Int("max_complexity", cfg.GraphQL.MaxComplexity).
@@ -745,6 +805,24 @@ func NewServer(cfg *config.Config, db *database.Connection, version string) *Ser
 			Msg("GraphQL API enabled")
 	}
 
+	// Initialize the REST response cache; its entries are invalidated by the realtime listener's
+	// change notifications, so it needs the listener to run
+	if cfg.API.ResponseCache.Enabled {
+		if cfg.Scaling.DisableRealtime || cfg.Scaling.WorkerOnly {
+			log.Warn().Msg("REST response cache requires the realtime listener, caching disabled")
+		} else if store, err := responsecache.NewStore(&cfg.Scaling, cfg.API.ResponseCache.MaxEntries); err != nil {
+			log.Warn().Err(err).Msg("Failed to initialize REST response cache, caching disabled")
+		} else {
+			responseCache := responsecache.New(store, cfg.API.ResponseCache, db.Pool())
+			realtimeListener.AddChangeObserver(responseCache)
+			server.rest.SetResponseCache(responseCache)
+			log.Info().
+				Strs("tables", cfg.API.ResponseCache.Tables).
+				Dur("ttl", cfg.API.ResponseCache.TTL).
+				Msg("REST response cache enabled")
+		}
+	}
+
 	// Start realtime listener (unless disabled or in worker-only mode)
 	if !cfg.Scaling.DisableRealtime && !cfg.Scaling.WorkerOnly {
 		if err := realtimeListener.Start(); err != nil {
@@ -1614,10 +1692,20 @@ func (s *Server) setupRoutes() {
 			middleware.OptionalAuthOrServiceKey(s.authHandler.authService, s.clientKeyService, s.db.Pool(), s.dashboardAuthHandler.jwtManager),
 			s.graphqlHandler.HandleGraphQL,
 		)
-		// Introspection endpoint (GET)
+		// Introspection endpoint (GET), and subscriptions over WebSocket (graphql-ws protocol)
+		// behind the realtime feature flag and realtime:connect scope
+		requireRealtime := middleware.RequireRealtimeEnabled(s.authHandler.authService.GetSettingsCache())
+		requireRealtimeScope := middleware.RequireScope(auth.ScopeRealtimeConnect)
 		s.app.Get("/api/v1/graphql",
 			middleware.OptionalAuthOrServiceKey(s.authHandler.authService, s.clientKeyService, s.db.Pool(), s.dashboardAuthHandler.jwtManager),
-			s.graphqlHandler.HandleIntrospection,
+			func(c *fiber.Ctx) error {
+				if !websocket.IsWebSocketUpgrade(c) {
+					return s.graphqlHandler.HandleIntrospection(c)
+				}
+				return requireRealtime(c)
+			},
+			requireRealtimeScope,
+			s.graphqlHandler.HandleWebSocket,
 		)
 		log.Info().Msg("GraphQL endpoint registered at /api/v1/graphql")
 	}
@@ -1732,6 +1820,12 @@ func (s *Server) setupRESTRoutes(router fiber.Router) {
 	// Dynamic routes using wildcard patterns
 	// Order matters: more specific routes first
 
+	// Multi-table transaction endpoint (ordered operations in a single transaction)
+	// Must be registered before /:schema so it isn't treated as a table name
+	router.Post("/_transaction",
+		middleware.RequireScope(auth.ScopeTablesWrite),
+		s.rest.HandleTransaction)
+
 	// POST query endpoint for complex filters (avoids URL length limits)
 	// Routes: /tables/:schema/:table/query and /tables/:table/query
 	router.Post("/:schema/:table/query",
@@ -1741,6 +1835,30 @@ func (s *Server) setupRESTRoutes(router fiber.Router) {
 		middleware.RequireScope(auth.ScopeTablesRead),
 		s.rest.HandleDynamicQuery)
 
+	// Bulk load via COPY: /tables/:schema/:table/bulk and /tables/:table/bulk
+	router.Post("/:schema/:table/bulk",
+		middleware.RequireScope(auth.ScopeTablesWrite),
+		s.rest.HandleBulkLoad)
+	router.Post("/:schema/bulk",
+		middleware.RequireScope(auth.ScopeTablesWrite),
+		s.rest.HandleBulkLoad)
+
+	// Vector tiles: /tables/:schema/:table/tiles/:z/:x/:y.mvt and /tables/:table/tiles/:z/:x/:y.mvt
+	router.Get("/:schema/:table/tiles/:z/:x/:y.mvt",
+		middleware.RequireScope(auth.ScopeTablesRead),
+		s.rest.HandleTile)
+	router.Get("/:schema/tiles/:z/:x/:y.mvt",
+		middleware.RequireScope(auth.ScopeTablesRead),
+		s.rest.HandleTile)
+
+	// Row history: /tables/:schema/:table/:id/history and /tables/:table/:id/history
+	router.Get("/:schema/:table/:id/history",
+		middleware.RequireScope(auth.ScopeTablesRead),
+		s.rest.HandleRowHistory)
+	router.Get("/:schema/:id/history",
+		middleware.RequireScope(auth.ScopeTablesRead),
+		s.rest.HandleRowHistory)
+
 	// Routes with ID parameter: /tables/:schema/:table/:id and /tables/:table/:id
 	// These handle GET (fetch one), PUT (replace), PATCH (update), DELETE (remove)
 	router.Get("/:schema/:table/:id",
@@ -1916,6 +2034,30 @@ func (s *Server) setupAdminRoutes(router fiber.Router) {
 	router.Patch("/realtime/tables/:schema/:table", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.realtimeAdminHandler.HandleUpdateRealtimeConfig)
 	router.Delete("/realtime/tables/:schema/:table", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.realtimeAdminHandler.HandleDisableRealtime)
 
+	// Row history admin routes - manage per-table change history
+	router.Post("/history/tables", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.historyAdminHandler.HandleEnableHistory)
+	router.Get("/history/tables", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.historyAdminHandler.HandleListHistoryTables)
+	router.Get("/history/tables/:schema/:table", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.historyAdminHandler.HandleGetHistoryStatus)
+	router.Patch("/history/tables/:schema/:table", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.historyAdminHandler.HandleUpdateHistoryConfig)
+	router.Delete("/history/tables/:schema/:table", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.historyAdminHandler.HandleDisableHistory)
+
+	// Column policy routes - per-role column visibility and masking
+	router.Get("/column-policies", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.columnPolicyHandler.HandleListColumnPolicies)
+	router.Post("/column-policies", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.columnPolicyHandler.HandleSetColumnPolicy)
+	router.Delete("/column-policies/:id", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.columnPolicyHandler.HandleDeleteColumnPolicy)
+
+	// Column JSON Schema routes - validate json/jsonb documents on write
+	router.Get("/json-schemas", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.jsonSchemaHandler.HandleListJSONSchemas)
+	router.Put("/json-schemas/:schema/:table/:column", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.jsonSchemaHandler.HandleSetJSONSchema)
+	router.Delete("/json-schemas/:schema/:table/:column", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.jsonSchemaHandler.HandleDeleteJSONSchema)
+
+	// GraphQL operation routes - registered persisted operations forming the allowlist
+	if s.graphqlHandler != nil {
+		router.Get("/graphql/operations", unifiedAuth, RequireRole("admin", "dashboard_admin", "service_role"), s.graphqlHandler.HandleListOperations)
+		router.Post("/graphql/operations", unifiedAuth, RequireRole("admin", "dashboard_admin", "service_role"), s.graphqlHandler.HandleRegisterOperations)
+		router.Delete("/graphql/operations/:hash", unifiedAuth, RequireRole("admin", "dashboard_admin", "service_role"), s.graphqlHandler.HandleDeleteOperation)
+	}
+
 	// OAuth provider management routes (require admin or dashboard_admin role)
 	router.Get("/oauth/providers", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.oauthProviderHandler.ListOAuthProviders)
 	router.Get("/oauth/providers/:id", unifiedAuth, RequireRole("admin", "dashboard_admin"), s.oauthProviderHandler.GetOAuthProvider)
@@ -2376,6 +2518,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.realtimeListener.Stop()
 	}
 
+	// Stop delivering change events to GraphQL subscriptions
+	if s.graphqlHandler != nil {
+		s.graphqlHandler.Shutdown()
+	}
+
 	// Shutdown realtime manager (close all WebSocket connections)
 	if s.realtimeManager != nil {
 		log.Info().Msg("Closing WebSocket connections")
@@ -2712,6 +2859,17 @@ func (s *Server) handleRealtimeBroadcast(c *fiber.Ctx) error {
 		})
 	}
 
+	// Channel policies apply to REST broadcasts like to WebSocket ones
+	role, claims := "anon", map[string]interface{}(nil)
+	if rlsCtx := upgradeRLSContext(c); rlsCtx != nil {
+		role, claims = rlsCtx.Role, rlsCtx.Claims
+	}
+	if !s.realtimeHandler.AuthorizeChannel(c.Context(), role, claims, req.Channel, realtime.ChannelActionSend) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not allowed to send to channel",
+		})
+	}
+
 	manager := s.realtimeHandler.GetManager()
 	recipientCount := manager.BroadcastToChannel(req.Channel, realtime.ServerMessage{
 		Type:    realtime.MessageTypeBroadcast,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -317,24 +318,42 @@ type TransformConfig struct {
 
 // RealtimeConfig contains realtime/websocket settings
 type RealtimeConfig struct {
-	Enabled                bool          `mapstructure:"enabled"`
-	MaxConnections         int           `mapstructure:"max_connections"`
-	MaxConnectionsPerUser  int           `mapstructure:"max_connections_per_user"` // Max connections per authenticated user (0 = unlimited)
-	MaxConnectionsPerIP    int           `mapstructure:"max_connections_per_ip"`   // Max connections per IP for anonymous connections (0 = unlimited)
-	PingInterval           time.Duration `mapstructure:"ping_interval"`
-	PongTimeout            time.Duration `mapstructure:"pong_timeout"`
-	WriteBufferSize        int           `mapstructure:"write_buffer_size"`
-	ReadBufferSize         int           `mapstructure:"read_buffer_size"`
-	MessageSizeLimit       int64         `mapstructure:"message_size_limit"`
-	ChannelBufferSize      int           `mapstructure:"channel_buffer_size"`
-	RLSCacheSize           int           `mapstructure:"rls_cache_size"`            // Maximum entries in RLS cache (default: 100000)
-	RLSCacheTTL            time.Duration `mapstructure:"rls_cache_ttl"`             // TTL for RLS cache entries (default: 30s)
-	ListenerPoolSize       int           `mapstructure:"listener_pool_size"`        // Number of LISTEN connections for redundancy (default: 2)
-	NotificationWorkers    int           `mapstructure:"notification_workers"`      // Number of workers for parallel notification processing (default: 4)
-	NotificationQueueSize  int           `mapstructure:"notification_queue_size"`   // Size of notification queue per worker (default: 1000)
-	ClientMessageQueueSize int           `mapstructure:"client_message_queue_size"` // Size of per-client message queue for async sending (default: 256)
-	SlowClientThreshold    int           `mapstructure:"slow_client_threshold"`     // Queue length threshold for slow client detection (default: 100)
-	SlowClientTimeout      time.Duration `mapstructure:"slow_client_timeout"`       // Duration before disconnecting slow clients (default: 30s)
+	Enabled                bool                    `mapstructure:"enabled"`
+	MaxConnections         int                     `mapstructure:"max_connections"`
+	MaxConnectionsPerUser  int                     `mapstructure:"max_connections_per_user"` // Max connections per authenticated user (0 = unlimited)
+	MaxConnectionsPerIP    int                     `mapstructure:"max_connections_per_ip"`   // Max connections per IP for anonymous connections (0 = unlimited)
+	PingInterval           time.Duration           `mapstructure:"ping_interval"`
+	PongTimeout            time.Duration           `mapstructure:"pong_timeout"`
+	WriteBufferSize        int                     `mapstructure:"write_buffer_size"`
+	ReadBufferSize         int                     `mapstructure:"read_buffer_size"`
+	MessageSizeLimit       int64                   `mapstructure:"message_size_limit"`
+	ChannelBufferSize      int                     `mapstructure:"channel_buffer_size"`
+	RLSCacheSize           int                     `mapstructure:"rls_cache_size"`            // Maximum entries in RLS cache (default: 100000)
+	RLSCacheTTL            time.Duration           `mapstructure:"rls_cache_ttl"`             // TTL for RLS cache entries (default: 30s)
+	ListenerPoolSize       int                     `mapstructure:"listener_pool_size"`        // Number of LISTEN connections for redundancy (default: 2)
+	NotificationWorkers    int                     `mapstructure:"notification_workers"`      // Number of workers for parallel notification processing (default: 4)
+	NotificationQueueSize  int                     `mapstructure:"notification_queue_size"`   // Size of notification queue per worker (default: 1000)
+	ClientMessageQueueSize int                     `mapstructure:"client_message_queue_size"` // Size of per-client message queue for async sending (default: 256)
+	SlowClientThreshold    int                     `mapstructure:"slow_client_threshold"`     // Queue length threshold for slow client detection (default: 100)
+	SlowClientTimeout      time.Duration           `mapstructure:"slow_client_timeout"`       // Duration before disconnecting slow clients (default: 30s)
+	ChangeSource           string                  `mapstructure:"change_source"`             // Source of database changes: "trigger" (pg_notify triggers) or "logical" (logical replication)
+	ReplicationSlot        string                  `mapstructure:"replication_slot"`          // Logical replication slot consumed in logical mode (default: fluxbase_realtime)
+	ReplicationPublication string                  `mapstructure:"replication_publication"`   // Publication of the realtime tables in logical mode (default: fluxbase_realtime)
+	ReplayBufferSize       int                     `mapstructure:"replay_buffer_size"`        // Change events retained for resuming subscriptions, 0 disables resuming (default: 1000)
+	ReplayRetention        time.Duration           `mapstructure:"replay_retention"`          // How long change events are retained for resuming subscriptions (default: 5m)
+	PresenceHeartbeat      time.Duration           `mapstructure:"presence_heartbeat"`        // Interval of presence heartbeats between instances, presences of instances silent for 3 intervals are removed (default: 10s)
+	ChannelPolicies        []RealtimeChannelPolicy `mapstructure:"channel_policies"`          // Who can join and send on broadcast and presence channels, the first matching policy applies
+}
+
+// RealtimeChannelPolicy restricts joining and sending on the broadcast and presence channels
+// matching a pattern, with a SQL check or a membership table run as the user
+type RealtimeChannelPolicy struct {
+	Pattern    string            `mapstructure:"pattern"`     // Channel name pattern, {name} matches a part without colons, e.g. room:{id}
+	Check      string            `mapstructure:"check"`       // SQL condition to join, {name} is bound to the matched part
+	SendCheck  string            `mapstructure:"send_check"`  // SQL condition to send, defaults to the join condition
+	Table      string            `mapstructure:"table"`       // Membership table (schema.table), instead of check
+	Match      map[string]string `mapstructure:"match"`       // Membership table column -> pattern parameter
+	UserColumn string            `mapstructure:"user_column"` // Membership table column holding the user ID (default: user_id)
 }
 
 // EmailConfig contains email/SMTP settings
@@ -388,6 +407,25 @@ type APIConfig struct {
 	MaxPageSize     int `mapstructure:"max_page_size"`     // Max rows per request (-1 = unlimited)
 	MaxTotalResults int `mapstructure:"max_total_results"` // Max total retrievable rows via offset+limit (-1 = unlimited)
 	DefaultPageSize int `mapstructure:"default_page_size"` // Auto-applied when no limit specified (-1 = no default)
+
+	// RowVersionColumn is the column used for row ETags (If-Match) on tables that have it.
+	// Integer columns are incremented on every update; other types must be maintained by the table
+	// (e.g. an updated_at trigger). Tables without the column fall back to the system column xmin.
+	RowVersionColumn string `mapstructure:"row_version_column"`
+
+	ResponseCache ResponseCacheConfig `mapstructure:"response_cache"`
+}
+
+// ResponseCacheConfig contains the REST response cache settings.
+// Cached reads are invalidated from the realtime change notifications, so only tables with realtime
+// enabled for INSERT, UPDATE and DELETE are cached, and only while the realtime listener is connected.
+// RLS policies of cached tables must depend only on the role and JWT claims, not on other tables.
+type ResponseCacheConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Tables       []string      `mapstructure:"tables"`         // Cached tables as schema.table (bare names are in public)
+	TTL          time.Duration `mapstructure:"ttl"`            // Upper bound on entry lifetime
+	MaxEntries   int           `mapstructure:"max_entries"`    // Max entries of the in-memory store
+	MaxEntrySize int           `mapstructure:"max_entry_size"` // Responses larger than this (bytes) aren't cached
 }
 
 // JobsConfig contains long-running background jobs settings
@@ -730,6 +768,12 @@ func setDefaults() {
 	viper.SetDefault("realtime.client_message_queue_size", 256) // Per-client message queue for async sending
 	viper.SetDefault("realtime.slow_client_threshold", 100)     // Disconnect clients with 100+ pending messages
 	viper.SetDefault("realtime.slow_client_timeout", "30s")     // After 30s of being slow
+	viper.SetDefault("realtime.change_source", "trigger")
+	viper.SetDefault("realtime.replication_slot", "fluxbase_realtime")
+	viper.SetDefault("realtime.replication_publication", "fluxbase_realtime")
+	viper.SetDefault("realtime.replay_buffer_size", 1000) // Change events retained for resuming subscriptions
+	viper.SetDefault("realtime.replay_retention", "5m")
+	viper.SetDefault("realtime.presence_heartbeat", "10s") // Presence heartbeat between instances
 
 	// Email defaults
 	viper.SetDefault("email.enabled", true)
@@ -777,6 +821,12 @@ func setDefaults() {
 	viper.SetDefault("api.max_page_size", 1000)      // Max 1000 rows per request
 	viper.SetDefault("api.max_total_results", 10000) // Max 10k total rows retrievable
 	viper.SetDefault("api.default_page_size", 1000)  // Default to 1000 rows if not specified
+	viper.SetDefault("api.row_version_column", "")   // Use xmin for row ETags unless a version column is configured
+	viper.SetDefault("api.response_cache.enabled", false)
+	viper.SetDefault("api.response_cache.tables", []string{})
+	viper.SetDefault("api.response_cache.ttl", "5m")
+	viper.SetDefault("api.response_cache.max_entries", 10000)
+	viper.SetDefault("api.response_cache.max_entry_size", 1048576) // 1MB
 
 	// Migrations defaults
 	viper.SetDefault("migrations.enabled", true) // Enabled by default for better DX (security still enforced via service key + IP allowlist)
@@ -883,10 +933,14 @@ func setDefaults() {
 	})
 
 	// GraphQL defaults
-	viper.SetDefault("graphql.enabled", true)        // Enabled by default
-	viper.SetDefault("graphql.max_depth", 10)        // Maximum query depth
-	viper.SetDefault("graphql.max_complexity", 1000) // Maximum query complexity
-	viper.SetDefault("graphql.introspection", true)  // Enable introspection (disable in production for security)
+	viper.SetDefault("graphql.enabled", true)                           // Enabled by default
+	viper.SetDefault("graphql.max_depth", 10)                           // Maximum query depth
+	viper.SetDefault("graphql.max_complexity", 1000)                    // Maximum query complexity
+	viper.SetDefault("graphql.introspection", true)                     // Enable introspection (disable in production for security)
+	viper.SetDefault("graphql.federation", false)                       // Don't serve Apollo Federation subgraph fields
+	viper.SetDefault("graphql.persisted_queries.enabled", true)         // Accept Automatic Persisted Queries
+	viper.SetDefault("graphql.persisted_queries.allowlist_only", false) // Allow arbitrary documents for all roles
+	viper.SetDefault("graphql.persisted_queries.cache_size", 1000)      // Automatic persisted queries kept per instance
 
 	// MCP defaults (Model Context Protocol server for AI assistants)
 	viper.SetDefault("mcp.enabled", true)                  // Enabled by default
@@ -979,6 +1033,13 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate realtime configuration if enabled
+	if c.Realtime.Enabled {
+		if err := c.Realtime.Validate(); err != nil {
+			return fmt.Errorf("realtime configuration error: %w", err)
+		}
+	}
+
 	// Validate functions configuration if enabled
 	if c.Functions.Enabled {
 		if err := c.Functions.Validate(); err != nil {
@@ -1473,6 +1534,66 @@ func (ac *APIConfig) Validate() error {
 		log.Warn().Msg("default_page_size is set to -1 (no default) - queries without limit parameter will return all rows")
 	}
 
+	if err := ac.ResponseCache.Validate(); err != nil {
+		return fmt.Errorf("response_cache: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates response cache configuration
+func (rc *ResponseCacheConfig) Validate() error {
+	if !rc.Enabled {
+		return nil
+	}
+	if rc.TTL <= 0 {
+		return fmt.Errorf("ttl must be positive, got: %s", rc.TTL)
+	}
+	if rc.MaxEntries <= 0 {
+		return fmt.Errorf("max_entries must be positive, got: %d", rc.MaxEntries)
+	}
+	if rc.MaxEntrySize <= 0 {
+		return fmt.Errorf("max_entry_size must be positive, got: %d", rc.MaxEntrySize)
+	}
+	for _, table := range rc.Tables {
+		parts := strings.Split(table, ".")
+		if len(parts) > 2 || parts[0] == "" || parts[len(parts)-1] == "" {
+			return fmt.Errorf("invalid table %q, expected schema.table", table)
+		}
+	}
+	return nil
+}
+
+// replicationNamePattern matches valid replication slot and publication names
+var replicationNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,63}$`)
+
+// Validate validates realtime configuration
+func (rc *RealtimeConfig) Validate() error {
+	for i, policy := range rc.ChannelPolicies {
+		if policy.Pattern == "" {
+			return fmt.Errorf("channel_policies[%d]: pattern is required", i)
+		}
+		if (policy.Check == "") == (policy.Table == "") {
+			return fmt.Errorf("channel_policies[%d] (%s): exactly one of check and table is required", i, policy.Pattern)
+		}
+		if policy.Table != "" && len(policy.Match) == 0 {
+			return fmt.Errorf("channel_policies[%d] (%s): match is required with table", i, policy.Pattern)
+		}
+	}
+
+	switch rc.ChangeSource {
+	case "", "trigger":
+		return nil
+	case "logical":
+	default:
+		return fmt.Errorf("invalid change_source: %s (must be trigger or logical)", rc.ChangeSource)
+	}
+	if rc.ReplicationSlot != "" && !replicationNamePattern.MatchString(rc.ReplicationSlot) {
+		return fmt.Errorf("invalid replication_slot %q: only lower case letters, digits and underscores are allowed", rc.ReplicationSlot)
+	}
+	if rc.ReplicationPublication != "" && !replicationNamePattern.MatchString(rc.ReplicationPublication) {
+		return fmt.Errorf("invalid replication_publication %q: only lower case letters, digits and underscores are allowed", rc.ReplicationPublication)
+	}
 	return nil
 }
 
@@ -803,6 +803,39 @@ func TestAPIConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "default_page_size",
 		},
+		{
+			name: "valid response cache",
+			config: APIConfig{
+				MaxPageSize:     1000,
+				MaxTotalResults: 10000,
+				DefaultPageSize: 100,
+				ResponseCache: ResponseCacheConfig{
+					Enabled:      true,
+					Tables:       []string{"products", "catalog.categories"},
+					TTL:          5 * time.Minute,
+					MaxEntries:   100,
+					MaxEntrySize: 1024,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid response cache table",
+			config: APIConfig{
+				MaxPageSize:     1000,
+				MaxTotalResults: 10000,
+				DefaultPageSize: 100,
+				ResponseCache: ResponseCacheConfig{
+					Enabled:      true,
+					Tables:       []string{"a.b.c"},
+					TTL:          5 * time.Minute,
+					MaxEntries:   100,
+					MaxEntrySize: 1024,
+				},
+			},
+			wantErr: true,
+			errMsg:  "response_cache",
+		},
 	}
 
 	for _, tt := range tests {
@@ -879,6 +912,102 @@ func TestScalingConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestRealtimeConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RealtimeConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "default change source",
+			config:  RealtimeConfig{},
+			wantErr: false,
+		},
+		{
+			name: "valid logical change source",
+			config: RealtimeConfig{
+				ChangeSource:           "logical",
+				ReplicationSlot:        "fluxbase_realtime",
+				ReplicationPublication: "fluxbase_realtime",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid change source",
+			config: RealtimeConfig{
+				ChangeSource: "wal2json",
+			},
+			wantErr: true,
+			errMsg:  "invalid change_source",
+		},
+		{
+			name: "invalid slot name",
+			config: RealtimeConfig{
+				ChangeSource:    "logical",
+				ReplicationSlot: "Fluxbase-Realtime",
+			},
+			wantErr: true,
+			errMsg:  "invalid replication_slot",
+		},
+		{
+			name: "invalid publication name",
+			config: RealtimeConfig{
+				ChangeSource:           "logical",
+				ReplicationPublication: "realtime; DROP TABLE users",
+			},
+			wantErr: true,
+			errMsg:  "invalid replication_publication",
+		},
+		{
+			name: "valid channel policies",
+			config: RealtimeConfig{
+				ChannelPolicies: []RealtimeChannelPolicy{
+					{Pattern: "room:{id}", Table: "room_members", Match: map[string]string{"room_id": "id"}},
+					{Pattern: "team:{id}", Check: "is_team_member({id}::uuid)"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "channel policy without pattern",
+			config: RealtimeConfig{
+				ChannelPolicies: []RealtimeChannelPolicy{{Check: "true"}},
+			},
+			wantErr: true,
+			errMsg:  "pattern is required",
+		},
+		{
+			name: "channel policy with check and table",
+			config: RealtimeConfig{
+				ChannelPolicies: []RealtimeChannelPolicy{{Pattern: "room:{id}", Check: "true", Table: "room_members"}},
+			},
+			wantErr: true,
+			errMsg:  "exactly one of check and table",
+		},
+		{
+			name: "channel policy table without match",
+			config: RealtimeConfig{
+				ChannelPolicies: []RealtimeChannelPolicy{{Pattern: "room:{id}", Table: "room_members"}},
+			},
+			wantErr: true,
+			errMsg:  "match is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestLoggingConfig_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1529,6 +1658,19 @@ func TestGraphQLConfig_Validate(t *testing.T) {
 			modify:  func(c *GraphQLConfig) { c.MaxDepth = 100; c.MaxComplexity = 100000 },
 			wantErr: false,
 		},
+		{
+			name:    "persisted queries with zero cache size",
+			modify:  func(c *GraphQLConfig) { c.PersistedQueries.Enabled = true },
+			wantErr: true,
+			errMsg:  "graphql persisted_queries cache_size must be at least 1",
+		},
+		{
+			name: "persisted queries with allowlist",
+			modify: func(c *GraphQLConfig) {
+				c.PersistedQueries = GraphQLPersistedQueriesConfig{Enabled: true, AllowlistOnly: true, CacheSize: 1000}
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
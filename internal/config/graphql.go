@@ -8,6 +8,18 @@ type GraphQLConfig struct {
 	MaxDepth      int  `mapstructure:"max_depth"`      // Maximum query depth (default: 10)
 	MaxComplexity int  `mapstructure:"max_complexity"` // Maximum query complexity score (default: 1000)
 	Introspection bool `mapstructure:"introspection"`  // Enable GraphQL introspection (default: true in dev, false in prod)
+	Federation    bool `mapstructure:"federation"`     // Serve the schema as an Apollo Federation subgraph (default: false)
+
+	PersistedQueries GraphQLPersistedQueriesConfig `mapstructure:"persisted_queries"`
+}
+
+// GraphQLPersistedQueriesConfig contains the persisted query settings.
+// Automatic persisted queries let clients send the SHA-256 hash of a query instead of its text;
+// registered operations are uploaded with the CLI or admin API and form the allowlist.
+type GraphQLPersistedQueriesConfig struct {
+	Enabled       bool `mapstructure:"enabled"`        // Accept Automatic Persisted Queries (default: true)
+	AllowlistOnly bool `mapstructure:"allowlist_only"` // Only registered operations run for non-admin roles (default: false)
+	CacheSize     int  `mapstructure:"cache_size"`     // Automatic persisted queries kept in memory per instance (default: 1000)
 }
 
 // Validate validates GraphQL configuration
@@ -24,5 +36,9 @@ func (gc *GraphQLConfig) Validate() error {
 		return fmt.Errorf("graphql max_complexity must be at least 1, got: %d", gc.MaxComplexity)
 	}
 
+	if gc.PersistedQueries.Enabled && gc.PersistedQueries.CacheSize < 1 {
+		return fmt.Errorf("graphql persisted_queries cache_size must be at least 1, got: %d", gc.PersistedQueries.CacheSize)
+	}
+
 	return nil
 }
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/fluxbase-eu/fluxbase/internal/jsonschema"
+	"github.com/rs/zerolog/log"
+)
+
+// ColumnJSONSchema is a JSON Schema that documents written to a json or jsonb column must satisfy
+type ColumnJSONSchema struct {
+	Schema     string          `json:"schema"`
+	Table      string          `json:"table"`
+	Column     string          `json:"column"`
+	Definition json.RawMessage `json:"json_schema"`
+
+	validator *jsonschema.Schema
+}
+
+// NewColumnJSONSchema compiles a JSON Schema for a column
+func NewColumnJSONSchema(schema, table, column string, definition json.RawMessage) (ColumnJSONSchema, error) {
+	validator, err := jsonschema.Compile(definition)
+	if err != nil {
+		return ColumnJSONSchema{}, err
+	}
+	return ColumnJSONSchema{
+		Schema:     schema,
+		Table:      table,
+		Column:     column,
+		Definition: definition,
+		validator:  validator,
+	}, nil
+}
+
+// JSONSchemaViolation describes a column value that does not satisfy the column's JSON Schema
+type JSONSchemaViolation struct {
+	Column  string `json:"column"`
+	Path    string `json:"path"` // JSON Pointer inside the column value, "" for the value itself
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// ValidateJSONColumns validates the json columns present in a row against their JSON Schemas.
+// SQL NULLs are not validated. Strings are validated as the JSON text PostgreSQL will parse.
+func (t *TableInfo) ValidateJSONColumns(row map[string]interface{}) []JSONSchemaViolation {
+	var violations []JSONSchemaViolation
+	for _, s := range t.JSONSchemas {
+		if s.validator == nil {
+			continue
+		}
+		value, ok := row[s.Column]
+		if !ok || value == nil {
+			continue
+		}
+
+		if text, isText := value.(string); isText {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(text), &doc); err != nil {
+				violations = append(violations, JSONSchemaViolation{
+					Column:  s.Column,
+					Keyword: "json",
+					Message: "value is not valid JSON",
+				})
+				continue
+			}
+			value = doc
+		}
+
+		for _, e := range s.validator.Validate(value) {
+			violations = append(violations, JSONSchemaViolation{
+				Column:  s.Column,
+				Path:    e.Path,
+				Keyword: e.Keyword,
+				Message: e.Message,
+			})
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Column < violations[j].Column })
+	return violations
+}
+
+// GetColumnJSONSchemas retrieves and compiles all column JSON Schemas.
+// Schemas that no longer compile are skipped with a warning.
+func (si *SchemaInspector) GetColumnJSONSchemas(ctx context.Context) ([]ColumnJSONSchema, error) {
+	query := `
+		SELECT schema_name, table_name, column_name, json_schema
+		FROM api.column_json_schemas
+		ORDER BY schema_name, table_name, column_name
+	`
+
+	rows, err := si.conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []ColumnJSONSchema
+	for rows.Next() {
+		var schemaName, tableName, columnName string
+		var definition []byte
+		if err := rows.Scan(&schemaName, &tableName, &columnName, &definition); err != nil {
+			return nil, err
+		}
+		s, err := NewColumnJSONSchema(schemaName, tableName, columnName, definition)
+		if err != nil {
+			log.Warn().Err(err).
+				Str("column", schemaName+"."+tableName+"."+columnName).
+				Msg("Skipping invalid column JSON schema")
+			continue
+		}
+		schemas = append(schemas, s)
+	}
+
+	return schemas, rows.Err()
+}
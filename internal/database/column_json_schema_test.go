@@ -0,0 +1,63 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableInfo_ValidateJSONColumns(t *testing.T) {
+	settings, err := NewColumnJSONSchema("public", "profiles", "settings", json.RawMessage(`{
+		"type": "object",
+		"required": ["theme"],
+		"properties": {"theme": {"enum": ["light", "dark"]}}
+	}`))
+	require.NoError(t, err)
+
+	table := TableInfo{Schema: "public", Name: "profiles", JSONSchemas: []ColumnJSONSchema{settings}}
+
+	tests := []struct {
+		name     string
+		row      map[string]interface{}
+		expected []JSONSchemaViolation
+	}{
+		{name: "valid document", row: map[string]interface{}{"settings": map[string]interface{}{"theme": "dark"}}},
+		{name: "column absent", row: map[string]interface{}{"name": "x"}},
+		{name: "sql null", row: map[string]interface{}{"settings": nil}},
+		{name: "valid json text", row: map[string]interface{}{"settings": `{"theme": "light"}`}},
+		{
+			name: "invalid document",
+			row:  map[string]interface{}{"settings": map[string]interface{}{"theme": "blue"}},
+			expected: []JSONSchemaViolation{
+				{Column: "settings", Path: "/theme", Keyword: "enum", Message: `value must be one of ["light","dark"]`},
+			},
+		},
+		{
+			name: "missing property in json text",
+			row:  map[string]interface{}{"settings": `{}`},
+			expected: []JSONSchemaViolation{
+				{Column: "settings", Path: "/theme", Keyword: "required", Message: "property is required"},
+			},
+		},
+		{
+			name: "malformed json text",
+			row:  map[string]interface{}{"settings": `{"theme":`},
+			expected: []JSONSchemaViolation{
+				{Column: "settings", Keyword: "json", Message: "value is not valid JSON"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, table.ValidateJSONColumns(tt.row))
+		})
+	}
+}
+
+func TestNewColumnJSONSchema_Invalid(t *testing.T) {
+	_, err := NewColumnJSONSchema("public", "profiles", "settings", json.RawMessage(`{"type": "dictionary"}`))
+	assert.Error(t, err)
+}
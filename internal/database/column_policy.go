@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ColumnPolicyMode controls how a protected column is returned to a role
+type ColumnPolicyMode string
+
+const (
+	ColumnPolicyHide ColumnPolicyMode = "hide" // Column is omitted from results
+	ColumnPolicyMask ColumnPolicyMode = "mask" // All but the trailing characters are masked, e.g. ****1234
+	ColumnPolicyHash ColumnPolicyMode = "hash" // Value is replaced by its SHA-256 hex digest
+	ColumnPolicyNull ColumnPolicyMode = "null" // Value is replaced by null
+)
+
+// DefaultMaskVisible is the number of trailing characters a mask leaves visible by default
+const DefaultMaskVisible = 4
+
+// maskPrefix replaces the hidden part of a masked value; it has a fixed length so the
+// original length is not revealed
+const maskPrefix = "****"
+
+// IsValid reports whether the mode is a known column policy mode
+func (m ColumnPolicyMode) IsValid() bool {
+	switch m {
+	case ColumnPolicyHide, ColumnPolicyMask, ColumnPolicyHash, ColumnPolicyNull:
+		return true
+	}
+	return false
+}
+
+// ColumnPolicy restricts how a column is returned to an application role.
+// Policies are loaded into the schema cache and applied to REST, GraphQL and realtime results.
+type ColumnPolicy struct {
+	Schema      string           `json:"schema"`
+	Table       string           `json:"table"`
+	Column      string           `json:"column"`
+	Role        string           `json:"role"`
+	Mode        ColumnPolicyMode `json:"mode"`
+	MaskVisible int              `json:"mask_visible"` // Trailing characters left visible by mask
+}
+
+// Apply returns the value as the policy's role may see it.
+// Hidden columns are removed by the caller; Apply returns nil for them.
+func (p ColumnPolicy) Apply(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch p.Mode {
+	case ColumnPolicyMask:
+		return maskValue(columnPolicyText(value), p.MaskVisible)
+	case ColumnPolicyHash:
+		sum := sha256.Sum256([]byte(columnPolicyText(value)))
+		return hex.EncodeToString(sum[:])
+	default:
+		return nil
+	}
+}
+
+// maskValue keeps the last visible characters of s behind a fixed mask
+func maskValue(s string, visible int) string {
+	runes := []rune(s)
+	if visible <= 0 || len(runes) <= visible {
+		return maskPrefix
+	}
+	return maskPrefix + string(runes[len(runes)-visible:])
+}
+
+// columnPolicyText renders a value as text for masking and hashing
+func columnPolicyText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// ColumnPoliciesForRole returns the table's column policies for a role keyed by column, or nil if none apply
+func (t *TableInfo) ColumnPoliciesForRole(role string) map[string]ColumnPolicy {
+	var policies map[string]ColumnPolicy
+	for _, p := range t.ColumnPolicies {
+		if p.Role != role {
+			continue
+		}
+		if policies == nil {
+			policies = make(map[string]ColumnPolicy)
+		}
+		policies[p.Column] = p
+	}
+	return policies
+}
+
+// ProtectedColumns returns the columns with a policy for the role, which the role can't filter, sort or aggregate on
+func (t *TableInfo) ProtectedColumns(role string) map[string]bool {
+	var protected map[string]bool
+	for _, p := range t.ColumnPolicies {
+		if p.Role != role {
+			continue
+		}
+		if protected == nil {
+			protected = make(map[string]bool)
+		}
+		protected[p.Column] = true
+	}
+	return protected
+}
+
+// ApplyColumnPolicies masks a row in place according to the role's column policies
+func (t *TableInfo) ApplyColumnPolicies(role string, row map[string]interface{}) {
+	if row == nil {
+		return
+	}
+	for _, p := range t.ColumnPolicies {
+		if p.Role != role {
+			continue
+		}
+		value, ok := row[p.Column]
+		if !ok {
+			continue
+		}
+		if p.Mode == ColumnPolicyHide {
+			delete(row, p.Column)
+			continue
+		}
+		row[p.Column] = p.Apply(value)
+	}
+}
+
+// GetColumnPolicies retrieves all column policies
+func (si *SchemaInspector) GetColumnPolicies(ctx context.Context) ([]ColumnPolicy, error) {
+	query := `
+		SELECT schema_name, table_name, column_name, role, mode, mask_visible
+		FROM api.column_policies
+		ORDER BY schema_name, table_name, column_name, role
+	`
+
+	rows, err := si.conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []ColumnPolicy
+	for rows.Next() {
+		var p ColumnPolicy
+		var mode string
+		if err := rows.Scan(&p.Schema, &p.Table, &p.Column, &p.Role, &mode, &p.MaskVisible); err != nil {
+			return nil, err
+		}
+		p.Mode = ColumnPolicyMode(strings.ToLower(mode))
+		policies = append(policies, p)
+	}
+
+	return policies, rows.Err()
+}
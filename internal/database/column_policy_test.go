@@ -0,0 +1,83 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnPolicyMode_IsValid(t *testing.T) {
+	for _, mode := range []ColumnPolicyMode{ColumnPolicyHide, ColumnPolicyMask, ColumnPolicyHash, ColumnPolicyNull} {
+		assert.True(t, mode.IsValid(), mode)
+	}
+	assert.False(t, ColumnPolicyMode("redact").IsValid())
+	assert.False(t, ColumnPolicyMode("").IsValid())
+}
+
+func TestColumnPolicy_Apply(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+
+	tests := []struct {
+		name     string
+		policy   ColumnPolicy
+		value    interface{}
+		expected interface{}
+	}{
+		{name: "mask keeps last characters", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 4}, value: "4111111111111234", expected: "****1234"},
+		{name: "mask short value", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 4}, value: "123", expected: "****"},
+		{name: "mask zero visible", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 0}, value: "abcdef", expected: "****"},
+		{name: "mask multibyte", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 2}, value: "héllö", expected: "****lö"},
+		{name: "mask number", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 2}, value: 123456, expected: "****56"},
+		{name: "hash", policy: ColumnPolicy{Mode: ColumnPolicyHash}, value: "secret", expected: hex.EncodeToString(sum[:])},
+		{name: "null", policy: ColumnPolicy{Mode: ColumnPolicyNull}, value: "secret", expected: nil},
+		{name: "nil stays nil", policy: ColumnPolicy{Mode: ColumnPolicyMask, MaskVisible: 4}, value: nil, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.Apply(tt.value))
+		})
+	}
+}
+
+func TestTableInfo_ApplyColumnPolicies(t *testing.T) {
+	table := TableInfo{
+		Schema: "public",
+		Name:   "customers",
+		ColumnPolicies: []ColumnPolicy{
+			{Column: "ssn", Role: "support", Mode: ColumnPolicyHide},
+			{Column: "card", Role: "support", Mode: ColumnPolicyMask, MaskVisible: 4},
+			{Column: "email", Role: "analyst", Mode: ColumnPolicyNull},
+		},
+	}
+
+	t.Run("applies only the role's policies", func(t *testing.T) {
+		row := map[string]interface{}{"id": 1, "ssn": "123-45-6789", "card": "4111111111111234", "email": "a@example.com"}
+		table.ApplyColumnPolicies("support", row)
+
+		assert.NotContains(t, row, "ssn")
+		assert.Equal(t, "****1234", row["card"])
+		assert.Equal(t, "a@example.com", row["email"])
+		assert.Equal(t, 1, row["id"])
+	})
+
+	t.Run("role without policies is unchanged", func(t *testing.T) {
+		row := map[string]interface{}{"ssn": "123-45-6789"}
+		table.ApplyColumnPolicies("admin", row)
+		assert.Equal(t, "123-45-6789", row["ssn"])
+	})
+
+	t.Run("missing columns are not added", func(t *testing.T) {
+		row := map[string]interface{}{"id": 1}
+		table.ApplyColumnPolicies("analyst", row)
+		assert.NotContains(t, row, "email")
+	})
+
+	t.Run("protected columns", func(t *testing.T) {
+		assert.Equal(t, map[string]bool{"ssn": true, "card": true}, table.ProtectedColumns("support"))
+		assert.Nil(t, table.ProtectedColumns("admin"))
+		assert.Len(t, table.ColumnPoliciesForRole("analyst"), 1)
+	})
+}
@@ -0,0 +1,12 @@
+-- ============================================================================
+-- ROW HISTORY - Rollback
+-- ============================================================================
+
+-- Drop the shared history function (CASCADE removes the triggers on user tables)
+DROP FUNCTION IF EXISTS api.record_row_history() CASCADE;
+
+DROP TABLE IF EXISTS api.row_history;
+DROP TABLE IF EXISTS api.history_registry;
+
+-- api.idempotency_keys (064) still lives in the api schema
+REVOKE USAGE ON SCHEMA api FROM authenticated, service_role;
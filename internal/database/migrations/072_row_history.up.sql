@@ -0,0 +1,166 @@
+-- ============================================================================
+-- ROW HISTORY - Opt-in per-table change history
+-- ============================================================================
+-- Tables registered in api.history_registry get an AFTER trigger that records
+-- every INSERT, UPDATE and DELETE into api.row_history together with the
+-- acting user (from the JWT claims), a timestamp and the old/new row values.
+-- The REST API uses the history to answer as_of queries and row history lookups.
+-- ============================================================================
+
+CREATE SCHEMA IF NOT EXISTS api;
+
+-- Registry of tables with history enabled
+CREATE TABLE IF NOT EXISTS api.history_registry (
+    id SERIAL PRIMARY KEY,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    history_enabled BOOLEAN NOT NULL DEFAULT true,
+    -- Primary key columns used to identify rows in the history
+    primary_key TEXT[] NOT NULL,
+    -- Columns omitted from recorded values (e.g. secrets or large blobs)
+    excluded_columns TEXT[] NOT NULL DEFAULT '{}',
+    -- When history recording started; as_of queries before this point are rejected
+    enabled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (schema_name, table_name)
+);
+
+-- Recorded row changes
+CREATE TABLE IF NOT EXISTS api.row_history (
+    id BIGSERIAL PRIMARY KEY,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    -- Primary key values of the changed row, e.g. {"id": 42}
+    row_pk JSONB NOT NULL,
+    -- SNAPSHOT rows capture existing data at the moment history was enabled
+    operation TEXT NOT NULL CHECK (operation IN ('INSERT', 'UPDATE', 'DELETE', 'SNAPSHOT')),
+    old_data JSONB,
+    new_data JSONB,
+    changed_by UUID,
+    changed_by_role TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    txid BIGINT NOT NULL DEFAULT txid_current()
+);
+
+-- Latest version of a row at a point in time
+CREATE INDEX IF NOT EXISTS idx_row_history_row
+    ON api.row_history(schema_name, table_name, row_pk, changed_at DESC, id DESC);
+
+-- Table-wide as_of scans
+CREATE INDEX IF NOT EXISTS idx_row_history_table_changed_at
+    ON api.row_history(schema_name, table_name, changed_at);
+
+COMMENT ON TABLE api.history_registry IS 'Tables with row history recording enabled';
+COMMENT ON TABLE api.row_history IS 'Recorded INSERT/UPDATE/DELETE changes for history-enabled tables';
+COMMENT ON COLUMN api.row_history.operation IS 'INSERT, UPDATE, DELETE, or SNAPSHOT for rows that existed when history was enabled';
+
+-- ============================================================================
+-- Shared history trigger function for user tables
+-- Runs as the function owner so writers don't need access to api.row_history.
+-- ============================================================================
+CREATE OR REPLACE FUNCTION api.record_row_history()
+RETURNS TRIGGER AS $$
+DECLARE
+    registry RECORD;
+    new_record JSONB;
+    old_record JSONB;
+    pk JSONB := '{}'::jsonb;
+    old_pk JSONB := '{}'::jsonb;
+    col TEXT;
+BEGIN
+    SELECT primary_key, excluded_columns INTO registry
+    FROM api.history_registry
+    WHERE schema_name = TG_TABLE_SCHEMA AND table_name = TG_TABLE_NAME AND history_enabled = true;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP != 'DELETE' THEN
+        new_record := to_jsonb(NEW);
+    END IF;
+    IF TG_OP != 'INSERT' THEN
+        old_record := to_jsonb(OLD);
+    END IF;
+
+    -- Identify the row by its primary key
+    FOREACH col IN ARRAY registry.primary_key LOOP
+        pk := pk || jsonb_build_object(col, COALESCE(new_record, old_record) -> col);
+        IF old_record IS NOT NULL THEN
+            old_pk := old_pk || jsonb_build_object(col, old_record -> col);
+        END IF;
+    END LOOP;
+
+    FOREACH col IN ARRAY registry.excluded_columns LOOP
+        new_record := new_record - col;
+        old_record := old_record - col;
+    END LOOP;
+
+    -- Skip updates that only touched excluded columns (or nothing at all)
+    IF TG_OP = 'UPDATE' AND new_record IS NOT DISTINCT FROM old_record THEN
+        RETURN NULL;
+    END IF;
+
+    -- A primary key change ends the history of the old key
+    IF TG_OP = 'UPDATE' AND old_pk IS DISTINCT FROM pk THEN
+        INSERT INTO api.row_history (schema_name, table_name, row_pk, operation, old_data, new_data, changed_by, changed_by_role)
+        VALUES (TG_TABLE_SCHEMA, TG_TABLE_NAME, old_pk, 'DELETE', old_record, NULL, auth.current_user_id(), auth.current_user_role());
+    END IF;
+
+    INSERT INTO api.row_history (schema_name, table_name, row_pk, operation, old_data, new_data, changed_by, changed_by_role)
+    VALUES (TG_TABLE_SCHEMA, TG_TABLE_NAME, pk, TG_OP, old_record, new_record, auth.current_user_id(), auth.current_user_role());
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = api, auth, pg_temp;
+
+COMMENT ON FUNCTION api.record_row_history() IS 'Shared history trigger for history-enabled user tables. Records old/new values and the acting user into api.row_history.';
+
+-- ============================================================================
+-- Row Level Security: history is only readable by admins and the service role
+-- ============================================================================
+ALTER TABLE api.history_registry ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api.row_history ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Admins can manage history configuration" ON api.history_registry;
+CREATE POLICY "Admins can manage history configuration"
+    ON api.history_registry
+    FOR ALL
+    TO authenticated
+    USING (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    )
+    WITH CHECK (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    );
+
+DROP POLICY IF EXISTS "Admins can read row history" ON api.row_history;
+CREATE POLICY "Admins can read row history"
+    ON api.row_history
+    FOR SELECT
+    TO authenticated
+    USING (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    );
+
+COMMENT ON POLICY "Admins can manage history configuration" ON api.history_registry
+    IS 'Only admins, dashboard admins, and service role can manage row history configuration';
+COMMENT ON POLICY "Admins can read row history" ON api.row_history
+    IS 'Only admins, dashboard admins, and service role can read recorded row history';
+
+-- Grant schema usage to RLS roles for SET ROLE operations
+GRANT USAGE ON SCHEMA api TO authenticated, service_role;
+
+-- Service role: Full access for admin operations
+GRANT ALL ON api.history_registry, api.row_history TO service_role;
+GRANT ALL ON SEQUENCE api.history_registry_id_seq, api.row_history_id_seq TO service_role;
+
+-- Authenticated role: Read access, restricted to admins by the policies above
+GRANT SELECT ON api.history_registry, api.row_history TO authenticated;
@@ -0,0 +1,5 @@
+-- ============================================================================
+-- COLUMN POLICIES - Rollback
+-- ============================================================================
+
+DROP TABLE IF EXISTS api.column_policies;
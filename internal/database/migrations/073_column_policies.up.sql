@@ -0,0 +1,58 @@
+-- ============================================================================
+-- COLUMN POLICIES - Column-level permissions and data masking per role
+-- ============================================================================
+-- RLS decides which rows a role sees; column policies decide how individual
+-- columns of those rows are returned. Policies are loaded into the schema
+-- cache and applied to REST, GraphQL and realtime payloads.
+-- ============================================================================
+
+CREATE SCHEMA IF NOT EXISTS api;
+
+CREATE TABLE IF NOT EXISTS api.column_policies (
+    id SERIAL PRIMARY KEY,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    -- Application role the policy applies to (the JWT role claim, e.g. 'support')
+    role TEXT NOT NULL,
+    -- hide: omit the column, mask: ****1234, hash: SHA-256 hex digest, null: return null
+    mode TEXT NOT NULL CHECK (mode IN ('hide', 'mask', 'hash', 'null')),
+    -- Trailing characters left visible by mask
+    mask_visible INTEGER NOT NULL DEFAULT 4 CHECK (mask_visible >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (schema_name, table_name, column_name, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_column_policies_table
+    ON api.column_policies(schema_name, table_name);
+
+COMMENT ON TABLE api.column_policies IS 'Per-role column visibility and masking rules applied by the REST, GraphQL and realtime APIs';
+COMMENT ON COLUMN api.column_policies.mode IS 'hide: omit the column, mask: keep trailing characters (****1234), hash: SHA-256 hex digest, null: return null';
+
+-- Only admins and service_role can read or manage column policies
+ALTER TABLE api.column_policies ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Admins can manage column policies" ON api.column_policies;
+CREATE POLICY "Admins can manage column policies"
+    ON api.column_policies
+    FOR ALL
+    TO authenticated
+    USING (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    )
+    WITH CHECK (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    );
+
+COMMENT ON POLICY "Admins can manage column policies" ON api.column_policies
+    IS 'Only admins, dashboard admins, and service role can manage column policies';
+
+GRANT ALL ON api.column_policies TO service_role;
+GRANT ALL ON SEQUENCE api.column_policies_id_seq TO service_role;
+GRANT SELECT, INSERT, UPDATE, DELETE ON api.column_policies TO authenticated;
+GRANT USAGE ON SEQUENCE api.column_policies_id_seq TO authenticated;
@@ -0,0 +1,5 @@
+-- ============================================================================
+-- COLUMN JSON SCHEMAS - Rollback
+-- ============================================================================
+
+DROP TABLE IF EXISTS api.column_json_schemas;
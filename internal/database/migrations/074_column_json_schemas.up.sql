@@ -0,0 +1,49 @@
+-- ============================================================================
+-- COLUMN JSON SCHEMAS - Validate json/jsonb documents on write
+-- ============================================================================
+-- A JSON Schema attached to a json or jsonb column is checked by the REST and
+-- GraphQL APIs before inserts and updates. Invalid documents are rejected with
+-- a 422 that lists the violating paths.
+-- ============================================================================
+
+CREATE SCHEMA IF NOT EXISTS api;
+
+CREATE TABLE IF NOT EXISTS api.column_json_schemas (
+    id SERIAL PRIMARY KEY,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    json_schema JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (schema_name, table_name, column_name)
+);
+
+COMMENT ON TABLE api.column_json_schemas IS 'JSON Schemas that documents written to json/jsonb columns through the REST and GraphQL APIs must satisfy';
+
+-- Only admins and service_role can read or manage column JSON schemas
+ALTER TABLE api.column_json_schemas ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Admins can manage column JSON schemas" ON api.column_json_schemas;
+CREATE POLICY "Admins can manage column JSON schemas"
+    ON api.column_json_schemas
+    FOR ALL
+    TO authenticated
+    USING (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    )
+    WITH CHECK (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    );
+
+COMMENT ON POLICY "Admins can manage column JSON schemas" ON api.column_json_schemas
+    IS 'Only admins, dashboard admins, and service role can manage column JSON schemas';
+
+GRANT ALL ON api.column_json_schemas TO service_role;
+GRANT ALL ON SEQUENCE api.column_json_schemas_id_seq TO service_role;
+GRANT SELECT, INSERT, UPDATE, DELETE ON api.column_json_schemas TO authenticated;
+GRANT USAGE ON SEQUENCE api.column_json_schemas_id_seq TO authenticated;
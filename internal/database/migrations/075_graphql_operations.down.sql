@@ -0,0 +1,5 @@
+-- ============================================================================
+-- GRAPHQL OPERATIONS - Rollback
+-- ============================================================================
+
+DROP TABLE IF EXISTS api.graphql_operations;
@@ -0,0 +1,49 @@
+-- ============================================================================
+-- GRAPHQL OPERATIONS - Registered persisted GraphQL operations
+-- ============================================================================
+-- Operations are uploaded with the CLI or admin API and can be executed by
+-- sending their SHA-256 hash instead of the document. With
+-- graphql.persisted_queries.allowlist_only, they are the only operations
+-- non-admin roles can run.
+-- ============================================================================
+
+CREATE SCHEMA IF NOT EXISTS api;
+
+CREATE TABLE IF NOT EXISTS api.graphql_operations (
+    -- Lowercase hex SHA-256 digest of the document, as sent by Automatic Persisted Queries clients
+    hash TEXT PRIMARY KEY CHECK (hash ~ '^[0-9a-f]{64}$'),
+    -- Name of the first named operation of the document, if any
+    name TEXT,
+    document TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_graphql_operations_name
+    ON api.graphql_operations(name);
+
+COMMENT ON TABLE api.graphql_operations IS 'Registered GraphQL operations, executable by hash and forming the allowlist of non-admin roles';
+
+-- Only admins and service_role can read or manage registered operations
+ALTER TABLE api.graphql_operations ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Admins can manage GraphQL operations" ON api.graphql_operations;
+CREATE POLICY "Admins can manage GraphQL operations"
+    ON api.graphql_operations
+    FOR ALL
+    TO authenticated
+    USING (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    )
+    WITH CHECK (
+        auth.current_user_role() = 'service_role'
+        OR auth.current_user_role() = 'dashboard_admin'
+        OR auth.is_admin()
+    );
+
+COMMENT ON POLICY "Admins can manage GraphQL operations" ON api.graphql_operations
+    IS 'Only admins, dashboard admins, and service role can manage GraphQL operations';
+
+GRANT ALL ON api.graphql_operations TO service_role;
+GRANT SELECT, INSERT, UPDATE, DELETE ON api.graphql_operations TO authenticated;
@@ -302,6 +302,28 @@ func (c *SchemaCache) Refresh(ctx context.Context) error {
 		}
 	}
 
+	// Column policies are attached to the tables they protect
+	policies := make(map[string][]ColumnPolicy)
+	columnPolicies, err := c.inspector.GetColumnPolicies(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get column policies")
+	}
+	for _, p := range columnPolicies {
+		key := makeKey(p.Schema, p.Table)
+		policies[key] = append(policies[key], p)
+	}
+
+	// JSON Schemas are attached to the tables whose columns they validate
+	jsonSchemas := make(map[string][]ColumnJSONSchema)
+	columnJSONSchemas, err := c.inspector.GetColumnJSONSchemas(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get column JSON schemas")
+	}
+	for _, s := range columnJSONSchemas {
+		key := makeKey(s.Schema, s.Table)
+		jsonSchemas[key] = append(jsonSchemas[key], s)
+	}
+
 	// Collect all tables, views, and materialized views
 	newTables := make(map[string]*TableInfo)
 	newViews := make(map[string]*TableInfo)
@@ -320,6 +342,8 @@ func (c *SchemaCache) Refresh(ctx context.Context) error {
 		for i := range tables {
 			table := tables[i]
 			key := makeKey(table.Schema, table.Name)
+			table.ColumnPolicies = policies[key]
+			table.JSONSchemas = jsonSchemas[key]
 			newTables[key] = &table
 			allTables = append(allTables, table)
 		}
@@ -332,6 +356,8 @@ func (c *SchemaCache) Refresh(ctx context.Context) error {
 			for i := range views {
 				view := views[i]
 				key := makeKey(view.Schema, view.Name)
+				view.ColumnPolicies = policies[key]
+				view.JSONSchemas = jsonSchemas[key]
 				newViews[key] = &view
 				allViews = append(allViews, view)
 			}
@@ -345,6 +371,7 @@ func (c *SchemaCache) Refresh(ctx context.Context) error {
 			for i := range matViews {
 				matView := matViews[i]
 				key := makeKey(matView.Schema, matView.Name)
+				matView.ColumnPolicies = policies[key]
 				newMatViews[key] = &matView
 				allMatViews = append(allMatViews, matView)
 			}
@@ -375,6 +402,25 @@ func (c *SchemaCache) Refresh(ctx context.Context) error {
 	return nil
 }
 
+// ApplyColumnPolicies returns a row of schema.table as the role may see it, according to the
+// cached column policies. The input row is never modified; a masked copy is returned if needed.
+func (c *SchemaCache) ApplyColumnPolicies(ctx context.Context, schema, table, role string, row map[string]interface{}) (map[string]interface{}, error) {
+	info, exists, err := c.GetTable(ctx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || row == nil || len(info.ColumnPoliciesForRole(role)) == 0 {
+		return row, nil
+	}
+
+	masked := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		masked[k] = v
+	}
+	info.ApplyColumnPolicies(role, masked)
+	return masked, nil
+}
+
 // TableCount returns the number of cached tables
 func (c *SchemaCache) TableCount() int {
 	c.mu.RLock()
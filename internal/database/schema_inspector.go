@@ -25,6 +25,11 @@ type TableInfo struct {
 	Indexes     []IndexInfo  `json:"indexes"`
 	RLSEnabled  bool         `json:"rls_enabled"`
 
+	// ColumnPolicies restrict how columns are returned per role (loaded by the schema cache)
+	ColumnPolicies []ColumnPolicy `json:"-"`
+	// JSONSchemas validate documents written to json columns (loaded by the schema cache)
+	JSONSchemas []ColumnJSONSchema `json:"-"`
+
 	// ColumnMap provides O(1) column lookup by name (populated lazily or by BuildColumnMap)
 	ColumnMap map[string]*ColumnInfo `json:"-"`
 }
@@ -382,23 +387,26 @@ func (si *SchemaInspector) getForeignKeys(ctx context.Context, schema, table str
 		SELECT
 			tc.constraint_name,
 			kcu.column_name,
-			ccu.table_schema || '.' || ccu.table_name AS referenced_table,
-			ccu.column_name AS referenced_column,
+			ref.table_schema || '.' || ref.table_name AS referenced_table,
+			ref.column_name AS referenced_column,
 			rc.delete_rule,
 			rc.update_rule
 		FROM information_schema.table_constraints AS tc
 		JOIN information_schema.key_column_usage AS kcu
 			ON tc.constraint_name = kcu.constraint_name
 			AND tc.table_schema = kcu.table_schema
-		JOIN information_schema.constraint_column_usage AS ccu
-			ON ccu.constraint_name = tc.constraint_name
-			AND ccu.table_schema = tc.table_schema
 		JOIN information_schema.referential_constraints AS rc
 			ON rc.constraint_name = tc.constraint_name
 			AND rc.constraint_schema = tc.table_schema
+		-- Columns of composite keys are paired with the referenced columns by position
+		JOIN information_schema.key_column_usage AS ref
+			ON ref.constraint_name = rc.unique_constraint_name
+			AND ref.constraint_schema = rc.unique_constraint_schema
+			AND ref.ordinal_position = kcu.position_in_unique_constraint
 		WHERE tc.constraint_type = 'FOREIGN KEY'
 			AND tc.table_schema = $1
 			AND tc.table_name = $2
+		ORDER BY tc.constraint_name, kcu.ordinal_position
 	`
 
 	rows, err := si.conn.Query(ctx, query, schema, table)
@@ -741,23 +749,25 @@ func (si *SchemaInspector) batchGetForeignKeys(ctx context.Context, schemas []st
 			tc.table_name,
 			tc.constraint_name,
 			kcu.column_name,
-			ccu.table_schema || '.' || ccu.table_name AS referenced_table,
-			ccu.column_name AS referenced_column,
+			ref.table_schema || '.' || ref.table_name AS referenced_table,
+			ref.column_name AS referenced_column,
 			rc.delete_rule,
 			rc.update_rule
 		FROM information_schema.table_constraints AS tc
 		JOIN information_schema.key_column_usage AS kcu
 			ON tc.constraint_name = kcu.constraint_name
 			AND tc.table_schema = kcu.table_schema
-		JOIN information_schema.constraint_column_usage AS ccu
-			ON ccu.constraint_name = tc.constraint_name
-			AND ccu.table_schema = tc.table_schema
 		JOIN information_schema.referential_constraints AS rc
 			ON rc.constraint_name = tc.constraint_name
 			AND rc.constraint_schema = tc.table_schema
+		-- Columns of composite keys are paired with the referenced columns by position
+		JOIN information_schema.key_column_usage AS ref
+			ON ref.constraint_name = rc.unique_constraint_name
+			AND ref.constraint_schema = rc.unique_constraint_schema
+			AND ref.ordinal_position = kcu.position_in_unique_constraint
 		WHERE tc.constraint_type = 'FOREIGN KEY'
 			AND tc.table_schema = ANY($1)
-		ORDER BY tc.table_schema, tc.table_name, tc.constraint_name
+		ORDER BY tc.table_schema, tc.table_name, tc.constraint_name, kcu.ordinal_position
 	`
 
 	rows, err := si.conn.Query(ctx, query, schemas)
@@ -1098,7 +1108,11 @@ func (si *SchemaInspector) getFunctionParameters(ctx context.Context, schema, fu
 	query := `
 		SELECT
 			COALESCE(p.parameter_name, '') as param_name,
-			p.data_type,
+			CASE
+				WHEN p.data_type = 'USER-DEFINED' THEN p.udt_name
+				WHEN p.data_type = 'ARRAY' THEN substr(p.udt_name, 2) || '[]'
+				ELSE p.data_type
+			END as data_type,
 			p.parameter_mode,
 			COALESCE(p.parameter_default, '') != '' as has_default,
 			p.ordinal_position
@@ -1137,6 +1151,52 @@ func (si *SchemaInspector) getFunctionParameters(ctx context.Context, schema, fu
 	return params, nil
 }
 
+// EnumInfo represents a PostgreSQL enum type
+type EnumInfo struct {
+	Schema string   `json:"schema"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// GetAllEnums retrieves the enum types of the specified schemas, with their labels in sort order
+func (si *SchemaInspector) GetAllEnums(ctx context.Context, schemas ...string) ([]EnumInfo, error) {
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+
+	query := `
+		SELECT
+			n.nspname AS schema_name,
+			t.typname AS type_name,
+			array_agg(e.enumlabel ORDER BY e.enumsortorder) AS labels
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		LEFT JOIN pg_depend d ON d.objid = t.oid AND d.deptype = 'e'
+		WHERE n.nspname = ANY($1)
+			AND d.objid IS NULL  -- Exclude extension types
+		GROUP BY n.nspname, t.typname
+		ORDER BY n.nspname, t.typname
+	`
+
+	rows, err := si.conn.Query(ctx, query, schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enums: %w", err)
+	}
+	defer rows.Close()
+
+	var enums []EnumInfo
+	for rows.Next() {
+		var enum EnumInfo
+		if err := rows.Scan(&enum.Schema, &enum.Name, &enum.Values); err != nil {
+			return nil, fmt.Errorf("failed to scan enum: %w", err)
+		}
+		enums = append(enums, enum)
+	}
+
+	return enums, rows.Err()
+}
+
 // BuildRESTPath builds a REST API path for a table
 func (si *SchemaInspector) BuildRESTPath(table TableInfo) string {
 	// Convert table name to plural form (simple pluralization)
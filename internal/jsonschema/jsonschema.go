@@ -0,0 +1,564 @@
+// Package jsonschema validates JSON documents against a JSON Schema.
+//
+// It implements the validation vocabulary commonly used to describe jsonb
+// columns: type, enum, const, the numeric, string, array and object
+// keywords, allOf/anyOf/oneOf/not, and local $ref pointers into $defs or
+// definitions. Unknown keywords are ignored, as the specification requires.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRefDepth bounds $ref resolution so recursive schemas can't loop forever
+const maxRefDepth = 32
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// ValidationError describes a value that does not satisfy the schema
+type ValidationError struct {
+	Path    string `json:"path"`    // JSON Pointer to the invalid value, "" for the document root
+	Keyword string `json:"keyword"` // Schema keyword that failed, e.g. "type" or "required"
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Compile parses a JSON Schema document and checks that it can be used for validation
+func Compile(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch root.(type) {
+	case bool, map[string]interface{}:
+	default:
+		return nil, fmt.Errorf("schema must be an object or a boolean")
+	}
+
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.check(root, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// check walks the schema, compiling patterns and verifying $ref targets
+func (s *Schema) check(node interface{}, location string) error {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		if _, isBool := node.(bool); isBool {
+			return nil
+		}
+		return fmt.Errorf("%s: schema must be an object or a boolean", location)
+	}
+
+	if t, ok := obj["type"]; ok {
+		if err := checkTypeKeyword(t); err != nil {
+			return fmt.Errorf("%s/type: %w", location, err)
+		}
+	}
+	if pattern, ok := obj["pattern"].(string); ok {
+		if err := s.compilePattern(pattern); err != nil {
+			return fmt.Errorf("%s/pattern: %w", location, err)
+		}
+	}
+	if patternProps, ok := obj["patternProperties"].(map[string]interface{}); ok {
+		for pattern, sub := range patternProps {
+			if err := s.compilePattern(pattern); err != nil {
+				return fmt.Errorf("%s/patternProperties: %w", location, err)
+			}
+			if err := s.check(sub, location+"/patternProperties/"+escapePointer(pattern)); err != nil {
+				return err
+			}
+		}
+	}
+	if ref, ok := obj["$ref"].(string); ok {
+		if _, err := s.resolveRef(ref); err != nil {
+			return fmt.Errorf("%s/$ref: %w", location, err)
+		}
+	}
+
+	for _, keyword := range []string{"items", "additionalProperties", "not", "contains", "propertyNames"} {
+		if sub, ok := obj[keyword]; ok {
+			if err := s.check(sub, location+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf", "prefixItems"} {
+		if list, ok := obj[keyword]; ok {
+			subs, ok := list.([]interface{})
+			if !ok || len(subs) == 0 {
+				return fmt.Errorf("%s/%s: must be a non-empty array", location, keyword)
+			}
+			for i, sub := range subs {
+				if err := s.check(sub, fmt.Sprintf("%s/%s/%d", location, keyword, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"properties", "$defs", "definitions"} {
+		if props, ok := obj[keyword].(map[string]interface{}); ok {
+			for name, sub := range props {
+				if err := s.check(sub, location+"/"+keyword+"/"+escapePointer(name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkTypeKeyword verifies the value of a "type" keyword
+func checkTypeKeyword(t interface{}) error {
+	names := []interface{}{t}
+	if list, ok := t.([]interface{}); ok {
+		names = list
+	}
+	for _, n := range names {
+		name, ok := n.(string)
+		if !ok {
+			return fmt.Errorf("must be a string or an array of strings")
+		}
+		switch name {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return nil
+}
+
+// compilePattern compiles and caches a regular expression
+func (s *Schema) compilePattern(pattern string) error {
+	if _, ok := s.patterns[pattern]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+// resolveRef resolves a local JSON Pointer reference such as #/$defs/address
+func (s *Schema) resolveRef(ref string) (interface{}, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only local references are supported: %s", ref)
+	}
+
+	node := s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]interface{}:
+			next, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("unresolvable reference: %s", ref)
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("unresolvable reference: %s", ref)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("unresolvable reference: %s", ref)
+		}
+	}
+	return node, nil
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json) against the schema.
+// It returns every violation found, ordered by path.
+func (s *Schema) Validate(value interface{}) []ValidationError {
+	var errs []ValidationError
+	s.validate(s.root, normalize(value), "", 0, &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// validate appends the violations of value against node to errs
+func (s *Schema) validate(node interface{}, value interface{}, path string, depth int, errs *[]ValidationError) {
+	if b, ok := node.(bool); ok {
+		if !b {
+			*errs = append(*errs, ValidationError{Path: path, Keyword: "false", Message: "no value is allowed here"})
+		}
+		return
+	}
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	fail := func(keyword, format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := obj["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			fail("$ref", "schema reference depth exceeded")
+			return
+		}
+		if target, err := s.resolveRef(ref); err == nil {
+			s.validate(target, value, path, depth+1, errs)
+		}
+	}
+
+	if t, ok := obj["type"]; ok && !matchesType(t, value) {
+		fail("type", "expected %s, got %s", typeNames(t), typeOf(value))
+		// The remaining keywords describe a value of the expected type
+		return
+	}
+	if enum, ok := obj["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if equal(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "value must be one of %s", compactJSON(enum))
+		}
+	}
+	if constant, ok := obj["const"]; ok && !equal(constant, value) {
+		fail("const", "value must be %s", compactJSON(constant))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		s.validateNumber(obj, v, fail)
+	case string:
+		s.validateString(obj, v, fail)
+	case []interface{}:
+		s.validateArray(obj, v, path, depth, errs, fail)
+	case map[string]interface{}:
+		s.validateObject(obj, v, path, depth, errs, fail)
+	}
+
+	if all, ok := obj["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			s.validate(sub, value, path, depth, errs)
+		}
+	}
+	if anyOf, ok := obj["anyOf"].([]interface{}); ok {
+		if s.countMatches(anyOf, value, path, depth) == 0 {
+			fail("anyOf", "value must match at least one of the allowed schemas")
+		}
+	}
+	if oneOf, ok := obj["oneOf"].([]interface{}); ok {
+		if n := s.countMatches(oneOf, value, path, depth); n != 1 {
+			fail("oneOf", "value must match exactly one of the allowed schemas, matched %d", n)
+		}
+	}
+	if not, ok := obj["not"]; ok && s.matches(not, value, path, depth) {
+		fail("not", "value must not match the disallowed schema")
+	}
+}
+
+// matches reports whether value satisfies node
+func (s *Schema) matches(node interface{}, value interface{}, path string, depth int) bool {
+	var errs []ValidationError
+	s.validate(node, value, path, depth, &errs)
+	return len(errs) == 0
+}
+
+// countMatches returns how many of the schemas value satisfies
+func (s *Schema) countMatches(schemas []interface{}, value interface{}, path string, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		if s.matches(sub, value, path, depth) {
+			n++
+		}
+	}
+	return n
+}
+
+// validateNumber applies the numeric keywords
+func (s *Schema) validateNumber(obj map[string]interface{}, v float64, fail func(string, string, ...interface{})) {
+	if min, ok := obj["minimum"].(float64); ok && v < min {
+		fail("minimum", "must be >= %v", min)
+	}
+	if max, ok := obj["maximum"].(float64); ok && v > max {
+		fail("maximum", "must be <= %v", max)
+	}
+	if min, ok := obj["exclusiveMinimum"].(float64); ok && v <= min {
+		fail("exclusiveMinimum", "must be > %v", min)
+	}
+	if max, ok := obj["exclusiveMaximum"].(float64); ok && v >= max {
+		fail("exclusiveMaximum", "must be < %v", max)
+	}
+	if m, ok := obj["multipleOf"].(float64); ok && m > 0 {
+		q := v / m
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "must be a multiple of %v", m)
+		}
+	}
+}
+
+// validateString applies the string keywords
+func (s *Schema) validateString(obj map[string]interface{}, v string, fail func(string, string, ...interface{})) {
+	length := utf8.RuneCountInString(v)
+	if min, ok := obj["minLength"].(float64); ok && float64(length) < min {
+		fail("minLength", "must be at least %v characters long", min)
+	}
+	if max, ok := obj["maxLength"].(float64); ok && float64(length) > max {
+		fail("maxLength", "must be at most %v characters long", max)
+	}
+	if pattern, ok := obj["pattern"].(string); ok {
+		if re := s.patterns[pattern]; re != nil && !re.MatchString(v) {
+			fail("pattern", "must match pattern %q", pattern)
+		}
+	}
+}
+
+// validateArray applies the array keywords
+func (s *Schema) validateArray(obj map[string]interface{}, v []interface{}, path string, depth int, errs *[]ValidationError, fail func(string, string, ...interface{})) {
+	if min, ok := obj["minItems"].(float64); ok && float64(len(v)) < min {
+		fail("minItems", "must contain at least %v items", min)
+	}
+	if max, ok := obj["maxItems"].(float64); ok && float64(len(v)) > max {
+		fail("maxItems", "must contain at most %v items", max)
+	}
+	if unique, ok := obj["uniqueItems"].(bool); ok && unique {
+	outer:
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equal(v[i], v[j]) {
+					fail("uniqueItems", "items %d and %d are equal", i, j)
+					break outer
+				}
+			}
+		}
+	}
+
+	start := 0
+	if prefix, ok := obj["prefixItems"].([]interface{}); ok {
+		for i := 0; i < len(prefix) && i < len(v); i++ {
+			s.validate(prefix[i], v[i], fmt.Sprintf("%s/%d", path, i), depth, errs)
+		}
+		start = len(prefix)
+	}
+	if items, ok := obj["items"]; ok {
+		for i := start; i < len(v); i++ {
+			s.validate(items, v[i], fmt.Sprintf("%s/%d", path, i), depth, errs)
+		}
+	}
+	if contains, ok := obj["contains"]; ok {
+		found := false
+		for i := range v {
+			if s.matches(contains, v[i], fmt.Sprintf("%s/%d", path, i), depth) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("contains", "must contain at least one matching item")
+		}
+	}
+}
+
+// validateObject applies the object keywords
+func (s *Schema) validateObject(obj map[string]interface{}, v map[string]interface{}, path string, depth int, errs *[]ValidationError, fail func(string, string, ...interface{})) {
+	if min, ok := obj["minProperties"].(float64); ok && float64(len(v)) < min {
+		fail("minProperties", "must have at least %v properties", min)
+	}
+	if max, ok := obj["maxProperties"].(float64); ok && float64(len(v)) > max {
+		fail("maxProperties", "must have at most %v properties", max)
+	}
+	if required, ok := obj["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := v[name]; !present {
+					*errs = append(*errs, ValidationError{
+						Path:    path + "/" + escapePointer(name),
+						Keyword: "required",
+						Message: "property is required",
+					})
+				}
+			}
+		}
+	}
+
+	props, _ := obj["properties"].(map[string]interface{})
+	patternProps, _ := obj["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := obj["additionalProperties"]
+	propertyNames, hasPropertyNames := obj["propertyNames"]
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "/" + escapePointer(key)
+		if hasPropertyNames && !s.matches(propertyNames, key, childPath, depth) {
+			*errs = append(*errs, ValidationError{Path: childPath, Keyword: "propertyNames", Message: "property name is not allowed"})
+		}
+
+		matched := false
+		if sub, ok := props[key]; ok {
+			matched = true
+			s.validate(sub, v[key], childPath, depth, errs)
+		}
+		for pattern, sub := range patternProps {
+			if re := s.patterns[pattern]; re != nil && re.MatchString(key) {
+				matched = true
+				s.validate(sub, v[key], childPath, depth, errs)
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*errs = append(*errs, ValidationError{Path: childPath, Keyword: "additionalProperties", Message: "additional property is not allowed"})
+				continue
+			}
+			s.validate(additional, v[key], childPath, depth, errs)
+		}
+	}
+}
+
+// matchesType reports whether value has one of the types named by a "type" keyword
+func matchesType(t interface{}, value interface{}) bool {
+	names := []interface{}{t}
+	if list, ok := t.([]interface{}); ok {
+		names = list
+	}
+	actual := typeOf(value)
+	for _, n := range names {
+		name, _ := n.(string)
+		if name == actual {
+			return true
+		}
+		if name == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNames renders a "type" keyword for error messages
+func typeNames(t interface{}) string {
+	list, ok := t.([]interface{})
+	if !ok {
+		return fmt.Sprintf("%v", t)
+	}
+	names := make([]string, 0, len(list))
+	for _, n := range list {
+		names = append(names, fmt.Sprintf("%v", n))
+	}
+	return strings.Join(names, " or ")
+}
+
+// typeOf returns the JSON Schema type of a decoded JSON value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalize converts a Go value to the representation produced by encoding/json,
+// so documents built in code validate the same way as decoded request bodies
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, float64, string:
+		return v
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = normalize(v[i])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = normalize(item)
+		}
+		return out
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+
+	// Round-trip anything else (structs, typed maps and slices) through JSON
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return value
+	}
+	return out
+}
+
+// equal compares two decoded JSON values
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// compactJSON renders a value as JSON for error messages
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// escapePointer escapes a property name for use in a JSON Pointer
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,144 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustCompile(t *testing.T, schema string) *Schema {
+	t.Helper()
+	s, err := Compile([]byte(schema))
+	require.NoError(t, err)
+	return s
+}
+
+func decode(t *testing.T, doc string) interface{} {
+	t.Helper()
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(doc), &v))
+	return v
+}
+
+func paths(errs []ValidationError) []string {
+	out := make([]string, len(errs))
+	for i, e := range errs {
+		out[i] = e.Path + " " + e.Keyword
+	}
+	return out
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "not json", schema: `{`},
+		{name: "not an object", schema: `"string"`},
+		{name: "unknown type", schema: `{"type": "date"}`},
+		{name: "bad pattern", schema: `{"type": "string", "pattern": "("}`},
+		{name: "remote ref", schema: `{"$ref": "https://example.com/schema.json"}`},
+		{name: "missing ref", schema: `{"$ref": "#/$defs/missing"}`},
+		{name: "empty anyOf", schema: `{"anyOf": []}`},
+		{name: "nested invalid", schema: `{"properties": {"a": {"type": 5}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidate_Settings(t *testing.T) {
+	s := mustCompile(t, `{
+		"type": "object",
+		"required": ["theme"],
+		"additionalProperties": false,
+		"properties": {
+			"theme": {"enum": ["light", "dark"]},
+			"font_size": {"type": "integer", "minimum": 8, "maximum": 32},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "uniqueItems": true},
+			"address": {"$ref": "#/$defs/address"}
+		},
+		"$defs": {
+			"address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {"city": {"type": "string"}, "zip": {"type": "string", "pattern": "^[0-9]{5}$"}}
+			}
+		}
+	}`)
+
+	t.Run("valid document", func(t *testing.T) {
+		errs := s.Validate(decode(t, `{"theme": "dark", "font_size": 14, "tags": ["a", "b"], "address": {"city": "Berlin", "zip": "10115"}}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("reports every violation with its path", func(t *testing.T) {
+		errs := s.Validate(decode(t, `{"theme": "blue", "font_size": 14.5, "tags": ["a", "", "a"], "address": {"zip": "ABC"}, "extra": 1}`))
+		assert.Equal(t, []string{
+			"/address/city required",
+			"/address/zip pattern",
+			"/extra additionalProperties",
+			"/font_size type",
+			"/tags uniqueItems",
+			"/tags/1 minLength",
+			"/theme enum",
+		}, paths(errs))
+	})
+
+	t.Run("wrong root type", func(t *testing.T) {
+		errs := s.Validate(decode(t, `[1, 2]`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "", errs[0].Path)
+		assert.Equal(t, "expected object, got array", errs[0].Message)
+	})
+
+	t.Run("go values are normalized", func(t *testing.T) {
+		errs := s.Validate(map[string]interface{}{"theme": "light", "font_size": 12})
+		assert.Empty(t, errs)
+	})
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	s := mustCompile(t, `{
+		"oneOf": [
+			{"type": "string"},
+			{"type": "number", "multipleOf": 5}
+		],
+		"not": {"const": "forbidden"}
+	}`)
+
+	assert.Empty(t, s.Validate("ok"))
+	assert.Empty(t, s.Validate(float64(10)))
+	assert.Equal(t, []string{" oneOf"}, paths(s.Validate(float64(7))))
+	assert.Equal(t, []string{" not"}, paths(s.Validate("forbidden")))
+	assert.Equal(t, []string{" oneOf"}, paths(s.Validate(true)))
+}
+
+func TestValidate_BooleanSchemas(t *testing.T) {
+	assert.Empty(t, mustCompile(t, `true`).Validate(decode(t, `{"any": 1}`)))
+	assert.Len(t, mustCompile(t, `false`).Validate(nil), 1)
+
+	s := mustCompile(t, `{"properties": {"locked": false}}`)
+	assert.Equal(t, []string{"/locked false"}, paths(s.Validate(decode(t, `{"locked": 1}`))))
+}
+
+func TestValidate_PointerEscaping(t *testing.T) {
+	s := mustCompile(t, `{"additionalProperties": {"type": "string"}}`)
+	errs := s.Validate(decode(t, `{"a/b": 1, "c~d": 2}`))
+	assert.Equal(t, []string{"/a~1b type", "/c~0d type"}, paths(errs))
+}
+
+func TestValidate_RecursiveRef(t *testing.T) {
+	s := mustCompile(t, `{
+		"type": "object",
+		"properties": {"children": {"type": "array", "items": {"$ref": "#"}}, "name": {"type": "string"}}
+	}`)
+	errs := s.Validate(decode(t, `{"name": "root", "children": [{"name": "a", "children": [{"name": 1}]}]}`))
+	assert.Equal(t, []string{"/children/0/children/0/name type"}, paths(errs))
+}
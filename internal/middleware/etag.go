@@ -87,11 +87,12 @@ func ETagWithConfig(config ETagConfig) fiber.Handler {
 			return nil
 		}
 
-		// Generate ETag from body hash
-		etag := generateETag(body, config.Weak)
-
-		// Set ETag header
-		c.Set("ETag", etag)
+		// Keep an ETag set by the handler (e.g. a row version), otherwise hash the body
+		etag := string(c.Response().Header.Peek("ETag"))
+		if etag == "" {
+			etag = generateETag(body, config.Weak)
+			c.Set("ETag", etag)
+		}
 
 		// Handle conditional request if enabled
 		if config.EnableConditional {
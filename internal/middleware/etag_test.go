@@ -280,6 +280,34 @@ func TestETagMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("keeps ETag set by handler", func(t *testing.T) {
+		app := fiber.New()
+		app.Use(ETag())
+		app.Get("/row", func(c *fiber.Ctx) error {
+			c.Set("ETag", `"row-version"`)
+			return c.JSON(fiber.Map{"id": 1})
+		})
+
+		req := httptest.NewRequest("GET", "/row", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"row-version"` {
+			t.Errorf("Expected handler ETag to be kept, got %s", etag)
+		}
+
+		req2 := httptest.NewRequest("GET", "/row", nil)
+		req2.Header.Set("If-None-Match", `"row-version"`)
+		resp2, err := app.Test(req2)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp2.StatusCode != 304 {
+			t.Errorf("Expected 304 status, got %d", resp2.StatusCode)
+		}
+	})
+
 	t.Run("no ETag for error responses", func(t *testing.T) {
 		app := fiber.New()
 		app.Use(ETag())
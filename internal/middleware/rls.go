@@ -234,6 +234,29 @@ func WrapWithServiceRole(ctx context.Context, conn *database.Connection, fn func
 // This is a helper function for setting RLS context in queries
 // If a branch pool is set in context (by BranchContext middleware), it uses that pool instead
 func WrapWithRLS(ctx context.Context, conn *database.Connection, c *fiber.Ctx, fn func(tx pgx.Tx) error) error {
+	tx, err := BeginWithRLS(ctx, conn, c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Execute the wrapped function
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// BeginWithRLS starts a transaction with the RLS context of the request applied.
+// Unlike WrapWithRLS, the caller owns the transaction and must commit or roll it back.
+// This is used when rows are consumed after the handler returns (e.g. streamed responses).
+func BeginWithRLS(ctx context.Context, conn *database.Connection, c *fiber.Ctx) (pgx.Tx, error) {
 	// Check for branch pool in context (set by BranchContext middleware)
 	pool := GetBranchPool(c)
 	if pool == nil {
@@ -244,9 +267,8 @@ func WrapWithRLS(ctx context.Context, conn *database.Connection, c *fiber.Ctx, f
 	// Start transaction
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer func() { _ = tx.Rollback(ctx) }()
 
 	// Set RLS context from Fiber context
 	userID := c.Locals("rls_user_id")
@@ -288,20 +310,11 @@ func WrapWithRLS(ctx context.Context, conn *database.Connection, c *fiber.Ctx, f
 		Msg("WrapWithRLS: Retrieved RLS context from Fiber locals")
 
 	if err := SetRLSContext(ctx, tx, userIDStr, role.(string), claims); err != nil {
-		return err
-	}
-
-	// Execute the wrapped function
-	if err := fn(tx); err != nil {
-		return err
-	}
-
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		_ = tx.Rollback(ctx)
+		return nil, err
 	}
 
-	return nil
+	return tx, nil
 }
 
 // GetRLSContext extracts RLS context from Fiber context
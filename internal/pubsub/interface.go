@@ -40,3 +40,7 @@ const PresenceChannel = "fluxbase:presence"
 
 // SchemaCacheChannel is the channel used for schema cache invalidation across instances
 const SchemaCacheChannel = "fluxbase:schema_cache"
+
+// ChangesChannel is the channel used to share the database changes read from the logical
+// replication slot with all instances
+const ChangesChannel = "fluxbase:changes"
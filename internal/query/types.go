@@ -54,8 +54,9 @@ type Filter struct {
 	Column    string
 	Operator  FilterOperator
 	Value     interface{}
-	IsOr      bool // OR instead of AND
-	OrGroupID int  // Groups OR filters together (filters with same non-zero ID are ORed)
+	IsOr      bool   // OR instead of AND
+	OrGroupID int    // Groups OR filters together (filters with same non-zero ID are ORed)
+	Language  string // Text search configuration for fts, plfts and wfts (default: default_text_search_config)
 }
 
 // OrderBy represents an ORDER BY clause
@@ -66,4 +67,5 @@ type OrderBy struct {
 	NullsFirst  bool           // Deprecated: use Nulls field instead
 	VectorOp    FilterOperator // Vector operator for similarity ordering (vec_l2, vec_cos, vec_ip)
 	VectorValue interface{}    // Vector value for similarity ordering
+	Rank        bool           // Order by full-text search relevance (ts_rank_cd) instead of the column value
 }
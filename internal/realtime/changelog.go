@@ -0,0 +1,98 @@
+package realtime
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ChangeLog gives each change event an increasing position and retains the latest events, so
+// subscriptions can resume after a reconnect by replaying the events they missed.
+//
+// Positions are local to the instance: they start at a random value, so positions from another
+// instance, or from before a restart, are not found in the log and require a resync.
+type ChangeLog struct {
+	mu        sync.Mutex
+	entries   []changeLogEntry // Retained events, oldest first
+	next      uint64           // Position of the next event
+	floor     uint64           // Position before the oldest retained event
+	maxEvents int
+	retention time.Duration
+}
+
+// changeLogEntry is a retained change event
+type changeLogEntry struct {
+	event *ChangeEvent
+	at    time.Time
+}
+
+// NewChangeLog creates a change log retaining up to maxEvents events for the retention period
+func NewChangeLog(maxEvents int, retention time.Duration) *ChangeLog {
+	if maxEvents <= 0 {
+		maxEvents = 1000
+	}
+	if retention <= 0 {
+		retention = 5 * time.Minute
+	}
+
+	// Leave room below 2^53, so positions are exact as JavaScript numbers
+	start := rand.Uint64N(1<<52) + 1
+	return &ChangeLog{
+		next:      start,
+		floor:     start - 1,
+		maxEvents: maxEvents,
+		retention: retention,
+	}
+}
+
+// Append sets the position of an event and retains it
+func (l *ChangeLog) Append(event *ChangeEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Position = l.next
+	l.next++
+
+	now := time.Now()
+	l.entries = append(l.entries, changeLogEntry{event: event, at: now})
+	l.evict(now)
+}
+
+// Position returns the position of the latest event, which subscriptions resume after when
+// they haven't received an event yet
+func (l *ChangeLog) Position() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next - 1
+}
+
+// Since returns the retained events after position and the position of the latest event.
+// ok is false when events after position are no longer retained, or position is unknown.
+func (l *ChangeLog) Since(position uint64) (events []*ChangeEvent, latest uint64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evict(time.Now())
+	latest = l.next - 1
+	if position < l.floor || position > latest {
+		return nil, latest, false
+	}
+
+	// Positions of the retained events are consecutive, starting after the floor
+	for _, entry := range l.entries[position-l.floor:] {
+		events = append(events, entry.event)
+	}
+	return events, latest, true
+}
+
+// evict drops the events over the size limit or older than the retention period
+func (l *ChangeLog) evict(now time.Time) {
+	drop := 0
+	for drop < len(l.entries) && (len(l.entries)-drop > l.maxEvents || now.Sub(l.entries[drop].at) > l.retention) {
+		drop++
+	}
+	if drop > 0 {
+		l.floor = l.entries[drop-1].event.Position
+		l.entries = l.entries[drop:]
+	}
+}
@@ -0,0 +1,71 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeLog_Positions(t *testing.T) {
+	l := NewChangeLog(10, time.Minute)
+	start := l.Position()
+	assert.Less(t, start, uint64(1<<53), "positions are exact as JavaScript numbers")
+
+	first, second := &ChangeEvent{Table: "posts"}, &ChangeEvent{Table: "posts"}
+	l.Append(first)
+	l.Append(second)
+	assert.Equal(t, start+1, first.Position)
+	assert.Equal(t, start+2, second.Position)
+	assert.Equal(t, start+2, l.Position())
+
+	events, latest, ok := l.Since(start)
+	require.True(t, ok)
+	assert.Equal(t, []*ChangeEvent{first, second}, events)
+	assert.Equal(t, start+2, latest)
+
+	events, _, ok = l.Since(first.Position)
+	require.True(t, ok)
+	assert.Equal(t, []*ChangeEvent{second}, events)
+
+	events, _, ok = l.Since(second.Position)
+	assert.True(t, ok, "nothing missed")
+	assert.Empty(t, events)
+
+	_, _, ok = l.Since(start + 3)
+	assert.False(t, ok, "positions from the future are unknown")
+	_, _, ok = l.Since(0)
+	assert.False(t, ok)
+}
+
+func TestChangeLog_EvictsBySize(t *testing.T) {
+	l := NewChangeLog(2, time.Minute)
+	start := l.Position()
+	for i := 0; i < 3; i++ {
+		l.Append(&ChangeEvent{})
+	}
+
+	_, _, ok := l.Since(start)
+	assert.False(t, ok, "the first event is no longer retained")
+
+	events, _, ok := l.Since(start + 1)
+	require.True(t, ok)
+	require.Len(t, events, 2)
+	assert.Equal(t, start+2, events[0].Position)
+}
+
+func TestChangeLog_EvictsByAge(t *testing.T) {
+	l := NewChangeLog(10, 20*time.Millisecond)
+	start := l.Position()
+	l.Append(&ChangeEvent{})
+
+	time.Sleep(40 * time.Millisecond)
+	_, latest, ok := l.Since(start)
+	assert.False(t, ok)
+	assert.Equal(t, start+1, latest)
+
+	events, _, ok := l.Since(latest)
+	assert.True(t, ok, "nothing was missed since the evicted event")
+	assert.Empty(t, events)
+}
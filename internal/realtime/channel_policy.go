@@ -0,0 +1,233 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ChannelAction is what a user does on a channel
+type ChannelAction string
+
+const (
+	ChannelActionJoin ChannelAction = "join" // Receive the broadcasts and presence of a channel
+	ChannelActionSend ChannelAction = "send" // Broadcast to a channel or track presence in it
+)
+
+// ChannelPolicy restricts who can join and send on the broadcast and presence channels whose
+// names match a pattern. Access is decided by a SQL condition run as the user, or by a row
+// for the user in a membership table.
+type ChannelPolicy struct {
+	Pattern    string            // Channel name pattern, {name} matches a part without colons: room:{id}
+	Check      string            // SQL condition to join, {name} is replaced by the matched part
+	SendCheck  string            // SQL condition to send, defaults to the join condition
+	Table      string            // Membership table (schema.table) used instead of Check
+	Match      map[string]string // Membership table column -> pattern parameter
+	UserColumn string            // Membership table column holding the user ID (default: user_id)
+}
+
+// patternParam matches a parameter in a channel pattern or policy condition
+var patternParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// compiledChannelPolicy is a channel policy ready to be evaluated
+type compiledChannelPolicy struct {
+	pattern  *regexp.Regexp
+	join     policyCondition
+	send     policyCondition
+	original string
+}
+
+// policyCondition is a SQL condition with $n placeholders for the pattern parameters in params
+type policyCondition struct {
+	sql    string
+	params []string
+}
+
+// compileChannelPolicy validates a policy and turns its pattern and conditions into a regular
+// expression and parameterized SQL
+func compileChannelPolicy(policy ChannelPolicy) (*compiledChannelPolicy, error) {
+	if policy.Pattern == "" {
+		return nil, fmt.Errorf("channel policy pattern is required")
+	}
+	if (policy.Check == "") == (policy.Table == "") {
+		return nil, fmt.Errorf("channel policy %s needs either a check or a table", policy.Pattern)
+	}
+
+	// Literal parts of the pattern are matched as is, parameters match a part without colons
+	params := make(map[string]bool)
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, m := range patternParam.FindAllStringSubmatchIndex(policy.Pattern, -1) {
+		name := policy.Pattern[m[2]:m[3]]
+		if params[name] {
+			return nil, fmt.Errorf("channel policy %s repeats parameter %s", policy.Pattern, name)
+		}
+		params[name] = true
+		expr.WriteString(regexp.QuoteMeta(policy.Pattern[last:m[0]]))
+		expr.WriteString("(?P<" + name + ">[^:]+)")
+		last = m[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(policy.Pattern[last:]))
+	expr.WriteString("$")
+
+	compiled := &compiledChannelPolicy{
+		pattern:  regexp.MustCompile(expr.String()),
+		original: policy.Pattern,
+	}
+
+	joinSQL := policy.Check
+	if policy.Table != "" {
+		var err error
+		if joinSQL, err = membershipCondition(policy, params); err != nil {
+			return nil, err
+		}
+	}
+	compiled.join = parameterizeCondition(joinSQL, params)
+
+	compiled.send = compiled.join
+	if policy.SendCheck != "" {
+		compiled.send = parameterizeCondition(policy.SendCheck, params)
+	}
+	return compiled, nil
+}
+
+// membershipCondition returns the SQL condition of a membership table policy: a row with the
+// user ID and the matched parts of the channel name must exist
+func membershipCondition(policy ChannelPolicy, params map[string]bool) (string, error) {
+	if len(policy.Match) == 0 {
+		return "", fmt.Errorf("channel policy %s needs match columns for table %s", policy.Pattern, policy.Table)
+	}
+
+	schema, table, found := strings.Cut(policy.Table, ".")
+	if !found {
+		schema, table = "public", policy.Table
+	}
+
+	userColumn := policy.UserColumn
+	if userColumn == "" {
+		userColumn = "user_id"
+	}
+
+	// Sort the columns so the SQL, and the cache keys built from it, are stable
+	columns := make([]string, 0, len(policy.Match))
+	for column := range policy.Match {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]string, 0, len(columns)+1)
+	for _, column := range columns {
+		param := policy.Match[column]
+		if !params[param] {
+			return "", fmt.Errorf("channel policy %s has no parameter %s for column %s", policy.Pattern, param, column)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s::text = {%s}", pgx.Identifier{column}.Sanitize(), param))
+	}
+	conditions = append(conditions, fmt.Sprintf("%s::text = auth.uid()::text", pgx.Identifier{userColumn}.Sanitize()))
+
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)",
+		pgx.Identifier{schema, table}.Sanitize(), strings.Join(conditions, " AND ")), nil
+}
+
+// parameterizeCondition replaces the pattern parameters in a condition with $n placeholders.
+// Other braces, like in JSON or array literals, are kept.
+func parameterizeCondition(sql string, params map[string]bool) policyCondition {
+	var condition policyCondition
+	numbers := make(map[string]int)
+	condition.sql = patternParam.ReplaceAllStringFunc(sql, func(match string) string {
+		name := match[1 : len(match)-1]
+		if !params[name] {
+			return match
+		}
+		if numbers[name] == 0 {
+			condition.params = append(condition.params, name)
+			numbers[name] = len(condition.params)
+		}
+		return "$" + strconv.Itoa(numbers[name])
+	})
+	return condition
+}
+
+// ChannelAuthorizer decides who can join and send on channels according to channel policies.
+// Decisions are cached per user claims like RLS checks.
+type ChannelAuthorizer struct {
+	db       SubscriptionDB
+	policies []*compiledChannelPolicy
+	cache    *rlsCache
+}
+
+// NewChannelAuthorizer creates a channel authorizer. The first policy matching a channel
+// applies; channels no policy matches are open to all users.
+func NewChannelAuthorizer(db SubscriptionDB, policies []ChannelPolicy, cacheConfig RLSCacheConfig) (*ChannelAuthorizer, error) {
+	a := &ChannelAuthorizer{
+		db:    db,
+		cache: newRLSCacheWithConfig(cacheConfig),
+	}
+	for _, policy := range policies {
+		compiled, err := compileChannelPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		a.policies = append(a.policies, compiled)
+	}
+	return a, nil
+}
+
+// Authorize checks whether a user with the given role and claims may join or send on a channel.
+// The service role bypasses channel policies like it bypasses RLS.
+func (a *ChannelAuthorizer) Authorize(ctx context.Context, role string, claims map[string]interface{}, channel string, action ChannelAction) bool {
+	if role == "service_role" {
+		return true
+	}
+
+	for _, policy := range a.policies {
+		match := policy.pattern.FindStringSubmatch(channel)
+		if match == nil {
+			continue
+		}
+
+		condition := policy.join
+		if action == ChannelActionSend {
+			condition = policy.send
+		}
+
+		cacheKey := a.cache.generateCacheKey(string(action), channel, role, policy.original, claims)
+		if allowed, found := a.cache.get(cacheKey); found {
+			return allowed
+		}
+
+		args := make([]interface{}, len(condition.params))
+		for i, name := range condition.params {
+			args[i] = match[policy.pattern.SubexpIndex(name)]
+		}
+
+		allowed, err := a.db.CheckChannelAccess(ctx, role, claims, condition.sql, args)
+		if err != nil {
+			// Deny without caching, so the next attempt checks again
+			log.Warn().Err(err).
+				Str("channel", channel).
+				Str("policy", policy.original).
+				Str("action", string(action)).
+				Msg("Channel policy check failed, denying access")
+			return false
+		}
+
+		a.cache.set(cacheKey, allowed)
+		log.Debug().
+			Str("channel", channel).
+			Str("policy", policy.original).
+			Str("action", string(action)).
+			Bool("allowed", allowed).
+			Msg("Channel policy checked")
+		return allowed
+	}
+
+	return true
+}
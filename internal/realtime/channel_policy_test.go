@@ -0,0 +1,121 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxbase-eu/fluxbase/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileChannelPolicy(t *testing.T) {
+	policy, err := compileChannelPolicy(ChannelPolicy{
+		Pattern:   "org:{org}:room.{id}",
+		Check:     "EXISTS (SELECT 1 FROM rooms WHERE id = {id}::uuid AND org = {org} AND tags @> '{a}' AND owner = {id}::uuid)",
+		SendCheck: "{org} = auth.jwt() ->> 'org'",
+	})
+	require.NoError(t, err)
+
+	assert.True(t, policy.pattern.MatchString("org:acme:room.42"))
+	assert.False(t, policy.pattern.MatchString("org:acme:room-42"), "literal parts match as is")
+	assert.False(t, policy.pattern.MatchString("org:acme:room.42:extra"))
+	assert.False(t, policy.pattern.MatchString("org::room.42"))
+
+	assert.Equal(t, "EXISTS (SELECT 1 FROM rooms WHERE id = $1::uuid AND org = $2 AND tags @> '{a}' AND owner = $1::uuid)", policy.join.sql)
+	assert.Equal(t, []string{"id", "org"}, policy.join.params)
+	assert.Equal(t, "$1 = auth.jwt() ->> 'org'", policy.send.sql)
+	assert.Equal(t, []string{"org"}, policy.send.params)
+}
+
+func TestCompileChannelPolicy_Membership(t *testing.T) {
+	policy, err := compileChannelPolicy(ChannelPolicy{
+		Pattern: "room:{id}",
+		Table:   "chat.room_members",
+		Match:   map[string]string{"room_id": "id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `EXISTS (SELECT 1 FROM "chat"."room_members" WHERE "room_id"::text = $1 AND "user_id"::text = auth.uid()::text)`, policy.join.sql)
+	assert.Equal(t, policy.join, policy.send)
+
+	policy, err = compileChannelPolicy(ChannelPolicy{
+		Pattern:    "room:{id}",
+		Table:      "members",
+		Match:      map[string]string{"room_id": "id"},
+		UserColumn: "member",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `EXISTS (SELECT 1 FROM "public"."members" WHERE "room_id"::text = $1 AND "member"::text = auth.uid()::text)`, policy.join.sql)
+}
+
+func TestCompileChannelPolicy_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ChannelPolicy
+		errMsg string
+	}{
+		{"no pattern", ChannelPolicy{Check: "true"}, "pattern is required"},
+		{"no check or table", ChannelPolicy{Pattern: "room:{id}"}, "either a check or a table"},
+		{"check and table", ChannelPolicy{Pattern: "room:{id}", Check: "true", Table: "members"}, "either a check or a table"},
+		{"table without match", ChannelPolicy{Pattern: "room:{id}", Table: "members"}, "needs match columns"},
+		{"unknown match parameter", ChannelPolicy{Pattern: "room:{id}", Table: "members", Match: map[string]string{"room_id": "room"}}, "no parameter room"},
+		{"repeated parameter", ChannelPolicy{Pattern: "{id}:{id}", Check: "true"}, "repeats parameter id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileChannelPolicy(tt.policy)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+
+	_, err := NewChannelAuthorizer(testutil.NewMockSubscriptionDB(), []ChannelPolicy{{Pattern: "room:{id}"}}, RLSCacheConfig{})
+	assert.Error(t, err)
+}
+
+// countingChannelDB counts the channel policy conditions evaluated by the database
+type countingChannelDB struct {
+	*testutil.MockSubscriptionDB
+	calls int
+}
+
+func (db *countingChannelDB) CheckChannelAccess(ctx context.Context, role string, claims map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	db.calls++
+	return db.MockSubscriptionDB.CheckChannelAccess(ctx, role, claims, condition, args)
+}
+
+func TestChannelAuthorizer(t *testing.T) {
+	db := &countingChannelDB{MockSubscriptionDB: testutil.NewMockSubscriptionDB()}
+	db.ChannelResults["is_member($1) [general]"] = true
+	db.ChannelResults["can_post($1) [general]"] = true
+	db.ChannelResults["is_member($1) [announcements]"] = true
+
+	a, err := NewChannelAuthorizer(db, []ChannelPolicy{
+		{Pattern: "room:{id}", Check: "is_member({id})", SendCheck: "can_post({id})"},
+		{Pattern: "room:*", Check: "false"},
+	}, RLSCacheConfig{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	claims := map[string]interface{}{"sub": "user-1"}
+
+	assert.True(t, a.Authorize(ctx, "authenticated", claims, "room:general", ChannelActionJoin))
+	assert.True(t, a.Authorize(ctx, "authenticated", claims, "room:general", ChannelActionSend))
+	assert.True(t, a.Authorize(ctx, "authenticated", claims, "room:announcements", ChannelActionJoin))
+	assert.False(t, a.Authorize(ctx, "authenticated", claims, "room:announcements", ChannelActionSend), "members may read but not post")
+	assert.False(t, a.Authorize(ctx, "authenticated", claims, "room:private", ChannelActionJoin))
+	assert.Equal(t, 5, db.calls)
+
+	// Results are cached per user claims
+	assert.True(t, a.Authorize(ctx, "authenticated", claims, "room:general", ChannelActionJoin))
+	assert.Equal(t, 5, db.calls)
+	a.Authorize(ctx, "authenticated", map[string]interface{}{"sub": "user-2"}, "room:general", ChannelActionJoin)
+	assert.Equal(t, 6, db.calls)
+
+	// The first matching policy applies, channels without a policy are open
+	assert.False(t, a.Authorize(ctx, "authenticated", claims, "room:*", ChannelActionJoin))
+	assert.True(t, a.Authorize(ctx, "anon", nil, "lobby", ChannelActionSend))
+
+	// The service role bypasses channel policies
+	assert.True(t, a.Authorize(ctx, "service_role", nil, "room:private", ChannelActionSend))
+}
@@ -1,95 +1,399 @@
 package realtime
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
-// Filter represents a Supabase-compatible filter for realtime subscriptions
-// Format: column=operator.value
-// Examples:
+// Filter is a realtime subscription filter. It uses the filter grammar of the REST API, so a
+// list can subscribe with the filters it queried with. Conditions are joined with &:
 //   - created_by=eq.user123
-//   - priority=gt.5
-//   - status=in.(queued,running)
+//   - status=in.(queued,running)&priority=gt.5
+//   - or=(status.eq.open,and(priority.gte.5,assignee.is.null))
+//   - tags=cs.{urgent}&title=not.ilike.*draft*
+//   - body=fts(english).cats, location=st_dwithin.1000,{"type":"Point","coordinates":[4.9,52.4]}
 //   - data->key=eq.value (JSONB path access)
 //   - data->>key=eq.value (JSONB text access)
+//
+// A Filter is either a condition on a column, or a group of Conditions that must all match,
+// or any of them for or groups. Parameters of the query that aren't filters, like select and
+// order, are ignored.
 type Filter struct {
 	Column   string
 	Operator string
 	Value    string
+	Negate   bool   // not.<operator>
+	Language string // Text search configuration of fts, plfts and wfts
+
+	Conditions []*Filter
+	Or         bool
+}
+
+// filterColumnRegex matches filtered columns, with JSONB path operators (-> and ->>)
+var filterColumnRegex = regexp.MustCompile(`^[\w]+(?:->?>?[\w]+)*$`)
+
+// filterLanguageRegex matches text search configurations
+var filterLanguageRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// filterOperators are the supported operators. The ones mapped to true are evaluated by
+// PostgreSQL, the others in memory.
+var filterOperators = map[string]bool{
+	"eq": false, "neq": false, "gt": false, "gte": false, "lt": false, "lte": false,
+	"like": false, "ilike": false, "is": false, "isnot": false, "in": false, "nin": false,
+	"cs": false, "cd": false, "ov": false,
+	"fts": true, "plfts": true, "wfts": true,
+	"sl": true, "sr": true, "nxr": true, "nxl": true, "adj": true,
+	"st_intersects": true, "st_contains": true, "st_within": true, "st_dwithin": true,
+	"st_touches": true, "st_crosses": true, "st_overlaps": true,
 }
 
-// filterRegex matches the Supabase filter format: column=operator.value
-// Supports JSONB path operators (-> and ->>) in column names
-var filterRegex = regexp.MustCompile(`^([\w]+(?:->?>?[\w]+)*)=(eq|neq|gt|gte|lt|lte|like|ilike|is|in)\.(.+)$`)
+// nonFilterParams are the query parameters of the REST API that don't filter rows
+var nonFilterParams = map[string]bool{
+	"select": true, "order": true, "limit": true, "offset": true, "cursor": true, "cursor_column": true,
+	"count": true, "truncate": true, "as_of": true, "fts_headline": true, "fts_headline_options": true,
+	"group_by": true, "having": true, "window": true,
+}
 
-// ParseFilter parses a Supabase-compatible filter string
+// ParseFilter parses a filter string in the REST API filter grammar
 // Returns nil if filterStr is empty (no filter)
 func ParseFilter(filterStr string) (*Filter, error) {
 	if filterStr == "" {
 		return nil, nil
 	}
 
-	matches := filterRegex.FindStringSubmatch(filterStr)
-	if matches == nil || len(matches) != 4 {
+	var conditions []*Filter
+	language := ""
+	for _, param := range strings.Split(filterStr, "&") {
+		if param == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter format: %s (expected: column=operator.value)", param)
+		}
+		key, value = unescapeFilterParam(key), unescapeFilterParam(value)
+
+		switch {
+		case key == "fts_language":
+			if !filterLanguageRegex.MatchString(value) {
+				return nil, fmt.Errorf("invalid text search language: %s", value)
+			}
+			language = value
+
+		case nonFilterParams[key]:
+			continue
+
+		case key == "or" || key == "and":
+			group, err := parseFilterGroup(value, key == "or")
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, group)
+
+		case strings.Contains(key, "."):
+			// Classic format: column.operator=value
+			column, operator, _ := strings.Cut(key, ".")
+			condition, err := parseFilterCondition(column, operator+"."+value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+
+		default:
+			condition, err := parseFilterCondition(key, value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if len(conditions) == 0 {
 		return nil, fmt.Errorf("invalid filter format: %s (expected: column=operator.value)", filterStr)
 	}
 
-	return &Filter{
-		Column:   matches[1],
-		Operator: matches[2],
-		Value:    matches[3],
-	}, nil
+	filter := conditions[0]
+	if len(conditions) > 1 {
+		filter = &Filter{Conditions: conditions}
+	}
+	filter.setLanguage(language)
+	return filter, nil
+}
+
+// unescapeFilterParam decodes a URL-encoded query parameter, keeping it as is if it isn't encoded
+func unescapeFilterParam(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil && strings.ContainsAny(s, "%+") {
+		return unescaped
+	}
+	return s
+}
+
+// parseFilterGroup parses the conditions of or=(...) and and=(...), which may nest groups:
+// or=(name.eq.John,and(age.gt.30,age.lt.40))
+func parseFilterGroup(value string, or bool) (*Filter, error) {
+	if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+		return nil, fmt.Errorf("invalid filter group: %s (expected: (column.operator.value,...))", value)
+	}
+
+	items, err := splitFilterGroup(value[1 : len(value)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	group := &Filter{Or: or}
+	for _, item := range items {
+		var condition *Filter
+		switch {
+		case strings.HasPrefix(item, "or("):
+			condition, err = parseFilterGroup(item[2:], true)
+		case strings.HasPrefix(item, "and("):
+			condition, err = parseFilterGroup(item[3:], false)
+		default:
+			column, expr, ok := strings.Cut(item, ".")
+			if !ok {
+				return nil, fmt.Errorf("invalid filter format in logical group: %s", item)
+			}
+			condition, err = parseFilterCondition(column, expr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		group.Conditions = append(group.Conditions, condition)
+	}
+
+	if len(group.Conditions) == 0 {
+		return nil, fmt.Errorf("empty filter group")
+	}
+	return group, nil
+}
+
+// splitFilterGroup splits the items of a group by commas outside of parentheses, braces,
+// brackets and quotes, so in.(a,b), cs.{a,b} and GeoJSON values stay whole
+func splitFilterGroup(value string) ([]string, error) {
+	var items []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '{' || c == '[':
+			depth++
+		case c == ')' || c == '}' || c == ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in filter expression")
+			}
+		case c == ',' && depth == 0:
+			if item := strings.TrimSpace(value[start:i]); item != "" {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 || quoted {
+		return nil, fmt.Errorf("unbalanced parentheses in filter expression")
+	}
+	if item := strings.TrimSpace(value[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items, nil
 }
 
-// Matches checks if a record matches this filter
+// parseFilterCondition parses the operator.value of a condition on a column, with an optional
+// not. prefix and the text search language form fts(english).term
+func parseFilterCondition(column, expr string) (*Filter, error) {
+	if !filterColumnRegex.MatchString(column) {
+		return nil, fmt.Errorf("invalid filter column: %s", column)
+	}
+
+	f := &Filter{Column: column}
+	if rest, ok := strings.CutPrefix(expr, "not."); ok {
+		f.Negate = true
+		expr = rest
+	}
+
+	operator, value, ok := strings.Cut(expr, ".")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid filter format: %s=%s (expected: column=operator.value)", column, expr)
+	}
+	if open := strings.Index(operator, "("); open > 0 && strings.HasSuffix(operator, ")") {
+		f.Language = operator[open+1 : len(operator)-1]
+		operator = operator[:open]
+		if !isTextSearchFilterOperator(operator) || !filterLanguageRegex.MatchString(f.Language) {
+			return nil, fmt.Errorf("invalid filter operator: %s", expr[:strings.Index(expr, ".")])
+		}
+	}
+	if _, ok := filterOperators[operator]; !ok {
+		return nil, fmt.Errorf("unsupported filter operator: %s", operator)
+	}
+	if operator == "st_dwithin" {
+		if _, _, err := parseSTDWithinValue(value); err != nil {
+			return nil, err
+		}
+	}
+
+	f.Operator = operator
+	f.Value = value
+	return f, nil
+}
+
+// isTextSearchFilterOperator reports whether op is fts, plfts or wfts
+func isTextSearchFilterOperator(op string) bool {
+	return op == "fts" || op == "plfts" || op == "wfts"
+}
+
+// setLanguage sets the default language of the text search conditions that don't name one
+func (f *Filter) setLanguage(language string) {
+	for _, c := range f.Conditions {
+		c.setLanguage(language)
+	}
+	if isTextSearchFilterOperator(f.Operator) && f.Language == "" {
+		f.Language = language
+	}
+}
+
+// String formats a condition in the filter grammar, e.g. title=not.ilike.*draft*
+func (f *Filter) String() string {
+	if len(f.Conditions) > 0 {
+		parts := make([]string, len(f.Conditions))
+		for i, c := range f.Conditions {
+			if len(c.Conditions) > 0 {
+				parts[i] = c.String()
+			} else {
+				parts[i] = strings.Replace(c.String(), "=", ".", 1)
+			}
+		}
+		op := "and"
+		if f.Or {
+			op = "or"
+		}
+		return op + "(" + strings.Join(parts, ",") + ")"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(f.Column)
+	sb.WriteByte('=')
+	if f.Negate {
+		sb.WriteString("not.")
+	}
+	sb.WriteString(f.Operator)
+	if f.Language != "" {
+		sb.WriteString("(" + f.Language + ")")
+	}
+	sb.WriteByte('.')
+	sb.WriteString(f.Value)
+	return sb.String()
+}
+
+// NeedsDatabase reports whether the filter has conditions that are evaluated by PostgreSQL,
+// like full-text search, range and PostGIS operators
+func (f *Filter) NeedsDatabase() bool {
+	if f == nil {
+		return false
+	}
+	for _, c := range f.Conditions {
+		if c.NeedsDatabase() {
+			return true
+		}
+	}
+	return filterOperators[f.Operator]
+}
+
+// Matches checks if a record matches this filter in memory
 // Returns true if the filter is nil (no filtering)
-// Returns false if the column doesn't exist in the record
+// Returns false if the column doesn't exist in the record, and for conditions that need the
+// database; use evaluate to check those
 func (f *Filter) Matches(record map[string]interface{}) bool {
 	if f == nil {
 		return true // No filter means match all
 	}
+	return f.evaluate(record, nil)
+}
+
+// evaluate checks if a record matches this filter. Conditions that can't be checked in memory
+// are passed to dbMatch, after the other conditions of their group didn't decide the result.
+func (f *Filter) evaluate(record map[string]interface{}, dbMatch func(condition *Filter) bool) bool {
+	if len(f.Conditions) > 0 {
+		for _, last := range []bool{false, true} {
+			for _, c := range f.Conditions {
+				if c.NeedsDatabase() == last && c.evaluate(record, dbMatch) == f.Or {
+					return f.Or
+				}
+			}
+		}
+		return !f.Or
+	}
 
 	recordValue, exists := f.getNestedValue(record)
 	if !exists {
 		return false
 	}
 
+	matched, decided := f.matchValue(recordValue)
+	if !decided {
+		// The database applies the negation, so NULLs don't match either way
+		return dbMatch != nil && dbMatch(f)
+	}
+	if f.Negate {
+		return !matched
+	}
+	return matched
+}
+
+// matchValue checks a column value against the condition, ignoring Negate. decided is false
+// when the condition needs the database.
+func (f *Filter) matchValue(recordValue interface{}) (matched, decided bool) {
 	switch f.Operator {
 	case "eq":
-		return f.matchesEquality(recordValue, f.Value, true)
+		return f.matchesEquality(recordValue, f.Value, true), true
 
 	case "neq":
-		return f.matchesEquality(recordValue, f.Value, false)
+		return f.matchesEquality(recordValue, f.Value, false), true
 
 	case "gt":
-		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp > 0 })
+		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp > 0 }), true
 
 	case "gte":
-		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp >= 0 })
+		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp >= 0 }), true
 
 	case "lt":
-		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp < 0 })
+		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp < 0 }), true
 
 	case "lte":
-		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp <= 0 })
+		return f.matchesNumericComparison(recordValue, f.Value, func(cmp int) bool { return cmp <= 0 }), true
 
 	case "is":
-		return f.matchesIs(recordValue, f.Value)
+		return f.matchesIs(recordValue, f.Value), true
+
+	case "isnot":
+		return !f.matchesIs(recordValue, f.Value), true
 
 	case "in":
-		return f.matchesIn(recordValue, f.Value)
+		return f.matchesIn(recordValue, f.Value), true
+
+	case "nin":
+		return !f.matchesIn(recordValue, f.Value), true
 
 	case "like":
-		return f.matchesPattern(recordValue, f.Value, false)
+		return f.matchesPattern(recordValue, f.Value, false), true
 
 	case "ilike":
-		return f.matchesPattern(recordValue, f.Value, true)
+		return f.matchesPattern(recordValue, f.Value, true), true
+
+	case "cs", "cd", "ov":
+		return f.matchesContainment(recordValue)
 
 	default:
-		return false
+		return false, !filterOperators[f.Operator]
 	}
 }
 
@@ -203,10 +507,10 @@ func (f *Filter) matchesIs(recordValue interface{}, filterValue string) bool {
 }
 
 // matchesIn checks if record value is in the list
-// Format: (value1,value2,value3)
+// Format: (value1,value2,value3), values may be quoted
 func (f *Filter) matchesIn(recordValue interface{}, filterValue string) bool {
-	// Remove parentheses and split by comma
-	listStr := strings.Trim(filterValue, "()")
+	// Remove parentheses or brackets and split by comma
+	listStr := strings.Trim(filterValue, "()[]")
 	if listStr == "" {
 		return false
 	}
@@ -215,7 +519,7 @@ func (f *Filter) matchesIn(recordValue interface{}, filterValue string) bool {
 	recordStr := fmt.Sprint(recordValue)
 
 	for _, v := range values {
-		if strings.TrimSpace(v) == recordStr {
+		if strings.Trim(strings.TrimSpace(v), "\"'") == recordStr {
 			return true
 		}
 	}
@@ -223,6 +527,126 @@ func (f *Filter) matchesIn(recordValue interface{}, filterValue string) bool {
 	return false
 }
 
+// matchesContainment handles cs (contains), cd (contained by) and ov (overlaps) on arrays and
+// JSONB values. Values of other types, like ranges, are left to the database.
+// Filter values are array literals ({a,b}), JSON or lists ((a,b)).
+func (f *Filter) matchesContainment(recordValue interface{}) (matched, decided bool) {
+	var filterValue interface{}
+	switch recordValue.(type) {
+	case nil:
+		return false, true
+
+	case []interface{}:
+		switch {
+		case strings.HasPrefix(f.Value, "{"):
+			elements, ok := parseTextArray(f.Value)
+			if !ok {
+				return false, true
+			}
+			filterValue = textArrayToJSON(elements)
+		case strings.HasPrefix(f.Value, "("):
+			var list []interface{}
+			for _, v := range strings.Split(strings.Trim(f.Value, "()"), ",") {
+				list = append(list, strings.Trim(strings.TrimSpace(v), "\"'"))
+			}
+			filterValue = list
+		default:
+			if err := json.Unmarshal([]byte(f.Value), &filterValue); err != nil {
+				return false, true
+			}
+		}
+
+	case map[string]interface{}:
+		if err := json.Unmarshal([]byte(f.Value), &filterValue); err != nil {
+			return false, true
+		}
+
+	default:
+		return false, false
+	}
+
+	switch f.Operator {
+	case "cs":
+		return jsonContains(recordValue, filterValue), true
+	case "cd":
+		return jsonContains(filterValue, recordValue), true
+	}
+
+	// ov: the arrays have an element in common
+	recordArray, ok := recordValue.([]interface{})
+	filterArray, ok2 := filterValue.([]interface{})
+	if !ok || !ok2 {
+		return false, true
+	}
+	for _, r := range recordArray {
+		for _, v := range filterArray {
+			if jsonContains(r, v) && jsonContains(v, r) {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// textArrayToJSON converts the elements of a parsed array literal to strings
+func textArrayToJSON(elements []interface{}) []interface{} {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		switch e := element.(type) {
+		case []interface{}:
+			values[i] = textArrayToJSON(e)
+		case *string:
+			values[i] = *e
+		}
+	}
+	return values
+}
+
+// jsonContains reports whether container contains contained the way the jsonb @> operator
+// does: objects contain a subset of their keys, arrays a subset of their elements. Scalars
+// are compared by their text, so 5 matches "5" from an array literal.
+func jsonContains(container, contained interface{}) bool {
+	switch c := contained.(type) {
+	case map[string]interface{}:
+		m, ok := container.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range c {
+			if v, ok := m[key]; !ok || !jsonContains(v, value) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		a, ok := container.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range c {
+			found := false
+			for _, v := range a {
+				if jsonContains(v, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+
+	default:
+		switch container.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+		return fmt.Sprint(container) == fmt.Sprint(contained)
+	}
+}
+
 // matchesPattern matches LIKE/ILIKE patterns
 // Supabase uses * as wildcard (converted to SQL %)
 func (f *Filter) matchesPattern(recordValue interface{}, pattern string, caseInsensitive bool) bool {
@@ -297,3 +721,149 @@ func compareNumeric(recordValue interface{}, filterValue string) int {
 	}
 	return 0
 }
+
+// textSearchFunctions are the tsquery functions of the text search operators
+var textSearchFunctions = map[string]string{
+	"fts":   "plainto_tsquery",
+	"plfts": "phraseto_tsquery",
+	"wfts":  "websearch_to_tsquery",
+}
+
+// sqlOperators are the SQL operators of the range and containment filter operators
+var sqlOperators = map[string]string{
+	"cs":  "@>",
+	"cd":  "<@",
+	"ov":  "&&",
+	"sl":  "<<",
+	"sr":  ">>",
+	"nxr": "&<",
+	"nxl": "&>",
+	"adj": "-|-",
+}
+
+// spatialFunctions are the PostGIS functions of the spatial filter operators
+var spatialFunctions = map[string]string{
+	"st_intersects": "ST_Intersects",
+	"st_contains":   "ST_Contains",
+	"st_within":     "ST_Within",
+	"st_touches":    "ST_Touches",
+	"st_crosses":    "ST_Crosses",
+	"st_overlaps":   "ST_Overlaps",
+}
+
+// conditionSQL returns the SQL of a condition for the database to evaluate, with its arguments
+// numbered from argN. It is the same SQL the REST API uses for the operator.
+func (f *Filter) conditionSQL(argN int) (string, []interface{}, error) {
+	column := filterColumnSQL(f.Column)
+
+	var sql string
+	var args []interface{}
+	switch {
+	case textSearchFunctions[f.Operator] != "":
+		fn := textSearchFunctions[f.Operator]
+		if f.Language == "" {
+			sql = fmt.Sprintf("%s @@ %s($%d)", column, fn, argN)
+			args = []interface{}{f.Value}
+		} else {
+			sql = fmt.Sprintf("%s @@ %s($%d::regconfig, $%d)", column, fn, argN, argN+1)
+			args = []interface{}{f.Language, f.Value}
+		}
+
+	case sqlOperators[f.Operator] != "":
+		sql = fmt.Sprintf("%s %s $%d", column, sqlOperators[f.Operator], argN)
+		args = []interface{}{f.Value}
+
+	case spatialFunctions[f.Operator] != "":
+		sql = fmt.Sprintf("%s(%s, ST_GeomFromGeoJSON($%d))", spatialFunctions[f.Operator], column, argN)
+		args = []interface{}{f.Value}
+
+	case f.Operator == "st_dwithin":
+		distance, geometry, err := parseSTDWithinValue(f.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		sql = fmt.Sprintf("ST_DWithin(%s, ST_GeomFromGeoJSON($%d), $%d)", column, argN, argN+1)
+		args = []interface{}{geometry, distance}
+
+	default:
+		return "", nil, fmt.Errorf("filter operator %s is not evaluated by the database", f.Operator)
+	}
+
+	if f.Negate {
+		sql = "NOT (" + sql + ")"
+	}
+	return sql, args, nil
+}
+
+// rootColumn returns the table column of the filtered column, data for data->key
+func (f *Filter) rootColumn() string {
+	column, _, _ := strings.Cut(f.Column, "->")
+	return column
+}
+
+// filterColumnSQL quotes a filtered column, with its JSONB path: data->'key'->>'name'
+func filterColumnSQL(column string) string {
+	var sb strings.Builder
+	remaining := column
+	op := ""
+	for {
+		idx := strings.Index(remaining, "->")
+		key := remaining
+		if idx >= 0 {
+			key = remaining[:idx]
+		}
+
+		switch {
+		case op == "":
+			sb.WriteString(pgx.Identifier{key}.Sanitize())
+		case isArrayIndex(key):
+			sb.WriteString(op + key)
+		default:
+			sb.WriteString(op + "'" + strings.ReplaceAll(key, "'", "''") + "'")
+		}
+
+		if idx < 0 {
+			return sb.String()
+		}
+		remaining = remaining[idx+2:]
+		op = "->"
+		if strings.HasPrefix(remaining, ">") {
+			remaining = remaining[1:]
+			op = "->>"
+		}
+	}
+}
+
+// isArrayIndex reports whether a JSONB path key is an array index
+func isArrayIndex(key string) bool {
+	_, err := strconv.Atoi(key)
+	return err == nil
+}
+
+// parseSTDWithinValue parses a compound value for ST_DWithin operator
+// Format: distance,{geojson} (e.g., "1000,{"type":"Point","coordinates":[-122.4,37.8]}")
+// Returns the distance (float64) and the GeoJSON geometry (string)
+func parseSTDWithinValue(value string) (float64, string, error) {
+	distanceStr, geometry, ok := strings.Cut(value, ",")
+	if !ok {
+		return 0, "", fmt.Errorf("st_dwithin value must be in format: distance,{geojson}")
+	}
+	distanceStr = strings.TrimSpace(distanceStr)
+	geometry = strings.TrimSpace(geometry)
+
+	distance, err := strconv.ParseFloat(distanceStr, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid distance value: %w", err)
+	}
+
+	if distance < 0 {
+		return 0, "", fmt.Errorf("distance cannot be negative")
+	}
+
+	// Basic validation that geometry looks like JSON
+	if !strings.HasPrefix(geometry, "{") || !strings.HasSuffix(geometry, "}") {
+		return 0, "", fmt.Errorf("geometry must be a valid GeoJSON object")
+	}
+
+	return distance, geometry, nil
+}
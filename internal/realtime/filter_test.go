@@ -2,6 +2,9 @@ package realtime
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseFilter(t *testing.T) {
@@ -812,3 +815,229 @@ func TestGetNestedValue(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFilter_Compound(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Filter
+	}{
+		{
+			name:  "conditions are anded",
+			input: "status=eq.open&priority=gte.5",
+			want: &Filter{Conditions: []*Filter{
+				{Column: "status", Operator: "eq", Value: "open"},
+				{Column: "priority", Operator: "gte", Value: "5"},
+			}},
+		},
+		{
+			name:  "or group with nested and",
+			input: "or=(status.eq.open,and(priority.gt.5,assignee.is.null))",
+			want: &Filter{Or: true, Conditions: []*Filter{
+				{Column: "status", Operator: "eq", Value: "open"},
+				{Conditions: []*Filter{
+					{Column: "priority", Operator: "gt", Value: "5"},
+					{Column: "assignee", Operator: "is", Value: "null"},
+				}},
+			}},
+		},
+		{
+			name:  "values with commas stay whole",
+			input: "or=(tags.cs.{a,b},status.in.(x,y))",
+			want: &Filter{Or: true, Conditions: []*Filter{
+				{Column: "tags", Operator: "cs", Value: "{a,b}"},
+				{Column: "status", Operator: "in", Value: "(x,y)"},
+			}},
+		},
+		{
+			name:  "negation",
+			input: "title=not.ilike.*draft*",
+			want:  &Filter{Column: "title", Operator: "ilike", Value: "*draft*", Negate: true},
+		},
+		{
+			name:  "classic format",
+			input: "priority.gt=5",
+			want:  &Filter{Column: "priority", Operator: "gt", Value: "5"},
+		},
+		{
+			name:  "text search language",
+			input: "title=fts(german).katze&body=wfts.cats&fts_language=english",
+			want: &Filter{Conditions: []*Filter{
+				{Column: "title", Operator: "fts", Value: "katze", Language: "german"},
+				{Column: "body", Operator: "wfts", Value: "cats", Language: "english"},
+			}},
+		},
+		{
+			name:  "non-filter parameters of the list query are ignored",
+			input: "select=id,title&status=eq.open&order=created_at.desc&limit=20",
+			want:  &Filter{Column: "status", Operator: "eq", Value: "open"},
+		},
+		{
+			name:  "URL-encoded",
+			input: "title=eq.hello%20world&body=plfts.fat+cats",
+			want: &Filter{Conditions: []*Filter{
+				{Column: "title", Operator: "eq", Value: "hello world"},
+				{Column: "body", Operator: "plfts", Value: "fat cats"},
+			}},
+		},
+		{
+			name:  "st_dwithin",
+			input: `location=st_dwithin.1000,{"type":"Point","coordinates":[4.9,52.4]}`,
+			want:  &Filter{Column: "location", Operator: "st_dwithin", Value: `1000,{"type":"Point","coordinates":[4.9,52.4]}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFilter_CompoundErrors(t *testing.T) {
+	for _, input := range []string{
+		"or=(status.eq.open",
+		"or=status.eq.open",
+		"or=()",
+		"or=(status)",
+		"and=(status.eq.open,or(priority.gt.5)",
+		"title=fts(bad-lang).cats",
+		"title=eq(english).cats",
+		"title=vec_l2.[1,2]",
+		"location=st_dwithin.far,{}",
+		"title;drop=eq.x",
+		"select=id",
+	} {
+		_, err := ParseFilter(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFilter_Matches_Compound(t *testing.T) {
+	record := map[string]interface{}{
+		"status":   "open",
+		"priority": float64(7),
+		"assignee": nil,
+		"tags":     []interface{}{"urgent", "backend"},
+		"meta":     map[string]interface{}{"source": "email", "labels": []interface{}{"a", "b"}},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"status=eq.open&priority=gt.5", true},
+		{"status=eq.open&priority=gt.10", false},
+		{"or=(status.eq.closed,priority.gt.5)", true},
+		{"or=(status.eq.closed,and(priority.gt.5,assignee.is.null))", true},
+		{"or=(status.eq.closed,and(priority.gt.5,assignee.isnot.null))", false},
+		{"status=not.eq.open", false},
+		{"status=not.in.(closed,archived)", true},
+		{"status=nin.(open)", false},
+		{`status=in.("open",closed)`, true},
+		{"tags=cs.{urgent}", true},
+		{"tags=cs.{urgent,frontend}", false},
+		{`tags=cs.["backend"]`, true},
+		{"tags=cd.{urgent,backend,frontend}", true},
+		{"tags=cd.{urgent}", false},
+		{"tags=ov.(frontend,backend)", true},
+		{"tags=ov.{frontend}", false},
+		{`meta=cs.{"source":"email"}`, true},
+		{`meta=cs.{"labels":["b"]}`, true},
+		{`meta=cs.{"source":"web"}`, false},
+		{"missing=not.eq.x", false},
+		{"assignee=cs.{a}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := ParseFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter.Matches(record))
+		})
+	}
+}
+
+func TestFilter_Evaluate_Database(t *testing.T) {
+	record := map[string]interface{}{"status": "open", "body": "about cats", "during": "[1,5)"}
+
+	var asked []string
+	dbMatch := func(condition *Filter) bool {
+		asked = append(asked, condition.String())
+		return condition.Value == "cats"
+	}
+
+	filter, err := ParseFilter("body=fts.cats&status=eq.open")
+	require.NoError(t, err)
+	assert.True(t, filter.NeedsDatabase())
+	assert.True(t, filter.evaluate(record, dbMatch))
+	assert.Equal(t, []string{"body=fts.cats"}, asked)
+	assert.False(t, filter.Matches(record), "without a database the condition doesn't match")
+
+	// In-memory conditions decide without the database
+	asked = nil
+	filter, _ = ParseFilter("or=(body.fts.dogs,status.eq.open)")
+	assert.True(t, filter.evaluate(record, dbMatch))
+	assert.Empty(t, asked)
+
+	// Containment on values that aren't arrays or JSON, like ranges, needs the database
+	filter, _ = ParseFilter("during=cs.3")
+	assert.False(t, filter.NeedsDatabase())
+	assert.False(t, filter.evaluate(record, dbMatch))
+	assert.Equal(t, []string{"during=cs.3"}, asked)
+
+	filter, _ = ParseFilter("status=eq.open")
+	assert.False(t, filter.NeedsDatabase())
+}
+
+func TestFilter_ConditionSQL(t *testing.T) {
+	tests := []struct {
+		filter   string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"body=fts.cats", `"body" @@ plainto_tsquery($2)`, []interface{}{"cats"}},
+		{"body=plfts(english).fat cats", `"body" @@ phraseto_tsquery($2::regconfig, $3)`, []interface{}{"english", "fat cats"}},
+		{"body=not.wfts.cats", `NOT ("body" @@ websearch_to_tsquery($2))`, []interface{}{"cats"}},
+		{"during=adj.[5,10)", `"during" -|- $2`, []interface{}{"[5,10)"}},
+		{"during=nxr.[5,10)", `"during" &< $2`, []interface{}{"[5,10)"}},
+		{"during=cs.3", `"during" @> $2`, []interface{}{"3"}},
+		{`area=st_intersects.{"type":"Point","coordinates":[1,2]}`, `ST_Intersects("area", ST_GeomFromGeoJSON($2))`, []interface{}{`{"type":"Point","coordinates":[1,2]}`}},
+		{`location=st_dwithin.1000,{"type":"Point","coordinates":[1,2]}`, `ST_DWithin("location", ST_GeomFromGeoJSON($2), $3)`, []interface{}{`{"type":"Point","coordinates":[1,2]}`, float64(1000)}},
+		{"doc->meta->>summary=fts.cats", `"doc"->'meta'->>'summary' @@ plainto_tsquery($2)`, []interface{}{"cats"}},
+		{"doc->items->0->>title=fts.cats", `"doc"->'items'->0->>'title' @@ plainto_tsquery($2)`, []interface{}{"cats"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := ParseFilter(tt.filter)
+			require.NoError(t, err)
+			sql, args, err := filter.conditionSQL(2)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+
+	_, _, err := (&Filter{Column: "status", Operator: "eq", Value: "open"}).conditionSQL(2)
+	assert.Error(t, err)
+}
+
+func TestFilter_String(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"title=not.ilike.*draft*", "title=not.ilike.*draft*"},
+		{"body=fts(english).cats", "body=fts(english).cats"},
+		{"or=(status.eq.open,and(priority.gt.5,assignee.is.null))", "or(status.eq.open,and(priority.gt.5,assignee.is.null))"},
+	}
+
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.input)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, filter.String())
+	}
+}
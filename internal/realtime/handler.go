@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -38,7 +39,8 @@ type ClientMessage struct {
 	Event          string          `json:"event,omitempty"` // INSERT, UPDATE, DELETE, or *
 	Schema         string          `json:"schema,omitempty"`
 	Table          string          `json:"table,omitempty"`
-	Filter         string          `json:"filter,omitempty"` // Supabase-compatible filter: column=operator.value
+	Filter         string          `json:"filter,omitempty"` // Filter in the REST API filter grammar: column=operator.value&or=(...)
+	Since          *uint64         `json:"since,omitempty"`  // Change position to resume the subscription after
 	Payload        json.RawMessage `json:"payload,omitempty"`
 	Config         json.RawMessage `json:"config,omitempty"` // Raw config - can be PostgresChangesConfig or LogSubscriptionConfig
 	SubscriptionID string          `json:"subscription_id,omitempty"`
@@ -54,10 +56,11 @@ type LogSubscriptionConfig struct {
 
 // PostgresChangesConfig represents the config object in postgres_changes subscriptions
 type PostgresChangesConfig struct {
-	Event  string `json:"event"`            // INSERT, UPDATE, DELETE, or *
-	Schema string `json:"schema"`           // Database schema
-	Table  string `json:"table"`            // Table name
-	Filter string `json:"filter,omitempty"` // Optional filter: column=operator.value
+	Event  string  `json:"event"`            // INSERT, UPDATE, DELETE, or *
+	Schema string  `json:"schema"`           // Database schema
+	Table  string  `json:"table"`            // Table name
+	Filter string  `json:"filter,omitempty"` // Optional filter in the REST API filter grammar
+	Since  *uint64 `json:"since,omitempty"`  // Optional change position to resume after, replaying the missed events
 }
 
 // ServerMessage represents a message to the client
@@ -84,10 +87,11 @@ type TokenClaims struct {
 
 // RealtimeHandler handles WebSocket connections
 type RealtimeHandler struct {
-	manager         *Manager
-	authService     AuthService
-	subManager      *SubscriptionManager
-	presenceManager *PresenceManager
+	manager           *Manager
+	authService       AuthService
+	subManager        *SubscriptionManager
+	presenceManager   *PresenceManager
+	channelAuthorizer *ChannelAuthorizer // Channel policies for broadcast and presence, nil allows all channels
 }
 
 // NewRealtimeHandler creates a new realtime handler
@@ -100,6 +104,53 @@ func NewRealtimeHandler(manager *Manager, authService AuthService, subManager *S
 	}
 }
 
+// SetPresencePubSub shares presence with the other instances through pub/sub, so clients see
+// the presences tracked on every instance
+func (h *RealtimeHandler) SetPresencePubSub(ps pubsub.PubSub, heartbeatInterval time.Duration) {
+	h.presenceManager.SetPubSub(h.manager.ctx, ps, heartbeatInterval, func(event, channel string, info *PresenceInfo) {
+		switch event {
+		case "join":
+			h.notifyPresenceJoin(channel, info)
+		case "leave":
+			h.notifyPresenceLeave(channel, info)
+		default:
+			h.notifyPresenceSync(channel)
+		}
+	})
+}
+
+// SetChannelAuthorizer sets the channel policies checked when joining and sending on broadcast
+// and presence channels
+func (h *RealtimeHandler) SetChannelAuthorizer(authorizer *ChannelAuthorizer) {
+	h.channelAuthorizer = authorizer
+}
+
+// AuthorizeChannel checks the channel policies for a user joining or sending on a channel
+func (h *RealtimeHandler) AuthorizeChannel(ctx context.Context, role string, claims map[string]interface{}, channel string, action ChannelAction) bool {
+	if h.channelAuthorizer == nil {
+		return true
+	}
+	return h.channelAuthorizer.Authorize(ctx, role, claims, channel, action)
+}
+
+// authorizeChannel checks the channel policies for a connection, sending an error when denied
+func (h *RealtimeHandler) authorizeChannel(conn *Connection, channel string, action ChannelAction) bool {
+	if h.AuthorizeChannel(context.Background(), conn.Role, conn.Claims, channel, action) {
+		return true
+	}
+
+	errMsg := "not allowed to join channel"
+	if action == ChannelActionSend {
+		errMsg = "not allowed to send to channel"
+	}
+	_ = conn.SendMessage(ServerMessage{
+		Type:    MessageTypeError,
+		Channel: channel,
+		Error:   errMsg,
+	})
+	return false
+}
+
 // HandleWebSocket handles WebSocket upgrade and communication
 func (h *RealtimeHandler) HandleWebSocket(c *fiber.Ctx) error {
 	// Check if WebSocket upgrade
@@ -275,6 +326,7 @@ func (h *RealtimeHandler) handleMessage(conn *Connection, msg ClientMessage) {
 
 		// Extract subscription details from either direct fields or config object
 		var event, schema, table, filter string
+		var since *uint64
 
 		if len(msg.Config) > 0 {
 			// New format: { type: "subscribe", channel: "...", config: { event, schema, table, filter } }
@@ -284,6 +336,7 @@ func (h *RealtimeHandler) handleMessage(conn *Connection, msg ClientMessage) {
 				schema = config.Schema
 				table = config.Table
 				filter = config.Filter
+				since = config.Since
 			}
 		}
 		// Fall back to legacy format fields if config wasn't parsed
@@ -293,6 +346,7 @@ func (h *RealtimeHandler) handleMessage(conn *Connection, msg ClientMessage) {
 			schema = msg.Schema
 			table = msg.Table
 			filter = msg.Filter
+			since = msg.Since
 		}
 
 		// Validate table is provided
@@ -327,19 +381,38 @@ func (h *RealtimeHandler) handleMessage(conn *Connection, msg ClientMessage) {
 		role := conn.Role
 		claims := conn.Claims
 
-		// Create RLS-aware subscription
+		// Create RLS-aware subscription, resuming after a position if given
 		subID := uuid.New().String()
-		_, err := h.subManager.CreateSubscription(
-			subID,
-			conn.ID,
-			*conn.UserID,
-			role,
-			claims,
-			schema,
-			table,
-			event,
-			filter,
-		)
+		var sub *Subscription
+		var replay *Replay
+		var err error
+		if since != nil {
+			sub, replay, err = h.subManager.ResumeSubscription(
+				context.Background(),
+				*since,
+				subID,
+				conn.ID,
+				*conn.UserID,
+				role,
+				claims,
+				schema,
+				table,
+				event,
+				filter,
+			)
+		} else {
+			_, err = h.subManager.CreateSubscription(
+				subID,
+				conn.ID,
+				*conn.UserID,
+				role,
+				claims,
+				schema,
+				table,
+				event,
+				filter,
+			)
+		}
 
 		if err != nil {
 			_ = conn.SendMessage(ServerMessage{
@@ -360,11 +433,38 @@ func (h *RealtimeHandler) handleMessage(conn *Connection, msg ClientMessage) {
 		if filter != "" {
 			ackPayload["filter"] = filter
 		}
+		if position := h.subManager.ChangePosition(); position != 0 {
+			ackPayload["position"] = position
+		}
 
+		if replay == nil {
+			_ = conn.SendMessage(ServerMessage{
+				Type:    MessageTypeAck,
+				Payload: ackPayload,
+			})
+			return
+		}
+
+		// Missed events that don't fit in the send queue can't be replayed either
+		if stats := conn.GetQueueStats(); stats.QueueCapacity > 0 && len(replay.Events) >= stats.QueueCapacity-stats.QueueLength {
+			replay.ResyncRequired = true
+			replay.Events = nil
+		}
+		ackPayload["position"] = replay.Position
+		ackPayload["resync_required"] = replay.ResyncRequired
+		ackPayload["replayed"] = len(replay.Events)
+
+		send := func(e *ChangeEvent) {
+			_ = conn.SendMessage(ServerMessage{Type: MessageTypeChange, Payload: e})
+		}
 		_ = conn.SendMessage(ServerMessage{
 			Type:    MessageTypeAck,
 			Payload: ackPayload,
 		})
+		for _, e := range replay.Events {
+			send(e)
+		}
+		h.subManager.FinishReplay(sub, replay, send)
 
 	case MessageTypeUnsubscribe:
 		// Handle unsubscribe with subscription_id
@@ -499,10 +599,16 @@ func (h *RealtimeHandler) handleBroadcast(conn *Connection, msg ClientMessage) {
 		return
 	}
 
-	// Subscribe connection to channel if not already subscribed
+	// Subscribe connection to channel if not already subscribed and allowed
 	if !conn.IsSubscribed(msg.Channel) {
+		if !h.authorizeChannel(conn, msg.Channel, ChannelActionJoin) {
+			return
+		}
 		conn.Subscribe(msg.Channel)
 	}
+	if !h.authorizeChannel(conn, msg.Channel, ChannelActionSend) {
+		return
+	}
 
 	// Build broadcast payload
 	broadcastPayload := map[string]interface{}{
@@ -545,8 +651,11 @@ func (h *RealtimeHandler) handlePresence(conn *Connection, msg ClientMessage) {
 		return
 	}
 
-	// Subscribe connection to channel if not already subscribed
+	// Subscribe connection to channel if not already subscribed and allowed
 	if !conn.IsSubscribed(msg.Channel) {
+		if !h.authorizeChannel(conn, msg.Channel, ChannelActionJoin) {
+			return
+		}
 		conn.Subscribe(msg.Channel)
 	}
 
@@ -578,6 +687,25 @@ func (h *RealtimeHandler) handlePresence(conn *Connection, msg ClientMessage) {
 			return
 		}
 
+		if !h.authorizeChannel(conn, msg.Channel, ChannelActionSend) {
+			return
+		}
+
+		// Presences are shared with the other instances in NOTIFY payloads, which are size limited
+		if err := checkPresenceSize(msg.Channel, &PresenceInfo{
+			Key:      presencePayload.Key,
+			State:    presencePayload.State,
+			UserID:   conn.UserID,
+			ConnID:   conn.ID,
+			JoinedAt: time.Now(),
+		}); err != nil {
+			_ = conn.SendMessage(ServerMessage{
+				Type:  MessageTypeError,
+				Error: err.Error(),
+			})
+			return
+		}
+
 		// Track presence
 		info, isNew := h.presenceManager.Track(
 			msg.Channel,
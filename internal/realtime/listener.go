@@ -22,6 +22,7 @@ type ChangeEvent struct {
 	Schema    string                 `json:"schema"`               // Schema name
 	Record    map[string]interface{} `json:"record"`               // New record data
 	OldRecord map[string]interface{} `json:"old_record,omitempty"` // Old record data (for UPDATE/DELETE)
+	Position  uint64                 `json:"position,omitempty"`   // Position in the change log, for resuming subscriptions
 }
 
 // LogChannel is the PubSub channel for execution log notifications.
@@ -202,6 +203,7 @@ func (l *Listener) processNotification(notification *pgconn.Notification) {
 
 	// Do RLS-aware filtering for table subscriptions
 	if l.subManager != nil {
+		l.subManager.RecordChange(&event)
 		filteredEvents := l.subManager.FilterEventForSubscribers(l.ctx, &event)
 
 		// Send to each connection that has access
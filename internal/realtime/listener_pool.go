@@ -13,6 +13,18 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// ChangeSource selects where the listener pool gets the database changes from.
+type ChangeSource string
+
+const (
+	// ChangeSourceTrigger listens for the notifications of the realtime triggers (pg_notify).
+	ChangeSourceTrigger ChangeSource = "trigger"
+
+	// ChangeSourceLogical consumes a logical replication slot with the pgoutput plugin. Rows of
+	// any size are supported and no change is lost while disconnected.
+	ChangeSourceLogical ChangeSource = "logical"
+)
+
 // ListenerPoolConfig holds configuration for the listener pool.
 type ListenerPoolConfig struct {
 	PoolSize      int // Number of LISTEN connections (default: 2)
@@ -20,6 +32,10 @@ type ListenerPoolConfig struct {
 	QueueSize     int // Size of notification queue per worker (default: 1000)
 	RetryInterval time.Duration
 	MaxRetries    int
+
+	ChangeSource    ChangeSource // Source of the changes (default: trigger)
+	ReplicationSlot string       // Replication slot of the logical source (default: fluxbase_realtime)
+	Publication     string       // Publication of the realtime tables for the logical source (default: fluxbase_realtime)
 }
 
 // DefaultListenerPoolConfig returns sensible defaults.
@@ -33,7 +49,31 @@ func DefaultListenerPoolConfig() ListenerPoolConfig {
 	}
 }
 
+// ChangeObserver is notified of every change notification the listener pool receives, before
+// subscription filtering. Observers that keep derived state (e.g. the REST response cache) use it
+// to learn which tables changed, and whether notifications may have been missed.
+type ChangeObserver interface {
+	// TableChanged is called for each change notification. It must not block.
+	TableChanged(schema, table string)
+
+	// ListeningChanged is called when the pool starts listening (first connection up) and stops
+	// listening (last connection lost). Changes made while not listening are never notified.
+	ListeningChanged(listening bool)
+
+	// ChangesMissed is called when a notification was dropped.
+	ChangesMissed()
+}
+
+// EventObserver receives every change event the listener pool processes, before subscription
+// filtering. It is used by consumers that manage their own subscriptions, like GraphQL
+// subscriptions, and must apply the RLS checks themselves.
+type EventObserver interface {
+	// ChangeReceived is called for each change event. It must not block or modify the event.
+	ChangeReceived(event *ChangeEvent)
+}
+
 // ListenerPool manages a pool of PostgreSQL LISTEN connections with parallel processing.
+// With the logical change source, a replication stream replaces the LISTEN connections.
 type ListenerPool struct {
 	config     ListenerPoolConfig
 	pool       *pgxpool.Pool
@@ -50,8 +90,13 @@ type ListenerPool struct {
 
 	// Connection tracking
 	activeConnections int32
+	listening         int32 // Connections that have executed LISTEN
 	connWg            sync.WaitGroup
 
+	// Observers of every change notification, set before Start
+	observers      []ChangeObserver
+	eventObservers []EventObserver
+
 	// Metrics
 	notificationsReceived  uint64
 	notificationsProcessed uint64
@@ -83,6 +128,15 @@ func NewListenerPool(
 	if config.MaxRetries <= 0 {
 		config.MaxRetries = 5
 	}
+	if config.ChangeSource == "" {
+		config.ChangeSource = ChangeSourceTrigger
+	}
+	if config.ReplicationSlot == "" {
+		config.ReplicationSlot = "fluxbase_realtime"
+	}
+	if config.Publication == "" {
+		config.Publication = "fluxbase_realtime"
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -98,6 +152,16 @@ func NewListenerPool(
 	}
 }
 
+// AddChangeObserver registers an observer of the change notifications. It must be called before Start.
+func (lp *ListenerPool) AddChangeObserver(observer ChangeObserver) {
+	lp.observers = append(lp.observers, observer)
+}
+
+// AddEventObserver registers an observer of the change events. It must be called before Start.
+func (lp *ListenerPool) AddEventObserver(observer EventObserver) {
+	lp.eventObservers = append(lp.eventObservers, observer)
+}
+
 // Start begins the listener pool.
 func (lp *ListenerPool) Start() error {
 	// Start worker goroutines
@@ -111,15 +175,30 @@ func (lp *ListenerPool) Start() error {
 		Int("queue_size", lp.config.QueueSize).
 		Msg("Listener pool workers started")
 
-	// Start listener connections
-	for i := 0; i < lp.config.PoolSize; i++ {
+	if lp.config.ChangeSource == ChangeSourceLogical {
+		// One instance consumes the slot and shares the changes with the others through pub/sub
 		lp.connWg.Add(1)
-		go lp.runListener(i)
-	}
+		go lp.runReplication()
+		if lp.pubsub != nil {
+			lp.connWg.Add(1)
+			go lp.listenChangesPubSub()
+		}
 
-	log.Info().
-		Int("pool_size", lp.config.PoolSize).
-		Msg("Listener pool connections started")
+		log.Info().
+			Str("slot", lp.config.ReplicationSlot).
+			Str("publication", lp.config.Publication).
+			Msg("Listener pool logical replication started")
+	} else {
+		// Start listener connections
+		for i := 0; i < lp.config.PoolSize; i++ {
+			lp.connWg.Add(1)
+			go lp.runListener(i)
+		}
+
+		log.Info().
+			Int("pool_size", lp.config.PoolSize).
+			Msg("Listener pool connections started")
+	}
 
 	// Start PubSub listeners if available
 	if lp.pubsub != nil {
@@ -236,6 +315,16 @@ func (lp *ListenerPool) listen(id int) error {
 
 	log.Debug().Int("listener_id", id).Msg("LISTEN started")
 
+	// Every connection receives every notification, so changes are only missed while none listens
+	if atomic.AddInt32(&lp.listening, 1) == 1 {
+		lp.notifyListening(true)
+	}
+	defer func() {
+		if atomic.AddInt32(&lp.listening, -1) == 0 {
+			lp.notifyListening(false)
+		}
+	}()
+
 	// Listen loop
 	for {
 		select {
@@ -264,6 +353,9 @@ func (lp *ListenerPool) listen(id int) error {
 			case lp.notificationCh <- notification:
 			case <-time.After(100 * time.Millisecond):
 				log.Warn().Int("listener_id", id).Msg("Notification queue full, dropping notification")
+				for _, observer := range lp.observers {
+					observer.ChangesMissed()
+				}
 			case <-lp.ctx.Done():
 				return nil
 			}
@@ -271,6 +363,13 @@ func (lp *ListenerPool) listen(id int) error {
 	}
 }
 
+// notifyListening tells the observers whether the pool is listening for changes.
+func (lp *ListenerPool) notifyListening(listening bool) {
+	for _, observer := range lp.observers {
+		observer.ListeningChanged(listening)
+	}
+}
+
 // worker processes notifications from the queue.
 func (lp *ListenerPool) worker(id int) {
 	defer lp.workerWg.Done()
@@ -288,9 +387,16 @@ func (lp *ListenerPool) processNotification(notification *pgconn.Notification) {
 	var event ChangeEvent
 	if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
 		log.Error().Err(err).Str("payload", notification.Payload).Msg("Failed to parse notification")
+		for _, observer := range lp.observers {
+			observer.ChangesMissed()
+		}
 		return
 	}
 
+	for _, observer := range lp.observers {
+		observer.TableChanged(event.Schema, event.Table)
+	}
+
 	// Skip debug logging for noisy events
 	isWorkerHeartbeat := event.Schema == "jobs" && event.Table == "workers" && event.Type == "UPDATE"
 	if !isWorkerHeartbeat {
@@ -306,6 +412,15 @@ func (lp *ListenerPool) processNotification(notification *pgconn.Notification) {
 		lp.enrichJobWithETA(&event)
 	}
 
+	// Assign the change log position before any consumer sees the event
+	if lp.subManager != nil {
+		lp.subManager.RecordChange(&event)
+	}
+
+	for _, observer := range lp.eventObservers {
+		observer.ChangeReceived(&event)
+	}
+
 	// Do RLS-aware filtering
 	if lp.subManager != nil {
 		filteredEvents := lp.subManager.FilterEventForSubscribers(lp.ctx, &event)
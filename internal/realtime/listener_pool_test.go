@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -353,3 +354,60 @@ func BenchmarkListenerPool_EnrichJobWithETA(b *testing.B) {
 		lp.enrichJobWithETA(event)
 	}
 }
+
+type recordingObserver struct {
+	changed []string
+	missed  int
+}
+
+func (o *recordingObserver) TableChanged(schema, table string) {
+	o.changed = append(o.changed, schema+"."+table)
+}
+
+func (o *recordingObserver) ListeningChanged(listening bool) {}
+
+func (o *recordingObserver) ChangesMissed() {
+	o.missed++
+}
+
+func TestListenerPool_ProcessNotificationNotifiesObservers(t *testing.T) {
+	handler := NewRealtimeHandler(NewManager(context.Background()), nil, nil)
+	lp := NewListenerPool(nil, handler, nil, nil, ListenerPoolConfig{})
+	observer := &recordingObserver{}
+	lp.AddChangeObserver(observer)
+
+	lp.processNotification(&pgconn.Notification{
+		Channel: "fluxbase_changes",
+		Payload: `{"schema":"public","table":"products","type":"UPDATE","record":{"id":1}}`,
+	})
+	lp.processNotification(&pgconn.Notification{Channel: "fluxbase_changes", Payload: "not json"})
+
+	assert.Equal(t, []string{"public.products"}, observer.changed)
+	assert.Equal(t, 1, observer.missed)
+}
+
+type recordingEventObserver struct {
+	events []*ChangeEvent
+}
+
+func (o *recordingEventObserver) ChangeReceived(event *ChangeEvent) {
+	o.events = append(o.events, event)
+}
+
+func TestListenerPool_ProcessNotificationNotifiesEventObservers(t *testing.T) {
+	handler := NewRealtimeHandler(NewManager(context.Background()), nil, nil)
+	lp := NewListenerPool(nil, handler, nil, nil, ListenerPoolConfig{})
+	observer := &recordingEventObserver{}
+	lp.AddEventObserver(observer)
+
+	lp.processNotification(&pgconn.Notification{
+		Channel: "fluxbase_changes",
+		Payload: `{"schema":"public","table":"products","type":"INSERT","record":{"id":1}}`,
+	})
+	lp.processNotification(&pgconn.Notification{Channel: "fluxbase_changes", Payload: "not json"})
+
+	if assert.Len(t, observer.events, 1) {
+		assert.Equal(t, "INSERT", observer.events[0].Type)
+		assert.Equal(t, float64(1), observer.events[0].Record["id"])
+	}
+}
@@ -0,0 +1,408 @@
+package realtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Logical replication streams are COPY data messages starting with one of these bytes.
+// See https://www.postgresql.org/docs/current/protocol-replication.html
+const (
+	xLogDataByteID            = 'w'
+	primaryKeepaliveByteID    = 'k'
+	standbyStatusUpdateByteID = 'r'
+)
+
+// postgresEpoch is the origin of the timestamps in the replication protocol
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// errShortMessage is returned for replication messages that end early
+var errShortMessage = errors.New("replication message too short")
+
+// lsn is a position in the PostgreSQL write-ahead log
+type lsn uint64
+
+// String formats an LSN the way PostgreSQL does, e.g. 16/B374D848
+func (l lsn) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// xLogData is a chunk of WAL data sent by the server, here a pgoutput message
+type xLogData struct {
+	walStart lsn
+	walEnd   lsn
+	data     []byte
+}
+
+// primaryKeepalive is a server heartbeat, optionally asking for a status update
+type primaryKeepalive struct {
+	walEnd         lsn
+	replyRequested bool
+}
+
+// pgoutputRelation describes a table, sent before the first change to it in a session
+type pgoutputRelation struct {
+	id      uint32
+	schema  string
+	name    string
+	columns []pgoutputColumn
+}
+
+// pgoutputColumn is a column of a relation
+type pgoutputColumn struct {
+	name    string
+	typeOID uint32
+	key     bool // Part of the replica identity
+}
+
+// pgoutputBegin starts a transaction
+type pgoutputBegin struct {
+	finalLSN lsn
+	xid      uint32
+}
+
+// pgoutputCommit ends a transaction. The changes are durable up to endLSN.
+type pgoutputCommit struct {
+	commitLSN lsn
+	endLSN    lsn
+}
+
+// pgoutputChange is a row inserted, updated or deleted
+type pgoutputChange struct {
+	op         string // INSERT, UPDATE or DELETE
+	relationID uint32
+	oldTuple   []tupleValue // Old row of UPDATE and DELETE, nil for UPDATEs when not sent
+	oldIsKey   bool         // oldTuple only holds the replica identity columns
+	newTuple   []tupleValue // Row of INSERT and UPDATE
+}
+
+// tupleValue is a column value of a row in text format
+type tupleValue struct {
+	kind byte // 'n' NULL, 'u' unchanged TOASTed value, 't' text
+	data []byte
+}
+
+// wireReader reads the big-endian fields of a protocol message. Reading past the end sets err.
+type wireReader struct {
+	buf []byte
+	err error
+}
+
+func (r *wireReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errShortMessage
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *wireReader) uint8() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *wireReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *wireReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *wireReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *wireReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errShortMessage
+	return ""
+}
+
+// parseXLogData parses the body of an XLogData message
+func parseXLogData(buf []byte) (xLogData, error) {
+	r := wireReader{buf: buf}
+	msg := xLogData{walStart: lsn(r.uint64()), walEnd: lsn(r.uint64())}
+	r.uint64() // Server clock
+	msg.data = r.buf
+	return msg, r.err
+}
+
+// parsePrimaryKeepalive parses the body of a primary keepalive message
+func parsePrimaryKeepalive(buf []byte) (primaryKeepalive, error) {
+	r := wireReader{buf: buf}
+	msg := primaryKeepalive{walEnd: lsn(r.uint64())}
+	r.uint64() // Server clock
+	msg.replyRequested = r.uint8() == 1
+	return msg, r.err
+}
+
+// encodeStandbyStatusUpdate encodes a status update reporting everything up to pos as
+// written, flushed and applied, so the server can release the WAL before it
+func encodeStandbyStatusUpdate(pos lsn, now time.Time) []byte {
+	buf := make([]byte, 0, 34)
+	buf = append(buf, standbyStatusUpdateByteID)
+	for i := 0; i < 3; i++ {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(pos))
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(now.Sub(postgresEpoch).Microseconds()))
+	return append(buf, 0)
+}
+
+// parsePgoutputMessage parses a pgoutput message. Messages that don't affect change events,
+// like origins, types and truncations, are returned as nil.
+func parsePgoutputMessage(buf []byte) (interface{}, error) {
+	if len(buf) == 0 {
+		return nil, errShortMessage
+	}
+	r := wireReader{buf: buf[1:]}
+
+	var msg interface{}
+	switch buf[0] {
+	case 'B':
+		begin := &pgoutputBegin{finalLSN: lsn(r.uint64())}
+		r.uint64() // Commit timestamp
+		begin.xid = r.uint32()
+		msg = begin
+
+	case 'C':
+		r.uint8() // Flags
+		msg = &pgoutputCommit{commitLSN: lsn(r.uint64()), endLSN: lsn(r.uint64())}
+
+	case 'R':
+		rel := &pgoutputRelation{id: r.uint32(), schema: r.cstring(), name: r.cstring()}
+		r.uint8() // Replica identity setting
+		rel.columns = make([]pgoutputColumn, r.uint16())
+		for i := range rel.columns {
+			flags := r.uint8()
+			rel.columns[i] = pgoutputColumn{name: r.cstring(), typeOID: r.uint32(), key: flags&1 == 1}
+			r.uint32() // Type modifier
+		}
+		msg = rel
+
+	case 'I':
+		change := &pgoutputChange{op: "INSERT", relationID: r.uint32()}
+		if r.uint8() != 'N' && r.err == nil {
+			return nil, fmt.Errorf("unexpected tuple type in insert")
+		}
+		change.newTuple = readTuple(&r)
+		msg = change
+
+	case 'U':
+		change := &pgoutputChange{op: "UPDATE", relationID: r.uint32()}
+		kind := r.uint8()
+		if kind == 'K' || kind == 'O' {
+			change.oldIsKey = kind == 'K'
+			change.oldTuple = readTuple(&r)
+			kind = r.uint8()
+		}
+		if kind != 'N' && r.err == nil {
+			return nil, fmt.Errorf("unexpected tuple type in update")
+		}
+		change.newTuple = readTuple(&r)
+		msg = change
+
+	case 'D':
+		change := &pgoutputChange{op: "DELETE", relationID: r.uint32()}
+		kind := r.uint8()
+		if kind != 'K' && kind != 'O' && r.err == nil {
+			return nil, fmt.Errorf("unexpected tuple type in delete")
+		}
+		change.oldIsKey = kind == 'K'
+		change.oldTuple = readTuple(&r)
+		msg = change
+
+	default:
+		return nil, nil
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return msg, nil
+}
+
+// readTuple reads the column values of a row
+func readTuple(r *wireReader) []tupleValue {
+	values := make([]tupleValue, r.uint16())
+	for i := range values {
+		values[i].kind = r.uint8()
+		if values[i].kind == 't' || values[i].kind == 'b' {
+			values[i].data = r.next(int(r.uint32()))
+		}
+	}
+	if r.err != nil {
+		return nil
+	}
+	return values
+}
+
+// timezoneHourOffset matches a UTC offset without minutes at the end of a timestamptz
+var timezoneHourOffset = regexp.MustCompile(`[+-]\d\d$`)
+
+// pgTextToJSON converts a value in PostgreSQL text format to the JSON to_jsonb produces for it,
+// so change events look the same whichever source they come from
+func pgTextToJSON(typeMap *pgtype.Map, typeOID uint32, text string) interface{} {
+	switch typeOID {
+	case pgtype.BoolOID:
+		return text == "t"
+
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID,
+		pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+		// NaN and infinities aren't JSON numbers, to_jsonb keeps them as strings
+		if json.Valid([]byte(text)) {
+			return json.RawMessage(text)
+		}
+		return text
+
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		return json.RawMessage(text)
+
+	case pgtype.TimestampOID, pgtype.TimestamptzOID:
+		// 2024-01-02 03:04:05+01 is rendered as 2024-01-02T03:04:05+01:00
+		if text == "infinity" || text == "-infinity" {
+			return text
+		}
+		text = strings.Replace(text, " ", "T", 1)
+		if typeOID == pgtype.TimestamptzOID && timezoneHourOffset.MatchString(text) {
+			text += ":00"
+		}
+		return text
+	}
+
+	if t, ok := typeMap.TypeForOID(typeOID); ok {
+		if arrayCodec, ok := t.Codec.(*pgtype.ArrayCodec); ok {
+			if elements, ok := parseTextArray(text); ok {
+				return arrayToJSON(typeMap, arrayCodec.ElementType.OID, elements)
+			}
+		}
+	}
+	return text
+}
+
+// arrayToJSON converts the elements of a parsed array
+func arrayToJSON(typeMap *pgtype.Map, elementOID uint32, elements []interface{}) []interface{} {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		switch e := element.(type) {
+		case []interface{}:
+			values[i] = arrayToJSON(typeMap, elementOID, e)
+		case *string:
+			values[i] = pgTextToJSON(typeMap, elementOID, *e)
+		default:
+			values[i] = nil
+		}
+	}
+	return values
+}
+
+// parseTextArray parses an array in text format, e.g. {1,NULL,"a b"} or {{1,2},{3,4}}.
+// Elements are returned as *string, nil for NULL, or []interface{} for nested arrays.
+func parseTextArray(text string) ([]interface{}, bool) {
+	// Arrays with other lower bounds start with their dimensions, e.g. [0:1]={1,2}
+	if strings.HasPrefix(text, "[") {
+		i := strings.Index(text, "=")
+		if i < 0 {
+			return nil, false
+		}
+		text = text[i+1:]
+	}
+	elements, rest, ok := parseTextArrayLevel(text)
+	return elements, ok && rest == ""
+}
+
+// parseTextArrayLevel parses one level of braces and returns the text after it
+func parseTextArrayLevel(text string) ([]interface{}, string, bool) {
+	if !strings.HasPrefix(text, "{") {
+		return nil, "", false
+	}
+	text = text[1:]
+	elements := []interface{}{}
+	if strings.HasPrefix(text, "}") {
+		return elements, text[1:], true
+	}
+
+	for {
+		switch {
+		case strings.HasPrefix(text, "{"):
+			nested, rest, ok := parseTextArrayLevel(text)
+			if !ok {
+				return nil, "", false
+			}
+			elements = append(elements, nested)
+			text = rest
+
+		case strings.HasPrefix(text, `"`):
+			var sb strings.Builder
+			i := 1
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				sb.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, "", false
+			}
+			element := sb.String()
+			elements = append(elements, &element)
+			text = text[i+1:]
+
+		default:
+			end := strings.IndexAny(text, ",}")
+			if end < 0 {
+				return nil, "", false
+			}
+			element := text[:end]
+			if element == "NULL" {
+				elements = append(elements, nil)
+			} else {
+				elements = append(elements, &element)
+			}
+			text = text[end:]
+		}
+
+		if text == "" {
+			return nil, "", false
+		}
+		if text[0] == '}' {
+			return elements, text[1:], true
+		}
+		if text[0] != ',' {
+			return nil, "", false
+		}
+		text = text[1:]
+	}
+}
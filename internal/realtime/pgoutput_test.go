@@ -0,0 +1,217 @@
+package realtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgoutputBuilder builds pgoutput messages for tests
+type pgoutputBuilder []byte
+
+func (b pgoutputBuilder) byte(v byte) pgoutputBuilder {
+	return append(b, v)
+}
+
+func (b pgoutputBuilder) uint16(v uint16) pgoutputBuilder {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+func (b pgoutputBuilder) uint32(v uint32) pgoutputBuilder {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+func (b pgoutputBuilder) uint64(v uint64) pgoutputBuilder {
+	return binary.BigEndian.AppendUint64(b, v)
+}
+
+func (b pgoutputBuilder) cstring(s string) pgoutputBuilder {
+	return append(append(b, s...), 0)
+}
+
+// tuple appends a row; nil values are NULL and "\x00u" marks an unchanged TOASTed value
+func (b pgoutputBuilder) tuple(values ...interface{}) pgoutputBuilder {
+	b = b.uint16(uint16(len(values)))
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			b = b.byte('n')
+		case string:
+			if v == "\x00u" {
+				b = b.byte('u')
+				continue
+			}
+			b = b.byte('t').uint32(uint32(len(v)))
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+func relationMessage(id uint32, schema, name string, columns ...pgoutputColumn) []byte {
+	b := pgoutputBuilder{'R'}.uint32(id).cstring(schema).cstring(name).byte('f').uint16(uint16(len(columns)))
+	for _, col := range columns {
+		flags := byte(0)
+		if col.key {
+			flags = 1
+		}
+		b = b.byte(flags).cstring(col.name).uint32(col.typeOID).uint32(0xFFFFFFFF)
+	}
+	return b
+}
+
+func beginMessage() []byte {
+	return pgoutputBuilder{'B'}.uint64(0x100).uint64(0).uint32(42)
+}
+
+func commitMessage(endLSN uint64) []byte {
+	return pgoutputBuilder{'C'}.byte(0).uint64(endLSN - 8).uint64(endLSN).uint64(0)
+}
+
+func TestParsePgoutputMessage(t *testing.T) {
+	t.Run("relation", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(relationMessage(16384, "public", "posts",
+			pgoutputColumn{name: "id", typeOID: pgtype.Int4OID, key: true},
+			pgoutputColumn{name: "title", typeOID: pgtype.TextOID},
+		))
+		require.NoError(t, err)
+		assert.Equal(t, &pgoutputRelation{
+			id:     16384,
+			schema: "public",
+			name:   "posts",
+			columns: []pgoutputColumn{
+				{name: "id", typeOID: pgtype.Int4OID, key: true},
+				{name: "title", typeOID: pgtype.TextOID},
+			},
+		}, msg)
+	})
+
+	t.Run("begin and commit", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(beginMessage())
+		require.NoError(t, err)
+		assert.Equal(t, &pgoutputBegin{finalLSN: 0x100, xid: 42}, msg)
+
+		msg, err = parsePgoutputMessage(commitMessage(0x208))
+		require.NoError(t, err)
+		assert.Equal(t, &pgoutputCommit{commitLSN: 0x200, endLSN: 0x208}, msg)
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(pgoutputBuilder{'I'}.uint32(16384).byte('N').tuple("1", nil))
+		require.NoError(t, err)
+		assert.Equal(t, &pgoutputChange{
+			op:         "INSERT",
+			relationID: 16384,
+			newTuple:   []tupleValue{{kind: 't', data: []byte("1")}, {kind: 'n'}},
+		}, msg)
+	})
+
+	t.Run("update with old row", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(pgoutputBuilder{'U'}.uint32(16384).byte('O').tuple("1", "a").byte('N').tuple("1", "\x00u"))
+		require.NoError(t, err)
+		change := msg.(*pgoutputChange)
+		assert.Equal(t, "UPDATE", change.op)
+		assert.False(t, change.oldIsKey)
+		assert.Len(t, change.oldTuple, 2)
+		assert.Equal(t, byte('u'), change.newTuple[1].kind)
+	})
+
+	t.Run("update without old row", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(pgoutputBuilder{'U'}.uint32(16384).byte('N').tuple("1", "b"))
+		require.NoError(t, err)
+		change := msg.(*pgoutputChange)
+		assert.Nil(t, change.oldTuple)
+		assert.Len(t, change.newTuple, 2)
+	})
+
+	t.Run("delete with key", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(pgoutputBuilder{'D'}.uint32(16384).byte('K').tuple("1", nil))
+		require.NoError(t, err)
+		change := msg.(*pgoutputChange)
+		assert.Equal(t, "DELETE", change.op)
+		assert.True(t, change.oldIsKey)
+		assert.Nil(t, change.newTuple)
+	})
+
+	t.Run("ignored messages", func(t *testing.T) {
+		msg, err := parsePgoutputMessage(pgoutputBuilder{'T'}.uint32(1).byte(0).uint32(16384))
+		require.NoError(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := parsePgoutputMessage(pgoutputBuilder{'I'}.uint32(16384).byte('N').uint16(2).byte('t').uint32(10))
+		assert.ErrorIs(t, err, errShortMessage)
+
+		_, err = parsePgoutputMessage(nil)
+		assert.ErrorIs(t, err, errShortMessage)
+	})
+}
+
+func TestReplicationMessages(t *testing.T) {
+	xld, err := parseXLogData(pgoutputBuilder{}.uint64(0x10).uint64(0x20).uint64(0).byte('B'))
+	require.NoError(t, err)
+	assert.Equal(t, xLogData{walStart: 0x10, walEnd: 0x20, data: []byte{'B'}}, xld)
+
+	keepalive, err := parsePrimaryKeepalive(pgoutputBuilder{}.uint64(0x30).uint64(0).byte(1))
+	require.NoError(t, err)
+	assert.Equal(t, primaryKeepalive{walEnd: 0x30, replyRequested: true}, keepalive)
+
+	status := encodeStandbyStatusUpdate(0x16B374D848, postgresEpoch.Add(time.Second))
+	require.Len(t, status, 34)
+	assert.Equal(t, byte('r'), status[0])
+	assert.Equal(t, uint64(0x16B374D848), binary.BigEndian.Uint64(status[1:]))
+	assert.Equal(t, uint64(0x16B374D848), binary.BigEndian.Uint64(status[17:]))
+	assert.Equal(t, uint64(1000000), binary.BigEndian.Uint64(status[25:]))
+
+	assert.Equal(t, "16/B374D848", lsn(0x16B374D848).String())
+}
+
+func TestPgTextToJSON(t *testing.T) {
+	typeMap := pgtype.NewMap()
+
+	tests := []struct {
+		name    string
+		typeOID uint32
+		text    string
+		want    string // JSON of the result
+	}{
+		{"bool", pgtype.BoolOID, "t", `true`},
+		{"integer", pgtype.Int8OID, "9007199254740993", `9007199254740993`},
+		{"numeric keeps its scale", pgtype.NumericOID, "12.50", `12.50`},
+		{"numeric NaN", pgtype.NumericOID, "NaN", `"NaN"`},
+		{"float infinity", pgtype.Float8OID, "Infinity", `"Infinity"`},
+		{"jsonb", pgtype.JSONBOID, `{"percent": 50}`, `{"percent":50}`},
+		{"text", pgtype.TextOID, "hello", `"hello"`},
+		{"uuid", pgtype.UUIDOID, "550e8400-e29b-41d4-a716-446655440000", `"550e8400-e29b-41d4-a716-446655440000"`},
+		{"timestamptz", pgtype.TimestamptzOID, "2024-01-02 03:04:05.123+00", `"2024-01-02T03:04:05.123+00:00"`},
+		{"timestamptz with minutes", pgtype.TimestamptzOID, "2024-01-02 03:04:05+05:30", `"2024-01-02T03:04:05+05:30"`},
+		{"timestamp", pgtype.TimestampOID, "2024-01-02 03:04:05", `"2024-01-02T03:04:05"`},
+		{"infinite timestamp", pgtype.TimestamptzOID, "infinity", `"infinity"`},
+		{"date", pgtype.DateOID, "2024-01-02", `"2024-01-02"`},
+		{"text array", pgtype.TextArrayOID, `{a,"b c",NULL,"d\"e"}`, `["a","b c",null,"d\"e"]`},
+		{"integer array", pgtype.Int4ArrayOID, `{{1,2},{3,4}}`, `[[1,2],[3,4]]`},
+		{"empty array", pgtype.Int4ArrayOID, `{}`, `[]`},
+		{"array with bounds", pgtype.Int4ArrayOID, `[0:1]={1,2}`, `[1,2]`},
+		{"unknown type", 99999, "(1,2)", `"(1,2)"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(pgTextToJSON(typeMap, tt.typeOID, tt.text))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestParseTextArray_Invalid(t *testing.T) {
+	for _, text := range []string{"", "{1,2", `{"a}`, "{1}x", "[0:1]"} {
+		_, ok := parseTextArray(text)
+		assert.False(t, ok, text)
+	}
+}
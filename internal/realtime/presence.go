@@ -1,9 +1,13 @@
 package realtime
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,15 +21,25 @@ type PresenceInfo struct {
 	UserID   *string
 	ConnID   string
 	JoinedAt time.Time
+	NodeID   string // Instance the presence is tracked on, empty for this instance
 }
 
-// PresenceManager manages presence tracking across channels
+// PresenceManager manages presence tracking across channels.
+// With pub/sub, presences are shared with the other instances, see SetPubSub.
 type PresenceManager struct {
 	// channel -> (presence_key -> PresenceInfo)
 	presences map[string]map[string]*PresenceInfo
 	// connection_id -> (channel -> presence_key)
 	connPresences map[string]map[string]string
 	mu            sync.RWMutex
+
+	// Cluster-wide presence, only set with pub/sub
+	nodeID            string                   // ID of this instance in presence messages
+	nodes             map[string]*presenceNode // node ID -> presences tracked on another instance
+	seq               uint64                   // Sequence number of the last local presence change
+	outbox            chan presenceMessage     // Messages waiting to be published, in order
+	heartbeatInterval time.Duration
+	onRemoteChange    func(event, channel string, info *PresenceInfo)
 }
 
 // NewPresenceManager creates a new presence manager
@@ -33,6 +47,7 @@ func NewPresenceManager() *PresenceManager {
 	return &PresenceManager{
 		presences:     make(map[string]map[string]*PresenceInfo),
 		connPresences: make(map[string]map[string]string),
+		nodes:         make(map[string]*presenceNode),
 	}
 }
 
@@ -72,6 +87,7 @@ func (pm *PresenceManager) Track(channel, key string, state PresenceState, userI
 		pm.connPresences[connID] = make(map[string]string)
 	}
 	pm.connPresences[connID][channel] = key
+	pm.sendChange(presenceMessageTrack, channel, info)
 
 	log.Debug().
 		Str("channel", channel).
@@ -93,6 +109,9 @@ func (pm *PresenceManager) Untrack(channel, key, connID string) *PresenceInfo {
 	if pm.presences[channel] != nil {
 		info = pm.presences[channel][key]
 		delete(pm.presences[channel], key)
+		if info != nil {
+			pm.sendChange(presenceMessageUntrack, channel, info)
+		}
 
 		// Clean up empty channel map
 		if len(pm.presences[channel]) == 0 {
@@ -121,6 +140,7 @@ func (pm *PresenceManager) Untrack(channel, key, connID string) *PresenceInfo {
 
 // GetPresenceState returns the current presence state for a channel
 // Returns in the format expected by the SDK: map[key][]PresenceState
+// Keys tracked on several instances have a state for each of them.
 func (pm *PresenceManager) GetPresenceState(channel string) map[string][]PresenceState {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
@@ -133,9 +153,25 @@ func (pm *PresenceManager) GetPresenceState(channel string) map[string][]Presenc
 		}
 	}
 
+	for _, nodeID := range pm.sortedNodeIDs() {
+		for key, info := range pm.nodes[nodeID].presences[channel] {
+			result[key] = append(result[key], info.State)
+		}
+	}
+
 	return result
 }
 
+// sortedNodeIDs returns the IDs of the other instances in a stable order
+func (pm *PresenceManager) sortedNodeIDs() []string {
+	ids := make([]string, 0, len(pm.nodes))
+	for id := range pm.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // CleanupConnection removes all presence entries for a disconnected connection
 // Returns a map of channel -> presence info that was removed
 func (pm *PresenceManager) CleanupConnection(connID string) map[string]*PresenceInfo {
@@ -156,6 +192,7 @@ func (pm *PresenceManager) CleanupConnection(connID string) map[string]*Presence
 			if info := pm.presences[channel][key]; info != nil {
 				removed[channel] = info
 				delete(pm.presences[channel], key)
+				pm.sendChange(presenceMessageUntrack, channel, info)
 
 				// Clean up empty channel map
 				if len(pm.presences[channel]) == 0 {
@@ -176,13 +213,53 @@ func (pm *PresenceManager) CleanupConnection(connID string) map[string]*Presence
 	return removed
 }
 
-// GetChannelPresenceCount returns the number of active presences in a channel
+// GetChannelPresenceCount returns the number of active presence keys in a channel
 func (pm *PresenceManager) GetChannelPresenceCount(channel string) int {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
-	if pm.presences[channel] != nil {
-		return len(pm.presences[channel])
+	keys := make(map[string]struct{}, len(pm.presences[channel]))
+	for key := range pm.presences[channel] {
+		keys[key] = struct{}{}
+	}
+	for _, node := range pm.nodes {
+		for key := range node.presences[channel] {
+			keys[key] = struct{}{}
+		}
 	}
-	return 0
+	return len(keys)
+}
+
+// SetPubSub shares presence with the other instances through pub/sub. Track and untrack are
+// published as they happen, and every heartbeatInterval each instance publishes a heartbeat
+// that lets the others detect missed messages and fetch its presences again. Presences of an
+// instance that missed three heartbeats are removed. onRemoteChange is called for presences
+// that join ("join"), leave ("leave") or change ("update") on other instances.
+func (pm *PresenceManager) SetPubSub(ctx context.Context, ps pubsub.PubSub, heartbeatInterval time.Duration, onRemoteChange func(event, channel string, info *PresenceInfo)) {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = 10 * time.Second
+	}
+
+	msgCh, err := ps.Subscribe(ctx, pubsub.PresenceChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to presence channel, presence stays local to this instance")
+		return
+	}
+
+	pm.mu.Lock()
+	pm.nodeID = uuid.New().String()
+	pm.outbox = make(chan presenceMessage, presenceOutboxSize)
+	pm.heartbeatInterval = heartbeatInterval
+	pm.onRemoteChange = onRemoteChange
+
+	// Publish the presences tracked so far, and ask the other instances for theirs
+	pm.sendState()
+	pm.send(presenceMessage{Type: presenceMessageSync})
+	pm.mu.Unlock()
+
+	go pm.publishLoop(ctx, ps)
+	go pm.receiveLoop(ctx, msgCh)
+	go pm.heartbeatLoop(ctx)
+
+	log.Info().Str("node_id", pm.nodeID).Msg("Presence shared with other instances via pub/sub")
 }
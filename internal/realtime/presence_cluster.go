@@ -0,0 +1,395 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
+	"github.com/rs/zerolog/log"
+)
+
+// Presence message types published on pubsub.PresenceChannel
+const (
+	presenceMessageTrack     = "track"     // A presence was tracked or updated
+	presenceMessageUntrack   = "untrack"   // A presence was removed
+	presenceMessageHeartbeat = "heartbeat" // The instance is alive, with its sequence number and presence count
+	presenceMessageSync      = "sync"      // Asks an instance, or all without a target, to publish its state
+	presenceMessageState     = "state"     // Part of all presences of an instance
+)
+
+// presenceOutboxSize is the number of presence messages waiting to be published. When it's
+// full messages are dropped; the other instances notice the gap and fetch the state again.
+const presenceOutboxSize = 1024
+
+// presenceMessageMaxSize is the size state messages are split at, to stay below the 8000 bytes
+// PostgreSQL NOTIFY payloads are limited to
+const presenceMessageMaxSize = 7000
+
+// presenceNodeTimeoutHeartbeats is the number of heartbeats an instance may miss before its
+// presences are removed
+const presenceNodeTimeoutHeartbeats = 3
+
+// presenceMessage is a presence change or state of an instance, shared through pub/sub
+type presenceMessage struct {
+	Type      string          `json:"type"`
+	Node      string          `json:"node"`
+	Seq       uint64          `json:"seq,omitempty"`       // Sequence number of the instance's last change
+	Count     int             `json:"count,omitempty"`     // Number of presences, in heartbeats
+	Target    string          `json:"target,omitempty"`    // Instance asked for its state, in syncs
+	Part      int             `json:"part,omitempty"`      // Index of a state message
+	Final     bool            `json:"final,omitempty"`     // Last state message
+	Presences []presenceEntry `json:"presences,omitempty"` // Changed presence, or part of the state
+}
+
+// presenceEntry is a presence in a message
+type presenceEntry struct {
+	Channel  string        `json:"channel"`
+	Key      string        `json:"key"`
+	State    PresenceState `json:"state,omitempty"`
+	UserID   *string       `json:"user_id,omitempty"`
+	ConnID   string        `json:"conn_id,omitempty"`
+	JoinedAt time.Time     `json:"joined_at"`
+}
+
+// presenceNode holds the presences tracked on another instance
+type presenceNode struct {
+	presences map[string]map[string]*PresenceInfo // channel -> (presence_key -> PresenceInfo)
+	count     int
+	seq       uint64          // Sequence number of the last change applied
+	lastSeen  time.Time       // When the last message of the instance was received
+	requested time.Time       // When the state of the instance was last asked for
+	pending   []presenceEntry // State being received
+	nextPart  int             // Index of the next state message, -1 when not receiving a state
+}
+
+// presenceChange is a presence that joined, left or was updated on another instance
+type presenceChange struct {
+	event   string
+	channel string
+	info    *PresenceInfo
+}
+
+// send queues a message for publishing. The caller must hold pm.mu, so messages are published
+// in the order of their sequence numbers.
+func (pm *PresenceManager) send(msg presenceMessage) {
+	if pm.outbox == nil {
+		return
+	}
+	msg.Node = pm.nodeID
+	select {
+	case pm.outbox <- msg:
+	default:
+		log.Warn().Str("type", msg.Type).Msg("Presence outbox full, dropping presence message")
+	}
+}
+
+// sendChange numbers a local presence change and queues it for publishing. The caller must hold pm.mu.
+func (pm *PresenceManager) sendChange(msgType, channel string, info *PresenceInfo) {
+	if pm.outbox == nil {
+		return
+	}
+	pm.seq++
+	pm.send(presenceMessage{Type: msgType, Seq: pm.seq, Presences: []presenceEntry{newPresenceEntry(channel, info)}})
+}
+
+// sendState queues all local presences, split into messages that fit the pub/sub backends.
+// The caller must hold pm.mu.
+func (pm *PresenceManager) sendState() {
+	var entries []presenceEntry
+	for channel, presences := range pm.presences {
+		for _, info := range presences {
+			entries = append(entries, newPresenceEntry(channel, info))
+		}
+	}
+
+	parts := splitPresenceEntries(entries, presenceMessageMaxSize)
+	for i, part := range parts {
+		pm.send(presenceMessage{Type: presenceMessageState, Seq: pm.seq, Part: i, Presences: part})
+	}
+	pm.send(presenceMessage{Type: presenceMessageState, Seq: pm.seq, Part: len(parts), Final: true})
+}
+
+// splitPresenceEntries splits entries into parts of about maxSize bytes of JSON
+func splitPresenceEntries(entries []presenceEntry, maxSize int) [][]presenceEntry {
+	var parts [][]presenceEntry
+	var part []presenceEntry
+	size := 0
+	for _, entry := range entries {
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		if len(part) > 0 && size+len(encoded) > maxSize {
+			parts = append(parts, part)
+			part, size = nil, 0
+		}
+		part = append(part, entry)
+		size += len(encoded) + 1
+	}
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// newPresenceEntry converts a presence for a message
+func newPresenceEntry(channel string, info *PresenceInfo) presenceEntry {
+	return presenceEntry{
+		Channel:  channel,
+		Key:      info.Key,
+		State:    info.State,
+		UserID:   info.UserID,
+		ConnID:   info.ConnID,
+		JoinedAt: info.JoinedAt,
+	}
+}
+
+// checkPresenceSize returns an error when a presence is too large to be published in a change
+// message, with its state being the only part the client controls the size of
+func checkPresenceSize(channel string, info *PresenceInfo) error {
+	encoded, err := json.Marshal(newPresenceEntry(channel, info))
+	if err != nil {
+		return fmt.Errorf("invalid presence state: %w", err)
+	}
+	if len(encoded) > presenceMessageMaxSize {
+		return fmt.Errorf("presence state is too large: %d bytes, at most %d", len(encoded), presenceMessageMaxSize)
+	}
+	return nil
+}
+
+// publishLoop publishes the queued presence messages
+func (pm *PresenceManager) publishLoop(ctx context.Context, ps pubsub.PubSub) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-pm.outbox:
+			payload, err := json.Marshal(msg)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to marshal presence message")
+				continue
+			}
+			if err := ps.Publish(ctx, pubsub.PresenceChannel, payload); err != nil {
+				log.Warn().Err(err).Str("type", msg.Type).Msg("Failed to publish presence message")
+			}
+		}
+	}
+}
+
+// receiveLoop applies the presence messages of the other instances
+func (pm *PresenceManager) receiveLoop(ctx context.Context, msgCh <-chan pubsub.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgCh:
+			if !ok {
+				return
+			}
+			var presenceMsg presenceMessage
+			if err := json.Unmarshal(msg.Payload, &presenceMsg); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal presence message")
+				continue
+			}
+			pm.notifyRemote(pm.handleMessage(presenceMsg, time.Now()))
+		}
+	}
+}
+
+// heartbeatLoop publishes heartbeats and removes the presences of instances that stopped sending them
+func (pm *PresenceManager) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(pm.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			pm.mu.Lock()
+			pm.send(presenceMessage{Type: presenceMessageHeartbeat, Seq: pm.seq, Count: pm.localCount()})
+			changes := pm.expireNodes(now)
+			pm.mu.Unlock()
+
+			pm.notifyRemote(changes)
+		}
+	}
+}
+
+// localCount returns the number of presences tracked on this instance. The caller must hold pm.mu.
+func (pm *PresenceManager) localCount() int {
+	count := 0
+	for _, presences := range pm.presences {
+		count += len(presences)
+	}
+	return count
+}
+
+// handleMessage applies a presence message and returns the resulting changes
+func (pm *PresenceManager) handleMessage(msg presenceMessage, now time.Time) []presenceChange {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if msg.Node == pm.nodeID || msg.Node == "" {
+		return nil
+	}
+
+	node := pm.nodes[msg.Node]
+	if node == nil {
+		node = &presenceNode{presences: make(map[string]map[string]*PresenceInfo), nextPart: -1}
+		pm.nodes[msg.Node] = node
+	}
+	node.lastSeen = now
+
+	var changes []presenceChange
+	switch msg.Type {
+	case presenceMessageTrack, presenceMessageUntrack:
+		if msg.Seq != node.seq+1 {
+			pm.requestState(msg.Node, node, now)
+		}
+		node.seq = msg.Seq
+		for _, entry := range msg.Presences {
+			if msg.Type == presenceMessageTrack {
+				changes = append(changes, node.track(msg.Node, entry))
+			} else if change := node.untrack(entry.Channel, entry.Key); change != nil {
+				changes = append(changes, *change)
+			}
+		}
+
+	case presenceMessageHeartbeat:
+		if msg.Seq != node.seq || msg.Count != node.count {
+			pm.requestState(msg.Node, node, now)
+		}
+
+	case presenceMessageSync:
+		if msg.Target == "" || msg.Target == pm.nodeID {
+			pm.sendState()
+		}
+
+	case presenceMessageState:
+		if msg.Part == 0 {
+			node.pending, node.nextPart = nil, 0
+		}
+		if msg.Part != node.nextPart {
+			// A part was missed, the next heartbeat asks for the state again
+			node.pending, node.nextPart = nil, -1
+			break
+		}
+		node.pending = append(node.pending, msg.Presences...)
+		node.nextPart++
+		if msg.Final {
+			changes = node.replace(msg.Node, node.pending)
+			node.seq = msg.Seq
+			node.pending, node.nextPart = nil, -1
+		}
+	}
+
+	return changes
+}
+
+// requestState asks an instance for its state, at most once per heartbeat interval.
+// The caller must hold pm.mu.
+func (pm *PresenceManager) requestState(nodeID string, node *presenceNode, now time.Time) {
+	if now.Sub(node.requested) < pm.heartbeatInterval {
+		return
+	}
+	node.requested = now
+	pm.send(presenceMessage{Type: presenceMessageSync, Target: nodeID})
+}
+
+// expireNodes removes the instances that missed too many heartbeats, with their presences.
+// The caller must hold pm.mu.
+func (pm *PresenceManager) expireNodes(now time.Time) []presenceChange {
+	var changes []presenceChange
+	for nodeID, node := range pm.nodes {
+		if now.Sub(node.lastSeen) <= presenceNodeTimeoutHeartbeats*pm.heartbeatInterval {
+			continue
+		}
+		changes = append(changes, node.replace(nodeID, nil)...)
+		delete(pm.nodes, nodeID)
+
+		log.Info().Str("node_id", nodeID).Msg("Removed presences of unresponsive instance")
+	}
+	return changes
+}
+
+// notifyRemote passes the changes of other instances to the callback
+func (pm *PresenceManager) notifyRemote(changes []presenceChange) {
+	if pm.onRemoteChange == nil {
+		return
+	}
+	for _, change := range changes {
+		pm.onRemoteChange(change.event, change.channel, change.info)
+	}
+}
+
+// track adds or updates a presence of the instance
+func (n *presenceNode) track(nodeID string, entry presenceEntry) presenceChange {
+	if n.presences[entry.Channel] == nil {
+		n.presences[entry.Channel] = make(map[string]*PresenceInfo)
+	}
+
+	event := "update"
+	if _, exists := n.presences[entry.Channel][entry.Key]; !exists {
+		event = "join"
+		n.count++
+	}
+
+	info := &PresenceInfo{
+		Key:      entry.Key,
+		State:    entry.State,
+		UserID:   entry.UserID,
+		ConnID:   entry.ConnID,
+		JoinedAt: entry.JoinedAt,
+		NodeID:   nodeID,
+	}
+	n.presences[entry.Channel][entry.Key] = info
+	return presenceChange{event: event, channel: entry.Channel, info: info}
+}
+
+// untrack removes a presence of the instance, returning nil if it wasn't there
+func (n *presenceNode) untrack(channel, key string) *presenceChange {
+	info := n.presences[channel][key]
+	if info == nil {
+		return nil
+	}
+
+	delete(n.presences[channel], key)
+	if len(n.presences[channel]) == 0 {
+		delete(n.presences, channel)
+	}
+	n.count--
+	return &presenceChange{event: "leave", channel: channel, info: info}
+}
+
+// replace sets all presences of the instance, returning what changed
+func (n *presenceNode) replace(nodeID string, entries []presenceEntry) []presenceChange {
+	var changes []presenceChange
+	kept := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		if kept[entry.Channel] == nil {
+			kept[entry.Channel] = make(map[string]bool)
+		}
+		kept[entry.Channel][entry.Key] = true
+
+		existing := n.presences[entry.Channel][entry.Key]
+		if existing != nil && reflect.DeepEqual(existing.State, entry.State) {
+			continue
+		}
+		changes = append(changes, n.track(nodeID, entry))
+	}
+
+	for channel, presences := range n.presences {
+		for key := range presences {
+			if !kept[channel][key] {
+				if change := n.untrack(channel, key); change != nil {
+					changes = append(changes, *change)
+				}
+			}
+		}
+	}
+	return changes
+}
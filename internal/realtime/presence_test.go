@@ -1,8 +1,14 @@
 package realtime
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -238,3 +244,153 @@ func TestPresenceInfo_Struct(t *testing.T) {
 	assert.Equal(t, "user-123", *info.UserID)
 	assert.Equal(t, "conn-1", info.ConnID)
 }
+
+// presenceRecorder records the remote presence changes of a presence manager
+type presenceRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *presenceRecorder) record(event, channel string, info *PresenceInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event+" "+channel+" "+info.Key)
+}
+
+func (r *presenceRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestPresenceManager_Cluster(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewLocalPubSub()
+
+	a, b := NewPresenceManager(), NewPresenceManager()
+	recorder := &presenceRecorder{}
+	a.SetPubSub(ctx, ps, time.Hour, nil)
+	b.SetPubSub(ctx, ps, time.Hour, recorder.record)
+
+	a.Track("room:1", "user:1", PresenceState{"status": "online"}, nil, "conn-1")
+	require.Eventually(t, func() bool { return len(b.GetPresenceState("room:1")) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "online", b.GetPresenceState("room:1")["user:1"][0]["status"])
+
+	// The same key tracked on both instances has a state for each
+	b.Track("room:1", "user:1", PresenceState{"status": "away"}, nil, "conn-2")
+	require.Eventually(t, func() bool { return len(a.GetPresenceState("room:1")["user:1"]) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, a.GetChannelPresenceCount("room:1"))
+	assert.Equal(t, "online", a.GetPresenceState("room:1")["user:1"][0]["status"], "local presences come first")
+
+	a.Track("room:1", "user:1", PresenceState{"status": "busy"}, nil, "conn-1")
+	a.CleanupConnection("conn-1")
+	require.Eventually(t, func() bool { return len(recorder.get()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"join room:1 user:1", "update room:1 user:1", "leave room:1 user:1"}, recorder.get())
+	assert.Len(t, b.GetPresenceState("room:1")["user:1"], 1)
+
+	// Instances started later get the presences tracked so far
+	c := NewPresenceManager()
+	c.SetPubSub(ctx, ps, time.Hour, nil)
+	require.Eventually(t, func() bool { return len(c.GetPresenceState("room:1")["user:1"]) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "away", c.GetPresenceState("room:1")["user:1"][0]["status"])
+}
+
+// newClusterTestPresenceManager returns a presence manager that queues its messages without publishing them
+func newClusterTestPresenceManager() *PresenceManager {
+	pm := NewPresenceManager()
+	pm.nodeID = "node-a"
+	pm.outbox = make(chan presenceMessage, 10)
+	pm.heartbeatInterval = time.Second
+	return pm
+}
+
+func TestPresenceManager_ClusterGaps(t *testing.T) {
+	pm := newClusterTestPresenceManager()
+	now := time.Now()
+	entry := presenceEntry{Channel: "room:1", Key: "user:1", State: PresenceState{"status": "online"}}
+
+	changes := pm.handleMessage(presenceMessage{Type: presenceMessageTrack, Node: "node-b", Seq: 1, Presences: []presenceEntry{entry}}, now)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "join", changes[0].event)
+	assert.Equal(t, "node-b", changes[0].info.NodeID)
+	assert.Empty(t, pm.outbox)
+
+	// A missed change or a heartbeat that doesn't add up asks for the state, once per interval
+	pm.handleMessage(presenceMessage{Type: presenceMessageUntrack, Node: "node-b", Seq: 3, Presences: []presenceEntry{entry}}, now)
+	require.Len(t, pm.outbox, 1)
+	assert.Equal(t, presenceMessage{Type: presenceMessageSync, Node: "node-a", Target: "node-b"}, <-pm.outbox)
+	pm.handleMessage(presenceMessage{Type: presenceMessageHeartbeat, Node: "node-b", Seq: 3, Count: 1}, now)
+	assert.Empty(t, pm.outbox)
+	pm.handleMessage(presenceMessage{Type: presenceMessageHeartbeat, Node: "node-b", Seq: 3, Count: 1}, now.Add(time.Second))
+	assert.Len(t, pm.outbox, 1)
+
+	// The state replaces the presences of the instance
+	other := presenceEntry{Channel: "room:2", Key: "user:2"}
+	assert.Empty(t, pm.handleMessage(presenceMessage{Type: presenceMessageState, Node: "node-b", Seq: 4, Part: 0, Presences: []presenceEntry{entry}}, now))
+	changes = pm.handleMessage(presenceMessage{Type: presenceMessageState, Node: "node-b", Seq: 4, Part: 1, Presences: []presenceEntry{other}, Final: true}, now)
+	require.Len(t, changes, 2)
+	assert.Equal(t, 1, pm.GetChannelPresenceCount("room:1"))
+	assert.Equal(t, 1, pm.GetChannelPresenceCount("room:2"))
+	assert.Equal(t, uint64(4), pm.nodes["node-b"].seq)
+
+	// States with a missing part are dropped
+	assert.Empty(t, pm.handleMessage(presenceMessage{Type: presenceMessageState, Node: "node-b", Seq: 5, Part: 1, Final: true}, now))
+	assert.Equal(t, 1, pm.GetChannelPresenceCount("room:2"))
+
+	// Own messages are ignored
+	assert.Empty(t, pm.handleMessage(presenceMessage{Type: presenceMessageTrack, Node: "node-a", Seq: 1, Presences: []presenceEntry{entry}}, now))
+}
+
+func TestPresenceManager_ClusterExpiresNodes(t *testing.T) {
+	pm := newClusterTestPresenceManager()
+	now := time.Now()
+	pm.handleMessage(presenceMessage{Type: presenceMessageTrack, Node: "node-b", Seq: 1, Presences: []presenceEntry{{Channel: "room:1", Key: "user:1"}}}, now)
+
+	assert.Empty(t, pm.expireNodes(now.Add(3*time.Second)))
+	changes := pm.expireNodes(now.Add(3*time.Second + time.Millisecond))
+	require.Len(t, changes, 1)
+	assert.Equal(t, "leave", changes[0].event)
+	assert.Empty(t, pm.nodes)
+	assert.Equal(t, 0, pm.GetChannelPresenceCount("room:1"))
+}
+
+func TestSplitPresenceEntries(t *testing.T) {
+	var entries []presenceEntry
+	for i := 0; i < 100; i++ {
+		entries = append(entries, presenceEntry{Channel: "room:1", Key: "user", State: PresenceState{"bio": strings.Repeat("x", 200)}})
+	}
+
+	parts := splitPresenceEntries(entries, presenceMessageMaxSize)
+	require.Greater(t, len(parts), 1)
+	total := 0
+	for _, part := range parts {
+		payload, err := json.Marshal(presenceMessage{Type: presenceMessageState, Node: "a5c2b3e4-1234-4f56-8abc-0123456789ab", Seq: 1 << 40, Part: 10, Presences: part})
+		require.NoError(t, err)
+		assert.Less(t, len(payload), 8000, "fits a PostgreSQL NOTIFY")
+		total += len(part)
+	}
+	assert.Equal(t, 100, total)
+	assert.Empty(t, splitPresenceEntries(nil, presenceMessageMaxSize))
+}
+
+func TestCheckPresenceSize(t *testing.T) {
+	userID := "user-123"
+	info := &PresenceInfo{Key: "user:123", State: PresenceState{"status": "online"}, UserID: &userID, ConnID: "conn-1", JoinedAt: time.Now()}
+	require.NoError(t, checkPresenceSize("room:1", info))
+
+	info.State = PresenceState{"bio": strings.Repeat("x", presenceMessageMaxSize)}
+	err := checkPresenceSize("room:1", info)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "presence state is too large")
+
+	// The largest accepted presence still fits a change message in a PostgreSQL NOTIFY
+	info.State = PresenceState{"bio": ""}
+	encoded, err := json.Marshal(newPresenceEntry("room:1", info))
+	require.NoError(t, err)
+	info.State = PresenceState{"bio": strings.Repeat("x", presenceMessageMaxSize-len(encoded))}
+	require.NoError(t, checkPresenceSize("room:1", info))
+	payload, err := json.Marshal(presenceMessage{Type: presenceMessageTrack, Node: "a5c2b3e4-1234-4f56-8abc-0123456789ab", Seq: 1 << 40, Presences: []presenceEntry{newPresenceEntry("room:1", info)}})
+	require.NoError(t, err)
+	assert.Less(t, len(payload), 8000)
+}
@@ -0,0 +1,611 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// changeMessageMaxSize is the size above which change events are published in chunks, to stay
+// below the 8000 bytes of a PostgreSQL NOTIFY
+const changeMessageMaxSize = 7000
+
+// changeChunkSize is the part of an event carried by a chunk, which fits changeMessageMaxSize
+// once base64 encoded with the chunk header
+const changeChunkSize = 5000
+
+// changeChunkPrefix starts the payload of chunks, change events start with their type
+var changeChunkPrefix = []byte(`{"chunk":`)
+
+// changeChunk is a part of a change event too large to be published in one message
+type changeChunk struct {
+	ID    string `json:"chunk"` // Event the chunk belongs to, first so chunks start with changeChunkPrefix
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+	Data  []byte `json:"data"`
+}
+
+// replicationStatusInterval is how often the consumed position is reported to the server,
+// which also serves as a heartbeat
+const replicationStatusInterval = 10 * time.Second
+
+// realtimeTablesQuery lists the tables with realtime enabled, with their excluded columns
+const realtimeTablesQuery = `
+SELECT schema_name, table_name, COALESCE(excluded_columns, '{}')
+FROM realtime.schema_registry
+WHERE realtime_enabled AND to_regclass(format('%I.%I', schema_name, table_name)) IS NOT NULL`
+
+// changeDecoder turns pgoutput messages into the change events of the realtime tables.
+// Events are held back until their transaction commits.
+type changeDecoder struct {
+	typeMap   *pgtype.Map
+	relations map[uint32]*pgoutputRelation
+	tables    map[string]map[string]bool // Realtime tables by schema.table, with their excluded columns
+	pending   []*ChangeEvent
+	inTx      bool
+}
+
+// newChangeDecoder creates a decoder of the changes to the given realtime tables
+func newChangeDecoder(tables map[string]map[string]bool) *changeDecoder {
+	return &changeDecoder{
+		typeMap:   pgtype.NewMap(),
+		relations: make(map[uint32]*pgoutputRelation),
+		tables:    tables,
+	}
+}
+
+// decode handles a pgoutput message. At the end of a transaction, it returns the transaction's
+// events and its commit.
+func (d *changeDecoder) decode(data []byte) ([]*ChangeEvent, *pgoutputCommit, error) {
+	msg, err := parsePgoutputMessage(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch msg := msg.(type) {
+	case *pgoutputRelation:
+		d.relations[msg.id] = msg
+
+	case *pgoutputBegin:
+		d.inTx = true
+		d.pending = nil
+
+	case *pgoutputCommit:
+		events := d.pending
+		d.inTx = false
+		d.pending = nil
+		return events, msg, nil
+
+	case *pgoutputChange:
+		rel := d.relations[msg.relationID]
+		if rel == nil {
+			return nil, nil, fmt.Errorf("change to unknown relation %d", msg.relationID)
+		}
+		// The registry is part of the publication so exclusions apply from the next change
+		if rel.schema == "realtime" && rel.name == "schema_registry" {
+			d.updateRegistry(rel, msg)
+			break
+		}
+		excluded, ok := d.tables[rel.schema+"."+rel.name]
+		if !ok {
+			break
+		}
+
+		event := &ChangeEvent{Type: msg.op, Schema: rel.schema, Table: rel.name}
+		if msg.oldTuple != nil {
+			event.OldRecord = d.record(rel, msg.oldTuple, msg.oldIsKey, nil, excluded)
+		}
+		if msg.newTuple != nil {
+			event.Record = d.record(rel, msg.newTuple, false, event.OldRecord, excluded)
+		}
+		d.pending = append(d.pending, event)
+	}
+
+	return nil, nil, nil
+}
+
+// record converts a row to the JSON object the triggers send. Unchanged TOASTed values aren't
+// sent in updates and are taken from the old row instead, which realtime tables have in full.
+func (d *changeDecoder) record(rel *pgoutputRelation, tuple []tupleValue, keyOnly bool, old map[string]interface{}, excluded map[string]bool) map[string]interface{} {
+	record := make(map[string]interface{}, len(tuple))
+	for i, value := range tuple {
+		if i >= len(rel.columns) {
+			break
+		}
+		col := rel.columns[i]
+		if excluded[col.name] || (keyOnly && !col.key) {
+			continue
+		}
+		switch value.kind {
+		case 'n':
+			record[col.name] = nil
+		case 'u':
+			if v, ok := old[col.name]; ok {
+				record[col.name] = v
+			}
+		default:
+			record[col.name] = pgTextToJSON(d.typeMap, col.typeOID, string(value.data))
+		}
+	}
+	return record
+}
+
+// updateRegistry applies a change to realtime.schema_registry to the realtime tables
+func (d *changeDecoder) updateRegistry(rel *pgoutputRelation, change *pgoutputChange) {
+	if change.oldTuple != nil {
+		old := d.record(rel, change.oldTuple, change.oldIsKey, nil, nil)
+		schema, _ := old["schema_name"].(string)
+		table, _ := old["table_name"].(string)
+		delete(d.tables, schema+"."+table)
+	}
+	if change.newTuple == nil {
+		return
+	}
+
+	row := d.record(rel, change.newTuple, false, nil, nil)
+	if enabled, _ := row["realtime_enabled"].(bool); !enabled {
+		return
+	}
+	schema, _ := row["schema_name"].(string)
+	table, _ := row["table_name"].(string)
+	excluded := make(map[string]bool)
+	if columns, ok := row["excluded_columns"].([]interface{}); ok {
+		for _, col := range columns {
+			if name, ok := col.(string); ok {
+				excluded[name] = true
+			}
+		}
+	}
+	d.tables[schema+"."+table] = excluded
+}
+
+// runReplication manages the logical replication stream with reconnection logic. Only one
+// instance can consume the slot, the others stand by until it's released.
+func (lp *ListenerPool) runReplication() {
+	defer lp.connWg.Done()
+
+	for {
+		err := lp.replicate()
+		if lp.ctx.Err() != nil {
+			return
+		}
+
+		retryInterval := lp.config.RetryInterval
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "55006" {
+			// The slot keeps the changes until this instance takes over, so there's no hurry
+			log.Debug().Str("slot", lp.config.ReplicationSlot).Msg("Replication slot in use by another instance, standing by")
+			retryInterval = replicationStatusInterval
+		} else {
+			atomic.AddUint64(&lp.connectionFailures, 1)
+			log.Error().Err(err).Str("slot", lp.config.ReplicationSlot).Msg("Replication error, reconnecting...")
+		}
+
+		select {
+		case <-time.After(retryInterval):
+			atomic.AddUint64(&lp.reconnections, 1)
+		case <-lp.ctx.Done():
+			return
+		}
+	}
+}
+
+// replicate streams the changes of the replication slot until the pool stops or the
+// connection fails. Changes are confirmed to the server once handed over, so the slot keeps
+// the WAL of every change that wasn't, and streaming resumes from there.
+func (lp *ListenerPool) replicate() error {
+	tables, err := lp.syncPublication(lp.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to sync publication: %w", err)
+	}
+	if err := lp.ensureReplicationSlot(lp.ctx); err != nil {
+		return fmt.Errorf("failed to create replication slot: %w", err)
+	}
+
+	connConfig := lp.pool.Config().ConnConfig.Config.Copy()
+	connConfig.RuntimeParams["replication"] = "database"
+	connConfig.RuntimeParams["datestyle"] = "ISO"
+	conn, err := pgconn.ConnectConfig(lp.ctx, connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	atomic.AddInt32(&lp.activeConnections, 1)
+	defer atomic.AddInt32(&lp.activeConnections, -1)
+
+	// Starting at 0/0 resumes after the position last confirmed for the slot
+	startSQL := fmt.Sprintf("START_REPLICATION SLOT %s LOGICAL 0/0 (proto_version '1', publication_names '%s')",
+		pgx.Identifier{lp.config.ReplicationSlot}.Sanitize(), lp.config.Publication)
+	conn.Frontend().Send(&pgproto3.Query{String: startSQL})
+	if err := conn.Frontend().Flush(); err != nil {
+		return err
+	}
+	for started := false; !started; {
+		msg, err := conn.ReceiveMessage(lp.ctx)
+		if err != nil {
+			return err
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			started = true
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+		}
+	}
+
+	log.Info().
+		Str("slot", lp.config.ReplicationSlot).
+		Str("publication", lp.config.Publication).
+		Int("tables", len(tables)).
+		Msg("Logical replication started")
+
+	// Without pub/sub, the changes are only delivered while streaming
+	if lp.pubsub == nil {
+		if atomic.AddInt32(&lp.listening, 1) == 1 {
+			lp.notifyListening(true)
+		}
+		defer func() {
+			if atomic.AddInt32(&lp.listening, -1) == 0 {
+				lp.notifyListening(false)
+			}
+		}()
+	}
+
+	decoder := newChangeDecoder(tables)
+	var confirmed lsn
+	nextStatus := time.Now()
+
+	for {
+		if !time.Now().Before(nextStatus) {
+			if err := sendStandbyStatus(conn, confirmed); err != nil {
+				return err
+			}
+			nextStatus = time.Now().Add(replicationStatusInterval)
+		}
+
+		receiveCtx, cancel := context.WithDeadline(lp.ctx, nextStatus)
+		msg, err := conn.ReceiveMessage(receiveCtx)
+		cancel()
+		if err != nil {
+			if lp.ctx.Err() != nil {
+				_ = sendStandbyStatus(conn, confirmed)
+				return nil
+			}
+			if pgconn.Timeout(err) {
+				continue
+			}
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+
+		case *pgproto3.CopyData:
+			if len(msg.Data) == 0 {
+				continue
+			}
+			switch msg.Data[0] {
+			case primaryKeepaliveByteID:
+				keepalive, err := parsePrimaryKeepalive(msg.Data[1:])
+				if err != nil {
+					return err
+				}
+				// Everything sent before the keepalive was handled, unless a transaction is open
+				if !decoder.inTx && keepalive.walEnd > confirmed {
+					confirmed = keepalive.walEnd
+				}
+				if keepalive.replyRequested {
+					nextStatus = time.Now()
+				}
+
+			case xLogDataByteID:
+				xld, err := parseXLogData(msg.Data[1:])
+				if err != nil {
+					return err
+				}
+				events, commit, err := decoder.decode(xld.data)
+				if err != nil {
+					return err
+				}
+				if commit == nil {
+					continue
+				}
+				// On failure the commit isn't confirmed, and the transaction is streamed again on
+				// reconnect, so its events may be handed over twice but are never lost
+				for _, event := range events {
+					if err := lp.handOffChange(event); err != nil {
+						return err
+					}
+				}
+				if commit.endLSN > confirmed {
+					confirmed = commit.endLSN
+				}
+			}
+		}
+	}
+}
+
+// handOffChange passes a change event to the workers, those of all instances with pub/sub.
+// The workers process notifications, so events take the form of a trigger's notification.
+func (lp *ListenerPool) handOffChange(event *ChangeEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if lp.pubsub != nil {
+		if err := lp.publishChange(payload); err != nil {
+			return fmt.Errorf("failed to publish change to %s.%s: %w", event.Schema, event.Table, err)
+		}
+		return nil
+	}
+
+	atomic.AddUint64(&lp.notificationsReceived, 1)
+	return lp.enqueue(&pgconn.Notification{Channel: pubsub.ChangesChannel, Payload: string(payload)})
+}
+
+// publishChange publishes the payload of a change event, in chunks when it's too large for a
+// single message
+func (lp *ListenerPool) publishChange(payload []byte) error {
+	if len(payload) <= changeMessageMaxSize {
+		return lp.pubsub.Publish(lp.ctx, pubsub.ChangesChannel, payload)
+	}
+
+	id := uuid.NewString()
+	count := (len(payload) + changeChunkSize - 1) / changeChunkSize
+	for i := 0; i < count; i++ {
+		end := min((i+1)*changeChunkSize, len(payload))
+		chunk, err := json.Marshal(changeChunk{ID: id, Index: i, Count: count, Data: payload[i*changeChunkSize : end]})
+		if err != nil {
+			return err
+		}
+		if err := lp.pubsub.Publish(lp.ctx, pubsub.ChangesChannel, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeAssembler puts the chunks of change events back together. The chunks of an event are
+// published in order by the instance consuming the slot, so a chunk out of sequence means
+// others were lost.
+type changeAssembler struct {
+	id    string
+	parts [][]byte
+}
+
+// add adds a chunk and returns the payload of its event once complete. missed is true when an
+// incomplete event was dropped.
+func (a *changeAssembler) add(chunk *changeChunk) (payload []byte, missed bool) {
+	if chunk.ID != a.id || chunk.Index != len(a.parts) {
+		missed = a.reset()
+		if chunk.Index != 0 {
+			return nil, true
+		}
+		a.id = chunk.ID
+	}
+
+	a.parts = append(a.parts, chunk.Data)
+	if len(a.parts) < chunk.Count {
+		return nil, missed
+	}
+	payload = bytes.Join(a.parts, nil)
+	a.reset()
+	return payload, missed
+}
+
+// reset drops the event being assembled and returns whether there was one
+func (a *changeAssembler) reset() bool {
+	pending := a.id != ""
+	a.id, a.parts = "", nil
+	return pending
+}
+
+// enqueue waits for room in the worker queue; changes from the slot are never dropped
+func (lp *ListenerPool) enqueue(notification *pgconn.Notification) error {
+	select {
+	case lp.notificationCh <- notification:
+		return nil
+	case <-lp.ctx.Done():
+		return lp.ctx.Err()
+	}
+}
+
+// listenChangesPubSub feeds the workers with the change events published by the instance
+// consuming the replication slot.
+func (lp *ListenerPool) listenChangesPubSub() {
+	defer lp.connWg.Done()
+
+	msgChan, err := lp.pubsub.Subscribe(lp.ctx, pubsub.ChangesChannel)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to changes channel")
+		return
+	}
+
+	if atomic.AddInt32(&lp.listening, 1) == 1 {
+		lp.notifyListening(true)
+	}
+	defer func() {
+		if atomic.AddInt32(&lp.listening, -1) == 0 {
+			lp.notifyListening(false)
+		}
+	}()
+
+	var assembler changeAssembler
+	for {
+		select {
+		case <-lp.ctx.Done():
+			return
+		case msg, ok := <-msgChan:
+			if !ok {
+				return
+			}
+
+			payload := msg.Payload
+			var missed bool
+			if bytes.HasPrefix(payload, changeChunkPrefix) {
+				var chunk changeChunk
+				if err := json.Unmarshal(payload, &chunk); err != nil {
+					log.Error().Err(err).Msg("Failed to parse change event chunk")
+					assembler.reset()
+					missed = true
+					payload = nil
+				} else {
+					payload, missed = assembler.add(&chunk)
+				}
+			} else {
+				missed = assembler.reset()
+			}
+			if missed {
+				log.Warn().Msg("Change event chunks missing, dropping the incomplete event")
+				for _, observer := range lp.observers {
+					observer.ChangesMissed()
+				}
+			}
+			if payload == nil {
+				continue
+			}
+
+			atomic.AddUint64(&lp.notificationsReceived, 1)
+			if err := lp.enqueue(&pgconn.Notification{Channel: msg.Channel, Payload: string(payload)}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// syncPublication makes the publication contain the realtime tables, and the registry to
+// follow their changes, and returns the realtime tables. Their replica identity is set to
+// FULL so updates and deletes carry the old row, like with triggers.
+func (lp *ListenerPool) syncPublication(ctx context.Context) (map[string]map[string]bool, error) {
+	publication := pgx.Identifier{lp.config.Publication}.Sanitize()
+
+	var exists bool
+	if err := lp.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_publication WHERE pubname = $1)", lp.config.Publication).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		if _, err := lp.pool.Exec(ctx, "ALTER TABLE realtime.schema_registry REPLICA IDENTITY FULL"); err != nil {
+			return nil, err
+		}
+		if _, err := lp.pool.Exec(ctx, "CREATE PUBLICATION "+publication+" FOR TABLE realtime.schema_registry"); err != nil && !isDuplicateObject(err) {
+			return nil, err
+		}
+	}
+
+	tables := make(map[string]map[string]bool)
+	registered := make(map[string]pgx.Identifier)
+	rows, err := lp.pool.Query(ctx, realtimeTablesQuery)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var schema, table string
+		var excludedColumns []string
+		if err := rows.Scan(&schema, &table, &excludedColumns); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		excluded := make(map[string]bool, len(excludedColumns))
+		for _, col := range excludedColumns {
+			excluded[col] = true
+		}
+		tables[schema+"."+table] = excluded
+		registered[schema+"."+table] = pgx.Identifier{schema, table}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	published := make(map[string]pgx.Identifier)
+	rows, err = lp.pool.Query(ctx, "SELECT schemaname, tablename FROM pg_publication_tables WHERE pubname = $1", lp.config.Publication)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		published[schema+"."+table] = pgx.Identifier{schema, table}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, table := range registered {
+		if _, ok := published[name]; ok {
+			continue
+		}
+		ident := table.Sanitize()
+		if _, err := lp.pool.Exec(ctx, "ALTER TABLE "+ident+" REPLICA IDENTITY FULL"); err != nil {
+			return nil, err
+		}
+		if _, err := lp.pool.Exec(ctx, "ALTER PUBLICATION "+publication+" ADD TABLE "+ident); err != nil && !isDuplicateObject(err) {
+			return nil, err
+		}
+	}
+	for name, ident := range published {
+		if _, ok := tables[name]; ok || name == "realtime.schema_registry" {
+			continue
+		}
+		if _, err := lp.pool.Exec(ctx, "ALTER PUBLICATION "+publication+" DROP TABLE "+ident.Sanitize()); err != nil && !isUndefinedObject(err) {
+			return nil, err
+		}
+	}
+
+	return tables, nil
+}
+
+// ensureReplicationSlot creates the replication slot if it doesn't exist
+func (lp *ListenerPool) ensureReplicationSlot(ctx context.Context) error {
+	var exists bool
+	if err := lp.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", lp.config.ReplicationSlot).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	_, err := lp.pool.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'pgoutput')", lp.config.ReplicationSlot)
+	if err != nil && !isDuplicateObject(err) {
+		return err
+	}
+	log.Info().Str("slot", lp.config.ReplicationSlot).Msg("Created logical replication slot")
+	return nil
+}
+
+// sendStandbyStatus reports the position up to which changes were handled
+func sendStandbyStatus(conn *pgconn.PgConn, pos lsn) error {
+	conn.Frontend().Send(&pgproto3.CopyData{Data: encodeStandbyStatusUpdate(pos, time.Now())})
+	return conn.Frontend().Flush()
+}
+
+// isDuplicateObject tells whether an error is PostgreSQL's duplicate_object, raised when
+// another instance created the object first
+func isDuplicateObject(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42710"
+}
+
+// isUndefinedObject tells whether an error is PostgreSQL's undefined_object
+func isUndefinedObject(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42704"
+}
@@ -0,0 +1,259 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/pubsub"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postsRelation is the relation message of a public.posts table
+func postsRelation() []byte {
+	return relationMessage(16384, "public", "posts",
+		pgoutputColumn{name: "id", typeOID: pgtype.Int4OID, key: true},
+		pgoutputColumn{name: "body", typeOID: pgtype.TextOID},
+		pgoutputColumn{name: "secret", typeOID: pgtype.TextOID},
+	)
+}
+
+// registryRelation is the relation message of realtime.schema_registry
+func registryRelation() []byte {
+	return relationMessage(16000, "realtime", "schema_registry",
+		pgoutputColumn{name: "id", typeOID: pgtype.Int4OID, key: true},
+		pgoutputColumn{name: "schema_name", typeOID: pgtype.TextOID},
+		pgoutputColumn{name: "table_name", typeOID: pgtype.TextOID},
+		pgoutputColumn{name: "realtime_enabled", typeOID: pgtype.BoolOID},
+		pgoutputColumn{name: "excluded_columns", typeOID: pgtype.TextArrayOID},
+	)
+}
+
+// decodeAll decodes messages and returns the events and end LSN of the last commit
+func decodeAll(t *testing.T, d *changeDecoder, messages ...[]byte) ([]*ChangeEvent, lsn) {
+	t.Helper()
+	var events []*ChangeEvent
+	var end lsn
+	for _, msg := range messages {
+		committed, commit, err := d.decode(msg)
+		require.NoError(t, err)
+		if commit != nil {
+			events = append(events, committed...)
+			end = commit.endLSN
+		}
+	}
+	return events, end
+}
+
+func TestChangeDecoder_Events(t *testing.T) {
+	d := newChangeDecoder(map[string]map[string]bool{"public.posts": {"secret": true}})
+
+	events, end := decodeAll(t, d,
+		postsRelation(),
+		beginMessage(),
+		pgoutputBuilder{'I'}.uint32(16384).byte('N').tuple("1", "hello", "s3cr3t"),
+		pgoutputBuilder{'U'}.uint32(16384).byte('O').tuple("1", "hello", "s3cr3t").byte('N').tuple("1", "\x00u", nil),
+		pgoutputBuilder{'D'}.uint32(16384).byte('O').tuple("1", "hello", nil),
+		commitMessage(0x208),
+	)
+	assert.Equal(t, lsn(0x208), end)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, &ChangeEvent{
+		Type:   "INSERT",
+		Schema: "public",
+		Table:  "posts",
+		Record: map[string]interface{}{"id": json.RawMessage("1"), "body": "hello"},
+	}, events[0])
+
+	// The unchanged TOASTed body is taken from the old row
+	assert.Equal(t, "UPDATE", events[1].Type)
+	assert.Equal(t, map[string]interface{}{"id": json.RawMessage("1"), "body": "hello"}, events[1].Record)
+	assert.Equal(t, map[string]interface{}{"id": json.RawMessage("1"), "body": "hello"}, events[1].OldRecord)
+
+	assert.Equal(t, "DELETE", events[2].Type)
+	assert.Nil(t, events[2].Record)
+	assert.Equal(t, map[string]interface{}{"id": json.RawMessage("1"), "body": "hello"}, events[2].OldRecord)
+}
+
+func TestChangeDecoder_HoldsEventsUntilCommit(t *testing.T) {
+	d := newChangeDecoder(map[string]map[string]bool{"public.posts": {}})
+
+	events, _ := decodeAll(t, d,
+		postsRelation(),
+		beginMessage(),
+		pgoutputBuilder{'I'}.uint32(16384).byte('N').tuple("1", "hello", nil),
+	)
+	assert.Empty(t, events)
+	assert.True(t, d.inTx)
+
+	events, _ = decodeAll(t, d, commitMessage(0x300))
+	assert.Len(t, events, 1)
+	assert.False(t, d.inTx)
+}
+
+func TestChangeDecoder_KeyOnlyOldRow(t *testing.T) {
+	d := newChangeDecoder(map[string]map[string]bool{"public.posts": {}})
+
+	events, _ := decodeAll(t, d,
+		postsRelation(),
+		beginMessage(),
+		pgoutputBuilder{'D'}.uint32(16384).byte('K').tuple("7", nil, nil),
+		commitMessage(0x208),
+	)
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]interface{}{"id": json.RawMessage("7")}, events[0].OldRecord)
+}
+
+func TestChangeDecoder_Registry(t *testing.T) {
+	d := newChangeDecoder(map[string]map[string]bool{})
+
+	insertPost := pgoutputBuilder{'I'}.uint32(16384).byte('N').tuple("1", "hello", "s3cr3t")
+
+	// Changes to tables without realtime are skipped
+	events, _ := decodeAll(t, d, registryRelation(), postsRelation(), beginMessage(), insertPost, commitMessage(0x100))
+	assert.Empty(t, events)
+
+	// Enabling realtime applies from the next change, with the excluded columns
+	events, _ = decodeAll(t, d,
+		beginMessage(),
+		pgoutputBuilder{'I'}.uint32(16000).byte('N').tuple("5", "public", "posts", "t", "{secret}"),
+		insertPost,
+		commitMessage(0x200),
+	)
+	require.Len(t, events, 1, "registry changes aren't events")
+	assert.Equal(t, map[string]interface{}{"id": json.RawMessage("1"), "body": "hello"}, events[0].Record)
+
+	// Disabling it stops the events
+	events, _ = decodeAll(t, d,
+		beginMessage(),
+		pgoutputBuilder{'U'}.uint32(16000).
+			byte('O').tuple("5", "public", "posts", "t", "{secret}").
+			byte('N').tuple("5", "public", "posts", "f", "{secret}"),
+		insertPost,
+		commitMessage(0x300),
+	)
+	assert.Empty(t, events)
+	assert.Empty(t, d.tables)
+}
+
+func TestChangeDecoder_UnknownRelation(t *testing.T) {
+	d := newChangeDecoder(map[string]map[string]bool{})
+	_, _, err := d.decode(pgoutputBuilder{'I'}.uint32(1).byte('N').tuple("1"))
+	assert.EqualError(t, err, "change to unknown relation 1")
+}
+
+func TestListenerPool_HandOffChange(t *testing.T) {
+	lp := NewListenerPool(nil, nil, nil, nil, ListenerPoolConfig{ChangeSource: ChangeSourceLogical})
+	assert.Equal(t, "fluxbase_realtime", lp.config.ReplicationSlot)
+	assert.Equal(t, "fluxbase_realtime", lp.config.Publication)
+
+	// Without pub/sub, events go straight to the workers as notifications
+	err := lp.handOffChange(&ChangeEvent{
+		Type:   "INSERT",
+		Schema: "public",
+		Table:  "posts",
+		Record: map[string]interface{}{"id": json.RawMessage("1"), "created_at": "2024-01-02T03:04:05+00:00"},
+	})
+	require.NoError(t, err)
+	require.Len(t, lp.notificationCh, 1)
+
+	notification := <-lp.notificationCh
+	var event ChangeEvent
+	require.NoError(t, json.Unmarshal([]byte(notification.Payload), &event))
+	assert.Equal(t, "posts", event.Table)
+	assert.Equal(t, float64(1), event.Record["id"], "same values as trigger notifications")
+	assert.Equal(t, uint64(1), lp.GetMetrics().NotificationsReceived)
+}
+
+// notifyPubSub is a local pub/sub with the payload limit of PostgreSQL NOTIFY
+type notifyPubSub struct {
+	*pubsub.LocalPubSub
+	err error
+}
+
+func (p *notifyPubSub) Publish(ctx context.Context, channel string, payload []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	if len(payload) > 8000 {
+		return fmt.Errorf("payload too large for PostgreSQL NOTIFY: %d bytes (max 8000)", len(payload))
+	}
+	return p.LocalPubSub.Publish(ctx, channel, payload)
+}
+
+func TestListenerPool_HandOffLargeChange(t *testing.T) {
+	ps := &notifyPubSub{LocalPubSub: pubsub.NewLocalPubSub()}
+	lp := NewListenerPool(nil, nil, nil, ps, ListenerPoolConfig{ChangeSource: ChangeSourceLogical})
+	observer := &recordingObserver{}
+	lp.AddChangeObserver(observer)
+	defer lp.cancel()
+
+	lp.connWg.Add(1)
+	go lp.listenChangesPubSub()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&lp.listening) == 1 }, time.Second, 10*time.Millisecond)
+
+	body := strings.Repeat("a\"é", 5000) // Over 8KB, more once escaped
+	require.NoError(t, lp.handOffChange(&ChangeEvent{
+		Type:   "INSERT",
+		Schema: "public",
+		Table:  "posts",
+		Record: map[string]interface{}{"id": json.RawMessage("1"), "body": body},
+	}))
+	require.NoError(t, lp.handOffChange(&ChangeEvent{Type: "DELETE", Schema: "public", Table: "posts"}))
+
+	var events []ChangeEvent
+	for len(events) < 2 {
+		select {
+		case notification := <-lp.notificationCh:
+			var event ChangeEvent
+			require.NoError(t, json.Unmarshal([]byte(notification.Payload), &event))
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatal("change events not received")
+		}
+	}
+	assert.Equal(t, body, events[0].Record["body"])
+	assert.Equal(t, "DELETE", events[1].Type, "events keep their order")
+	assert.Zero(t, observer.missed)
+}
+
+func TestListenerPool_HandOffChangePublishError(t *testing.T) {
+	ps := &notifyPubSub{LocalPubSub: pubsub.NewLocalPubSub(), err: errors.New("connection refused")}
+	lp := NewListenerPool(nil, nil, nil, ps, ListenerPoolConfig{ChangeSource: ChangeSourceLogical})
+
+	// The error stops the stream, so the change isn't confirmed and is streamed again
+	err := lp.handOffChange(&ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts"})
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestChangeAssembler(t *testing.T) {
+	var a changeAssembler
+
+	payload, missed := a.add(&changeChunk{ID: "a", Index: 0, Count: 2, Data: []byte("hel")})
+	assert.Nil(t, payload)
+	assert.False(t, missed)
+	payload, missed = a.add(&changeChunk{ID: "a", Index: 1, Count: 2, Data: []byte("lo")})
+	assert.Equal(t, "hello", string(payload))
+	assert.False(t, missed)
+
+	// A lost chunk drops the incomplete event
+	a.add(&changeChunk{ID: "b", Index: 0, Count: 3, Data: []byte("x")})
+	payload, missed = a.add(&changeChunk{ID: "b", Index: 2, Count: 3, Data: []byte("z")})
+	assert.Nil(t, payload)
+	assert.True(t, missed)
+
+	// So does the start of another event
+	a.add(&changeChunk{ID: "c", Index: 0, Count: 2, Data: []byte("x")})
+	payload, missed = a.add(&changeChunk{ID: "d", Index: 0, Count: 1, Data: []byte("d")})
+	assert.Equal(t, "d", string(payload))
+	assert.True(t, missed)
+	assert.False(t, a.reset())
+}
@@ -150,6 +150,20 @@ type SubscriptionDB interface {
 	CheckJobOwnership(ctx context.Context, execID, userID uuid.UUID) (isOwner bool, exists bool, err error)
 	// CheckFunctionOwnership checks if a user owns a function execution.
 	CheckFunctionOwnership(ctx context.Context, execID, userID uuid.UUID) (isOwner bool, exists bool, err error)
+	// MatchesFilter checks a change record against a SQL condition on its columns, with arguments
+	// numbered from $2. It evaluates the filter conditions that need PostgreSQL, like full-text
+	// search, range and PostGIS operators.
+	MatchesFilter(ctx context.Context, schema, table string, record map[string]interface{}, condition string, args []interface{}) (bool, error)
+	// CheckChannelAccess evaluates the SQL condition of a channel policy as the user, with the
+	// role and claims RLS policies see, and arguments numbered from $1.
+	CheckChannelAccess(ctx context.Context, role string, claims map[string]interface{}, condition string, args []interface{}) (bool, error)
+}
+
+// ColumnPolicyApplier masks change records according to per-role column policies.
+// It is implemented by the database schema cache.
+type ColumnPolicyApplier interface {
+	// ApplyColumnPolicies returns a copy of the row as the role may see it, without modifying row.
+	ApplyColumnPolicies(ctx context.Context, schema, table, role string, row map[string]interface{}) (map[string]interface{}, error)
 }
 
 // pgxSubscriptionDB implements SubscriptionDB using a pgxpool.Pool.
@@ -178,30 +192,54 @@ func (db *pgxSubscriptionDB) IsTableRealtimeEnabled(ctx context.Context, schema,
 }
 
 func (db *pgxSubscriptionDB) CheckRLSAccess(ctx context.Context, schema, table, role string, claims map[string]interface{}, recordID interface{}) (bool, error) {
-	conn, err := db.pool.Acquire(ctx)
+	tx, err := db.beginAsUser(ctx, role, claims)
 	if err != nil {
 		return false, err
 	}
-	defer conn.Release()
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Start a transaction for SET LOCAL (required by PostgreSQL)
-	tx, err := conn.Begin(ctx)
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s WHERE id = $1", schema, table)
+	err = tx.QueryRow(ctx, query, recordID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (db *pgxSubscriptionDB) CheckChannelAccess(ctx context.Context, role string, claims map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	tx, err := db.beginAsUser(ctx, role, claims)
 	if err != nil {
 		return false, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Use provided claims, ensuring role is set
-	jwtClaims := claims
-	if jwtClaims == nil {
-		jwtClaims = make(map[string]interface{})
+	var allowed bool
+	err = tx.QueryRow(ctx, fmt.Sprintf("SELECT COALESCE((%s), false)", condition), args...).Scan(&allowed)
+	return allowed, err
+}
+
+// beginAsUser starts a transaction with the database role and JWT claims of a user, so RLS
+// policies apply as for their REST requests. The caller must roll it back.
+func (db *pgxSubscriptionDB) beginAsUser(ctx context.Context, role string, claims map[string]interface{}) (pgx.Tx, error) {
+	// Start a transaction for SET LOCAL (required by PostgreSQL)
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use provided claims, ensuring role is set for RLS policies that use it
+	jwtClaims := make(map[string]interface{}, len(claims)+1)
+	for k, v := range claims {
+		jwtClaims[k] = v
 	}
-	// Ensure role is set in claims for RLS policies that use it
 	jwtClaims["role"] = role
 
 	jwtClaimsJSON, err := json.Marshal(jwtClaims)
 	if err != nil {
-		return false, err
+		_ = tx.Rollback(ctx)
+		return nil, err
 	}
 
 	// Map application role to database role
@@ -213,24 +251,30 @@ func (db *pgxSubscriptionDB) CheckRLSAccess(ctx context.Context, schema, table,
 		dbRole = "anon"
 	}
 
-	_, err = tx.Exec(ctx, fmt.Sprintf("SET LOCAL ROLE %s", dbRole))
-	if err != nil {
-		return false, err
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL ROLE %s", dbRole)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
 	}
 
-	_, err = tx.Exec(ctx, "SELECT set_config('request.jwt.claims', $1, true)", string(jwtClaimsJSON))
-	if err != nil {
-		return false, err
+	if _, err := tx.Exec(ctx, "SELECT set_config('request.jwt.claims', $1, true)", string(jwtClaimsJSON)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
 	}
 
-	var count int
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s WHERE id = $1", schema, table)
-	err = tx.QueryRow(ctx, query, recordID).Scan(&count)
+	return tx, nil
+}
+
+func (db *pgxSubscriptionDB) MatchesFilter(ctx context.Context, schema, table string, record map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	recordJSON, err := json.Marshal(record)
 	if err != nil {
 		return false, err
 	}
 
-	return count > 0, nil
+	var matches bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM jsonb_populate_record(NULL::%s, $1::jsonb) WHERE %s)",
+		pgx.Identifier{schema, table}.Sanitize(), condition)
+	err = db.pool.QueryRow(ctx, query, append([]interface{}{string(recordJSON)}, args...)...).Scan(&matches)
+	return matches, err
 }
 
 func (db *pgxSubscriptionDB) CheckRPCOwnership(ctx context.Context, execID, userID uuid.UUID) (bool, bool, error) {
@@ -292,8 +336,14 @@ type Subscription struct {
 	Table  string
 	Schema string
 	Event  string  // INSERT, UPDATE, DELETE, or * for all
-	Filter *Filter // Supabase-compatible filter (column=operator.value)
+	Filter *Filter // Filter in the REST API filter grammar (column=operator.value&or=(...))
 	ConnID string  // Connection ID this subscription belongs to
+
+	// Live events held back while a resumed subscription replays the events it missed
+	replayMu     sync.Mutex
+	replaying    bool
+	replayBuffer []*ChangeEvent
+	replayedTo   uint64 // Position of the last replayed event; later live events up to it are skipped
 }
 
 // copyClaims creates a shallow copy of claims map to prevent concurrent map access during logging.
@@ -343,6 +393,8 @@ type SubscriptionManager struct {
 	execLogSubs   map[string]map[string]bool      // execution ID -> subscription IDs
 	allLogsSubs   map[string]*AllLogsSubscription // subscription ID -> all-logs subscription
 	rlsCache      *rlsCache                       // RLS check result cache
+	columnPolicy  ColumnPolicyApplier             // Optional per-role column masking
+	changeLog     *ChangeLog                      // Optional log of recent changes for resuming subscriptions
 	mu            sync.RWMutex
 }
 
@@ -367,6 +419,43 @@ func NewSubscriptionManagerWithConfig(db SubscriptionDB, cacheConfig RLSCacheCon
 	}
 }
 
+// SetColumnPolicyApplier sets the column policies applied to change events before delivery
+func (sm *SubscriptionManager) SetColumnPolicyApplier(applier ColumnPolicyApplier) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.columnPolicy = applier
+}
+
+// SetChangeLog sets the log that positions change events and retains them for resumed subscriptions
+func (sm *SubscriptionManager) SetChangeLog(changeLog *ChangeLog) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.changeLog = changeLog
+}
+
+// RecordChange gives a change event its position in the change log, if there is one
+func (sm *SubscriptionManager) RecordChange(event *ChangeEvent) {
+	sm.mu.RLock()
+	changeLog := sm.changeLog
+	sm.mu.RUnlock()
+
+	if changeLog != nil {
+		changeLog.Append(event)
+	}
+}
+
+// ChangePosition returns the position of the latest change event, 0 without a change log
+func (sm *SubscriptionManager) ChangePosition() uint64 {
+	sm.mu.RLock()
+	changeLog := sm.changeLog
+	sm.mu.RUnlock()
+
+	if changeLog == nil {
+		return 0
+	}
+	return changeLog.Position()
+}
+
 // CreateSubscription creates a new RLS-aware subscription
 func (sm *SubscriptionManager) CreateSubscription(
 	subID string,
@@ -378,6 +467,108 @@ func (sm *SubscriptionManager) CreateSubscription(
 	table string,
 	event string,
 	filterStr string,
+) (*Subscription, error) {
+	return sm.createSubscription(subID, connID, userID, role, claims, schema, table, event, filterStr, false)
+}
+
+// Replay holds the events a resumed subscription missed
+type Replay struct {
+	Events         []*ChangeEvent // Missed events the subscriber may see, oldest first
+	Position       uint64         // Position of the latest change event when resuming
+	ResyncRequired bool           // The missed events are no longer retained, the subscriber has to refetch
+}
+
+// ResumeSubscription creates a subscription like CreateSubscription that resumes after the change
+// at position since. It returns the retained events after since that the subscriber may see,
+// with RLS checked now. Live events are held back until FinishReplay is called, after the
+// replayed events were sent, so none is missed or sent twice.
+func (sm *SubscriptionManager) ResumeSubscription(
+	ctx context.Context,
+	since uint64,
+	subID string,
+	connID string,
+	userID string,
+	role string,
+	claims map[string]interface{},
+	schema string,
+	table string,
+	event string,
+	filterStr string,
+) (*Subscription, *Replay, error) {
+	sub, err := sm.createSubscription(subID, connID, userID, role, claims, schema, table, event, filterStr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sm.mu.RLock()
+	changeLog := sm.changeLog
+	sm.mu.RUnlock()
+
+	replay := &Replay{ResyncRequired: true}
+	if changeLog == nil {
+		return sub, replay, nil
+	}
+
+	events, latest, ok := changeLog.Since(since)
+	replay.Position = latest
+	if !ok {
+		return sub, replay, nil
+	}
+
+	replay.ResyncRequired = false
+	for _, e := range events {
+		if e.Schema != schema || e.Table != table {
+			continue
+		}
+		if visible := sm.VisibleEvent(ctx, sub, e); visible != nil {
+			replay.Events = append(replay.Events, visible)
+		}
+	}
+	return sub, replay, nil
+}
+
+// FinishReplay passes the live events held back during the replay to send, except the ones that
+// were replayed, and lets the following ones through
+func (sm *SubscriptionManager) FinishReplay(sub *Subscription, replay *Replay, send func(*ChangeEvent)) {
+	sub.replayMu.Lock()
+	defer sub.replayMu.Unlock()
+
+	if !replay.ResyncRequired {
+		sub.replayedTo = replay.Position
+	}
+	for _, e := range sub.replayBuffer {
+		if e.Position == 0 || e.Position > sub.replayedTo {
+			send(e)
+		}
+	}
+	sub.replaying = false
+	sub.replayBuffer = nil
+}
+
+// holdForReplay keeps a live event back while the subscription replays the events it missed, and
+// drops live events that were replayed already. It returns false when the event can be sent.
+func (sub *Subscription) holdForReplay(event *ChangeEvent) bool {
+	sub.replayMu.Lock()
+	defer sub.replayMu.Unlock()
+	if sub.replaying {
+		sub.replayBuffer = append(sub.replayBuffer, event)
+		return true
+	}
+	return event.Position != 0 && event.Position <= sub.replayedTo
+}
+
+// createSubscription creates and registers a subscription, holding back live events if replaying
+func (sm *SubscriptionManager) createSubscription(
+	subID string,
+	connID string,
+	userID string,
+	role string,
+	claims map[string]interface{},
+	schema string,
+	table string,
+	event string,
+	filterStr string,
+	replaying bool,
 ) (*Subscription, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -408,6 +599,8 @@ func (sm *SubscriptionManager) CreateSubscription(
 		Event:  event,
 		Filter: filter,
 		ConnID: connID,
+
+		replaying: replaying,
 	}
 
 	// Store subscription
@@ -511,6 +704,8 @@ func (sm *SubscriptionManager) FilterEventForSubscribers(ctx context.Context, ev
 
 	// Filter for each subscription
 	result := make(map[string]*ChangeEvent)
+	maskedByRole := make(map[string]*ChangeEvent)
+	dbFilterResults := make(map[string]bool)
 	for _, subID := range subIDsCopy {
 		sm.mu.RLock()
 		sub, exists := sm.subscriptions[subID]
@@ -526,17 +721,82 @@ func (sm *SubscriptionManager) FilterEventForSubscribers(ctx context.Context, ev
 		}
 
 		// Check RLS access
-		if sm.checkRLSAccess(ctx, sub, event) {
-			// Check Supabase-compatible filter
-			if sm.matchesFilter(event, sub) {
-				result[sub.ConnID] = event
-			}
+		if !sm.checkRLSAccess(ctx, sub, event) {
+			continue
+		}
+
+		// Apply column policies; filters only see what the subscriber may see
+		delivered, ok := maskedByRole[sub.Role]
+		if !ok {
+			delivered = sm.applyColumnPolicies(ctx, event, sub.Role)
+			maskedByRole[sub.Role] = delivered
+		}
+		if delivered == nil {
+			continue
+		}
+
+		// Check the subscription filter
+		if sm.matchesFilter(ctx, delivered, sub, dbFilterResults) && !sub.holdForReplay(delivered) {
+			result[sub.ConnID] = delivered
 		}
 	}
 
 	return result
 }
 
+// VisibleEvent returns the event as the subscriber may see it, or nil if the subscription doesn't
+// receive it. It applies the same event type, RLS, column policy and filter checks as
+// FilterEventForSubscribers, for subscriptions managed outside of the manager.
+func (sm *SubscriptionManager) VisibleEvent(ctx context.Context, sub *Subscription, event *ChangeEvent) *ChangeEvent {
+	if !sm.matchesEvent(event.Type, sub.Event) {
+		return nil
+	}
+	if !sm.checkRLSAccess(ctx, sub, event) {
+		return nil
+	}
+	delivered := sm.applyColumnPolicies(ctx, event, sub.Role)
+	if delivered == nil || !sm.matchesFilter(ctx, delivered, sub, nil) {
+		return nil
+	}
+	return delivered
+}
+
+// IsTableEnabled checks if a table is enabled for realtime
+func (sm *SubscriptionManager) IsTableEnabled(schema, table string) bool {
+	return sm.isTableAllowedUnsafe(schema, table)
+}
+
+// applyColumnPolicies returns the event as the role may see it.
+// Returns nil if the policies can't be evaluated, so the event is not delivered unmasked.
+func (sm *SubscriptionManager) applyColumnPolicies(ctx context.Context, event *ChangeEvent, role string) *ChangeEvent {
+	sm.mu.RLock()
+	applier := sm.columnPolicy
+	sm.mu.RUnlock()
+
+	if applier == nil {
+		return event
+	}
+	if role == "" {
+		role = "anon"
+	}
+
+	record, err := applier.ApplyColumnPolicies(ctx, event.Schema, event.Table, role, event.Record)
+	if err != nil {
+		log.Error().Err(err).Str("table", event.Schema+"."+event.Table).Msg("Failed to apply column policies to change event")
+		return nil
+	}
+	oldRecord, err := applier.ApplyColumnPolicies(ctx, event.Schema, event.Table, role, event.OldRecord)
+	if err != nil {
+		log.Error().Err(err).Str("table", event.Schema+"."+event.Table).Msg("Failed to apply column policies to change event")
+		return nil
+	}
+
+	masked := *event
+	masked.Record = record
+	masked.OldRecord = oldRecord
+	return &masked
+}
+
 // matchesEvent checks if an event type matches the subscription event filter
 func (sm *SubscriptionManager) matchesEvent(eventType, subEvent string) bool {
 	if subEvent == "*" {
@@ -545,19 +805,48 @@ func (sm *SubscriptionManager) matchesEvent(eventType, subEvent string) bool {
 	return eventType == subEvent
 }
 
-// matchesFilter checks if an event matches the subscription filter
-func (sm *SubscriptionManager) matchesFilter(event *ChangeEvent, sub *Subscription) bool {
-	// Use new Supabase-compatible filter if present
-	if sub.Filter != nil {
-		record := event.Record
-		if record == nil {
-			record = event.OldRecord
-		}
-		return sub.Filter.Matches(record)
+// matchesFilter checks if an event matches the subscription filter. Conditions that need the
+// database are evaluated there; dbResults, when set, shares their results between the
+// subscribers of an event, keyed by role since the record differs per role.
+func (sm *SubscriptionManager) matchesFilter(ctx context.Context, event *ChangeEvent, sub *Subscription, dbResults map[string]bool) bool {
+	// No filter specified - match all
+	if sub.Filter == nil {
+		return true
 	}
 
-	// No filter specified - match all
-	return true
+	record := event.Record
+	if record == nil {
+		record = event.OldRecord
+	}
+	return sub.Filter.evaluate(record, func(condition *Filter) bool {
+		key := sub.Role + ":" + condition.String()
+		if matches, ok := dbResults[key]; ok {
+			return matches
+		}
+
+		matches := false
+		if sm.db != nil {
+			// Only the filtered column is populated, so values masked for the subscriber
+			// never have to parse as their column type
+			column := condition.rootColumn()
+			where, args, err := condition.conditionSQL(2)
+			if err == nil {
+				matches, err = sm.db.MatchesFilter(ctx, event.Schema, event.Table,
+					map[string]interface{}{column: record[column]}, where, args)
+			}
+			if err != nil {
+				log.Warn().Err(err).
+					Str("subscription_id", sub.ID).
+					Str("condition", condition.String()).
+					Msg("Failed to evaluate realtime filter condition")
+				matches = false
+			}
+		}
+		if dbResults != nil {
+			dbResults[key] = matches
+		}
+		return matches
+	})
 }
 
 // checkRLSAccess verifies if a user can access a record based on RLS policies
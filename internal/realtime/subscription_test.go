@@ -2,6 +2,8 @@
 package realtime
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -298,12 +300,148 @@ func TestSubscriptionManager_MatchesFilter(t *testing.T) {
 				Filter: filterObj,
 			}
 
-			result := sm.matchesFilter(tt.event, sub)
+			result := sm.matchesFilter(context.Background(), tt.event, sub, nil)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+// fakeColumnPolicies hides the "secret" column for the "support" role
+type fakeColumnPolicies struct {
+	err error
+}
+
+func (f *fakeColumnPolicies) ApplyColumnPolicies(ctx context.Context, schema, table, role string, row map[string]interface{}) (map[string]interface{}, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if role != "support" || row == nil {
+		return row, nil
+	}
+	masked := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if k != "secret" {
+			masked[k] = v
+		}
+	}
+	return masked, nil
+}
+
+func TestSubscriptionManager_ColumnPolicies(t *testing.T) {
+	event := &ChangeEvent{
+		Type:      "UPDATE",
+		Schema:    "public",
+		Table:     "users",
+		Record:    map[string]interface{}{"id": 1, "secret": "s3cr3t"},
+		OldRecord: map[string]interface{}{"id": 1, "secret": "old"},
+	}
+
+	t.Run("masks records per role", func(t *testing.T) {
+		sm := newTestSubscriptionManager()
+		sm.SetColumnPolicyApplier(&fakeColumnPolicies{})
+		_, err := sm.CreateSubscription("sub1", "conn1", "user1", "support", nil, "public", "users", "*", "")
+		require.NoError(t, err)
+		_, err = sm.CreateSubscription("sub2", "conn2", "user2", "admin", nil, "public", "users", "*", "")
+		require.NoError(t, err)
+
+		result := sm.FilterEventForSubscribers(context.Background(), event)
+
+		require.Len(t, result, 2)
+		assert.NotContains(t, result["conn1"].Record, "secret")
+		assert.NotContains(t, result["conn1"].OldRecord, "secret")
+		assert.Equal(t, "s3cr3t", result["conn2"].Record["secret"])
+		assert.Equal(t, "s3cr3t", event.Record["secret"], "original event must not be modified")
+	})
+
+	t.Run("filters see masked records", func(t *testing.T) {
+		sm := newTestSubscriptionManager()
+		sm.SetColumnPolicyApplier(&fakeColumnPolicies{})
+		_, err := sm.CreateSubscription("sub1", "conn1", "user1", "support", nil, "public", "users", "*", "secret=eq.s3cr3t")
+		require.NoError(t, err)
+
+		assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), event))
+	})
+
+	t.Run("policy errors drop the event", func(t *testing.T) {
+		sm := newTestSubscriptionManager()
+		sm.SetColumnPolicyApplier(&fakeColumnPolicies{err: errors.New("cache unavailable")})
+		_, err := sm.CreateSubscription("sub1", "conn1", "user1", "support", nil, "public", "users", "*", "")
+		require.NoError(t, err)
+
+		assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), event))
+	})
+}
+
+func TestSubscriptionManager_VisibleEvent(t *testing.T) {
+	mockDB := testutil.NewMockSubscriptionDB()
+	mockDB.EnableTable("public", "users")
+	mockDB.RLSResults["public.users.2"] = false
+	sm := NewSubscriptionManager(mockDB)
+	sm.SetColumnPolicyApplier(&fakeColumnPolicies{})
+
+	sub := &Subscription{ID: "gql1", UserID: "user1", Role: "support", Schema: "public", Table: "users", Event: "UPDATE"}
+	event := &ChangeEvent{
+		Type:   "UPDATE",
+		Schema: "public",
+		Table:  "users",
+		Record: map[string]interface{}{"id": 1, "secret": "s3cr3t"},
+	}
+
+	visible := sm.VisibleEvent(context.Background(), sub, event)
+	require.NotNil(t, visible)
+	assert.NotContains(t, visible.Record, "secret")
+
+	assert.Nil(t, sm.VisibleEvent(context.Background(), sub, &ChangeEvent{Type: "INSERT", Schema: "public", Table: "users", Record: map[string]interface{}{"id": 1}}))
+	assert.Nil(t, sm.VisibleEvent(context.Background(), sub, &ChangeEvent{Type: "UPDATE", Schema: "public", Table: "users", Record: map[string]interface{}{"id": 2}}))
+
+	assert.True(t, sm.IsTableEnabled("public", "users"))
+	assert.False(t, sm.IsTableEnabled("public", "posts"))
+}
+
+// countingFilterDB counts the filter conditions evaluated by the database
+type countingFilterDB struct {
+	*testutil.MockSubscriptionDB
+	filterCalls int
+}
+
+func (db *countingFilterDB) MatchesFilter(ctx context.Context, schema, table string, record map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	db.filterCalls++
+	return db.MockSubscriptionDB.MatchesFilter(ctx, schema, table, record, condition, args)
+}
+
+func TestSubscriptionManager_DatabaseFilterConditions(t *testing.T) {
+	mockDB := &countingFilterDB{MockSubscriptionDB: testutil.NewMockSubscriptionDB()}
+	mockDB.EnableTable("public", "posts")
+	mockDB.FilterResults[`public.posts "body" @@ plainto_tsquery($2::regconfig, $3)`] = true
+	sm := NewSubscriptionManager(mockDB)
+
+	filter := "status=eq.open&body=fts.cats&fts_language=english"
+	for _, id := range []string{"sub1", "sub2"} {
+		_, err := sm.CreateSubscription(id, "conn-"+id, "user1", "authenticated", nil, "public", "posts", "*", filter)
+		require.NoError(t, err)
+	}
+
+	event := &ChangeEvent{
+		Type:   "INSERT",
+		Schema: "public",
+		Table:  "posts",
+		Record: map[string]interface{}{"id": 1, "status": "open", "body": "about cats"},
+	}
+	assert.Len(t, sm.FilterEventForSubscribers(context.Background(), event), 2)
+	assert.Equal(t, 1, mockDB.filterCalls, "subscribers with the same role share the result")
+
+	// Conditions evaluated in memory decide first
+	event.Record = map[string]interface{}{"id": 2, "status": "closed", "body": "about cats"}
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), event))
+	assert.Equal(t, 1, mockDB.filterCalls)
+
+	sub := &Subscription{ID: "gql1", Role: "authenticated", Schema: "public", Table: "posts", Event: "*"}
+	sub.Filter, _ = ParseFilter("body=not.fts.cats")
+	event.Record = map[string]interface{}{"id": 3, "body": "about dogs"}
+	assert.Nil(t, sm.VisibleEvent(context.Background(), sub, event), "unknown conditions don't match")
+	assert.Equal(t, 2, mockDB.filterCalls)
+}
+
 func TestSubscriptionManager_Stats(t *testing.T) {
 	sm := newTestSubscriptionManager()
 
@@ -439,3 +577,78 @@ func TestCopyClaims(t *testing.T) {
 		assert.Nil(t, original["new_key"])
 	})
 }
+
+func TestSubscriptionManager_ResumeSubscription(t *testing.T) {
+	mockDB := testutil.NewMockSubscriptionDB()
+	mockDB.EnableTable("public", "posts")
+	mockDB.RLSResults["public.posts.2"] = false
+	sm := NewSubscriptionManager(mockDB)
+	assert.Zero(t, sm.ChangePosition())
+
+	sm.SetChangeLog(NewChangeLog(10, time.Minute))
+	since := sm.ChangePosition()
+	require.NotZero(t, since)
+
+	change := func(table string, id int) *ChangeEvent {
+		event := &ChangeEvent{Type: "INSERT", Schema: "public", Table: table, Record: map[string]interface{}{"id": id}}
+		sm.RecordChange(event)
+		return event
+	}
+	change("posts", 1)
+	change("posts", 2)
+	change("comments", 3)
+	last := change("posts", 4)
+
+	sub, replay, err := sm.ResumeSubscription(context.Background(), since, "sub1", "conn1", "user1", "authenticated", nil, "public", "posts", "*", "")
+	require.NoError(t, err)
+	assert.False(t, replay.ResyncRequired)
+	assert.Equal(t, last.Position, replay.Position)
+	require.Len(t, replay.Events, 2, "other tables and rows hidden by RLS are skipped")
+	assert.Equal(t, 1, replay.Events[0].Record["id"])
+	assert.Equal(t, 4, replay.Events[1].Record["id"])
+
+	// Live events are held back until the replay is done, replayed ones aren't sent again
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), last))
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), change("posts", 5)))
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), &ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts", Record: map[string]interface{}{"id": 6}}))
+
+	var sent []*ChangeEvent
+	sm.FinishReplay(sub, replay, func(e *ChangeEvent) { sent = append(sent, e) })
+	require.Len(t, sent, 2)
+	assert.Equal(t, 5, sent[0].Record["id"])
+	assert.Equal(t, 6, sent[1].Record["id"])
+
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), last))
+	assert.Len(t, sm.FilterEventForSubscribers(context.Background(), change("posts", 7)), 1)
+}
+
+func TestSubscriptionManager_ResumeSubscriptionResync(t *testing.T) {
+	sm := newTestSubscriptionManager()
+
+	// Without a change log nothing can be replayed
+	sub, replay, err := sm.ResumeSubscription(context.Background(), 1, "sub1", "conn1", "user1", "authenticated", nil, "public", "posts", "*", "")
+	require.NoError(t, err)
+	assert.True(t, replay.ResyncRequired)
+
+	held := &ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts", Record: map[string]interface{}{"id": 1}}
+	assert.Empty(t, sm.FilterEventForSubscribers(context.Background(), held))
+	var sent []*ChangeEvent
+	sm.FinishReplay(sub, replay, func(e *ChangeEvent) { sent = append(sent, e) })
+	assert.Len(t, sent, 1, "live events still arrive")
+
+	// Positions that are no longer retained, or from another instance
+	sm.SetChangeLog(NewChangeLog(1, time.Minute))
+	since := sm.ChangePosition()
+	sm.RecordChange(&ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts"})
+	sm.RecordChange(&ChangeEvent{Type: "INSERT", Schema: "public", Table: "posts"})
+	for _, position := range []uint64{since, since + 100} {
+		_, replay, err = sm.ResumeSubscription(context.Background(), position, "sub2", "conn2", "user1", "authenticated", nil, "public", "posts", "*", "")
+		require.NoError(t, err)
+		assert.True(t, replay.ResyncRequired)
+		assert.Empty(t, replay.Events)
+		assert.Equal(t, since+2, replay.Position)
+	}
+
+	_, _, err = sm.ResumeSubscription(context.Background(), since, "sub3", "conn3", "user1", "authenticated", nil, "public", "disabled", "*", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package responsecache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// trackedTTL is how long a table's realtime registration is trusted before it is checked again
+const trackedTTL = 30 * time.Second
+
+// Cache caches responses of the configured tables.
+//
+// Entries are invalidated from the realtime change notifications (it implements
+// realtime.ChangeObserver), so a table is only cached while the listener is connected and
+// while the table notifies INSERT, UPDATE and DELETE. Anything that may have hidden a change
+// (a dropped notification, a lost listener) invalidates every table.
+type Cache struct {
+	store        Store
+	tables       map[string]bool
+	ttl          time.Duration
+	maxEntrySize int
+
+	listening atomic.Bool
+
+	// tracked reports whether a table sends change notifications for every write
+	tracked    func(ctx context.Context, schema, table string) (bool, error)
+	trackedMu  sync.Mutex
+	trackedFor map[string]trackedStatus
+}
+
+type trackedStatus struct {
+	tracked   bool
+	checkedAt time.Time
+}
+
+// New creates a response cache for the tables of cfg. The pool is used to read the realtime
+// registry of the tables.
+func New(store Store, cfg config.ResponseCacheConfig, pool *pgxpool.Pool) *Cache {
+	c := &Cache{
+		store:        store,
+		tables:       make(map[string]bool),
+		ttl:          cfg.TTL,
+		maxEntrySize: cfg.MaxEntrySize,
+		trackedFor:   make(map[string]trackedStatus),
+	}
+	for _, table := range cfg.Tables {
+		if !strings.Contains(table, ".") {
+			table = "public." + table
+		}
+		c.tables[table] = true
+	}
+	if pool != nil {
+		c.tracked = func(ctx context.Context, schema, table string) (bool, error) {
+			return realtimeTracked(ctx, pool, schema, table)
+		}
+	}
+	return c
+}
+
+// realtimeTracked reports whether realtime is enabled for all write events of a table
+func realtimeTracked(ctx context.Context, pool *pgxpool.Pool, schema, table string) (bool, error) {
+	var tracked bool
+	err := pool.QueryRow(ctx, `
+		SELECT realtime_enabled AND events @> ARRAY['INSERT', 'UPDATE', 'DELETE']
+		FROM realtime.schema_registry
+		WHERE schema_name = $1 AND table_name = $2
+	`, schema, table).Scan(&tracked)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return tracked, err
+}
+
+// Cacheable reports whether responses of a table may be cached right now
+func (c *Cache) Cacheable(ctx context.Context, schema, table string) bool {
+	name := schema + "." + table
+	if !c.tables[name] || !c.listening.Load() {
+		return false
+	}
+
+	c.trackedMu.Lock()
+	status, ok := c.trackedFor[name]
+	c.trackedMu.Unlock()
+	if ok && time.Since(status.checkedAt) < trackedTTL {
+		return status.tracked
+	}
+
+	tracked := false
+	if c.tracked != nil {
+		var err error
+		tracked, err = c.tracked(ctx, schema, table)
+		if err != nil {
+			log.Warn().Err(err).Str("table", name).Msg("Failed to check realtime registration for response cache")
+			return false
+		}
+	}
+	if !tracked {
+		log.Warn().Str("table", name).Msg("Response cache skipped: realtime is not enabled for INSERT, UPDATE and DELETE on the table")
+	}
+
+	c.trackedMu.Lock()
+	c.trackedFor[name] = trackedStatus{tracked: tracked, checkedAt: time.Now()}
+	c.trackedMu.Unlock()
+	return tracked
+}
+
+// Key returns the entry key of a request fingerprint under the table's current generation.
+// The key must be taken before the response is computed, so a change made meanwhile can't
+// leave a stale response under the new generation.
+func (c *Cache) Key(ctx context.Context, schema, table, fingerprint string) (string, bool) {
+	name := schema + "." + table
+	generation, err := c.store.Generation(ctx, name)
+	if err != nil {
+		log.Warn().Err(err).Str("table", name).Msg("Failed to read response cache generation")
+		return "", false
+	}
+	return name + ":" + generation + ":" + fingerprint, true
+}
+
+// Get returns the cached entry of a key
+func (c *Cache) Get(ctx context.Context, schema, table, key string) (*Entry, bool) {
+	entry, ok, err := c.store.Get(ctx, schema+"."+table, key)
+	if err != nil {
+		log.Warn().Err(err).Str("table", schema+"."+table).Msg("Failed to read response cache")
+		return nil, false
+	}
+	return entry, ok
+}
+
+// Set caches an entry unless it exceeds the maximum entry size
+func (c *Cache) Set(ctx context.Context, schema, table, key string, entry *Entry) {
+	if len(entry.Body) > c.maxEntrySize {
+		return
+	}
+	if err := c.store.Set(ctx, schema+"."+table, key, entry, c.ttl); err != nil {
+		log.Warn().Err(err).Str("table", schema+"."+table).Msg("Failed to write response cache")
+	}
+}
+
+// Invalidate drops the entries of a table
+func (c *Cache) Invalidate(ctx context.Context, schema, table string) {
+	name := schema + "." + table
+	if !c.tables[name] {
+		return
+	}
+	if err := c.store.Invalidate(ctx, name); err != nil {
+		// The entries can't be trusted anymore; stop serving them until the store recovers
+		log.Error().Err(err).Str("table", name).Msg("Failed to invalidate response cache")
+		c.invalidateAll()
+	}
+}
+
+// TableChanged invalidates a table after a change notification
+func (c *Cache) TableChanged(schema, table string) {
+	c.Invalidate(context.Background(), schema, table)
+}
+
+// ListeningChanged enables the cache while the change listener is connected. Changes made
+// while it wasn't are unknown, so every table is invalidated either way.
+func (c *Cache) ListeningChanged(listening bool) {
+	if !listening {
+		c.listening.Store(false)
+	}
+	c.invalidateAll()
+	if listening {
+		c.listening.Store(true)
+	}
+}
+
+// ChangesMissed invalidates every table after a dropped change notification
+func (c *Cache) ChangesMissed() {
+	c.invalidateAll()
+}
+
+func (c *Cache) invalidateAll() {
+	if err := c.store.InvalidateAll(context.Background()); err != nil {
+		log.Error().Err(err).Msg("Failed to invalidate response cache, disabling it until the listener reconnects")
+		c.listening.Store(false)
+	}
+}
+
+// Close closes the underlying store
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
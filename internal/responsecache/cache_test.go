@@ -0,0 +1,110 @@
+package responsecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCache(tracked bool) *Cache {
+	c := New(NewMemoryStore(100), config.ResponseCacheConfig{
+		Enabled:      true,
+		Tables:       []string{"products", "catalog.categories"},
+		TTL:          time.Minute,
+		MaxEntries:   100,
+		MaxEntrySize: 16,
+	}, nil)
+	c.tracked = func(ctx context.Context, schema, table string) (bool, error) {
+		return tracked, nil
+	}
+	return c
+}
+
+func TestCache_Cacheable(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not cacheable until the listener is connected", func(t *testing.T) {
+		c := newTestCache(true)
+		assert.False(t, c.Cacheable(ctx, "public", "products"))
+
+		c.ListeningChanged(true)
+		assert.True(t, c.Cacheable(ctx, "public", "products"))
+		assert.True(t, c.Cacheable(ctx, "catalog", "categories"))
+		assert.False(t, c.Cacheable(ctx, "public", "orders"), "table not configured")
+
+		c.ListeningChanged(false)
+		assert.False(t, c.Cacheable(ctx, "public", "products"))
+	})
+
+	t.Run("not cacheable without realtime notifications", func(t *testing.T) {
+		c := newTestCache(false)
+		c.ListeningChanged(true)
+		assert.False(t, c.Cacheable(ctx, "public", "products"))
+	})
+}
+
+func TestCache_InvalidatedByChanges(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(true)
+	c.ListeningChanged(true)
+
+	store := func(fingerprint string) string {
+		key, ok := c.Key(ctx, "public", "products", fingerprint)
+		assert.True(t, ok)
+		c.Set(ctx, "public", "products", key, &Entry{Status: 200, Body: []byte(`[]`)})
+		return key
+	}
+	hit := func(fingerprint string) bool {
+		key, _ := c.Key(ctx, "public", "products", fingerprint)
+		_, ok := c.Get(ctx, "public", "products", key)
+		return ok
+	}
+
+	store("a")
+	assert.True(t, hit("a"))
+
+	c.TableChanged("public", "orders")
+	assert.True(t, hit("a"), "changes to other tables keep the entry")
+
+	c.TableChanged("public", "products")
+	assert.False(t, hit("a"))
+
+	store("a")
+	c.ChangesMissed()
+	assert.False(t, hit("a"))
+
+	store("a")
+	c.ListeningChanged(false)
+	c.ListeningChanged(true)
+	assert.False(t, hit("a"))
+}
+
+func TestCache_KeyTakenBeforeChangeIsNotServed(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(true)
+	c.ListeningChanged(true)
+
+	// A response computed while the table changes is stored under the old generation
+	key, _ := c.Key(ctx, "public", "products", "a")
+	c.TableChanged("public", "products")
+	c.Set(ctx, "public", "products", key, &Entry{Status: 200})
+
+	next, _ := c.Key(ctx, "public", "products", "a")
+	assert.NotEqual(t, key, next)
+	_, ok := c.Get(ctx, "public", "products", next)
+	assert.False(t, ok)
+}
+
+func TestCache_SkipsLargeEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(true)
+	c.ListeningChanged(true)
+
+	key, _ := c.Key(ctx, "public", "products", "a")
+	c.Set(ctx, "public", "products", key, &Entry{Status: 200, Body: []byte(`[{"name":"larger than 16 bytes"}]`)})
+	_, ok := c.Get(ctx, "public", "products", key)
+	assert.False(t, ok)
+}
@@ -0,0 +1,36 @@
+package responsecache
+
+import (
+	"fmt"
+
+	"github.com/fluxbase-eu/fluxbase/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// NewStore creates a response cache store based on the scaling configuration.
+//
+// Backend options:
+// - "local", "postgres": In-memory store per instance; every instance invalidates its own
+// entries from the change notifications it receives
+// - "redis": Redis-compatible store shared by all instances
+func NewStore(cfg *config.ScalingConfig, maxEntries int) (Store, error) {
+	switch cfg.Backend {
+	case "local", "", "postgres":
+		log.Info().Int("max_entries", maxEntries).Msg("Using in-memory response cache")
+		return NewMemoryStore(maxEntries), nil
+
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("redis_url is required for redis response cache backend")
+		}
+		log.Info().Msg("Using Redis-compatible response cache")
+		store, err := NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("unknown response cache backend: %s (valid options: local, postgres, redis)", cfg.Backend)
+	}
+}
@@ -0,0 +1,129 @@
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements Store using an in-memory LRU.
+// Each instance keeps its own entries and invalidates them from its own change listener.
+type MemoryStore struct {
+	mu          sync.Mutex
+	maxEntries  int
+	epoch       uint64
+	generations map[string]uint64
+	entries     map[string]*list.Element
+	lru         *list.List // front is most recently used
+}
+
+type memoryEntry struct {
+	table     string
+	key       string
+	entry     *Entry
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory response cache holding at most maxEntries entries.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryStore{
+		maxEntries:  maxEntries,
+		generations: make(map[string]uint64),
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// Generation returns the current generation of a table.
+func (s *MemoryStore) Generation(ctx context.Context, table string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strconv.FormatUint(s.epoch, 10) + "." + strconv.FormatUint(s.generations[table], 10), nil
+}
+
+// Get retrieves the entry stored under key for a table.
+func (s *MemoryStore) Get(ctx context.Context, table, key string) (*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if time.Now().After(e.expiresAt) {
+		s.remove(el)
+		return nil, false, nil
+	}
+	s.lru.MoveToFront(el)
+	return e.entry, true, nil
+}
+
+// Set stores an entry under key for a table for at most ttl.
+func (s *MemoryStore) Set(ctx context.Context, table, key string, entry *Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{
+		table:     table,
+		key:       key,
+		entry:     entry,
+		expiresAt: time.Now().Add(ttl),
+	})
+	for s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Invalidate advances the generation of a table and drops its entries.
+func (s *MemoryStore) Invalidate(ctx context.Context, table string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generations[table]++
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*memoryEntry).table == table {
+			s.remove(el)
+		}
+		el = next
+	}
+	return nil
+}
+
+// InvalidateAll advances the generation of every table and drops all entries.
+func (s *MemoryStore) InvalidateAll(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.epoch++
+	s.entries = make(map[string]*list.Element)
+	s.lru.Init()
+	return nil
+}
+
+// Len returns the number of stored entries.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// Close releases the stored entries.
+func (s *MemoryStore) Close() error {
+	return s.InvalidateAll(context.Background())
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
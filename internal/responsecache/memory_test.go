@@ -0,0 +1,88 @@
+package responsecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_GetSet(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	defer store.Close()
+
+	_, ok, err := store.Get(ctx, "public.products", "k1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	entry := &Entry{Status: 200, Body: []byte(`[]`)}
+	require.NoError(t, store.Set(ctx, "public.products", "k1", entry, time.Minute))
+
+	got, ok, err := store.Get(ctx, "public.products", "k1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, entry, got)
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	defer store.Close()
+
+	require.NoError(t, store.Set(ctx, "public.products", "k1", &Entry{Status: 200}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok, err := store.Get(ctx, "public.products", "k1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	defer store.Close()
+
+	require.NoError(t, store.Set(ctx, "public.products", "k1", &Entry{}, time.Minute))
+	require.NoError(t, store.Set(ctx, "public.products", "k2", &Entry{}, time.Minute))
+	_, _, _ = store.Get(ctx, "public.products", "k1")
+	require.NoError(t, store.Set(ctx, "public.products", "k3", &Entry{}, time.Minute))
+
+	_, ok, _ := store.Get(ctx, "public.products", "k2")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok, _ = store.Get(ctx, "public.products", "k1")
+	assert.True(t, ok)
+	_, ok, _ = store.Get(ctx, "public.products", "k3")
+	assert.True(t, ok)
+}
+
+func TestMemoryStore_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	defer store.Close()
+
+	before, _ := store.Generation(ctx, "public.products")
+	other, _ := store.Generation(ctx, "public.categories")
+	require.NoError(t, store.Set(ctx, "public.products", "p", &Entry{}, time.Minute))
+	require.NoError(t, store.Set(ctx, "public.categories", "c", &Entry{}, time.Minute))
+
+	require.NoError(t, store.Invalidate(ctx, "public.products"))
+
+	after, _ := store.Generation(ctx, "public.products")
+	assert.NotEqual(t, before, after)
+	otherAfter, _ := store.Generation(ctx, "public.categories")
+	assert.Equal(t, other, otherAfter)
+
+	_, ok, _ := store.Get(ctx, "public.products", "p")
+	assert.False(t, ok)
+	_, ok, _ = store.Get(ctx, "public.categories", "c")
+	assert.True(t, ok)
+
+	require.NoError(t, store.InvalidateAll(ctx))
+	otherAfter, _ = store.Generation(ctx, "public.categories")
+	assert.NotEqual(t, other, otherAfter)
+	assert.Equal(t, 0, store.Len())
+}
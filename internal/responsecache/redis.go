@@ -0,0 +1,113 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	redisKeyPrefix = "fluxbase:respcache:"
+	redisEpochKey  = redisKeyPrefix + "epoch"
+)
+
+// RedisStore implements Store using Redis (or Redis-compatible backends like Dragonfly).
+// All instances share the entries and generations; every instance still advances the
+// generations from its own change listener, which is harmless because it is idempotent
+// from the cache's point of view.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a new Redis-backed response cache.
+// url should be in the format: redis://[password@]host:port[/db]
+func NewRedisStore(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("addr", opts.Addr).Msg("Connected to Redis-compatible backend for response cache")
+
+	return &RedisStore{
+		client: client,
+	}, nil
+}
+
+func redisGenerationKey(table string) string {
+	return redisKeyPrefix + "gen:" + table
+}
+
+// Generation returns the current generation of a table.
+func (s *RedisStore) Generation(ctx context.Context, table string) (string, error) {
+	values, err := s.client.MGet(ctx, redisEpochKey, redisGenerationKey(table)).Result()
+	if err != nil {
+		return "", err
+	}
+	generation := ""
+	for i, v := range values {
+		if i > 0 {
+			generation += "."
+		}
+		if str, ok := v.(string); ok {
+			generation += str
+		} else {
+			generation += "0"
+		}
+	}
+	return generation, nil
+}
+
+// Get retrieves the entry stored under key for a table.
+func (s *RedisStore) Get(ctx context.Context, table, key string) (*Entry, bool, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, err
+	}
+	return &entry, true, nil
+}
+
+// Set stores an entry under key for a table for at most ttl.
+func (s *RedisStore) Set(ctx context.Context, table, key string, entry *Entry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
+
+// Invalidate advances the generation of a table. Entries of older generations expire on their own.
+func (s *RedisStore) Invalidate(ctx context.Context, table string) error {
+	return s.client.Incr(ctx, redisGenerationKey(table)).Err()
+}
+
+// InvalidateAll advances the generation of every table.
+func (s *RedisStore) InvalidateAll(ctx context.Context) error {
+	return s.client.Incr(ctx, redisEpochKey).Err()
+}
+
+// Close closes the Redis connection.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
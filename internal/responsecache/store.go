@@ -0,0 +1,43 @@
+// Package responsecache caches REST read responses for tables that opt in, and invalidates them
+// from the database change notifications consumed by the realtime listener.
+package responsecache
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is a cached response
+type Entry struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    []byte            `json:"body"`
+}
+
+// Store is the interface for response cache storage backends.
+// It follows the scaling backend of the deployment:
+// - Memory: Single instance deployments, or any deployment where every instance listens for changes
+// - Redis: Multi-instance deployments sharing one cache (works with Dragonfly, Redis, Valkey, KeyDB)
+//
+// Entries are grouped by table. Every table has a generation that is part of its entry keys, so
+// invalidating a table only needs to advance its generation; entries of older generations are
+// never read again and age out.
+type Store interface {
+	// Generation returns the current generation of a table.
+	Generation(ctx context.Context, table string) (string, error)
+
+	// Get retrieves the entry stored under key for a table.
+	Get(ctx context.Context, table, key string) (*Entry, bool, error)
+
+	// Set stores an entry under key for a table for at most ttl.
+	Set(ctx context.Context, table, key string, entry *Entry, ttl time.Duration) error
+
+	// Invalidate advances the generation of a table, dropping its entries.
+	Invalidate(ctx context.Context, table string) error
+
+	// InvalidateAll advances the generation of every table.
+	InvalidateAll(ctx context.Context) error
+
+	// Close closes the store and releases resources.
+	Close() error
+}
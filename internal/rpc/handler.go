@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -23,13 +25,35 @@ type Handler struct {
 	authService    *auth.Service
 	scheduler      *Scheduler
 	loggingService *logging.Service
+	onChange       func()
 }
 
+var (
+	// ErrProcedureNotFound is returned by InvokeAs for unknown and disabled procedures
+	ErrProcedureNotFound = errors.New("procedure not found")
+
+	// ErrAccessDenied is wrapped by InvokeAs errors for callers not allowed to invoke a procedure
+	ErrAccessDenied = errors.New("access denied")
+)
+
 // SetScheduler sets the scheduler for procedure lifecycle management
 func (h *Handler) SetScheduler(scheduler *Scheduler) {
 	h.scheduler = scheduler
 }
 
+// SetOnChange sets a function called after procedures are updated, deleted or synced,
+// e.g. to regenerate schemas exposing them
+func (h *Handler) SetOnChange(fn func()) {
+	h.onChange = fn
+}
+
+// notifyChange calls the change function, if any
+func (h *Handler) notifyChange() {
+	if h.onChange != nil {
+		h.onChange()
+	}
+}
+
 // GetExecutor returns the executor for external use (e.g., scheduler)
 func (h *Handler) GetExecutor() *Executor {
 	return h.executor
@@ -187,6 +211,7 @@ func (h *Handler) UpdateProcedure(c *fiber.Ctx) error {
 			log.Warn().Err(err).Str("procedure", procedure.Name).Msg("Failed to reschedule procedure")
 		}
 	}
+	h.notifyChange()
 
 	return c.JSON(procedure)
 }
@@ -210,6 +235,8 @@ func (h *Handler) DeleteProcedure(c *fiber.Ctx) error {
 		})
 	}
 
+	h.notifyChange()
+
 	return c.JSON(fiber.Map{
 		"message": "Procedure deleted successfully",
 	})
@@ -419,6 +446,10 @@ func (h *Handler) SyncProcedures(c *fiber.Ctx) error {
 		}
 	}
 
+	if !req.Options.DryRun && result.Summary.Created+result.Summary.Updated+result.Summary.Deleted > 0 {
+		h.notifyChange()
+	}
+
 	return c.JSON(result)
 }
 
@@ -790,6 +821,48 @@ func (h *Handler) Invoke(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+// ListEnabledProcedures returns the enabled procedures of all namespaces
+func (h *Handler) ListEnabledProcedures(ctx context.Context) ([]*Procedure, error) {
+	procedures, err := h.storage.ListProcedures(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make([]*Procedure, 0, len(procedures))
+	for _, proc := range procedures {
+		if proc.Enabled {
+			enabled = append(enabled, proc)
+		}
+	}
+	return enabled, nil
+}
+
+// InvokeAs invokes a procedure synchronously for a caller other than an HTTP request, such
+// as a GraphQL field, with the same access checks as Invoke. The caller's identity and the
+// parameters are taken from execCtx.
+func (h *Handler) InvokeAs(ctx context.Context, namespace, name string, execCtx *ExecuteContext) (*ExecuteResult, error) {
+	procedure, err := h.storage.GetProcedureByName(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get procedure: %w", err)
+	}
+	if procedure == nil || !procedure.Enabled {
+		return nil, ErrProcedureNotFound
+	}
+
+	if execCtx.UserRole == "" {
+		execCtx.UserRole = "anon"
+	}
+	if err := h.validator.ValidateAccess(procedure, execCtx.UserRole, execCtx.UserID != ""); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccessDenied, err.Error())
+	}
+
+	execCtx.Procedure = procedure
+	execCtx.IsAsync = false
+	execCtx.DisableExecutionLogs = procedure.DisableExecutionLogs
+
+	return h.executor.Execute(ctx, execCtx)
+}
+
 // GetPublicExecution returns execution status for user's own execution
 // GET /api/v1/rpc/executions/:id
 func (h *Handler) GetPublicExecution(c *fiber.Ctx) error {
@@ -50,6 +50,11 @@ type Procedure struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// IsReadOnly reports whether the procedure's query only reads data
+func (p *Procedure) IsReadOnly() bool {
+	return NewValidator().IsReadOnlySQL(p.SQLQuery)
+}
+
 // ProcedureSummary is a lightweight version for listings
 type ProcedureSummary struct {
 	ID                      string    `json:"id"`
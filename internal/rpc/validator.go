@@ -283,6 +283,39 @@ func (v *Validator) ValidateAccess(proc *Procedure, userRole string, isAuthentic
 	return nil
 }
 
+// IsReadOnlySQL reports whether a query only reads data: a single SELECT without data-modifying
+// CTEs, row locks or SELECT INTO. Queries that can't be parsed aren't read-only.
+func (v *Validator) IsReadOnlySQL(sql string) bool {
+	processedSQL, _ := v.preprocessNamedParams(sql)
+	parseResult, err := pg_query.Parse(processedSQL)
+	if err != nil || len(parseResult.Stmts) != 1 {
+		return false
+	}
+
+	return isReadOnlySelect(parseResult.Stmts[0].Stmt.GetSelectStmt())
+}
+
+// isReadOnlySelect reports whether a SELECT statement, including its set operations, only reads data
+func isReadOnlySelect(sel *pg_query.SelectStmt) bool {
+	if sel == nil || sel.IntoClause != nil || len(sel.LockingClause) > 0 {
+		return false
+	}
+	if sel.WithClause != nil {
+		for _, cte := range sel.WithClause.Ctes {
+			if !isReadOnlySelect(cte.GetCommonTableExpr().GetCtequery().GetSelectStmt()) {
+				return false
+			}
+		}
+	}
+	if sel.Larg != nil && !isReadOnlySelect(sel.Larg) {
+		return false
+	}
+	if sel.Rarg != nil && !isReadOnlySelect(sel.Rarg) {
+		return false
+	}
+	return true
+}
+
 // getOperationType determines the SQL operation type from a parsed statement
 func (v *Validator) getOperationType(stmt *pg_query.Node) string {
 	if stmt == nil {
@@ -439,6 +439,33 @@ func TestValidator_GetOperationType(t *testing.T) {
 	}
 }
 
+func TestValidator_IsReadOnlySQL(t *testing.T) {
+	v := NewValidator()
+
+	testCases := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{"select", "SELECT * FROM users WHERE id = $user_id", true},
+		{"select with CTE", "WITH active AS (SELECT * FROM users WHERE active) SELECT count(*) FROM active", true},
+		{"union", "SELECT id FROM users UNION SELECT id FROM admins", true},
+		{"insert", "INSERT INTO users (name) VALUES ($name)", false},
+		{"update", "UPDATE users SET name = $name WHERE id = $id", false},
+		{"data-modifying CTE", "WITH moved AS (DELETE FROM queue RETURNING *) SELECT * FROM moved", false},
+		{"row locks", "SELECT * FROM jobs FOR UPDATE SKIP LOCKED", false},
+		{"select into", "SELECT * INTO backup FROM users", false},
+		{"multiple statements", "SELECT 1; SELECT 2", false},
+		{"invalid", "SELEC * FROM users", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, v.IsReadOnlySQL(tc.sql))
+		})
+	}
+}
+
 func TestValidator_ExtractTables(t *testing.T) {
 	v := NewValidator()
 
@@ -372,13 +372,23 @@ type MockSubscriptionDB struct {
 		IsOwner bool
 		Exists  bool
 	}
+
+	// FilterResults maps "schema.table" and a SQL filter condition, separated by a space,
+	// e.g. `public.posts "body" @@ plainto_tsquery($2)`, to the condition's result
+	FilterResults map[string]bool
+
+	// ChannelResults maps a channel policy condition and its arguments, separated by a space,
+	// e.g. `$1 = 'general' [general]`, to the condition's result
+	ChannelResults map[string]bool
 }
 
 // NewMockSubscriptionDB creates a new mock subscription database
 func NewMockSubscriptionDB() *MockSubscriptionDB {
 	return &MockSubscriptionDB{
-		EnabledTables: make(map[string]bool),
-		RLSResults:    make(map[string]bool),
+		EnabledTables:  make(map[string]bool),
+		RLSResults:     make(map[string]bool),
+		FilterResults:  make(map[string]bool),
+		ChannelResults: make(map[string]bool),
 		OwnershipResults: make(map[uuid.UUID]struct {
 			IsOwner bool
 			Exists  bool
@@ -445,3 +455,21 @@ func (m *MockSubscriptionDB) CheckFunctionOwnership(ctx context.Context, execID,
 	}
 	return false, false, nil
 }
+
+// MatchesFilter implements SubscriptionDB
+func (m *MockSubscriptionDB) MatchesFilter(ctx context.Context, schema, table string, record map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Default: no match
+	return m.FilterResults[schema+"."+table+" "+condition], nil
+}
+
+// CheckChannelAccess returns the configured result of a channel policy condition
+func (m *MockSubscriptionDB) CheckChannelAccess(ctx context.Context, role string, claims map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Default: deny access
+	return m.ChannelResults[fmt.Sprintf("%s %v", condition, args)], nil
+}
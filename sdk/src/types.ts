@@ -330,7 +330,7 @@ export interface RealtimeMessage {
   event?: string; // INSERT, UPDATE, DELETE, or *
   schema?: string;
   table?: string;
-  filter?: string; // Supabase-compatible filter: column=operator.value
+  filter?: string; // Filter in the REST API filter grammar: column=operator.value&or=(...)
   payload?: unknown;
   error?: string;
   config?: PostgresChangesConfig; // Alternative format for postgres_changes
@@ -346,7 +346,8 @@ export interface PostgresChangesConfig {
   event: "INSERT" | "UPDATE" | "DELETE" | "*";
   schema: string;
   table: string;
-  filter?: string; // Optional filter: column=operator.value
+  filter?: string; // Optional filter in the REST API filter grammar, e.g. status=eq.open&or=(...)
+  since?: number; // Optional change position to resume after, replaying the missed events
 }
 
 /**